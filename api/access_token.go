@@ -9,12 +9,16 @@ import (
 )
 
 type AccessToken struct {
-	ID         uuid.UUID       `json:"id"`
-	CreatedAt  time.Time       `json:"createdAt"`
-	ExpiresAt  *time.Time      `json:"expiresAt,omitempty"`
-	LastUsedAt *time.Time      `json:"lastUsedAt,omitempty"`
-	Label      *string         `json:"label,omitempty"`
-	UserRole   *types.UserRole `json:"userRole,omitempty"`
+	ID               uuid.UUID               `json:"id"`
+	CreatedAt        time.Time               `json:"createdAt"`
+	ExpiresAt        *time.Time              `json:"expiresAt,omitempty"`
+	LastUsedAt       *time.Time              `json:"lastUsedAt,omitempty"`
+	LastUsedIP       *string                 `json:"lastUsedIp,omitempty"`
+	Label            *string                 `json:"label,omitempty"`
+	UserRole         *types.UserRole         `json:"userRole,omitempty"`
+	CustomRoleID     *uuid.UUID              `json:"customRoleId,omitempty"`
+	Scopes           types.AccessTokenScopes `json:"scopes,omitempty"`
+	UnusedExpiryDays *int                    `json:"unusedExpiryDays,omitempty"`
 }
 
 func (obj AccessToken) WithKey(key authkey.Key) AccessTokenWithKey {
@@ -30,4 +34,12 @@ type CreateAccessTokenRequest struct {
 	ExpiresAt *time.Time      `json:"expiresAt"`
 	Label     *string         `json:"label"`
 	UserRole  *types.UserRole `json:"userRole"`
+	// CustomRoleID restricts the token to the permissions of a custom role instead. It cannot be combined
+	// with UserRole.
+	CustomRoleID *uuid.UUID `json:"customRoleId,omitempty"`
+	// Scopes restrict the token to actions on resources, like "deployments:read", or on single artifacts, like
+	// "artifacts:write:myapp". They cannot be combined with UserRole or CustomRoleID.
+	Scopes types.AccessTokenScopes `json:"scopes,omitempty"`
+	// UnusedExpiryDays lets the token expire once it was not used for this many days.
+	UnusedExpiryDays *int `json:"unusedExpiryDays,omitempty"`
 }
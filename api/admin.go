@@ -0,0 +1,59 @@
+package api
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+type AdminOrganization struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"createdAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	Name      string     `json:"name"`
+	Slug      *string    `json:"slug,omitempty"`
+	// Features holds the features of the organization, whether granted by its subscription or by a super admin.
+	Features           []types.Feature          `json:"features"`
+	SubscriptionType   types.SubscriptionType   `json:"subscriptionType"`
+	SubscriptionPeriod types.SubscriptionPeriod `json:"subscriptionPeriod"`
+	SubscriptionEndsAt time.Time                `json:"subscriptionEndsAt"`
+	// The subscription quantities are -1 for unlimited.
+	SubscriptionCustomerOrganizationQuantity int64 `json:"subscriptionCustomerOrganizationQuantity"`
+	SubscriptionUserAccountQuantity          int64 `json:"subscriptionUserAccountQuantity"`
+	UserAccountCount                         int64 `json:"userAccountCount"`
+}
+
+type UpdateAdminOrganizationSubscriptionRequest struct {
+	SubscriptionType   types.SubscriptionType   `json:"subscriptionType"`
+	SubscriptionPeriod types.SubscriptionPeriod `json:"subscriptionPeriod"`
+	SubscriptionEndsAt time.Time                `json:"subscriptionEndsAt"`
+	// The subscription quantities are -1 for unlimited.
+	SubscriptionCustomerOrganizationQuantity int64 `json:"subscriptionCustomerOrganizationQuantity"`
+	SubscriptionUserAccountQuantity          int64 `json:"subscriptionUserAccountQuantity"`
+}
+
+type AdminUserAccount struct {
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"createdAt"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name,omitempty"`
+	EmailVerified bool      `json:"emailVerified"`
+	MFAEnabled    bool      `json:"mfaEnabled"`
+	IsSuperAdmin  bool      `json:"isSuperAdmin"`
+	// ServiceAccountOrganizationID is set for service accounts, which belong to this organization only.
+	ServiceAccountOrganizationID *uuid.UUID `json:"serviceAccountOrganizationId,omitempty"`
+	LastLoggedInAt               *time.Time `json:"lastLoggedInAt,omitempty"`
+	OrganizationCount            int64      `json:"organizationCount"`
+}
+
+type ImpersonateUserAccountRequest struct {
+	OrganizationID uuid.UUID `json:"organizationId"`
+}
+
+type ImpersonateUserAccountResponse struct {
+	Token string `json:"token"`
+	// LoginURL signs in with the token when opened in a browser, preferably in a private window, so that the session
+	// of the super admin is kept.
+	LoginURL string `json:"loginUrl"`
+}
@@ -14,6 +14,20 @@ type AgentResource struct {
 	DeploymentLogsEnabled bool               `json:"deploymentLogsEnabled"`
 	DeploymentLogsAfter   *time.Time         `json:"deploymentLogsAfter,omitempty"`
 	Deployments           []AgentDeployment  `json:"deployments,omitempty"`
+	// VolumeBackupStorage is where the agent stores volume snapshots. It is always set for Docker agents.
+	VolumeBackupStorage *AgentVolumeBackupStorage `json:"volumeBackupStorage,omitempty"`
+}
+
+type AgentVolumeBackupStorage struct {
+	Type              types.VolumeBackupStorageType `json:"type"`
+	Retention         int                           `json:"retention"`
+	S3Endpoint        string                        `json:"s3Endpoint,omitempty"`
+	S3Region          string                        `json:"s3Region,omitempty"`
+	S3Bucket          string                        `json:"s3Bucket,omitempty"`
+	S3Prefix          string                        `json:"s3Prefix,omitempty"`
+	S3UsePathStyle    bool                          `json:"s3UsePathStyle,omitempty"`
+	S3AccessKeyID     string                        `json:"s3AccessKeyId,omitempty"`
+	S3SecretAccessKey string                        `json:"s3SecretAccessKey,omitempty"`
 }
 
 type AgentRegistryAuth struct {
@@ -37,22 +51,80 @@ type AgentDeployment struct {
 	EnvFile             []byte            `json:"envFile"`
 	DockerType          *types.DockerType `json:"dockerType"`
 	ImageCleanupEnabled bool              `json:"imageCleanupEnabled"`
+	// ApplicationVersionID is needed for [types.VolumeBackupPolicyBeforeVersionUpdate].
+	ApplicationVersionID    uuid.UUID                `json:"applicationVersionId"`
+	VolumeBackupPolicy      types.VolumeBackupPolicy `json:"volumeBackupPolicy,omitempty"`
+	RestoreVolumeSnapshotID *uuid.UUID               `json:"restoreVolumeSnapshotId,omitempty"`
 
 	// Kubernetes specific data
 
-	ReleaseName        string         `json:"releaseName"`
-	ChartUrl           string         `json:"chartUrl"`
-	ChartName          string         `json:"chartName"`
-	ChartVersion       string         `json:"chartVersion"`
-	Values             map[string]any `json:"values"`
-	IgnoreRevisionSkew bool           `json:"ignoreRevisionSkew"`
-	HelmOptions        *HelmOptions   `json:"helmOptions,omitempty"`
+	ReleaseName        string                     `json:"releaseName"`
+	SourceType         types.KubernetesSourceType `json:"sourceType,omitempty"`
+	ChartUrl           string                     `json:"chartUrl"`
+	ChartName          string                     `json:"chartName"`
+	ChartVersion       string                     `json:"chartVersion"`
+	Values             map[string]any             `json:"values"`
+	IgnoreRevisionSkew bool                       `json:"ignoreRevisionSkew"`
+	HelmOptions        *HelmOptions               `json:"helmOptions,omitempty"`
+	HealthRules        []AgentHealthRule          `json:"healthRules,omitempty"`
+
+	// ManifestBundle is the bundle of a kustomize application version. Values are then not Helm values but the
+	// overlay with the patches of the customer, see manifestbundle.Build.
+	ManifestBundle []byte `json:"manifestBundle,omitempty"`
+
+	// Systemd specific data, in addition to ReleaseName and Values
+
+	// PackageRef is also the module of an OpenTofu deployment.
+	PackageRef string `json:"packageRef,omitempty"`
+	UnitFile   []byte `json:"unitFile,omitempty"`
+
+	// OpenTofu specific data, in addition to ReleaseName, Values and PackageRef
+
+	// ApprovedPlanDigest is the digest of the plan of this revision that was approved by a user.
+	ApprovedPlanDigest string `json:"approvedPlanDigest,omitempty"`
+}
+
+// AgentHealthRule is a health rule of the application version, see [types.ApplicationVersionHealthRule].
+type AgentHealthRule struct {
+	Kind     string `json:"kind"`
+	JSONPath string `json:"jsonPath"`
+	Value    string `json:"value,omitempty"`
 }
 
 type AgentDeploymentStatus struct {
 	RevisionID uuid.UUID                  `json:"revisionId"`
 	Type       types.DeploymentStatusType `json:"type"`
 	Message    string                     `json:"message"`
+	// CreatedAt is only set if the agent queued the status while the hub could not be reached.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+}
+
+// AgentDeploymentPlan is the plan of an OpenTofu deployment revision, which has to be approved before the agent
+// applies it.
+type AgentDeploymentPlan struct {
+	RevisionID uuid.UUID `json:"revisionId"`
+	Digest     string    `json:"digest"`
+	HasChanges bool      `json:"hasChanges"`
+	Output     string    `json:"output"`
+}
+
+type AgentDeploymentMetadata struct {
+	RevisionID uuid.UUID                         `json:"revisionId"`
+	Outputs    map[string]types.DeploymentOutput `json:"outputs"`
+}
+
+// AgentVolumeSnapshots are all snapshots of a deployment that the agent keeps, after retention was applied.
+type AgentVolumeSnapshots struct {
+	DeploymentID uuid.UUID             `json:"deploymentId"`
+	Snapshots    []AgentVolumeSnapshot `json:"snapshots"`
+}
+
+type AgentVolumeSnapshot struct {
+	ID         uuid.UUID `json:"id"`
+	RevisionID uuid.UUID `json:"revisionId"`
+	CreatedAt  time.Time `json:"createdAt"`
+	SizeBytes  int64     `json:"sizeBytes"`
+	Volumes    []string  `json:"volumes"`
 }
 
 type AgentDeploymentTargetMetricsRequest struct {
@@ -61,4 +133,8 @@ type AgentDeploymentTargetMetricsRequest struct {
 	MemoryBytes    int64                        `json:"memoryBytes"`
 	MemoryUsage    float64                      `json:"memoryUsage"`
 	DiskMetrics    []DeploymentTargetDiskMetric `json:"diskMetrics,omitempty"`
+	// ContainerMetrics only contains the containers or pods of deployments managed by the agent.
+	ContainerMetrics []DeploymentContainerMetric `json:"containerMetrics,omitempty"`
+	// CreatedAt is only set if the agent queued the metrics while the hub could not be reached.
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
 }
@@ -0,0 +1,26 @@
+package api
+
+import "time"
+
+type DeploymentTargetAgentNetwork struct {
+	UpdatedAt         *time.Time `json:"updatedAt,omitempty"`
+	ProxyURL          *string    `json:"proxyUrl,omitempty"`
+	ProxyUsername     *string    `json:"proxyUsername,omitempty"`
+	ProxyPasswordSet  bool       `json:"proxyPasswordSet"`
+	NoProxy           *string    `json:"noProxy,omitempty"`
+	CACertificates    *string    `json:"caCertificates,omitempty"`
+	ClientCertificate *string    `json:"clientCertificate,omitempty"`
+	ClientKeySet      bool       `json:"clientKeySet"`
+}
+
+type DeploymentTargetAgentNetworkRequest struct {
+	ProxyURL      *string `json:"proxyUrl"`
+	ProxyUsername *string `json:"proxyUsername"`
+	// ProxyPassword can be omitted to keep the password of the existing settings if the username is unchanged.
+	ProxyPassword     *string `json:"proxyPassword"`
+	NoProxy           *string `json:"noProxy"`
+	CACertificates    *string `json:"caCertificates"`
+	ClientCertificate *string `json:"clientCertificate"`
+	// ClientKey can be omitted to keep the key of the existing settings if the client certificate is unchanged.
+	ClientKey *string `json:"clientKey"`
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+type AuditLogEntry struct {
+	ID                        uuid.UUID                       `json:"id"`
+	CreatedAt                 time.Time                       `json:"createdAt"`
+	ActorUserAccountID        *uuid.UUID                      `json:"actorUserAccountId,omitempty"`
+	ActorEmail                *string                         `json:"actorEmail,omitempty"`
+	AuthMethod                types.AuditLogAuthMethod        `json:"authMethod"`
+	CustomerOrganizationID    *uuid.UUID                      `json:"customerOrganizationId,omitempty"`
+	PartnerOrganizationID     *uuid.UUID                      `json:"partnerOrganizationId,omitempty"`
+	Resource                  string                          `json:"resource"`
+	ResourceID                *string                         `json:"resourceId,omitempty"`
+	Action                    types.AuditLogAction            `json:"action"`
+	HTTPMethod                *string                         `json:"httpMethod,omitempty"`
+	Path                      *string                         `json:"path,omitempty"`
+	StatusCode                *int                            `json:"statusCode,omitempty"`
+	Changes                   map[string]types.AuditLogChange `json:"changes,omitempty"`
+	RemoteAddress             *string                         `json:"remoteAddress,omitempty"`
+	RequestID                 *string                         `json:"requestId,omitempty"`
+	ImpersonatorUserAccountID *uuid.UUID                      `json:"impersonatorUserAccountId,omitempty"`
+	ImpersonatorEmail         *string                         `json:"impersonatorEmail,omitempty"`
+}
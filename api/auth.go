@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+
 	"github.com/distr-sh/distr/internal/validation"
 	"github.com/google/uuid"
 )
@@ -9,12 +11,18 @@ type AuthLoginRequest struct {
 	Email    string  `json:"email"`
 	Password string  `json:"password"`
 	MFACode  *string `json:"mfaCode"`
+	// WebAuthnSessionID and WebAuthnCredential answer the passkey challenge of a previous response instead of MFACode.
+	WebAuthnSessionID *uuid.UUID `json:"webAuthnSessionId,omitempty"`
+	// WebAuthnCredential is the PublicKeyCredential returned by navigator.credentials.get(), serialized with toJSON().
+	WebAuthnCredential json.RawMessage `json:"webAuthnCredential,omitempty"`
 }
 
 type AuthLoginResponse struct {
 	Token       string  `json:"token,omitempty"`
 	RequiresMFA bool    `json:"requiresMfa"`
 	RedirectURL *string `json:"redirectUrl,omitempty"`
+	// WebAuthn is set with RequiresMFA if the user has registered passkeys, which can be used as the second factor.
+	WebAuthn *BeginWebAuthnLoginResponse `json:"webAuthn,omitempty"`
 }
 
 type AuthRegistrationRequest struct {
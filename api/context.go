@@ -13,4 +13,12 @@ type ContextResponse struct {
 	// domains and legacy branding domains, falling back to the instance default.
 	RegistryHost          string `json:"registryHost,omitzero"`
 	CanCreateOrganization bool   `json:"canCreateOrganization"`
+	// Permissions are the effective permissions of the current credential in the organization.
+	Permissions types.PermissionSet `json:"permissions"`
+	// WebAuthnRequired is set when the organization requires the user to sign in with a passkey, which they did
+	// not. Permissions is empty until they do.
+	WebAuthnRequired bool `json:"webAuthnRequired,omitempty"`
+	// MFARequired is set when the organization requires the user to sign in with a second factor, which they did
+	// not. Permissions is empty until they do.
+	MFARequired bool `json:"mfaRequired,omitempty"`
 }
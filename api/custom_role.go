@@ -0,0 +1,79 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+const (
+	customRoleNameMaxLength        = 100
+	customRoleDescriptionMaxLength = 1000
+)
+
+type CustomRole struct {
+	ID          uuid.UUID           `json:"id"`
+	CreatedAt   time.Time           `json:"createdAt"`
+	UpdatedAt   time.Time           `json:"updatedAt"`
+	Name        string              `json:"name"`
+	Description *string             `json:"description,omitempty"`
+	Permissions types.PermissionSet `json:"permissions"`
+}
+
+type CustomRoleRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description,omitempty"`
+	// Permissions are "resource:action" pairs. A higher action implies the lower ones on the same resource,
+	// so "license_keys:write" also grants "license_keys:read".
+	Permissions []types.Permission `json:"permissions"`
+}
+
+func (r *CustomRoleRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Description != nil {
+		if description := strings.TrimSpace(*r.Description); description != "" {
+			r.Description = &description
+		} else {
+			r.Description = nil
+		}
+	}
+	r.Permissions = types.NewPermissionSet(r.Permissions...)
+}
+
+func (r *CustomRoleRequest) Validate() error {
+	if r.Name == "" {
+		return validation.NewValidationFailedError("name is required")
+	}
+	if len(r.Name) > customRoleNameMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("name must be at most %v characters", customRoleNameMaxLength))
+	}
+	if _, err := types.ParseUserRole(r.Name); err == nil {
+		return validation.NewValidationFailedError("name must not be the name of a built-in role")
+	}
+	if r.Description != nil && len(*r.Description) > customRoleDescriptionMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("description must be at most %v characters", customRoleDescriptionMaxLength))
+	}
+	if len(r.Permissions) == 0 {
+		return validation.NewValidationFailedError("permissions must not be empty")
+	}
+	return nil
+}
+
+type BuiltInRole struct {
+	UserRole    types.UserRole      `json:"userRole"`
+	Permissions types.PermissionSet `json:"permissions"`
+}
+
+// PermissionsResponse describes the permission model: every resource and action a custom role can combine, and
+// the permissions of the built-in roles, which serve as presets.
+type PermissionsResponse struct {
+	Resources    []types.Resource `json:"resources"`
+	Actions      []types.Action   `json:"actions"`
+	BuiltInRoles []BuiltInRole    `json:"builtInRoles"`
+}
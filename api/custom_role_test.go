@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestCustomRoleRequestNormalize(t *testing.T) {
+	g := NewWithT(t)
+
+	request := api.CustomRoleRequest{
+		Name:        "  License Manager  ",
+		Description: new("   "),
+		Permissions: []types.Permission{"license_keys:read", "applications:read", "license_keys:write"},
+	}
+	request.Normalize()
+
+	g.Expect(request.Name).To(Equal("License Manager"))
+	g.Expect(request.Description).To(BeNil())
+	g.Expect(request.Permissions).To(Equal([]types.Permission{"applications:read", "license_keys:write"}))
+
+	request.Description = new(" Manages license keys ")
+	request.Normalize()
+	g.Expect(request.Description).To(HaveValue(Equal("Manages license keys")))
+}
+
+func TestCustomRoleRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	valid := func() api.CustomRoleRequest {
+		return api.CustomRoleRequest{
+			Name:        "License Manager",
+			Permissions: []types.Permission{"license_keys:write"},
+		}
+	}
+	request := valid()
+	g.Expect(request.Validate()).To(Succeed())
+
+	request.Name = ""
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("name is required")))
+
+	request = valid()
+	request.Name = strings.Repeat("a", 101)
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("name must be at most")))
+
+	request = valid()
+	request.Name = string(types.UserRoleAdmin)
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("built-in role")))
+
+	request = valid()
+	request.Description = new(strings.Repeat("a", 1001))
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("description must be at most")))
+
+	request = valid()
+	request.Permissions = nil
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("permissions must not be empty")))
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/saml"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+type CustomSAMLConfiguration struct {
+	ID                  uuid.UUID                 `json:"id"`
+	CreatedAt           time.Time                 `json:"createdAt"`
+	UpdatedAt           time.Time                 `json:"updatedAt"`
+	CustomDomainID      uuid.UUID                 `json:"customDomainId"`
+	Name                string                    `json:"name"`
+	Slug                string                    `json:"slug"`
+	Enabled             bool                      `json:"enabled"`
+	IDPMetadata         string                    `json:"idpMetadata"`
+	IDPEntityID         string                    `json:"idpEntityId"`
+	AllowIDPInitiated   bool                      `json:"allowIdpInitiated"`
+	EmailAttribute      *string                   `json:"emailAttribute,omitempty"`
+	NameAttribute       *string                   `json:"nameAttribute,omitempty"`
+	RoleAttribute       *string                   `json:"roleAttribute,omitempty"`
+	RoleMapping         map[string]types.UserRole `json:"roleMapping"`
+	CreateUnknownUsers  bool                      `json:"createUnknownUsers"`
+	DefaultUserRole     types.UserRole            `json:"defaultUserRole"`
+	AllowedEmailDomains []string                  `json:"allowedEmailDomains"`
+	// The service provider details are entered at the identity provider, either one by one or by uploading
+	// the metadata document.
+	SPEntityID    string `json:"spEntityId"`
+	SPMetadataURL string `json:"spMetadataUrl"`
+	SPACSURL      string `json:"spAcsUrl"`
+	SPCertificate string `json:"spCertificate"`
+}
+
+type CustomSAMLConfigurationRequest struct {
+	CustomDomainID uuid.UUID `json:"customDomainId"`
+	// CustomerOrganizationID targets a customer's own provider instead of the caller's organization.
+	// Only a vendor or partner admin may set it; a customer caller may only ever target itself.
+	CustomerOrganizationID *uuid.UUID `json:"customerOrganizationId,omitempty"`
+	Name                   string     `json:"name"`
+	Slug                   string     `json:"slug"`
+	Enabled                bool       `json:"enabled"`
+	// IDPMetadata is the metadata XML document exported by the identity provider.
+	IDPMetadata         string                    `json:"idpMetadata"`
+	AllowIDPInitiated   bool                      `json:"allowIdpInitiated"`
+	EmailAttribute      *string                   `json:"emailAttribute,omitempty"`
+	NameAttribute       *string                   `json:"nameAttribute,omitempty"`
+	RoleAttribute       *string                   `json:"roleAttribute,omitempty"`
+	RoleMapping         map[string]types.UserRole `json:"roleMapping"`
+	CreateUnknownUsers  bool                      `json:"createUnknownUsers"`
+	DefaultUserRole     types.UserRole            `json:"defaultUserRole"`
+	AllowedEmailDomains []string                  `json:"allowedEmailDomains"`
+
+	// IDPEntityID is read from IDPMetadata by Validate.
+	IDPEntityID string `json:"-"`
+}
+
+func (r *CustomSAMLConfigurationRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Slug = validation.NormalizeSlug(r.Slug)
+	r.IDPMetadata = strings.TrimSpace(r.IDPMetadata)
+	r.EmailAttribute = normalizeSAMLAttribute(r.EmailAttribute)
+	r.NameAttribute = normalizeSAMLAttribute(r.NameAttribute)
+	r.RoleAttribute = normalizeSAMLAttribute(r.RoleAttribute)
+
+	roleMapping := make(map[string]types.UserRole, len(r.RoleMapping))
+	for value, role := range r.RoleMapping {
+		if value = strings.TrimSpace(value); value != "" {
+			roleMapping[value] = role
+		}
+	}
+	r.RoleMapping = roleMapping
+
+	domains := make([]string, 0, len(r.AllowedEmailDomains))
+	for _, domain := range r.AllowedEmailDomains {
+		domain = validation.NormalizeHostname(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain != "" && !slices.Contains(domains, domain) {
+			domains = append(domains, domain)
+		}
+	}
+	r.AllowedEmailDomains = domains
+}
+
+func normalizeSAMLAttribute(attribute *string) *string {
+	if attribute == nil {
+		return nil
+	} else if trimmed := strings.TrimSpace(*attribute); trimmed != "" {
+		return &trimmed
+	}
+	return nil
+}
+
+func (r *CustomSAMLConfigurationRequest) Validate() error {
+	if r.CustomDomainID == uuid.Nil {
+		return validation.NewValidationFailedError("customDomainId is required")
+	}
+	if r.Name == "" {
+		return validation.NewValidationFailedError("name is required")
+	}
+	if len(r.Name) > customOIDCConfigurationNameMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("name must be at most %v characters", customOIDCConfigurationNameMaxLength))
+	}
+	if r.Slug == "" {
+		return validation.NewValidationFailedError("slug is required")
+	}
+	if err := validation.ValidateSlug(r.Slug); err != nil {
+		return err
+	}
+	if metadata, err := saml.ParseMetadata([]byte(r.IDPMetadata)); err != nil {
+		return validation.NewValidationFailedError(fmt.Sprintf("idpMetadata is invalid: %v", err))
+	} else {
+		r.IDPEntityID = metadata.EntityID
+	}
+	if _, err := types.ParseUserRole(string(r.DefaultUserRole)); err != nil {
+		return validation.NewValidationFailedError("defaultUserRole is invalid")
+	}
+	if len(r.RoleMapping) > 0 && r.RoleAttribute == nil {
+		return validation.NewValidationFailedError("roleAttribute is required when roleMapping is set")
+	}
+	for value, role := range r.RoleMapping {
+		if _, err := types.ParseUserRole(string(role)); err != nil {
+			return validation.NewValidationFailedError(
+				fmt.Sprintf("roleMapping maps %q to an invalid role", value))
+		}
+	}
+	for _, domain := range r.AllowedEmailDomains {
+		if err := validation.ValidateHostname(domain); err != nil {
+			return validation.NewValidationFailedError(
+				fmt.Sprintf("allowedEmailDomains contains the invalid domain %q", domain))
+		}
+	}
+	if r.CreateUnknownUsers && len(r.AllowedEmailDomains) == 0 {
+		return validation.NewValidationFailedError(
+			"allowedEmailDomains is required when accounts are created on first sign-in")
+	}
+	return nil
+}
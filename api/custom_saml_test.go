@@ -0,0 +1,95 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+// testSAMLMetadata is the minimal metadata document of an identity provider that ParseMetadata accepts.
+const testSAMLMetadata = `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" ` +
+	`entityID="https://idp.acme.com/saml">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data><ds:X509Certificate>MIIB</ds:X509Certificate></ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+      Location="https://idp.acme.com/saml/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`
+
+func validCustomSAMLConfigurationRequest() api.CustomSAMLConfigurationRequest {
+	return api.CustomSAMLConfigurationRequest{
+		CustomDomainID:  uuid.New(),
+		Name:            "Acme ADFS",
+		Slug:            "acme-adfs",
+		IDPMetadata:     testSAMLMetadata,
+		DefaultUserRole: types.UserRoleReadWrite,
+	}
+}
+
+func TestCustomSAMLConfigurationRequestNormalize(t *testing.T) {
+	g := NewWithT(t)
+
+	request := api.CustomSAMLConfigurationRequest{
+		Name:                "  Acme ADFS  ",
+		Slug:                "  Acme-ADFS  ",
+		IDPMetadata:         "\n" + testSAMLMetadata + "\n",
+		EmailAttribute:      new("  "),
+		RoleAttribute:       new(" groups "),
+		RoleMapping:         map[string]types.UserRole{" admins ": types.UserRoleAdmin, " ": types.UserRoleReadOnly},
+		AllowedEmailDomains: []string{" @Acme.com ", "acme.com", ""},
+	}
+	request.Normalize()
+
+	g.Expect(request.Name).To(Equal("Acme ADFS"))
+	g.Expect(request.Slug).To(Equal("acme-adfs"))
+	g.Expect(request.IDPMetadata).To(Equal(testSAMLMetadata))
+	g.Expect(request.EmailAttribute).To(BeNil())
+	g.Expect(request.RoleAttribute).To(Equal(new("groups")))
+	g.Expect(request.RoleMapping).To(Equal(map[string]types.UserRole{"admins": types.UserRoleAdmin}))
+	g.Expect(request.AllowedEmailDomains).To(Equal([]string{"acme.com"}))
+}
+
+func TestCustomSAMLConfigurationRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+	valid := validCustomSAMLConfigurationRequest()
+	g.Expect(valid.Validate()).To(Succeed())
+	g.Expect(valid.IDPEntityID).To(Equal("https://idp.acme.com/saml"))
+
+	invalid := map[string]func(r *api.CustomSAMLConfigurationRequest){
+		"missing custom domain": func(r *api.CustomSAMLConfigurationRequest) { r.CustomDomainID = uuid.Nil },
+		"missing name":          func(r *api.CustomSAMLConfigurationRequest) { r.Name = "" },
+		"missing slug":          func(r *api.CustomSAMLConfigurationRequest) { r.Slug = "" },
+		"invalid slug":          func(r *api.CustomSAMLConfigurationRequest) { r.Slug = "Acme ADFS" },
+		"missing metadata":      func(r *api.CustomSAMLConfigurationRequest) { r.IDPMetadata = "" },
+		"invalid metadata":      func(r *api.CustomSAMLConfigurationRequest) { r.IDPMetadata = "<md:EntityDescriptor" },
+		"unknown role":          func(r *api.CustomSAMLConfigurationRequest) { r.DefaultUserRole = "root" },
+		"role mapping without attribute": func(r *api.CustomSAMLConfigurationRequest) {
+			r.RoleMapping = map[string]types.UserRole{"admins": types.UserRoleAdmin}
+		},
+		"unknown mapped role": func(r *api.CustomSAMLConfigurationRequest) {
+			r.RoleAttribute = new("groups")
+			r.RoleMapping = map[string]types.UserRole{"admins": "root"}
+		},
+		"provisioning without email domains": func(r *api.CustomSAMLConfigurationRequest) {
+			r.CreateUnknownUsers = true
+			r.AllowedEmailDomains = nil
+		},
+	}
+	for name, modify := range invalid {
+		request := validCustomSAMLConfigurationRequest()
+		modify(&request)
+		g.Expect(request.Validate()).To(HaveOccurred(), name)
+	}
+
+	withRoles := validCustomSAMLConfigurationRequest()
+	withRoles.RoleAttribute = new("groups")
+	withRoles.RoleMapping = map[string]types.UserRole{"admins": types.UserRoleAdmin}
+	g.Expect(withRoles.Validate()).To(Succeed())
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+type DeploymentRevisionPlan struct {
+	DeploymentRevisionID uuid.UUID  `json:"deploymentRevisionId"`
+	CreatedAt            time.Time  `json:"createdAt"`
+	Digest               string     `json:"digest"`
+	HasChanges           bool       `json:"hasChanges"`
+	Output               string     `json:"output"`
+	ApprovedAt           *time.Time `json:"approvedAt,omitempty"`
+}
+
+// ApproveDeploymentPlanRequest contains the digest of the plan that was reviewed, so that a plan that changed in
+// the meantime is not approved.
+type ApproveDeploymentPlanRequest struct {
+	Digest string `json:"digest"`
+}
+
+type DeploymentMetadata struct {
+	DeploymentRevisionID uuid.UUID                         `json:"deploymentRevisionId"`
+	UpdatedAt            time.Time                         `json:"updatedAt"`
+	Outputs              map[string]types.DeploymentOutput `json:"outputs"`
+}
@@ -24,8 +24,11 @@ type DeploymentRequest struct {
 	ForceRestart             bool              `json:"forceRestart"`
 	IgnoreRevisionSkew       bool              `json:"ignoreRevisionSkew"`
 	HelmOptions              *HelmOptions      `json:"helmOptions,omitempty"`
-	ValuesHash               []byte            `json:"-"`
-	CreatedByUserAccountID   *uuid.UUID        `json:"-"`
+	// VolumeBackupPolicy defaults to the policy of the previous revision of the deployment.
+	VolumeBackupPolicy      *types.VolumeBackupPolicy `json:"volumeBackupPolicy,omitempty"`
+	RestoreVolumeSnapshotID *uuid.UUID                `json:"-"`
+	ValuesHash              []byte                    `json:"-"`
+	CreatedByUserAccountID  *uuid.UUID                `json:"-"`
 }
 
 func (d *DeploymentRequest) GetValuesYAML() []byte {
@@ -9,6 +9,7 @@ type DeploymentTargetMetrics struct {
 	MemoryBytes        int64                        `json:"memoryBytes"`
 	MemoryUsage        float64                      `json:"memoryUsage"`
 	DiskMetrics        []DeploymentTargetDiskMetric `json:"diskMetrics,omitempty"`
+	ContainerMetrics   []DeploymentContainerMetric  `json:"containerMetrics,omitempty"`
 }
 
 type DeploymentTargetDiskMetric struct {
@@ -18,3 +19,24 @@ type DeploymentTargetDiskMetric struct {
 	BytesTotal int64  `json:"bytesTotal"`
 	BytesUsed  int64  `json:"bytesUsed"`
 }
+
+// DeploymentContainerMetric is the resource usage of a container of a Docker deployment or a pod of a Kubernetes
+// deployment.
+type DeploymentContainerMetric struct {
+	DeploymentID uuid.UUID `json:"deploymentId"`
+	// Service is the Compose or Swarm service of a container or the workload that owns a pod.
+	Service string `json:"service"`
+	// Name is the name of the container or pod.
+	Name          string `json:"name"`
+	CPUUsedMillis int64  `json:"cpuUsedMillis"`
+	// CPUUsage is relative to the CPU limit of the container or pod, or to the CPUs of the host if there is none.
+	CPUUsage        float64 `json:"cpuUsage"`
+	MemoryUsedBytes int64   `json:"memoryUsedBytes"`
+	// MemoryUsage is relative to the memory limit of the container or pod, or to the memory of the host if there is
+	// none.
+	MemoryUsage float64 `json:"memoryUsage"`
+	Restarts    int64   `json:"restarts"`
+	// NetworkRxBytes and NetworkTxBytes are counted since the container was started. They are always 0 for pods.
+	NetworkRxBytes int64 `json:"networkRxBytes"`
+	NetworkTxBytes int64 `json:"networkTxBytes"`
+}
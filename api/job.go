@@ -0,0 +1,25 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobRun struct {
+	ID      uuid.UUID `json:"id"`
+	JobName string    `json:"jobName"`
+	// Holder identifies the hub replica that ran the job.
+	Holder     string     `json:"holder"`
+	StartedAt  time.Time  `json:"startedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Status     string     `json:"status" enum:"running,succeeded,failed"`
+	Error      *string    `json:"error,omitempty"`
+}
+
+type JobLease struct {
+	JobName    string    `json:"jobName"`
+	Holder     string    `json:"holder"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/validation"
 	"github.com/google/uuid"
 )
 
@@ -37,3 +38,72 @@ type UpdateLicenseKeyResponse struct {
 	types.LicenseKey
 	AffectedDeployments []AffectedDeployment `json:"affectedDeployments"`
 }
+
+type LicenseKeyFilter struct {
+	LicenseTemplateID      *uuid.UUID `json:"licenseTemplateId,omitempty"`
+	CustomerOrganizationID *uuid.UUID `json:"customerOrganizationId,omitempty"`
+	ExpiresAfter           *time.Time `json:"expiresAfter,omitempty"`
+	ExpiresBefore          *time.Time `json:"expiresBefore,omitempty"`
+}
+
+// BulkLicenseKeyRevisionRequest creates a new revision for every license key matching Filter.
+// ExtendByDays and ExpiresAt are mutually exclusive. Fields that are not set are taken over from the latest
+// revision of each license key.
+type BulkLicenseKeyRevisionRequest struct {
+	Filter       LicenseKeyFilter `json:"filter"`
+	ExtendByDays *int             `json:"extendByDays,omitempty"`
+	NotBefore    *time.Time       `json:"notBefore,omitempty"`
+	ExpiresAt    *time.Time       `json:"expiresAt,omitempty"`
+	Payload      *json.RawMessage `json:"payload,omitempty"`
+}
+
+func (r BulkLicenseKeyRevisionRequest) Validate() error {
+	if r.ExtendByDays != nil && r.ExpiresAt != nil {
+		return validation.NewValidationFailedError("extendByDays and expiresAt must not be set at the same time")
+	}
+	if r.ExtendByDays != nil && *r.ExtendByDays <= 0 {
+		return validation.NewValidationFailedError("extendByDays must be positive")
+	}
+	if r.ExtendByDays == nil && r.ExpiresAt == nil && r.NotBefore == nil && r.Payload == nil {
+		return validation.NewValidationFailedError(
+			"at least one of extendByDays, expiresAt, notBefore or payload is required")
+	}
+	if r.Filter.ExpiresAfter != nil && r.Filter.ExpiresBefore != nil &&
+		!r.Filter.ExpiresBefore.After(*r.Filter.ExpiresAfter) {
+		return validation.NewValidationFailedError("filter.expiresBefore must be after filter.expiresAfter")
+	}
+	return nil
+}
+
+type BulkLicenseKeyRevision struct {
+	LicenseKeyID           uuid.UUID  `json:"licenseKeyId"`
+	Name                   string     `json:"name"`
+	CustomerOrganizationID *uuid.UUID `json:"customerOrganizationId,omitempty"`
+	PreviousNotBefore      time.Time  `json:"previousNotBefore"`
+	PreviousExpiresAt      time.Time  `json:"previousExpiresAt"`
+	NotBefore              time.Time  `json:"notBefore"`
+	ExpiresAt              time.Time  `json:"expiresAt"`
+	PayloadChanged         bool       `json:"payloadChanged"`
+}
+
+type BulkLicenseKeyRevisionResponse struct {
+	Revisions           []BulkLicenseKeyRevision `json:"revisions"`
+	AffectedDeployments []AffectedDeployment     `json:"affectedDeployments"`
+	Applied             bool                     `json:"applied"`
+}
+
+type LicenseKeyExpirationReminders struct {
+	ReminderDays []int `json:"reminderDays"`
+}
+
+func (r LicenseKeyExpirationReminders) Validate() error {
+	if len(r.ReminderDays) > 10 {
+		return validation.NewValidationFailedError("at most 10 reminders are allowed")
+	}
+	for _, days := range r.ReminderDays {
+		if days < 1 || days > 365 {
+			return validation.NewValidationFailedError("reminder days must be between 1 and 365")
+		}
+	}
+	return nil
+}
@@ -17,6 +17,8 @@ type NotificationRecord struct {
 	MetricType                         *string    `json:"metricType,omitempty"`
 	DiskDevice                         *string    `json:"diskDevice,omitempty"`
 	DiskPath                           *string    `json:"diskPath,omitempty"`
+	DeploymentID                       *uuid.UUID `json:"deploymentId,omitempty"`
+	ContainerName                      *string    `json:"containerName,omitempty"`
 	PreviousDeploymentTargetMetricsID  *uuid.UUID `json:"previousDeploymentTargetMetricsId,omitempty"`
 	CurrentDeploymentTargetMetricsID   *uuid.UUID `json:"currentDeploymentTargetMetricsId,omitempty"`
 	Message                            string     `json:"message"`
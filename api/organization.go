@@ -1,7 +1,11 @@
 package api
 
 import (
+	"fmt"
+	"net/netip"
 	"regexp"
+	"strings"
+	"time"
 
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/distr-sh/distr/internal/validation"
@@ -43,3 +47,45 @@ func (r UpdateOrganizationWebhookRequest) Validate() error {
 
 	return nil
 }
+
+type OrganizationSecurityPolicy struct {
+	// RequireWebAuthnForAdmins takes all permissions from admins who did not sign in with a passkey. Members with a
+	// custom role that grants managing any resource count as admins.
+	RequireWebAuthnForAdmins bool `json:"requireWebAuthnForAdmins"`
+	// RequireMFA takes all permissions from members who signed in with neither a second factor, a passkey nor the
+	// identity provider of the organization.
+	RequireMFA bool `json:"requireMfa"`
+	// IPAllowlist restricts logins and the usage of access tokens to these IP addresses or networks in CIDR
+	// notation. An empty list allows all addresses.
+	IPAllowlist []string `json:"ipAllowlist"`
+	// MaxAccessTokenLifetime is the longest lifetime of new access tokens. When set, access tokens must expire.
+	MaxAccessTokenLifetime *types.Duration `json:"maxAccessTokenLifetime"`
+	// DisablePasswordLogin rejects password logins while the organization has an identity provider configured.
+	DisablePasswordLogin bool `json:"disablePasswordLogin"`
+	// SessionIdleTimeout revokes sessions that were not used for this long.
+	SessionIdleTimeout *types.Duration `json:"sessionIdleTimeout"`
+}
+
+// minSessionIdleTimeout keeps the idle timeout above the interval in which the last usage of a session is recorded.
+const minSessionIdleTimeout = 5 * time.Minute
+
+func (p *OrganizationSecurityPolicy) Validate() error {
+	for i, entry := range p.IPAllowlist {
+		entry = strings.TrimSpace(entry)
+		if addr, err := netip.ParseAddr(entry); err == nil {
+			p.IPAllowlist[i] = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()).String()
+		} else if prefix, err := netip.ParsePrefix(entry); err == nil {
+			p.IPAllowlist[i] = prefix.Masked().String()
+		} else {
+			return validation.NewValidationFailedError(fmt.Sprintf("invalid IP address or network: %q", entry))
+		}
+	}
+	if p.MaxAccessTokenLifetime != nil && *p.MaxAccessTokenLifetime <= 0 {
+		return validation.NewValidationFailedError("maxAccessTokenLifetime must be positive")
+	}
+	if p.SessionIdleTimeout != nil && time.Duration(*p.SessionIdleTimeout) < minSessionIdleTimeout {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("sessionIdleTimeout must be at least %v", minSessionIdleTimeout))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestOrganizationSecurityPolicyValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := api.OrganizationSecurityPolicy{
+		IPAllowlist: []string{" 203.0.113.7 ", "2001:db8::1", "::ffff:198.51.100.1", "10.1.2.3/8"},
+	}
+	g.Expect(policy.Validate()).To(Succeed())
+	g.Expect(policy.IPAllowlist).To(Equal([]string{"203.0.113.7/32", "2001:db8::1/128", "198.51.100.1/32", "10.0.0.0/8"}))
+
+	policy.IPAllowlist = []string{"10.0.0.0/33"}
+	g.Expect(policy.Validate()).To(MatchError(ContainSubstring("invalid IP address or network")))
+
+	policy.IPAllowlist = nil
+	policy.MaxAccessTokenLifetime = new(types.Duration(0))
+	g.Expect(policy.Validate()).To(MatchError(ContainSubstring("maxAccessTokenLifetime must be positive")))
+
+	policy.MaxAccessTokenLifetime = new(types.Duration(30 * 24 * time.Hour))
+	policy.SessionIdleTimeout = new(types.Duration(time.Minute))
+	g.Expect(policy.Validate()).To(MatchError(ContainSubstring("sessionIdleTimeout must be at least")))
+
+	policy.SessionIdleTimeout = new(types.Duration(time.Hour))
+	g.Expect(policy.Validate()).To(Succeed())
+}
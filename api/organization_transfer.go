@@ -0,0 +1,27 @@
+package api
+
+import "github.com/google/uuid"
+
+// OrganizationExportKeyHeader carries the key that the secrets of an organization archive are encrypted with.
+const OrganizationExportKeyHeader = "X-Distr-Export-Key"
+
+type OrganizationTransferTable struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
+
+type OrganizationImportConflict struct {
+	Table      string `json:"table"`
+	Column     string `json:"column,omitempty"`
+	Value      string `json:"value,omitempty"`
+	Resolution string `json:"resolution"`
+}
+
+type OrganizationImportResponse struct {
+	// OrganizationID is the ID of the imported organization. For a dry run, no organization is created.
+	OrganizationID uuid.UUID                    `json:"organizationId"`
+	DryRun         bool                         `json:"dryRun"`
+	Tables         []OrganizationTransferTable  `json:"tables"`
+	Blobs          int                          `json:"blobs"`
+	Conflicts      []OrganizationImportConflict `json:"conflicts"`
+}
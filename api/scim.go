@@ -0,0 +1,48 @@
+package api
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/authkey"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+type ScimToken struct {
+	ID                     uuid.UUID  `json:"id"`
+	CreatedAt              time.Time  `json:"createdAt"`
+	LastUsedAt             *time.Time `json:"lastUsedAt,omitempty"`
+	Label                  *string    `json:"label,omitempty"`
+	CustomerOrganizationID *uuid.UUID `json:"customerOrganizationId,omitempty"`
+}
+
+func (obj ScimToken) WithKey(key authkey.Key) ScimTokenWithKey {
+	return ScimTokenWithKey{obj, key}
+}
+
+type ScimTokenWithKey struct {
+	ScimToken
+	Key authkey.Key `json:"key"`
+}
+
+type CreateScimTokenRequest struct {
+	Label                  *string    `json:"label"`
+	CustomerOrganizationID *uuid.UUID `json:"customerOrganizationId"`
+}
+
+type ScimGroup struct {
+	ID                     uuid.UUID       `json:"id"`
+	CreatedAt              time.Time       `json:"createdAt"`
+	DisplayName            string          `json:"displayName"`
+	ExternalID             *string         `json:"externalId,omitempty"`
+	UserRole               *types.UserRole `json:"userRole,omitempty"`
+	MemberCount            int             `json:"memberCount"`
+	CustomerOrganizationID *uuid.UUID      `json:"customerOrganizationId,omitempty"`
+}
+
+// UpdateScimGroupUserRoleRequest maps a provisioned group to a role. Once any group in a scope is mapped to a
+// role, the roles of all provisioned users in that scope are managed by their group memberships.
+type UpdateScimGroupUserRoleRequest struct {
+	UserRole               *types.UserRole `json:"userRole"`
+	CustomerOrganizationID *uuid.UUID      `json:"customerOrganizationId"`
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+const serviceAccountNameMaxLength = 100
+
+type ServiceAccount struct {
+	ID           uuid.UUID      `json:"id"`
+	CreatedAt    time.Time      `json:"createdAt"`
+	Name         string         `json:"name"`
+	UserRole     types.UserRole `json:"userRole"`
+	CustomRoleID *uuid.UUID     `json:"customRoleId,omitempty"`
+}
+
+type ServiceAccountRequest struct {
+	Name     string         `json:"name"`
+	UserRole types.UserRole `json:"userRole"`
+	// CustomRoleID replaces the permissions of UserRole if set.
+	CustomRoleID *uuid.UUID `json:"customRoleId,omitempty"`
+}
+
+func (r *ServiceAccountRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+}
+
+func (r *ServiceAccountRequest) Validate() error {
+	if r.Name == "" {
+		return validation.NewValidationFailedError("name is required")
+	}
+	if len(r.Name) > serviceAccountNameMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("name must be at most %v characters", serviceAccountNameMaxLength))
+	}
+	if _, err := types.ParseUserRole(string(r.UserRole)); err != nil {
+		return validation.NewValidationFailedError("userRole is invalid")
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package api_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestServiceAccountRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	valid := func() api.ServiceAccountRequest {
+		return api.ServiceAccountRequest{Name: "  CI  ", UserRole: types.UserRoleReadWrite}
+	}
+	request := valid()
+	request.Normalize()
+	g.Expect(request.Name).To(Equal("CI"))
+	g.Expect(request.Validate()).To(Succeed())
+
+	request.Name = " "
+	request.Normalize()
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("name is required")))
+
+	request = valid()
+	request.Name = strings.Repeat("a", 101)
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("name must be at most")))
+
+	request = valid()
+	request.UserRole = ""
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("userRole is invalid")))
+}
@@ -0,0 +1,26 @@
+package api
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Task struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"createdAt"`
+	Type      string    `json:"type"`
+	// Status is "dead" if the last attempt failed.
+	Status      string    `json:"status" enum:"pending,running,succeeded,dead"`
+	Attempts    int       `json:"attempts"`
+	MaxAttempts int       `json:"maxAttempts"`
+	RunAfter    time.Time `json:"runAfter"`
+	// Progress is in percent.
+	Progress        *int            `json:"progress,omitempty"`
+	ProgressMessage *string         `json:"progressMessage,omitempty"`
+	Result          json.RawMessage `json:"result,omitempty"`
+	Error           *string         `json:"error,omitempty"`
+	StartedAt       *time.Time      `json:"startedAt,omitempty"`
+	FinishedAt      *time.Time      `json:"finishedAt,omitempty"`
+}
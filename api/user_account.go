@@ -12,6 +12,9 @@ type CreateUserAccountRequest struct {
 	UserRole               types.UserRole `json:"userRole"`
 	CustomerOrganizationID *uuid.UUID     `json:"customerOrganizationId,omitempty"`
 	PartnerOrganizationID  *uuid.UUID     `json:"partnerOrganizationId,omitempty"`
+	// CustomRoleID replaces the permissions of UserRole. Only members of the vendor organization itself can
+	// be assigned a custom role.
+	CustomRoleID *uuid.UUID `json:"customRoleId,omitempty"`
 }
 
 type CreateUserAccountResponse struct {
@@ -50,7 +53,18 @@ func (r UpdateUserAccountEmailRequest) Validate() error {
 	return nil
 }
 
+// PatchUserAccountRequest only changes the fields that are set. The custom role is kept unless CustomRoleID is set or
+// RemoveCustomRole is true.
 type PatchUserAccountRequest struct {
-	Name     *string         `json:"name"`
-	UserRole *types.UserRole `json:"userRole"`
+	Name             *string         `json:"name"`
+	UserRole         *types.UserRole `json:"userRole"`
+	CustomRoleID     *uuid.UUID      `json:"customRoleId"`
+	RemoveCustomRole bool            `json:"removeCustomRole"`
+}
+
+func (r PatchUserAccountRequest) Validate() error {
+	if r.CustomRoleID != nil && r.RemoveCustomRole {
+		return validation.NewValidationFailedError("customRoleId and removeCustomRole must not be set together")
+	}
+	return nil
 }
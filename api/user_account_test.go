@@ -0,0 +1,19 @@
+package api_test
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestPatchUserAccountRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(api.PatchUserAccountRequest{}.Validate()).To(Succeed())
+	g.Expect(api.PatchUserAccountRequest{CustomRoleID: new(uuid.New())}.Validate()).To(Succeed())
+	g.Expect(api.PatchUserAccountRequest{RemoveCustomRole: true}.Validate()).To(Succeed())
+	g.Expect(api.PatchUserAccountRequest{CustomRoleID: new(uuid.New()), RemoveCustomRole: true}.Validate()).
+		To(MatchError(ContainSubstring("must not be set together")))
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserSession struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastSeenAt time.Time `json:"lastSeenAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	// Device is a short description of the browser and operating system, derived from the user agent.
+	Device        string  `json:"device,omitempty"`
+	UserAgent     *string `json:"userAgent,omitempty"`
+	RemoteAddress *string `json:"remoteAddress,omitempty"`
+	// Current is true for the session of the token that was used for the request.
+	Current bool `json:"current"`
+}
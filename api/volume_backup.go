@@ -0,0 +1,42 @@
+package api
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+type VolumeBackupStorage struct {
+	UpdatedAt            *time.Time                    `json:"updatedAt,omitempty"`
+	Type                 types.VolumeBackupStorageType `json:"type"`
+	Retention            int                           `json:"retention"`
+	S3Endpoint           *string                       `json:"s3Endpoint,omitempty"`
+	S3Region             *string                       `json:"s3Region,omitempty"`
+	S3Bucket             *string                       `json:"s3Bucket,omitempty"`
+	S3Prefix             *string                       `json:"s3Prefix,omitempty"`
+	S3UsePathStyle       bool                          `json:"s3UsePathStyle"`
+	S3AccessKeyID        *string                       `json:"s3AccessKeyId,omitempty"`
+	S3SecretAccessKeySet bool                          `json:"s3SecretAccessKeySet"`
+}
+
+type VolumeBackupStorageRequest struct {
+	Type           types.VolumeBackupStorageType `json:"type"`
+	Retention      int                           `json:"retention"`
+	S3Endpoint     *string                       `json:"s3Endpoint"`
+	S3Region       *string                       `json:"s3Region"`
+	S3Bucket       *string                       `json:"s3Bucket"`
+	S3Prefix       *string                       `json:"s3Prefix"`
+	S3UsePathStyle bool                          `json:"s3UsePathStyle"`
+	S3AccessKeyID  *string                       `json:"s3AccessKeyId"`
+	// S3SecretAccessKey can be omitted to keep the secret access key of the existing storage.
+	S3SecretAccessKey *string `json:"s3SecretAccessKey"`
+}
+
+type DeploymentVolumeSnapshot struct {
+	ID                   uuid.UUID `json:"id"`
+	CreatedAt            time.Time `json:"createdAt"`
+	DeploymentRevisionID uuid.UUID `json:"deploymentRevisionId"`
+	SizeBytes            int64     `json:"sizeBytes"`
+	Volumes              []string  `json:"volumes"`
+}
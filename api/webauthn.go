@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/google/uuid"
+)
+
+const webAuthnCredentialNameMaxLength = 100
+
+type WebAuthnCredential struct {
+	ID         uuid.UUID  `json:"id"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	Name       string     `json:"name"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+}
+
+type BeginWebAuthnRegistrationResponse struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	// Options are passed to navigator.credentials.create() as they are.
+	Options *protocol.CredentialCreation `json:"options"`
+}
+
+type FinishWebAuthnRegistrationRequest struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	Name      string    `json:"name"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.create(), serialized with toJSON().
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (r *FinishWebAuthnRegistrationRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.SessionID == uuid.Nil {
+		return validation.NewValidationFailedError("sessionId is required")
+	} else if len(r.Credential) == 0 {
+		return validation.NewValidationFailedError("credential is required")
+	}
+	return validateWebAuthnCredentialName(r.Name)
+}
+
+type UpdateWebAuthnCredentialRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *UpdateWebAuthnCredentialRequest) Validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	return validateWebAuthnCredentialName(r.Name)
+}
+
+func validateWebAuthnCredentialName(name string) error {
+	if name == "" {
+		return validation.NewValidationFailedError("name is required")
+	} else if len(name) > webAuthnCredentialNameMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("name must be at most %v characters", webAuthnCredentialNameMaxLength))
+	}
+	return nil
+}
+
+type BeginWebAuthnLoginResponse struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	// Options are passed to navigator.credentials.get() as they are.
+	Options *protocol.CredentialAssertion `json:"options"`
+}
+
+type FinishWebAuthnLoginRequest struct {
+	SessionID uuid.UUID `json:"sessionId"`
+	// Credential is the PublicKeyCredential returned by navigator.credentials.get(), serialized with toJSON().
+	Credential json.RawMessage `json:"credential"`
+}
+
+func (r *FinishWebAuthnLoginRequest) Validate() error {
+	if r.SessionID == uuid.Nil {
+		return validation.NewValidationFailedError("sessionId is required")
+	} else if len(r.Credential) == 0 {
+		return validation.NewValidationFailedError("credential is required")
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package api_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestFinishWebAuthnRegistrationRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	request := api.FinishWebAuthnRegistrationRequest{
+		SessionID:  uuid.New(),
+		Name:       "  YubiKey  ",
+		Credential: json.RawMessage(`{}`),
+	}
+	g.Expect(request.Validate()).To(Succeed())
+	g.Expect(request.Name).To(Equal("YubiKey"))
+
+	request.Name = " "
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("name is required")))
+
+	request.Name = strings.Repeat("a", 101)
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("at most 100 characters")))
+
+	request.Name = "YubiKey"
+	request.SessionID = uuid.Nil
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("sessionId is required")))
+
+	request.SessionID = uuid.New()
+	request.Credential = nil
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("credential is required")))
+}
+
+func TestFinishWebAuthnLoginRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	request := api.FinishWebAuthnLoginRequest{SessionID: uuid.New(), Credential: json.RawMessage(`{}`)}
+	g.Expect(request.Validate()).To(Succeed())
+
+	request.Credential = nil
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("credential is required")))
+}
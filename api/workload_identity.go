@@ -0,0 +1,93 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+const (
+	workloadIdentityTrustPolicyNameMaxLength = 100
+	defaultWorkloadIdentityTokenLifetime     = 15 * time.Minute
+	minWorkloadIdentityTokenLifetime         = time.Minute
+	maxWorkloadIdentityTokenLifetime         = 12 * time.Hour
+)
+
+type WorkloadIdentityTrustPolicy struct {
+	ID             uuid.UUID               `json:"id"`
+	CreatedAt      time.Time               `json:"createdAt"`
+	Name           string                  `json:"name"`
+	Issuer         string                  `json:"issuer"`
+	Audience       string                  `json:"audience"`
+	SubjectPattern string                  `json:"subjectPattern"`
+	ClaimPatterns  map[string]string       `json:"claimPatterns,omitempty"`
+	Scopes         types.AccessTokenScopes `json:"scopes,omitempty"`
+	TokenLifetime  types.Duration          `json:"tokenLifetime"`
+}
+
+type WorkloadIdentityTrustPolicyRequest struct {
+	Name string `json:"name"`
+	// Issuer is the issuer of the ID tokens, like "https://token.actions.githubusercontent.com".
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+	// SubjectPattern must match the "sub" claim of the ID token, where "*" matches any number of characters,
+	// like "repo:acme/app:ref:refs/tags/*".
+	SubjectPattern string `json:"subjectPattern"`
+	// ClaimPatterns must match further claims of the ID token, like {"ref_protected": "true"}.
+	ClaimPatterns map[string]string `json:"claimPatterns,omitempty"`
+	// Scopes restrict the access tokens issued for the policy, like the scopes of an access token.
+	Scopes types.AccessTokenScopes `json:"scopes,omitempty"`
+	// TokenLifetime is how long an access token issued for the policy is valid, 15 minutes by default.
+	TokenLifetime *types.Duration `json:"tokenLifetime,omitempty"`
+}
+
+func (r *WorkloadIdentityTrustPolicyRequest) Normalize() {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Issuer = strings.TrimSpace(r.Issuer)
+	r.Audience = strings.TrimSpace(r.Audience)
+	r.SubjectPattern = strings.TrimSpace(r.SubjectPattern)
+	if r.TokenLifetime == nil {
+		r.TokenLifetime = new(types.Duration(defaultWorkloadIdentityTokenLifetime))
+	}
+}
+
+func (r *WorkloadIdentityTrustPolicyRequest) Validate() error {
+	if r.Name == "" {
+		return validation.NewValidationFailedError("name is required")
+	}
+	if len(r.Name) > workloadIdentityTrustPolicyNameMaxLength {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("name must be at most %v characters", workloadIdentityTrustPolicyNameMaxLength))
+	}
+	if r.Issuer == "" {
+		return validation.NewValidationFailedError("issuer is required")
+	}
+	if r.Audience == "" {
+		return validation.NewValidationFailedError("audience is required")
+	}
+	// A subject that matches everything would trust every workload of the issuer, like any repository on GitHub.
+	if strings.Trim(r.SubjectPattern, "*") == "" {
+		return validation.NewValidationFailedError("subjectPattern must not match every subject")
+	}
+	for name := range r.ClaimPatterns {
+		if name == "" || name == "sub" {
+			return validation.NewValidationFailedError("claimPatterns must not contain an empty name or sub")
+		}
+	}
+	if r.TokenLifetime != nil && (time.Duration(*r.TokenLifetime) < minWorkloadIdentityTokenLifetime ||
+		time.Duration(*r.TokenLifetime) > maxWorkloadIdentityTokenLifetime) {
+		return validation.NewValidationFailedError(fmt.Sprintf("tokenLifetime must be between %v and %v",
+			minWorkloadIdentityTokenLifetime, maxWorkloadIdentityTokenLifetime))
+	}
+	return nil
+}
+
+// WorkloadIdentityTokenRequest exchanges an ID token for a short-lived access token.
+type WorkloadIdentityTokenRequest struct {
+	TrustPolicyID uuid.UUID `json:"trustPolicyId"`
+	Token         string    `json:"token"`
+}
@@ -0,0 +1,48 @@
+package api_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestWorkloadIdentityTrustPolicyRequestValidate(t *testing.T) {
+	g := NewWithT(t)
+
+	valid := func() api.WorkloadIdentityTrustPolicyRequest {
+		return api.WorkloadIdentityTrustPolicyRequest{
+			Name:           " Release ",
+			Issuer:         "https://token.actions.githubusercontent.com",
+			Audience:       "distr",
+			SubjectPattern: "repo:acme/app:ref:refs/tags/*",
+		}
+	}
+	request := valid()
+	request.Normalize()
+	g.Expect(request.Name).To(Equal("Release"))
+	g.Expect(request.TokenLifetime).To(HaveValue(Equal(types.Duration(15 * time.Minute))))
+	g.Expect(request.Validate()).To(Succeed())
+
+	request = valid()
+	request.SubjectPattern = "**"
+	request.Normalize()
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("must not match every subject")))
+
+	request = valid()
+	request.Audience = ""
+	request.Normalize()
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("audience is required")))
+
+	request = valid()
+	request.ClaimPatterns = map[string]string{"sub": "*"}
+	request.Normalize()
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("claimPatterns")))
+
+	request = valid()
+	request.TokenLifetime = new(types.Duration(24 * time.Hour))
+	request.Normalize()
+	g.Expect(request.Validate()).To(MatchError(ContainSubstring("tokenLifetime must be between")))
+}
@@ -28,6 +28,20 @@ type AgentDeployment struct {
 	ProjectName string           `json:"projectName"`
 	DockerType  types.DockerType `json:"docker_type,omitempty"`
 	State       State            `json:"phase"`
+
+	ApplicationVersionID uuid.UUID `json:"applicationVersionId"`
+	// PendingVolumeSnapshot is set until the volumes of the previous revision have been saved, so that a failed
+	// snapshot is retried before the revision is applied again.
+	PendingVolumeSnapshot *VolumeSnapshotSource `json:"pendingVolumeSnapshot,omitempty"`
+	// RestoredVolumeSnapshotID is the last snapshot that was restored, so that it is not restored again.
+	RestoredVolumeSnapshotID *uuid.UUID `json:"restoredVolumeSnapshotId,omitempty"`
+}
+
+// VolumeSnapshotSource is the revision whose volumes are saved before another revision is applied.
+type VolumeSnapshotSource struct {
+	ProjectName          string    `json:"projectName"`
+	RevisionID           uuid.UUID `json:"revisionId"`
+	ApplicationVersionID uuid.UUID `json:"applicationVersionId"`
 }
 
 func (d AgentDeployment) GetDeploymentID() uuid.UUID {
@@ -55,6 +69,8 @@ func NewAgentDeployment(deployment api.AgentDeployment) (*AgentDeployment, error
 			RevisionID:  deployment.RevisionID,
 			ProjectName: name,
 			DockerType:  *deployment.DockerType,
+
+			ApplicationVersionID: deployment.ApplicationVersionID,
 		}, nil
 	}
 }
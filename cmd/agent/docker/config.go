@@ -8,3 +8,16 @@ func ScratchDir() string {
 	}
 	return "./scratch"
 }
+
+// QuadletDir is where the units of the deployments are written to if the agent runs in Quadlet mode.
+func QuadletDir() string {
+	if dir := os.Getenv("DISTR_QUADLET_DIR"); dir != "" {
+		return dir
+	}
+	return "/etc/containers/systemd"
+}
+
+// SystemdUser is true if the units are managed by the systemd user instance, which is the case for rootless Podman.
+func SystemdUser() bool {
+	return os.Getenv("DISTR_SYSTEMD_USER") == "true"
+}
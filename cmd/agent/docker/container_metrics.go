@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/docker/cli/cli/compose/convert"
+	composeapi "github.com/docker/compose/v5/pkg/api"
+	"github.com/moby/moby/api/types/container"
+	mobyClient "github.com/moby/moby/client"
+	"go.uber.org/zap"
+)
+
+const swarmServiceNameLabel = "com.docker.swarm.service.name"
+
+// containerMetrics returns the resource usage of the running containers of all deployments. Deployments whose
+// containers cannot be listed are skipped.
+func containerMetrics(ctx context.Context) ([]api.DeploymentContainerMetric, error) {
+	deployments, err := GetExistingDeployments()
+	if err != nil {
+		return nil, err
+	}
+	var result []api.DeploymentContainerMetric
+	for _, deployment := range deployments {
+		metrics, err := deploymentContainerMetrics(ctx, deployment)
+		if err != nil {
+			logger.Warn("failed to collect container metrics",
+				zap.String("projectName", deployment.ProjectName), zap.Error(err))
+			continue
+		}
+		result = append(result, metrics...)
+	}
+	slices.SortFunc(result, func(a, b api.DeploymentContainerMetric) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result, nil
+}
+
+func deploymentContainerMetrics(
+	ctx context.Context,
+	deployment AgentDeployment,
+) ([]api.DeploymentContainerMetric, error) {
+	// Quadlet containers also have the compose project label
+	projectLabel, serviceLabel := composeapi.ProjectLabel, composeapi.ServiceLabel
+	if deployment.DockerType == types.DockerTypeSwarm {
+		projectLabel, serviceLabel = convert.LabelNamespace, swarmServiceNameLabel
+	}
+
+	apiClient := dockerCli.Client()
+	containers, err := apiClient.ContainerList(ctx, mobyClient.ContainerListOptions{
+		Filters: mobyClient.Filters{}.Add("label", projectLabel+"="+deployment.ProjectName),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]api.DeploymentContainerMetric, 0, len(containers.Items))
+	for _, summary := range containers.Items {
+		metric, err := singleContainerMetric(ctx, summary.ID)
+		if err != nil {
+			// the container may have been stopped in the meantime
+			logger.Debug("failed to collect metrics of container", zap.String("containerId", summary.ID), zap.Error(err))
+			continue
+		}
+		metric.DeploymentID = deployment.ID
+		metric.Service = summary.Labels[serviceLabel]
+		result = append(result, metric)
+	}
+	return result, nil
+}
+
+func singleContainerMetric(ctx context.Context, containerID string) (api.DeploymentContainerMetric, error) {
+	apiClient := dockerCli.Client()
+	inspect, err := apiClient.ContainerInspect(ctx, containerID, mobyClient.ContainerInspectOptions{})
+	if err != nil {
+		return api.DeploymentContainerMetric{}, fmt.Errorf("could not inspect container: %w", err)
+	}
+
+	statsResult, err := apiClient.ContainerStats(ctx, containerID, mobyClient.ContainerStatsOptions{
+		IncludePreviousSample: true,
+	})
+	if err != nil {
+		return api.DeploymentContainerMetric{}, fmt.Errorf("could not get container stats: %w", err)
+	}
+	defer statsResult.Body.Close()
+	var stats container.StatsResponse
+	if err := json.NewDecoder(statsResult.Body).Decode(&stats); err != nil {
+		return api.DeploymentContainerMetric{}, fmt.Errorf("could not decode container stats: %w", err)
+	}
+
+	metric := api.DeploymentContainerMetric{
+		Name:     strings.TrimPrefix(inspect.Container.Name, "/"),
+		Restarts: int64(inspect.Container.RestartCount),
+	}
+
+	// the same calculation as docker stats
+	onlineCPUs := float64(stats.CPUStats.OnlineCPUs)
+	cpuDelta := float64(stats.CPUStats.CPUUsage.TotalUsage) - float64(stats.PreCPUStats.CPUUsage.TotalUsage)
+	systemDelta := float64(stats.CPUStats.SystemUsage) - float64(stats.PreCPUStats.SystemUsage)
+	if cpuDelta > 0 && systemDelta > 0 && onlineCPUs > 0 {
+		usedCPUs := cpuDelta / systemDelta * onlineCPUs
+		metric.CPUUsedMillis = int64(usedCPUs * 1000)
+		if inspect.Container.HostConfig != nil && inspect.Container.HostConfig.NanoCPUs > 0 {
+			metric.CPUUsage = usedCPUs / (float64(inspect.Container.HostConfig.NanoCPUs) / 1e9)
+		} else {
+			metric.CPUUsage = usedCPUs / onlineCPUs
+		}
+	}
+
+	// the page cache can be reclaimed, so it is not counted as used like in docker stats. The limit is the memory of
+	// the host if the container has no limit.
+	memoryUsed := stats.MemoryStats.Usage
+	for _, key := range []string{"inactive_file", "total_inactive_file"} {
+		if inactive, ok := stats.MemoryStats.Stats[key]; ok && inactive < memoryUsed {
+			memoryUsed -= inactive
+			break
+		}
+	}
+	metric.MemoryUsedBytes = int64(memoryUsed)
+	if stats.MemoryStats.Limit > 0 {
+		metric.MemoryUsage = float64(memoryUsed) / float64(stats.MemoryStats.Limit)
+	}
+
+	for _, network := range stats.Networks {
+		metric.NetworkRxBytes += int64(network.RxBytes)
+		metric.NetworkTxBytes += int64(network.TxBytes)
+	}
+
+	return metric, nil
+}
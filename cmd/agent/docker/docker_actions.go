@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/compose-spec/compose-go/v2/dotenv"
+	composetypes "github.com/compose-spec/compose-go/v2/types"
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/agentauth"
 	"github.com/distr-sh/distr/internal/agentenv"
@@ -23,6 +24,8 @@ import (
 func DockerEngineApply(
 	ctx context.Context,
 	deployment api.AgentDeployment,
+	previous *AgentDeployment,
+	storage *api.AgentVolumeBackupStorage,
 	updateStatus func(string),
 ) (agentDeployment *AgentDeployment, status string, err error) {
 	logger := logger.With(zap.Stringer("deploymentId", deployment.ID))
@@ -30,18 +33,36 @@ func DockerEngineApply(
 	if err != nil {
 		return agentDeployment, status, err
 	}
+	if previous != nil {
+		agentDeployment.RestoredVolumeSnapshotID = previous.RestoredVolumeSnapshotID
+	}
 
 	agentDeployment.State = StateProgressing
+	if *deployment.DockerType != types.DockerTypeSwarm && containerRuntime != types.ContainerRuntimeQuadlet {
+		agentDeployment.PendingVolumeSnapshot = pendingVolumeSnapshot(deployment, previous)
+	}
 	if err = SaveDeployment(*agentDeployment); err != nil {
 		logger.Warn("failed to save deployment before apply", zap.Error(err))
 	}
 
-	if *deployment.DockerType == types.DockerTypeSwarm {
+	if *deployment.DockerType == types.DockerTypeSwarm && isPodman() {
+		err = errors.New("swarm mode is not supported by Podman")
+	} else if *deployment.DockerType == types.DockerTypeSwarm {
 		logger.Debug("applying compose file in swarm mode")
 		status, err = ApplyComposeFileSwarm(ctx, deployment, updateStatus)
+	} else if containerRuntime == types.ContainerRuntimeQuadlet {
+		if deployment.VolumeBackupPolicy != types.VolumeBackupPolicyNone || deployment.RestoreVolumeSnapshotID != nil {
+			logger.Warn("volume backups are not supported for Quadlet deployments")
+		}
+		logger.Debug("applying compose file as Quadlet units")
+		err = ApplyQuadlet(ctx, deployment, updateStatus)
+		if err == nil {
+			status = "Quadlet units started successfully"
+		}
 	} else {
 		logger.Debug("applying compose file")
-		err = ApplyComposeFile(ctx, deployment, updateStatus)
+		beforeUp := volumeBackupHook(deployment, agentDeployment, storage, updateStatus)
+		err = ApplyComposeFile(ctx, deployment, beforeUp, updateStatus)
 		if err == nil {
 			status = "compose command executed successfully"
 		}
@@ -60,7 +81,14 @@ func DockerEngineApply(
 	return agentDeployment, status, err
 }
 
-func ApplyComposeFile(ctx context.Context, deployment api.AgentDeployment, updateStatus func(string)) error {
+// ApplyComposeFile runs compose up for the deployment. If beforeUp is not nil, it is called with the loaded project
+// before the containers are created.
+func ApplyComposeFile(
+	ctx context.Context,
+	deployment api.AgentDeployment,
+	beforeUp func(context.Context, composeapi.Compose, *composetypes.Project) error,
+	updateStatus func(string),
+) error {
 	updateStatus("initializing compose service")
 
 	// Write the compose file and the env file into a dedicated working directory using their canonical
@@ -91,6 +119,12 @@ func ApplyComposeFile(ctx context.Context, deployment api.AgentDeployment, updat
 		return fmt.Errorf("failed to load compose project: %w", err)
 	}
 
+	if beforeUp != nil {
+		if err := beforeUp(ctx, composeService, project); err != nil {
+			return err
+		}
+	}
+
 	err = composeService.Up(ctx, project, composeapi.UpOptions{
 		Create: composeapi.CreateOptions{RemoveOrphans: true},
 		Start:  composeapi.StartOptions{Project: project},
@@ -187,6 +221,8 @@ func ApplyComposeFileSwarm(
 func DockerEngineUninstall(ctx context.Context, deployment AgentDeployment) error {
 	if deployment.DockerType == types.DockerTypeSwarm {
 		return UninstallDockerSwarm(ctx, deployment)
+	} else if containerRuntime == types.ContainerRuntimeQuadlet {
+		return UninstallQuadlet(ctx, deployment)
 	}
 	return UninstallDockerCompose(ctx, deployment)
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/avast/retry-go/v5"
+	cerrdefs "github.com/containerd/errdefs"
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/docker/compose/v5/pkg/api"
 	mobyClient "github.com/moby/moby/client"
@@ -54,7 +55,15 @@ func DeleteImages(ctx context.Context, images []string) (aggErr error) {
 			aggErr,
 			deleteImageRetrier.Do(func() error {
 				result, err := apiClient.ImageRemove(ctx, image, mobyClient.ImageRemoveOptions{PruneChildren: true})
-				if err != nil {
+				if cerrdefs.IsNotFound(err) {
+					logger.Debug("old image was already deleted")
+					return nil
+				} else if isPodman() && cerrdefs.IsConflict(err) {
+					// Podman shares the image store with other tools on the host (e.g. podman run or a Quadlet unit of
+					// the customer), so an image that is still in use is expected and retrying would not help.
+					logger.Info("old image is still in use and is not deleted", zap.Error(err))
+					return nil
+				} else if err != nil {
 					logger.Warn("failed to delete old image", zap.Error(err))
 				} else {
 					logger.Info("deleted old image", zap.Any("result", result))
@@ -7,6 +7,7 @@ import (
 	"maps"
 	"net/http"
 	"os/signal"
+	"path"
 	"slices"
 	"sync/atomic"
 	"syscall"
@@ -17,6 +18,8 @@ import (
 	"github.com/distr-sh/distr/internal/agentcheck"
 	"github.com/distr-sh/distr/internal/agentclient"
 	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agentmetrics"
+	"github.com/distr-sh/distr/internal/agentqueue"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	"github.com/distr-sh/distr/internal/deploymenttargetlogs"
 	"github.com/distr-sh/distr/internal/types"
@@ -44,20 +47,28 @@ var (
 			return zapcore.NewTee(c, platformLoggingCore)
 		}),
 	))
-	client         = util.Require(agentclient.NewFromEnv(logger))
-	dockerCli      = util.Require(dockercommand.NewDockerCli())
-	composeService composeapi.Compose
-	health         = agentcheck.NewServer(time.Hour)
-	logWatcher     = NewLogsWatcher(30 * time.Second)
+	client           = util.Require(agentclient.NewFromEnv(logger))
+	dockerCli        = util.Require(dockercommand.NewDockerCli())
+	composeService   composeapi.Compose
+	health           = agentcheck.NewServer(time.Hour)
+	metricsCollector = agentmetrics.NewCollector()
+	logWatcher       = NewLogsWatcher(30 * time.Second)
 )
 
 func init() {
 	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: client}
+	if queue, err := agentqueue.Open(path.Join(ScratchDir(), "queue"), int64(agentenv.QueueMaxSizeMB)<<20); err != nil {
+		logger.Warn("failed to open queue, requests are lost while the hub cannot be reached", zap.Error(err))
+	} else {
+		client.SetQueue(queue)
+		health.SetQueueStats(queue.Stats)
+	}
 	if agentenv.AgentVersionID == "" {
 		logger.Warn("AgentVersionID is not set. self updates will be disabled")
 	}
 	util.Must(dockerCli.Initialize(flags.NewClientOptions()))
 	composeService = util.Require(compose.NewComposeService(dockerCli))
+	containerRuntime = detectContainerRuntime(context.Background())
 }
 
 func main() {
@@ -80,7 +91,8 @@ func main() {
 	logger.Info("docker agent is starting",
 		zap.String("version", buildconfig.Version()),
 		zap.String("commit", buildconfig.Commit()),
-		zap.Bool("release", buildconfig.IsRelease()))
+		zap.Bool("release", buildconfig.IsRelease()),
+		zap.String("containerRuntime", string(containerRuntime)))
 
 	go func() {
 		if err := startHealthServer(); err != nil {
@@ -95,19 +107,21 @@ func main() {
 
 func mainLoop(ctx context.Context) {
 	tick := time.Tick(agentenv.Interval)
+	resources := client.WatchResource(ctx, agentenv.ResourceWait, agentenv.Interval)
 	logsGoroutine := util.NewToggleableGoroutine(logWatcher.Watch)
 
 loop:
 	for ctx.Err() == nil {
 		select {
 		case <-tick:
+		case <-resources.Changed():
 		case <-ctx.Done():
 			break loop
 		}
 
 		health.Heartbeat()
 
-		if resource, err := client.Resource(ctx); err != nil {
+		if resource, err := resources.Resource(ctx); err != nil {
 			logger.Error("failed to get resource", zap.Error(err))
 		} else {
 			if selfUpdateIfRequired(ctx, *resource) {
@@ -170,7 +184,9 @@ loop:
 							progressCtx, progressCancel := context.WithCancel(ctx)
 							defer progressCancel()
 							updateStatus := sendProgressInterval(progressCtx, deployment.RevisionID)
-							agentDeployment, status, err = DockerEngineApply(ctx, deployment, updateStatus)
+							agentDeployment, status, err = DockerEngineApply(
+								ctx, deployment, agentDeployment, resource.VolumeBackupStorage, updateStatus,
+							)
 							if err == nil {
 								if deployment.ImageCleanupEnabled {
 									if delErr := DeleteImages(ctx, previousDeploymentImages); delErr != nil {
@@ -237,7 +253,10 @@ func sendProgressInterval(ctx context.Context, revisionID uuid.UUID) func(string
 }
 
 func startHealthServer() error {
-	err := http.ListenAndServe("127.0.0.1:8765", health)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsCollector.Handler())
+	mux.Handle("/", health)
+	err := http.ListenAndServe("127.0.0.1:8765", mux)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
@@ -140,6 +140,14 @@ func startMetrics(ctx context.Context) {
 			reportMetrics.DiskMetrics = dm
 		}
 
+		if cm, err := containerMetrics(ctx); err != nil {
+			logger.Warn("failed to collect container metrics", zap.Error(err))
+		} else {
+			reportMetrics.ContainerMetrics = cm
+		}
+
+		metricsCollector.Update(reportMetrics)
+
 		if err := client.ReportMetrics(ctx, reportMetrics); err != nil {
 			logger.Error("failed to report metrics", zap.Error(err))
 			return err
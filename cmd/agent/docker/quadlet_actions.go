@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/quadlet"
+	composeapi "github.com/docker/compose/v5/pkg/api"
+	"github.com/docker/compose/v5/pkg/compose"
+	mobyClient "github.com/moby/moby/client"
+	"go.uber.org/zap"
+)
+
+// ApplyQuadlet converts the compose file of the deployment to Quadlet units, so that the containers are managed by
+// systemd instead of the agent. The images are pulled by the agent, because the units never pull on their own.
+func ApplyQuadlet(ctx context.Context, deployment api.AgentDeployment, updateStatus func(string)) error {
+	updateStatus("initializing compose service")
+
+	workDir, cleanup, err := WriteComposeWorkingDir(deployment)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	eventProcessor := NewEventProcessor(updateStatus)
+	composeService, err := ComposeServiceForDeployment(deployment, compose.WithEventProcessor(eventProcessor))
+	if err != nil {
+		return fmt.Errorf("failed to initialize compose service: %w", err)
+	}
+
+	loadOpts := composeapi.ProjectLoadOptions{
+		WorkingDir:  workDir.Path,
+		ConfigPaths: []string{workDir.ComposeFile},
+	}
+	if workDir.EnvFile != "" {
+		loadOpts.EnvFiles = []string{workDir.EnvFile}
+	}
+
+	project, err := composeService.LoadProject(ctx, loadOpts)
+	if err != nil {
+		return fmt.Errorf("failed to load compose project: %w", err)
+	}
+
+	units, err := quadlet.FromProject(project)
+	if err != nil {
+		return fmt.Errorf("failed to convert compose project to Quadlet units: %w", err)
+	}
+
+	updateStatus("pulling images")
+	if err := composeService.Pull(ctx, project, composeapi.PullOptions{Quiet: true}); err != nil {
+		return fmt.Errorf("failed to pull images: %w", err)
+	}
+
+	updateStatus("writing Quadlet units")
+	dir := quadletProjectDir(project.Name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	existing, err := readQuadletUnits(dir)
+	if err != nil {
+		return err
+	}
+
+	var changed []quadlet.Unit
+	for _, unit := range units {
+		unitChanged, err := writeQuadletFile(dir, unit.FileName, unit.Content, 0o644, existing)
+		if err != nil {
+			return err
+		}
+		// The environment may contain secrets, so unlike the unit it is only readable by the agent and systemd.
+		environmentChanged := false
+		if len(unit.Environment) > 0 {
+			environmentChanged, err = writeQuadletFile(dir, unit.EnvironmentFileName(), unit.Environment, 0o600, existing)
+			if err != nil {
+				return err
+			}
+		}
+		if unitChanged || environmentChanged {
+			changed = append(changed, unit)
+		}
+	}
+
+	// The files that are left over belong to services that were removed from the compose file or no longer have an
+	// environment.
+	for fileName := range existing {
+		if !quadlet.IsEnvironmentFile(fileName) {
+			if _, err := systemctl(ctx, "stop", quadlet.ServiceName(fileName)); err != nil {
+				logger.Warn("failed to stop removed Quadlet unit", zap.String("unit", fileName), zap.Error(err))
+			}
+		}
+		if err := os.Remove(path.Join(dir, fileName)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+
+	updateStatus("starting Quadlet units")
+	var services []string
+	for _, unit := range units {
+		services = append(services, unit.ServiceName())
+	}
+	if _, err := systemctl(ctx, append([]string{"start"}, services...)...); err != nil {
+		return err
+	}
+	// Starting a unit that is already running has no effect, so the containers with a changed unit must be
+	// restarted to pick up the new configuration.
+	var restart []string
+	for _, unit := range changed {
+		if unit.IsContainer() {
+			restart = append(restart, unit.ServiceName())
+		}
+	}
+	if len(restart) > 0 {
+		if _, err := systemctl(ctx, append([]string{"restart"}, restart...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UninstallQuadlet stops and removes the units of the deployment. The containers are removed by Podman when their
+// unit stops, but the volumes and networks outlive their units, so they are removed like "compose down --volumes".
+func UninstallQuadlet(ctx context.Context, deployment AgentDeployment) error {
+	dir := quadletProjectDir(deployment.ProjectName)
+	units, err := readQuadletUnits(dir)
+	if err != nil {
+		return err
+	}
+	var services []string
+	for fileName := range units {
+		if !quadlet.IsEnvironmentFile(fileName) {
+			services = append(services, quadlet.ServiceName(fileName))
+		}
+	}
+	if len(services) > 0 {
+		if _, err := systemctl(ctx, append([]string{"stop"}, services...)...); err != nil {
+			return err
+		}
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	} else if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	}
+
+	var aggErr error
+	apiClient := dockerCli.Client()
+	filters := mobyClient.Filters{}.Add("label", composeapi.ProjectLabel+"="+deployment.ProjectName)
+	if volumes, err := apiClient.VolumeList(ctx, mobyClient.VolumeListOptions{Filters: filters}); err != nil {
+		aggErr = errors.Join(aggErr, err)
+	} else {
+		for _, volume := range volumes.Items {
+			_, err := apiClient.VolumeRemove(ctx, volume.Name, mobyClient.VolumeRemoveOptions{})
+			aggErr = errors.Join(aggErr, err)
+		}
+	}
+	if networks, err := apiClient.NetworkList(ctx, mobyClient.NetworkListOptions{Filters: filters}); err != nil {
+		aggErr = errors.Join(aggErr, err)
+	} else {
+		for _, network := range networks.Items {
+			_, err := apiClient.NetworkRemove(ctx, network.ID, mobyClient.NetworkRemoveOptions{})
+			aggErr = errors.Join(aggErr, err)
+		}
+	}
+	return aggErr
+}
+
+func RunQuadletRestart(ctx context.Context, deployment AgentDeployment) error {
+	units, err := readQuadletUnits(quadletProjectDir(deployment.ProjectName))
+	if err != nil {
+		return err
+	}
+	var services []string
+	for fileName := range units {
+		if (quadlet.Unit{FileName: fileName}).IsContainer() {
+			services = append(services, quadlet.ServiceName(fileName))
+		}
+	}
+	if len(services) == 0 {
+		return fmt.Errorf("deployment %v has no Quadlet units", deployment.ProjectName)
+	}
+	_, err = systemctl(ctx, append([]string{"restart"}, services...)...)
+	return err
+}
+
+// quadletProjectDir is the directory with the units of a deployment. Quadlet also reads units from subdirectories,
+// which keeps the units of the agent apart from units that were created by someone else.
+func quadletProjectDir(projectName string) string {
+	return path.Join(QuadletDir(), "distr-"+projectName)
+}
+
+// writeQuadletFile writes a unit or environment file to dir, unless it has the same content already, and removes it
+// from existing. It returns whether the file was written.
+func writeQuadletFile(
+	dir, fileName string,
+	content []byte,
+	perm os.FileMode,
+	existing map[string][]byte,
+) (bool, error) {
+	current, ok := existing[fileName]
+	delete(existing, fileName)
+	if ok && bytes.Equal(current, content) {
+		return false, nil
+	}
+	return true, writeFileAtomic(path.Join(dir, fileName), content, perm)
+}
+
+// readQuadletUnits returns the contents of the units and their environment files in dir by their file name. A missing
+// dir has no units.
+func readQuadletUnits(dir string) (map[string][]byte, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string][]byte{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	units := make(map[string][]byte, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") {
+			continue
+		}
+		if content, err := os.ReadFile(path.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		} else {
+			units[entry.Name()] = content
+		}
+	}
+	return units, nil
+}
+
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp := name + ".tmp"
+	// A leftover of an interrupted write would otherwise keep its permissions.
+	if err := os.Remove(tmp); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	} else if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// systemctl manages units of the systemd user instance if the agent runs for rootless Podman.
+func systemctl(ctx context.Context, args ...string) (string, error) {
+	if SystemdUser() {
+		args = append([]string{"--user"}, args...)
+	}
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("systemctl %v failed: %w: %v", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
@@ -16,6 +16,9 @@ import (
 func RunDockerRestart(ctx context.Context, deployment AgentDeployment) error {
 	switch deployment.DockerType {
 	case types.DockerTypeCompose:
+		if containerRuntime == types.ContainerRuntimeQuadlet {
+			return RunQuadletRestart(ctx, deployment)
+		}
 		return RunDockerComposeRestart(ctx, deployment)
 	case types.DockerTypeSwarm:
 		return RunDockerSwarmRestart(ctx, deployment)
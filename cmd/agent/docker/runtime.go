@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/types"
+	mobyClient "github.com/moby/moby/client"
+	"go.uber.org/zap"
+)
+
+// containerRuntime is the runtime that the agent manages the deployments with. It is detected once on startup.
+var containerRuntime = types.ContainerRuntimeDocker
+
+func detectContainerRuntime(ctx context.Context) types.ContainerRuntime {
+	if types.ContainerRuntime(os.Getenv("DISTR_CONTAINER_RUNTIME")) == types.ContainerRuntimeQuadlet {
+		return types.ContainerRuntimeQuadlet
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	version, err := dockerCli.Client().ServerVersion(ctx, mobyClient.ServerVersionOptions{})
+	if err != nil {
+		logger.Warn("failed to detect container runtime, assuming docker", zap.Error(err))
+		return types.ContainerRuntimeDocker
+	}
+	// The Docker compatible API of Podman reports itself as the "Podman Engine" component.
+	for _, component := range version.Components {
+		if strings.Contains(component.Name, "Podman") {
+			return types.ContainerRuntimePodman
+		}
+	}
+	return types.ContainerRuntimeDocker
+}
+
+// isPodman is true if the deployments are run by Podman, regardless of whether compose or Quadlet is used.
+func isPodman() bool {
+	return containerRuntime == types.ContainerRuntimePodman || containerRuntime == types.ContainerRuntimeQuadlet
+}
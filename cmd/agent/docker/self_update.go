@@ -10,13 +10,16 @@ import (
 	"regexp"
 	"strings"
 
+	"github.com/distr-sh/distr/internal/agenthost"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/moby/moby/api/types/container"
 	mobyClient "github.com/moby/moby/client"
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
 
-// updateContainerName is the fixed name of the updater container, used to enforce single-flight.
+// updateContainerName is the fixed name of the updater container (or the transient unit in Quadlet mode), used to
+// enforce single-flight.
 const updateContainerName = "distr-agent-update"
 
 // containerIDPattern matches the agent's own container ID in /proc/self/mountinfo. Docker mounts
@@ -25,6 +28,11 @@ const updateContainerName = "distr-agent-update"
 var containerIDPattern = regexp.MustCompile(`containers/([0-9a-f]{64})/`)
 
 func RunAgentSelfUpdate(ctx context.Context) error {
+	if containerRuntime == types.ContainerRuntimeQuadlet {
+		// Like the agents that are installed on the host, a Quadlet agent is updated by its install script.
+		return agenthost.SelfUpdate{Logger: logger, Client: client, UnitName: updateContainerName, User: SystemdUser()}.
+			Run(ctx)
+	}
 	if manifest, err := client.Manifest(ctx); err != nil {
 		return fmt.Errorf("error fetching agent manifest: %w", err)
 	} else if parsedManifest, err := DecodeComposeFile(manifest); err != nil {
@@ -113,17 +121,24 @@ func ApplyAgentComposeFile(ctx context.Context, manifest map[string]any) error {
 		return err
 	}
 
-	cmd := exec.CommandContext(ctx,
-		"docker", "run", "--detach", "--rm",
+	args := []string{
+		"run", "--detach", "--rm",
 		"--name", updateContainerName,
 		"--entrypoint", "/usr/local/bin/docker-entrypoint.sh",
-		"--env", "HOST_DOCKER_CONFIG_DIR="+os.Getenv("HOST_DOCKER_CONFIG_DIR"),
-		"--env", "DOCKER_HOST="+os.Getenv("DOCKER_HOST"),
+		"--env", "HOST_DOCKER_CONFIG_DIR=" + os.Getenv("HOST_DOCKER_CONFIG_DIR"),
+		"--env", "DOCKER_HOST=" + os.Getenv("DOCKER_HOST"),
 		"--volumes-from", containerID,
-		imageName,
-		"docker", "compose", "-f", file.Name(), "up", "-d",
-	)
-	out, err := cmd.CombinedOutput()
+	}
+	if socket := os.Getenv("HOST_DOCKER_SOCKET"); socket != "" {
+		// The manifest mounts the socket of rootless Podman from this variable, so the update must keep it.
+		args = append(args, "--env", "HOST_DOCKER_SOCKET="+socket)
+	}
+	if isPodman() {
+		// SELinux would otherwise deny the updater access to the mounted socket, like it does for the agent.
+		args = append(args, "--security-opt", "label=disable")
+	}
+	args = append(args, imageName, "docker", "compose", "-f", file.Name(), "up", "-d")
+	out, err := exec.CommandContext(ctx, "docker", args...).CombinedOutput()
 	logger.Sugar().Infof("self-update output: %v", strings.TrimSpace(string(out)))
 	return err
 }
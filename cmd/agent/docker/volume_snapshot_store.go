@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentnet"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/volumesnapshot"
+	"github.com/google/uuid"
+)
+
+var errSnapshotNotFound = errors.New("volume snapshot not found")
+
+// StoredSnapshot is kept next to every archive, so that snapshots can be listed without reading the archives.
+type StoredSnapshot struct {
+	volumesnapshot.Manifest
+	SizeBytes int64 `json:"sizeBytes"`
+}
+
+// SnapshotStore keeps the volume snapshots of the deployments, grouped by deployment.
+type SnapshotStore interface {
+	// Put stores the archive of a snapshot, which is read from file.
+	Put(ctx context.Context, snapshot StoredSnapshot, file *os.File) error
+	// List returns the snapshots of a deployment, newest first.
+	List(ctx context.Context, deploymentID uuid.UUID) ([]StoredSnapshot, error)
+	Open(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) (io.ReadCloser, error)
+	Delete(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) error
+}
+
+func NewSnapshotStore(ctx context.Context, storage api.AgentVolumeBackupStorage) (SnapshotStore, error) {
+	switch storage.Type {
+	case types.VolumeBackupStorageLocal:
+		return &localSnapshotStore{dir: path.Join(ScratchDir(), "volume-snapshots")}, nil
+	case types.VolumeBackupStorageS3:
+		return newS3SnapshotStore(ctx, storage)
+	default:
+		return nil, fmt.Errorf("unsupported volume backup storage type: %v", storage.Type)
+	}
+}
+
+func sortSnapshots(snapshots []StoredSnapshot) {
+	slices.SortFunc(snapshots, func(a, b StoredSnapshot) int { return b.CreatedAt.Compare(a.CreatedAt) })
+}
+
+type localSnapshotStore struct {
+	dir string
+}
+
+func (s *localSnapshotStore) deploymentDir(deploymentID uuid.UUID) string {
+	return path.Join(s.dir, deploymentID.String())
+}
+
+func (s *localSnapshotStore) Put(ctx context.Context, snapshot StoredSnapshot, file *os.File) error {
+	dir := s.deploymentDir(snapshot.DeploymentID)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	archive := path.Join(dir, snapshot.ID.String()+".tar.gz")
+	if err := copyFile(archive, file); err != nil {
+		return err
+	}
+	if data, err := json.Marshal(snapshot); err != nil {
+		return err
+	} else {
+		// the sidecar is written last, so that only complete archives are listed
+		return writeFileAtomic(path.Join(dir, snapshot.ID.String()+".json"), data, 0o600)
+	}
+}
+
+func (s *localSnapshotStore) List(ctx context.Context, deploymentID uuid.UUID) ([]StoredSnapshot, error) {
+	dir := s.deploymentDir(deploymentID)
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var result []StoredSnapshot
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		var snapshot StoredSnapshot
+		if data, err := os.ReadFile(path.Join(dir, entry.Name())); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(data, &snapshot); err != nil {
+			return nil, fmt.Errorf("invalid snapshot %v: %w", entry.Name(), err)
+		}
+		result = append(result, snapshot)
+	}
+	sortSnapshots(result)
+	return result, nil
+}
+
+func (s *localSnapshotStore) Open(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) (io.ReadCloser, error) {
+	file, err := os.Open(path.Join(s.deploymentDir(deploymentID), id.String()+".tar.gz"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, errSnapshotNotFound
+	}
+	return file, err
+}
+
+func (s *localSnapshotStore) Delete(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) error {
+	dir := s.deploymentDir(deploymentID)
+	if err := os.Remove(path.Join(dir, id.String()+".json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Remove(path.Join(dir, id.String()+".tar.gz")); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func copyFile(name string, src *os.File) error {
+	if _, err := src.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	tmp := name + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		_ = dst.Close()
+		return err
+	} else if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+// s3SnapshotStore uploads every archive with a single request, which limits the size of a snapshot to 5 GB.
+type s3SnapshotStore struct {
+	client *s3.Client
+	bucket string
+	prefix string
+}
+
+func newS3SnapshotStore(ctx context.Context, storage api.AgentVolumeBackupStorage) (*s3SnapshotStore, error) {
+	options := func(o *s3.Options) {
+		if storage.S3Region != "" {
+			o.Region = storage.S3Region
+		}
+		if storage.S3Endpoint != "" {
+			o.BaseEndpoint = &storage.S3Endpoint
+		}
+		o.UsePathStyle = storage.S3UsePathStyle
+		if storage.S3AccessKeyID != "" {
+			o.Credentials = aws.NewCredentialsCache(
+				credentials.NewStaticCredentialsProvider(storage.S3AccessKeyID, storage.S3SecretAccessKey, ""),
+			)
+		}
+	}
+	// without static credentials, the default credential chain is used, e.g. the role of an EC2 instance
+	config, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithHTTPClient(
+		awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+			t.Proxy = http.ProxyFromEnvironment
+			t.TLSClientConfig = agentnet.TLSConfig()
+		}),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load S3 config: %w", err)
+	}
+	return &s3SnapshotStore{
+		client: s3.NewFromConfig(config, options),
+		bucket: storage.S3Bucket,
+		prefix: strings.Trim(storage.S3Prefix, "/"),
+	}, nil
+}
+
+func (s *s3SnapshotStore) key(deploymentID uuid.UUID, name string) string {
+	return path.Join(s.prefix, deploymentID.String(), name)
+}
+
+func (s *s3SnapshotStore) Put(ctx context.Context, snapshot StoredSnapshot, file *os.File) error {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:        &s.bucket,
+		Key:           aws.String(s.key(snapshot.DeploymentID, snapshot.ID.String()+".tar.gz")),
+		Body:          file,
+		ContentLength: &snapshot.SizeBytes,
+		ContentType:   aws.String("application/gzip"),
+	}); err != nil {
+		return fmt.Errorf("failed to upload snapshot: %w", err)
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      &s.bucket,
+		Key:         aws.String(s.key(snapshot.DeploymentID, snapshot.ID.String()+".json")),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/json"),
+	}); err != nil {
+		return fmt.Errorf("failed to upload snapshot manifest: %w", err)
+	}
+	return nil
+}
+
+func (s *s3SnapshotStore) List(ctx context.Context, deploymentID uuid.UUID) ([]StoredSnapshot, error) {
+	var result []StoredSnapshot
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: &s.bucket,
+		Prefix: aws.String(s.key(deploymentID, "") + "/"),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list snapshots: %w", err)
+		}
+		for _, object := range page.Contents {
+			if object.Key == nil || !strings.HasSuffix(*object.Key, ".json") {
+				continue
+			}
+			output, err := s.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &s.bucket, Key: object.Key})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get snapshot manifest: %w", err)
+			}
+			var snapshot StoredSnapshot
+			err = json.NewDecoder(output.Body).Decode(&snapshot)
+			_ = output.Body.Close()
+			if err != nil {
+				return nil, fmt.Errorf("invalid snapshot %v: %w", *object.Key, err)
+			}
+			result = append(result, snapshot)
+		}
+	}
+	sortSnapshots(result)
+	return result, nil
+}
+
+func (s *s3SnapshotStore) Open(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) (io.ReadCloser, error) {
+	output, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: &s.bucket,
+		Key:    aws.String(s.key(deploymentID, id.String()+".tar.gz")),
+	})
+	if _, ok := errors.AsType[*s3types.NoSuchKey](err); ok {
+		return nil, errSnapshotNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to download snapshot: %w", err)
+	}
+	return output.Body, nil
+}
+
+func (s *s3SnapshotStore) Delete(ctx context.Context, deploymentID uuid.UUID, id uuid.UUID) error {
+	for _, name := range []string{id.String() + ".json", id.String() + ".tar.gz"} {
+		if _, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: &s.bucket,
+			Key:    aws.String(s.key(deploymentID, name)),
+		}); err != nil {
+			return fmt.Errorf("failed to delete snapshot: %w", err)
+		}
+	}
+	return nil
+}
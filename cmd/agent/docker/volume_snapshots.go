@@ -0,0 +1,352 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+
+	composetypes "github.com/compose-spec/compose-go/v2/types"
+	cerrdefs "github.com/containerd/errdefs"
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/distr-sh/distr/internal/volumesnapshot"
+	composeapi "github.com/docker/compose/v5/pkg/api"
+	"github.com/google/uuid"
+	"github.com/moby/moby/api/types/mount"
+	mobyClient "github.com/moby/moby/client"
+	"go.uber.org/zap"
+)
+
+// volumeMount is a container that mounts a volume of a compose project, which is needed to copy the contents of
+// the volume, as the Docker API has no access to volumes on their own.
+type volumeMount struct {
+	ContainerID string
+	Destination string
+}
+
+// pendingVolumeSnapshot returns the revision whose volumes must be saved before deployment is applied. A snapshot
+// that is still pending from a failed apply is kept, because the volumes still belong to that revision.
+func pendingVolumeSnapshot(deployment api.AgentDeployment, previous *AgentDeployment) *VolumeSnapshotSource {
+	if previous == nil {
+		return nil
+	} else if previous.PendingVolumeSnapshot != nil {
+		return previous.PendingVolumeSnapshot
+	} else if previous.RevisionID == deployment.RevisionID {
+		return nil
+	}
+	switch deployment.VolumeBackupPolicy {
+	case types.VolumeBackupPolicyBeforeUpdate:
+	case types.VolumeBackupPolicyBeforeVersionUpdate:
+		if previous.ApplicationVersionID == deployment.ApplicationVersionID {
+			return nil
+		}
+	default:
+		return nil
+	}
+	return &VolumeSnapshotSource{
+		ProjectName:          previous.ProjectName,
+		RevisionID:           previous.RevisionID,
+		ApplicationVersionID: previous.ApplicationVersionID,
+	}
+}
+
+// volumeBackupHook returns the hook that runs before the compose project of deployment is started. It saves the
+// volumes if a snapshot is pending and restores them if the deployment refers to a snapshot that was not restored
+// yet. Both are recorded in agentDeployment right away, so that they are not repeated if the apply fails afterward.
+func volumeBackupHook(
+	deployment api.AgentDeployment,
+	agentDeployment *AgentDeployment,
+	storage *api.AgentVolumeBackupStorage,
+	updateStatus func(string),
+) func(context.Context, composeapi.Compose, *composetypes.Project) error {
+	return func(ctx context.Context, composeService composeapi.Compose, project *composetypes.Project) error {
+		restore := deployment.RestoreVolumeSnapshotID != nil &&
+			!util.PtrEq(agentDeployment.RestoredVolumeSnapshotID, deployment.RestoreVolumeSnapshotID)
+		if agentDeployment.PendingVolumeSnapshot == nil && !restore {
+			return nil
+		} else if storage == nil {
+			return errors.New("volume backups are not supported by this version of Distr")
+		}
+
+		store, err := NewSnapshotStore(ctx, *storage)
+		if err != nil {
+			return err
+		}
+
+		if source := agentDeployment.PendingVolumeSnapshot; source != nil {
+			updateStatus("creating volume snapshot")
+			if snapshot, err := CreateVolumeSnapshot(ctx, composeService, store, deployment.ID, *source); err != nil {
+				return fmt.Errorf("failed to create volume snapshot: %w", err)
+			} else if snapshot != nil {
+				logger.Info("volume snapshot created",
+					zap.Stringer("deploymentId", deployment.ID), zap.Stringer("snapshotId", snapshot.ID))
+			}
+			agentDeployment.PendingVolumeSnapshot = nil
+			if err := SaveDeployment(*agentDeployment); err != nil {
+				logger.Warn("failed to save deployment after snapshot", zap.Error(err))
+			}
+			pruneVolumeSnapshots(ctx, store, deployment, storage.Retention)
+		}
+
+		if restore {
+			updateStatus("restoring volume snapshot")
+			if err := RestoreVolumeSnapshot(ctx, composeService, store, project, deployment); err != nil {
+				return fmt.Errorf("failed to restore volume snapshot: %w", err)
+			}
+			agentDeployment.RestoredVolumeSnapshotID = util.PtrCopy(deployment.RestoreVolumeSnapshotID)
+			if err := SaveDeployment(*agentDeployment); err != nil {
+				logger.Warn("failed to save deployment after restore", zap.Error(err))
+			}
+		}
+		return nil
+	}
+}
+
+// CreateVolumeSnapshot copies the contents of the named volumes of a compose project into a new snapshot. The
+// containers are stopped while the volumes are copied, so that the snapshot is consistent, and started again
+// afterward. It returns nil if the project has no volumes.
+func CreateVolumeSnapshot(
+	ctx context.Context,
+	composeService composeapi.Compose,
+	store SnapshotStore,
+	deploymentID uuid.UUID,
+	source VolumeSnapshotSource,
+) (*StoredSnapshot, error) {
+	apiClient := dockerCli.Client()
+	filters := mobyClient.Filters{}.Add("label", composeapi.ProjectLabel+"="+source.ProjectName)
+
+	volumes, err := apiClient.VolumeList(ctx, mobyClient.VolumeListOptions{Filters: filters})
+	if err != nil {
+		return nil, err
+	}
+	volumeKeys := make(map[string]string, len(volumes.Items))
+	for _, volume := range volumes.Items {
+		if key := volume.Labels[composeapi.VolumeLabel]; key != "" {
+			volumeKeys[volume.Name] = key
+		}
+	}
+
+	mounts, err := getVolumeMounts(ctx, source.ProjectName, volumeKeys)
+	if err != nil {
+		return nil, err
+	} else if len(mounts) == 0 {
+		logger.Info("skipping volume snapshot because the deployment has no volumes",
+			zap.Stringer("deploymentId", deploymentID))
+		return nil, nil
+	}
+
+	manifest := volumesnapshot.Manifest{
+		ID:                   uuid.New(),
+		DeploymentID:         deploymentID,
+		RevisionID:           source.RevisionID,
+		ApplicationVersionID: source.ApplicationVersionID,
+		CreatedAt:            time.Now().UTC(),
+	}
+	for key := range mounts {
+		manifest.Volumes = append(manifest.Volumes, key)
+	}
+	slices.Sort(manifest.Volumes)
+
+	file, err := os.CreateTemp(ScratchDir(), "volume-snapshot-*.tar.gz")
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+	}()
+
+	if err := composeService.Stop(ctx, source.ProjectName, composeapi.StopOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to stop containers: %w", err)
+	}
+	err = writeVolumeSnapshot(ctx, file, manifest, mounts)
+	if startErr := composeService.Start(ctx, source.ProjectName, composeapi.StartOptions{}); startErr != nil {
+		err = errors.Join(err, fmt.Errorf("failed to start containers: %w", startErr))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := StoredSnapshot{Manifest: manifest}
+	if info, err := file.Stat(); err != nil {
+		return nil, err
+	} else {
+		snapshot.SizeBytes = info.Size()
+	}
+	if err := store.Put(ctx, snapshot, file); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+func writeVolumeSnapshot(
+	ctx context.Context,
+	file *os.File,
+	manifest volumesnapshot.Manifest,
+	mounts map[string]volumeMount,
+) error {
+	apiClient := dockerCli.Client()
+	writer, err := volumesnapshot.NewWriter(file, manifest)
+	if err != nil {
+		return err
+	}
+	for _, key := range manifest.Volumes {
+		m := mounts[key]
+		result, err := apiClient.CopyFromContainer(
+			ctx, m.ContainerID, mobyClient.CopyFromContainerOptions{SourcePath: m.Destination},
+		)
+		if err != nil {
+			return fmt.Errorf("failed to copy volume %v: %w", key, err)
+		}
+		err = writer.AddVolume(key, result.Content)
+		_ = result.Content.Close()
+		if err != nil {
+			return fmt.Errorf("failed to copy volume %v: %w", key, err)
+		}
+	}
+	return writer.Close()
+}
+
+// RestoreVolumeSnapshot replaces the volumes of the project with the contents of the snapshot of the deployment.
+// The containers of the project are removed and created again with empty volumes, which the snapshot is then copied
+// to. The containers are started by the following compose up.
+func RestoreVolumeSnapshot(
+	ctx context.Context,
+	composeService composeapi.Compose,
+	store SnapshotStore,
+	project *composetypes.Project,
+	deployment api.AgentDeployment,
+) error {
+	snapshotID := *deployment.RestoreVolumeSnapshotID
+	manifest, err := readVolumeSnapshot(ctx, store, deployment.ID, snapshotID, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := composeService.Down(ctx, project.Name, composeapi.DownOptions{RemoveOrphans: true}); err != nil {
+		return fmt.Errorf("failed to remove containers: %w", err)
+	}
+
+	apiClient := dockerCli.Client()
+	volumeKeys := map[string]string{}
+	for _, key := range manifest.Volumes {
+		volume, ok := project.Volumes[key]
+		if !ok || bool(volume.External) {
+			logger.Warn("volume of snapshot is not restored because it is not part of the deployment",
+				zap.String("volume", key))
+			continue
+		}
+		volumeKeys[volume.Name] = key
+		if _, err := apiClient.VolumeRemove(ctx, volume.Name, mobyClient.VolumeRemoveOptions{}); err != nil &&
+			!cerrdefs.IsNotFound(err) {
+			return fmt.Errorf("failed to remove volume %v: %w", volume.Name, err)
+		}
+	}
+
+	if err := composeService.Create(ctx, project, composeapi.CreateOptions{RemoveOrphans: true}); err != nil {
+		return fmt.Errorf("failed to create containers: %w", err)
+	}
+
+	mounts, err := getVolumeMounts(ctx, project.Name, volumeKeys)
+	if err != nil {
+		return err
+	}
+	_, err = readVolumeSnapshot(ctx, store, deployment.ID, snapshotID,
+		func(volume string, content io.Reader) error {
+			m, ok := mounts[volume]
+			if !ok {
+				_, err := io.Copy(io.Discard, content)
+				return err
+			}
+			_, err := apiClient.CopyToContainer(ctx, m.ContainerID, mobyClient.CopyToContainerOptions{
+				DestinationPath: m.Destination,
+				Content:         content,
+				CopyUIDGID:      true,
+			})
+			return err
+		},
+	)
+	return err
+}
+
+func readVolumeSnapshot(
+	ctx context.Context,
+	store SnapshotStore,
+	deploymentID uuid.UUID,
+	id uuid.UUID,
+	restore volumesnapshot.RestoreFunc,
+) (*volumesnapshot.Manifest, error) {
+	rc, err := store.Open(ctx, deploymentID, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return volumesnapshot.Read(rc, restore)
+}
+
+// getVolumeMounts returns a container of the project for every volume in volumeKeys, which maps the names of the
+// volumes to their key in the compose file.
+func getVolumeMounts(
+	ctx context.Context,
+	projectName string,
+	volumeKeys map[string]string,
+) (map[string]volumeMount, error) {
+	containers, err := dockerCli.Client().ContainerList(ctx, mobyClient.ContainerListOptions{
+		All:     true,
+		Filters: mobyClient.Filters{}.Add("label", composeapi.ProjectLabel+"="+projectName),
+	})
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]volumeMount{}
+	for _, container := range containers.Items {
+		for _, m := range container.Mounts {
+			if key, ok := volumeKeys[m.Name]; ok && m.Type == mount.TypeVolume {
+				if _, exists := result[key]; !exists {
+					result[key] = volumeMount{ContainerID: container.ID, Destination: m.Destination}
+				}
+			}
+		}
+	}
+	return result, nil
+}
+
+// pruneVolumeSnapshots deletes the oldest snapshots of the deployment beyond retention and reports the remaining
+// ones to the hub. The snapshot that the deployment restores is kept regardless.
+func pruneVolumeSnapshots(ctx context.Context, store SnapshotStore, deployment api.AgentDeployment, retention int) {
+	logger := logger.With(zap.Stringer("deploymentId", deployment.ID))
+	snapshots, err := store.List(ctx, deployment.ID)
+	if err != nil {
+		logger.Warn("failed to list volume snapshots", zap.Error(err))
+		return
+	}
+
+	var kept []StoredSnapshot
+	for _, snapshot := range snapshots {
+		if len(kept) < retention || util.PtrEq(&snapshot.ID, deployment.RestoreVolumeSnapshotID) {
+			kept = append(kept, snapshot)
+		} else if err := store.Delete(ctx, deployment.ID, snapshot.ID); err != nil {
+			logger.Warn("failed to delete volume snapshot", zap.Stringer("snapshotId", snapshot.ID), zap.Error(err))
+			kept = append(kept, snapshot)
+		}
+	}
+
+	report := api.AgentVolumeSnapshots{DeploymentID: deployment.ID, Snapshots: []api.AgentVolumeSnapshot{}}
+	for _, snapshot := range kept {
+		report.Snapshots = append(report.Snapshots, api.AgentVolumeSnapshot{
+			ID:         snapshot.ID,
+			RevisionID: snapshot.RevisionID,
+			CreatedAt:  snapshot.CreatedAt,
+			SizeBytes:  snapshot.SizeBytes,
+			Volumes:    snapshot.Volumes,
+		})
+	}
+	if err := client.VolumeSnapshots(ctx, report); err != nil {
+		logger.Warn("failed to report volume snapshots", zap.Error(err))
+	}
+}
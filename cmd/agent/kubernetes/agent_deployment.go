@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/google/uuid"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	applyconfigurationscorev1 "k8s.io/client-go/applyconfigurations/core/v1"
@@ -23,11 +24,14 @@ const (
 )
 
 type AgentDeployment struct {
-	ID           uuid.UUID `json:"id"`
-	RevisionID   uuid.UUID `json:"revisionId"`
-	ReleaseName  string    `json:"releaseName"`
-	HelmRevision *int      `json:"helmRevision,omitempty"`
-	State        State     `json:"phase"`
+	ID           uuid.UUID                  `json:"id"`
+	RevisionID   uuid.UUID                  `json:"revisionId"`
+	ReleaseName  string                     `json:"releaseName"`
+	SourceType   types.KubernetesSourceType `json:"sourceType,omitempty"`
+	HelmRevision *int                       `json:"helmRevision,omitempty"`
+	State        State                      `json:"phase"`
+	// Inventory references the resources applied for a kustomize deployment, which are not part of a Helm release.
+	Inventory []InventoryEntry `json:"inventory,omitempty"`
 }
 
 func (d AgentDeployment) GetDeploymentID() uuid.UUID {
@@ -38,6 +42,10 @@ func (d AgentDeployment) GetDeploymentRevisionID() uuid.UUID {
 	return d.RevisionID
 }
 
+func (d AgentDeployment) IsKustomize() bool {
+	return d.SourceType == types.KubernetesSourceTypeKustomize
+}
+
 func (d *AgentDeployment) SecretName() string {
 	return fmt.Sprintf("sh.distr.agent.v1.%v", d.ReleaseName)
 }
@@ -47,6 +55,7 @@ func NewAgentDeployment(deployment api.AgentDeployment) AgentDeployment {
 		ReleaseName: deployment.ReleaseName,
 		ID:          deployment.ID,
 		RevisionID:  deployment.RevisionID,
+		SourceType:  deployment.SourceType,
 	}
 }
 
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"go.uber.org/zap"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/kubectl/pkg/polymorphichelpers"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+// nodeCapacity is used for the usage of pods that do not have a limit.
+type nodeCapacity struct {
+	cpuMillis   int64
+	memoryBytes int64
+}
+
+// podMetrics returns the resource usage of the pods of the workloads of all deployments. Pods of deployments whose
+// resources cannot be read are skipped.
+func podMetrics(
+	ctx context.Context,
+	namespace string,
+	nodes map[string]nodeCapacity,
+) ([]api.DeploymentContainerMetric, error) {
+	deployments, err := GetExistingDeployments(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	var result []api.DeploymentContainerMetric
+	for _, deployment := range deployments {
+		metrics, err := deploymentPodMetrics(ctx, namespace, deployment, nodes)
+		if err != nil {
+			logger.Warn("failed to collect pod metrics", zap.Stringer("deploymentId", deployment.ID), zap.Error(err))
+			continue
+		}
+		result = append(result, metrics...)
+	}
+	slices.SortFunc(result, func(a, b api.DeploymentContainerMetric) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return result, nil
+}
+
+func deploymentPodMetrics(
+	ctx context.Context,
+	namespace string,
+	deployment AgentDeployment,
+	nodes map[string]nodeCapacity,
+) ([]api.DeploymentContainerMetric, error) {
+	resources, err := GetDeploymentResources(ctx, namespace, deployment)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []api.DeploymentContainerMetric
+	seen := map[string]struct{}{}
+	for _, obj := range FromUnstructuredSlice(resources) {
+		// services select the same pods as their workloads
+		if _, ok := obj.(*corev1.Service); ok {
+			continue
+		}
+		if metaObj, ok := obj.(metav1.Object); ok && metaObj.GetNamespace() == "" {
+			metaObj.SetNamespace(namespace)
+		}
+		// resources that do not have pods are expected
+		podNamespace, selector, err := polymorphichelpers.SelectorsForObject(obj)
+		if err != nil {
+			continue
+		}
+		workload, _ := obj.(metav1.Object)
+
+		pods, err := k8sClient.CoreV1().Pods(podNamespace).
+			List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+		podMetricsList, err := metricsClientSet.MetricsV1beta1().PodMetricses(podNamespace).
+			List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, pod := range pods.Items {
+			if _, ok := seen[pod.Name]; ok || pod.Status.Phase != corev1.PodRunning {
+				continue
+			}
+			seen[pod.Name] = struct{}{}
+			idx := slices.IndexFunc(podMetricsList.Items, func(m metricsv1beta1.PodMetrics) bool {
+				return m.Name == pod.Name
+			})
+			if idx < 0 {
+				// the metrics server has not scraped the pod yet
+				continue
+			}
+			metric := singlePodMetric(pod, podMetricsList.Items[idx], nodes[pod.Spec.NodeName])
+			metric.DeploymentID = deployment.ID
+			if workload != nil {
+				metric.Service = workload.GetName()
+			}
+			result = append(result, metric)
+		}
+	}
+	return result, nil
+}
+
+func singlePodMetric(
+	pod corev1.Pod,
+	podMetrics metricsv1beta1.PodMetrics,
+	node nodeCapacity,
+) api.DeploymentContainerMetric {
+	metric := api.DeploymentContainerMetric{Name: pod.Name}
+	for _, container := range podMetrics.Containers {
+		metric.CPUUsedMillis += container.Usage.Cpu().MilliValue()
+		metric.MemoryUsedBytes += container.Usage.Memory().Value()
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		metric.Restarts += int64(status.RestartCount)
+	}
+
+	cpuLimit, memoryLimit := node.cpuMillis, node.memoryBytes
+	if limit, ok := podLimit(pod, corev1.ResourceCPU); ok {
+		cpuLimit = limit.MilliValue()
+	}
+	if limit, ok := podLimit(pod, corev1.ResourceMemory); ok {
+		memoryLimit = limit.Value()
+	}
+	if cpuLimit > 0 {
+		metric.CPUUsage = float64(metric.CPUUsedMillis) / float64(cpuLimit)
+	}
+	if memoryLimit > 0 {
+		metric.MemoryUsage = float64(metric.MemoryUsedBytes) / float64(memoryLimit)
+	}
+	return metric
+}
+
+// podLimit returns the sum of the limits of the containers of a pod. A pod is only limited if all of its containers
+// are.
+func podLimit(pod corev1.Pod, name corev1.ResourceName) (resource.Quantity, bool) {
+	var sum resource.Quantity
+	for _, container := range pod.Spec.Containers {
+		if limit, ok := container.Resources.Limits[name]; !ok || limit.IsZero() {
+			return resource.Quantity{}, false
+		} else {
+			sum.Add(limit)
+		}
+	}
+	return sum, len(pod.Spec.Containers) > 0
+}
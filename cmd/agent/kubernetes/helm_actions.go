@@ -3,11 +3,13 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"time"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/agentauth"
 	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agentnet"
 	"github.com/distr-sh/distr/internal/util"
 	slogzap "github.com/samber/slog-zap/v2"
 	"go.uber.org/zap"
@@ -19,6 +21,7 @@ import (
 	"helm.sh/helm/v4/pkg/registry"
 	"helm.sh/helm/v4/pkg/release"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"oras.land/oras-go/v2/registry/remote/retry"
 )
 
 var helmEnvSettings = cli.New()
@@ -32,7 +35,9 @@ func GetHelmActionConfig(
 	cfg.SetLogger(slogzap.Option{Logger: logger.With(zap.String("component", "helm"))}.NewZapHandler())
 
 	if deployment != nil {
-		var clientOpts []registry.ClientOption
+		clientOpts := []registry.ClientOption{
+			registry.ClientOptHTTPClient(&http.Client{Transport: retry.NewTransport(agentnet.Transport())}),
+		}
 
 		if agentenv.DistrRegistryPlainHTTP {
 			clientOpts = append(clientOpts, registry.ClientOptPlainHTTP())
@@ -83,8 +83,8 @@ func (lw *logsWatcher) collect(ctx context.Context) {
 		}
 
 		var resources []runtime.Object
-		if resUnstr, err := GetHelmManifest(ctx, namespace, d.ReleaseName); err != nil {
-			logger.Error("could not get helm manifest for deployment", zap.Error(err))
+		if resUnstr, err := GetDeploymentResources(ctx, namespace, d); err != nil {
+			logger.Error("could not get resources for deployment", zap.Error(err))
 			continue
 		} else {
 			resources = FromUnstructuredSlice(resUnstr)
@@ -18,6 +18,8 @@ import (
 	"github.com/distr-sh/distr/internal/agentcheck"
 	"github.com/distr-sh/distr/internal/agentclient"
 	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agentmetrics"
+	"github.com/distr-sh/distr/internal/agentqueue"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	"github.com/distr-sh/distr/internal/deploymenttargetlogs"
 	"github.com/distr-sh/distr/internal/types"
@@ -56,11 +58,21 @@ var (
 	metricsClientSet = util.Require(metricsv.NewForConfig(util.Require(k8sConfigFlags.ToRESTConfig())))
 	k8sDynamicClient = util.Require(dynamic.NewForConfig(util.Require(k8sConfigFlags.ToRESTConfig())))
 	k8sRestMapper    = util.Require(k8sConfigFlags.ToRESTMapper())
+	metricsCollector = agentmetrics.NewCollector()
 	agentConfigDirs  []string
 )
 
 func init() {
 	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: agentClient}
+	// older manifests have no volume for the queue
+	if dir := os.Getenv("DISTR_QUEUE_DIR"); dir != "" {
+		if queue, err := agentqueue.Open(dir, int64(agentenv.QueueMaxSizeMB)<<20); err != nil {
+			logger.Warn("failed to open queue, requests are lost while the hub cannot be reached", zap.Error(err))
+		} else {
+			agentClient.SetQueue(queue)
+			health.SetQueueStats(queue.Stats)
+		}
+	}
 	if agentenv.AgentVersionID == "" {
 		logger.Warn("AgentVersionID is not set. self updates will be disabled")
 	}
@@ -114,10 +126,12 @@ func main() {
 	metricsGoroutine := util.NewToggleableGoroutine(watchMetrics)
 
 	tick := time.Tick(agentenv.Interval)
+	resources := agentClient.WatchResource(ctx, agentenv.ResourceWait, agentenv.Interval)
 
 	for ctx.Err() == nil {
 		select {
 		case <-tick:
+		case <-resources.Changed():
 		case <-ctx.Done():
 			continue
 		}
@@ -132,7 +146,7 @@ func main() {
 			logger.Debug("agent client config unchanged")
 		}
 
-		res, err := agentClient.Resource(ctx)
+		res, err := resources.Resource(ctx)
 		if err != nil {
 			logger.Error("could not get resource", zap.Error(err))
 			continue
@@ -149,6 +163,7 @@ func main() {
 		logsWatcher.SetNamespace(res.Namespace)
 		logsWatcher.SetLogsAfter(res.DeploymentLogsAfter)
 		logsGoroutine.GoOrCancel(ctx, res.DeploymentLogsEnabled)
+		metricsNamespace.Store(res.Namespace)
 		metricsGoroutine.GoOrCancel(ctx, res.MetricsEnabled)
 
 		existingDeployments, err := GetExistingDeployments(ctx, res.Namespace)
@@ -167,10 +182,12 @@ func main() {
 			)
 			if !resourceHasExistingDeployment {
 				logger.Info("uninstalling orphan deployment", zap.String("id", existing.ID.String()))
-				if err := RunHelmUninstall(ctx, res.Namespace, existing.ReleaseName); err != nil {
+				if err := runUninstall(ctx, res.Namespace, existing); err != nil {
 					logger.Warn("could not uninstall old deployment", zap.Error(err))
 				} else if err := DeleteDeployment(ctx, res.Namespace, existing); err != nil {
 					logger.Warn("could not delete old AgentDeployment resource", zap.Error(err))
+				} else {
+					delete(deploymentProgress, existing.ID)
 				}
 			}
 		}
@@ -188,6 +205,23 @@ func main() {
 					break
 				}
 			}
+			isKustomize := deployment.SourceType == types.KubernetesSourceTypeKustomize
+			if currentDeployment != nil && currentDeployment.IsKustomize() != isKustomize {
+				// The new version is packaged differently, so the resources of the current one are removed first.
+				logger.Info("packaging type changed. uninstalling current deployment")
+				if err := runUninstall(ctx, res.Namespace, *currentDeployment); err != nil {
+					logger.Warn("could not uninstall current deployment", zap.Error(err))
+					pushErrorStatus(ctx, deployment, fmt.Errorf("could not uninstall current deployment: %w", err))
+					continue
+				}
+				currentDeployment = nil
+			}
+
+			if isKustomize {
+				runManifestApply(ctx, res.Namespace, deployment, currentDeployment)
+				continue
+			}
+
 			if err := verifyLatestHelmRelease(ctx, res.Namespace, deployment, currentDeployment); err != nil {
 				if errors.Is(err, driver.ErrReleaseNotFound) {
 					logger.Info("current helm release does not exist")
@@ -279,13 +313,7 @@ func runInstallOrUpgrade(
 ) {
 	progress := Progress(deployment)
 
-	if _, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), deployment); err != nil {
-		logger.Error("failed to ensure docker auth", zap.Error(err))
-		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure docker auth: %w", err))
-	} else if err := ensureImagePullSecret(ctx, namespace, deployment); err != nil {
-		logger.Error("failed to ensure image pull secret", zap.Error(err))
-		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure image pull secret: %w", err))
-	}
+	ensureRegistryAuth(ctx, namespace, deployment)
 
 	if currentDeployment == nil || currentDeployment.HelmRevision == nil {
 		err := progress.Run(ctx, func() error {
@@ -340,27 +368,80 @@ func runInstallOrUpgrade(
 			pushRunningStatus(ctx, deployment, successMessage)
 		}
 	} else {
-		logger.Info("no action required. running status check")
-		if resources, err := GetHelmManifest(ctx, namespace, deployment.ReleaseName); err != nil {
-			logger.Warn("could not get helm manifest", zap.Error(err))
-			pushErrorStatus(ctx, deployment, fmt.Errorf("could not get helm manifest: %w", err))
-		} else {
-			var err error
-			for _, resource := range resources {
-				logger.Sugar().Debugf("check status for %v %v", resource.GetKind(), resource.GetName())
-				if err = CheckStatus(ctx, namespace, resource); err != nil {
-					break
-				}
-			}
+		runStatusCheck(ctx, namespace, deployment, *currentDeployment)
+	}
+}
 
-			if err != nil {
-				logger.Warn("resource status error", zap.Error(err))
-				pushErrorStatus(ctx, deployment, fmt.Errorf("resource status error: %w", err))
-			} else {
-				logger.Info("status check passed")
-				pushHealthyStatus(ctx, deployment, fmt.Sprintf("status check passed. %v resources healthy", len(resources)))
+func runManifestApply(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	currentDeployment *AgentDeployment,
+) {
+	ensureRegistryAuth(ctx, namespace, deployment)
+
+	if currentDeployment == nil ||
+		currentDeployment.RevisionID != deployment.RevisionID ||
+		currentDeployment.State != StateReady {
+		successMessage := "manifest apply succeeded"
+		err := Progress(deployment).Run(ctx, func() error {
+			if updatedDeployment, err := RunManifestApply(ctx, namespace, deployment, currentDeployment); err != nil {
+				return fmt.Errorf("manifest apply failed: %w", err)
+			} else if deployment.ForceRestart && currentDeployment != nil {
+				if err := ForceRestart(ctx, namespace, *updatedDeployment); err != nil {
+					pushErrorStatus(ctx, deployment, fmt.Errorf("%v; force restart error: %w", successMessage, err))
+				} else {
+					successMessage += "; force restart succeeded"
+				}
 			}
+			return nil
+		})
+		if err != nil {
+			logger.Error("apply error", zap.Error(err))
+			pushErrorStatus(ctx, deployment, fmt.Errorf("apply error: %w", err))
+		} else {
+			logger.Info(successMessage)
+			pushRunningStatus(ctx, deployment, successMessage)
 		}
+	} else {
+		runStatusCheck(ctx, namespace, deployment, *currentDeployment)
+	}
+}
+
+func runStatusCheck(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	currentDeployment AgentDeployment,
+) {
+	logger.Info("no action required. running status check")
+	if resources, err := GetDeploymentManifest(ctx, namespace, currentDeployment); err != nil {
+		logger.Warn("could not get manifest", zap.Error(err))
+		pushErrorStatus(ctx, deployment, fmt.Errorf("could not get manifest: %w", err))
+	} else if statusType, message, err := CheckStatus(ctx, namespace, resources, deployment.HealthRules); err != nil {
+		logger.Warn("resource status error", zap.Error(err))
+		pushErrorStatus(ctx, deployment, fmt.Errorf("resource status error: %w", err))
+	} else {
+		statusType, message = EscalateProgressing(deployment, statusType, message, time.Now())
+		logger.Info("status check finished", zap.String("status", string(statusType)), zap.String("message", message))
+		pushStatus(ctx, deployment, statusType, message)
+	}
+}
+
+func runUninstall(ctx context.Context, namespace string, deployment AgentDeployment) error {
+	if deployment.IsKustomize() {
+		return RunManifestUninstall(ctx, deployment)
+	}
+	return RunHelmUninstall(ctx, namespace, deployment.ReleaseName)
+}
+
+func ensureRegistryAuth(ctx context.Context, namespace string, deployment api.AgentDeployment) {
+	if _, err := agentauth.EnsureAuth(ctx, agentClient.RawToken(), deployment); err != nil {
+		logger.Error("failed to ensure docker auth", zap.Error(err))
+		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure docker auth: %w", err))
+	} else if err := ensureImagePullSecret(ctx, namespace, deployment); err != nil {
+		logger.Error("failed to ensure image pull secret", zap.Error(err))
+		pushErrorStatus(ctx, deployment, fmt.Errorf("failed to ensure image pull secret: %w", err))
 	}
 }
 
@@ -393,8 +474,13 @@ func (psr *progressStatusRunner) Run(ctx context.Context, f func() error) error
 	return f()
 }
 
-func pushHealthyStatus(ctx context.Context, deployment api.AgentDeployment, status string) {
-	if err := agentClient.Status(ctx, deployment.RevisionID, types.DeploymentStatusTypeHealthy, status); err != nil {
+func pushStatus(
+	ctx context.Context,
+	deployment api.AgentDeployment,
+	statusType types.DeploymentStatusType,
+	status string,
+) {
+	if err := agentClient.Status(ctx, deployment.RevisionID, statusType, status); err != nil {
 		logger.Warn("status push failed", zap.Error(err))
 	}
 }
@@ -410,7 +496,7 @@ func pushProgressingStatus(ctx context.Context, deployment api.AgentDeployment)
 		ctx,
 		deployment.RevisionID,
 		types.DeploymentStatusTypeProgressing,
-		"helm operation in progress",
+		"operation in progress",
 	); err != nil {
 		logger.Warn("status push failed", zap.Error(err))
 	}
@@ -449,7 +535,10 @@ func ensureImagePullSecret(ctx context.Context, namespace string, deployment api
 }
 
 func startHealthServer() error {
-	err := http.ListenAndServe(":8765", health)
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metricsCollector.Handler())
+	mux.Handle("/", health)
+	err := http.ListenAndServe(":8765", mux)
 	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
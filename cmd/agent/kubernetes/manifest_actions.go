@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/manifestbundle"
+	"github.com/distr-sh/distr/internal/util"
+	"go.uber.org/zap"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+const fieldManager = "distr-agent"
+
+// InventoryEntry references a resource that was applied for a kustomize deployment.
+type InventoryEntry struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name"`
+}
+
+func NewInventoryEntry(obj *unstructured.Unstructured) InventoryEntry {
+	return InventoryEntry{
+		APIVersion: obj.GetAPIVersion(),
+		Kind:       obj.GetKind(),
+		Namespace:  obj.GetNamespace(),
+		Name:       obj.GetName(),
+	}
+}
+
+// Unstructured returns an object that only has the type and metadata of the entry.
+func (e InventoryEntry) Unstructured() *unstructured.Unstructured {
+	var obj unstructured.Unstructured
+	obj.SetAPIVersion(e.APIVersion)
+	obj.SetKind(e.Kind)
+	obj.SetNamespace(e.Namespace)
+	obj.SetName(e.Name)
+	return &obj
+}
+
+// isSameResource ignores the version of the API, so that an object is not pruned if it is only moved to a newer one.
+func (e InventoryEntry) isSameResource(other InventoryEntry) bool {
+	return e.groupKind() == other.groupKind() && e.Namespace == other.Namespace && e.Name == other.Name
+}
+
+func (e InventoryEntry) groupKind() schema.GroupKind {
+	return schema.FromAPIVersionAndKind(e.APIVersion, e.Kind).GroupKind()
+}
+
+// RunManifestApply builds the manifest bundle of a kustomize deployment with the patches of the customer and applies
+// the resulting resources with server-side apply. Resources that are in the inventory of the current deployment but
+// not part of the new one are deleted.
+func RunManifestApply(
+	ctx context.Context,
+	namespace string,
+	deployment api.AgentDeployment,
+	currentDeployment *AgentDeployment,
+) (*AgentDeployment, error) {
+	manifest, err := manifestbundle.Build(deployment.ManifestBundle, deployment.Values)
+	if err != nil {
+		return nil, err
+	}
+	objects, err := DecodeResourceYaml(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode resources: %w", err)
+	}
+
+	var previous []InventoryEntry
+	if currentDeployment != nil {
+		previous = currentDeployment.Inventory
+	}
+
+	agentDeployment := NewAgentDeployment(deployment)
+	agentDeployment.State = StateProgressing
+	agentDeployment.Inventory = previous
+	if err := SaveDeployment(ctx, namespace, agentDeployment); err != nil {
+		logger.Warn("failed to save deployment before apply", zap.Error(err))
+	}
+
+	var applied []InventoryEntry
+	for _, obj := range objects {
+		addImagePullSecretToPodSpec(deployment.ReleaseName, obj)
+		if entry, err := applyResource(ctx, namespace, obj); err != nil {
+			// Everything that was applied so far must be tracked, so that it can be pruned later.
+			agentDeployment.Inventory = mergeInventory(previous, applied)
+			agentDeployment.State = StateFailed
+			if err := SaveDeployment(ctx, namespace, agentDeployment); err != nil {
+				logger.Warn("failed to save deployment after failed apply", zap.Error(err))
+			}
+			return &agentDeployment, fmt.Errorf("could not apply %v %v: %w", obj.GetKind(), obj.GetName(), err)
+		} else {
+			applied = append(applied, entry)
+		}
+	}
+
+	var obsolete []InventoryEntry
+	for _, entry := range previous {
+		if !slices.ContainsFunc(applied, entry.isSameResource) {
+			obsolete = append(obsolete, entry)
+		}
+	}
+	remaining, pruneErr := deleteResources(ctx, obsolete)
+
+	agentDeployment.Inventory = append(applied, remaining...)
+	agentDeployment.State = StateReady
+	if pruneErr != nil {
+		agentDeployment.State = StateFailed
+		pruneErr = fmt.Errorf("could not prune resources: %w", pruneErr)
+	}
+	if err := SaveDeployment(ctx, namespace, agentDeployment); err != nil {
+		logger.Warn("failed to save deployment after apply", zap.Error(err))
+	}
+	return &agentDeployment, pruneErr
+}
+
+// RunManifestUninstall deletes all resources in the inventory of a kustomize deployment.
+func RunManifestUninstall(ctx context.Context, deployment AgentDeployment) error {
+	if _, err := deleteResources(ctx, deployment.Inventory); err != nil {
+		return fmt.Errorf("manifest uninstall failed: %w", err)
+	}
+	return nil
+}
+
+// GetDeploymentManifest returns the resources of a deployment as they were rendered. For kustomize deployments, these
+// are only references to the resources in the inventory.
+func GetDeploymentManifest(
+	ctx context.Context,
+	namespace string,
+	deployment AgentDeployment,
+) ([]*unstructured.Unstructured, error) {
+	if !deployment.IsKustomize() {
+		return GetHelmManifest(ctx, namespace, deployment.ReleaseName)
+	}
+	resources := make([]*unstructured.Unstructured, len(deployment.Inventory))
+	for i, entry := range deployment.Inventory {
+		resources[i] = entry.Unstructured()
+	}
+	return resources, nil
+}
+
+// GetDeploymentResources returns the resources of a deployment including their spec. For kustomize deployments, the
+// resources in the inventory are fetched from the cluster and those that do not exist anymore are skipped.
+func GetDeploymentResources(
+	ctx context.Context,
+	namespace string,
+	deployment AgentDeployment,
+) ([]*unstructured.Unstructured, error) {
+	manifest, err := GetDeploymentManifest(ctx, namespace, deployment)
+	if err != nil || !deployment.IsKustomize() {
+		return manifest, err
+	}
+	resources := make([]*unstructured.Unstructured, 0, len(manifest))
+	for _, resource := range manifest {
+		if live, err := getLiveResource(ctx, namespace, resource); k8serrors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, fmt.Errorf("could not get %v %v: %w", resource.GetKind(), resource.GetName(), err)
+		} else {
+			resources = append(resources, live)
+		}
+	}
+	return resources, nil
+}
+
+func applyResource(ctx context.Context, namespace string, obj *unstructured.Unstructured) (InventoryEntry, error) {
+	mapping, err := getRESTMapping(obj.GroupVersionKind())
+	if err != nil {
+		return InventoryEntry{}, err
+	}
+	var resource dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if obj.GetNamespace() == "" {
+			obj.SetNamespace(namespace)
+		}
+		resource = k8sDynamicClient.Resource(mapping.Resource).Namespace(obj.GetNamespace())
+	} else {
+		obj.SetNamespace("")
+		resource = k8sDynamicClient.Resource(mapping.Resource)
+	}
+	logger.Debug("applying resource",
+		zap.String("resourceNamespace", obj.GetNamespace()),
+		zap.String("resourceKind", obj.GetKind()),
+		zap.String("resourceName", obj.GetName()))
+	if _, err := resource.Apply(ctx, obj.GetName(), obj,
+		metav1.ApplyOptions{FieldManager: fieldManager, Force: true}); err != nil {
+		return InventoryEntry{}, err
+	}
+	return NewInventoryEntry(obj), nil
+}
+
+// deleteResources deletes the resources in reverse order and returns those that could not be deleted.
+func deleteResources(ctx context.Context, entries []InventoryEntry) ([]InventoryEntry, error) {
+	var remaining []InventoryEntry
+	var aggregateErr error
+	for _, entry := range slices.Backward(entries) {
+		if err := deleteResource(ctx, entry); err != nil {
+			remaining = append(remaining, entry)
+			aggregateErr = errors.Join(aggregateErr,
+				fmt.Errorf("could not delete %v %v: %w", entry.Kind, entry.Name, err))
+		}
+	}
+	slices.Reverse(remaining)
+	return remaining, aggregateErr
+}
+
+func deleteResource(ctx context.Context, entry InventoryEntry) error {
+	gvk := schema.FromAPIVersionAndKind(entry.APIVersion, entry.Kind)
+	mapping, err := getRESTMapping(gvk)
+	if meta.IsNoMatchError(err) {
+		// the API of the resource does not exist anymore, e.g. because its CRD was deleted
+		return nil
+	} else if err != nil {
+		return err
+	}
+	var resource dynamic.ResourceInterface
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		resource = k8sDynamicClient.Resource(mapping.Resource).Namespace(entry.Namespace)
+	} else {
+		resource = k8sDynamicClient.Resource(mapping.Resource)
+	}
+	logger.Debug("deleting resource",
+		zap.String("resourceNamespace", entry.Namespace),
+		zap.String("resourceKind", entry.Kind),
+		zap.String("resourceName", entry.Name))
+	err = resource.Delete(ctx, entry.Name,
+		metav1.DeleteOptions{PropagationPolicy: util.PtrTo(metav1.DeletePropagationBackground)})
+	if k8serrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// getRESTMapping resets the cached discovery information if the kind is not found, because it might have been
+// created by a CustomResourceDefinition that was applied just before.
+func getRESTMapping(gvk schema.GroupVersionKind) (*meta.RESTMapping, error) {
+	mapping, err := k8sRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if meta.IsNoMatchError(err) {
+		meta.MaybeResetRESTMapper(k8sRestMapper)
+		mapping, err = k8sRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	return mapping, err
+}
+
+func mergeInventory(entries []InventoryEntry, added []InventoryEntry) []InventoryEntry {
+	result := slices.Clone(entries)
+	for _, entry := range added {
+		if !slices.ContainsFunc(result, entry.isSameResource) {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+// addImagePullSecretToPodSpec adds the pull secret of the deployment to the pod template of workload resources.
+func addImagePullSecretToPodSpec(releaseName string, obj *unstructured.Unstructured) {
+	var podSpecPath []string
+	switch obj.GroupVersionKind().GroupKind() {
+	case schema.GroupKind{Kind: "Pod"}:
+		podSpecPath = []string{"spec"}
+	case schema.GroupKind{Group: "apps", Kind: "Deployment"},
+		schema.GroupKind{Group: "apps", Kind: "StatefulSet"},
+		schema.GroupKind{Group: "apps", Kind: "DaemonSet"},
+		schema.GroupKind{Group: "apps", Kind: "ReplicaSet"},
+		schema.GroupKind{Group: "batch", Kind: "Job"}:
+		podSpecPath = []string{"spec", "template", "spec"}
+	case schema.GroupKind{Group: "batch", Kind: "CronJob"}:
+		podSpecPath = []string{"spec", "jobTemplate", "spec", "template", "spec"}
+	default:
+		return
+	}
+	name := PullSecretName(releaseName)
+	secrets, _, _ := unstructured.NestedSlice(obj.Object, append(podSpecPath, "imagePullSecrets")...)
+	for _, secret := range secrets {
+		if m, ok := secret.(map[string]any); ok && m["name"] == name {
+			return
+		}
+	}
+	secrets = append(secrets, map[string]any{"name": name})
+	if err := unstructured.SetNestedSlice(obj.Object, secrets, append(podSpecPath, "imagePullSecrets")...); err != nil {
+		logger.Warn("could not add image pull secret", zap.String("resourceName", obj.GetName()), zap.Error(err))
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 
 	"github.com/distr-sh/distr/api"
@@ -9,6 +10,9 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+// metricsNamespace is the namespace of the deployments whose pods are included in the metrics.
+var metricsNamespace atomic.Value
+
 func watchMetrics(ctx context.Context) {
 	logger.Info("starting metrics watch")
 	tick := time.Tick(30 * time.Second)
@@ -28,6 +32,7 @@ func doReportMetrics(ctx context.Context) {
 	var cpuUsageM int64
 	var memoryCapacityBytes int64
 	var memoryUsageBytes int64
+	nodeCapacities := map[string]nodeCapacity{}
 	if nodes, err := k8sClient.CoreV1().Nodes().List(ctx, metav1.ListOptions{}); err != nil {
 		logger.Error("getting nodes failed", zap.Error(err))
 		return
@@ -36,6 +41,10 @@ func doReportMetrics(ctx context.Context) {
 			logger.Info("node", zap.String("name", node.Name))
 			cpuCapacityM += node.Status.Capacity.Cpu().MilliValue()
 			memoryCapacityBytes += node.Status.Capacity.Memory().Value()
+			nodeCapacities[node.Name] = nodeCapacity{
+				cpuMillis:   node.Status.Capacity.Cpu().MilliValue(),
+				memoryBytes: node.Status.Capacity.Memory().Value(),
+			}
 
 			if nodeMetrics, err := metricsClientSet.MetricsV1beta1().NodeMetricses().
 				Get(ctx, node.Name, metav1.GetOptions{}); err != nil {
@@ -56,12 +65,21 @@ func doReportMetrics(ctx context.Context) {
 		zap.Any("memUsageSum", memoryUsageBytes))
 
 	if cpuCapacityM > 0 && memoryCapacityBytes > 0 {
-		if err := agentClient.ReportMetrics(ctx, api.AgentDeploymentTargetMetricsRequest{
+		reportMetrics := api.AgentDeploymentTargetMetricsRequest{
 			CPUCoresMillis: cpuCapacityM,
 			CPUUsage:       float64(cpuUsageM) / float64(cpuCapacityM),
 			MemoryBytes:    memoryCapacityBytes,
 			MemoryUsage:    float64(memoryUsageBytes) / float64(memoryCapacityBytes),
-		}); err != nil {
+		}
+		if namespace, ok := metricsNamespace.Load().(string); ok {
+			if cm, err := podMetrics(ctx, namespace, nodeCapacities); err != nil {
+				logger.Warn("failed to collect pod metrics", zap.Error(err))
+			} else {
+				reportMetrics.ContainerMetrics = cm
+			}
+		}
+		metricsCollector.Update(reportMetrics)
+		if err := agentClient.ReportMetrics(ctx, reportMetrics); err != nil {
 			logger.Error("failed to report metrics", zap.Error(err))
 		}
 	}
@@ -2,43 +2,167 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/healthrule"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/fluxcd/cli-utils/pkg/kstatus/status"
+	"github.com/google/uuid"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/dynamic"
 )
 
-func CheckStatus(ctx context.Context, namespace string, resource *unstructured.Unstructured) error {
-	switch resource.GetKind() {
-	case "Deployment":
-		if deployment, err := k8sClient.AppsV1().Deployments(namespace).
-			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
-			return err
-		} else if deployment.Status.ReadyReplicas < *deployment.Spec.Replicas {
-			return ReplicasError(resource, deployment.Status.ReadyReplicas, *deployment.Spec.Replicas)
+// progressDeadline is how long the resources of a revision may be in progress before the deployment is reported as
+// failed, like the default progressDeadlineSeconds of a Kubernetes Deployment.
+const progressDeadline = 10 * time.Minute
+
+// deploymentProgress remembers for each deployment since when the resources of its current revision are in progress
+// and whether they were healthy before. It is only used by the main loop.
+var deploymentProgress = map[uuid.UUID]*progressState{}
+
+type progressState struct {
+	revisionID uuid.UUID
+	since      time.Time
+	wasHealthy bool
+}
+
+// EscalateProgressing reports a progressing deployment as failed if its resources were all healthy before or have
+// not become healthy within progressDeadline. Otherwise a workload that can never become ready, like one with an
+// image that can not be pulled, would be reported as progressing forever.
+func EscalateProgressing(
+	deployment api.AgentDeployment,
+	statusType types.DeploymentStatusType,
+	message string,
+	now time.Time,
+) (types.DeploymentStatusType, string) {
+	state := deploymentProgress[deployment.ID]
+	if state == nil || state.revisionID != deployment.RevisionID {
+		state = &progressState{revisionID: deployment.RevisionID}
+		deploymentProgress[deployment.ID] = state
+	}
+	if statusType != types.DeploymentStatusTypeProgressing {
+		state.since = time.Time{}
+		state.wasHealthy = state.wasHealthy || statusType == types.DeploymentStatusTypeHealthy
+		return statusType, message
+	}
+	if state.since.IsZero() {
+		state.since = now
+	}
+	if state.wasHealthy {
+		return types.DeploymentStatusTypeError, message + " (resources were healthy before)"
+	} else if now.Sub(state.since) > progressDeadline {
+		return types.DeploymentStatusTypeError, fmt.Sprintf("%v (not ready after %v)", message, progressDeadline)
+	}
+	return statusType, message
+}
+
+// CheckStatus computes the status of the resources of a release from their observedGeneration and conditions,
+// following the conventions of kstatus. Resources of a kind with health rules are checked by these rules instead, for
+// example an Ingress, which kstatus considers ready as soon as it exists, can be required to have an address with
+// the rule {.status.loadBalancer.ingress}.
+// The message names the first resource that failed or, if none did, the first one that is not ready yet.
+func CheckStatus(
+	ctx context.Context,
+	namespace string,
+	resources []*unstructured.Unstructured,
+	rules []api.AgentHealthRule,
+) (types.DeploymentStatusType, string, error) {
+	var progressingMessage string
+	for _, resource := range resources {
+		logger.Sugar().Debugf("check status for %v %v", resource.GetKind(), resource.GetName())
+		live, err := getLiveResource(ctx, namespace, resource)
+		if k8serrors.IsNotFound(err) {
+			return types.DeploymentStatusTypeError, ResourceStatusMessage(resource, "resource not found"), nil
+		} else if err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("could not get %v %v: %w",
+				resource.GetKind(), resource.GetName(), err)
+		}
+		resourceStatus, message, err := computeResourceStatus(live, rules)
+		if err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("could not compute status of %v %v: %w",
+				resource.GetKind(), resource.GetName(), err)
+		}
+		switch resourceStatus {
+		case status.CurrentStatus:
+		case status.InProgressStatus, status.TerminatingStatus:
+			if progressingMessage == "" {
+				progressingMessage = ResourceStatusMessage(resource, message)
+			}
+		default:
+			return types.DeploymentStatusTypeError, ResourceStatusMessage(resource, message), nil
 		}
-	case "StatefulSet":
-		if statefulSet, err := k8sClient.AppsV1().StatefulSets(namespace).
-			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
-			return err
-		} else if statefulSet.Status.ReadyReplicas < *statefulSet.Spec.Replicas {
-			return ReplicasError(resource, statefulSet.Status.ReadyReplicas, *statefulSet.Spec.Replicas)
+	}
+	if progressingMessage != "" {
+		return types.DeploymentStatusTypeProgressing, progressingMessage, nil
+	}
+	return types.DeploymentStatusTypeHealthy,
+		fmt.Sprintf("status check passed. %v resources healthy", len(resources)), nil
+}
+
+func computeResourceStatus(
+	resource *unstructured.Unstructured,
+	rules []api.AgentHealthRule,
+) (status.Status, string, error) {
+	matching := matchingHealthRules(resource, rules)
+	if len(matching) == 0 {
+		if result, err := status.Compute(resource); err != nil {
+			return status.UnknownStatus, "", err
+		} else {
+			return result.Status, result.Message, nil
+		}
+	}
+	for _, rule := range matching {
+		err := healthrule.Check(rule.JSONPath, rule.Value, resource.Object)
+		if errors.Is(err, healthrule.ErrNotSatisfied) {
+			return status.InProgressStatus, err.Error(), nil
+		} else if err != nil {
+			return status.UnknownStatus, "", err
 		}
-	case "DaemonSet":
-		if daemonSet, err := k8sClient.AppsV1().DaemonSets(namespace).
-			Get(ctx, resource.GetName(), metav1.GetOptions{}); err != nil {
-			return err
-		} else if daemonSet.Status.NumberUnavailable > 0 {
-			return ReplicasError(resource, daemonSet.Status.NumberReady, daemonSet.Status.DesiredNumberScheduled)
+	}
+	return status.CurrentStatus, "", nil
+}
+
+// matchingHealthRules returns the rules for the kind of the resource, which is either given by its name only or
+// prefixed by the API group.
+func matchingHealthRules(resource *unstructured.Unstructured, rules []api.AgentHealthRule) []api.AgentHealthRule {
+	gvk := resource.GroupVersionKind()
+	var result []api.AgentHealthRule
+	for _, rule := range rules {
+		if rule.Kind == gvk.Kind || gvk.Group != "" && rule.Kind == gvk.Group+"/"+gvk.Kind {
+			result = append(result, rule)
 		}
 	}
-	return nil
+	return result
 }
 
-func ReplicasError(resource *unstructured.Unstructured, ready, desired int32) error {
-	return ResourceStatusError(resource, fmt.Sprintf("ReadyReplicas (%v) is less than desired (%v)", ready, desired))
+func getLiveResource(
+	ctx context.Context,
+	namespace string,
+	resource *unstructured.Unstructured,
+) (*unstructured.Unstructured, error) {
+	gvk := resource.GroupVersionKind()
+	mapping, err := k8sRestMapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, err
+	}
+	var client dynamic.ResourceInterface
+	if mapping.Scope == meta.RESTScopeNamespace {
+		if resource.GetNamespace() != "" {
+			namespace = resource.GetNamespace()
+		}
+		client = k8sDynamicClient.Resource(mapping.Resource).Namespace(namespace)
+	} else {
+		client = k8sDynamicClient.Resource(mapping.Resource)
+	}
+	return client.Get(ctx, resource.GetName(), metav1.GetOptions{})
 }
 
-func ResourceStatusError(resource *unstructured.Unstructured, msg string) error {
-	return fmt.Errorf("%v %v status check failed: %v", resource.GetKind(), resource.GetName(), msg)
+func ResourceStatusMessage(resource *unstructured.Unstructured, msg string) string {
+	return fmt.Sprintf("%v %v: %v", resource.GetKind(), resource.GetName(), msg)
 }
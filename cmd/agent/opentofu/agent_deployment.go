@@ -0,0 +1,81 @@
+package main
+
+import (
+	"path"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agenthost"
+	"github.com/google/uuid"
+)
+
+type State string
+
+const (
+	StateUnspecified State = ""
+	StateProgressing State = "progressing"
+	// StatePlanned means that the plan of the revision is waiting for approval.
+	StatePlanned State = "planned"
+	StateReady   State = "ready"
+	StateFailed  State = "failed"
+)
+
+type AgentDeployment struct {
+	ID          uuid.UUID `json:"id"`
+	RevisionID  uuid.UUID `json:"revisionId"`
+	ReleaseName string    `json:"releaseName"`
+	State       State     `json:"phase"`
+	PlanDigest  string    `json:"planDigest,omitempty"`
+	HasChanges  bool      `json:"hasChanges,omitempty"`
+	// OutputsReported is set once the outputs of the applied revision were sent to the hub.
+	OutputsReported bool   `json:"outputsReported,omitempty"`
+	Error           string `json:"error,omitempty"`
+	// UpdatedAt is set when the deployment is saved.
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func (d AgentDeployment) GetDeploymentID() uuid.UUID {
+	return d.ID
+}
+
+func (d AgentDeployment) GetDeploymentRevisionID() uuid.UUID {
+	return d.RevisionID
+}
+
+// Dir contains a directory with the module of every revision that was not cleaned up yet.
+func (d *AgentDeployment) Dir() string {
+	return path.Join(ModulesDir(), d.ReleaseName)
+}
+
+// RevisionDir is the working directory of the revision, which contains the module, its variables and the plan.
+func (d *AgentDeployment) RevisionDir() string {
+	return path.Join(d.Dir(), d.RevisionID.String())
+}
+
+// Workspace keeps the state of every deployment separate, even if they share the same backend.
+func (d *AgentDeployment) Workspace() string {
+	return d.ReleaseName
+}
+
+func NewAgentDeployment(deployment api.AgentDeployment) AgentDeployment {
+	return AgentDeployment{
+		ID:          deployment.ID,
+		RevisionID:  deployment.RevisionID,
+		ReleaseName: deployment.ReleaseName,
+	}
+}
+
+var deploymentStore agenthost.DeploymentStore[AgentDeployment]
+
+func GetExistingDeployments() (map[uuid.UUID]AgentDeployment, error) {
+	return deploymentStore.GetAll()
+}
+
+func SaveDeployment(deployment AgentDeployment) error {
+	deployment.UpdatedAt = time.Now()
+	return deploymentStore.Save(deployment)
+}
+
+func DeleteDeployment(deployment AgentDeployment) error {
+	return deploymentStore.Delete(deployment)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path"
+	"strconv"
+
+	"github.com/distr-sh/distr/internal/agenthost"
+)
+
+// ModulesDir holds a directory for every deployment with the modules of its revisions.
+func ModulesDir() string {
+	if dir := os.Getenv("DISTR_TOFU_MODULES_DIR"); dir != "" {
+		return dir
+	}
+	return path.Join(agenthost.ScratchDir(), "modules")
+}
+
+// TofuBinary is the command that is run, which can also be terraform.
+func TofuBinary() string {
+	if binary := os.Getenv("DISTR_TOFU_BINARY"); binary != "" {
+		return binary
+	}
+	return "tofu"
+}
+
+// BackendType is the type of the backend that stores the state, e.g. s3, gcs, azurerm or pg. It replaces the
+// backend that the module declares, if any.
+func BackendType() string {
+	if backend := os.Getenv("DISTR_TOFU_BACKEND"); backend != "" {
+		return backend
+	}
+	return "local"
+}
+
+// BackendConfigFile is an optional file with the configuration of the backend, e.g. the bucket of an s3 backend.
+func BackendConfigFile() string {
+	return os.Getenv("DISTR_TOFU_BACKEND_CONFIG")
+}
+
+// StateDir holds the state of the local backend, if it is used.
+func StateDir() string {
+	return path.Join(agenthost.ScratchDir(), "state")
+}
+
+// DestroyOnDelete enables destroying the infrastructure of a deployment when it is deleted. It is disabled by
+// default, because a destroy is not approved by anyone.
+func DestroyOnDelete() bool {
+	value, _ := strconv.ParseBool(os.Getenv("DISTR_TOFU_DESTROY_ON_DELETE"))
+	return value
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentauth"
+	"github.com/distr-sh/distr/internal/agentcheck"
+	"github.com/distr-sh/distr/internal/agentclient"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agenthost"
+	"github.com/distr-sh/distr/internal/buildconfig"
+	"github.com/distr-sh/distr/internal/deploymenttargetlogs"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	platformLoggingCore = &deploymenttargetlogs.Core{Encoder: zapcore.NewConsoleEncoder(func() zapcore.EncoderConfig {
+		cfg := zap.NewDevelopmentEncoderConfig()
+		cfg.TimeKey = ""
+		cfg.LevelKey = ""
+		return cfg
+	}())}
+	logger = util.Require(zap.NewDevelopment(
+		zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			// Platform logging should use the same logging level as the base core
+			platformLoggingCore.LevelEnabler = c
+			return zapcore.NewTee(c, platformLoggingCore)
+		}),
+	))
+	client     = util.Require(agentclient.NewFromEnv(logger))
+	health     = agentcheck.NewServer(time.Hour)
+	selfUpdate = agenthost.SelfUpdate{Logger: logger, Client: client}
+)
+
+func init() {
+	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: client}
+	if agentenv.AgentVersionID == "" {
+		logger.Warn("AgentVersionID is not set. self updates will be disabled")
+	}
+}
+
+func main() {
+	defer func() {
+		if err := logger.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+			fmt.Println(err)
+		}
+	}()
+
+	defer func() {
+		if reason := recover(); reason != nil {
+			logger.Panic("agent panic", zap.Any("reason", reason))
+		}
+	}()
+
+	ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+
+	context.AfterFunc(ctx, func() { logger.Info("shutdown signal received") })
+
+	logger.Info("opentofu agent is starting",
+		zap.String("version", buildconfig.Version()),
+		zap.String("commit", buildconfig.Commit()),
+		zap.Bool("release", buildconfig.IsRelease()))
+
+	go func() {
+		if err := agenthost.StartHealthServer(health); err != nil {
+			logger.Warn("health server error", zap.Error(err))
+		}
+	}()
+
+	mainLoop(ctx)
+
+	logger.Info("shutting down")
+}
+
+func mainLoop(ctx context.Context) {
+	tick := time.Tick(agentenv.Interval)
+	resources := client.WatchResource(ctx, agentenv.ResourceWait, agentenv.Interval)
+
+loop:
+	for ctx.Err() == nil {
+		select {
+		case <-tick:
+		case <-resources.Changed():
+		case <-ctx.Done():
+			break loop
+		}
+
+		health.Heartbeat()
+
+		resource, err := resources.Resource(ctx)
+		if err != nil {
+			logger.Error("failed to get resource", zap.Error(err))
+			continue
+		}
+
+		if agenthost.SelfUpdateIfRequired(ctx, selfUpdate, *resource) {
+			continue
+		}
+
+		deployments, err := GetExistingDeployments()
+		if err != nil {
+			logger.Error("could not get existing deployments", zap.Error(err))
+			continue
+		}
+		cleanupOldDeployments(ctx, *resource, deployments)
+
+		if len(resource.Deployments) == 0 {
+			logger.Info("no deployment in resource response")
+			continue
+		}
+
+		for _, deployment := range resource.Deployments {
+			var agentDeployment *AgentDeployment
+			if existing, ok := deployments[deployment.ID]; ok {
+				agentDeployment = &existing
+			}
+			statusType, status, err := runDeployment(ctx, deployment, agentDeployment)
+			if err != nil {
+				err = client.StatusWithError(ctx, deployment.RevisionID, err)
+			} else {
+				err = client.Status(ctx, deployment.RevisionID, statusType, status)
+			}
+			if err != nil {
+				logger.Error("failed to send status", zap.Error(err))
+			}
+		}
+	}
+}
+
+// failedRetryInterval is how long a failed deployment is left alone before it is planned again. Every plan has to
+// be approved again, so it is not repeated in every loop.
+const failedRetryInterval = 5 * time.Minute
+
+// runDeployment creates a plan for a revision that was not planned yet, applies the plan once it was approved and
+// reports the outputs of the module after it was applied.
+func runDeployment(
+	ctx context.Context,
+	deployment api.AgentDeployment,
+	agentDeployment *AgentDeployment,
+) (types.DeploymentStatusType, string, error) {
+	progressCtx, progressCancel := context.WithCancel(ctx)
+	defer progressCancel()
+
+	if agentDeployment != nil && agentDeployment.RevisionID == deployment.RevisionID &&
+		agentDeployment.State == StateFailed && time.Since(agentDeployment.UpdatedAt) < failedRetryInterval {
+		return types.DeploymentStatusTypeError, agentDeployment.Error, nil
+	}
+
+	if agentDeployment == nil ||
+		agentDeployment.RevisionID != deployment.RevisionID ||
+		(agentDeployment.State != StatePlanned && agentDeployment.State != StateReady) {
+		authClient, err := agentauth.EnsureAuth(ctx, client.RawToken(), deployment)
+		if err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("registry auth error: %w", err)
+		}
+		updateStatus := agenthost.SendProgressInterval(progressCtx, logger, client, deployment.RevisionID)
+		if agentDeployment, err = Plan(ctx, authClient, deployment, updateStatus); err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("plan failed: %w", err)
+		}
+	}
+
+	if agentDeployment.State == StatePlanned {
+		if agentDeployment.HasChanges && deployment.ApprovedPlanDigest != agentDeployment.PlanDigest {
+			// The plan is sent again in every loop, because the hub ignores a plan that it already has.
+			if plan, err := PlanRequest(*agentDeployment); err != nil {
+				return types.DeploymentStatusTypeError, "", fmt.Errorf("could not read plan: %w", err)
+			} else if err := client.Plan(ctx, *plan); err != nil {
+				return types.DeploymentStatusTypeError, "", fmt.Errorf("could not send plan: %w", err)
+			}
+			return types.DeploymentStatusTypeProgressing, "waiting for approval of the plan", nil
+		}
+
+		updateStatus := agenthost.SendProgressInterval(progressCtx, logger, client, deployment.RevisionID)
+		var err error
+		if agentDeployment, err = Apply(ctx, *agentDeployment, updateStatus); err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("apply failed: %w", err)
+		}
+	}
+
+	if !agentDeployment.OutputsReported {
+		if outputs, err := Outputs(ctx, *agentDeployment); err != nil {
+			logger.Warn("could not get outputs", zap.Error(err))
+		} else if err := client.Metadata(ctx, api.AgentDeploymentMetadata{
+			RevisionID: agentDeployment.RevisionID,
+			Outputs:    outputs,
+		}); err != nil {
+			logger.Warn("could not send outputs", zap.Error(err))
+		} else {
+			agentDeployment.OutputsReported = true
+			if err := SaveDeployment(*agentDeployment); err != nil {
+				logger.Warn("failed to save deployment after sending outputs", zap.Error(err))
+			}
+		}
+	}
+
+	return types.DeploymentStatusTypeHealthy, "plan was applied", nil
+}
+
+func cleanupOldDeployments(ctx context.Context, resource api.AgentResource, deployments map[uuid.UUID]AgentDeployment) {
+	for _, deployment := range deployments {
+		resourceHasExistingDeployment := slices.ContainsFunc(
+			resource.Deployments,
+			func(d api.AgentDeployment) bool { return d.ID == deployment.ID },
+		)
+		if !resourceHasExistingDeployment {
+			logger.Info("uninstalling old deployment", zap.String("id", deployment.ID.String()))
+			if err := Uninstall(ctx, deployment); err != nil {
+				logger.Warn("could not uninstall deployment", zap.Error(err))
+			} else if err := DeleteDeployment(deployment); err != nil {
+				logger.Warn("could not delete deployment", zap.Error(err))
+			}
+		}
+	}
+}
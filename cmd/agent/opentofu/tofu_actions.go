@@ -0,0 +1,289 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentpackage"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	planFile            = "distr.tfplan"
+	planOutputFile      = "distr.tfplan.txt"
+	varsFile            = "distr.auto.tfvars.json"
+	backendOverrideFile = "distr_backend_override.tf"
+	// maxPlanOutputSize keeps the plan that is sent to the hub at a size that can be reviewed in a browser.
+	maxPlanOutputSize = 1 << 20
+	// planExitCodeChanges is the exit code of plan with -detailed-exitcode if there are changes.
+	planExitCodeChanges = 2
+)
+
+// Plan pulls the module of the deployment, initializes it with the backend of the customer and creates a plan. The
+// plan is kept in the revision directory until it is approved and applied.
+func Plan(
+	ctx context.Context,
+	authClient *auth.Client,
+	deployment api.AgentDeployment,
+	progress func(string),
+) (*AgentDeployment, error) {
+	agentDeployment := NewAgentDeployment(deployment)
+	agentDeployment.State = StateProgressing
+	if err := SaveDeployment(agentDeployment); err != nil {
+		logger.Warn("failed to save deployment before plan", zap.Error(err))
+	}
+
+	if err := plan(ctx, authClient, deployment, &agentDeployment, progress); err != nil {
+		agentDeployment.State = StateFailed
+		agentDeployment.Error = err.Error()
+		if err := SaveDeployment(agentDeployment); err != nil {
+			logger.Warn("failed to save deployment after failed plan", zap.Error(err))
+		}
+		return &agentDeployment, err
+	}
+
+	agentDeployment.State = StatePlanned
+	if err := SaveDeployment(agentDeployment); err != nil {
+		logger.Warn("failed to save deployment after plan", zap.Error(err))
+	}
+	return &agentDeployment, nil
+}
+
+func plan(
+	ctx context.Context,
+	authClient *auth.Client,
+	deployment api.AgentDeployment,
+	agentDeployment *AgentDeployment,
+	progress func(string),
+) error {
+	dir := agentDeployment.RevisionDir()
+	if err := os.RemoveAll(dir); err != nil {
+		return err
+	} else if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	progress("pulling module")
+	if err := agentpackage.Pull(ctx, logger, authClient, deployment.PackageRef, dir); err != nil {
+		return err
+	}
+
+	values := deployment.Values
+	if values == nil {
+		values = map[string]any{}
+	}
+	if data, err := json.Marshal(values); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(dir, varsFile), data, 0o600); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(dir, backendOverrideFile), backendOverride(), 0o600); err != nil {
+		return err
+	}
+
+	progress("initializing module")
+	if _, err := tofu(ctx, dir, append([]string{"init", "-input=false", "-no-color", "-reconfigure"},
+		backendConfigArgs()...)...); err != nil {
+		return err
+	} else if _, err := tofu(ctx, dir, "workspace", "select", "-or-create", agentDeployment.Workspace()); err != nil {
+		return err
+	}
+
+	progress("creating plan")
+	_, err := tofu(ctx, dir, "plan", "-input=false", "-no-color", "-detailed-exitcode", "-out="+planFile)
+	if exitErr, ok := errors.AsType[*exec.ExitError](err); ok && exitErr.ExitCode() == planExitCodeChanges {
+		agentDeployment.HasChanges = true
+	} else if err != nil {
+		return err
+	}
+
+	if output, err := tofu(ctx, dir, "show", "-no-color", planFile); err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(dir, planOutputFile), []byte(output), 0o600); err != nil {
+		return err
+	} else if digest, err := fileDigest(path.Join(dir, planFile)); err != nil {
+		return err
+	} else {
+		agentDeployment.PlanDigest = digest
+		return nil
+	}
+}
+
+// PlanRequest returns the plan of the deployment in the form that is sent to the hub for approval.
+func PlanRequest(deployment AgentDeployment) (*api.AgentDeploymentPlan, error) {
+	output, err := os.ReadFile(path.Join(deployment.RevisionDir(), planOutputFile))
+	if err != nil {
+		return nil, err
+	}
+	if len(output) > maxPlanOutputSize {
+		output = append(output[:maxPlanOutputSize], "\n\n[plan output truncated]\n"...)
+	}
+	return &api.AgentDeploymentPlan{
+		RevisionID: deployment.RevisionID,
+		Digest:     deployment.PlanDigest,
+		HasChanges: deployment.HasChanges,
+		Output:     string(output),
+	}, nil
+}
+
+// Apply applies the plan of the deployment, which must have been approved before.
+func Apply(ctx context.Context, deployment AgentDeployment, progress func(string)) (*AgentDeployment, error) {
+	dir := deployment.RevisionDir()
+	if digest, err := fileDigest(path.Join(dir, planFile)); err != nil {
+		return nil, err
+	} else if digest != deployment.PlanDigest {
+		return nil, errors.New("plan file has changed after it was approved")
+	}
+
+	deployment.State = StateProgressing
+	if err := SaveDeployment(deployment); err != nil {
+		logger.Warn("failed to save deployment before apply", zap.Error(err))
+	}
+
+	progress("applying plan")
+	if _, err := tofu(ctx, dir, "apply", "-input=false", "-no-color", planFile); err != nil {
+		// A plan can only be applied once, so the next attempt has to start with a new plan.
+		deployment.State = StateFailed
+		deployment.Error = err.Error()
+		if err := SaveDeployment(deployment); err != nil {
+			logger.Warn("failed to save deployment after failed apply", zap.Error(err))
+		}
+		return &deployment, err
+	}
+
+	deployment.State = StateReady
+	deployment.Error = ""
+	if err := SaveDeployment(deployment); err != nil {
+		logger.Warn("failed to save deployment after apply", zap.Error(err))
+	}
+
+	// The plan contains the values of all variables, including secrets, and is not needed anymore.
+	if err := os.Remove(path.Join(dir, planFile)); err != nil {
+		logger.Warn("could not delete plan", zap.Error(err))
+	}
+	if err := pruneRevisions(deployment); err != nil {
+		logger.Warn("could not delete old revisions", zap.Error(err))
+	}
+	return &deployment, nil
+}
+
+// Outputs returns the outputs of the module of the deployment. The values of sensitive outputs are omitted.
+func Outputs(ctx context.Context, deployment AgentDeployment) (map[string]types.DeploymentOutput, error) {
+	out, err := tofu(ctx, deployment.RevisionDir(), "output", "-json", "-no-color")
+	if err != nil {
+		return nil, err
+	}
+	var outputs map[string]struct {
+		Sensitive bool `json:"sensitive"`
+		Value     any  `json:"value"`
+	}
+	if err := json.Unmarshal([]byte(out), &outputs); err != nil {
+		return nil, fmt.Errorf("could not parse outputs: %w", err)
+	}
+	result := make(map[string]types.DeploymentOutput, len(outputs))
+	for name, output := range outputs {
+		if output.Sensitive {
+			result[name] = types.DeploymentOutput{Sensitive: true}
+		} else {
+			result[name] = types.DeploymentOutput{Value: output.Value}
+		}
+	}
+	return result, nil
+}
+
+// Uninstall deletes the working directory of the deployment. The infrastructure is only destroyed if that is enabled
+// with DestroyOnDelete.
+func Uninstall(ctx context.Context, deployment AgentDeployment) error {
+	if DestroyOnDelete() {
+		if _, err := os.Stat(deployment.RevisionDir()); err != nil {
+			return fmt.Errorf("can not destroy deployment without its module: %w", err)
+		} else if _, err := tofu(ctx, deployment.RevisionDir(),
+			"destroy", "-input=false", "-no-color", "-auto-approve"); err != nil {
+			return err
+		}
+	}
+	return os.RemoveAll(deployment.Dir())
+}
+
+func backendOverride() []byte {
+	return fmt.Appendf(nil, "terraform {\n  backend %q {}\n}\n", BackendType())
+}
+
+func backendConfigArgs() []string {
+	var args []string
+	if BackendType() == "local" {
+		// The revision directories are deleted, so the state must be kept somewhere else.
+		args = append(args,
+			"-backend-config=path="+path.Join(StateDir(), "terraform.tfstate"),
+			"-backend-config=workspace_dir="+StateDir())
+	}
+	if file := BackendConfigFile(); file != "" {
+		args = append(args, "-backend-config="+file)
+	}
+	return args
+}
+
+// pruneRevisions deletes the directories of all revisions except the one of the deployment.
+func pruneRevisions(deployment AgentDeployment) error {
+	entries, err := os.ReadDir(deployment.Dir())
+	if err != nil {
+		return err
+	}
+	var aggregateErr error
+	for _, entry := range entries {
+		if entry.Name() != deployment.RevisionID.String() {
+			aggregateErr = errors.Join(aggregateErr, os.RemoveAll(path.Join(deployment.Dir(), entry.Name())))
+		}
+	}
+	return aggregateErr
+}
+
+func fileDigest(name string) (string, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = file.Close() }()
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// tofu runs a command in dir and returns its output. If it fails, the error contains the end of the error output.
+func tofu(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, TofuBinary(), args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "TF_IN_AUTOMATION=1", "TF_INPUT=0")
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	logger.Debug("running tofu", zap.Strings("args", args), zap.String("dir", dir))
+	if err := cmd.Run(); err != nil {
+		return stdout.String(), fmt.Errorf("%v %v failed: %w: %v", TofuBinary(), args[0], err, tail(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// tail returns the last lines of the output of a command, which contain the error in most cases.
+func tail(output string) string {
+	const maxLines = 20
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxLines {
+		lines = lines[len(lines)-maxLines:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"path"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agenthost"
+	"github.com/google/uuid"
+)
+
+type State string
+
+const (
+	StateUnspecified State = ""
+	StateProgressing State = "progressing"
+	StateReady       State = "ready"
+	StateFailed      State = "failed"
+)
+
+type AgentDeployment struct {
+	ID          uuid.UUID `json:"id"`
+	RevisionID  uuid.UUID `json:"revisionId"`
+	ReleaseName string    `json:"releaseName"`
+	State       State     `json:"phase"`
+}
+
+func (d AgentDeployment) GetDeploymentID() uuid.UUID {
+	return d.ID
+}
+
+func (d AgentDeployment) GetDeploymentRevisionID() uuid.UUID {
+	return d.RevisionID
+}
+
+// UnitName is prefixed so that deployments do not collide with the units of the system or of the agent itself, like
+// distr-agent.service.
+func (d *AgentDeployment) UnitName() string {
+	return "distr-app-" + d.ReleaseName + ".service"
+}
+
+// Dir contains the versions of the deployment and the current symlink to the installed one.
+func (d *AgentDeployment) Dir() string {
+	return path.Join(agentenv.SystemdDeploymentsDir, d.ReleaseName)
+}
+
+func NewAgentDeployment(deployment api.AgentDeployment) AgentDeployment {
+	return AgentDeployment{
+		ID:          deployment.ID,
+		RevisionID:  deployment.RevisionID,
+		ReleaseName: deployment.ReleaseName,
+	}
+}
+
+var deploymentStore agenthost.DeploymentStore[AgentDeployment]
+
+func GetExistingDeployments() (map[uuid.UUID]AgentDeployment, error) {
+	return deploymentStore.GetAll()
+}
+
+func SaveDeployment(deployment AgentDeployment) error {
+	return deploymentStore.Save(deployment)
+}
+
+func DeleteDeployment(deployment AgentDeployment) error {
+	return deploymentStore.Delete(deployment)
+}
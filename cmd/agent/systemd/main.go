@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentauth"
+	"github.com/distr-sh/distr/internal/agentcheck"
+	"github.com/distr-sh/distr/internal/agentclient"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agenthost"
+	"github.com/distr-sh/distr/internal/buildconfig"
+	"github.com/distr-sh/distr/internal/deploymenttargetlogs"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var (
+	platformLoggingCore = &deploymenttargetlogs.Core{Encoder: zapcore.NewConsoleEncoder(func() zapcore.EncoderConfig {
+		cfg := zap.NewDevelopmentEncoderConfig()
+		cfg.TimeKey = ""
+		cfg.LevelKey = ""
+		return cfg
+	}())}
+	logger = util.Require(zap.NewDevelopment(
+		zap.WrapCore(func(c zapcore.Core) zapcore.Core {
+			// Platform logging should use the same logging level as the base core
+			platformLoggingCore.LevelEnabler = c
+			return zapcore.NewTee(c, platformLoggingCore)
+		}),
+	))
+	client     = util.Require(agentclient.NewFromEnv(logger))
+	health     = agentcheck.NewServer(time.Hour)
+	selfUpdate = agenthost.SelfUpdate{Logger: logger, Client: client}
+)
+
+func init() {
+	platformLoggingCore.Collector = &deploymenttargetlogs.BufferedCollector{Delegate: client}
+	if agentenv.AgentVersionID == "" {
+		logger.Warn("AgentVersionID is not set. self updates will be disabled")
+	}
+}
+
+func main() {
+	defer func() {
+		if err := logger.Sync(); err != nil && !errors.Is(err, syscall.EINVAL) {
+			fmt.Println(err)
+		}
+	}()
+
+	defer func() {
+		if reason := recover(); reason != nil {
+			logger.Panic("agent panic", zap.Any("reason", reason))
+		}
+	}()
+
+	ctx, _ := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+
+	context.AfterFunc(ctx, func() { logger.Info("shutdown signal received") })
+
+	logger.Info("systemd agent is starting",
+		zap.String("version", buildconfig.Version()),
+		zap.String("commit", buildconfig.Commit()),
+		zap.Bool("release", buildconfig.IsRelease()))
+
+	go func() {
+		if err := agenthost.StartHealthServer(health); err != nil {
+			logger.Warn("health server error", zap.Error(err))
+		}
+	}()
+
+	mainLoop(ctx)
+
+	logger.Info("shutting down")
+}
+
+func mainLoop(ctx context.Context) {
+	tick := time.Tick(agentenv.Interval)
+	resources := client.WatchResource(ctx, agentenv.ResourceWait, agentenv.Interval)
+
+loop:
+	for ctx.Err() == nil {
+		select {
+		case <-tick:
+		case <-resources.Changed():
+		case <-ctx.Done():
+			break loop
+		}
+
+		health.Heartbeat()
+
+		resource, err := resources.Resource(ctx)
+		if err != nil {
+			logger.Error("failed to get resource", zap.Error(err))
+			continue
+		}
+
+		if agenthost.SelfUpdateIfRequired(ctx, selfUpdate, *resource) {
+			continue
+		}
+
+		deployments, err := GetExistingDeployments()
+		if err != nil {
+			logger.Error("could not get existing deployments", zap.Error(err))
+			continue
+		}
+		cleanupOldDeployments(ctx, *resource, deployments)
+
+		if len(resource.Deployments) == 0 {
+			logger.Info("no deployment in resource response")
+			continue
+		}
+
+		for _, deployment := range resource.Deployments {
+			var agentDeployment *AgentDeployment
+			if existing, ok := deployments[deployment.ID]; ok {
+				agentDeployment = &existing
+			}
+			statusType, status, err := runDeployment(ctx, deployment, agentDeployment)
+			if err != nil {
+				err = client.StatusWithError(ctx, deployment.RevisionID, err)
+			} else {
+				err = client.Status(ctx, deployment.RevisionID, statusType, status)
+			}
+			if err != nil {
+				logger.Error("failed to send status", zap.Error(err))
+			}
+		}
+	}
+}
+
+// runDeployment installs the deployment if its revision is not installed yet and checks its status otherwise.
+func runDeployment(
+	ctx context.Context,
+	deployment api.AgentDeployment,
+	agentDeployment *AgentDeployment,
+) (types.DeploymentStatusType, string, error) {
+	authClient, err := agentauth.EnsureAuth(ctx, client.RawToken(), deployment)
+	if err != nil {
+		return types.DeploymentStatusTypeError, "", fmt.Errorf("registry auth error: %w", err)
+	}
+
+	if agentDeployment != nil && agentDeployment.ReleaseName != deployment.ReleaseName {
+		// The release name determines the unit and directory, so the old ones must be removed.
+		if err := Uninstall(ctx, *agentDeployment); err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("could not uninstall previous release: %w", err)
+		}
+		agentDeployment = nil
+	}
+
+	if agentDeployment == nil ||
+		agentDeployment.RevisionID != deployment.RevisionID ||
+		agentDeployment.State != StateReady {
+		progressCtx, progressCancel := context.WithCancel(ctx)
+		defer progressCancel()
+		updateStatus := agenthost.SendProgressInterval(progressCtx, logger, client, deployment.RevisionID)
+		// Install always restarts the unit, so ForceRestart needs no extra handling.
+		if _, err := Install(ctx, authClient, deployment, updateStatus); err != nil {
+			return types.DeploymentStatusTypeError, "", fmt.Errorf("install failed: %w", err)
+		}
+		return types.DeploymentStatusTypeRunning, "install succeeded", nil
+	}
+
+	return CheckStatus(ctx, *agentDeployment)
+}
+
+func cleanupOldDeployments(ctx context.Context, resource api.AgentResource, deployments map[uuid.UUID]AgentDeployment) {
+	for _, deployment := range deployments {
+		resourceHasExistingDeployment := slices.ContainsFunc(
+			resource.Deployments,
+			func(d api.AgentDeployment) bool { return d.ID == deployment.ID },
+		)
+		if !resourceHasExistingDeployment {
+			logger.Info("uninstalling old deployment", zap.String("id", deployment.ID.String()))
+			if err := Uninstall(ctx, deployment); err != nil {
+				logger.Warn("could not uninstall deployment", zap.Error(err))
+			} else if err := DeleteDeployment(deployment); err != nil {
+				logger.Warn("could not delete deployment", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agentpackage"
+	"github.com/distr-sh/distr/internal/types"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+const (
+	versionsDir    = "versions"
+	currentLink    = "current"
+	templateSuffix = ".tmpl"
+)
+
+// TemplateData is available in the unit file and in the config templates of a package.
+type TemplateData struct {
+	Values  map[string]any
+	Release ReleaseData
+}
+
+type ReleaseData struct {
+	Name string
+	// Dir is the directory of the deployment. The installed version is at Dir/current.
+	Dir      string
+	Revision string
+}
+
+// Install extracts the package of the deployment into a new version directory, renders its config templates and
+// the unit file, switches the current symlink to the new version and restarts the unit.
+func Install(
+	ctx context.Context,
+	authClient *auth.Client,
+	deployment api.AgentDeployment,
+	progress func(string),
+) (*AgentDeployment, error) {
+	agentDeployment := NewAgentDeployment(deployment)
+	agentDeployment.State = StateProgressing
+	if err := SaveDeployment(agentDeployment); err != nil {
+		logger.Warn("failed to save deployment before install", zap.Error(err))
+	}
+
+	if err := install(ctx, authClient, deployment, agentDeployment, progress); err != nil {
+		agentDeployment.State = StateFailed
+		if err := SaveDeployment(agentDeployment); err != nil {
+			logger.Warn("failed to save deployment after failed install", zap.Error(err))
+		}
+		return &agentDeployment, err
+	}
+
+	agentDeployment.State = StateReady
+	if err := SaveDeployment(agentDeployment); err != nil {
+		logger.Warn("failed to save deployment after install", zap.Error(err))
+	}
+	return &agentDeployment, nil
+}
+
+func install(
+	ctx context.Context,
+	authClient *auth.Client,
+	deployment api.AgentDeployment,
+	agentDeployment AgentDeployment,
+	progress func(string),
+) error {
+	dir := agentDeployment.Dir()
+	version := deployment.RevisionID.String()
+	versionDir := path.Join(dir, versionsDir, version)
+	tmpDir := versionDir + ".tmp"
+	data := TemplateData{
+		Values:  deployment.Values,
+		Release: ReleaseData{Name: deployment.ReleaseName, Dir: dir, Revision: version},
+	}
+
+	if err := os.RemoveAll(tmpDir); err != nil {
+		return err
+	} else if err := os.MkdirAll(tmpDir, 0o755); err != nil {
+		return err
+	}
+	defer func() { _ = os.RemoveAll(tmpDir) }()
+
+	progress("pulling package")
+	if err := agentpackage.Pull(ctx, logger, authClient, deployment.PackageRef, tmpDir); err != nil {
+		return err
+	}
+
+	progress("rendering config files")
+	if err := RenderTemplates(tmpDir, data); err != nil {
+		return err
+	}
+	unit, err := renderTemplate(agentDeployment.UnitName(), string(deployment.UnitFile), data)
+	if err != nil {
+		return err
+	}
+
+	if err := os.RemoveAll(versionDir); err != nil {
+		return err
+	} else if err := os.Rename(tmpDir, versionDir); err != nil {
+		return err
+	}
+	previous, _ := os.Readlink(path.Join(dir, currentLink))
+	if err := writeFileAtomic(path.Join(agentenv.SystemdUnitDir, agentDeployment.UnitName()), unit, 0o644); err != nil {
+		return fmt.Errorf("could not write unit file: %w", err)
+	} else if err := switchCurrentLink(dir, path.Join(versionsDir, version)); err != nil {
+		return fmt.Errorf("could not switch to new version: %w", err)
+	}
+
+	progress("restarting unit")
+	if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+		return err
+	} else if _, err := systemctl(ctx, "enable", agentDeployment.UnitName()); err != nil {
+		return err
+	} else if _, err := systemctl(ctx, "restart", agentDeployment.UnitName()); err != nil {
+		return err
+	}
+
+	// The previous version is kept for a manual rollback.
+	if err := pruneVersions(dir, version, path.Base(previous)); err != nil {
+		logger.Warn("could not delete old versions", zap.Error(err))
+	}
+	return nil
+}
+
+// Uninstall stops and disables the unit of the deployment and deletes its unit file and directory.
+func Uninstall(ctx context.Context, deployment AgentDeployment) error {
+	unitFile := path.Join(agentenv.SystemdUnitDir, deployment.UnitName())
+	if _, err := os.Stat(unitFile); err == nil {
+		if _, err := systemctl(ctx, "disable", "--now", deployment.UnitName()); err != nil {
+			return err
+		} else if err := os.Remove(unitFile); err != nil {
+			return err
+		} else if _, err := systemctl(ctx, "daemon-reload"); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return os.RemoveAll(deployment.Dir())
+}
+
+// CheckStatus reports the status of a deployment based on the state of its unit.
+func CheckStatus(ctx context.Context, deployment AgentDeployment) (types.DeploymentStatusType, string, error) {
+	out, err := systemctl(ctx, "show", "--property=LoadState,ActiveState,SubState,Result", deployment.UnitName())
+	if err != nil {
+		return types.DeploymentStatusTypeError, "", err
+	}
+	props := parseProperties(out)
+	message := fmt.Sprintf("%v is %v (%v)", deployment.UnitName(), props["ActiveState"], props["SubState"])
+	if props["LoadState"] != "loaded" {
+		return types.DeploymentStatusTypeError,
+			fmt.Sprintf("%v could not be loaded (%v)", deployment.UnitName(), props["LoadState"]), nil
+	}
+	switch props["ActiveState"] {
+	case "active":
+		return types.DeploymentStatusTypeHealthy, message, nil
+	case "activating", "deactivating", "reloading", "refreshing":
+		return types.DeploymentStatusTypeProgressing, message, nil
+	case "inactive":
+		// a oneshot service that exited successfully
+		if props["Result"] == "success" {
+			return types.DeploymentStatusTypeHealthy, message, nil
+		}
+	}
+	return types.DeploymentStatusTypeError, fmt.Sprintf("%v, result: %v", message, props["Result"]), nil
+}
+
+// RenderTemplates renders all files in dir with the templateSuffix and replaces them with the result.
+func RenderTemplates(dir string, data TemplateData) error {
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = root.Close() }()
+	return fs.WalkDir(root.FS(), ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() || !strings.HasSuffix(name, templateSuffix) {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		if text, err := root.ReadFile(name); err != nil {
+			return err
+		} else if result, err := renderTemplate(name, string(text), data); err != nil {
+			return err
+		} else if err := root.WriteFile(strings.TrimSuffix(name, templateSuffix), result, info.Mode().Perm()); err != nil {
+			return err
+		} else {
+			return root.Remove(name)
+		}
+	})
+}
+
+func renderTemplate(name, text string, data TemplateData) ([]byte, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse template %v: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("could not render template %v: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// switchCurrentLink replaces the current symlink with a rename, so that it always points to a complete version.
+func switchCurrentLink(dir, target string) error {
+	tmpLink := path.Join(dir, currentLink+".tmp")
+	if err := os.Remove(tmpLink); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	} else if err := os.Symlink(target, tmpLink); err != nil {
+		return err
+	}
+	return os.Rename(tmpLink, path.Join(dir, currentLink))
+}
+
+func pruneVersions(dir string, keep ...string) error {
+	entries, err := os.ReadDir(path.Join(dir, versionsDir))
+	if err != nil {
+		return err
+	}
+	var aggregateErr error
+	for _, entry := range entries {
+		if !slices.Contains(keep, entry.Name()) {
+			aggregateErr = errors.Join(aggregateErr, os.RemoveAll(path.Join(dir, versionsDir, entry.Name())))
+		}
+	}
+	return aggregateErr
+}
+
+func writeFileAtomic(name string, data []byte, perm os.FileMode) error {
+	tmp := name + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, name)
+}
+
+func systemctl(ctx context.Context, args ...string) (string, error) {
+	out, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("systemctl %v failed: %w: %v", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// parseProperties parses the output of systemctl show, which has a Key=Value pair on every line.
+func parseProperties(out string) map[string]string {
+	result := map[string]string{}
+	for line := range strings.Lines(out) {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/limit"
+	"github.com/distr-sh/distr/internal/superadmin"
+	"github.com/distr-sh/distr/internal/svc"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+func NewAdminCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "admin",
+		Short: "inspect and fix the organizations and users of this instance",
+		Long: "Changes made with these commands are logged by the hub, but not recorded in the audit log of the " +
+			"organization. Use the admin API as a super admin to have them recorded.",
+	}
+	cmd.AddCommand(NewAdminOrganizationsCommand(), NewAdminUsersCommand())
+	return cmd
+}
+
+func NewAdminOrganizationsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "organizations",
+		Aliases: []string{"orgs"},
+		Short:   "manage organizations",
+	}
+	cmd.AddCommand(
+		NewAdminListOrganizationsCommand(),
+		NewAdminSetSubscriptionCommand(),
+		NewAdminSetFeaturesCommand("grant-feature", "grant features to an organization", true),
+		NewAdminSetFeaturesCommand("revoke-feature", "revoke features from an organization", false),
+		NewAdminDeleteOrganizationCommand(),
+		NewAdminRestoreOrganizationCommand(),
+	)
+	return cmd
+}
+
+func NewAdminUsersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "users",
+		Short: "manage user accounts",
+	}
+	cmd.AddCommand(NewAdminListUsersCommand(), NewAdminResetMFACommand())
+	return cmd
+}
+
+type AdminListOrganizationsOptions struct {
+	Search  string
+	Deleted bool
+	Count   int
+}
+
+func NewAdminListOrganizationsCommand() *cobra.Command {
+	var opts AdminListOrganizationsOptions
+	cmd := &cobra.Command{
+		Use:    "list",
+		Short:  "list organizations, newest first",
+		Args:   cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				orgs, err := db.GetOrganizationOverviews(ctx, types.OrganizationOverviewFilter{
+					Search:  opts.Search,
+					Deleted: opts.Deleted,
+					Before:  time.Now(),
+					Count:   opts.Count,
+				})
+				if err != nil {
+					return err
+				}
+				printOrganizations(cmd.OutOrStdout(), orgs...)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Search, "search", "s", "", "only list organizations whose ID, name or slug match")
+	cmd.Flags().BoolVar(&opts.Deleted, "deleted", false, "list soft-deleted instead of active organizations")
+	cmd.Flags().IntVar(&opts.Count, "count", 50, "maximum number of organizations to list")
+	return cmd
+}
+
+type AdminSetSubscriptionOptions struct {
+	Type                    string
+	Period                  string
+	EndsAt                  string
+	CustomerOrganizationQty int64
+	UserAccountQty          int64
+}
+
+func NewAdminSetSubscriptionCommand() *cobra.Command {
+	var opts AdminSetSubscriptionOptions
+	cmd := &cobra.Command{
+		Use:   "set-subscription <organization-id>",
+		Short: "change the subscription type and limits of an organization",
+		Long: "Flags that are not set keep their current value. " +
+			"The features of the new subscription type are granted.",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orgID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				org, err := db.GetOrganizationOverview(ctx, orgID)
+				if err != nil {
+					return err
+				}
+				s := superadmin.Subscription{
+					Type:                    org.SubscriptionType,
+					Period:                  org.SubscriptionPeriod,
+					EndsAt:                  org.SubscriptionEndsAt,
+					CustomerOrganizationQty: org.SubscriptionCustomerOrganizationQty,
+					UserAccountQty:          org.SubscriptionUserAccountQty,
+				}
+				if cmd.Flags().Changed("type") {
+					s.Type = types.SubscriptionType(opts.Type)
+				}
+				if cmd.Flags().Changed("period") {
+					s.Period = types.SubscriptionPeriod(opts.Period)
+				}
+				if cmd.Flags().Changed("ends-at") {
+					if s.EndsAt, err = time.Parse(time.DateOnly, opts.EndsAt); err != nil {
+						return fmt.Errorf("invalid ends-at: %w", err)
+					}
+				}
+				if cmd.Flags().Changed("max-customer-organizations") {
+					s.CustomerOrganizationQty = limit.New(opts.CustomerOrganizationQty)
+				}
+				if cmd.Flags().Changed("max-users") {
+					s.UserAccountQty = limit.New(opts.UserAccountQty)
+				}
+				_, after, err := superadmin.UpdateSubscription(ctx, orgID, s)
+				if err != nil {
+					return err
+				}
+				log.Info("subscription updated", zap.Stringer("organizationId", orgID),
+					zap.String("subscriptionType", string(after.SubscriptionType)))
+				printOrganizations(cmd.OutOrStdout(), *after)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&opts.Type, "type", "", "subscription type")
+	cmd.Flags().StringVar(&opts.Period, "period", "", "subscription period (monthly or yearly)")
+	cmd.Flags().StringVar(&opts.EndsAt, "ends-at", "", "date when the subscription ends (yyyy-mm-dd)")
+	cmd.Flags().Int64Var(&opts.CustomerOrganizationQty, "max-customer-organizations", 0,
+		"maximum number of customer organizations. -1 means unlimited")
+	cmd.Flags().Int64Var(&opts.UserAccountQty, "max-users", 0, "maximum number of users. -1 means unlimited")
+	return cmd
+}
+
+func NewAdminSetFeaturesCommand(use, short string, enabled bool) *cobra.Command {
+	return &cobra.Command{
+		Use:       use + " <organization-id> <feature> [feature...]",
+		Short:     short,
+		Long:      fmt.Sprintf("feature must be one of: %v", strings.Join(featureNames(types.AllFeatures()), ", ")),
+		Args:      cobra.MinimumNArgs(2),
+		PreRun:    func(cmd *cobra.Command, args []string) { env.Initialize() },
+		ValidArgs: featureNames(types.AllFeatures()),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orgID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			features := make([]types.Feature, 0, len(args)-1)
+			for _, arg := range args[1:] {
+				features = append(features, types.Feature(arg))
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				_, after, err := superadmin.SetFeatures(ctx, orgID, features, enabled)
+				if err != nil {
+					return err
+				}
+				log.Info("features updated", zap.Stringer("organizationId", orgID),
+					zap.Any("features", features), zap.Bool("enabled", enabled))
+				printOrganizations(cmd.OutOrStdout(), *after)
+				return nil
+			})
+		},
+	}
+}
+
+func NewAdminDeleteOrganizationCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "delete <organization-id>",
+		Short:  "soft-delete an organization",
+		Long:   "The organization is purged by the Organization cleanup and can be restored until then.",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orgID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				if _, err := superadmin.DeleteOrganization(ctx, orgID); err != nil {
+					return err
+				}
+				log.Info("organization deleted", zap.Stringer("organizationId", orgID))
+				return nil
+			})
+		},
+	}
+}
+
+func NewAdminRestoreOrganizationCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "restore <organization-id>",
+		Short:  "restore a soft-deleted organization that was not purged yet",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orgID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				if _, err := superadmin.RestoreOrganization(ctx, orgID); err != nil {
+					return err
+				}
+				log.Info("organization restored", zap.Stringer("organizationId", orgID))
+				return nil
+			})
+		},
+	}
+}
+
+type AdminListUsersOptions struct {
+	Search         string
+	OrganizationID string
+	Count          int
+}
+
+func NewAdminListUsersCommand() *cobra.Command {
+	var opts AdminListUsersOptions
+	cmd := &cobra.Command{
+		Use:    "list",
+		Short:  "list user accounts, newest first",
+		Args:   cobra.NoArgs,
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			filter := types.UserAccountOverviewFilter{Search: opts.Search, Before: time.Now(), Count: opts.Count}
+			if opts.OrganizationID != "" {
+				if id, err := uuid.Parse(opts.OrganizationID); err != nil {
+					return fmt.Errorf("invalid organization-id: %w", err)
+				} else {
+					filter.OrganizationID = &id
+				}
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				users, err := db.GetUserAccountOverviews(ctx, filter)
+				if err != nil {
+					return err
+				}
+				printUserAccounts(cmd.OutOrStdout(), users)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Search, "search", "s", "", "only list users whose ID, email or name match")
+	cmd.Flags().StringVarP(&opts.OrganizationID, "organization-id", "o", "", "only list members of this organization")
+	cmd.Flags().IntVar(&opts.Count, "count", 50, "maximum number of users to list")
+	return cmd
+}
+
+func NewAdminResetMFACommand() *cobra.Command {
+	return &cobra.Command{
+		Use:    "reset-mfa <user-id>",
+		Short:  "disable the second factor of a user who lost it and revoke their sessions",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			userID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid user-id: %w", err)
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				if err := superadmin.ResetMFA(ctx, userID); err != nil {
+					return err
+				}
+				log.Info("MFA reset", zap.Stringer("userId", userID))
+				return nil
+			})
+		},
+	}
+}
+
+func init() {
+	RootCommand.AddCommand(NewAdminCommand())
+}
+
+func runAdmin(ctx context.Context, f func(ctx context.Context, log *zap.Logger) error) error {
+	registry := util.Require(svc.NewDefault(ctx))
+	defer func() { util.Must(registry.Shutdown(ctx)) }()
+	log := registry.GetLogger()
+	ctx = internalctx.WithDb(ctx, registry.GetDbPool())
+	ctx = internalctx.WithLogger(ctx, log)
+	if s3Client := registry.GetS3Client(); s3Client != nil {
+		ctx = internalctx.WithS3Client(ctx, s3Client)
+	}
+	return f(ctx, log)
+}
+
+func printOrganizations(w io.Writer, orgs ...types.OrganizationOverview) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tNAME\tSLUG\tSUBSCRIPTION\tENDS AT\tUSERS\tFEATURES\tDELETED AT")
+	for _, org := range orgs {
+		_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			org.ID,
+			org.Name,
+			util.PtrDerefOr(org.Slug, "-"),
+			org.SubscriptionType,
+			org.SubscriptionEndsAt.Format(time.DateOnly),
+			formatUsage(org.UserAccountCount, org.SubscriptionUserAccountQty),
+			strings.Join(featureNames(org.Features), ","),
+			formatOptionalTime(org.DeletedAt),
+		)
+	}
+	_ = tw.Flush()
+}
+
+func printUserAccounts(w io.Writer, users []types.UserAccountOverview) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ID\tEMAIL\tNAME\tORGANIZATIONS\tMFA\tSUPER ADMIN\tLAST LOGIN")
+	for _, user := range users {
+		_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\t%v\t%v\t%v\n",
+			user.ID,
+			user.Email,
+			user.Name,
+			user.OrganizationCount,
+			user.MFAEnabled,
+			user.IsSuperAdmin,
+			formatOptionalTime(user.LastLoggedInAt),
+		)
+	}
+	_ = tw.Flush()
+}
+
+func formatUsage(count int64, max limit.Limit) string {
+	if max.IsUnlimited() {
+		return fmt.Sprintf("%v", count)
+	}
+	return fmt.Sprintf("%v/%v", count, max.Value())
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func featureNames(features []types.Feature) []string {
+	names := make([]string, len(features))
+	for i, feature := range features {
+		names[i] = string(feature)
+	}
+	return names
+}
@@ -27,6 +27,10 @@ const (
 	oidcState                = "OIDCState"
 	artifactBlob             = "ArtifactBlob"
 	organization             = "Organization"
+	auditLog                 = "AuditLog"
+	userSession              = "UserSession"
+	jobRun                   = "JobRun"
+	task                     = "Task"
 )
 
 type CleanupOptions struct {
@@ -39,12 +43,16 @@ func NewCleanupCommand() *cobra.Command {
 	cmd := cobra.Command{
 		Use: "cleanup <type> [type...]",
 		Long: fmt.Sprintf(
-			"type must be one of: %v, %v, %v, %v, %v",
+			"type must be one of: %v, %v, %v, %v, %v, %v, %v, %v, %v",
 			deploymentRevisionStatus,
 			deploymentTargetMetrics,
 			oidcState,
 			artifactBlob,
 			organization,
+			auditLog,
+			userSession,
+			jobRun,
+			task,
 		),
 		Short: "delete old data",
 		Args:  cobra.MinimumNArgs(1),
@@ -54,6 +62,10 @@ func NewCleanupCommand() *cobra.Command {
 			oidcState,
 			artifactBlob,
 			organization,
+			auditLog,
+			userSession,
+			jobRun,
+			task,
 		},
 		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
 		Run: func(cmd *cobra.Command, args []string) {
@@ -88,6 +100,14 @@ func resolveCleanupFunc(cleanupType string, registry *svc.Registry) (func(contex
 		return cleanup.RunArtifactBlobCleanup, nil
 	case organization:
 		return cleanup.RunOrganizationCleanup, nil
+	case auditLog:
+		return cleanup.RunAuditLogCleanup, nil
+	case userSession:
+		return cleanup.RunUserSessionCleanup, nil
+	case jobRun:
+		return cleanup.RunJobRunCleanup, nil
+	case task:
+		return cleanup.RunTaskCleanup, nil
 	default:
 		return nil, fmt.Errorf("invalid cleanup type: %v", cleanupType)
 	}
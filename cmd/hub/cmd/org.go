@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/orgtransfer"
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+)
+
+// exportKeyEnv holds the key that the secrets of an archive are encrypted with, unless --key-file is set.
+const exportKeyEnv = "DISTR_EXPORT_KEY"
+
+func NewOrgCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "org",
+		Short: "move organizations between Distr instances",
+		Long: "Secrets in the archive are encrypted with a key of at least " +
+			fmt.Sprint(orgtransfer.MinKeyLength) + " characters, read from --key-file or the " + exportKeyEnv +
+			" environment variable. The same key is needed to import the archive.",
+	}
+	cmd.AddCommand(NewOrgExportCommand(), NewOrgImportCommand())
+	return cmd
+}
+
+type OrgExportOptions struct {
+	Output  string
+	KeyFile string
+}
+
+func NewOrgExportCommand() *cobra.Command {
+	var opts OrgExportOptions
+	cmd := &cobra.Command{
+		Use:    "export <organization-id>",
+		Short:  "export an organization into an archive",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			orgID, err := uuid.Parse(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid organization-id: %w", err)
+			}
+			key, err := readExportKey(opts.KeyFile)
+			if err != nil {
+				return err
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) (err error) {
+				file, err := os.Create(opts.Output)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					err = errors.Join(err, file.Close())
+					if err != nil {
+						_ = os.Remove(opts.Output)
+					}
+				}()
+				if err := orgtransfer.Export(ctx, orgID, key, file); err != nil {
+					return err
+				}
+				log.Info("organization exported", zap.Stringer("organizationId", orgID), zap.String("file", opts.Output))
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "file to write the archive to")
+	cmd.Flags().StringVar(&opts.KeyFile, "key-file", "", "file that contains the key")
+	_ = cmd.MarkFlagRequired("output")
+	return cmd
+}
+
+type OrgImportOptions struct {
+	Owner   string
+	Name    string
+	KeyFile string
+	DryRun  bool
+}
+
+func NewOrgImportCommand() *cobra.Command {
+	var opts OrgImportOptions
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "create an organization from an archive",
+		Long: "All IDs are replaced by new ones. Values that could not be imported as they were, like a slug that " +
+			"is already taken, are listed as conflicts.",
+		Args:   cobra.ExactArgs(1),
+		PreRun: func(cmd *cobra.Command, args []string) { env.Initialize() },
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := readExportKey(opts.KeyFile)
+			if err != nil {
+				return err
+			}
+			return runAdmin(cmd.Context(), func(ctx context.Context, log *zap.Logger) error {
+				owner, err := db.GetUserAccountByEmail(ctx, opts.Owner)
+				if err != nil {
+					return fmt.Errorf("could not get owner %v: %w", opts.Owner, err)
+				}
+				file, err := os.Open(args[0])
+				if err != nil {
+					return err
+				}
+				defer func() { _ = file.Close() }()
+				result, err := orgtransfer.Import(ctx, file, orgtransfer.ImportOptions{
+					Key:     key,
+					OwnerID: owner.ID,
+					Name:    opts.Name,
+					DryRun:  opts.DryRun,
+				})
+				if err != nil {
+					return err
+				}
+				printImportResult(cmd.OutOrStdout(), *result)
+				return nil
+			})
+		},
+	}
+	cmd.Flags().StringVar(&opts.Owner, "owner", "", "email of the user who becomes admin of the organization")
+	cmd.Flags().StringVar(&opts.Name, "name", "", "name of the organization (default: the name in the archive)")
+	cmd.Flags().StringVar(&opts.KeyFile, "key-file", "", "file that contains the key")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "check the archive and list conflicts without importing it")
+	_ = cmd.MarkFlagRequired("owner")
+	return cmd
+}
+
+func init() {
+	RootCommand.AddCommand(NewOrgCommand())
+}
+
+func readExportKey(keyFile string) (string, error) {
+	if keyFile == "" {
+		if key := os.Getenv(exportKeyEnv); key != "" {
+			return key, nil
+		}
+		return "", fmt.Errorf("either --key-file or %v must be set", exportKeyEnv)
+	}
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func printImportResult(w io.Writer, result orgtransfer.ImportResult) {
+	if result.DryRun {
+		_, _ = fmt.Fprintln(w, "Dry run, nothing was imported.")
+	} else {
+		_, _ = fmt.Fprintf(w, "Imported organization %v.\n", result.OrganizationID)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\nTABLE\tROWS")
+	for _, table := range result.Tables {
+		_, _ = fmt.Fprintf(tw, "%v\t%v\n", table.Name, table.Rows)
+	}
+	_, _ = fmt.Fprintf(tw, "registry blobs\t%v\n", result.Blobs)
+	if len(result.Conflicts) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nTABLE\tCOLUMN\tVALUE\tRESOLUTION")
+		for _, c := range result.Conflicts {
+			_, _ = fmt.Fprintf(tw, "%v\t%v\t%v\t%v\n", c.Table, c.Column, c.Value, c.Resolution)
+		}
+	}
+	_ = tw.Flush()
+}
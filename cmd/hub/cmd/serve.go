@@ -100,6 +100,7 @@ func runServe(ctx context.Context, opts ServeOptions) {
 	go func() { util.Must(metricsServer.Start(env.MetricsAddr())) }()
 	go func() { util.Must(internalServer.Start(env.InternalServerAddr())) }()
 	registry.GetJobsScheduler().Start()
+	go registry.GetAgentNotifier().Run(sigCtx, registry.GetDbPool())
 	server.WaitForShutdown()
 	artifactsServer.WaitForShutdown()
 	metricsServer.WaitForShutdown()
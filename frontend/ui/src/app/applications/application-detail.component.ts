@@ -275,6 +275,7 @@ export class ApplicationDetailComponent implements OnInit, OnDestroy {
           {
             name: this.newVersionForm.controls.versionName.value!,
             linkTemplate: this.newVersionForm.controls.linkTemplate.value!,
+            sourceType: 'helm',
             chartType: versionFormVal.chartType!,
             chartName: versionFormVal.chartName ?? undefined,
             chartUrl: versionFormVal.chartUrl!,
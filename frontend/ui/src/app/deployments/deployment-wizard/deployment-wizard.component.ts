@@ -16,6 +16,7 @@ import {takeUntilDestroyed, toObservable, toSignal} from '@angular/core/rxjs-int
 import {FormBuilder, FormControl, FormGroup, ReactiveFormsModule, Validators} from '@angular/forms';
 import {
   Application,
+  ContainerRuntime,
   CustomerOrganization,
   DeploymentTarget,
   DeploymentTargetScope,
@@ -111,6 +112,7 @@ export class DeploymentWizardComponent implements OnInit {
     imageCleanupEnabled: new FormControl<boolean>(true, {nonNullable: true}),
     deploymentLogsEnabled: new FormControl<boolean>(true, {nonNullable: true}),
     automaticUpdatesEnabled: new FormControl<boolean>(true, {nonNullable: true}),
+    containerRuntime: new FormControl<ContainerRuntime>('docker', {nonNullable: true}),
     customDockerEndpoint: new FormControl<boolean>(false, {nonNullable: true}),
     dockerEndpoint: new FormControl<string>('', {
       nonNullable: true,
@@ -318,6 +320,7 @@ export class DeploymentWizardComponent implements OnInit {
       } else {
         this.deploymentTargetForm.controls.resources.disable();
       }
+      this.deploymentTargetForm.controls.containerRuntime.disable();
       this.deploymentTargetForm.controls.customDockerEndpoint.disable();
       this.deploymentTargetForm.controls.dockerEndpoint.disable();
     } else if (type === 'docker') {
@@ -328,6 +331,7 @@ export class DeploymentWizardComponent implements OnInit {
       this.deploymentTargetForm.controls.imageCleanupEnabled.enable();
       this.deploymentTargetForm.controls.customResources.disable();
       this.deploymentTargetForm.controls.resources.disable();
+      this.deploymentTargetForm.controls.containerRuntime.enable();
       this.deploymentTargetForm.controls.customDockerEndpoint.enable();
       if (this.deploymentTargetForm.controls.customDockerEndpoint.value) {
         this.deploymentTargetForm.controls.dockerEndpoint.enable();
@@ -444,6 +448,7 @@ export class DeploymentWizardComponent implements OnInit {
                   memoryRequest: this.deploymentTargetForm.value.resources?.memoryRequest!,
                 }
               : undefined,
+            containerRuntime: app.type === 'docker' ? this.deploymentTargetForm.value.containerRuntime : undefined,
             dockerEndpoint: this.deploymentTargetForm.value.customDockerEndpoint
               ? this.deploymentTargetForm.value.dockerEndpoint
               : undefined,
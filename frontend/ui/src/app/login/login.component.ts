@@ -8,7 +8,7 @@ import {OidcButtonsComponent} from '../components/oidc-buttons.component';
 import {PortalLogoComponent} from '../components/portal-logo/portal-logo.component';
 import {AutotrimDirective} from '../directives/autotrim.directive';
 import {PlaceholderDirective} from '../directives/placeholder.directive';
-import {AuthService} from '../services/auth.service';
+import {AuthService, WebAuthnChallenge} from '../services/auth.service';
 import {PortalBrandingService} from '../services/portal-branding.service';
 import {PortalService} from '../services/portal.service';
 import {ToastService} from '../services/toast.service';
@@ -120,6 +120,11 @@ export class LoginComponent implements OnInit {
             'organization’s identity provider. Please contact your administrator.'
         );
         break;
+      case 'oidc-security-policy':
+        this.toast.error(
+          'The security policy of your organization does not allow this login. Please contact your administrator.'
+        );
+        break;
     }
 
     const jwt = this.route.snapshot.queryParamMap.get('jwt');
@@ -165,7 +170,17 @@ export class LoginComponent implements OnInit {
     this.loading.set(true);
 
     try {
-      const response = await lastValueFrom(this.auth.login(email, password, mfaCode));
+      let response = await lastValueFrom(this.auth.login(email, password, mfaCode));
+      if (response.requiresMfa && response.webAuthn) {
+        // A registered passkey is the preferred second factor. The MFA code form remains as a fallback if the
+        // user cancels the browser prompt.
+        const credential = await this.getPasskeyCredential(response.webAuthn);
+        if (credential) {
+          response = await lastValueFrom(
+            this.auth.login(email, password, undefined, {sessionId: response.webAuthn.sessionId, credential})
+          );
+        }
+      }
       if (response.requiresMfa) {
         this.mfaRequired.set(true);
       } else if (response.redirectUrl && !this.route.snapshot.queryParamMap.has('stay')) {
@@ -187,6 +202,17 @@ export class LoginComponent implements OnInit {
     }
   }
 
+  private async getPasskeyCredential(challenge: WebAuthnChallenge): Promise<unknown> {
+    try {
+      const credential = await navigator.credentials.get({
+        publicKey: PublicKeyCredential.parseRequestOptionsFromJSON(challenge.options.publicKey),
+      });
+      return credential instanceof PublicKeyCredential ? credential.toJSON() : undefined;
+    } catch {
+      return undefined;
+    }
+  }
+
   public reset() {
     this.emailPasswordForm.reset();
     this.mfaCodeForm.reset();
@@ -1,7 +1,9 @@
 import {HttpClient} from '@angular/common/http';
 import {inject, Injectable} from '@angular/core';
 import {map, Observable, of, switchMap, tap} from 'rxjs';
+import {Task} from '../types/task';
 import {ReactiveList} from './cache';
+import {TasksService} from './tasks.service';
 
 export interface HasDownloads {
   downloadsTotal?: number;
@@ -84,6 +86,7 @@ class ArtifactsReactiveList extends ReactiveList<ArtifactWithTags> {
 export class ArtifactsService {
   private readonly artifactsUrl = '/api/v1/artifacts';
   private readonly http = inject(HttpClient);
+  private readonly tasks = inject(TasksService);
   private readonly cache = new ArtifactsReactiveList(this.http.get<ArtifactWithTags[]>(this.artifactsUrl));
 
   public list(): Observable<ArtifactWithTags[]> {
@@ -142,9 +145,11 @@ export class ArtifactsService {
   }
 
   public syncArtifact(id: string): Observable<ArtifactWithTags> {
-    return this.http
-      .post<ArtifactWithTags>(`${this.artifactsUrl}/${id}/sync`, {})
-      .pipe(tap((it) => this.cache.save(it)));
+    return this.http.post<Task>(`${this.artifactsUrl}/${id}/sync`, {}).pipe(
+      switchMap((task) => this.tasks.waitForResult(task)),
+      switchMap(() => this.http.get<ArtifactWithTags>(`${this.artifactsUrl}/${id}`)),
+      tap((it) => this.cache.save(it))
+    );
   }
 
   public deleteArtifactTag(artifact: ArtifactWithTags, tagName: string) {
@@ -10,6 +10,11 @@ const tokenStorageKey = 'cloud_token';
 const actionTokenStorageKey = 'distr_action_token';
 const authBaseUrl = '/api/v1/auth';
 
+export interface WebAuthnChallenge {
+  sessionId: string;
+  options: {publicKey: PublicKeyCredentialRequestOptionsJSON};
+}
+
 export interface JWTClaims {
   sub: string;
   // Special tokens (password reset, invite, verification) are not scoped to an organization.
@@ -97,17 +102,30 @@ export class AuthService {
   public login(
     email: string,
     password: string,
-    mfaCode?: string
-  ): Observable<{requiresMfa: boolean; redirectUrl?: string}> {
-    return this.httpClient.post<LoginResponse>(`${authBaseUrl}/login`, {email, password, mfaCode}).pipe(
-      tap((r) => {
-        if (!r.requiresMfa) {
-          this.token = r.token;
-          this.actionToken = null;
-        }
-      }),
-      map((r) => (r.requiresMfa ? {requiresMfa: true} : {requiresMfa: false, redirectUrl: r.redirectUrl}))
-    );
+    mfaCode?: string,
+    webAuthn?: {sessionId: string; credential: unknown}
+  ): Observable<{requiresMfa: boolean; redirectUrl?: string; webAuthn?: WebAuthnChallenge}> {
+    return this.httpClient
+      .post<LoginResponse>(`${authBaseUrl}/login`, {
+        email,
+        password,
+        mfaCode,
+        webAuthnSessionId: webAuthn?.sessionId,
+        webAuthnCredential: webAuthn?.credential,
+      })
+      .pipe(
+        tap((r) => {
+          if (!r.requiresMfa) {
+            this.token = r.token;
+            this.actionToken = null;
+          }
+        }),
+        map((r) =>
+          r.requiresMfa
+            ? {requiresMfa: true, webAuthn: r.webAuthn as WebAuthnChallenge | undefined}
+            : {requiresMfa: false, redirectUrl: r.redirectUrl}
+        )
+      );
   }
 
   public loginWithToken(jwt: string) {
@@ -0,0 +1,32 @@
+import {HttpClient} from '@angular/common/http';
+import {inject, Injectable} from '@angular/core';
+import {filter, map, Observable, switchMap, take, timer} from 'rxjs';
+import {Task} from '../types/task';
+
+const baseUrl = '/api/v1/tasks';
+
+@Injectable({providedIn: 'root'})
+export class TasksService {
+  private readonly httpClient = inject(HttpClient);
+
+  public get<T>(id: string): Observable<Task<T>> {
+    return this.httpClient.get<Task<T>>(`${baseUrl}/${id}`);
+  }
+
+  /**
+   * Polls the task until it succeeded and emits its result. Errors if the task failed on its last attempt.
+   */
+  public waitForResult<T>(task: Task<T>, intervalMs = 2000): Observable<T | undefined> {
+    return timer(0, intervalMs).pipe(
+      switchMap(() => this.get<T>(task.id)),
+      filter((it) => it.status === 'succeeded' || it.status === 'dead'),
+      take(1),
+      map((it) => {
+        if (it.status === 'dead') {
+          throw new Error(it.error ?? 'task failed');
+        }
+        return it.result;
+      })
+    );
+  }
+}
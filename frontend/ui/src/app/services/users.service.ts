@@ -16,6 +16,8 @@ export interface CreateUserAccountRequest {
 export interface PatchUserAccountRequest {
   name?: string;
   userRole?: UserRole;
+  customRoleId?: string;
+  removeCustomRole?: boolean;
 }
 
 export interface UserAccountInvitationResponse {
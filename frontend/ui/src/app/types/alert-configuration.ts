@@ -7,6 +7,8 @@ export interface CreateUpdateAlertConfigurationRequest {
   cpuTriggerThresholdPercent?: number;
   memoryTriggerThresholdPercent?: number;
   diskTriggerThresholdPercent?: number;
+  containerCpuTriggerThresholdPercent?: number;
+  containerMemoryTriggerThresholdPercent?: number;
   deploymentTargetIds?: string[];
   userAccountIds?: string[];
 }
@@ -20,6 +22,8 @@ export interface AlertConfiguration {
   cpuTriggerThresholdPercent?: number;
   memoryTriggerThresholdPercent?: number;
   diskTriggerThresholdPercent?: number;
+  containerCpuTriggerThresholdPercent?: number;
+  containerMemoryTriggerThresholdPercent?: number;
   deploymentTargetIds?: string[];
   userAccountIds?: string[];
   userAccounts?: UserAccount[];
@@ -5,6 +5,7 @@ export interface DeploymentTargetLatestMetrics {
   memoryBytes: number;
   memoryUsage: number;
   diskMetrics?: DeploymentTargetDiskMetric[];
+  containerMetrics?: DeploymentContainerMetric[];
 }
 
 interface DeploymentTargetDiskMetric {
@@ -14,3 +15,16 @@ interface DeploymentTargetDiskMetric {
   bytesTotal: number;
   bytesUsed: number;
 }
+
+interface DeploymentContainerMetric {
+  deploymentId: string;
+  service: string;
+  name: string;
+  cpuUsedMillis: number;
+  cpuUsage: number;
+  memoryUsedBytes: number;
+  memoryUsage: number;
+  restarts: number;
+  networkRxBytes: number;
+  networkTxBytes: number;
+}
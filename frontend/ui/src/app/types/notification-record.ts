@@ -2,7 +2,7 @@ import {DeploymentRevisionStatus} from '@distr-sh/distr-sdk';
 import {DeploymentTargetLatestMetrics} from './deployment-target-metrics';
 
 export type NotificationRecordType = 'alert' | 'warning' | 'resolved';
-export type NotificationRecordMetricType = 'cpu' | 'memory' | 'disk';
+export type NotificationRecordMetricType = 'cpu' | 'memory' | 'disk' | 'containerCpu' | 'containerMemory';
 
 export interface NotificationRecord {
   id: string;
@@ -16,6 +16,8 @@ export interface NotificationRecord {
   metricType?: NotificationRecordMetricType;
   diskDevice?: string;
   diskPath?: string;
+  deploymentId?: string;
+  containerName?: string;
   message: string;
   currentDeploymentRevisionStatus?: DeploymentRevisionStatus;
   currentDeploymentTargetMetrics?: DeploymentTargetLatestMetrics;
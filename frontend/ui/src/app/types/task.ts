@@ -0,0 +1,17 @@
+export type TaskStatus = 'pending' | 'running' | 'succeeded' | 'dead';
+
+export interface Task<T = unknown> {
+  id: string;
+  createdAt: string;
+  type: string;
+  status: TaskStatus;
+  attempts: number;
+  maxAttempts: number;
+  runAfter: string;
+  progress?: number;
+  progressMessage?: string;
+  result?: T;
+  error?: string;
+  startedAt?: string;
+  finishedAt?: string;
+}
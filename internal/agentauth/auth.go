@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"maps"
+	"net/http"
 	"os"
 	"path"
 
 	containerdlog "github.com/containerd/log"
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/agentnet"
 	dockerconfig "github.com/docker/cli/cli/config"
 	"github.com/google/uuid"
 	"oras.land/oras-go/v2/registry/remote"
@@ -19,6 +21,8 @@ import (
 )
 
 var (
+	// httpClient is used for all registry requests, so that they use the network settings of the agent.
+	httpClient       = &http.Client{Transport: retry.NewTransport(agentnet.Transport())}
 	previousAuth     = map[uuid.UUID]map[string]api.AgentRegistryAuth{}
 	previousJWT      = map[uuid.UUID]string{}
 	credentialStores = map[uuid.UUID]credentials.Store{}
@@ -56,6 +60,7 @@ func EnsureAuth(
 	if reg, err := remote.NewRegistry(agentenv.DistrRegistryHost); err != nil {
 		return nil, err
 	} else if previousJWT[deployment.ID] != jwt {
+		reg.Client = &auth.Client{Client: httpClient}
 		reg.PlainHTTP = agentenv.DistrRegistryPlainHTTP
 		if err := credentials.Login(ctx, store, reg, auth.Credential{Username: "-", Password: jwt}); err != nil {
 			return nil, fmt.Errorf("docker login failed for %v: %w", agentenv.DistrRegistryHost, err)
@@ -69,6 +74,7 @@ func EnsureAuth(
 			if reg, err := remote.NewRegistry(url); err != nil {
 				return nil, err
 			} else {
+				reg.Client = &auth.Client{Client: httpClient}
 				if err := credentials.Login(ctx, store, reg, auth.Credential{
 					Username: registry.Username,
 					Password: registry.Password,
@@ -88,7 +94,7 @@ func EnsureAuth(
 	}
 
 	return &auth.Client{
-		Client:     retry.DefaultClient,
+		Client:     httpClient,
 		Credential: credentials.Credential(store),
 	}, nil
 }
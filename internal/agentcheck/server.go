@@ -1,15 +1,23 @@
 package agentcheck
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
+
+	"github.com/distr-sh/distr/internal/agentqueue"
 )
 
+type healthResponse struct {
+	Queue agentqueue.Stats `json:"queue"`
+}
+
 type Server struct {
 	heartbeatTimestamp       *time.Time
 	heartbeatHealthyDuration time.Duration
+	queueStats               func() agentqueue.Stats
 	mut                      sync.RWMutex
 }
 
@@ -28,6 +36,14 @@ func (h *Server) HeartbeatAt(t time.Time) {
 	h.heartbeatTimestamp = &t
 }
 
+// SetQueueStats adds the depth of the queue of the agent to the response of a healthy agent. A full queue does not
+// make the agent unhealthy, because it only means that the hub cannot be reached.
+func (h *Server) SetQueueStats(stats func() agentqueue.Stats) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	h.queueStats = stats
+}
+
 func (h *Server) IsStale() bool {
 	return h.heartbeatTimestamp == nil || h.heartbeatTimestamp.Add(h.heartbeatHealthyDuration).Before(time.Now())
 }
@@ -45,6 +61,9 @@ func (h *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				h.heartbeatTimestamp.Format(time.RFC3339Nano),
 				h.heartbeatHealthyDuration),
 			http.StatusServiceUnavailable)
+	} else if h.queueStats != nil {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(healthResponse{Queue: h.queueStats()})
 	} else {
 		w.WriteHeader(http.StatusNoContent)
 	}
@@ -14,6 +14,8 @@ import (
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/agentclient/useragent"
+	"github.com/distr-sh/distr/internal/agentnet"
+	"github.com/distr-sh/distr/internal/agentqueue"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	"github.com/distr-sh/distr/internal/deploymentlogs"
 	"github.com/distr-sh/distr/internal/deploymenttargetlogs"
@@ -34,6 +36,11 @@ type clientData struct {
 	metricsEndpoint              string
 	deploymentLogsEndpoint       string
 	deploymentTargetLogsEndpoint string
+	// plansEndpoint and metadataEndpoint are only used by the OpenTofu agent
+	plansEndpoint    string
+	metadataEndpoint string
+	// volumeSnapshotsEndpoint is only used by the Docker agent
+	volumeSnapshotsEndpoint string
 }
 
 type Client struct {
@@ -43,24 +50,78 @@ type Client struct {
 	token      jwt.Token
 	rawToken   string
 	mutex      sync.Mutex
+
+	resourceMutex sync.Mutex
+	resource      *api.AgentResource
+	resourceETag  string
+
+	queue *agentqueue.Queue
+}
+
+// SetQueue enables queueing of status updates, logs and metrics. While the hub cannot be reached, they are kept in
+// the queue and sent in order, with their original timestamps, once the hub can be reached again.
+func (c *Client) SetQueue(queue *agentqueue.Queue) {
+	c.queue = queue
+}
+
+// QueueStats returns the depth of the queue, which is empty if queueing is not enabled.
+func (c *Client) QueueStats() agentqueue.Stats {
+	if c.queue == nil {
+		return agentqueue.Stats{}
+	}
+	return c.queue.Stats()
 }
 
+// Resource returns the resource of the agent. If it did not change since the last call, the hub only confirms
+// that and the previous resource is returned.
 func (c *Client) Resource(ctx context.Context) (*api.AgentResource, error) {
+	resource, _, err := c.resourceWithWait(ctx, 0)
+	return resource, err
+}
+
+// resourceWithWait returns the resource of the agent. If wait is positive, the hub holds back the response until
+// the resource changes or the time is up, and then returns the previous resource if it did not change. etag is
+// empty if the hub does not support ETags.
+func (c *Client) resourceWithWait(
+	ctx context.Context,
+	wait time.Duration,
+) (resource *api.AgentResource, etag string, err error) {
+	c.resourceMutex.Lock()
+	previous, previousETag := c.resource, c.resourceETag
+	c.resourceMutex.Unlock()
+
+	endpoint := c.resourceEndpoint
+	if wait > 0 {
+		endpoint += fmt.Sprintf("?wait=%d", int(wait.Seconds()))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if previous != nil && previousETag != "" {
+		req.Header.Set("If-None-Match", previousETag)
+	}
+
+	resp, err := c.doAuthenticated(ctx, req, true)
+	if statusErr, ok := errors.AsType[*httpstatus.StatusError](err); ok &&
+		statusErr.StatusCode == http.StatusNotModified {
+		c.replayQueue()
+		return previous, previousETag, nil
+	} else if err != nil {
+		return nil, "", err
+	}
+	defer drainAndClose(resp)
+	c.replayQueue()
 	var result api.AgentResource
-	if req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.resourceEndpoint, nil); err != nil {
-		return nil, err
-	} else {
-		req.Header.Set("Content-Type", "application/json")
-		if resp, err := c.doAuthenticated(ctx, req, true); err != nil {
-			return nil, err
-		} else {
-			defer drainAndClose(resp)
-			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-				return nil, err
-			}
-			return &result, nil
-		}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, "", err
 	}
+	etag = resp.Header.Get("ETag")
+	c.resourceMutex.Lock()
+	c.resource, c.resourceETag = &result, etag
+	c.resourceMutex.Unlock()
+	return &result, etag, nil
 }
 
 func (c *Client) Manifest(ctx context.Context) ([]byte, error) {
@@ -89,14 +150,49 @@ func (c *Client) Status(
 		Message:    message,
 		Type:       statusType,
 	}
+	return c.sendOrQueue(ctx, agentqueue.KindStatus, deploymentStatus)
+}
+
+// Plan reports the plan of an OpenTofu deployment revision, which must then be approved by a user.
+func (c *Client) Plan(ctx context.Context, plan api.AgentDeploymentPlan) error {
+	return c.sendJSON(ctx, http.MethodPost, c.plansEndpoint, plan)
+}
+
+// Metadata reports the metadata of a deployment after it was applied, e.g. the outputs of an OpenTofu module.
+func (c *Client) Metadata(ctx context.Context, metadata api.AgentDeploymentMetadata) error {
+	return c.sendJSON(ctx, http.MethodPut, c.metadataEndpoint, metadata)
+}
+
+// VolumeSnapshots reports all volume snapshots that the agent keeps for a deployment.
+func (c *Client) VolumeSnapshots(ctx context.Context, snapshots api.AgentVolumeSnapshots) error {
+	if c.volumeSnapshotsEndpoint == "" {
+		return errors.New("DISTR_VOLUME_SNAPSHOTS_ENDPOINT is not set")
+	}
+	return c.sendJSON(ctx, http.MethodPut, c.volumeSnapshotsEndpoint, snapshots)
+}
+
+func (c *Client) sendJSON(ctx context.Context, method string, endpoint string, body any) error {
+	return c.sendJSONWithLogging(ctx, method, endpoint, body, true)
+}
+
+func (c *Client) sendJSONWithLogging(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	body any,
+	loggingEnabled bool,
+) error {
+	if endpoint == "" {
+		return fmt.Errorf("no endpoint configured for %v request", method)
+	}
 	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(deploymentStatus); err != nil {
+	if err := json.NewEncoder(&buf).Encode(body); err != nil {
 		return err
-	} else if req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.statusEndpoint, &buf); err != nil {
+	} else if req, err := http.NewRequestWithContext(ctx, method, endpoint, &buf); err != nil {
 		return err
 	} else {
 		req.Header.Set("Content-Type", "application/json")
-		if resp, err := c.doAuthenticated(ctx, req, true); err != nil {
+		if resp, err := c.doAuthenticated(ctx, req, loggingEnabled); err != nil {
 			return err
 		} else {
 			drainAndClose(resp)
@@ -106,49 +202,135 @@ func (c *Client) Status(
 }
 
 func (c *Client) ExportDeploymentLogs(ctx context.Context, records []api.DeploymentLogRecord) error {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(records); err != nil {
-		return err
-	} else if req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.deploymentLogsEndpoint, &buf); err != nil {
-		return err
-	} else {
-		req.Header.Set("Content-Type", "application/json")
-		if resp, err := c.doAuthenticated(ctx, req, true); err != nil {
-			if statusErr, ok := errors.AsType[*httpstatus.StatusError](err); ok &&
-				statusErr.StatusCode == http.StatusBadRequest {
-				// The server rejected the batch as invalid; surface it as a permanent
-				// rejection so the collector drops it instead of retrying forever.
-				return fmt.Errorf("%w: %w", deploymentlogs.ErrRecordsRejected, err)
-			}
+	return c.sendOrQueue(ctx, agentqueue.KindDeploymentLogs, records)
+}
+
+func (c *Client) ExportDeploymentTargetLogs(records ...api.DeploymentTargetLogRecord) error {
+	return c.sendOrQueue(context.TODO(), agentqueue.KindDeploymentTargetLogs, records)
+}
+
+// sendOrQueue sends the payload of a request that can be queued. If queueing is enabled and the hub cannot be
+// reached, the request is queued and no error is returned. If other requests are queued already, it is queued as
+// well, so that the order is kept.
+func (c *Client) sendOrQueue(ctx context.Context, kind agentqueue.Kind, payload any) error {
+	if c.queue == nil {
+		return c.send(ctx, kind, payload)
+	}
+	createdAt := time.Now().UTC()
+	queued := c.queue.Len() > 0
+	if !queued {
+		if err := c.send(ctx, kind, payload); err == nil || !isRetryable(err) {
 			return err
-		} else {
-			drainAndClose(resp)
 		}
-		return nil
 	}
+	if data, err := json.Marshal(payload); err != nil {
+		return err
+	} else if err := c.queue.Push(agentqueue.Entry{Kind: kind, CreatedAt: createdAt, Payload: data}); err != nil {
+		return fmt.Errorf("failed to queue %v request: %w", kind, err)
+	}
+	if queued {
+		c.replayQueue()
+	}
+	return nil
 }
 
-func (c *Client) ExportDeploymentTargetLogs(records ...api.DeploymentTargetLogRecord) error {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(records); err != nil {
+func (c *Client) send(ctx context.Context, kind agentqueue.Kind, payload any) error {
+	switch kind {
+	case agentqueue.KindStatus:
+		return c.sendJSON(ctx, http.MethodPost, c.statusEndpoint, payload)
+	case agentqueue.KindMetrics:
+		return c.sendJSON(ctx, http.MethodPost, c.metricsEndpoint, payload)
+	case agentqueue.KindDeploymentLogs:
+		err := c.sendJSON(ctx, http.MethodPut, c.deploymentLogsEndpoint, payload)
+		if isBadRequest(err) {
+			// The server rejected the batch as invalid; surface it as a permanent
+			// rejection so the collector drops it instead of retrying forever.
+			return fmt.Errorf("%w: %w", deploymentlogs.ErrRecordsRejected, err)
+		}
 		return err
-	} else if req, err := http.NewRequest(http.MethodPut, c.deploymentTargetLogsEndpoint, &buf); err != nil {
+	case agentqueue.KindDeploymentTargetLogs:
+		err := c.sendJSONWithLogging(ctx, http.MethodPut, c.deploymentTargetLogsEndpoint, payload, false)
+		if isBadRequest(err) {
+			return fmt.Errorf("%w: %w", deploymenttargetlogs.ErrRecordsRejected, err)
+		}
 		return err
+	default:
+		return fmt.Errorf("unknown request kind: %v", kind)
+	}
+}
+
+// sendQueued sends a queued request. Status updates and metrics are sent with the time when they were queued, log
+// records have their own timestamps.
+func (c *Client) sendQueued(ctx context.Context, entry agentqueue.Entry) error {
+	var payload any = entry.Payload
+	switch entry.Kind {
+	case agentqueue.KindStatus:
+		var status api.AgentDeploymentStatus
+		if err := json.Unmarshal(entry.Payload, &status); err != nil {
+			return fmt.Errorf("%w: %w", agentqueue.ErrRejected, err)
+		}
+		status.CreatedAt = &entry.CreatedAt
+		payload = status
+	case agentqueue.KindMetrics:
+		var metrics api.AgentDeploymentTargetMetricsRequest
+		if err := json.Unmarshal(entry.Payload, &metrics); err != nil {
+			return fmt.Errorf("%w: %w", agentqueue.ErrRejected, err)
+		}
+		metrics.CreatedAt = &entry.CreatedAt
+		payload = metrics
+	}
+	if err := c.send(ctx, entry.Kind, payload); err != nil && !isRetryable(err) {
+		return fmt.Errorf("%w: %w", agentqueue.ErrRejected, err)
 	} else {
-		req.Header.Set("Content-Type", "application/json")
-		if resp, err := c.doAuthenticated(context.TODO(), req, false); err != nil {
-			if statusErr, ok := errors.AsType[*httpstatus.StatusError](err); ok &&
-				statusErr.StatusCode == http.StatusBadRequest {
-				// The server rejected the batch as invalid; surface it as a permanent
-				// rejection so the collector drops it instead of retrying forever.
-				return fmt.Errorf("%w: %w", deploymenttargetlogs.ErrRecordsRejected, err)
+		return err
+	}
+}
+
+// replayQueue sends the queued requests in the background, unless they are being sent already.
+func (c *Client) replayQueue() {
+	if c.queue == nil || c.queue.Len() == 0 {
+		return
+	}
+	go func() {
+		sent, ok, err := c.queue.TryReplay(func(entry agentqueue.Entry) error {
+			err := c.sendQueued(context.Background(), entry)
+			if errors.Is(err, agentqueue.ErrRejected) {
+				c.logger.Warn("dropping queued request rejected by the hub",
+					zap.String("kind", string(entry.Kind)), zap.Error(err))
 			}
 			return err
-		} else {
-			drainAndClose(resp)
+		})
+		if !ok {
+			return
+		} else if sent > 0 {
+			c.logger.Info("sent queued requests", zap.Int("count", sent), zap.Int("remaining", c.queue.Len()))
+		}
+		if err != nil {
+			c.logger.Debug("failed to send queued requests", zap.Error(err))
+		}
+	}()
+}
+
+// isRetryable reports whether a request that failed with err may succeed later, e.g. because the hub could not be
+// reached or had an internal error. Requests that the hub rejected as invalid are never retried, and neither are
+// requests that the caller canceled.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	} else if statusErr, ok := errors.AsType[*httpstatus.StatusError](err); ok {
+		switch statusErr.StatusCode {
+		case http.StatusUnauthorized, http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		default:
+			return statusErr.StatusCode >= 500
 		}
-		return nil
 	}
+	return true
+}
+
+func isBadRequest(err error) bool {
+	statusErr, ok := errors.AsType[*httpstatus.StatusError](err)
+	return ok && statusErr.StatusCode == http.StatusBadRequest
 }
 
 func (c *Client) Login(ctx context.Context) error {
@@ -218,20 +400,7 @@ func (c *Client) RawToken() string {
 }
 
 func (c *Client) ReportMetrics(ctx context.Context, metrics api.AgentDeploymentTargetMetricsRequest) error {
-	var buf bytes.Buffer
-	if err := json.NewEncoder(&buf).Encode(metrics); err != nil {
-		return err
-	} else if req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.metricsEndpoint, &buf); err != nil {
-		return err
-	} else {
-		req.Header.Set("Content-Type", "application/json")
-		if resp, err := c.doAuthenticated(ctx, req, true); err != nil {
-			return err
-		} else {
-			drainAndClose(resp)
-			return nil
-		}
-	}
+	return c.sendOrQueue(ctx, agentqueue.KindMetrics, metrics)
 }
 
 func (c *Client) doAuthenticated(ctx context.Context, r *http.Request, loggingEnabled bool) (*http.Response, error) {
@@ -305,10 +474,16 @@ func (c *Client) ReloadFromEnv() (changed bool, err error) {
 	} else if d.deploymentTargetLogsEndpoint, err = readEnvVar("DISTR_AGENT_LOGS_ENDPOINT"); err != nil {
 		return changed, err
 	} else {
+		d.plansEndpoint = os.Getenv("DISTR_PLANS_ENDPOINT")
+		d.metadataEndpoint = os.Getenv("DISTR_METADATA_ENDPOINT")
+		d.volumeSnapshotsEndpoint = os.Getenv("DISTR_VOLUME_SNAPSHOTS_ENDPOINT")
 		changed = c.clientData != d
 		if changed {
 			c.clientData = d
 			c.ClearToken()
+			c.resourceMutex.Lock()
+			c.resource, c.resourceETag = nil, ""
+			c.resourceMutex.Unlock()
 		}
 		return changed, err
 	}
@@ -317,7 +492,8 @@ func (c *Client) ReloadFromEnv() (changed bool, err error) {
 func NewFromEnv(logger *zap.Logger) (*Client, error) {
 	client := Client{
 		httpClient: &http.Client{
-			Timeout: 2 * time.Minute,
+			Timeout:   2 * time.Minute,
+			Transport: agentnet.Transport(),
 		},
 		logger: logger,
 	}
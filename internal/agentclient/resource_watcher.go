@@ -0,0 +1,80 @@
+package agentclient
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"go.uber.org/zap"
+)
+
+// ResourceWatcher keeps the resource of the agent up to date by waiting at the hub for it to change, so that
+// agents apply changes right away instead of requesting their resource every interval.
+type ResourceWatcher struct {
+	client        *Client
+	retryInterval time.Duration
+	changed       chan struct{}
+	watching      atomic.Bool
+}
+
+// WatchResource starts to wait for changes of the resource until ctx is done. If wait is zero or the hub does not
+// support waiting, the watcher never reports changes and ResourceWatcher.Resource requests the resource on every
+// call. After an error, waiting is retried after retryInterval.
+func (c *Client) WatchResource(ctx context.Context, wait, retryInterval time.Duration) *ResourceWatcher {
+	w := &ResourceWatcher{client: c, retryInterval: retryInterval, changed: make(chan struct{}, 1)}
+	if wait > 0 {
+		go w.run(ctx, wait)
+	}
+	return w
+}
+
+// Changed receives a value when the resource changed. Changes are not queued, a receiver that is slow to react
+// only gets one value for multiple changes.
+func (w *ResourceWatcher) Changed() <-chan struct{} {
+	return w.changed
+}
+
+// Resource returns the latest resource. While the watcher waits for changes at the hub, it does so without a
+// request.
+func (w *ResourceWatcher) Resource(ctx context.Context) (*api.AgentResource, error) {
+	if w.watching.Load() {
+		w.client.resourceMutex.Lock()
+		resource := w.client.resource
+		w.client.resourceMutex.Unlock()
+		if resource != nil {
+			return resource, nil
+		}
+	}
+	return w.client.Resource(ctx)
+}
+
+func (w *ResourceWatcher) run(ctx context.Context, wait time.Duration) {
+	var previous *api.AgentResource
+	for ctx.Err() == nil {
+		resource, etag, err := w.client.resourceWithWait(ctx, wait)
+		if err != nil {
+			w.watching.Store(false)
+			if ctx.Err() == nil {
+				w.client.logger.Warn("waiting for resource changes failed", zap.Error(err))
+				select {
+				case <-ctx.Done():
+				case <-time.After(w.retryInterval):
+				}
+			}
+			continue
+		} else if etag == "" {
+			w.client.logger.Info("hub does not support waiting for resource changes")
+			return
+		}
+		w.watching.Store(true)
+		if resource != previous {
+			previous = resource
+			select {
+			case w.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+	w.watching.Store(false)
+}
@@ -55,11 +55,11 @@ func BuildPreConnectURL(
 
 func GenerateConnectScript(
 	ctx context.Context,
-	targetID uuid.UUID,
+	deploymentTarget types.DeploymentTarget,
 	org types.OrganizationWithBranding,
 	targetSecret string,
 ) (string, error) {
-	connectURL, err := BuildConnectURL(ctx, targetID, org, targetSecret)
+	connectURL, err := BuildConnectURL(ctx, deploymentTarget.ID, org, targetSecret)
 	if err != nil {
 		return "", fmt.Errorf("failed to build connect URL: %w", err)
 	}
@@ -74,7 +74,7 @@ func GenerateConnectScript(
 	}
 
 	script.WriteString("# Connect to Distr agent\n")
-	script.WriteString(generateDockerConnectCommand(connectURL))
+	script.WriteString(generateDockerConnectCommand(deploymentTarget.GetContainerRuntime(), connectURL))
 
 	if org.PostConnectScript != nil && strings.TrimSpace(*org.PostConnectScript) != "" {
 		script.WriteString("\n\n# Post-connect script\n")
@@ -93,8 +93,25 @@ func generateScriptCommand(scriptURL string, isSudo bool) string {
 	return fmt.Sprintf("curl -fsSL '%s' | %s", scriptURL, shCmd)
 }
 
-func generateDockerConnectCommand(connectURL string) string {
-	return fmt.Sprintf("curl -fsSL '%s' | docker compose -f - up -d", connectURL)
+func generateDockerConnectCommand(runtime types.ContainerRuntime, connectURL string) string {
+	switch runtime {
+	case types.ContainerRuntimePodman:
+		// The socket of rootless Podman depends on the user, so it is detected on the host and passed to the manifest.
+		return fmt.Sprintf(
+			"curl -fsSL '%s' | HOST_DOCKER_SOCKET=\"$(podman info --format '{{.Host.RemoteSocket.Path}}')\" "+
+				"podman compose -f - up -d",
+			connectURL,
+		)
+	case types.ContainerRuntimeQuadlet:
+		// Not run with sudo, because the script installs the agent for rootless Podman if run by a regular user.
+		return fmt.Sprintf("curl -fsSL '%s' | sh", connectURL)
+	default:
+		return fmt.Sprintf("curl -fsSL '%s' | docker compose -f - up -d", connectURL)
+	}
+}
+
+func generateSystemdConnectCommand(connectURL string) string {
+	return fmt.Sprintf("curl -fsSL '%s' | sudo sh", connectURL)
 }
 
 func generateKubernetesConnectCommand(namespace string, connectURL string) string {
@@ -122,12 +139,14 @@ func GenerateConnectCommand(
 
 	switch deploymentTarget.Type {
 	case types.DeploymentTypeDocker:
-		return generateDockerConnectCommand(connectURL), nil
+		return generateDockerConnectCommand(deploymentTarget.GetContainerRuntime(), connectURL), nil
 	case types.DeploymentTypeKubernetes:
 		if deploymentTarget.Namespace == nil {
 			return "", fmt.Errorf("kubernetes deployment target must have a namespace")
 		}
 		return generateKubernetesConnectCommand(*deploymentTarget.Namespace, connectURL), nil
+	case types.DeploymentTypeSystemd, types.DeploymentTypeOpenTofu:
+		return generateSystemdConnectCommand(connectURL), nil
 	default:
 		return "", fmt.Errorf("unsupported deployment type: %s", deploymentTarget.Type)
 	}
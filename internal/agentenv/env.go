@@ -13,4 +13,19 @@ var (
 	Interval               = envutil.GetEnvParsedOrDefault("DISTR_INTERVAL", envparse.PositiveDuration, 5*time.Second)
 	DistrRegistryHost      = envutil.GetEnv("DISTR_REGISTRY_HOST")
 	DistrRegistryPlainHTTP = envutil.GetEnvParsedOrDefault("DISTR_REGISTRY_PLAIN_HTTP", strconv.ParseBool, false)
+
+	// ResourceWait is how long the hub holds back a resource request until the resource changes. Zero disables
+	// waiting, the resource is then requested every Interval.
+	ResourceWait = envutil.GetEnvParsedOrDefault(
+		"DISTR_RESOURCE_WAIT", envparse.NonNegativeDuration, 50*time.Second)
+
+	// QueueMaxSizeMB limits the size of the queue for status updates, logs and metrics that are kept while the hub
+	// cannot be reached. If it is exceeded, the oldest entries are dropped.
+	QueueMaxSizeMB = envutil.GetEnvParsedOrDefault("DISTR_QUEUE_MAX_SIZE_MB", envparse.PositiveNumber, 64)
+
+	// SystemdDeploymentsDir holds a directory for every deployment of the systemd agent with its installed versions.
+	SystemdDeploymentsDir = envutil.GetEnvOrDefault(
+		"DISTR_SYSTEMD_DEPLOYMENTS_DIR", "/opt/distr", envutil.GetEnvOpts{})
+	// SystemdUnitDir is where the systemd agent writes the units of the deployments to.
+	SystemdUnitDir = envutil.GetEnvOrDefault("DISTR_SYSTEMD_UNIT_DIR", "/etc/systemd/system", envutil.GetEnvOpts{})
 )
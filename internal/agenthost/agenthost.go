@@ -0,0 +1,97 @@
+// Package agenthost contains what the agents that are installed directly on a host and run as a systemd service have
+// in common, like the systemd agent and the OpenTofu agent.
+package agenthost
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/agentclient"
+	"github.com/distr-sh/distr/internal/agentenv"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ScratchDir holds the state of the agent, like the deployments it has installed.
+func ScratchDir() string {
+	if dir := os.Getenv("DISTR_AGENT_SCRATCH_DIR"); dir != "" {
+		return dir
+	}
+	return "./scratch"
+}
+
+// StartHealthServer serves the health check of the agent on the port that the install script checks.
+func StartHealthServer(handler http.Handler) error {
+	err := http.ListenAndServe("127.0.0.1:8765", handler)
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
+
+// SendProgressInterval reports the last status passed to the returned function as progress of the revision every
+// interval, until ctx is canceled.
+func SendProgressInterval(
+	ctx context.Context,
+	logger *zap.Logger,
+	client *agentclient.Client,
+	revisionID uuid.UUID,
+) func(string) {
+	var status atomic.Value
+	status.Store("initializing")
+
+	go func() {
+		tick := time.Tick(agentenv.Interval)
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Debug("stop sending progress updates")
+				return
+			case <-tick:
+				logger.Info("sending progress update")
+				err := client.Status(
+					ctx,
+					revisionID,
+					types.DeploymentStatusTypeProgressing,
+					status.Load().(string),
+				)
+				if err != nil {
+					logger.Warn("error updating status", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func(s string) { status.Store(s) }
+}
+
+// SelfUpdateIfRequired starts a self-update if the hub expects another agent version. It reports whether the update
+// was started, in which case the agent is going to be restarted soon.
+func SelfUpdateIfRequired(ctx context.Context, update SelfUpdate, resource api.AgentResource) bool {
+	if agentenv.AgentVersionID == "" {
+		return false
+	} else if agentenv.AgentVersionID == resource.Version.ID.String() {
+		update.Logger.Debug("agent version is up to date")
+		return false
+	}
+
+	update.Logger.Info("agent version has changed. starting self-update")
+	if err := update.Run(ctx); err != nil {
+		update.Logger.Error("self update failed", zap.Error(err))
+		if len(resource.Deployments) > 0 {
+			if err := update.Client.StatusWithError(ctx, resource.Deployments[0].RevisionID, err); err != nil {
+				update.Logger.Error("failed to send status", zap.Error(err))
+			}
+		}
+		return false
+	}
+	update.Logger.Info("self-update has been started")
+	return true
+}
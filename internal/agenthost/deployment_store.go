@@ -0,0 +1,72 @@
+package agenthost
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Deployment is the state that an agent keeps for each of its deployments.
+type Deployment interface {
+	GetDeploymentID() uuid.UUID
+}
+
+// DeploymentStore keeps a JSON file for every deployment in the deployments directory of ScratchDir.
+type DeploymentStore[T Deployment] struct {
+	mut sync.RWMutex
+}
+
+func (s *DeploymentStore[T]) Dir() string {
+	return path.Join(ScratchDir(), "deployments")
+}
+
+func (s *DeploymentStore[T]) FileName(deployment T) string {
+	return path.Join(s.Dir(), deployment.GetDeploymentID().String())
+}
+
+func (s *DeploymentStore[T]) GetAll() (map[uuid.UUID]T, error) {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	entries, err := os.ReadDir(s.Dir())
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	result := make(map[uuid.UUID]T, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		var d T
+		if data, err := os.ReadFile(path.Join(s.Dir(), entry.Name())); err != nil {
+			return nil, err
+		} else if err := json.Unmarshal(data, &d); err != nil {
+			return nil, err
+		}
+		result[d.GetDeploymentID()] = d
+	}
+	return result, nil
+}
+
+func (s *DeploymentStore[T]) Save(deployment T) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if err := os.MkdirAll(s.Dir(), 0o700); err != nil {
+		return err
+	} else if data, err := json.Marshal(deployment); err != nil {
+		return err
+	} else {
+		return os.WriteFile(s.FileName(deployment), data, 0o600)
+	}
+}
+
+func (s *DeploymentStore[T]) Delete(deployment T) error {
+	return os.Remove(s.FileName(deployment))
+}
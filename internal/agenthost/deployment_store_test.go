@@ -0,0 +1,40 @@
+package agenthost
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+type testDeployment struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+}
+
+func (d testDeployment) GetDeploymentID() uuid.UUID {
+	return d.ID
+}
+
+func TestDeploymentStore(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("DISTR_AGENT_SCRATCH_DIR", t.TempDir())
+	var store DeploymentStore[testDeployment]
+
+	deployments, err := store.GetAll()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deployments).To(BeEmpty())
+
+	d := testDeployment{ID: uuid.New(), Name: "a"}
+	g.Expect(store.Save(d)).To(Succeed())
+	d.Name = "b"
+	g.Expect(store.Save(d)).To(Succeed())
+	deployments, err = store.GetAll()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deployments).To(Equal(map[uuid.UUID]testDeployment{d.ID: d}))
+
+	g.Expect(store.Delete(d)).To(Succeed())
+	deployments, err = store.GetAll()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deployments).To(BeEmpty())
+}
@@ -0,0 +1,63 @@
+package agenthost
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/agentclient"
+	"go.uber.org/zap"
+)
+
+// DefaultUpdateUnitName is the fixed name of the transient unit that runs the install script, used to enforce
+// single-flight.
+const DefaultUpdateUnitName = "distr-agent-update"
+
+// SelfUpdate runs the install script of the new agent version in a transient unit. Running it from the agent directly
+// would not work, because the script restarts the agent and would be stopped along with it.
+type SelfUpdate struct {
+	Logger *zap.Logger
+	Client *agentclient.Client
+	// UnitName defaults to DefaultUpdateUnitName.
+	UnitName string
+	// User runs the unit with the service manager of the user instead of the one of the system.
+	User bool
+}
+
+func (u SelfUpdate) Run(ctx context.Context) error {
+	unitName := u.UnitName
+	if unitName == "" {
+		unitName = DefaultUpdateUnitName
+	}
+
+	if out, _ := exec.CommandContext(ctx, "systemctl", u.args("is-active", unitName)...).Output(); strings.TrimSpace(
+		string(out)) == "active" {
+		u.Logger.Info("self-update is already in progress, skipping")
+		return nil
+	}
+
+	script, err := u.Client.Manifest(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching agent install script: %w", err)
+	}
+	file := path.Join(ScratchDir(), "distr-update.sh")
+	if err := os.WriteFile(file, script, 0o700); err != nil {
+		return err
+	}
+
+	out, err := exec.CommandContext(ctx,
+		"systemd-run", u.args("--unit", unitName, "--collect", "--no-block", "sh", file)...,
+	).CombinedOutput()
+	u.Logger.Sugar().Infof("self-update output: %v", strings.TrimSpace(string(out)))
+	return err
+}
+
+func (u SelfUpdate) args(args ...string) []string {
+	if u.User {
+		return append([]string{"--user"}, args...)
+	}
+	return args
+}
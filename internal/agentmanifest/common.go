@@ -3,10 +3,16 @@ package agentmanifest
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"fmt"
 	"io"
+	"maps"
 	"net/url"
 	"path"
+	"slices"
+	"strings"
 	"text/template"
 
 	"github.com/distr-sh/distr/internal/buildconfig"
@@ -14,17 +20,19 @@ import (
 	"github.com/distr-sh/distr/internal/env"
 	"github.com/distr-sh/distr/internal/resources"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
 )
 
 func Get(
 	ctx context.Context,
 	deploymentTarget types.DeploymentTargetFull,
 	org types.OrganizationWithBranding,
+	network *types.DeploymentTargetAgentNetwork,
 	secret *string,
 ) (io.Reader, error) {
 	if tmpl, err := getTemplate(deploymentTarget); err != nil {
 		return nil, err
-	} else if data, err := getTemplateData(ctx, deploymentTarget, org, secret); err != nil {
+	} else if data, err := getTemplateData(ctx, deploymentTarget, org, network, secret); err != nil {
 		return nil, err
 	} else {
 		var buf bytes.Buffer
@@ -36,6 +44,7 @@ func getTemplateData(
 	ctx context.Context,
 	deploymentTarget types.DeploymentTargetFull,
 	org types.OrganizationWithBranding,
+	network *types.DeploymentTargetAgentNetwork,
 	secret *string,
 ) (map[string]any, error) {
 	var (
@@ -46,6 +55,9 @@ func getTemplateData(
 		metricsEndpoint   string
 		logsEndpoint      string
 		agentLogsEndpoint string
+		plansEndpoint     string
+		metadataEndpoint  string
+		snapshotsEndpoint string
 	)
 
 	if u, err := url.Parse(customdomains.AppDomainOrDefault(ctx, org.ID, org.Branding)); err != nil {
@@ -59,28 +71,37 @@ func getTemplateData(
 		metricsEndpoint = u.JoinPath("metrics").String()
 		logsEndpoint = u.JoinPath("logs").String()
 		agentLogsEndpoint = u.JoinPath("deployment-target-logs").String()
+		plansEndpoint = u.JoinPath("plans").String()
+		metadataEndpoint = u.JoinPath("metadata").String()
+		snapshotsEndpoint = u.JoinPath("volume-snapshots").String()
 	}
 
 	result := map[string]any{
-		"agentDockerConfig": base64.StdEncoding.EncodeToString(env.AgentDockerConfig()),
-		"agentInterval":     env.AgentInterval(),
-		"agentVersion":      deploymentTarget.AgentVersion.Name,
-		"agentVersionId":    deploymentTarget.AgentVersion.ID,
-		"autohealAll":       deploymentTarget.AutohealEnabled,
-		"loginEndpoint":     loginEndpoint,
-		"manifestEndpoint":  manifestEndpoint,
-		"metricsEndpoint":   metricsEndpoint,
-		"registryEnabled":   env.RegistryEnabled(),
-		"registryHost":      customdomains.RegistryDomainOrDefault(ctx, org.ID, org.Branding),
-		"registryPlainHttp": buildconfig.IsDevelopment(),
-		"resourcesEndpoint": resourcesEndpoint,
-		"statusEndpoint":    statusEndpoint,
-		"targetId":          deploymentTarget.ID,
-		"targetSecret":      secret,
-		"logsEndpoint":      logsEndpoint,
-		"agentLogsEndpoint": agentLogsEndpoint,
-		"metricsEnabled":    deploymentTarget.MetricsEnabled,
-		"dockerSocketPath":  deploymentTarget.DockerSocketPath(),
+		"agentDockerConfig":       base64.StdEncoding.EncodeToString(env.AgentDockerConfig()),
+		"agentInterval":           env.AgentInterval(),
+		"agentVersion":            deploymentTarget.AgentVersion.Name,
+		"agentVersionId":          deploymentTarget.AgentVersion.ID,
+		"autohealAll":             deploymentTarget.AutohealEnabled,
+		"loginEndpoint":           loginEndpoint,
+		"manifestEndpoint":        manifestEndpoint,
+		"metricsEndpoint":         metricsEndpoint,
+		"registryEnabled":         env.RegistryEnabled(),
+		"registryHost":            customdomains.RegistryDomainOrDefault(ctx, org.ID, org.Branding),
+		"registryPlainHttp":       buildconfig.IsDevelopment(),
+		"resourcesEndpoint":       resourcesEndpoint,
+		"statusEndpoint":          statusEndpoint,
+		"targetId":                deploymentTarget.ID,
+		"targetSecret":            secret,
+		"logsEndpoint":            logsEndpoint,
+		"agentLogsEndpoint":       agentLogsEndpoint,
+		"plansEndpoint":           plansEndpoint,
+		"metadataEndpoint":        metadataEndpoint,
+		"volumeSnapshotsEndpoint": snapshotsEndpoint,
+		"metricsEnabled":          deploymentTarget.MetricsEnabled,
+		"dockerSocketPath":        deploymentTarget.DockerSocketPath(),
+		"podman":                  deploymentTarget.IsPodman(),
+		// Rootless Podman has a socket per user, which the connect command detects on the host.
+		"detectDockerSocket": deploymentTarget.IsPodman() && deploymentTarget.DockerEndpoint == nil,
 	}
 	if deploymentTarget.Namespace != nil {
 		result["targetNamespace"] = *deploymentTarget.Namespace
@@ -91,16 +112,55 @@ func getTemplateData(
 	if deploymentTarget.Resources != nil {
 		result["targetResources"] = deploymentTarget.Resources
 	}
+	if network != nil {
+		if err := addAgentNetworkTemplateData(result, *network); err != nil {
+			return nil, err
+		}
+	}
 	return result, nil
 }
 
+func addAgentNetworkTemplateData(result map[string]any, network types.DeploymentTargetAgentNetwork) error {
+	proxyURL, err := network.ProxyURLWithCredentials()
+	if err != nil {
+		return err
+	}
+	// Docker Compose interpolates "$" in the manifest, which url.UserPassword does not escape.
+	proxyURL = strings.ReplaceAll(proxyURL, "$", "%24")
+	values := map[string]string{
+		"proxyUrl":          proxyURL,
+		"noProxy":           util.PtrDerefOrDefault(network.NoProxy),
+		"caCertificates":    util.PtrDerefOrDefault(network.CACertificates),
+		"clientCertificate": util.PtrDerefOrDefault(network.ClientCertificate),
+		"clientKey":         util.PtrDerefOrDefault(network.ClientKey),
+	}
+	// Changes of the settings must restart the Kubernetes agent, which only reads them on startup.
+	checksum := sha256.New()
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		if value := values[key]; value != "" {
+			result[key] = value
+			_, _ = fmt.Fprintf(checksum, "%v=%q\n", key, value)
+		}
+	}
+	result["agentNetworkChecksum"] = hex.EncodeToString(checksum.Sum(nil))
+	return nil
+}
+
 func getTemplate(deploymentTarget types.DeploymentTargetFull) (*template.Template, error) {
-	if deploymentTarget.Type == types.DeploymentTypeDocker {
+	if deploymentTarget.Type == types.DeploymentTypeDocker &&
+		deploymentTarget.GetContainerRuntime() == types.ContainerRuntimeQuadlet {
+		return resources.GetTemplate("agent/quadlet/v1/install.sh.tmpl")
+	} else if deploymentTarget.Type == types.DeploymentTypeDocker {
 		return resources.GetTemplate(path.Join(
 			"agent/docker",
 			deploymentTarget.AgentVersion.ComposeFileRevision,
 			"docker-compose.yaml.tmpl",
 		))
+	} else if deploymentTarget.Type == types.DeploymentTypeSystemd {
+		// There is only one revision of the install script so far, so AgentVersion does not need to track it yet.
+		return resources.GetTemplate("agent/systemd/v1/install.sh.tmpl")
+	} else if deploymentTarget.Type == types.DeploymentTypeOpenTofu {
+		return resources.GetTemplate("agent/opentofu/v1/install.sh.tmpl")
 	} else {
 		return resources.GetTemplate(path.Join(
 			"agent/kubernetes",
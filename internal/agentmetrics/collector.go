@@ -0,0 +1,167 @@
+// Package agentmetrics exposes the latest metrics that an agent reported to the hub in the Prometheus exposition
+// format, so that they can also be scraped by a Prometheus server of the customer.
+package agentmetrics
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "distr_agent"
+
+var (
+	diskLabels      = []string{"device", "path", "fs_type"}
+	containerLabels = []string{"deployment_id", "service", "name"}
+
+	cpuCoresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "cpu_cores"),
+		"Number of CPU cores of the host or cluster.",
+		nil, nil,
+	)
+	cpuUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "cpu_usage_ratio"),
+		"CPU usage of the host or cluster.",
+		nil, nil,
+	)
+	memoryBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_bytes"),
+		"Memory of the host or cluster.",
+		nil, nil,
+	)
+	memoryUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "", "memory_usage_ratio"),
+		"Memory usage of the host or cluster.",
+		nil, nil,
+	)
+	diskSizeDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "size_bytes"),
+		"Size of a filesystem of the host.",
+		diskLabels, nil,
+	)
+	diskUsedDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "disk", "used_bytes"),
+		"Used bytes of a filesystem of the host.",
+		diskLabels, nil,
+	)
+	containerCPUCoresDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "cpu_cores"),
+		"CPU cores used by a container or pod of a deployment.",
+		containerLabels, nil,
+	)
+	containerCPUUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "cpu_usage_ratio"),
+		"CPU usage of a container or pod relative to its limit, or to the CPUs of the host if it has none.",
+		containerLabels, nil,
+	)
+	containerMemoryBytesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "memory_bytes"),
+		"Memory used by a container or pod of a deployment.",
+		containerLabels, nil,
+	)
+	containerMemoryUsageDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "memory_usage_ratio"),
+		"Memory usage of a container or pod relative to its limit, or to the memory of the host if it has none.",
+		containerLabels, nil,
+	)
+	containerRestartsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "restarts_total"),
+		"Number of restarts of a container or of the containers of a pod.",
+		containerLabels, nil,
+	)
+	containerNetworkRxDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "network_receive_bytes_total"),
+		"Bytes received by a container since it was started.",
+		containerLabels, nil,
+	)
+	containerNetworkTxDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "container", "network_transmit_bytes_total"),
+		"Bytes transmitted by a container since it was started.",
+		containerLabels, nil,
+	)
+)
+
+// Collector exports the metrics passed to [Collector.Update]. It does not export anything before the first update.
+type Collector struct {
+	metrics *api.AgentDeploymentTargetMetricsRequest
+	mut     sync.RWMutex
+}
+
+var _ prometheus.Collector = (*Collector)(nil)
+
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Update replaces the exported metrics.
+func (c *Collector) Update(metrics api.AgentDeploymentTargetMetricsRequest) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.metrics = &metrics
+}
+
+// Handler returns a handler that serves the metrics of c and nothing else.
+func (c *Collector) Handler() http.Handler {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+}
+
+// Describe implements [prometheus.Collector].
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cpuCoresDesc
+	ch <- cpuUsageDesc
+	ch <- memoryBytesDesc
+	ch <- memoryUsageDesc
+	ch <- diskSizeDesc
+	ch <- diskUsedDesc
+	ch <- containerCPUCoresDesc
+	ch <- containerCPUUsageDesc
+	ch <- containerMemoryBytesDesc
+	ch <- containerMemoryUsageDesc
+	ch <- containerRestartsDesc
+	ch <- containerNetworkRxDesc
+	ch <- containerNetworkTxDesc
+}
+
+// Collect implements [prometheus.Collector].
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	if c.metrics == nil {
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(cpuCoresDesc, prometheus.GaugeValue, float64(c.metrics.CPUCoresMillis)/1000)
+	ch <- prometheus.MustNewConstMetric(cpuUsageDesc, prometheus.GaugeValue, c.metrics.CPUUsage)
+	ch <- prometheus.MustNewConstMetric(memoryBytesDesc, prometheus.GaugeValue, float64(c.metrics.MemoryBytes))
+	ch <- prometheus.MustNewConstMetric(memoryUsageDesc, prometheus.GaugeValue, c.metrics.MemoryUsage)
+
+	for _, disk := range c.metrics.DiskMetrics {
+		labels := []string{disk.Device, disk.Path, disk.FsType}
+		ch <- prometheus.MustNewConstMetric(diskSizeDesc, prometheus.GaugeValue, float64(disk.BytesTotal), labels...)
+		ch <- prometheus.MustNewConstMetric(diskUsedDesc, prometheus.GaugeValue, float64(disk.BytesUsed), labels...)
+	}
+
+	for _, container := range c.metrics.ContainerMetrics {
+		labels := []string{container.DeploymentID.String(), container.Service, container.Name}
+		ch <- prometheus.MustNewConstMetric(
+			containerCPUCoresDesc, prometheus.GaugeValue, float64(container.CPUUsedMillis)/1000, labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerCPUUsageDesc, prometheus.GaugeValue, container.CPUUsage, labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerMemoryBytesDesc, prometheus.GaugeValue, float64(container.MemoryUsedBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerMemoryUsageDesc, prometheus.GaugeValue, container.MemoryUsage, labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerRestartsDesc, prometheus.CounterValue, float64(container.Restarts), labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerNetworkRxDesc, prometheus.CounterValue, float64(container.NetworkRxBytes), labels...)
+		ch <- prometheus.MustNewConstMetric(
+			containerNetworkTxDesc, prometheus.CounterValue, float64(container.NetworkTxBytes), labels...)
+	}
+}
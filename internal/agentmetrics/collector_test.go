@@ -0,0 +1,82 @@
+package agentmetrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestCollector_Empty(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(testutil.CollectAndCount(NewCollector())).To(Equal(0))
+}
+
+func TestCollector(t *testing.T) {
+	g := NewWithT(t)
+	deploymentID := uuid.MustParse("0197a4c5-1b2c-7d3e-8f40-5a6b7c8d9e0f")
+	c := NewCollector()
+	c.Update(api.AgentDeploymentTargetMetricsRequest{
+		CPUCoresMillis: 4000,
+		CPUUsage:       0.5,
+		MemoryBytes:    1024,
+		MemoryUsage:    0.25,
+		DiskMetrics: []api.DeploymentTargetDiskMetric{
+			{Device: "/dev/sda1", Path: "/", FsType: "ext4", BytesTotal: 100, BytesUsed: 40},
+		},
+		ContainerMetrics: []api.DeploymentContainerMetric{
+			{
+				DeploymentID:    deploymentID,
+				Service:         "web",
+				Name:            "web-1",
+				CPUUsedMillis:   250,
+				CPUUsage:        0.125,
+				MemoryUsedBytes: 512,
+				MemoryUsage:     0.5,
+				Restarts:        3,
+				NetworkRxBytes:  10,
+				NetworkTxBytes:  20,
+			},
+		},
+	})
+
+	g.Expect(testutil.CollectAndCount(c)).To(Equal(13))
+	g.Expect(testutil.CollectAndCompare(c, strings.NewReader(`
+# HELP distr_agent_cpu_cores Number of CPU cores of the host or cluster.
+# TYPE distr_agent_cpu_cores gauge
+distr_agent_cpu_cores 4
+# HELP distr_agent_container_cpu_cores CPU cores used by a container or pod of a deployment.
+# TYPE distr_agent_container_cpu_cores gauge
+distr_agent_container_cpu_cores{deployment_id="0197a4c5-1b2c-7d3e-8f40-5a6b7c8d9e0f",name="web-1",service="web"} 0.25
+# HELP distr_agent_container_restarts_total Number of restarts of a container or of the containers of a pod.
+# TYPE distr_agent_container_restarts_total counter
+distr_agent_container_restarts_total{deployment_id="0197a4c5-1b2c-7d3e-8f40-5a6b7c8d9e0f",name="web-1",service="web"} 3
+# HELP distr_agent_disk_used_bytes Used bytes of a filesystem of the host.
+# TYPE distr_agent_disk_used_bytes gauge
+distr_agent_disk_used_bytes{device="/dev/sda1",fs_type="ext4",path="/"} 40
+`),
+		"distr_agent_cpu_cores",
+		"distr_agent_container_cpu_cores",
+		"distr_agent_container_restarts_total",
+		"distr_agent_disk_used_bytes",
+	)).To(Succeed())
+}
+
+func TestCollector_Handler(t *testing.T) {
+	g := NewWithT(t)
+	c := NewCollector()
+	c.Update(api.AgentDeploymentTargetMetricsRequest{MemoryBytes: 2048})
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	g.Expect(rec.Code).To(Equal(http.StatusOK))
+	body, err := io.ReadAll(rec.Body)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(body)).To(ContainSubstring("distr_agent_memory_bytes 2048"))
+}
@@ -0,0 +1,127 @@
+// Package agentnet applies the network settings of an agent to all of its outbound connections. These are:
+//
+//   - a proxy in the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables
+//   - CA certificates in DISTR_CA_CERTIFICATES, which are trusted in addition to the system CAs
+//   - a client certificate in DISTR_CLIENT_CERTIFICATE and DISTR_CLIENT_KEY, which is presented if a server asks for
+//     one, e.g. the hub behind a reverse proxy that requires mutual TLS
+//
+// The settings are read once when the package is initialized and applied to [http.DefaultTransport]. Clients that
+// need their own transport must use [Transport] or [TLSConfig]. So that other processes and libraries that only read
+// the system CAs trust the additional CA certificates as well, they are written to a directory that is added to
+// SSL_CERT_DIR.
+package agentnet
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/envutil"
+	"github.com/distr-sh/distr/internal/util"
+)
+
+var tlsConfig = util.Require(tlsConfigFromEnv())
+
+func init() {
+	excludeKubernetesAPIFromProxy()
+	if transport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport.TLSClientConfig = TLSConfig()
+	}
+}
+
+// TLSConfig returns a copy of the TLS configuration for outbound connections.
+func TLSConfig() *tls.Config {
+	return tlsConfig.Clone()
+}
+
+// Transport returns a new transport that uses the proxy from the environment and the TLS configuration of the agent.
+func Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	transport.TLSClientConfig = TLSConfig()
+	return transport
+}
+
+// NewTLSConfig returns a TLS configuration that trusts the system CAs and caCertificates, and presents the client
+// certificate if clientCertificate and clientKey are not empty. All certificates are PEM encoded.
+func NewTLSConfig(caCertificates, clientCertificate, clientKey string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caCertificates != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM([]byte(caCertificates)) {
+			return nil, errors.New("CA certificates do not contain a PEM encoded certificate")
+		}
+		config.RootCAs = pool
+	}
+	if clientCertificate != "" || clientKey != "" {
+		if cert, err := tls.X509KeyPair([]byte(clientCertificate), []byte(clientKey)); err != nil {
+			return nil, fmt.Errorf("invalid client certificate: %w", err)
+		} else {
+			config.Certificates = []tls.Certificate{cert}
+		}
+	}
+	return config, nil
+}
+
+func tlsConfigFromEnv() (*tls.Config, error) {
+	caCertificates := envutil.GetEnv("DISTR_CA_CERTIFICATES")
+	if caCertificates != "" {
+		if err := addSystemCertDir(caCertificates); err != nil {
+			return nil, fmt.Errorf("could not add CA certificates to SSL_CERT_DIR: %w", err)
+		}
+	}
+	config, err := NewTLSConfig(
+		caCertificates,
+		envutil.GetEnv("DISTR_CLIENT_CERTIFICATE"),
+		envutil.GetEnv("DISTR_CLIENT_KEY"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("malformed network settings: %w", err)
+	}
+	return config, nil
+}
+
+// addSystemCertDir must be called before the system CAs are loaded for the first time, because they are cached.
+// The directory is created with a random name and is only accessible by the agent and its child processes, so that
+// other users of the host cannot create it first and plant a CA certificate that the agent would trust.
+func addSystemCertDir(caCertificates string) error {
+	dir, err := os.MkdirTemp("", "distr-ca-certificates-")
+	if err != nil {
+		return err
+	} else if err := os.WriteFile(path.Join(dir, "ca-certificates.pem"), []byte(caCertificates), 0o600); err != nil {
+		return err
+	}
+	dirs := envutil.GetEnv("SSL_CERT_DIR")
+	if dirs == "" {
+		// SSL_CERT_DIR replaces the default directories, the default file is still loaded
+		dirs = "/etc/ssl/certs:/etc/pki/tls/certs"
+	}
+	return os.Setenv("SSL_CERT_DIR", dir+":"+dirs)
+}
+
+// excludeKubernetesAPIFromProxy makes sure that the Kubernetes agent connects to the API server of its cluster
+// directly, because the Kubernetes client also uses the proxy from the environment.
+func excludeKubernetesAPIFromProxy() {
+	host := envutil.GetEnv("KUBERNETES_SERVICE_HOST")
+	if host == "" || (getProxyEnv("HTTPS_PROXY") == "" && getProxyEnv("HTTP_PROXY") == "") {
+		return
+	}
+	noProxy := []string{host, "kubernetes.default.svc"}
+	if s := getProxyEnv("NO_PROXY"); s != "" {
+		noProxy = append([]string{s}, noProxy...)
+	}
+	_ = os.Setenv("NO_PROXY", strings.Join(noProxy, ","))
+}
+
+// getProxyEnv also reads the lowercase variant of key, like [http.ProxyFromEnvironment].
+func getProxyEnv(key string) string {
+	return envutil.GetEnvOrDefault(key, envutil.GetEnv(strings.ToLower(key)), envutil.GetEnvOpts{})
+}
@@ -0,0 +1,121 @@
+package agentnet
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func generateClientCertificate(g Gomega) (certPEM, keyPEM string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	g.Expect(err).NotTo(HaveOccurred())
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "agent"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		// the certificate is its own CA
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	g.Expect(err).NotTo(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	g.Expect(err).NotTo(HaveOccurred())
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	g := NewWithT(t)
+
+	clientCert, clientKey := generateClientCertificate(g)
+	clientCAs := x509.NewCertPool()
+	g.Expect(clientCAs.AppendCertsFromPEM([]byte(clientCert))).To(BeTrue())
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+	serverCA := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}))
+
+	get := func(config *tls.Config) error {
+		client := http.Client{Transport: &http.Transport{TLSClientConfig: config}}
+		resp, err := client.Get(server.URL)
+		if err == nil {
+			_ = resp.Body.Close()
+		}
+		return err
+	}
+
+	config, err := NewTLSConfig("", "", "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(get(config)).To(MatchError(ContainSubstring("certificate")), "server CA is not trusted")
+
+	config, err = NewTLSConfig(serverCA, "", "")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(get(config)).To(HaveOccurred(), "client certificate is missing")
+
+	config, err = NewTLSConfig(serverCA, clientCert, clientKey)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(get(config)).To(Succeed())
+
+	_, err = NewTLSConfig("not a certificate", "", "")
+	g.Expect(err).To(HaveOccurred())
+	_, err = NewTLSConfig("", clientCert, "")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestExcludeKubernetesAPIFromProxy(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("KUBERNETES_SERVICE_HOST", "10.0.0.1")
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("HTTP_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("http_proxy", "")
+	t.Setenv("NO_PROXY", "")
+	t.Setenv("no_proxy", ".internal")
+
+	excludeKubernetesAPIFromProxy()
+	g.Expect(os.Getenv("NO_PROXY")).To(BeEmpty(), "no proxy is configured")
+
+	t.Setenv("https_proxy", "http://proxy:3128")
+	excludeKubernetesAPIFromProxy()
+	g.Expect(os.Getenv("NO_PROXY")).To(Equal(".internal,10.0.0.1,kubernetes.default.svc"))
+}
+
+func TestAddSystemCertDir(t *testing.T) {
+	g := NewWithT(t)
+	t.Setenv("TMPDIR", t.TempDir())
+	t.Setenv("SSL_CERT_DIR", "/etc/ssl/certs")
+
+	g.Expect(addSystemCertDir("certificates")).To(Succeed())
+	dir, rest, _ := strings.Cut(os.Getenv("SSL_CERT_DIR"), ":")
+	g.Expect(rest).To(Equal("/etc/ssl/certs"))
+	g.Expect(dir).To(HavePrefix(os.TempDir()))
+
+	info, err := os.Stat(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o700)))
+	g.Expect(os.ReadFile(path.Join(dir, "ca-certificates.pem"))).To(Equal([]byte("certificates")))
+
+	// every call gets its own directory, so that a directory created by someone else is never used
+	g.Expect(addSystemCertDir("certificates")).To(Succeed())
+	g.Expect(os.Getenv("SSL_CERT_DIR")).NotTo(HavePrefix(dir + ":"))
+}
@@ -0,0 +1,124 @@
+// Package agentnotify wakes up agents that wait for a change of their resource. Changes are published by database
+// triggers on the channel agent_resource_changed, so that agents are notified no matter which replica of the hub
+// they are connected to and which replica made the change.
+package agentnotify
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const channel = "agent_resource_changed"
+
+const (
+	deploymentTargetPrefix = "deployment_target:"
+	organizationPrefix     = "organization:"
+)
+
+// reconnectInterval is the time to wait before listening again after the connection to the database was lost.
+const reconnectInterval = 5 * time.Second
+
+type subscription struct {
+	deploymentTargetID uuid.UUID
+	organizationID     uuid.UUID
+	changed            chan struct{}
+}
+
+type Notifier struct {
+	logger        *zap.Logger
+	mutex         sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+func New(logger *zap.Logger) *Notifier {
+	return &Notifier{logger: logger, subscriptions: map[*subscription]struct{}{}}
+}
+
+// Subscribe returns a channel that receives a value when the resource of the deployment target may have changed.
+// Changes are not queued, a receiver that is slow to react only gets one value for multiple changes. The returned
+// function must be called when the caller no longer waits for changes.
+func (n *Notifier) Subscribe(deploymentTargetID, organizationID uuid.UUID) (<-chan struct{}, func()) {
+	s := &subscription{
+		deploymentTargetID: deploymentTargetID,
+		organizationID:     organizationID,
+		changed:            make(chan struct{}, 1),
+	}
+	n.mutex.Lock()
+	n.subscriptions[s] = struct{}{}
+	n.mutex.Unlock()
+	return s.changed, func() {
+		n.mutex.Lock()
+		delete(n.subscriptions, s)
+		n.mutex.Unlock()
+	}
+}
+
+// Run listens for changes until ctx is done. If the connection to the database is lost, all subscribers are
+// notified, as changes may have been missed until the connection is established again.
+func (n *Notifier) Run(ctx context.Context, pool *pgxpool.Pool) {
+	for ctx.Err() == nil {
+		if err := n.listen(ctx, pool); err != nil && ctx.Err() == nil {
+			n.logger.Warn("listening for agent resource changes failed", zap.Error(err))
+			n.notify(func(*subscription) bool { return true })
+			select {
+			case <-ctx.Done():
+			case <-time.After(reconnectInterval):
+			}
+		}
+	}
+}
+
+func (n *Notifier) listen(ctx context.Context, pool *pgxpool.Pool) error {
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	// the connection is not returned to the pool, as it would keep listening
+	pgConn := conn.Hijack()
+	defer func() { _ = pgConn.Close(context.Background()) }()
+
+	if _, err := pgConn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		notification, err := pgConn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		n.handle(notification.Payload)
+	}
+}
+
+func (n *Notifier) handle(payload string) {
+	if id, ok := strings.CutPrefix(payload, deploymentTargetPrefix); ok {
+		if id, err := uuid.Parse(id); err == nil {
+			n.notify(func(s *subscription) bool { return s.deploymentTargetID == id })
+			return
+		}
+	} else if id, ok := strings.CutPrefix(payload, organizationPrefix); ok {
+		if id, err := uuid.Parse(id); err == nil {
+			n.notify(func(s *subscription) bool { return s.organizationID == id })
+			return
+		}
+	}
+	n.logger.Warn("invalid agent resource change notification", zap.String("payload", payload))
+}
+
+func (n *Notifier) notify(match func(s *subscription) bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	for s := range n.subscriptions {
+		if match(s) {
+			select {
+			case s.changed <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
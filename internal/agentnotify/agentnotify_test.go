@@ -0,0 +1,56 @@
+package agentnotify
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+func TestNotifierNotifiesMatchingSubscriptions(t *testing.T) {
+	g := NewWithT(t)
+	n := New(zap.NewNop())
+	targetID, otherTargetID, orgID := uuid.New(), uuid.New(), uuid.New()
+
+	changed, unsubscribe := n.Subscribe(targetID, orgID)
+	defer unsubscribe()
+	otherChanged, unsubscribeOther := n.Subscribe(otherTargetID, uuid.New())
+	defer unsubscribeOther()
+
+	n.handle(deploymentTargetPrefix + targetID.String())
+	g.Expect(changed).To(Receive())
+	g.Expect(otherChanged).NotTo(Receive())
+
+	n.handle(organizationPrefix + orgID.String())
+	g.Expect(changed).To(Receive())
+	g.Expect(otherChanged).NotTo(Receive())
+}
+
+func TestNotifierDoesNotQueueChanges(t *testing.T) {
+	g := NewWithT(t)
+	n := New(zap.NewNop())
+	targetID := uuid.New()
+	changed, unsubscribe := n.Subscribe(targetID, uuid.New())
+
+	n.handle(deploymentTargetPrefix + targetID.String())
+	n.handle(deploymentTargetPrefix + targetID.String())
+	g.Expect(changed).To(Receive())
+	g.Expect(changed).NotTo(Receive())
+
+	unsubscribe()
+	n.handle(deploymentTargetPrefix + targetID.String())
+	g.Expect(changed).NotTo(Receive())
+	g.Expect(n.subscriptions).To(BeEmpty())
+}
+
+func TestNotifierIgnoresInvalidPayloads(t *testing.T) {
+	g := NewWithT(t)
+	n := New(zap.NewNop())
+	changed, unsubscribe := n.Subscribe(uuid.New(), uuid.New())
+	defer unsubscribe()
+
+	n.handle("deployment_target:not-a-uuid")
+	n.handle("something else")
+	g.Expect(changed).NotTo(Receive())
+}
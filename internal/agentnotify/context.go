@@ -0,0 +1,19 @@
+package agentnotify
+
+import "context"
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given Notifier, retrievable via FromContext.
+func NewContext(ctx context.Context, notifier *Notifier) context.Context {
+	return context.WithValue(ctx, contextKey{}, notifier)
+}
+
+// FromContext returns the Notifier carried by ctx, or nil if there is none. Agents then get their resource without
+// waiting for changes.
+func FromContext(ctx context.Context) *Notifier {
+	if notifier, ok := ctx.Value(contextKey{}).(*Notifier); ok {
+		return notifier
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+// Package agentpackage downloads packages, i.e. tarballs stored in an OCI registry, for agents that install them
+// directly on the host.
+package agentpackage
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/agentenv"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"go.uber.org/zap"
+	"oras.land/oras-go/v2/content"
+	"oras.land/oras-go/v2/registry/remote"
+	"oras.land/oras-go/v2/registry/remote/auth"
+)
+
+// Pull downloads the tarball that ref points to and extracts it into dir. If ref is an image index, the
+// manifest for the platform of the agent is used. The tarball is the only layer of the manifest or, if there are
+// several, the first one that is a tar archive.
+func Pull(ctx context.Context, log *zap.Logger, authClient *auth.Client, ref string, dir string) error {
+	repo, err := remote.NewRepository(ref)
+	if err != nil {
+		return err
+	} else if repo.Reference.Reference == "" {
+		return fmt.Errorf("package reference %v has neither a tag nor a digest", ref)
+	}
+	repo.Client = authClient
+	repo.PlainHTTP = agentenv.DistrRegistryPlainHTTP && repo.Reference.Registry == agentenv.DistrRegistryHost
+
+	manifest, err := fetchManifest(ctx, repo)
+	if err != nil {
+		return fmt.Errorf("could not fetch manifest of %v: %w", ref, err)
+	}
+	layer, err := packageLayer(manifest)
+	if err != nil {
+		return fmt.Errorf("invalid package %v: %w", ref, err)
+	}
+	rc, err := repo.Blobs().Fetch(ctx, layer)
+	if err != nil {
+		return fmt.Errorf("could not fetch package %v: %w", ref, err)
+	}
+	defer func() { _ = rc.Close() }()
+	vr := content.NewVerifyReader(rc, layer)
+	if err := extractTar(log, vr, dir); err != nil {
+		return fmt.Errorf("could not extract package %v: %w", ref, err)
+	}
+	return vr.Verify()
+}
+
+func fetchManifest(ctx context.Context, repo *remote.Repository) (*ocispec.Manifest, error) {
+	desc, rc, err := repo.FetchReference(ctx, repo.Reference.Reference)
+	if err != nil {
+		return nil, err
+	}
+	data, err := content.ReadAll(rc, desc)
+	_ = rc.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if desc.MediaType == ocispec.MediaTypeImageIndex {
+		var index ocispec.Index
+		if err := json.Unmarshal(data, &index); err != nil {
+			return nil, err
+		}
+		i := slices.IndexFunc(index.Manifests, func(m ocispec.Descriptor) bool {
+			return m.Platform == nil ||
+				m.Platform.OS == runtime.GOOS && m.Platform.Architecture == runtime.GOARCH
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("index has no manifest for %v/%v", runtime.GOOS, runtime.GOARCH)
+		}
+		if data, err = content.FetchAll(ctx, repo, index.Manifests[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	var manifest ocispec.Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+func packageLayer(manifest *ocispec.Manifest) (ocispec.Descriptor, error) {
+	if len(manifest.Layers) == 1 {
+		return manifest.Layers[0], nil
+	}
+	for _, layer := range manifest.Layers {
+		title := layer.Annotations[ocispec.AnnotationTitle]
+		if strings.Contains(layer.MediaType, "tar") ||
+			strings.HasSuffix(title, ".tar.gz") || strings.HasSuffix(title, ".tgz") || strings.HasSuffix(title, ".tar") {
+			return layer, nil
+		}
+	}
+	return ocispec.Descriptor{}, errors.New("manifest has no tar archive")
+}
+
+// extractTar extracts a tar archive, which may be gzipped, into dir. Files can not be written outside of dir, not
+// even through symlinks contained in the archive.
+func extractTar(log *zap.Logger, r io.Reader, dir string) error {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	} else {
+		r = br
+	}
+
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = root.Close() }()
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if name == "." {
+			continue
+		}
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := root.MkdirAll(name, mode|0o700); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := root.MkdirAll(path.Dir(name), 0o755); err != nil {
+				return err
+			} else if err := writeFile(root, name, tr, mode); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			if err := root.MkdirAll(path.Dir(name), 0o755); err != nil {
+				return err
+			} else if err := root.Symlink(header.Linkname, name); err != nil {
+				return err
+			}
+		default:
+			log.Sugar().Warnf("skipping %v with unsupported type %v", header.Name, header.Typeflag)
+		}
+	}
+}
+
+func writeFile(root *os.Root, name string, r io.Reader, mode os.FileMode) error {
+	file, err := root.OpenFile(name, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(file, r); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,97 @@
+package agentpackage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"go.uber.org/zap"
+)
+
+type tarEntry struct {
+	header  tar.Header
+	content string
+}
+
+func buildTar(t *testing.T, gzipped bool, entries ...tarEntry) *bytes.Buffer {
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var gw *gzip.Writer
+	if gzipped {
+		gw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(gw)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for _, entry := range entries {
+		entry.header.Size = int64(len(entry.content))
+		if err := tw.WriteHeader(&entry.header); err != nil {
+			t.Fatal(err)
+		} else if _, err := tw.Write([]byte(entry.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func TestExtractTar(t *testing.T) {
+	for _, gzipped := range []bool{false, true} {
+		g := NewWithT(t)
+		dir := t.TempDir()
+		archive := buildTar(t, gzipped,
+			tarEntry{header: tar.Header{Name: "./bin/", Typeflag: tar.TypeDir, Mode: 0o755}},
+			tarEntry{header: tar.Header{Name: "./bin/app", Typeflag: tar.TypeReg, Mode: 0o755}, content: "binary"},
+			tarEntry{header: tar.Header{Name: "config/app.conf", Typeflag: tar.TypeReg, Mode: 0o644}, content: "conf"},
+			tarEntry{header: tar.Header{Name: "app", Typeflag: tar.TypeSymlink, Linkname: "bin/app"}},
+		)
+
+		g.Expect(extractTar(zap.NewNop(), archive, dir)).To(Succeed())
+		g.Expect(os.ReadFile(path.Join(dir, "bin/app"))).To(BeEquivalentTo("binary"))
+		g.Expect(os.ReadFile(path.Join(dir, "config/app.conf"))).To(BeEquivalentTo("conf"))
+		g.Expect(os.Readlink(path.Join(dir, "app"))).To(Equal("bin/app"))
+		info, err := os.Stat(path.Join(dir, "bin/app"))
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(info.Mode().Perm()).To(Equal(os.FileMode(0o755)))
+	}
+}
+
+func TestExtractTarDoesNotWriteOutsideOfDir(t *testing.T) {
+	t.Run("path", func(t *testing.T) {
+		g := NewWithT(t)
+		parent := t.TempDir()
+		dir := path.Join(parent, "package")
+		g.Expect(os.Mkdir(dir, 0o755)).To(Succeed())
+		archive := buildTar(t, false,
+			tarEntry{header: tar.Header{Name: "../escaped", Typeflag: tar.TypeReg, Mode: 0o644}, content: "x"},
+		)
+
+		g.Expect(extractTar(zap.NewNop(), archive, dir)).NotTo(Succeed())
+		g.Expect(path.Join(parent, "escaped")).NotTo(BeAnExistingFile())
+	})
+
+	t.Run("symlink", func(t *testing.T) {
+		g := NewWithT(t)
+		parent := t.TempDir()
+		dir := path.Join(parent, "package")
+		g.Expect(os.Mkdir(dir, 0o755)).To(Succeed())
+		archive := buildTar(t, false,
+			tarEntry{header: tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: ".."}},
+			tarEntry{header: tar.Header{Name: "link/escaped", Typeflag: tar.TypeReg, Mode: 0o644}, content: "x"},
+		)
+
+		g.Expect(extractTar(zap.NewNop(), archive, dir)).NotTo(Succeed())
+		g.Expect(path.Join(parent, "escaped")).NotTo(BeAnExistingFile())
+	})
+}
@@ -0,0 +1,223 @@
+// Package agentqueue is an on-disk queue for the requests that an agent sends to the hub, like status updates, logs
+// and metrics. While the hub cannot be reached, requests are kept in the queue and sent later in the order in which
+// they were made.
+//
+// Every entry is a file in the directory of the queue, named after its sequence number, so that the queue survives
+// restarts of the agent. If the queue exceeds its size limit, the oldest entries are dropped.
+package agentqueue
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Kind string
+
+const (
+	KindStatus               Kind = "status"
+	KindDeploymentLogs       Kind = "deploymentLogs"
+	KindDeploymentTargetLogs Kind = "deploymentTargetLogs"
+	KindMetrics              Kind = "metrics"
+)
+
+// ErrRejected must be returned by the send function of [Queue.Replay] if the hub rejected an entry permanently, so
+// that it is dropped instead of retried.
+var ErrRejected = errors.New("entry rejected")
+
+type Entry struct {
+	Kind Kind `json:"kind"`
+	// CreatedAt is when the request was originally made.
+	CreatedAt time.Time       `json:"createdAt"`
+	Payload   json.RawMessage `json:"payload"`
+}
+
+// Stats describes the depth of the queue.
+type Stats struct {
+	Entries int   `json:"entries"`
+	Bytes   int64 `json:"bytes"`
+	// Dropped is the number of entries that were dropped because the size limit was reached since the agent started.
+	Dropped int64 `json:"dropped"`
+}
+
+type queuedEntry struct {
+	seq  uint64
+	size int64
+}
+
+type Queue struct {
+	dir      string
+	maxBytes int64
+
+	mut     sync.Mutex
+	entries []queuedEntry
+	bytes   int64
+	dropped int64
+	nextSeq uint64
+
+	// replayMut makes sure that only one replay runs at a time, so that entries are sent in order.
+	replayMut sync.Mutex
+}
+
+// Open opens the queue in dir, which is created if it does not exist yet. Entries from previous runs are kept.
+func Open(dir string, maxBytes int64) (*Queue, error) {
+	if maxBytes <= 0 {
+		return nil, errors.New("maxBytes must be positive")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	q := &Queue{dir: dir, maxBytes: maxBytes}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// left over from a write that was interrupted
+			_ = os.Remove(path.Join(dir, name))
+			continue
+		}
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, ".json"), 10, 64)
+		if err != nil || !strings.HasSuffix(name, ".json") || file.IsDir() {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			return nil, err
+		}
+		q.entries = append(q.entries, queuedEntry{seq: seq, size: info.Size()})
+		q.bytes += info.Size()
+	}
+	slices.SortFunc(q.entries, func(a, b queuedEntry) int { return cmp.Compare(a.seq, b.seq) })
+	if len(q.entries) > 0 {
+		q.nextSeq = q.entries[len(q.entries)-1].seq + 1
+	}
+	q.evictNoLock()
+	return q, nil
+}
+
+// Push appends an entry to the queue. If the queue then exceeds its size limit, the oldest entries are dropped.
+func (q *Queue) Push(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	} else if int64(len(data)) > q.maxBytes {
+		return fmt.Errorf("entry of %v bytes exceeds the queue size limit of %v bytes", len(data), q.maxBytes)
+	}
+
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	seq := q.nextSeq
+	name := q.fileName(seq)
+	if err := os.WriteFile(name+".tmp", data, 0o600); err != nil {
+		return err
+	} else if err := os.Rename(name+".tmp", name); err != nil {
+		_ = os.Remove(name + ".tmp")
+		return err
+	}
+	q.nextSeq++
+	q.entries = append(q.entries, queuedEntry{seq: seq, size: int64(len(data))})
+	q.bytes += int64(len(data))
+	q.evictNoLock()
+	return nil
+}
+
+// Len returns the number of entries in the queue.
+func (q *Queue) Len() int {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.entries)
+}
+
+func (q *Queue) Stats() Stats {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return Stats{Entries: len(q.entries), Bytes: q.bytes, Dropped: q.dropped}
+}
+
+// Replay sends the entries of the queue in order until the queue is empty or send fails. An entry is removed if send
+// succeeds or returns [ErrRejected]. Entries that are pushed during the replay are sent as well. Replay returns the
+// number of entries that were sent and the first error other than ErrRejected.
+func (q *Queue) Replay(send func(Entry) error) (int, error) {
+	q.replayMut.Lock()
+	defer q.replayMut.Unlock()
+	return q.replayNoLock(send)
+}
+
+// TryReplay is like [Queue.Replay], but returns right away with ok set to false if another replay is running.
+func (q *Queue) TryReplay(send func(Entry) error) (sent int, ok bool, err error) {
+	if !q.replayMut.TryLock() {
+		return 0, false, nil
+	}
+	defer q.replayMut.Unlock()
+	sent, err = q.replayNoLock(send)
+	return sent, true, err
+}
+
+func (q *Queue) replayNoLock(send func(Entry) error) (int, error) {
+	sent := 0
+	for {
+		q.mut.Lock()
+		if len(q.entries) == 0 {
+			q.mut.Unlock()
+			return sent, nil
+		}
+		head := q.entries[0]
+		q.mut.Unlock()
+
+		var entry Entry
+		if data, err := os.ReadFile(q.fileName(head.seq)); errors.Is(err, os.ErrNotExist) {
+			// the entry was dropped in the meantime
+			q.remove(head.seq)
+			continue
+		} else if err != nil {
+			return sent, err
+		} else if err := json.Unmarshal(data, &entry); err != nil {
+			// a corrupt entry can never be sent
+			q.remove(head.seq)
+			continue
+		}
+
+		if err := send(entry); err != nil && !errors.Is(err, ErrRejected) {
+			return sent, err
+		} else if err == nil {
+			sent++
+		}
+		q.remove(head.seq)
+	}
+}
+
+func (q *Queue) remove(seq uint64) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	if i := slices.IndexFunc(q.entries, func(e queuedEntry) bool { return e.seq == seq }); i >= 0 {
+		q.bytes -= q.entries[i].size
+		q.entries = slices.Delete(q.entries, i, i+1)
+		_ = os.Remove(q.fileName(seq))
+	}
+}
+
+func (q *Queue) evictNoLock() {
+	for q.bytes > q.maxBytes && len(q.entries) > 0 {
+		oldest := q.entries[0]
+		_ = os.Remove(q.fileName(oldest.seq))
+		q.entries = q.entries[1:]
+		q.bytes -= oldest.size
+		q.dropped++
+	}
+}
+
+func (q *Queue) fileName(seq uint64) string {
+	// zero padding keeps the files sorted by name
+	return path.Join(q.dir, fmt.Sprintf("%020d.json", seq))
+}
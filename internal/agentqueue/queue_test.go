@@ -0,0 +1,129 @@
+package agentqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func entry(n int) Entry {
+	return Entry{
+		Kind:      KindStatus,
+		CreatedAt: time.Date(2026, 1, 1, 0, 0, n, 0, time.UTC),
+		Payload:   json.RawMessage(fmt.Sprintf(`{"n":%d}`, n)),
+	}
+}
+
+func TestReplayInOrder(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	q, err := Open(dir, 1<<20)
+	g.Expect(err).NotTo(HaveOccurred())
+	for i := range 3 {
+		g.Expect(q.Push(entry(i))).To(Succeed())
+	}
+	g.Expect(q.Len()).To(Equal(3))
+
+	// entries survive a restart
+	q, err = Open(dir, 1<<20)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(q.Len()).To(Equal(3))
+
+	var sent []Entry
+	n, err := q.Replay(func(e Entry) error {
+		sent = append(sent, e)
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(3))
+	g.Expect(sent).To(Equal([]Entry{entry(0), entry(1), entry(2)}))
+	g.Expect(q.Stats()).To(Equal(Stats{}))
+
+	files, err := os.ReadDir(dir)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(files).To(BeEmpty())
+}
+
+func TestReplayStopsOnError(t *testing.T) {
+	g := NewWithT(t)
+	q, err := Open(t.TempDir(), 1<<20)
+	g.Expect(err).NotTo(HaveOccurred())
+	for i := range 3 {
+		g.Expect(q.Push(entry(i))).To(Succeed())
+	}
+
+	calls := 0
+	n, err := q.Replay(func(e Entry) error {
+		calls++
+		switch calls {
+		case 1:
+			return fmt.Errorf("%w: revision does not exist", ErrRejected)
+		case 2:
+			return errors.New("connection refused")
+		default:
+			return nil
+		}
+	})
+	g.Expect(err).To(MatchError("connection refused"))
+	g.Expect(n).To(Equal(0))
+	// the rejected entry is dropped, the failed one is kept for the next replay
+	g.Expect(q.Len()).To(Equal(2))
+
+	var sent []Entry
+	n, err = q.Replay(func(e Entry) error {
+		sent = append(sent, e)
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(n).To(Equal(2))
+	g.Expect(sent).To(Equal([]Entry{entry(1), entry(2)}))
+}
+
+func TestPushDropsOldestEntries(t *testing.T) {
+	g := NewWithT(t)
+	data, err := json.Marshal(entry(0))
+	g.Expect(err).NotTo(HaveOccurred())
+	q, err := Open(t.TempDir(), int64(2*len(data)))
+	g.Expect(err).NotTo(HaveOccurred())
+	for i := range 3 {
+		g.Expect(q.Push(entry(i))).To(Succeed())
+	}
+	g.Expect(q.Stats()).To(Equal(Stats{Entries: 2, Bytes: int64(2 * len(data)), Dropped: 1}))
+
+	var sent []Entry
+	_, err = q.Replay(func(e Entry) error {
+		sent = append(sent, e)
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sent).To(Equal([]Entry{entry(1), entry(2)}))
+
+	tooLarge := Entry{Kind: KindStatus, Payload: json.RawMessage(`"` + strings.Repeat("x", 2*len(data)) + `"`)}
+	g.Expect(q.Push(tooLarge)).To(MatchError(ContainSubstring("exceeds the queue size limit")))
+}
+
+func TestOpenSkipsIncompleteAndCorruptEntries(t *testing.T) {
+	g := NewWithT(t)
+	dir := t.TempDir()
+	g.Expect(os.WriteFile(path.Join(dir, fmt.Sprintf("%020d.json.tmp", 0)), []byte("{"), 0o600)).To(Succeed())
+	g.Expect(os.WriteFile(path.Join(dir, fmt.Sprintf("%020d.json", 1)), []byte("{"), 0o600)).To(Succeed())
+	q, err := Open(dir, 1<<20)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(q.Len()).To(Equal(1))
+	g.Expect(q.Push(entry(2))).To(Succeed())
+
+	var sent []Entry
+	_, err = q.Replay(func(e Entry) error {
+		sent = append(sent, e)
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(sent).To(Equal([]Entry{entry(2)}))
+}
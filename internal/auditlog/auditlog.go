@@ -0,0 +1,246 @@
+// Package auditlog records mutating actions of users and service accounts in the organization's audit log.
+//
+// Every mutating API request that passes through Middleware is recorded with its actor, scope and route.
+// Handlers add what only they know, like the before/after diff of the changed resource, with RecordChange.
+package auditlog
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authkey"
+	"github.com/distr-sh/distr/internal/authn/authinfo"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// ResourceArtifactTags is recorded for changes to artifact tags, which are identified by "artifact:tag" and can
+// be made through both the API and the registry.
+const ResourceArtifactTags = "artifact_tags"
+
+// Event describes a change. Fields left empty by handlers are derived from the request by Middleware.
+type Event struct {
+	// OrganizationID overrides the organization of the credential, for changes of super admins to other
+	// organizations and for imported organizations.
+	OrganizationID *uuid.UUID
+	Resource       string
+	ResourceID     *string
+	Action         types.AuditLogAction
+	Changes        map[string]types.AuditLogChange
+}
+
+type contextKey struct{}
+
+func eventFromContext(ctx context.Context) *Event {
+	if event, ok := ctx.Value(contextKey{}).(*Event); ok {
+		return event
+	}
+	return nil
+}
+
+// SetResource overrides the resource derived from the route, for routes that change a nested resource, like
+// the tag of an artifact.
+func SetResource(ctx context.Context, resource string, resourceID string) {
+	if event := eventFromContext(ctx); event != nil {
+		event.Resource = resource
+		event.ResourceID = &resourceID
+	}
+}
+
+// SetAction overrides the action derived from the HTTP method, for actions that are neither of create, update and
+// delete.
+func SetAction(ctx context.Context, action types.AuditLogAction) {
+	if event := eventFromContext(ctx); event != nil {
+		event.Action = action
+	}
+}
+
+// SetOrganization records the current event in the audit log of the given organization instead of the one of the
+// credential, for endpoints where a super admin changes another organization or that create one.
+func SetOrganization(ctx context.Context, orgID uuid.UUID) {
+	if event := eventFromContext(ctx); event != nil {
+		event.OrganizationID = &orgID
+	}
+}
+
+// RecordChange adds the diff between before and after to the current event. before is nil for created and
+// after is nil for deleted resources. See Diff for the meaning of redact.
+func RecordChange(ctx context.Context, before, after any, redact ...string) {
+	event := eventFromContext(ctx)
+	if event == nil {
+		return
+	}
+	beforeFields, err := toFields(before)
+	if err != nil {
+		reportDiffError(ctx, err)
+		return
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		reportDiffError(ctx, err)
+		return
+	}
+
+	event.Changes = diffFields(beforeFields, afterFields, redact)
+	if event.ResourceID == nil {
+		event.ResourceID = idField(afterFields, beforeFields)
+	}
+	if event.Action == "" && len(beforeFields) == 0 {
+		event.Action = types.AuditLogActionCreate
+	} else if event.Action == "" && len(afterFields) == 0 {
+		event.Action = types.AuditLogActionDelete
+	}
+}
+
+func reportDiffError(ctx context.Context, err error) {
+	internalctx.GetLogger(ctx).Warn("could not compute audit log diff", zap.Error(err))
+	sentry.GetHubFromContext(ctx).CaptureException(err)
+}
+
+// idField returns the "id" of the first of fields that has one. Routes that create a resource, or receive its ID
+// in the body, have no path parameter to identify it.
+func idField(fields ...map[string]any) *string {
+	for _, f := range fields {
+		if id, ok := f["id"].(string); ok {
+			return &id
+		}
+	}
+	return nil
+}
+
+// Middleware records every successful mutating request of an authenticated user. It must be installed after
+// the authentication middleware. Failed requests are not recorded, as they did not change anything.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isMutating(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		event := &Event{}
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), contextKey{}, event)))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if status >= http.StatusBadRequest {
+			return
+		}
+
+		ctx := r.Context()
+		authInfo, err := auth.Authentication.Get(ctx)
+		if err != nil {
+			return
+		}
+		if event.Resource == "" {
+			event.Resource = resourceFromRoutePattern(chi.RouteContext(ctx).RoutePattern())
+		}
+		if event.ResourceID == nil {
+			event.ResourceID = resourceIDFromURLParams(chi.RouteContext(ctx))
+		}
+		if event.Action == "" {
+			event.Action = actionFromMethod(r.Method)
+		}
+		if err := Record(ctx, authInfo, r, status, *event); err != nil {
+			internalctx.GetLogger(ctx).Warn("could not record audit log entry", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		}
+	})
+}
+
+// Record writes an event to the audit log of the organization of authInfo, unless the event names another one.
+// It is used directly for changes that are not made through the API, like pushes to the registry.
+func Record(ctx context.Context, authInfo authinfo.AuthInfo, r *http.Request, status int, event Event) error {
+	entry := types.AuditLogEntry{
+		AuthMethod: authMethod(authInfo),
+		Resource:   event.Resource,
+		ResourceID: event.ResourceID,
+		Action:     event.Action,
+		HTTPMethod: &r.Method,
+		Path:       &r.URL.Path,
+		StatusCode: &status,
+		Changes:    event.Changes,
+	}
+	if event.OrganizationID != nil {
+		entry.OrganizationID = *event.OrganizationID
+	} else if orgID := authInfo.CurrentOrgID(); orgID != nil {
+		entry.OrganizationID = *orgID
+		entry.CustomerOrganizationID = authInfo.CurrentCustomerOrgID()
+		entry.PartnerOrganizationID = authInfo.CurrentPartnerOrgID()
+	} else {
+		return nil
+	}
+	entry.ActorUserAccountID = new(authInfo.CurrentUserID())
+	entry.ActorEmail = new(authInfo.CurrentUserEmail())
+	if impersonator := authInfo.CurrentImpersonator(); impersonator != nil {
+		entry.ImpersonatorUserAccountID = &impersonator.UserID
+		entry.ImpersonatorEmail = &impersonator.Email
+	}
+	if addr := chimiddleware.GetClientIP(ctx); addr != "" {
+		entry.RemoteAddress = &addr
+	}
+	if requestID := chimiddleware.GetReqID(ctx); requestID != "" {
+		entry.RequestID = &requestID
+	}
+	return db.CreateAuditLogEntry(ctx, &entry)
+}
+
+func isMutating(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+func actionFromMethod(method string) types.AuditLogAction {
+	switch method {
+	case http.MethodPost:
+		return types.AuditLogActionCreate
+	case http.MethodDelete:
+		return types.AuditLogActionDelete
+	default:
+		return types.AuditLogActionUpdate
+	}
+}
+
+// authMethod tells service accounts from users that authenticated with an access token or a session token. Agents
+// are not recorded, as their routes do not pass through Middleware.
+func authMethod(authInfo authinfo.AuthInfo) types.AuditLogAuthMethod {
+	if authInfo.IsServiceAccount() {
+		return types.AuditLogAuthMethodServiceAccount
+	} else if _, ok := authInfo.Token().(authkey.Key); ok {
+		return types.AuditLogAuthMethodAccessToken
+	} else {
+		return types.AuditLogAuthMethodSession
+	}
+}
+
+// resourceFromRoutePattern returns the first segment of an API route, so "/api/v1/deployment-targets/{id}"
+// becomes "deployment_targets", matching the resource names of the permission model where there is one.
+func resourceFromRoutePattern(pattern string) string {
+	pattern = strings.TrimPrefix(pattern, "/api/v1/")
+	resource, _, _ := strings.Cut(pattern, "/")
+	return strings.ReplaceAll(resource, "-", "_")
+}
+
+// resourceIDFromURLParams returns the first path parameter, which identifies the resource of the route.
+func resourceIDFromURLParams(rctx *chi.Context) *string {
+	for i, key := range rctx.URLParams.Keys {
+		if key != "*" && i < len(rctx.URLParams.Values) && rctx.URLParams.Values[i] != "" {
+			return &rctx.URLParams.Values[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package auditlog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/go-chi/chi/v5"
+	. "github.com/onsi/gomega"
+)
+
+func TestResourceFromRoutePattern(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(resourceFromRoutePattern("/api/v1/deployment-targets/{deploymentTargetId}")).
+		To(Equal("deployment_targets"))
+	g.Expect(resourceFromRoutePattern("/api/v1/secrets/")).To(Equal("secrets"))
+	g.Expect(resourceFromRoutePattern("/api/v1/organization")).To(Equal("organization"))
+}
+
+func TestResourceIDFromURLParams(t *testing.T) {
+	g := NewWithT(t)
+
+	rctx := chi.NewRouteContext()
+	g.Expect(resourceIDFromURLParams(rctx)).To(BeNil())
+
+	rctx.URLParams.Add("*", "ignored")
+	rctx.URLParams.Add("artifactId", "a1")
+	rctx.URLParams.Add("tagName", "v1")
+	g.Expect(resourceIDFromURLParams(rctx)).To(HaveValue(Equal("a1")))
+}
+
+func TestActionFromMethod(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(actionFromMethod(http.MethodPost)).To(Equal(types.AuditLogActionCreate))
+	g.Expect(actionFromMethod(http.MethodPut)).To(Equal(types.AuditLogActionUpdate))
+	g.Expect(actionFromMethod(http.MethodPatch)).To(Equal(types.AuditLogActionUpdate))
+	g.Expect(actionFromMethod(http.MethodDelete)).To(Equal(types.AuditLogActionDelete))
+}
+
+func TestRecordChange(t *testing.T) {
+	g := NewWithT(t)
+
+	// Without the middleware, there is no event to record to.
+	RecordChange(context.Background(), nil, map[string]any{"id": "1"})
+
+	event := &Event{}
+	ctx := context.WithValue(context.Background(), contextKey{}, event)
+	RecordChange(ctx, nil, map[string]any{"id": "1", "name": "new"})
+	g.Expect(event.ResourceID).To(HaveValue(Equal("1")))
+	g.Expect(event.Action).To(Equal(types.AuditLogActionCreate))
+	g.Expect(event.Changes).To(HaveKey("name"))
+
+	// The ID is taken from the resource even if it did not change.
+	event = &Event{}
+	ctx = context.WithValue(context.Background(), contextKey{}, event)
+	RecordChange(ctx, map[string]any{"id": "2", "name": "old"}, map[string]any{"id": "2", "name": "new"})
+	g.Expect(event.ResourceID).To(HaveValue(Equal("2")))
+	g.Expect(event.Action).To(BeEmpty())
+	g.Expect(event.Changes).To(HaveLen(1))
+
+	SetResource(ctx, ResourceArtifactTags, "app:v1")
+	g.Expect(event.Resource).To(Equal(ResourceArtifactTags))
+	g.Expect(event.ResourceID).To(HaveValue(Equal("app:v1")))
+}
@@ -0,0 +1,105 @@
+package auditlog
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/types"
+)
+
+const redactedValue = "[REDACTED]"
+
+// sensitiveFieldNames are matched case-insensitively against every field name, including those of nested
+// objects. A field is redacted if its name contains one of them.
+var sensitiveFieldNames = []string{"password", "secret", "token", "privatekey", "apikey", "credential"}
+
+// Diff returns the top-level fields of the JSON representations of before and after that differ. Either of
+// them may be nil, for example when a resource is created or deleted. Values of sensitive fields, as well as
+// those named in redact, are replaced with a placeholder so that the change is recorded without its value.
+func Diff(before, after any, redact ...string) (map[string]types.AuditLogChange, error) {
+	beforeFields, err := toFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := toFields(after)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(beforeFields, afterFields, redact), nil
+}
+
+func diffFields(beforeFields, afterFields map[string]any, redact []string) map[string]types.AuditLogChange {
+	keys := slices.Collect(maps.Keys(beforeFields))
+	for key := range afterFields {
+		if _, ok := beforeFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	changes := map[string]types.AuditLogChange{}
+	for _, key := range keys {
+		beforeValue, afterValue := beforeFields[key], afterFields[key]
+		if reflect.DeepEqual(beforeValue, afterValue) {
+			continue
+		}
+		changes[key] = types.AuditLogChange{
+			Before: redactField(key, beforeValue, redact),
+			After:  redactField(key, afterValue, redact),
+		}
+	}
+	return changes
+}
+
+func toFields(value any) (map[string]any, error) {
+	fields := map[string]any{}
+	if value == nil || reflect.ValueOf(value).Kind() == reflect.Pointer && reflect.ValueOf(value).IsNil() {
+		return fields, nil
+	}
+	if data, err := json.Marshal(value); err != nil {
+		return nil, fmt.Errorf("could not marshal audit log value: %w", err)
+	} else if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("audit log value must be a JSON object: %w", err)
+	}
+	return fields, nil
+}
+
+func redactField(key string, value any, redact []string) any {
+	if value == nil {
+		return nil
+	} else if isSensitive(key, redact) {
+		return redactedValue
+	}
+	switch value := value.(type) {
+	case map[string]any:
+		result := make(map[string]any, len(value))
+		for k, v := range value {
+			result[k] = redactField(k, v, redact)
+		}
+		return result
+	case []any:
+		result := make([]any, len(value))
+		for i, v := range value {
+			result[i] = redactField("", v, redact)
+		}
+		return result
+	default:
+		return value
+	}
+}
+
+func isSensitive(key string, redact []string) bool {
+	if key == "" {
+		return false
+	}
+	lower := strings.ToLower(key)
+	for _, name := range sensitiveFieldNames {
+		if strings.Contains(lower, name) {
+			return true
+		}
+	}
+	return slices.ContainsFunc(redact, func(name string) bool { return strings.EqualFold(name, key) })
+}
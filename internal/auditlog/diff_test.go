@@ -0,0 +1,80 @@
+package auditlog
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+type testResource struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Password string            `json:"password,omitempty"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Nested   *testNested       `json:"nested,omitempty"`
+}
+
+type testNested struct {
+	APIKey string `json:"apiKey"`
+	URL    string `json:"url"`
+}
+
+func TestDiffReturnsChangedFields(t *testing.T) {
+	g := NewWithT(t)
+
+	changes, err := Diff(
+		testResource{ID: "1", Name: "old", Labels: map[string]string{"a": "1"}},
+		testResource{ID: "1", Name: "new", Labels: map[string]string{"a": "1"}},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changes).To(Equal(map[string]types.AuditLogChange{
+		"name": {Before: "old", After: "new"},
+	}))
+}
+
+func TestDiffCreateAndDelete(t *testing.T) {
+	g := NewWithT(t)
+
+	created, err := Diff(nil, &testResource{ID: "1", Name: "new"})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(created).To(HaveKeyWithValue("id", types.AuditLogChange{After: "1"}))
+	g.Expect(created).To(HaveKeyWithValue("name", types.AuditLogChange{After: "new"}))
+
+	deleted, err := Diff((*testResource)(nil), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(deleted).To(BeEmpty())
+}
+
+func TestDiffRedactsSensitiveFields(t *testing.T) {
+	g := NewWithT(t)
+
+	changes, err := Diff(
+		testResource{ID: "1", Password: "hunter2", Nested: &testNested{APIKey: "a", URL: "https://a"}},
+		testResource{ID: "1", Password: "hunter3", Nested: &testNested{APIKey: "b", URL: "https://b"}},
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changes).To(HaveKeyWithValue("password", types.AuditLogChange{Before: redactedValue, After: redactedValue}))
+	g.Expect(changes).To(HaveKeyWithValue("nested", types.AuditLogChange{
+		Before: map[string]any{"apiKey": redactedValue, "url": "https://a"},
+		After:  map[string]any{"apiKey": redactedValue, "url": "https://b"},
+	}))
+
+	// Unchanged sensitive fields are not recorded at all, and additional fields can be redacted.
+	changes, err = Diff(
+		map[string]any{"password": "same", "value": "old"},
+		map[string]any{"password": "same", "value": "new"},
+		"Value",
+	)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(changes).To(Equal(map[string]types.AuditLogChange{
+		"value": {Before: redactedValue, After: redactedValue},
+	}))
+}
+
+func TestDiffRejectsNonObjects(t *testing.T) {
+	g := NewWithT(t)
+
+	_, err := Diff([]string{"a"}, nil)
+	g.Expect(err).To(HaveOccurred())
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/distr-sh/distr/internal/authn/authinfo"
 	"github.com/distr-sh/distr/internal/authn/authkey"
 	"github.com/distr-sh/distr/internal/authn/jwt"
+	authnScim "github.com/distr-sh/distr/internal/authn/scim"
 	authnSupportBundle "github.com/distr-sh/distr/internal/authn/supportbundle"
 	"github.com/distr-sh/distr/internal/authn/token"
 	internalctx "github.com/distr-sh/distr/internal/context"
@@ -47,7 +48,13 @@ var AgentAuthentication = authn.New(
 
 // ArtifactsAuthentication supports Basic auth login for OCI clients, where the password should be a PAT.
 // The given PAT is verified against the database, to make sure that the user still exists.
+// CI jobs can log in with the ID of a workload identity trust policy and an ID token instead.
 var ArtifactsAuthentication = authn.New(
+	authn.Chain3(
+		authinfo.WorkloadIdentityAuthenticator(),
+		authinfo.DbAuthenticator(),
+		authinfo.DropUser(),
+	),
 	authn.Chain(
 		token.NewExtractor(
 			token.WithExtractorFuncs(token.FromBasicAuth()),
@@ -77,6 +84,11 @@ var SupportBundleAuthentication = authn.New[*types.SupportBundle](
 	authnSupportBundle.Authenticator(),
 )
 
+// ScimAuthentication authenticates identity providers calling the SCIM API with a SCIM token.
+var ScimAuthentication = authn.New[*types.ScimToken](
+	authnScim.Authenticator(),
+)
+
 func handleUnknownError(w http.ResponseWriter, r *http.Request, err error) {
 	if !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) {
 		internalctx.GetLogger(r.Context()).Error("error authenticating request", zap.Error(err))
@@ -90,4 +102,5 @@ func init() {
 	AgentAuthentication.SetUnknownErrorHandler(handleUnknownError)
 	ArtifactsAuthentication.SetUnknownErrorHandler(handleUnknownError)
 	SupportBundleAuthentication.SetUnknownErrorHandler(handleUnknownError)
+	ScimAuthentication.SetUnknownErrorHandler(handleUnknownError)
 }
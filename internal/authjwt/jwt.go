@@ -14,7 +14,8 @@ import (
 )
 
 const (
-	defaultTokenExpiration = 24 * time.Hour
+	defaultTokenExpiration       = 24 * time.Hour
+	impersonationTokenExpiration = time.Hour
 )
 
 const (
@@ -28,6 +29,11 @@ const (
 	PartnerOrgIDKey      = "p_org"
 	TokenScopeKey        = "scope"
 	SuperAdminKey        = "is_super_admin"
+	// WebAuthnKey marks tokens issued for a passkey login, which organizations can require for their admins.
+	WebAuthnKey = "webauthn"
+	// ImpersonatorIDKey and ImpersonatorEmailKey identify the super admin who acts as the user of the token.
+	ImpersonatorIDKey    = "impersonator_id"
+	ImpersonatorEmailKey = "impersonator_email"
 
 	audienceUserValue  = "user"
 	audienceAgentValue = "agent"
@@ -51,8 +57,51 @@ var JWTAuth = sync.OnceValue(func() *jwtauth.JWTAuth {
 	return jwtauth.New("HS256", env.JWTSecret(), nil)
 })
 
-func GenerateDefaultToken(user types.UserAccount, org types.OrganizationWithUserRole) (jwt.Token, string, error) {
-	return generateUserToken(user, &org, defaultTokenExpiration, nil)
+// GenerateDefaultToken generates a login token for the session with the given ID, which is stored as jti. The
+// token is only accepted as long as the session exists.
+func GenerateDefaultToken(
+	user types.UserAccount,
+	org types.OrganizationWithUserRole,
+	sessionID uuid.UUID,
+) (jwt.Token, string, error) {
+	return generateUserToken(user, &org, defaultTokenExpiration, map[string]any{jwt.JwtIDKey: sessionID.String()})
+}
+
+// GenerateWebAuthnToken generates a default token for a user who signed in with a passkey.
+func GenerateWebAuthnToken(
+	user types.UserAccount,
+	org types.OrganizationWithUserRole,
+	sessionID uuid.UUID,
+) (jwt.Token, string, error) {
+	return generateUserToken(user, &org, defaultTokenExpiration, map[string]any{
+		jwt.JwtIDKey: sessionID.String(),
+		WebAuthnKey:  true,
+	})
+}
+
+// GenerateImpersonationToken generates a short-lived login token for a super admin who acts as user in the given
+// organization. The token carries the super admin, so that their requests are attributed to them in the audit log.
+func GenerateImpersonationToken(
+	user types.UserAccount,
+	org types.OrganizationWithUserRole,
+	sessionID uuid.UUID,
+	impersonator types.UserAccount,
+) (jwt.Token, string, error) {
+	return generateUserToken(user, &org, impersonationTokenExpiration, map[string]any{
+		jwt.JwtIDKey:         sessionID.String(),
+		ImpersonatorIDKey:    impersonator.ID.String(),
+		ImpersonatorEmailKey: impersonator.Email,
+	})
+}
+
+// IsWebAuthnToken reports whether the token was issued for a passkey login. Tokens derived from it, like the one
+// issued when switching to another organization, must keep the claim.
+func IsWebAuthnToken(token any) bool {
+	var value bool
+	if t, ok := token.(jwt.Token); ok {
+		_ = t.Get(WebAuthnKey, &value)
+	}
+	return value
 }
 
 func GenerateResetToken(user types.UserAccount) (jwt.Token, string, error) {
@@ -10,6 +10,9 @@ type AuthInfo interface {
 	CurrentUserID() uuid.UUID
 	CurrentUserEmail() string
 	CurrentUserRole() *types.UserRole
+	// CurrentPermissions returns what the credential may do in the current organization. Before the
+	// DbAuthenticator ran, it is nil unless the credential itself is restricted, like an access token with a role.
+	CurrentPermissions() types.PermissionSet
 	CurrentOrgID() *uuid.UUID
 	CurrentCustomerOrgID() *uuid.UUID
 	CurrentPartnerOrgID() *uuid.UUID
@@ -18,9 +21,26 @@ type AuthInfo interface {
 	// scope for regular login tokens, PATs and agent tokens.
 	TokenScope() authjwt.TokenScope
 	IsSuperAdmin() bool
+	// IsServiceAccount reports whether the credential is an access token of a service account, which is not a
+	// person and can only be managed by the admins of its organization.
+	IsServiceAccount() bool
+	// CurrentSessionID returns the session a login token was issued for, or nil for all other credentials.
+	CurrentSessionID() *uuid.UUID
+	// CurrentAccessTokenScopes returns the scopes of an access token, or nil for all other credentials. The
+	// permissions they grant are already part of CurrentPermissions, but restrictions to single artifacts must be
+	// checked where artifacts are accessed.
+	CurrentAccessTokenScopes() types.AccessTokenScopes
+	// CurrentImpersonator returns the super admin who acts as the user with an impersonation token, or nil.
+	CurrentImpersonator() *Impersonator
 	Token() any
 }
 
+// Impersonator is a super admin who acts as another user.
+type Impersonator struct {
+	UserID uuid.UUID
+	Email  string
+}
+
 type AgentAuthInfo interface {
 	CurrentDeploymentTargetID() uuid.UUID
 	CurrentOrgID() uuid.UUID
@@ -36,4 +56,10 @@ type AuthInfoWithOrganization interface {
 type AuthInfoWithUserAndOrganization interface {
 	AuthInfoWithOrganization
 	CurrentUser() *types.UserAccount
+	// WebAuthnRequired reports whether the organization requires the user to sign in with a passkey, which they
+	// did not. Until they do, CurrentPermissions is empty.
+	WebAuthnRequired() bool
+	// MFARequired reports whether the organization requires the user to sign in with a second factor, which they
+	// did not. Until they do, CurrentPermissions is empty.
+	MFARequired() bool
 }
@@ -3,18 +3,24 @@ package authinfo
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authjwt"
 	"github.com/distr-sh/distr/internal/authn"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/distr-sh/distr/internal/util"
+	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwt"
 )
 
 type DbAuthInfo struct {
 	AuthInfo
-	user *types.UserAccount
-	org  *types.OrganizationWithBranding
+	user             *types.UserAccount
+	org              *types.OrganizationWithBranding
+	webAuthnRequired bool
+	mfaRequired      bool
 }
 
 func (a DbAuthInfo) CurrentUser() *types.UserAccount {
@@ -32,9 +38,21 @@ func (a DbAuthInfo) CurrentOrgWithBranding() *types.OrganizationWithBranding {
 	return a.org
 }
 
+func (a DbAuthInfo) WebAuthnRequired() bool {
+	return a.webAuthnRequired
+}
+
+func (a DbAuthInfo) MFARequired() bool {
+	return a.mfaRequired
+}
+
 func DbAuthenticator() authn.Authenticator[AuthInfo, AuthInfoWithUserAndOrganization] {
 	fn := func(ctx context.Context, a AuthInfo) (AuthInfoWithUserAndOrganization, error) {
 		if a.CurrentOrgID() != nil {
+			session, err := verifySession(ctx, a)
+			if err != nil {
+				return nil, err
+			}
 			// Super admins: skip membership check, just verify user and org exist
 			if a.IsSuperAdmin() {
 				user, err := db.GetUserAccountByID(ctx, a.CurrentUserID())
@@ -58,7 +76,10 @@ func DbAuthenticator() authn.Authenticator[AuthInfo, AuthInfoWithUserAndOrganiza
 						emailVerified:          a.CurrentUserEmailVerified(),
 						tokenScope:             a.TokenScope(),
 						userRole:               nil, // Super admins don't have a role
+						permissions:            types.UserRoleAdmin.Permissions(),
 						isSuperAdmin:           true,
+						sessionID:              a.CurrentSessionID(),
+						accessTokenScopes:      a.CurrentAccessTokenScopes(),
 						rawToken:               a.Token(),
 					},
 					user: user,
@@ -81,7 +102,21 @@ func DbAuthenticator() authn.Authenticator[AuthInfo, AuthInfoWithUserAndOrganiza
 					// fine (e.g. a PAT scoped to a lower role); above means the
 					// user was demoted after the credential was issued.
 					return nil, authn.ErrBadAuthentication
+				} else if err := enforceSecurityPolicy(ctx, a, session, o.Organization); err != nil {
+					return nil, err
+				} else if permissions, err := membershipPermissions(ctx, u, *a.CurrentOrgID()); err != nil {
+					return nil, err
 				} else {
+					webAuthnRequired := webAuthnRequired(a, permissions, o)
+					// Like the role, the permissions of the credential can only narrow down the permissions
+					// of the membership.
+					if a.CurrentPermissions() != nil {
+						permissions = permissions.Intersect(a.CurrentPermissions())
+					}
+					mfaRequired := mfaRequired(session, u, o)
+					if webAuthnRequired || mfaRequired {
+						permissions = types.PermissionSet{}
+					}
 					return &DbAuthInfo{
 						AuthInfo: &SimpleAuthInfo{
 							userID:                 a.CurrentUserID(),
@@ -92,11 +127,18 @@ func DbAuthenticator() authn.Authenticator[AuthInfo, AuthInfoWithUserAndOrganiza
 							emailVerified:          a.CurrentUserEmailVerified(),
 							tokenScope:             a.TokenScope(),
 							userRole:               a.CurrentUserRole(),
+							permissions:            permissions,
 							isSuperAdmin:           false,
+							isServiceAccount:       a.IsServiceAccount(),
+							sessionID:              a.CurrentSessionID(),
+							accessTokenScopes:      a.CurrentAccessTokenScopes(),
+							impersonator:           a.CurrentImpersonator(),
 							rawToken:               a.Token(),
 						},
-						user: util.PtrTo(u.AsUserAccount()),
-						org:  o,
+						user:             util.PtrTo(u.AsUserAccount()),
+						org:              o,
+						webAuthnRequired: webAuthnRequired,
+						mfaRequired:      mfaRequired,
 					}, nil
 				}
 			}
@@ -115,6 +157,51 @@ func DbAuthenticator() authn.Authenticator[AuthInfo, AuthInfoWithUserAndOrganiza
 	return authn.AuthenticatorFunc[AuthInfo, AuthInfoWithUserAndOrganization](fn)
 }
 
+// verifySession makes sure that the session of a login token was not revoked. Login tokens are the only JWTs
+// with an organization, so one without a session ID was issued before sessions were introduced and is rejected.
+// The session is nil for access tokens.
+func verifySession(ctx context.Context, a AuthInfo) (*types.UserSession, error) {
+	if _, ok := a.Token().(jwt.Token); !ok {
+		return nil, nil
+	} else if a.CurrentSessionID() == nil {
+		return nil, fmt.Errorf("%w: JWT has no session", authn.ErrBadAuthentication)
+	} else if session, err := db.TouchUserSession(ctx, *a.CurrentSessionID(), a.CurrentUserID()); errors.Is(
+		err, apierrors.ErrNotFound) {
+		return nil, fmt.Errorf("%w: session was revoked or is expired", authn.ErrBadAuthentication)
+	} else {
+		return session, err
+	}
+}
+
+// membershipPermissions returns the permissions of the custom role assigned to the membership, or those of
+// its built-in role otherwise.
+func membershipPermissions(
+	ctx context.Context,
+	u *types.UserAccountWithUserRole,
+	orgID uuid.UUID,
+) (types.PermissionSet, error) {
+	if u.CustomRoleID == nil {
+		return u.UserRole.Permissions(), nil
+	} else if customRole, err := db.GetCustomRole(ctx, *u.CustomRoleID, orgID); err != nil {
+		return nil, err
+	} else {
+		return customRole.Permissions, nil
+	}
+}
+
+// webAuthnRequired reports whether the organization requires its admins to sign in with a passkey and the
+// credential is a login token that was issued without one. A member is an admin if the permissions of the
+// membership, built-in or custom, grant managing any resource. Such a session keeps access to the organization
+// context and the user's own settings, where a passkey can be registered, but loses all permissions.
+// Access tokens and impersonations are not affected, they are not issued by a login of the user.
+func webAuthnRequired(a AuthInfo, permissions types.PermissionSet, o *types.OrganizationWithBranding) bool {
+	if _, ok := a.Token().(jwt.Token); !ok {
+		return false
+	}
+	return o.SecurityPolicy.RequireWebAuthnForAdmins && permissions.GrantsManage() &&
+		!authjwt.IsWebAuthnToken(a.Token()) && a.CurrentImpersonator() == nil
+}
+
 type agentDBAuthInfo struct {
 	AuthInfo
 	org *types.OrganizationWithBranding
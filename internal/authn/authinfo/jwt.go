@@ -50,6 +50,24 @@ func FromUserJWT(token jwt.Token) (*SimpleAuthInfo, error) {
 		}
 	}
 
+	if sessionIDStr, ok := token.JwtID(); ok {
+		if sessionID, err := uuid.Parse(sessionIDStr); err != nil {
+			return nil, fmt.Errorf("%w: JWT jti is invalid: %w", authn.ErrBadAuthentication, err)
+		} else {
+			result.sessionID = &sessionID
+		}
+	}
+
+	var impersonatorIDStr string
+	if err := token.Get(authjwt.ImpersonatorIDKey, &impersonatorIDStr); err == nil {
+		if impersonatorID, err := uuid.Parse(impersonatorIDStr); err != nil {
+			return nil, fmt.Errorf("%w: JWT impersonator is invalid: %w", authn.ErrBadAuthentication, err)
+		} else {
+			result.impersonator = &Impersonator{UserID: impersonatorID}
+			_ = token.Get(authjwt.ImpersonatorEmailKey, &result.impersonator.Email)
+		}
+	}
+
 	_ = token.Get(authjwt.UserEmailKey, &result.userEmail)
 	_ = token.Get(authjwt.UserEmailVerifiedKey, &result.emailVerified)
 	_ = token.Get(authjwt.SuperAdminKey, &result.isSuperAdmin)
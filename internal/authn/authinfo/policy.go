@@ -0,0 +1,49 @@
+package authinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authn"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/securitypolicy"
+	"github.com/distr-sh/distr/internal/types"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// enforceSecurityPolicy rejects credentials of members that the security policy of the organization does not
+// allow (anymore). The policy is checked for every request rather than only at login, so that a change applies to
+// existing sessions and access tokens right away. Super admins are not subject to it.
+func enforceSecurityPolicy(
+	ctx context.Context,
+	a AuthInfo,
+	session *types.UserSession,
+	org types.Organization,
+) error {
+	ip := chimiddleware.GetClientIP(ctx)
+	var err error
+	if session == nil {
+		err = securitypolicy.CheckAddress(org, ip)
+	} else if err = securitypolicy.CheckIdle(org, *session); err != nil {
+		// The session was touched already, so it has to be revoked for the timeout to stick.
+		if err := db.DeleteUserSession(ctx, session.ID, a.CurrentUserID()); err != nil &&
+			!errors.Is(err, apierrors.ErrNotFound) {
+			return err
+		}
+	} else {
+		err = securitypolicy.CheckLogin(ctx, org, session.AuthMethod, ip)
+	}
+	if errors.Is(err, securitypolicy.ErrViolation) {
+		return fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+	}
+	return err
+}
+
+// mfaRequired reports whether the organization requires a second factor and the session was created without one.
+// Like webAuthnRequired, such a session keeps access to the user's own settings, where MFA can be enabled, but
+// loses all permissions. Access tokens are not affected.
+func mfaRequired(session *types.UserSession, u *types.UserAccountWithUserRole, o *types.OrganizationWithBranding) bool {
+	return session != nil && o.SecurityPolicy.RequireMFA && !session.AuthMethod.SatisfiesMFA(u.MFAEnabled)
+}
@@ -15,7 +15,12 @@ type SimpleAuthInfo struct {
 	emailVerified          bool
 	tokenScope             authjwt.TokenScope
 	userRole               *types.UserRole
+	permissions            types.PermissionSet
 	isSuperAdmin           bool
+	isServiceAccount       bool
+	sessionID              *uuid.UUID
+	accessTokenScopes      types.AccessTokenScopes
+	impersonator           *Impersonator
 	rawToken               any
 }
 
@@ -43,9 +48,26 @@ func (i *SimpleAuthInfo) CurrentUserEmail() string { return i.userEmail }
 // CurrentUserRole implements AuthInfo.
 func (i *SimpleAuthInfo) CurrentUserRole() *types.UserRole { return i.userRole }
 
+// CurrentPermissions implements AuthInfo.
+func (i *SimpleAuthInfo) CurrentPermissions() types.PermissionSet { return i.permissions }
+
 // IsSuperAdmin implements AuthInfo.
 func (i *SimpleAuthInfo) IsSuperAdmin() bool { return i.isSuperAdmin }
 
+// IsServiceAccount implements AuthInfo.
+func (i *SimpleAuthInfo) IsServiceAccount() bool { return i.isServiceAccount }
+
+// CurrentSessionID implements AuthInfo.
+func (i *SimpleAuthInfo) CurrentSessionID() *uuid.UUID { return i.sessionID }
+
+// CurrentAccessTokenScopes implements AuthInfo.
+func (i *SimpleAuthInfo) CurrentAccessTokenScopes() types.AccessTokenScopes {
+	return i.accessTokenScopes
+}
+
+// CurrentImpersonator implements AuthInfo.
+func (i *SimpleAuthInfo) CurrentImpersonator() *Impersonator { return i.impersonator }
+
 // Token implements AuthInfo.
 func (i *SimpleAuthInfo) Token() any { return i.rawToken }
 
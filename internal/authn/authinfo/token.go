@@ -9,25 +9,42 @@ import (
 	"github.com/distr-sh/distr/internal/authkey"
 	"github.com/distr-sh/distr/internal/authn"
 	"github.com/distr-sh/distr/internal/db"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 )
 
 func FromAuthKey(ctx context.Context, token authkey.Key) (AuthInfo, error) {
-	if at, err := db.GetAccessTokenByKeyUpdatingLastUsed(ctx, token); err != nil {
+	if at, err := db.GetAccessTokenByKeyUpdatingLastUsed(ctx, token, chimiddleware.GetClientIP(ctx)); err != nil {
 		if errors.Is(err, apierrors.ErrNotFound) {
 			err = fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
 		}
 		return nil, err
 	} else {
 		role := at.EffectiveUserRole()
-		return &SimpleAuthInfo{
+		info := &SimpleAuthInfo{
 			userID:                 at.UserAccount.ID,
 			userEmail:              at.UserAccount.Email,
 			emailVerified:          at.UserAccount.EmailVerifiedAt != nil,
 			organizationID:         &at.OrganizationID,
 			customerOrganizationID: at.CustomerOrganizationID,
 			userRole:               &role,
+			isServiceAccount:       at.UserAccount.IsServiceAccount(),
+			accessTokenScopes:      at.Scopes,
 			rawToken:               token,
-		}, nil
+		}
+		// The permissions of the token are only an upper bound, the DbAuthenticator intersects them with the
+		// current permissions of the user.
+		if at.AccessToken.CustomRoleID != nil {
+			if customRole, err := db.GetCustomRole(ctx, *at.AccessToken.CustomRoleID, at.OrganizationID); err != nil {
+				return nil, err
+			} else {
+				info.permissions = customRole.Permissions
+			}
+		} else if at.AccessToken.UserRole != nil {
+			info.permissions = at.AccessToken.UserRole.Permissions()
+		} else if len(at.Scopes) > 0 {
+			info.permissions = at.Scopes.Permissions()
+		}
+		return info, nil
 	}
 }
 
@@ -0,0 +1,58 @@
+package authinfo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authn"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/workloadidentity"
+	"github.com/google/uuid"
+)
+
+// WorkloadIdentityAuthenticator authenticates OCI clients that log in with the ID of a trust policy as username
+// and an ID token that satisfies it as password, as the service account of the policy. Other credentials are
+// left to the next authenticator.
+func WorkloadIdentityAuthenticator() authn.Authenticator[*http.Request, AuthInfo] {
+	fn := func(ctx context.Context, r *http.Request) (AuthInfo, error) {
+		username, password, ok := r.BasicAuth()
+		if !ok || strings.Count(password, ".") != 2 {
+			return nil, authn.ErrNoAuthentication
+		}
+		trustPolicyID, err := uuid.Parse(username)
+		if err != nil {
+			return nil, authn.ErrNoAuthentication
+		}
+		policy, err := workloadidentity.Verify(ctx, trustPolicyID, password)
+		if errors.Is(err, workloadidentity.ErrUntrusted) {
+			return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+		} else if err != nil {
+			return nil, err
+		}
+		serviceAccount, err := db.GetServiceAccount(ctx, policy.ServiceAccountID, policy.OrganizationID)
+		if errors.Is(err, apierrors.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+		} else if err != nil {
+			return nil, err
+		}
+		info := &SimpleAuthInfo{
+			userID:            serviceAccount.ID,
+			userEmail:         serviceAccount.Email,
+			emailVerified:     true,
+			organizationID:    &policy.OrganizationID,
+			userRole:          &serviceAccount.UserRole,
+			isServiceAccount:  true,
+			accessTokenScopes: policy.Scopes,
+			rawToken:          workloadidentity.Token{TrustPolicyID: policy.ID},
+		}
+		if len(policy.Scopes) > 0 {
+			info.permissions = policy.Scopes.Permissions()
+		}
+		return info, nil
+	}
+	return authn.AuthenticatorFunc[*http.Request, AuthInfo](fn)
+}
@@ -0,0 +1,42 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authkey"
+	"github.com/distr-sh/distr/internal/authn"
+	"github.com/distr-sh/distr/internal/authn/token"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+// Authenticator authenticates identity providers using a SCIM token passed as Bearer token.
+func Authenticator() authn.RequestAuthenticator[*types.ScimToken] {
+	extractToken := token.FromHeader("Bearer")
+	return authn.AuthenticatorFunc[*http.Request, *types.ScimToken](
+		func(ctx context.Context, r *http.Request) (*types.ScimToken, error) {
+			encoded := extractToken(r)
+			if encoded == "" {
+				return nil, authn.ErrNoAuthentication
+			}
+
+			key, err := authkey.Parse(encoded)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w", authn.ErrBadAuthentication, err)
+			}
+
+			scimToken, err := db.GetScimTokenByKeyUpdatingLastUsed(ctx, key)
+			if errors.Is(err, apierrors.ErrNotFound) {
+				return nil, fmt.Errorf("%w: invalid token", authn.ErrBadAuthentication)
+			} else if err != nil {
+				return nil, err
+			}
+
+			return scimToken, nil
+		},
+	)
+}
@@ -0,0 +1,19 @@
+package cleanup
+
+import (
+	"context"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"go.uber.org/zap"
+)
+
+func RunAuditLogCleanup(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	if count, err := db.CleanupAuditLogEntries(ctx); err != nil {
+		return err
+	} else {
+		log.Info("AuditLogEntries cleanup finished", zap.Int64("rowsDeleted", count))
+		return nil
+	}
+}
@@ -8,12 +8,24 @@ import (
 	"go.uber.org/zap"
 )
 
+// RunOIDCStateCleanup deletes expired OIDC states as well as expired SAML requests and WebAuthn sessions, which
+// serve the same purpose for SAML and passkey logins and expire after the same time.
 func RunOIDCStateCleanup(ctx context.Context) error {
 	log := internalctx.GetLogger(ctx)
 	if count, err := db.CleanupOIDCStates(ctx); err != nil {
 		return err
 	} else {
 		log.Info("OIDCStates cleanup finished", zap.Int64("rowsDeleted", count))
+	}
+	if count, err := db.CleanupSAMLRequests(ctx); err != nil {
+		return err
+	} else {
+		log.Info("SAMLRequests cleanup finished", zap.Int64("rowsDeleted", count))
+	}
+	if count, err := db.CleanupWebAuthnSessions(ctx); err != nil {
+		return err
+	} else {
+		log.Info("WebAuthnSessions cleanup finished", zap.Int64("rowsDeleted", count))
 		return nil
 	}
 }
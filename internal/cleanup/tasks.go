@@ -0,0 +1,19 @@
+package cleanup
+
+import (
+	"context"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"go.uber.org/zap"
+)
+
+func RunTaskCleanup(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	if count, err := db.CleanupTasks(ctx); err != nil {
+		return err
+	} else {
+		log.Info("Tasks cleanup finished", zap.Int64("rowsDeleted", count))
+		return nil
+	}
+}
@@ -0,0 +1,19 @@
+package cleanup
+
+import (
+	"context"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"go.uber.org/zap"
+)
+
+func RunUserSessionCleanup(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+	if count, err := db.CleanupUserSessions(ctx); err != nil {
+		return err
+	} else {
+		log.Info("UserSessions cleanup finished", zap.Int64("rowsDeleted", count))
+		return nil
+	}
+}
@@ -14,6 +14,9 @@ const (
 	MediaTypeTextPlain        = "text/plain"
 	MediaTypeTextXYaml        = "text/x-yaml"
 	MediaTypeApplicationXYaml = "application/x-yaml"
+	MediaTypeGzip             = "application/gzip"
+	MediaTypeXGzip            = "application/x-gzip"
+	MediaTypeXCompressedTar   = "application/x-compressed-tar"
 )
 
 func IsYaml(header textproto.MIMEHeader) error {
@@ -25,6 +28,23 @@ func IsYaml(header textproto.MIMEHeader) error {
 		MediaTypeApplicationXYaml)
 }
 
+func IsYamlOrGzip(header textproto.MIMEHeader) error {
+	return HasMediaType(header,
+		MediaTypeJSON,
+		MediaTypeYAML,
+		MediaTypeTextYAML,
+		MediaTypeTextXYaml,
+		MediaTypeApplicationXYaml,
+		MediaTypeGzip,
+		MediaTypeXGzip,
+		MediaTypeXCompressedTar,
+		MediaTypeOctetStream)
+}
+
+func IsPlainText(header textproto.MIMEHeader) error {
+	return HasMediaType(header, MediaTypeTextPlain, MediaTypeOctetStream)
+}
+
 func HasMediaType(header textproto.MIMEHeader, acceptedContentTypes ...string) error {
 	contentType, err := ParseContentType(header.Get("Content-Type"))
 	if err != nil {
@@ -15,12 +15,14 @@ import (
 
 const (
 	accessTokenOutputExpr = `
-	tok.id, tok.created_at, tok.expires_at, tok.last_used_at, tok.label, tok.key,
-	tok.user_account_id, tok.organization_id, tok.user_role AS token_user_role
+	tok.id, tok.created_at, tok.expires_at, tok.last_used_at, tok.last_used_ip, tok.label, tok.key,
+	tok.user_account_id, tok.organization_id, tok.user_role AS token_user_role,
+	tok.custom_role_id AS token_custom_role_id, tok.scopes, tok.unused_expiry_days
 `
 	accessTokenWithUserAccountOutputExpr = accessTokenOutputExpr + `,
 	(` + userAccountOutputExpr + `) AS user_account,
 	oua.user_role,
+	oua.custom_role_id,
 	oua.customer_organization_id
 `
 )
@@ -30,17 +32,23 @@ func CreateAccessToken(ctx context.Context, token *types.AccessToken) error {
 	rows, err := db.Query(
 		ctx,
 		fmt.Sprintf(
-			`INSERT INTO AccessToken AS tok (label, expires_at, key, user_account_id, organization_id, user_role)
-			VALUES (@label, @expiresAt, @key, @userAccountId, @orgId, @userRole)
+			`INSERT INTO AccessToken AS tok
+				(label, expires_at, key, user_account_id, organization_id, user_role, custom_role_id, scopes,
+					unused_expiry_days)
+			VALUES (@label, @expiresAt, @key, @userAccountId, @orgId, @userRole, @customRoleId,
+				coalesce(@scopes::TEXT[], '{}'), @unusedExpiryDays)
 			RETURNING %v`,
 			accessTokenOutputExpr),
 		pgx.NamedArgs{
-			"label":         token.Label,
-			"expiresAt":     token.ExpiresAt,
-			"key":           token.Key[:],
-			"userAccountId": token.UserAccountID,
-			"orgId":         token.OrganizationID,
-			"userRole":      token.UserRole,
+			"label":            token.Label,
+			"expiresAt":        token.ExpiresAt,
+			"key":              token.Key[:],
+			"userAccountId":    token.UserAccountID,
+			"orgId":            token.OrganizationID,
+			"userRole":         token.UserRole,
+			"customRoleId":     token.CustomRoleID,
+			"scopes":           token.Scopes,
+			"unusedExpiryDays": token.UnusedExpiryDays,
 		},
 	)
 	if err != nil {
@@ -66,6 +74,31 @@ func DeleteAccessToken(ctx context.Context, id, userID uuid.UUID) error {
 	return nil
 }
 
+// RotateAccessTokenKey replaces the key of the token, so that the old key stops working immediately.
+func RotateAccessTokenKey(ctx context.Context, id, userID uuid.UUID, key authkey.Key) (*types.AccessToken, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		fmt.Sprintf(
+			`UPDATE AccessToken AS tok SET key = @key
+			WHERE tok.id = @id AND tok.user_account_id = @userId
+			RETURNING %v`,
+			accessTokenOutputExpr),
+		pgx.NamedArgs{"id": id, "userId": userID, "key": key[:]},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not rotate access token: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.AccessToken]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not rotate access token: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
 func GetAccessTokens(ctx context.Context, userID, orgID uuid.UUID) ([]types.AccessToken, error) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(
@@ -86,9 +119,12 @@ func GetAccessTokens(ctx context.Context, userID, orgID uuid.UUID) ([]types.Acce
 	}
 }
 
+// GetAccessTokenByKeyUpdatingLastUsed returns apierrors.ErrNotFound unless the token exists and has neither expired
+// nor been unused for longer than its unused expiry. The time and IP address of the usage are recorded.
 func GetAccessTokenByKeyUpdatingLastUsed(
 	ctx context.Context,
 	key authkey.Key,
+	remoteAddress string,
 ) (*types.AccessTokenWithUserAccount, error) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(
@@ -96,8 +132,11 @@ func GetAccessTokenByKeyUpdatingLastUsed(
 		fmt.Sprintf(
 			`WITH updated AS (
 				UPDATE AccessToken
-				SET last_used_at = now()
-				WHERE key = @key AND (expires_at IS NULL OR expires_at > now())
+				SET last_used_at = now(), last_used_ip = coalesce(NULLIF(@remoteAddress, ''), last_used_ip)
+				WHERE key = @key
+					AND (expires_at IS NULL OR expires_at > now())
+					AND (unused_expiry_days IS NULL
+						OR coalesce(last_used_at, created_at) + make_interval(days => unused_expiry_days) > now())
 				RETURNING *
 			)
 			SELECT %v FROM updated tok
@@ -107,7 +146,7 @@ func GetAccessTokenByKeyUpdatingLastUsed(
 			`,
 			accessTokenWithUserAccountOutputExpr,
 		),
-		pgx.NamedArgs{"key": key[:]},
+		pgx.NamedArgs{"key": key[:], "remoteAddress": remoteAddress},
 	)
 	if err != nil {
 		return nil, fmt.Errorf("error querying access token: %w", err)
@@ -133,3 +172,17 @@ func DeleteAccessTokensOfUserInOrg(ctx context.Context, userID, orgID uuid.UUID)
 	}
 	return nil
 }
+
+// DeleteExpiredAccessTokens deletes the expired tokens of the user in the organization.
+func DeleteExpiredAccessTokens(ctx context.Context, userID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM AccessToken
+		WHERE user_account_id = @userId AND organization_id = @orgId AND expires_at < now()`,
+		pgx.NamedArgs{"userId": userID, "orgId": orgID},
+	); err != nil {
+		return fmt.Errorf("could not delete expired tokens: %w", err)
+	}
+	return nil
+}
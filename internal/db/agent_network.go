@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const deploymentTargetAgentNetworkOutputExpr = `
+	n.deployment_target_id,
+	n.updated_at,
+	n.proxy_url,
+	n.proxy_username,
+	n.proxy_password,
+	n.no_proxy,
+	n.ca_certificates,
+	n.client_certificate,
+	n.client_key
+`
+
+func GetDeploymentTargetAgentNetwork(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+) (*types.DeploymentTargetAgentNetwork, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentTargetAgentNetworkOutputExpr+`
+		FROM DeploymentTargetAgentNetwork n
+		WHERE n.deployment_target_id = @deploymentTargetId`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentTargetAgentNetwork: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentTargetAgentNetwork])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentTargetAgentNetwork: %w", err)
+	}
+	return &result, nil
+}
+
+// SaveDeploymentTargetAgentNetwork creates or replaces the agent network settings of a deployment target. The proxy
+// password of existing settings is kept if network.ProxyPassword is nil and the proxy URL and username are unchanged,
+// and the client key is kept if network.ClientKey is nil and the client certificate is unchanged.
+func SaveDeploymentTargetAgentNetwork(ctx context.Context, network *types.DeploymentTargetAgentNetwork) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO DeploymentTargetAgentNetwork AS n (
+			deployment_target_id, proxy_url, proxy_username, proxy_password, no_proxy, ca_certificates,
+			client_certificate, client_key
+		) VALUES (
+			@deploymentTargetId, @proxyUrl, @proxyUsername, @proxyPassword, @noProxy, @caCertificates,
+			@clientCertificate, @clientKey
+		)
+		ON CONFLICT (deployment_target_id) DO UPDATE SET
+			updated_at = current_timestamp,
+			proxy_url = EXCLUDED.proxy_url,
+			proxy_username = EXCLUDED.proxy_username,
+			proxy_password = CASE
+				WHEN EXCLUDED.proxy_password IS NULL AND EXCLUDED.proxy_username = n.proxy_username
+					AND EXCLUDED.proxy_url = n.proxy_url
+					THEN n.proxy_password
+				ELSE EXCLUDED.proxy_password
+			END,
+			no_proxy = EXCLUDED.no_proxy,
+			ca_certificates = EXCLUDED.ca_certificates,
+			client_certificate = EXCLUDED.client_certificate,
+			client_key = CASE
+				WHEN EXCLUDED.client_key IS NULL AND EXCLUDED.client_certificate = n.client_certificate
+					THEN n.client_key
+				ELSE EXCLUDED.client_key
+			END
+		RETURNING`+deploymentTargetAgentNetworkOutputExpr,
+		pgx.NamedArgs{
+			"deploymentTargetId": network.DeploymentTargetID,
+			"proxyUrl":           network.ProxyURL,
+			"proxyUsername":      network.ProxyUsername,
+			"proxyPassword":      network.ProxyPassword,
+			"noProxy":            network.NoProxy,
+			"caCertificates":     network.CACertificates,
+			"clientCertificate":  network.ClientCertificate,
+			"clientKey":          network.ClientKey,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not save DeploymentTargetAgentNetwork: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentTargetAgentNetwork])
+	if err != nil {
+		return fmt.Errorf("could not save DeploymentTargetAgentNetwork: %w", err)
+	}
+	*network = result
+	return nil
+}
+
+func DeleteDeploymentTargetAgentNetwork(ctx context.Context, deploymentTargetID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentTargetAgentNetwork WHERE deployment_target_id = @deploymentTargetId`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete DeploymentTargetAgentNetwork: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
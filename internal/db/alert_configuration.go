@@ -24,6 +24,8 @@ const (
 	c.cpu_trigger_threshold_percent,
 	c.memory_trigger_threshold_percent,
 	c.disk_trigger_threshold_percent,
+	c.container_cpu_trigger_threshold_percent,
+	c.container_memory_trigger_threshold_percent,
 	(
 		SELECT array_agg(dt.id)
 		FROM DeploymentTarget dt
@@ -174,7 +176,9 @@ func CreateAlertConfiguration(ctx context.Context, config *types.AlertConfigurat
 				status_trigger_enabled,
 				cpu_trigger_threshold_percent,
 				memory_trigger_threshold_percent,
-				disk_trigger_threshold_percent
+				disk_trigger_threshold_percent,
+				container_cpu_trigger_threshold_percent,
+				container_memory_trigger_threshold_percent
 			) VALUES (
 				@organizationID,
 				@customerOrganizationID,
@@ -183,20 +187,24 @@ func CreateAlertConfiguration(ctx context.Context, config *types.AlertConfigurat
 				@statusTriggerEnabled,
 				@cpuTriggerThreshold,
 				@memoryTriggerThreshold,
-				@diskTriggerThreshold
+				@diskTriggerThreshold,
+				@containerCpuTriggerThreshold,
+				@containerMemoryTriggerThreshold
 			)
 			RETURNING id
 		)
 		SELECT id FROM inserted`,
 			pgx.NamedArgs{
-				"organizationID":         config.OrganizationID,
-				"customerOrganizationID": config.CustomerOrganizationID,
-				"name":                   config.Name,
-				"enabled":                config.Enabled,
-				"statusTriggerEnabled":   config.StatusTriggerEnabled,
-				"cpuTriggerThreshold":    config.CpuTriggerThreshold,
-				"memoryTriggerThreshold": config.MemoryTriggerThreshold,
-				"diskTriggerThreshold":   config.DiskTriggerThreshold,
+				"organizationID":                  config.OrganizationID,
+				"customerOrganizationID":          config.CustomerOrganizationID,
+				"name":                            config.Name,
+				"enabled":                         config.Enabled,
+				"statusTriggerEnabled":            config.StatusTriggerEnabled,
+				"cpuTriggerThreshold":             config.CpuTriggerThreshold,
+				"memoryTriggerThreshold":          config.MemoryTriggerThreshold,
+				"diskTriggerThreshold":            config.DiskTriggerThreshold,
+				"containerCpuTriggerThreshold":    config.ContainerCpuTriggerThreshold,
+				"containerMemoryTriggerThreshold": config.ContainerMemoryTriggerThreshold,
 			},
 		)
 		if err != nil {
@@ -233,21 +241,25 @@ func UpdateAlertConfiguration(ctx context.Context, config *types.AlertConfigurat
 				status_trigger_enabled = @statusTriggerEnabled,
 				cpu_trigger_threshold_percent = @cpuTriggerThreshold,
 				memory_trigger_threshold_percent = @memoryTriggerThreshold,
-				disk_trigger_threshold_percent = @diskTriggerThreshold
+				disk_trigger_threshold_percent = @diskTriggerThreshold,
+				container_cpu_trigger_threshold_percent = @containerCpuTriggerThreshold,
+				container_memory_trigger_threshold_percent = @containerMemoryTriggerThreshold
 			WHERE id = @id
 				AND organization_id = @orgID
 				AND ((@customerOrgIsNull AND customer_organization_id IS NULL) OR (customer_organization_id = @customerOrgID))`,
 			pgx.NamedArgs{
-				"id":                     config.ID,
-				"name":                   config.Name,
-				"enabled":                config.Enabled,
-				"statusTriggerEnabled":   config.StatusTriggerEnabled,
-				"cpuTriggerThreshold":    config.CpuTriggerThreshold,
-				"memoryTriggerThreshold": config.MemoryTriggerThreshold,
-				"diskTriggerThreshold":   config.DiskTriggerThreshold,
-				"orgID":                  config.OrganizationID,
-				"customerOrgID":          config.CustomerOrganizationID,
-				"customerOrgIsNull":      config.CustomerOrganizationID == nil,
+				"id":                              config.ID,
+				"name":                            config.Name,
+				"enabled":                         config.Enabled,
+				"statusTriggerEnabled":            config.StatusTriggerEnabled,
+				"cpuTriggerThreshold":             config.CpuTriggerThreshold,
+				"memoryTriggerThreshold":          config.MemoryTriggerThreshold,
+				"diskTriggerThreshold":            config.DiskTriggerThreshold,
+				"containerCpuTriggerThreshold":    config.ContainerCpuTriggerThreshold,
+				"containerMemoryTriggerThreshold": config.ContainerMemoryTriggerThreshold,
+				"orgID":                           config.OrganizationID,
+				"customerOrgID":                   config.CustomerOrganizationID,
+				"customerOrgIsNull":               config.CustomerOrganizationID == nil,
 			},
 		)
 		if err != nil {
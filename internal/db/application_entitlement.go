@@ -23,7 +23,7 @@ const (
 		coalesce((
 		   	SELECT array_agg(
 				row(av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-					av.chart_type, av.chart_name, av.chart_url, av.chart_version)
+					av.source_type, av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.package_ref)
 				ORDER BY av.created_at ASC
 			)
 		   	FROM ApplicationEntitlement_ApplicationVersion alav
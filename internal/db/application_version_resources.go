@@ -74,3 +74,47 @@ func GetApplicationVersionResourcesVisibleToCustomers(
 	}
 	return result, nil
 }
+
+func CreateApplicationVersionHealthRules(
+	ctx context.Context,
+	versionID uuid.UUID,
+	rules []types.ApplicationVersionHealthRule,
+) error {
+	if len(rules) == 0 {
+		return nil
+	}
+	db := internalctx.GetDb(ctx)
+	_, err := db.CopyFrom(
+		ctx,
+		pgx.Identifier{"applicationversionhealthrule"},
+		[]string{"application_version_id", "kind", "json_path", "value"},
+		pgx.CopyFromSlice(len(rules), func(i int) ([]any, error) {
+			return []any{versionID, rules[i].Kind, rules[i].JSONPath, rules[i].Value}, nil
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("could not create ApplicationVersionHealthRules: %w", err)
+	}
+	return nil
+}
+
+func GetApplicationVersionHealthRules(
+	ctx context.Context,
+	versionID uuid.UUID,
+) ([]types.ApplicationVersionHealthRule, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT id, application_version_id, kind, json_path, value
+		FROM ApplicationVersionHealthRule
+		WHERE application_version_id = @versionId
+		ORDER BY kind, json_path`,
+		pgx.NamedArgs{"versionId": versionID})
+	if err != nil {
+		return nil, fmt.Errorf("could not query ApplicationVersionHealthRules: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ApplicationVersionHealthRule])
+	if err != nil {
+		return nil, fmt.Errorf("could not scan ApplicationVersionHealthRules: %w", err)
+	}
+	return result, nil
+}
@@ -18,12 +18,13 @@ import (
 const (
 	applicationOutputExpr        = `a.id, a.created_at, a.organization_id, a.name, a.type, a.image_id`
 	applicationVersionOutputExpr = `av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-		av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.values_file_data, av.template_file_data,
-	 av.compose_file_data`
+		av.source_type, av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.package_ref,
+		av.values_file_data, av.template_file_data, av.compose_file_data, av.manifest_bundle_data, av.unit_file_data`
 	applicationWithVersionsOutputExpr = applicationOutputExpr + `,
 		coalesce((
 			SELECT array_agg(row(av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-				av.chart_type, av.chart_name, av.chart_url, av.chart_version) ORDER BY av.created_at ASC)
+				av.source_type, av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.package_ref)
+				ORDER BY av.created_at ASC)
 			FROM ApplicationVersion av
 			WHERE av.application_id = a.id
 		), array[]::record[]) AS versions `
@@ -31,7 +32,8 @@ const (
 	applicationWithEntitledVersionsOutputExpr = applicationOutputExpr + `,
 		coalesce((
 			SELECT array_agg(row(av.id, av.created_at, av.archived_at, av.name, av.link_template, av.application_id,
-				av.chart_type, av.chart_name, av.chart_url, av.chart_version) ORDER BY av.created_at ASC)
+				av.source_type, av.chart_type, av.chart_name, av.chart_url, av.chart_version, av.package_ref)
+				ORDER BY av.created_at ASC)
 			FROM ApplicationVersion av
 			WHERE av.application_id = a.id and
 				((av.id IN
@@ -220,10 +222,12 @@ func CreateApplicationVersion(ctx context.Context, applicationVersion *types.App
 		"name":          applicationVersion.Name,
 		"linkTemplate":  applicationVersion.LinkTemplate,
 		"applicationId": applicationVersion.ApplicationID,
+		"sourceType":    applicationVersion.SourceType,
 		"chartType":     applicationVersion.ChartType,
 		"chartName":     applicationVersion.ChartName,
 		"chartUrl":      applicationVersion.ChartUrl,
 		"chartVersion":  applicationVersion.ChartVersion,
+		"packageRef":    applicationVersion.PackageRef,
 	}
 	if applicationVersion.ComposeFileData != nil {
 		args["composeFileData"] = applicationVersion.ComposeFileData
@@ -234,15 +238,23 @@ func CreateApplicationVersion(ctx context.Context, applicationVersion *types.App
 	if applicationVersion.TemplateFileData != nil {
 		args["templateFileData"] = applicationVersion.TemplateFileData
 	}
+	if applicationVersion.ManifestBundleData != nil {
+		args["manifestBundleData"] = applicationVersion.ManifestBundleData
+	}
+	if applicationVersion.UnitFileData != nil {
+		args["unitFileData"] = applicationVersion.UnitFileData
+	}
 
 	row, err := db.Query(ctx,
-		`INSERT INTO ApplicationVersion AS av (name, link_template, application_id, chart_type, chart_name, chart_url,
-				chart_version, compose_file_data, values_file_data, template_file_data)
-		VALUES (@name, @linkTemplate, @applicationId, @chartType, @chartName, @chartUrl, @chartVersion,
-			@composeFileData::bytea, @valuesFileData::bytea, @templateFileData::bytea)
-		RETURNING av.id, av.created_at, av.archived_at, av.name, av.link_template, av.chart_type, av.chart_name,
-			av.chart_url, av.chart_version, av.values_file_data, av.template_file_data, av.compose_file_data,
-			av.application_id`,
+		`INSERT INTO ApplicationVersion AS av (name, link_template, application_id, source_type, chart_type, chart_name,
+				chart_url, chart_version, package_ref, compose_file_data, values_file_data, template_file_data,
+				manifest_bundle_data, unit_file_data)
+		VALUES (@name, @linkTemplate, @applicationId, @sourceType, @chartType, @chartName, @chartUrl, @chartVersion,
+			@packageRef, @composeFileData::bytea, @valuesFileData::bytea, @templateFileData::bytea,
+			@manifestBundleData::bytea, @unitFileData::bytea)
+		RETURNING av.id, av.created_at, av.archived_at, av.name, av.link_template, av.source_type, av.chart_type,
+			av.chart_name, av.chart_url, av.chart_version, av.package_ref, av.values_file_data, av.template_file_data,
+			av.compose_file_data, av.manifest_bundle_data, av.unit_file_data, av.application_id`,
 		args)
 	if err != nil {
 		return fmt.Errorf("cannot create ApplicationVersion: %w", err)
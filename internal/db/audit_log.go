@@ -0,0 +1,141 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/jackc/pgx/v5"
+)
+
+const auditLogEntryOutputExpr = `
+	e.id, e.created_at, e.organization_id, e.actor_user_account_id, e.actor_email, e.auth_method,
+	e.customer_organization_id, e.partner_organization_id, e.resource, e.resource_id, e.action,
+	e.http_method, e.path, e.status_code, e.changes, e.remote_address, e.request_id, e.impersonator_user_account_id,
+	e.impersonator_email
+`
+
+func CreateAuditLogEntry(ctx context.Context, entry *types.AuditLogEntry) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO AuditLogEntry AS e (
+			organization_id, actor_user_account_id, actor_email, auth_method, customer_organization_id,
+			partner_organization_id, resource, resource_id, action, http_method, path, status_code, changes,
+			remote_address, request_id, impersonator_user_account_id, impersonator_email
+		) VALUES (
+			@organizationId, @actorUserAccountId, @actorEmail, @authMethod, @customerOrganizationId,
+			@partnerOrganizationId, @resource, @resourceId, @action, @httpMethod, @path, @statusCode, @changes,
+			@remoteAddress, @requestId, @impersonatorUserAccountId, @impersonatorEmail
+		)
+		RETURNING`+auditLogEntryOutputExpr,
+		pgx.NamedArgs{
+			"organizationId":            entry.OrganizationID,
+			"actorUserAccountId":        entry.ActorUserAccountID,
+			"actorEmail":                entry.ActorEmail,
+			"authMethod":                entry.AuthMethod,
+			"customerOrganizationId":    entry.CustomerOrganizationID,
+			"partnerOrganizationId":     entry.PartnerOrganizationID,
+			"resource":                  entry.Resource,
+			"resourceId":                entry.ResourceID,
+			"action":                    entry.Action,
+			"httpMethod":                entry.HTTPMethod,
+			"path":                      entry.Path,
+			"statusCode":                entry.StatusCode,
+			"changes":                   entry.Changes,
+			"remoteAddress":             entry.RemoteAddress,
+			"requestId":                 entry.RequestID,
+			"impersonatorUserAccountId": entry.ImpersonatorUserAccountID,
+			"impersonatorEmail":         entry.ImpersonatorEmail,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert AuditLogEntry: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.AuditLogEntry])
+	if err != nil {
+		return fmt.Errorf("could not collect AuditLogEntry: %w", err)
+	}
+	*entry = created
+	return nil
+}
+
+func GetAuditLogEntries(ctx context.Context, filter types.AuditLogFilter) ([]types.AuditLogEntry, error) {
+	db := internalctx.GetDb(ctx)
+
+	conditions := []string{
+		"e.organization_id = @orgId",
+		"e.created_at < @before",
+	}
+	args := pgx.NamedArgs{
+		"orgId":  filter.OrgID,
+		"before": filter.Before,
+		"count":  filter.Count,
+	}
+
+	if !filter.After.IsZero() {
+		conditions = append(conditions, "e.created_at > @after")
+		args["after"] = filter.After
+	}
+	if filter.ActorUserAccountID != nil {
+		conditions = append(conditions, "e.actor_user_account_id = @actorUserAccountId")
+		args["actorUserAccountId"] = *filter.ActorUserAccountID
+	}
+	if filter.AuthMethod != nil {
+		conditions = append(conditions, "e.auth_method = @authMethod")
+		args["authMethod"] = *filter.AuthMethod
+	}
+	if filter.CustomerOrganizationID != nil {
+		conditions = append(conditions, "e.customer_organization_id = @customerOrganizationId")
+		args["customerOrganizationId"] = *filter.CustomerOrganizationID
+	}
+	if filter.PartnerOrganizationID != nil {
+		conditions = append(conditions, "e.partner_organization_id = @partnerOrganizationId")
+		args["partnerOrganizationId"] = *filter.PartnerOrganizationID
+	}
+	if filter.Resource != nil {
+		conditions = append(conditions, "e.resource = @resource")
+		args["resource"] = *filter.Resource
+	}
+	if filter.ResourceID != nil {
+		conditions = append(conditions, "e.resource_id = @resourceId")
+		args["resourceId"] = *filter.ResourceID
+	}
+	if filter.Action != nil {
+		conditions = append(conditions, "e.action = @action")
+		args["action"] = *filter.Action
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT"+auditLogEntryOutputExpr+
+			`FROM AuditLogEntry e
+			WHERE `+strings.Join(conditions, " AND ")+`
+			ORDER BY e.created_at DESC
+			LIMIT @count`,
+		args,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query AuditLogEntry: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.AuditLogEntry])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect AuditLogEntry: %w", err)
+	}
+	return result, nil
+}
+
+func CleanupAuditLogEntries(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM AuditLogEntry WHERE current_timestamp - created_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": env.AuditLogEntriesMaxAge()},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up AuditLogEntry: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const SAMLRequestMaxAge = OIDCStateMaxAge
+
+type SAMLRequest struct {
+	ID                        string    `db:"id"`
+	CreatedAt                 time.Time `db:"created_at"`
+	CustomSAMLConfigurationID uuid.UUID `db:"custom_saml_configuration_id"`
+}
+
+func (s SAMLRequest) Expired() bool {
+	return s.CreatedAt.Before(time.Now().UTC().Add(-SAMLRequestMaxAge))
+}
+
+func CreateSAMLRequest(ctx context.Context, id string, customSAMLConfigurationID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(ctx,
+		`INSERT INTO SAMLRequest (id, custom_saml_configuration_id) VALUES (@id, @customSamlConfigurationId)`,
+		pgx.NamedArgs{"id": id, "customSamlConfigurationId": customSAMLConfigurationID},
+	); err != nil {
+		return fmt.Errorf("could not insert SAMLRequest: %w", err)
+	}
+	return nil
+}
+
+func DeleteSAMLRequest(ctx context.Context, id string) (SAMLRequest, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`DELETE FROM SAMLRequest AS s WHERE s.id = @id
+		RETURNING s.id, s.created_at, s.custom_saml_configuration_id`,
+		pgx.NamedArgs{"id": id})
+	if err != nil {
+		return SAMLRequest{}, fmt.Errorf("could not delete SAMLRequest: %w", err)
+	}
+	request, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[SAMLRequest])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return SAMLRequest{}, apierrors.ErrNotFound
+	} else if err != nil {
+		return SAMLRequest{}, fmt.Errorf("could not delete SAMLRequest: %w", err)
+	}
+	return request, nil
+}
+
+func CleanupSAMLRequests(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM SAMLRequest WHERE current_timestamp - created_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": SAMLRequestMaxAge},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up SAMLRequest: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -0,0 +1,137 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const customRoleOutputExpr = `
+	cr.id, cr.created_at, cr.updated_at, cr.organization_id, cr.name, cr.description, cr.permissions
+`
+
+func GetCustomRoles(ctx context.Context, organizationID uuid.UUID) ([]types.CustomRole, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customRoleOutputExpr+
+			`FROM CustomRole cr
+			WHERE cr.organization_id = @organizationId
+			ORDER BY cr.name`,
+		pgx.NamedArgs{"organizationId": organizationID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomRole: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.CustomRole])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect CustomRole: %w", err)
+	}
+	return result, nil
+}
+
+func GetCustomRole(ctx context.Context, id, organizationID uuid.UUID) (*types.CustomRole, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customRoleOutputExpr+
+			`FROM CustomRole cr
+			WHERE cr.id = @id AND cr.organization_id = @organizationId`,
+		pgx.NamedArgs{"id": id, "organizationId": organizationID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomRole: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomRole])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get CustomRole: %w", err)
+	}
+	return &result, nil
+}
+
+func CreateCustomRole(ctx context.Context, role *types.CustomRole) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO CustomRole AS cr (organization_id, name, description, permissions)
+		VALUES (@organizationId, @name, @description, @permissions)
+		RETURNING`+customRoleOutputExpr,
+		pgx.NamedArgs{
+			"organizationId": role.OrganizationID,
+			"name":           role.Name,
+			"description":    role.Description,
+			"permissions":    role.Permissions,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert CustomRole: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomRole])
+	if err != nil {
+		return mapCustomRoleError(err)
+	}
+	*role = created
+	return nil
+}
+
+func UpdateCustomRole(ctx context.Context, role *types.CustomRole) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`UPDATE CustomRole AS cr SET
+			updated_at = current_timestamp,
+			name = @name,
+			description = @description,
+			permissions = @permissions
+		WHERE cr.id = @id AND cr.organization_id = @organizationId
+		RETURNING`+customRoleOutputExpr,
+		pgx.NamedArgs{
+			"id":             role.ID,
+			"organizationId": role.OrganizationID,
+			"name":           role.Name,
+			"description":    role.Description,
+			"permissions":    role.Permissions,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update CustomRole: %w", err)
+	}
+	updated, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomRole])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierrors.ErrNotFound
+	} else if err != nil {
+		return mapCustomRoleError(err)
+	}
+	*role = updated
+	return nil
+}
+
+func mapCustomRoleError(err error) error {
+	if pgErr, ok := errors.AsType[*pgconn.PgError](err); ok && pgErr.Code == pgerrcode.UniqueViolation {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	}
+	return fmt.Errorf("could not save CustomRole: %w", err)
+}
+
+// DeleteCustomRole returns apierrors.ErrConflict if the role is still assigned to a user or an access token.
+func DeleteCustomRole(ctx context.Context, id, organizationID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM CustomRole WHERE id = @id AND organization_id = @organizationId`,
+		pgx.NamedArgs{"id": id, "organizationId": organizationID},
+	)
+	if isStillReferencedError(err) {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not delete CustomRole: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
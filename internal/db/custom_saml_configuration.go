@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const customSAMLConfigurationOutputExpr = `
+	c.id, c.created_at, c.updated_at, c.updated_by_user_account_id, c.organization_id, c.custom_domain_id,
+	c.name, c.slug, c.enabled, c.idp_metadata, c.idp_entity_id, c.sp_certificate, c.sp_private_key,
+	c.allow_idp_initiated, c.email_attribute, c.name_attribute, c.role_attribute, c.role_mapping,
+	c.create_unknown_users, c.default_user_role, c.allowed_email_domains
+`
+
+func customSAMLConfigurationArgs(c types.CustomSAMLConfiguration) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"organizationId":         c.OrganizationID,
+		"customDomainId":         c.CustomDomainID,
+		"updatedByUserAccountId": c.UpdatedByUserAccountID,
+		"name":                   c.Name,
+		"slug":                   c.Slug,
+		"enabled":                c.Enabled,
+		"idpMetadata":            c.IDPMetadata,
+		"idpEntityId":            c.IDPEntityID,
+		"spCertificate":          c.SPCertificate,
+		"spPrivateKey":           c.SPPrivateKey,
+		"allowIdpInitiated":      c.AllowIDPInitiated,
+		"emailAttribute":         c.EmailAttribute,
+		"nameAttribute":          c.NameAttribute,
+		"roleAttribute":          c.RoleAttribute,
+		"roleMapping":            c.RoleMapping,
+		"createUnknownUsers":     c.CreateUnknownUsers,
+		"defaultUserRole":        c.DefaultUserRole,
+		"allowedEmailDomains":    c.AllowedEmailDomains,
+	}
+}
+
+func CreateCustomSAMLConfiguration(ctx context.Context, c *types.CustomSAMLConfiguration) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO CustomSAMLConfiguration AS c (
+			organization_id, custom_domain_id, updated_by_user_account_id, name, slug, enabled, idp_metadata,
+			idp_entity_id, sp_certificate, sp_private_key, allow_idp_initiated, email_attribute, name_attribute,
+			role_attribute, role_mapping, create_unknown_users, default_user_role, allowed_email_domains
+		) VALUES (
+			@organizationId, @customDomainId, @updatedByUserAccountId, @name, @slug, @enabled, @idpMetadata,
+			@idpEntityId, @spCertificate, @spPrivateKey, @allowIdpInitiated, @emailAttribute, @nameAttribute,
+			@roleAttribute, @roleMapping, @createUnknownUsers, @defaultUserRole, @allowedEmailDomains
+		) RETURNING`+customSAMLConfigurationOutputExpr,
+		customSAMLConfigurationArgs(*c),
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert CustomSAMLConfiguration: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if err != nil {
+		return mapCustomSAMLConfigurationError(err)
+	}
+	*c = created
+	return nil
+}
+
+func UpdateCustomSAMLConfiguration(ctx context.Context, c *types.CustomSAMLConfiguration) error {
+	db := internalctx.GetDb(ctx)
+	args := customSAMLConfigurationArgs(*c)
+	args["id"] = c.ID
+	rows, err := db.Query(ctx,
+		`UPDATE CustomSAMLConfiguration AS c SET
+			updated_at = current_timestamp,
+			updated_by_user_account_id = @updatedByUserAccountId,
+			custom_domain_id = @customDomainId,
+			name = @name,
+			slug = @slug,
+			enabled = @enabled,
+			idp_metadata = @idpMetadata,
+			idp_entity_id = @idpEntityId,
+			allow_idp_initiated = @allowIdpInitiated,
+			email_attribute = @emailAttribute,
+			name_attribute = @nameAttribute,
+			role_attribute = @roleAttribute,
+			role_mapping = @roleMapping,
+			create_unknown_users = @createUnknownUsers,
+			default_user_role = @defaultUserRole,
+			allowed_email_domains = @allowedEmailDomains
+		WHERE c.id = @id AND c.organization_id = @organizationId
+		RETURNING`+customSAMLConfigurationOutputExpr,
+		args,
+	)
+	if err != nil {
+		return fmt.Errorf("could not update CustomSAMLConfiguration: %w", err)
+	}
+	updated, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierrors.ErrNotFound
+	} else if err != nil {
+		return mapCustomSAMLConfigurationError(err)
+	}
+	*c = updated
+	return nil
+}
+
+func mapCustomSAMLConfigurationError(err error) error {
+	if pgErr, ok := errors.AsType[*pgconn.PgError](err); ok {
+		switch pgErr.Code {
+		case pgerrcode.UniqueViolation:
+			return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+		case pgerrcode.ForeignKeyViolation:
+			return fmt.Errorf("%w: %w", apierrors.ErrBadRequest, err)
+		}
+	}
+	return fmt.Errorf("could not save CustomSAMLConfiguration: %w", err)
+}
+
+func GetCustomSAMLConfigurations(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	customerOrgID, partnerOrgID *uuid.UUID,
+) ([]types.CustomSAMLConfiguration, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customSAMLConfigurationOutputExpr+
+			`FROM CustomSAMLConfiguration c`+customOIDCConfigurationScopeExpr+
+			`WHERE c.organization_id = @organizationId
+			ORDER BY c.name`,
+		customOIDCConfigurationScopeArgs(pgx.NamedArgs{"organizationId": organizationID}, customerOrgID, partnerOrgID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect CustomSAMLConfiguration: %w", err)
+	}
+	return result, nil
+}
+
+func GetCustomSAMLConfigurationsForDomain(
+	ctx context.Context,
+	customDomainID uuid.UUID,
+) ([]types.CustomSAMLConfiguration, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customSAMLConfigurationOutputExpr+
+			`FROM CustomSAMLConfiguration c
+			WHERE c.custom_domain_id = @customDomainId AND c.enabled
+			ORDER BY c.name`,
+		pgx.NamedArgs{"customDomainId": customDomainID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect CustomSAMLConfiguration: %w", err)
+	}
+	return result, nil
+}
+
+func GetCustomSAMLConfigurationOfOrganization(
+	ctx context.Context,
+	id, organizationID uuid.UUID,
+	customerOrgID, partnerOrgID *uuid.UUID,
+) (*types.CustomSAMLConfiguration, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customSAMLConfigurationOutputExpr+
+			`FROM CustomSAMLConfiguration c`+customOIDCConfigurationScopeExpr+
+			`WHERE c.id = @id AND c.organization_id = @organizationId`,
+		customOIDCConfigurationScopeArgs(
+			pgx.NamedArgs{"id": id, "organizationId": organizationID}, customerOrgID, partnerOrgID),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get CustomSAMLConfiguration: %w", err)
+	}
+	return &result, nil
+}
+
+func GetCustomSAMLConfigurationBySlug(
+	ctx context.Context,
+	organizationSlug, slug string,
+) (*types.CustomSAMLConfiguration, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+customSAMLConfigurationOutputExpr+
+			`FROM CustomSAMLConfiguration c
+			JOIN Organization o ON o.id = c.organization_id
+			WHERE o.slug = @organizationSlug AND c.slug = @slug`,
+		pgx.NamedArgs{"organizationSlug": organizationSlug, "slug": slug},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.CustomSAMLConfiguration])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get CustomSAMLConfiguration: %w", err)
+	}
+	return &result, nil
+}
+
+func DeleteCustomSAMLConfiguration(
+	ctx context.Context,
+	id, organizationID uuid.UUID,
+	customerOrgID, partnerOrgID *uuid.UUID,
+) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM CustomSAMLConfiguration c
+		USING CustomDomain d
+		WHERE c.id = @id AND c.organization_id = @organizationId AND d.id = c.custom_domain_id
+			AND (@isVendor
+				OR d.customer_organization_id = @customerOrganizationId
+				OR EXISTS (
+					SELECT 1 FROM CustomerOrganization co
+					WHERE co.id = d.customer_organization_id AND co.partner_organization_id = @partnerOrganizationId
+				))`,
+		customOIDCConfigurationScopeArgs(
+			pgx.NamedArgs{"id": id, "organizationId": organizationID}, customerOrgID, partnerOrgID),
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete CustomSAMLConfiguration: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func ExistsCustomSAMLConfigurationForOrganization(ctx context.Context, organizationID uuid.UUID) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (SELECT 1 FROM CustomSAMLConfiguration WHERE organization_id = @organizationId)`,
+		pgx.NamedArgs{"organizationId": organizationID},
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	exists, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+	if err != nil {
+		return false, fmt.Errorf("could not query CustomSAMLConfiguration: %w", err)
+	}
+	return exists, nil
+}
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// SaveDeploymentMetadata replaces the metadata of the deployment.
+func SaveDeploymentMetadata(ctx context.Context, metadata *types.DeploymentMetadata) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO DeploymentMetadata AS m (deployment_id, deployment_revision_id, outputs)
+		VALUES (@deploymentId, @deploymentRevisionId, @outputs)
+		ON CONFLICT (deployment_id) DO UPDATE SET
+			deployment_revision_id = EXCLUDED.deployment_revision_id,
+			updated_at = current_timestamp,
+			outputs = EXCLUDED.outputs
+		RETURNING m.deployment_id, m.deployment_revision_id, m.updated_at, m.outputs`,
+		pgx.NamedArgs{
+			"deploymentId":         metadata.DeploymentID,
+			"deploymentRevisionId": metadata.DeploymentRevisionID,
+			"outputs":              metadata.Outputs,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not save DeploymentMetadata: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentMetadata])
+	if pgErr := new(pgconn.PgError); errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not save DeploymentMetadata: %w", err)
+	}
+	*metadata = result
+	return nil
+}
+
+func GetDeploymentMetadata(ctx context.Context, deploymentID uuid.UUID) (*types.DeploymentMetadata, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT m.deployment_id, m.deployment_revision_id, m.updated_at, m.outputs
+		FROM DeploymentMetadata m
+		WHERE m.deployment_id = @deploymentId`,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentMetadata: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentMetadata])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentMetadata: %w", err)
+	}
+	return &result, nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	deploymentRevisionPlanOutputExpr = `
+		p.deployment_revision_id, p.created_at, p.digest, p.has_changes, p.output, p.approved_at,
+		p.approved_by_user_account_id
+	`
+	latestDeploymentRevisionIDExpr = `
+		(SELECT id FROM DeploymentRevision WHERE deployment_id = @deploymentId ORDER BY created_at DESC LIMIT 1)
+	`
+)
+
+// SaveDeploymentRevisionPlan stores the plan of a revision. If the revision already has a plan with the same digest,
+// nothing changes. Otherwise, the previous plan is replaced and with it its approval.
+func SaveDeploymentRevisionPlan(ctx context.Context, plan *types.DeploymentRevisionPlan) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(
+		ctx,
+		`INSERT INTO DeploymentRevisionPlan AS p (deployment_revision_id, digest, has_changes, output)
+		VALUES (@deploymentRevisionId, @digest, @hasChanges, @output)
+		ON CONFLICT (deployment_revision_id) DO UPDATE SET
+			created_at = current_timestamp,
+			digest = EXCLUDED.digest,
+			has_changes = EXCLUDED.has_changes,
+			output = EXCLUDED.output,
+			approved_at = NULL,
+			approved_by_user_account_id = NULL
+		WHERE p.digest != EXCLUDED.digest`,
+		pgx.NamedArgs{
+			"deploymentRevisionId": plan.DeploymentRevisionID,
+			"digest":               plan.Digest,
+			"hasChanges":           plan.HasChanges,
+			"output":               plan.Output,
+		},
+	)
+	if pgErr := new(pgconn.PgError); errors.As(err, &pgErr) && pgErr.Code == pgerrcode.ForeignKeyViolation {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not save DeploymentRevisionPlan: %w", err)
+	}
+	return nil
+}
+
+func GetDeploymentRevisionPlan(ctx context.Context, revisionID uuid.UUID) (*types.DeploymentRevisionPlan, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentRevisionPlanOutputExpr+`
+		FROM DeploymentRevisionPlan p
+		WHERE p.deployment_revision_id = @deploymentRevisionId`,
+		pgx.NamedArgs{"deploymentRevisionId": revisionID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentRevisionPlan: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentRevisionPlan])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentRevisionPlan: %w", err)
+	}
+	return &result, nil
+}
+
+// GetLatestDeploymentRevisionPlan returns the plan of the current revision of the deployment.
+func GetLatestDeploymentRevisionPlan(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+) (*types.DeploymentRevisionPlan, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentRevisionPlanOutputExpr+`
+		FROM DeploymentRevisionPlan p
+		WHERE p.deployment_revision_id = `+latestDeploymentRevisionIDExpr,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentRevisionPlan: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentRevisionPlan])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentRevisionPlan: %w", err)
+	}
+	return &result, nil
+}
+
+// ApproveLatestDeploymentRevisionPlan approves the plan of the current revision of the deployment, but only if it
+// still has the given digest. Otherwise, [apierrors.ErrConflict] is returned.
+func ApproveLatestDeploymentRevisionPlan(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	digest string,
+	userID uuid.UUID,
+) (*types.DeploymentRevisionPlan, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`UPDATE DeploymentRevisionPlan AS p SET
+			approved_at = coalesce(p.approved_at, current_timestamp),
+			approved_by_user_account_id = coalesce(p.approved_by_user_account_id, @userId)
+		WHERE p.deployment_revision_id = `+latestDeploymentRevisionIDExpr+`
+			AND p.digest = @digest
+		RETURNING`+deploymentRevisionPlanOutputExpr,
+		pgx.NamedArgs{"deploymentId": deploymentID, "digest": digest, "userId": userID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not update DeploymentRevisionPlan: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentRevisionPlan])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: the plan does not exist anymore or has changed", apierrors.ErrConflict)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentRevisionPlan: %w", err)
+	}
+	return &result, nil
+}
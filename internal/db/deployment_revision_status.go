@@ -17,13 +17,22 @@ import (
 	"go.uber.org/zap"
 )
 
+// CreateDeploymentRevisionStatus creates the status with the current time, unless CreatedAt is set to an earlier time
+// because the agent queued the status while the hub could not be reached.
 func CreateDeploymentRevisionStatus(ctx context.Context, status *types.DeploymentRevisionStatus) error {
 	db := internalctx.GetDb(ctx)
+	var createdAt *time.Time
+	if !status.CreatedAt.IsZero() {
+		createdAt = &status.CreatedAt
+	}
 	rows, err := db.Query(
 		ctx,
 		`WITH inserted AS (
-			INSERT INTO DeploymentRevisionStatus (deployment_revision_id, message, type)
-			VALUES (@deploymentRevisionId, @message, @type)
+			INSERT INTO DeploymentRevisionStatus (deployment_revision_id, message, type, created_at)
+			VALUES (
+				@deploymentRevisionId, @message, @type,
+				LEAST(COALESCE(@createdAt, current_timestamp), current_timestamp)
+			)
 			RETURNING *
 		)
 		SELECT id, created_at, deployment_revision_id, type, message FROM inserted
@@ -32,6 +41,7 @@ func CreateDeploymentRevisionStatus(ctx context.Context, status *types.Deploymen
 			"deploymentRevisionId": status.DeploymentRevisionID,
 			"message":              status.Message,
 			"type":                 status.Type,
+			"createdAt":            createdAt,
 		},
 	)
 	if err != nil {
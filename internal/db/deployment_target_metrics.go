@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/env"
@@ -22,7 +23,24 @@ const (
 		dtm.memory_usage,
 		array_agg(row(dtdm.device, dtdm.path, dtdm.fs_type, dtdm.bytes_total, dtdm.bytes_used) ORDER BY dtdm.device)
 			FILTER (WHERE dtdm.id IS NOT NULL)
-			AS disk_metrics
+			AS disk_metrics,
+		(` + deploymentContainerMetricsExpr + `) AS container_metrics
+	`
+	deploymentContainerMetricsExpr = `
+		SELECT array_agg(row(
+			dcm.deployment_id,
+			dcm.service,
+			dcm.name,
+			dcm.cpu_used_millis,
+			dcm.cpu_usage,
+			dcm.memory_used_bytes,
+			dcm.memory_usage,
+			dcm.restarts,
+			dcm.network_rx_bytes,
+			dcm.network_tx_bytes
+		) ORDER BY dcm.deployment_id, dcm.service, dcm.name)
+		FROM DeploymentContainerMetrics dcm
+		WHERE dcm.deployment_target_metrics_id = dtm.id
 	`
 )
 
@@ -110,16 +128,20 @@ func GetLatestDeploymentTargetMetricsForID(ctx context.Context, id uuid.UUID) (*
 	return &result, nil
 }
 
+// CreateDeploymentTargetMetrics creates the metrics with the current time, unless createdAt is an earlier time
+// because the agent queued the metrics while the hub could not be reached.
 func CreateDeploymentTargetMetrics(
 	ctx context.Context,
 	metrics *types.DeploymentTargetMetrics,
+	createdAt *time.Time,
 ) error {
 	db := internalctx.GetDb(ctx)
 
 	err := db.QueryRow(ctx,
 		"INSERT INTO DeploymentTargetMetrics "+
-			"(deployment_target_id, cpu_cores_millis, cpu_usage, memory_bytes, memory_usage) "+
-			"VALUES (@deploymentTargetId, @cpuCoresMillis, @cpuUsage, @memoryBytes, @memoryUsage) "+
+			"(deployment_target_id, cpu_cores_millis, cpu_usage, memory_bytes, memory_usage, created_at) "+
+			"VALUES (@deploymentTargetId, @cpuCoresMillis, @cpuUsage, @memoryBytes, @memoryUsage, "+
+			"LEAST(COALESCE(@createdAt, current_timestamp), current_timestamp)) "+
 			"RETURNING id",
 		pgx.NamedArgs{
 			"deploymentTargetId": metrics.DeploymentTargetID,
@@ -127,24 +149,44 @@ func CreateDeploymentTargetMetrics(
 			"cpuUsage":           metrics.CPUUsage,
 			"memoryBytes":        metrics.MemoryBytes,
 			"memoryUsage":        metrics.MemoryUsage,
+			"createdAt":          createdAt,
 		}).Scan(&metrics.ID)
 	if err != nil {
 		return err
 	}
 
-	if len(metrics.DiskMetrics) == 0 {
-		return nil
+	if len(metrics.DiskMetrics) > 0 {
+		_, err = db.CopyFrom(
+			ctx,
+			pgx.Identifier{"deploymenttargetdiskmetrics"},
+			[]string{"deployment_target_metrics_id", "device", "path", "fs_type", "bytes_total", "bytes_used"},
+			pgx.CopyFromSlice(len(metrics.DiskMetrics), func(i int) ([]any, error) {
+				d := metrics.DiskMetrics[i]
+				return []any{metrics.ID, d.Device, d.Path, d.FsType, d.BytesTotal, d.BytesUsed}, nil
+			}),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
-	_, err = db.CopyFrom(
-		ctx,
-		pgx.Identifier{"deploymenttargetdiskmetrics"},
-		[]string{"deployment_target_metrics_id", "device", "path", "fs_type", "bytes_total", "bytes_used"},
-		pgx.CopyFromSlice(len(metrics.DiskMetrics), func(i int) ([]any, error) {
-			d := metrics.DiskMetrics[i]
-			return []any{metrics.ID, d.Device, d.Path, d.FsType, d.BytesTotal, d.BytesUsed}, nil
-		}),
-	)
+	if len(metrics.ContainerMetrics) > 0 {
+		_, err = db.CopyFrom(
+			ctx,
+			pgx.Identifier{"deploymentcontainermetrics"},
+			[]string{
+				"deployment_target_metrics_id", "deployment_id", "service", "name", "cpu_used_millis", "cpu_usage",
+				"memory_used_bytes", "memory_usage", "restarts", "network_rx_bytes", "network_tx_bytes",
+			},
+			pgx.CopyFromSlice(len(metrics.ContainerMetrics), func(i int) ([]any, error) {
+				c := metrics.ContainerMetrics[i]
+				return []any{
+					metrics.ID, c.DeploymentID, c.Service, c.Name, c.CPUUsedMillis, c.CPUUsage,
+					c.MemoryUsedBytes, c.MemoryUsage, c.Restarts, c.NetworkRxBytes, c.NetworkTxBytes,
+				}, nil
+			}),
+		)
+	}
 	return err
 }
 
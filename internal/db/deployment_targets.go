@@ -38,7 +38,8 @@ const (
 			dt.resources_cpu_limit,
 			dt.resources_memory_limit
 		) END,
-		dt.docker_endpoint
+		dt.docker_endpoint,
+		dt.container_runtime
 	`
 	deploymentTargetOutputExpr = deploymentTargetOutputExprBase +
 		", CASE WHEN co.id IS NOT NULL THEN (" + customerOrganizationOutputExpr + ") END AS customer_organization"
@@ -227,6 +228,7 @@ func CreateDeploymentTarget(
 		"automaticUpdates":      dt.AutomaticUpdatesEnabled,
 		"customerOrgId":         customerOrgID,
 		"dockerEndpoint":        dt.DockerEndpoint,
+		"containerRuntime":      dt.ContainerRuntime,
 	}
 
 	if dt.Resources != nil {
@@ -243,11 +245,11 @@ func CreateDeploymentTarget(
 			(name, type, organization_id, namespace, scope, agent_version_id, metrics_enabled, image_cleanup_enabled,
 				deployment_logs_enabled, deployment_logs_after, autoheal_enabled, automatic_updates_enabled,
 				customer_organization_id, resources_cpu_request, resources_memory_request, resources_cpu_limit,
-				resources_memory_limit, docker_endpoint)
+				resources_memory_limit, docker_endpoint, container_runtime)
 			VALUES (@name, @type, @orgId, @namespace, @scope, @agentVersionId, @metricsEnabled, @imageCleanupEnabled,
 				@deploymentLogsEnabled, @deploymentLogsAfter, @autohealEnabled, @automaticUpdates,
 				@customerOrgId, @resourcesCpuRequest, @resourcesMemoryRequest, @resourcesCpuLimit,
-				@resourcesMemoryLimit, @dockerEndpoint)
+				@resourcesMemoryLimit, @dockerEndpoint, @containerRuntime)
 			RETURNING *
 		)
 		SELECT `+deploymentTargetFullOutputExpr+` FROM inserted dt`+deploymentTargetJoinExpr,
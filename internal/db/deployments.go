@@ -118,6 +118,8 @@ func GetDeploymentsForDeploymentTarget(
 				dr.created_at AS deployment_revision_created_at,
 				dr.force_restart AS force_restart,
 				dr.ignore_revision_skew AS ignore_revision_skew,
+				dr.volume_backup_policy AS volume_backup_policy,
+				dr.restore_volume_snapshot_id AS restore_volume_snapshot_id,
 				CASE WHEN dr.helm_options_timeout IS NOT NULL THEN (
 					dr.helm_options_timeout,
 					dr.helm_options_wait_strategy,
@@ -317,14 +319,20 @@ func DeleteDeploymentWithID(ctx context.Context, id uuid.UUID) error {
 func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentRequest) (*types.DeploymentRevision, error) {
 	db := internalctx.GetDb(ctx)
 	args := pgx.NamedArgs{
-		"deploymentId":           request.DeploymentID,
-		"applicationVersionId":   request.ApplicationVersionID,
-		"valuesYaml":             request.ValuesYaml,
-		"envFileData":            request.EnvFileData,
-		"valuesHash":             request.ValuesHash,
-		"forceRestart":           request.ForceRestart,
-		"ignoreRevisionSkew":     request.IgnoreRevisionSkew,
-		"createdByUserAccountId": request.CreatedByUserAccountID,
+		"deploymentId":            request.DeploymentID,
+		"applicationVersionId":    request.ApplicationVersionID,
+		"valuesYaml":              request.ValuesYaml,
+		"envFileData":             request.EnvFileData,
+		"valuesHash":              request.ValuesHash,
+		"forceRestart":            request.ForceRestart,
+		"ignoreRevisionSkew":      request.IgnoreRevisionSkew,
+		"volumeBackupPolicy":      types.VolumeBackupPolicyNone,
+		"restoreVolumeSnapshotId": request.RestoreVolumeSnapshotID,
+		"createdByUserAccountId":  request.CreatedByUserAccountID,
+	}
+
+	if request.VolumeBackupPolicy != nil {
+		args["volumeBackupPolicy"] = *request.VolumeBackupPolicy
 	}
 
 	if request.HelmOptions != nil {
@@ -352,6 +360,8 @@ func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentReques
 			helm_options_rollback_on_failure,
 			helm_options_cleanup_on_failure,
 			helm_options_force_conflicts,
+			volume_backup_policy,
+			restore_volume_snapshot_id,
 			created_by_user_account_id
 		) VALUES (
 		 	@deploymentId,
@@ -366,6 +376,8 @@ func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentReques
 			@helmOptionsRollbackOnFailure,
 			@helmOptionsCleanupOnFailure,
 			@helmOptionsForceConflicts,
+			@volumeBackupPolicy,
+			@restoreVolumeSnapshotId,
 			@createdByUserAccountId
 		) RETURNING
 		 	dr.id,
@@ -375,6 +387,8 @@ func CreateDeploymentRevision(ctx context.Context, request *api.DeploymentReques
 			dr.values_hash,
 			dr.force_restart,
 			dr.ignore_revision_skew,
+			dr.volume_backup_policy,
+			dr.restore_volume_snapshot_id,
 			dr.created_by_user_account_id,
 			CASE WHEN dr.helm_options_timeout IS NOT NULL THEN (
 				dr.helm_options_timeout,
@@ -419,6 +433,47 @@ func GetLatestDeploymentRevisionIDs(ctx context.Context, deploymentID uuid.UUID,
 	return result, nil
 }
 
+// GetLatestDeploymentRevision returns the current revision of the deployment, without its values and env file.
+func GetLatestDeploymentRevision(ctx context.Context, deploymentID uuid.UUID) (*types.DeploymentRevision, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT
+				dr.id,
+				dr.created_at,
+				dr.deployment_id,
+				dr.application_version_id,
+				dr.values_hash,
+				dr.force_restart,
+				dr.ignore_revision_skew,
+				dr.volume_backup_policy,
+				dr.restore_volume_snapshot_id,
+				CASE WHEN dr.helm_options_timeout IS NOT NULL THEN (
+					dr.helm_options_timeout,
+					dr.helm_options_wait_strategy,
+					dr.helm_options_rollback_on_failure,
+					dr.helm_options_cleanup_on_failure,
+					dr.helm_options_force_conflicts
+				) END AS helm_options,
+				dr.created_by_user_account_id
+			FROM DeploymentRevision dr
+			WHERE dr.deployment_id = @deploymentId
+			ORDER BY dr.created_at DESC
+			LIMIT 1`,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentRevision: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentRevision])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentRevision: %w", err)
+	}
+	return &result, nil
+}
+
 func GetDeploymentRevisions(
 	ctx context.Context,
 	deploymentID uuid.UUID,
@@ -437,6 +492,8 @@ func GetDeploymentRevisions(
 				dr.env_file_data AS env_file_data,
 				dr.force_restart AS force_restart,
 				dr.ignore_revision_skew AS ignore_revision_skew,
+				dr.volume_backup_policy AS volume_backup_policy,
+				dr.restore_volume_snapshot_id AS restore_volume_snapshot_id,
 				CASE WHEN dr.helm_options_timeout IS NOT NULL THEN (
 					dr.helm_options_timeout,
 					dr.helm_options_wait_strategy,
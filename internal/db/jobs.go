@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const jobRunOutputExpr = `r.id, r.job_name, r.holder, r.started_at, r.finished_at, r.status, r.error`
+
+// TryAcquireJobLease reports whether holder got the lease of the job, which is the case unless another holder has
+// a lease that did not expire yet.
+func TryAcquireJobLease(ctx context.Context, jobName, holder string, duration time.Duration) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`INSERT INTO JobLease AS l (job_name, holder, expires_at)
+		VALUES (@jobName, @holder, current_timestamp + @duration)
+		ON CONFLICT (job_name) DO UPDATE
+			SET holder = excluded.holder, acquired_at = current_timestamp, expires_at = excluded.expires_at
+			WHERE l.expires_at <= current_timestamp`,
+		pgx.NamedArgs{"jobName": jobName, "holder": holder, "duration": duration},
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not acquire JobLease: %w", err)
+	}
+	return cmd.RowsAffected() > 0, nil
+}
+
+func GetJobLeases(ctx context.Context) ([]types.JobLease, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT job_name, holder, acquired_at, expires_at FROM JobLease ORDER BY job_name`)
+	if err != nil {
+		return nil, fmt.Errorf("could not query JobLease: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.JobLease])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect JobLease: %w", err)
+	}
+	return result, nil
+}
+
+// AbandonJobRuns marks the runs of a job that are still running as failed. It must only be called by the holder
+// of the lease of the job, because any other run can then only be left over from a replica that stopped.
+func AbandonJobRuns(ctx context.Context, jobName string) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`UPDATE JobRun
+		SET status = 'failed', finished_at = current_timestamp, error = 'the replica stopped before the job finished'
+		WHERE job_name = @jobName AND status = 'running'`,
+		pgx.NamedArgs{"jobName": jobName},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update JobRun: %w", err)
+	}
+	return nil
+}
+
+func CreateJobRun(ctx context.Context, run *types.JobRun) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO JobRun AS r (job_name, holder) VALUES (@jobName, @holder) RETURNING `+jobRunOutputExpr,
+		pgx.NamedArgs{"jobName": run.JobName, "holder": run.Holder},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert JobRun: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.JobRun])
+	if err != nil {
+		return fmt.Errorf("could not insert JobRun: %w", err)
+	}
+	*run = created
+	return nil
+}
+
+func FinishJobRun(ctx context.Context, id uuid.UUID, runErr error) error {
+	db := internalctx.GetDb(ctx)
+	status := types.JobRunStatusSucceeded
+	var errorMessage *string
+	if runErr != nil {
+		status = types.JobRunStatusFailed
+		errorMessage = new(runErr.Error())
+	}
+	_, err := db.Exec(ctx,
+		`UPDATE JobRun SET finished_at = current_timestamp, status = @status, error = @error WHERE id = @id`,
+		pgx.NamedArgs{"id": id, "status": status, "error": errorMessage},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update JobRun: %w", err)
+	}
+	return nil
+}
+
+// GetJobRuns returns the newest runs first. If jobName is nil, the runs of all jobs are returned.
+func GetJobRuns(ctx context.Context, jobName *string, before time.Time, count int) ([]types.JobRun, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT `+jobRunOutputExpr+`
+		FROM JobRun r
+		WHERE (@jobName::TEXT IS NULL OR r.job_name = @jobName) AND r.started_at < @before
+		ORDER BY r.started_at DESC
+		LIMIT @count`,
+		pgx.NamedArgs{"jobName": jobName, "before": before, "count": count},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query JobRun: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.JobRun])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect JobRun: %w", err)
+	}
+	return result, nil
+}
+
+func CleanupJobRuns(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM JobRun WHERE current_timestamp - started_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": env.JobRunsMaxAge()},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up JobRun: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
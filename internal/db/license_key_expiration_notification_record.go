@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// TryClaimLicenseKeyExpirationNotification inserts a LicenseKeyExpirationNotificationRecord for the given
+// license key, expiry and reminder offset. It returns the ID of the inserted record, or nil if a reminder for
+// this combination has already been claimed.
+func TryClaimLicenseKeyExpirationNotification(
+	ctx context.Context,
+	licenseKeyID uuid.UUID,
+	expiresAt time.Time,
+	daysBefore int,
+) (*uuid.UUID, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx, `
+		INSERT INTO LicenseKeyExpirationNotificationRecord (license_key_id, expires_at, days_before)
+		VALUES (@licenseKeyId, @expiresAt, @daysBefore)
+		ON CONFLICT (license_key_id, expires_at, days_before) DO NOTHING
+		RETURNING id`,
+		pgx.NamedArgs{
+			"licenseKeyId": licenseKeyID,
+			"expiresAt":    expiresAt.UTC(),
+			"daysBefore":   daysBefore,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim license key expiration notification: %w", err)
+	}
+
+	if id, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[uuid.UUID]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to collect LicenseKeyExpirationNotificationRecord ID: %w", err)
+	} else {
+		return &id, nil
+	}
+}
+
+// DeleteLicenseKeyExpirationNotificationRecord releases a previously claimed reminder,
+// e.g. when the emails could not be sent, so that the next run tries again.
+func DeleteLicenseKeyExpirationNotificationRecord(ctx context.Context, id uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM LicenseKeyExpirationNotificationRecord WHERE id = @id`,
+		pgx.NamedArgs{"id": id},
+	); err != nil {
+		return fmt.Errorf("failed to delete LicenseKeyExpirationNotificationRecord: %w", err)
+	}
+	return nil
+}
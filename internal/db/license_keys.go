@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/distr-sh/distr/internal/apierrors"
 	internalctx "github.com/distr-sh/distr/internal/context"
@@ -115,6 +117,73 @@ func GetLicenseKeysByPartnerOrgID(ctx context.Context, partnerOrgID, orgID uuid.
 	return result, nil
 }
 
+// GetLicenseKeysExpiringBefore returns all license keys of the organization whose latest revision has not
+// expired yet but expires before the given time, ordered by their expiry.
+func GetLicenseKeysExpiringBefore(ctx context.Context, orgID uuid.UUID, before time.Time) ([]types.LicenseKey, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx, `
+		SELECT `+licenseKeyOutExpr+`
+		FROM LicenseKey lk`+licenseKeyLatestRevisionJoin+`
+		WHERE lk.organization_id = @orgId
+			AND lr.expires_at > now()
+			AND lr.expires_at <= @before
+		ORDER BY lr.expires_at, lk.name`,
+		pgx.NamedArgs{"orgId": orgID, "before": before.UTC()},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query LicenseKey: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.LicenseKey])
+	if err != nil {
+		return nil, fmt.Errorf("could not query LicenseKey: %w", err)
+	}
+	return result, nil
+}
+
+// GetLicenseKeysMatching returns all license keys with at least one revision that match the given filter.
+func GetLicenseKeysMatching(ctx context.Context, filter types.LicenseKeyFilter) ([]types.LicenseKey, error) {
+	db := internalctx.GetDb(ctx)
+
+	conditions := []string{
+		"lk.organization_id = @orgId",
+		"lr.payload IS NOT NULL",
+	}
+	args := pgx.NamedArgs{"orgId": filter.OrgID}
+
+	if filter.LicenseTemplateID != nil {
+		conditions = append(conditions, "lk.license_template_id = @licenseTemplateId")
+		args["licenseTemplateId"] = *filter.LicenseTemplateID
+	}
+	if filter.CustomerOrganizationID != nil {
+		conditions = append(conditions, "lk.customer_organization_id = @customerOrgId")
+		args["customerOrgId"] = *filter.CustomerOrganizationID
+	}
+	if filter.ExpiresAfter != nil {
+		conditions = append(conditions, "lr.expires_at >= @expiresAfter")
+		args["expiresAfter"] = filter.ExpiresAfter.UTC()
+	}
+	if filter.ExpiresBefore != nil {
+		conditions = append(conditions, "lr.expires_at < @expiresBefore")
+		args["expiresBefore"] = filter.ExpiresBefore.UTC()
+	}
+
+	rows, err := db.Query(ctx, `
+		SELECT `+licenseKeyOutExpr+`
+		FROM LicenseKey lk`+licenseKeyLatestRevisionJoin+`
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY lk.name`,
+		args,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query LicenseKey: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.LicenseKey])
+	if err != nil {
+		return nil, fmt.Errorf("could not query LicenseKey: %w", err)
+	}
+	return result, nil
+}
+
 func GetLicenseKeyByID(ctx context.Context, id uuid.UUID) (*types.LicenseKey, error) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx, `
@@ -25,6 +25,8 @@ const notificationRecordOutputExpr = `
 	r.metric_type,
 	r.disk_device,
 	r.disk_path,
+	r.deployment_id,
+	r.container_name,
 	r.previous_deployment_target_metrics_id,
 	r.current_deployment_target_metrics_id,
 	r.message `
@@ -45,6 +47,8 @@ func SaveNotificationRecord(ctx context.Context, record *types.NotificationRecor
 				metric_type,
 				disk_device,
 				disk_path,
+				deployment_id,
+				container_name,
 				previous_deployment_target_metrics_id,
 				current_deployment_target_metrics_id,
 				message
@@ -60,6 +64,8 @@ func SaveNotificationRecord(ctx context.Context, record *types.NotificationRecor
 				@metricType,
 				@diskDevice,
 				@diskPath,
+				@deploymentID,
+				@containerName,
 				@previousMetricsID,
 				@currentMetricsID,
 				@message
@@ -78,6 +84,8 @@ func SaveNotificationRecord(ctx context.Context, record *types.NotificationRecor
 			"metricType":                 record.MetricType,
 			"diskDevice":                 record.DiskDevice,
 			"diskPath":                   record.DiskPath,
+			"deploymentID":               record.DeploymentID,
+			"containerName":              record.ContainerName,
 			"previousMetricsID":          record.PreviousDeploymentTargetMetricsID,
 			"currentMetricsID":           record.CurrentDeploymentTargetMetricsID,
 			"message":                    record.Message,
@@ -151,7 +159,8 @@ func GetNotificationRecords(
 				dtm.memory_bytes,
 				dtm.memory_usage,
 				array_agg(row(dtdm.device, dtdm.path, dtdm.fs_type, dtdm.bytes_total, dtdm.bytes_used) ORDER BY dtdm.device)
-					FILTER (WHERE dtdm.id IS NOT NULL)
+					FILTER (WHERE dtdm.id IS NOT NULL),
+				(`+deploymentContainerMetricsExpr+`)
 			) END AS current_deployment_target_metrics
 		FROM NotificationRecord r
 		LEFT JOIN DeploymentTarget dt
@@ -38,7 +38,9 @@ const (
 		o.post_connect_script,
 		o.connect_script_is_sudo,
 		o.stripe_webhook_secret,
-		(o.stripe_webhook_secret IS NOT NULL)
+		(o.stripe_webhook_secret IS NOT NULL),
+		o.license_key_expiration_reminder_days,
+		o.security_policy
 	`
 	organizationWithUserRoleOutputExpr = organizationOutputExpr + `,
 		j.user_role,
@@ -46,7 +48,8 @@ const (
 		cu.name AS customer_organization_name,
 		po.id AS partner_organization_id,
 		po.name AS partner_organization_name,
-		j.created_at as joined_org_at `
+		j.created_at as joined_org_at,
+		j.custom_role_id `
 )
 
 func CreateOrganization(ctx context.Context, org *types.Organization) error {
@@ -285,7 +288,8 @@ func GetAllOrganizationsForSuperAdmin(ctx context.Context) ([]types.Organization
 			NULL::TEXT as customer_organization_name,
 			NULL::UUID as partner_organization_id,
 			NULL::TEXT as partner_organization_name,
-			o.created_at as joined_org_at
+			o.created_at as joined_org_at,
+			NULL::UUID as custom_role_id
 			FROM Organization o
 			WHERE o.deleted_at IS NULL
 			ORDER BY o.subscription_type::text, o.name
@@ -429,6 +433,87 @@ func GetOrganizationByID(ctx context.Context, orgID uuid.UUID) (*types.Organizat
 	}
 }
 
+const organizationOverviewOutputExpr = organizationOutputExpr + `,
+	o.deleted_at,
+	(SELECT count(*) FROM Organization_UserAccount j WHERE j.organization_id = o.id) AS user_account_count
+`
+
+// GetOrganizationOverviews lists the organizations of the instance for super admins, newest first.
+func GetOrganizationOverviews(
+	ctx context.Context,
+	filter types.OrganizationOverviewFilter,
+) ([]types.OrganizationOverview, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+organizationOverviewOutputExpr+`
+		FROM Organization o
+		WHERE (o.deleted_at IS NOT NULL) = @deleted
+			AND o.created_at < @before
+			AND (@search = ''
+				OR o.id::text = @search
+				OR o.name ILIKE '%' || @search || '%'
+				OR o.slug ILIKE '%' || @search || '%')
+		ORDER BY o.created_at DESC
+		LIMIT @count`,
+		pgx.NamedArgs{
+			"deleted": filter.Deleted,
+			"before":  filter.Before,
+			"search":  filter.Search,
+			"count":   filter.Count,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Organization: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByPos[types.OrganizationOverview])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect Organization: %w", err)
+	}
+	return result, nil
+}
+
+// GetOrganizationOverview returns an organization for super admins, even if it was soft-deleted.
+func GetOrganizationOverview(ctx context.Context, orgID uuid.UUID) (*types.OrganizationOverview, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+organizationOverviewOutputExpr+" FROM Organization o WHERE o.id = @id",
+		pgx.NamedArgs{"id": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Organization: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[types.OrganizationOverview])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: organization %v", apierrors.ErrNotFound, orgID)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect Organization: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+// GetOrganizationsWithLicenseKeyExpirationReminders returns all organizations that have licensing enabled
+// and at least one license key expiration reminder configured.
+func GetOrganizationsWithLicenseKeyExpirationReminders(ctx context.Context) ([]types.Organization, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+organizationOutputExpr+`
+		FROM Organization o
+		WHERE o.deleted_at IS NULL
+			AND @feature = ANY(o.features)
+			AND cardinality(o.license_key_expiration_reminder_days) > 0`,
+		pgx.NamedArgs{"feature": types.FeatureLicensing},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query organizations: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByPos[types.Organization])
+	if err != nil {
+		return nil, fmt.Errorf("could not map organizations: %w", err)
+	}
+	return result, nil
+}
+
 func GetOrganizationWithBranding(ctx context.Context, orgID uuid.UUID) (*types.OrganizationWithBranding, error) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
@@ -469,6 +554,52 @@ func SetOrganizationStripeWebhookSecret(ctx context.Context, orgID uuid.UUID, se
 	return nil
 }
 
+func SetOrganizationLicenseKeyExpirationReminderDays(ctx context.Context, orgID uuid.UUID, days []int) error {
+	if days == nil {
+		days = []int{}
+	}
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`UPDATE Organization SET license_key_expiration_reminder_days = @days WHERE id = @id`,
+		pgx.NamedArgs{"id": orgID, "days": days},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Organization license key expiration reminder days: %w", err)
+	}
+	return nil
+}
+
+func SetOrganizationSecurityPolicy(ctx context.Context, orgID uuid.UUID, policy types.SecurityPolicy) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`UPDATE Organization SET security_policy = @policy WHERE id = @id`,
+		pgx.NamedArgs{"id": orgID, "policy": policy},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Organization security_policy: %w", err)
+	}
+	return nil
+}
+
+// ExistsEnabledCustomSSOConfigurationForOrganization reports whether members of the organization can sign in with
+// one of its own OIDC or SAML identity providers.
+func ExistsEnabledCustomSSOConfigurationForOrganization(ctx context.Context, orgID uuid.UUID) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (SELECT 1 FROM CustomOIDCConfiguration WHERE organization_id = @id AND enabled)
+			OR EXISTS (SELECT 1 FROM CustomSAMLConfiguration WHERE organization_id = @id AND enabled)`,
+		pgx.NamedArgs{"id": orgID},
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not query identity provider configurations: %w", err)
+	}
+	exists, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+	if err != nil {
+		return false, fmt.Errorf("could not query identity provider configurations: %w", err)
+	}
+	return exists, nil
+}
+
 func DeleteOrganizationsOlderThan(ctx context.Context, minAge time.Duration) (int64, error) {
 	var rowsAffected int64
 	err := RunTx(ctx, func(ctx context.Context) error {
@@ -517,3 +648,30 @@ func SetOrganizationDeletedAtNow(ctx context.Context, orgID uuid.UUID) error {
 
 	return nil
 }
+
+// RestoreOrganization undoes SetOrganizationDeletedAtNow, as long as the organization was not purged yet. It returns
+// apierrors.ErrNotFound if the organization does not exist or is not deleted.
+func RestoreOrganization(ctx context.Context, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		"UPDATE Organization SET deleted_at = NULL WHERE id = @id AND deleted_at IS NOT NULL",
+		pgx.NamedArgs{"id": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Organization: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+
+	RunAfterTx(ctx, func(ctx context.Context) {
+		log := internalctx.GetLogger(ctx)
+		if c := internalctx.GetPrometheusCollector(ctx); c != nil {
+			c.IncOrganizationsTotal()
+		} else {
+			log.Warn("could not update organizations total metric because collector is nil")
+		}
+	})
+
+	return nil
+}
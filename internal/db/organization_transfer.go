@@ -0,0 +1,292 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// organizationTransferTables are the tables exported with an organization, in an order in which their rows can be
+// inserted. Each condition selects the rows of the organization @organizationId.
+var organizationTransferTables = []struct{ name, where string }{
+	{"File", `organization_id = @organizationId`},
+	{"OrganizationBranding", `organization_id = @organizationId`},
+	{"PartnerOrganization", `organization_id = @organizationId`},
+	{"CustomerOrganization", `organization_id = @organizationId`},
+	{"Application", `organization_id = @organizationId`},
+	{"ApplicationVersion", `application_id IN (SELECT id FROM Application WHERE organization_id = @organizationId)`},
+	{"ApplicationVersionResource", `application_version_id IN (
+		SELECT av.id FROM ApplicationVersion av
+		JOIN Application a ON a.id = av.application_id
+		WHERE a.organization_id = @organizationId)`},
+	{"ApplicationVersionHealthRule", `application_version_id IN (
+		SELECT av.id FROM ApplicationVersion av
+		JOIN Application a ON a.id = av.application_id
+		WHERE a.organization_id = @organizationId)`},
+	{"Artifact", `organization_id = @organizationId`},
+	{"ArtifactVersion", `artifact_id IN (SELECT id FROM Artifact WHERE organization_id = @organizationId)`},
+	{"ArtifactVersionPart", `artifact_version_id IN (
+		SELECT av.id FROM ArtifactVersion av
+		JOIN Artifact a ON a.id = av.artifact_id
+		WHERE a.organization_id = @organizationId)`},
+	{"ApplicationEntitlement", `organization_id = @organizationId`},
+	{"ApplicationEntitlement_ApplicationVersion", `application_entitlement_id IN (
+		SELECT id FROM ApplicationEntitlement WHERE organization_id = @organizationId)`},
+	{"ArtifactEntitlement", `organization_id = @organizationId`},
+	{"ArtifactEntitlement_Artifact", `artifact_entitlement_id IN (
+		SELECT id FROM ArtifactEntitlement WHERE organization_id = @organizationId)`},
+	{"LicenseTemplate", `organization_id = @organizationId`},
+	{"LicenseKey", `organization_id = @organizationId`},
+	{"LicenseKeyRevision", `license_key_id IN (SELECT id FROM LicenseKey WHERE organization_id = @organizationId)`},
+	{"Secret", `organization_id = @organizationId`},
+	{"DeploymentTarget", `organization_id = @organizationId`},
+	{"Deployment", `deployment_target_id IN (SELECT id FROM DeploymentTarget WHERE organization_id = @organizationId)`},
+	{"DeploymentRevision", `deployment_id IN (
+		SELECT d.id FROM Deployment d
+		JOIN DeploymentTarget dt ON dt.id = d.deployment_target_id
+		WHERE dt.organization_id = @organizationId)`},
+	{"DeploymentTargetNotes", `deployment_target_id IN (
+		SELECT id FROM DeploymentTarget WHERE organization_id = @organizationId)`},
+	{"DeploymentTargetAgentNetwork", `deployment_target_id IN (
+		SELECT id FROM DeploymentTarget WHERE organization_id = @organizationId)`},
+	{"DeploymentTargetVolumeBackupStorage", `deployment_target_id IN (
+		SELECT id FROM DeploymentTarget WHERE organization_id = @organizationId)`},
+}
+
+// insertOrganizationTransferRowsBatchSize limits the number of rows inserted by a single statement, as rows of files
+// and application versions can be large.
+const insertOrganizationTransferRowsBatchSize = 100
+
+// OrganizationTransferTables returns the names of the tables exported with an organization, in the order in which
+// they must be imported.
+func OrganizationTransferTables() []string {
+	names := make([]string, len(organizationTransferTables))
+	for i, table := range organizationTransferTables {
+		names[i] = table.name
+	}
+	return names
+}
+
+// GetOrganizationTransferRow returns the row of the organization as a JSON object with a field per column.
+func GetOrganizationTransferRow(ctx context.Context, organizationID uuid.UUID) (json.RawMessage, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT row_to_json(o) FROM Organization o WHERE o.id = @organizationId`,
+		pgx.NamedArgs{"organizationId": organizationID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query Organization: %w", err)
+	}
+	if row, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[json.RawMessage]); err != nil {
+		return nil, fmt.Errorf("failed to collect Organization: %w", err)
+	} else {
+		return row, nil
+	}
+}
+
+// GetOrganizationTransferRows returns the rows of one of OrganizationTransferTables that belong to the
+// organization, each as a JSON object with a field per column.
+func GetOrganizationTransferRows(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	table string,
+) ([]json.RawMessage, error) {
+	where, err := organizationTransferTableCondition(table)
+	if err != nil {
+		return nil, err
+	}
+
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT row_to_json(t) FROM `+table+` t WHERE `+where,
+		pgx.NamedArgs{"organizationId": organizationID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %v: %w", table, err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[json.RawMessage]); err != nil {
+		return nil, fmt.Errorf("failed to collect %v: %w", table, err)
+	} else {
+		return result, nil
+	}
+}
+
+// GetTableColumns describes the columns of a table of the current schema, so that rows exported by another version
+// of Distr can be inserted.
+func GetTableColumns(ctx context.Context, table string) ([]types.TableColumn, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT
+			a.attname AS name,
+			NOT a.attnotnull AS nullable,
+			a.attgenerated <> '' AS generated,
+			coalesce((
+				SELECT ft.relname::text
+				FROM pg_constraint fk
+				JOIN pg_class ft ON ft.oid = fk.confrelid
+				WHERE fk.conrelid = a.attrelid AND fk.contype = 'f' AND fk.conkey = ARRAY[a.attnum]
+				LIMIT 1
+			), '') AS referenced_table
+		FROM pg_attribute a
+		WHERE a.attrelid = to_regclass(@table) AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum`,
+		pgx.NamedArgs{"table": table},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query columns of %v: %w", table, err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.TableColumn]); err != nil {
+		return nil, fmt.Errorf("failed to collect columns of %v: %w", table, err)
+	} else if len(result) == 0 {
+		return nil, fmt.Errorf("table %v does not exist", table)
+	} else {
+		return result, nil
+	}
+}
+
+// InsertOrganizationTransferRows inserts rows given as JSON objects into one of OrganizationTransferTables. Only
+// the given columns are inserted, the others get their default value.
+func InsertOrganizationTransferRows(
+	ctx context.Context,
+	table string,
+	columns []string,
+	rows []json.RawMessage,
+) error {
+	if _, err := organizationTransferTableCondition(table); err != nil {
+		return err
+	}
+	columnList := sanitizedColumnList(columns)
+	db := internalctx.GetDb(ctx)
+	for batch := range slices.Chunk(rows, insertOrganizationTransferRowsBatchSize) {
+		data, err := json.Marshal(batch)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec(ctx,
+			`INSERT INTO `+table+` (`+columnList+`)
+			SELECT `+columnList+` FROM json_populate_recordset(NULL::`+table+`, @rows::json)`,
+			pgx.NamedArgs{"rows": string(data)},
+		); err != nil {
+			return fmt.Errorf("failed to insert into %v: %w", table, err)
+		}
+	}
+	return nil
+}
+
+// UpdateOrganizationFromTransferRow sets the given columns of an organization to the values of a row exported with
+// GetOrganizationTransferRow.
+func UpdateOrganizationFromTransferRow(
+	ctx context.Context,
+	organizationID uuid.UUID,
+	columns []string,
+	row json.RawMessage,
+) error {
+	if len(columns) == 0 {
+		return nil
+	}
+	columnList := sanitizedColumnList(columns)
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(ctx,
+		`UPDATE Organization SET (`+columnList+`) = (
+			SELECT `+columnList+` FROM json_populate_record(NULL::Organization, @row::json)
+		)
+		WHERE id = @organizationId`,
+		pgx.NamedArgs{"organizationId": organizationID, "row": string(row)},
+	); err != nil {
+		return fmt.Errorf("failed to update Organization: %w", err)
+	}
+	return nil
+}
+
+// IsOrganizationSlugTaken returns whether another organization, including soft-deleted ones, has the slug.
+func IsOrganizationSlugTaken(ctx context.Context, slug string) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (SELECT 1 FROM Organization WHERE slug = @slug)`,
+		pgx.NamedArgs{"slug": slug},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to query Organization: %w", err)
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+}
+
+// IsOrganizationBrandingDomainTaken returns whether the branding of an organization uses the domain for its app or
+// registry.
+func IsOrganizationBrandingDomainTaken(ctx context.Context, domain string) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (SELECT 1 FROM OrganizationBranding WHERE app_domain = @domain OR registry_domain = @domain)`,
+		pgx.NamedArgs{"domain": domain},
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to query OrganizationBranding: %w", err)
+	}
+	return pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool])
+}
+
+// GetOrganizationBlobDigests returns the digests of all registry blobs referenced by the artifacts of the
+// organization.
+func GetOrganizationBlobDigests(ctx context.Context, organizationID uuid.UUID) ([]string, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx, `
+		SELECT av.manifest_blob_digest
+		FROM ArtifactVersion av
+		JOIN Artifact a ON a.id = av.artifact_id
+		WHERE a.organization_id = @organizationId
+		UNION
+		SELECT avp.artifact_blob_digest
+		FROM ArtifactVersionPart avp
+		JOIN ArtifactVersion av ON av.id = avp.artifact_version_id
+		JOIN Artifact a ON a.id = av.artifact_id
+		WHERE a.organization_id = @organizationId
+	`, pgx.NamedArgs{"organizationId": organizationID})
+	if err != nil {
+		return nil, fmt.Errorf("could not query blob digests: %w", err)
+	}
+	digests, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect blob digests: %w", err)
+	}
+	return digests, nil
+}
+
+// GetSchemaVersion returns the version of the last database migration that was applied.
+func GetSchemaVersion(ctx context.Context) (uint, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query schema_migrations: %w", err)
+	}
+	if version, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64]); err != nil {
+		return 0, fmt.Errorf("failed to collect schema_migrations: %w", err)
+	} else {
+		return uint(version), nil
+	}
+}
+
+// organizationTransferTableCondition returns the condition for the rows of one of OrganizationTransferTables. As
+// table names cannot be query parameters, it also guards against names from archives that are no such table.
+func organizationTransferTableCondition(table string) (string, error) {
+	for _, t := range organizationTransferTables {
+		if t.name == table {
+			return t.where, nil
+		}
+	}
+	return "", fmt.Errorf("table %v is not exported with an organization", table)
+}
+
+func sanitizedColumnList(columns []string) string {
+	sanitized := make([]string, len(columns))
+	for i, column := range columns {
+		sanitized[i] = pgx.Identifier{column}.Sanitize()
+	}
+	return strings.Join(sanitized, ", ")
+}
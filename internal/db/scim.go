@@ -0,0 +1,505 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authkey"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	scimTokenOutputExpr = `
+		t.id, t.created_at, t.last_used_at, t.label, t.key, t.organization_id, t.customer_organization_id
+	`
+	scimUserOutputExpr = `
+		su.user_account_id,
+		su.organization_id,
+		su.customer_organization_id,
+		su.created_at,
+		su.updated_at,
+		su.external_id,
+		su.active,
+		u.email,
+		u.name,
+		j.user_role,
+		coalesce((
+			SELECT array_agg(m.scim_group_id ORDER BY m.scim_group_id)
+			FROM ScimGroupMember m
+			WHERE m.organization_id = su.organization_id AND m.user_account_id = su.user_account_id
+		), '{}') AS group_ids
+	`
+	scimUserFromExpr = `
+		FROM ScimUser su
+		INNER JOIN UserAccount u ON u.id = su.user_account_id
+		LEFT JOIN Organization_UserAccount j
+			ON j.organization_id = su.organization_id AND j.user_account_id = su.user_account_id
+	`
+	scimGroupOutputExpr = `
+		g.id,
+		g.created_at,
+		g.updated_at,
+		g.organization_id,
+		g.customer_organization_id,
+		g.display_name,
+		g.external_id,
+		g.user_role,
+		coalesce((
+			SELECT array_agg(m.user_account_id ORDER BY m.user_account_id)
+			FROM ScimGroupMember m
+			WHERE m.scim_group_id = g.id
+		), '{}') AS member_ids
+	`
+)
+
+func CreateScimToken(ctx context.Context, token *types.ScimToken) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO ScimToken AS t (label, key, organization_id, customer_organization_id)
+		VALUES (@label, @key, @orgId, @customerOrgId)
+		RETURNING`+scimTokenOutputExpr,
+		pgx.NamedArgs{
+			"label":         token.Label,
+			"key":           token.Key[:],
+			"orgId":         token.OrganizationID,
+			"customerOrgId": token.CustomerOrganizationID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not create ScimToken: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ScimToken]); err != nil {
+		return fmt.Errorf("could not create ScimToken: %w", err)
+	} else {
+		*token = result
+		return nil
+	}
+}
+
+func GetScimTokens(ctx context.Context, orgID uuid.UUID, customerOrgID *uuid.UUID) ([]types.ScimToken, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+scimTokenOutputExpr+`
+		FROM ScimToken t
+		WHERE t.organization_id = @orgId AND t.customer_organization_id IS NOT DISTINCT FROM @customerOrgId
+		ORDER BY t.created_at`,
+		pgx.NamedArgs{"orgId": orgID, "customerOrgId": customerOrgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimToken: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ScimToken]); err != nil {
+		return nil, fmt.Errorf("could not collect ScimToken: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func DeleteScimToken(ctx context.Context, id, orgID uuid.UUID, customerOrgID *uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM ScimToken
+		WHERE id = @id
+			AND organization_id = @orgId
+			AND customer_organization_id IS NOT DISTINCT FROM @customerOrgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID, "customerOrgId": customerOrgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ScimToken: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func GetScimTokenByKeyUpdatingLastUsed(ctx context.Context, key authkey.Key) (*types.ScimToken, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`UPDATE ScimToken AS t
+		SET last_used_at = now()
+		WHERE key = @key
+		RETURNING`+scimTokenOutputExpr,
+		pgx.NamedArgs{"key": key[:]},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimToken: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ScimToken]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not get ScimToken: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+// GetScimUsers returns the users provisioned in the given scope. If email or externalID are set, only users
+// with the given email (compared case-insensitively) or external ID are returned.
+func GetScimUsers(
+	ctx context.Context,
+	orgID uuid.UUID,
+	customerOrgID *uuid.UUID,
+	email *string,
+	externalID *string,
+) ([]types.ScimUser, error) {
+	db := internalctx.GetDb(ctx)
+
+	conditions := []string{
+		"su.organization_id = @orgId",
+		"su.customer_organization_id IS NOT DISTINCT FROM @customerOrgId",
+	}
+	args := pgx.NamedArgs{"orgId": orgID, "customerOrgId": customerOrgID}
+
+	if email != nil {
+		conditions = append(conditions, "lower(u.email) = lower(@email)")
+		args["email"] = *email
+	}
+	if externalID != nil {
+		conditions = append(conditions, "su.external_id = @externalId")
+		args["externalId"] = *externalID
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT "+scimUserOutputExpr+scimUserFromExpr+
+			"WHERE "+strings.Join(conditions, " AND ")+`
+		ORDER BY su.created_at, su.user_account_id`,
+		args,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimUser: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ScimUser]); err != nil {
+		return nil, fmt.Errorf("could not collect ScimUser: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetScimUser(
+	ctx context.Context,
+	orgID uuid.UUID,
+	customerOrgID *uuid.UUID,
+	userID uuid.UUID,
+) (*types.ScimUser, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+scimUserOutputExpr+scimUserFromExpr+`
+		WHERE su.organization_id = @orgId
+			AND su.customer_organization_id IS NOT DISTINCT FROM @customerOrgId
+			AND su.user_account_id = @userId`,
+		pgx.NamedArgs{"orgId": orgID, "customerOrgId": customerOrgID, "userId": userID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimUser: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ScimUser]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not get ScimUser: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+func CreateScimUser(
+	ctx context.Context,
+	orgID uuid.UUID,
+	customerOrgID *uuid.UUID,
+	userID uuid.UUID,
+	externalID *string,
+	active bool,
+) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`INSERT INTO ScimUser (organization_id, customer_organization_id, user_account_id, external_id, active)
+		VALUES (@orgId, @customerOrgId, @userId, @externalId, @active)`,
+		pgx.NamedArgs{
+			"orgId":         orgID,
+			"customerOrgId": customerOrgID,
+			"userId":        userID,
+			"externalId":    externalID,
+			"active":        active,
+		},
+	)
+	if pgerr := (*pgconn.PgError)(nil); errors.As(err, &pgerr) && pgerr.Code == pgerrcode.UniqueViolation {
+		return apierrors.ErrAlreadyExists
+	} else if err != nil {
+		return fmt.Errorf("could not create ScimUser: %w", err)
+	}
+	return nil
+}
+
+func UpdateScimUser(ctx context.Context, orgID, userID uuid.UUID, externalID *string, active bool) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE ScimUser
+		SET external_id = @externalId, active = @active, updated_at = current_timestamp
+		WHERE organization_id = @orgId AND user_account_id = @userId`,
+		pgx.NamedArgs{"orgId": orgID, "userId": userID, "externalId": externalID, "active": active},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update ScimUser: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func DeleteScimUser(ctx context.Context, orgID, userID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		"DELETE FROM ScimUser WHERE organization_id = @orgId AND user_account_id = @userId",
+		pgx.NamedArgs{"orgId": orgID, "userId": userID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ScimUser: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// GetScimGroups returns the groups provisioned in the given scope. If displayName or externalID are set, only
+// groups with the given display name or external ID are returned.
+func GetScimGroups(
+	ctx context.Context,
+	orgID uuid.UUID,
+	customerOrgID *uuid.UUID,
+	displayName *string,
+	externalID *string,
+) ([]types.ScimGroup, error) {
+	db := internalctx.GetDb(ctx)
+
+	conditions := []string{
+		"g.organization_id = @orgId",
+		"g.customer_organization_id IS NOT DISTINCT FROM @customerOrgId",
+	}
+	args := pgx.NamedArgs{"orgId": orgID, "customerOrgId": customerOrgID}
+
+	if displayName != nil {
+		conditions = append(conditions, "g.display_name = @displayName")
+		args["displayName"] = *displayName
+	}
+	if externalID != nil {
+		conditions = append(conditions, "g.external_id = @externalId")
+		args["externalId"] = *externalID
+	}
+
+	rows, err := db.Query(ctx,
+		"SELECT "+scimGroupOutputExpr+`
+		FROM ScimGroup g
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY g.display_name`,
+		args,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimGroup: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.ScimGroup]); err != nil {
+		return nil, fmt.Errorf("could not collect ScimGroup: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetScimGroup(ctx context.Context, orgID uuid.UUID, customerOrgID *uuid.UUID, id uuid.UUID) (
+	*types.ScimGroup,
+	error,
+) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+scimGroupOutputExpr+`
+		FROM ScimGroup g
+		WHERE g.id = @id
+			AND g.organization_id = @orgId
+			AND g.customer_organization_id IS NOT DISTINCT FROM @customerOrgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID, "customerOrgId": customerOrgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimGroup: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ScimGroup]); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, apierrors.ErrNotFound
+		}
+		return nil, fmt.Errorf("could not get ScimGroup: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+func CreateScimGroup(ctx context.Context, group *types.ScimGroup) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO ScimGroup AS g (organization_id, customer_organization_id, display_name, external_id)
+		VALUES (@orgId, @customerOrgId, @displayName, @externalId)
+		RETURNING`+scimGroupOutputExpr,
+		pgx.NamedArgs{
+			"orgId":         group.OrganizationID,
+			"customerOrgId": group.CustomerOrganizationID,
+			"displayName":   group.DisplayName,
+			"externalId":    group.ExternalID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not create ScimGroup: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.ScimGroup]); err != nil {
+		if pgerr := (*pgconn.PgError)(nil); errors.As(err, &pgerr) && pgerr.Code == pgerrcode.UniqueViolation {
+			return apierrors.ErrAlreadyExists
+		}
+		return fmt.Errorf("could not create ScimGroup: %w", err)
+	} else {
+		*group = result
+		return nil
+	}
+}
+
+func UpdateScimGroup(ctx context.Context, group *types.ScimGroup) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE ScimGroup
+		SET display_name = @displayName, external_id = @externalId, updated_at = current_timestamp
+		WHERE id = @id`,
+		pgx.NamedArgs{"id": group.ID, "displayName": group.DisplayName, "externalId": group.ExternalID},
+	)
+	if pgerr := (*pgconn.PgError)(nil); errors.As(err, &pgerr) && pgerr.Code == pgerrcode.UniqueViolation {
+		return apierrors.ErrAlreadyExists
+	} else if err != nil {
+		return fmt.Errorf("could not update ScimGroup: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func UpdateScimGroupUserRole(ctx context.Context, id uuid.UUID, role *types.UserRole) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		"UPDATE ScimGroup SET user_role = @role, updated_at = current_timestamp WHERE id = @id",
+		pgx.NamedArgs{"id": id, "role": role},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update ScimGroup: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func DeleteScimGroup(ctx context.Context, id uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx, "DELETE FROM ScimGroup WHERE id = @id", pgx.NamedArgs{"id": id})
+	if err != nil {
+		return fmt.Errorf("could not delete ScimGroup: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// AddScimGroupMembers adds the given users to the group. Every user must be provisioned in the same scope as
+// the group, otherwise an error wrapping apierrors.ErrBadRequest is returned.
+func AddScimGroupMembers(ctx context.Context, group types.ScimGroup, userIDs []uuid.UUID) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT count(*)
+		FROM ScimUser su
+		WHERE su.organization_id = @orgId
+			AND su.customer_organization_id IS NOT DISTINCT FROM @customerOrgId
+			AND su.user_account_id = ANY(@userIds)`,
+		pgx.NamedArgs{
+			"orgId":         group.OrganizationID,
+			"customerOrgId": group.CustomerOrganizationID,
+			"userIds":       userIDs,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not query ScimUser: %w", err)
+	}
+	count, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int])
+	if err != nil {
+		return fmt.Errorf("could not query ScimUser: %w", err)
+	} else if count != len(userIDs) {
+		return apierrors.NewBadRequest("group members must be users provisioned in the same scope")
+	}
+
+	_, err = db.Exec(ctx,
+		`INSERT INTO ScimGroupMember (scim_group_id, organization_id, user_account_id)
+		SELECT @groupId, @orgId, unnest(@userIds::UUID[])
+		ON CONFLICT DO NOTHING`,
+		pgx.NamedArgs{"groupId": group.ID, "orgId": group.OrganizationID, "userIds": userIDs},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert ScimGroupMember: %w", err)
+	}
+	return nil
+}
+
+func RemoveScimGroupMembers(ctx context.Context, groupID uuid.UUID, userIDs []uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		"DELETE FROM ScimGroupMember WHERE scim_group_id = @groupId AND user_account_id = ANY(@userIds)",
+		pgx.NamedArgs{"groupId": groupID, "userIds": userIDs},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete ScimGroupMember: %w", err)
+	}
+	return nil
+}
+
+// GetScimGroupUserRoles returns the roles of all groups the user is a member of that are mapped to a role.
+func GetScimGroupUserRoles(ctx context.Context, orgID, userID uuid.UUID) ([]types.UserRole, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT DISTINCT g.user_role
+		FROM ScimGroupMember m
+		INNER JOIN ScimGroup g ON g.id = m.scim_group_id
+		WHERE m.organization_id = @orgId AND m.user_account_id = @userId AND g.user_role IS NOT NULL`,
+		pgx.NamedArgs{"orgId": orgID, "userId": userID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query ScimGroup: %w", err)
+	}
+	if result, err := pgx.CollectRows(rows, pgx.RowTo[types.UserRole]); err != nil {
+		return nil, fmt.Errorf("could not collect ScimGroup: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+// ExistsScimGroupWithUserRole reports whether any group in the given scope is mapped to a role. Only then are
+// the roles of provisioned users managed through their group memberships.
+func ExistsScimGroupWithUserRole(ctx context.Context, orgID uuid.UUID, customerOrgID *uuid.UUID) (bool, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT EXISTS (
+			SELECT 1 FROM ScimGroup
+			WHERE organization_id = @orgId
+				AND customer_organization_id IS NOT DISTINCT FROM @customerOrgId
+				AND user_role IS NOT NULL
+		)`,
+		pgx.NamedArgs{"orgId": orgID, "customerOrgId": customerOrgID},
+	)
+	if err != nil {
+		return false, fmt.Errorf("could not query ScimGroup: %w", err)
+	}
+	if result, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[bool]); err != nil {
+		return false, fmt.Errorf("could not query ScimGroup: %w", err)
+	} else {
+		return result, nil
+	}
+}
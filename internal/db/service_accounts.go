@@ -0,0 +1,113 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreateServiceAccount creates a service account that belongs to the organization. Service accounts have no
+// password, and their email address is a placeholder that is never sent to and cannot be used to sign in.
+// The membership in the organization must be created separately.
+func CreateServiceAccount(ctx context.Context, userAccount *types.UserAccount, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	id := uuid.New()
+	rows, err := db.Query(ctx,
+		`INSERT INTO UserAccount AS u (id, email, name, email_verified_at, service_account_organization_id)
+		VALUES (@id, @email, @name, now(), @orgId)
+		RETURNING `+userAccountOutputExpr,
+		pgx.NamedArgs{
+			"id":    id,
+			"email": fmt.Sprintf("service-account-%v@service-accounts.invalid", id),
+			"name":  userAccount.Name,
+			"orgId": orgID,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not create service account: %w", err)
+	} else if created, err := pgx.CollectExactlyOneRow[types.UserAccount](rows, pgx.RowToStructByPos); err != nil {
+		return fmt.Errorf("could not create service account: %w", err)
+	} else {
+		*userAccount = created
+		return nil
+	}
+}
+
+func UpdateServiceAccountName(ctx context.Context, id, orgID uuid.UUID, name string) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE UserAccount SET name = @name WHERE id = @id AND service_account_organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID, "name": name},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update service account: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func GetServiceAccounts(ctx context.Context, orgID uuid.UUID) ([]types.UserAccountWithUserRole, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+userAccountWithRoleOutputExprWithAlias+`
+		FROM UserAccount u
+		INNER JOIN Organization_UserAccount j
+			ON u.id = j.user_account_id AND j.organization_id = u.service_account_organization_id
+		WHERE u.service_account_organization_id = @orgId
+		ORDER BY u.name`,
+		pgx.NamedArgs{"orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query service accounts: %w", err)
+	} else if result, err := pgx.CollectRows[types.UserAccountWithUserRole](rows, pgx.RowToStructByPos); err != nil {
+		return nil, fmt.Errorf("could not map service accounts: %w", err)
+	} else {
+		return result, nil
+	}
+}
+
+func GetServiceAccount(ctx context.Context, id, orgID uuid.UUID) (*types.UserAccountWithUserRole, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+userAccountWithRoleOutputExprWithAlias+`
+		FROM UserAccount u
+		INNER JOIN Organization_UserAccount j
+			ON u.id = j.user_account_id AND j.organization_id = u.service_account_organization_id
+		WHERE u.id = @id AND u.service_account_organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query service account: %w", err)
+	} else if result, err := pgx.CollectExactlyOneRow[types.UserAccountWithUserRole](
+		rows, pgx.RowToStructByPos,
+	); errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not map service account: %w", err)
+	} else {
+		return &result, nil
+	}
+}
+
+func DeleteServiceAccount(ctx context.Context, id, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM UserAccount WHERE id = @id AND service_account_organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if isStillReferencedError(err) {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not delete service account: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
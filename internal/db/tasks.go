@@ -0,0 +1,294 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const taskOutputExpr = `
+	t.id, t.created_at, t.organization_id, t.type, t.payload, t.dedup_key, t.status, t.attempts, t.max_attempts,
+	t.run_after, t.lease_holder, t.lease_expires_at, t.progress, t.progress_message, t.result, t.error, t.started_at,
+	t.finished_at
+`
+
+// CreateTask returns apierrors.ErrAlreadyExists if the task has a dedup key and another task with the same key is
+// pending or running.
+func CreateTask(ctx context.Context, task *types.Task) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO Task AS t (organization_id, type, payload, dedup_key, max_attempts)
+		VALUES (@organizationId, @type, @payload, @dedupKey, @maxAttempts)
+		ON CONFLICT (dedup_key) WHERE status IN ('pending', 'running') DO NOTHING
+		RETURNING`+taskOutputExpr,
+		pgx.NamedArgs{
+			"organizationId": task.OrganizationID,
+			"type":           task.Type,
+			"payload":        task.Payload,
+			"dedupKey":       task.DedupKey,
+			"maxAttempts":    task.MaxAttempts,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert Task: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Task])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierrors.ErrAlreadyExists
+	} else if err != nil {
+		return fmt.Errorf("could not insert Task: %w", err)
+	}
+	*task = created
+	return nil
+}
+
+// GetTask only returns tasks of the given organization, or any task if orgID is nil.
+func GetTask(ctx context.Context, id uuid.UUID, orgID *uuid.UUID) (*types.Task, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT`+taskOutputExpr+`FROM Task t WHERE t.id = @id AND (@orgId::UUID IS NULL OR t.organization_id = @orgId)`,
+		pgx.NamedArgs{"id": id, "orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Task: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Task])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not query Task: %w", err)
+	}
+	return &result, nil
+}
+
+func GetActiveTaskByDedupKey(ctx context.Context, dedupKey string) (*types.Task, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT`+taskOutputExpr+`FROM Task t WHERE t.dedup_key = @dedupKey AND t.status IN ('pending', 'running')`,
+		pgx.NamedArgs{"dedupKey": dedupKey},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Task: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Task])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not query Task: %w", err)
+	}
+	return &result, nil
+}
+
+// GetTasks returns the newest tasks first. If status is nil, tasks of all statuses are returned.
+func GetTasks(ctx context.Context, status *types.TaskStatus, before time.Time, count int) ([]types.Task, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT`+taskOutputExpr+`
+		FROM Task t
+		WHERE (@status::TASK_STATUS IS NULL OR t.status = @status) AND t.created_at < @before
+		ORDER BY t.created_at DESC
+		LIMIT @count`,
+		pgx.NamedArgs{"status": status, "before": before, "count": count},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query Task: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.Task])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect Task: %w", err)
+	}
+	return result, nil
+}
+
+// LeaseTask picks the next due task of one of the given types, including running tasks whose lease expired because
+// their worker stopped and that have attempts left, and leases it to holder. It returns apierrors.ErrNotFound if there
+// is no such task.
+func LeaseTask(
+	ctx context.Context,
+	taskTypes []string,
+	holder string,
+	leaseDuration time.Duration,
+) (*types.Task, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`UPDATE Task AS t
+		SET status = 'running',
+			attempts = t.attempts + 1,
+			lease_holder = @holder,
+			lease_expires_at = current_timestamp + @leaseDuration,
+			started_at = current_timestamp,
+			progress = NULL,
+			progress_message = NULL
+		WHERE t.id = (
+			SELECT id FROM Task
+			WHERE type = ANY(@types)
+				AND ((status = 'pending' AND run_after <= current_timestamp)
+					OR (status = 'running' AND lease_expires_at <= current_timestamp AND attempts < max_attempts))
+			ORDER BY run_after
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING`+taskOutputExpr,
+		pgx.NamedArgs{"types": taskTypes, "holder": holder, "leaseDuration": leaseDuration},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not lease Task: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Task])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not lease Task: %w", err)
+	}
+	return &result, nil
+}
+
+// FailAbandonedTasks moves running tasks of the given types to the dead letter state if their lease expired during
+// their last attempt. Their worker stopped without failing them, like when the task crashed or exhausted the memory
+// of its process, so LeaseTask would otherwise retry them forever.
+func FailAbandonedTasks(ctx context.Context, taskTypes []string) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE Task
+		SET status = 'dead', finished_at = current_timestamp, lease_holder = NULL, lease_expires_at = NULL,
+			error = 'the lease of the last attempt expired before the task finished'
+		WHERE type = ANY(@types) AND status = 'running' AND lease_expires_at <= current_timestamp
+			AND attempts >= max_attempts`,
+		pgx.NamedArgs{"types": taskTypes},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not update Task: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// ExtendTaskLease returns apierrors.ErrNotFound if holder lost the lease of the task.
+func ExtendTaskLease(ctx context.Context, id uuid.UUID, holder string, leaseDuration time.Duration) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE Task SET lease_expires_at = current_timestamp + @leaseDuration
+		WHERE id = @id AND lease_holder = @holder AND status = 'running'`,
+		pgx.NamedArgs{"id": id, "holder": holder, "leaseDuration": leaseDuration},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Task: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func UpdateTaskProgress(ctx context.Context, id uuid.UUID, progress int, message *string) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`UPDATE Task SET progress = @progress, progress_message = @message WHERE id = @id AND status = 'running'`,
+		pgx.NamedArgs{"id": id, "progress": progress, "message": message},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Task: %w", err)
+	}
+	return nil
+}
+
+func CompleteTask(ctx context.Context, id uuid.UUID, holder string, result json.RawMessage) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE Task
+		SET status = 'succeeded', result = @result, error = NULL, progress = 100, finished_at = current_timestamp,
+			lease_holder = NULL, lease_expires_at = NULL
+		WHERE id = @id AND lease_holder = @holder AND status = 'running'`,
+		pgx.NamedArgs{"id": id, "holder": holder, "result": result},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Task: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// ReleaseTask returns a task to the queue without counting the attempt, like when its worker shuts down.
+func ReleaseTask(ctx context.Context, id uuid.UUID, holder string) error {
+	db := internalctx.GetDb(ctx)
+	_, err := db.Exec(ctx,
+		`UPDATE Task
+		SET status = 'pending', attempts = attempts - 1, run_after = current_timestamp, lease_holder = NULL,
+			lease_expires_at = NULL
+		WHERE id = @id AND lease_holder = @holder AND status = 'running'`,
+		pgx.NamedArgs{"id": id, "holder": holder},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Task: %w", err)
+	}
+	return nil
+}
+
+// FailTask schedules the next attempt of the task after retryDelay, or moves it to the dead letter state if it has
+// no attempts left.
+func FailTask(ctx context.Context, id uuid.UUID, holder string, taskErr error, retryDelay time.Duration) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE Task
+		SET status = CASE WHEN attempts < max_attempts THEN 'pending' ELSE 'dead' END::TASK_STATUS,
+			run_after = current_timestamp + @retryDelay,
+			finished_at = CASE WHEN attempts < max_attempts THEN NULL ELSE current_timestamp END,
+			error = @error, lease_holder = NULL, lease_expires_at = NULL
+		WHERE id = @id AND lease_holder = @holder AND status = 'running'`,
+		pgx.NamedArgs{"id": id, "holder": holder, "error": taskErr.Error(), "retryDelay": retryDelay},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update Task: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// RetryTask gives a dead task another round of attempts. It returns apierrors.ErrNotFound unless the task is dead,
+// and apierrors.ErrConflict if another task with the same dedup key is pending or running.
+func RetryTask(ctx context.Context, id uuid.UUID) (*types.Task, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`UPDATE Task AS t
+		SET status = 'pending', attempts = 0, run_after = current_timestamp, finished_at = NULL
+		WHERE t.id = @id AND t.status = 'dead'
+		RETURNING`+taskOutputExpr,
+		pgx.NamedArgs{"id": id},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not update Task: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.Task])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if pgerr, ok := errors.AsType[*pgconn.PgError](err); ok && pgerr.Code == pgerrcode.UniqueViolation {
+		return nil, fmt.Errorf("%w: another task with the same dedup key is pending or running", apierrors.ErrConflict)
+	} else if err != nil {
+		return nil, fmt.Errorf("could not update Task: %w", err)
+	}
+	return &result, nil
+}
+
+func CleanupTasks(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM Task WHERE finished_at IS NOT NULL AND current_timestamp - finished_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": env.TasksMaxAge()},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up Task: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
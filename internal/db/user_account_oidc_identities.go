@@ -23,7 +23,8 @@ const userAccountOIDCIdentityOutputExpr = `
 	i.subject,
 	i.email,
 	i.last_login_at,
-	i.custom_oidc_configuration_id`
+	i.custom_oidc_configuration_id,
+	i.custom_saml_configuration_id`
 
 func GetUserAccountWithOIDCIdentity(
 	ctx context.Context,
@@ -41,7 +42,8 @@ func GetUserAccountWithOIDCIdentity(
 		FROM UserAccountOIDCIdentity i
 		INNER JOIN UserAccount u ON u.id = i.user_account_id
 		WHERE i.issuer = @issuer AND i.subject = @subject
-			AND i.custom_oidc_configuration_id IS NOT DISTINCT FROM @customOidcConfigurationId`,
+			AND i.custom_oidc_configuration_id IS NOT DISTINCT FROM @customOidcConfigurationId
+			AND i.custom_saml_configuration_id IS NULL`,
 		pgx.NamedArgs{
 			"issuer":                    issuer,
 			"subject":                   subject,
@@ -64,16 +66,56 @@ func GetUserAccountWithOIDCIdentity(
 	return &res.User, &res.Identity, nil
 }
 
+func GetUserAccountWithSAMLIdentity(
+	ctx context.Context,
+	customSAMLConfigurationID uuid.UUID,
+	issuer, subject string,
+) (
+	*types.UserAccount,
+	*types.UserAccountOIDCIdentity,
+	error,
+) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT ("+userAccountOutputExpr+`),
+			(`+userAccountOIDCIdentityOutputExpr+`)
+		FROM UserAccountOIDCIdentity i
+		INNER JOIN UserAccount u ON u.id = i.user_account_id
+		WHERE i.issuer = @issuer AND i.subject = @subject
+			AND i.custom_saml_configuration_id = @customSamlConfigurationId`,
+		pgx.NamedArgs{
+			"issuer":                    issuer,
+			"subject":                   subject,
+			"customSamlConfigurationId": customSAMLConfigurationID,
+		},
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not query UserAccountOIDCIdentity: %w", err)
+	}
+	res, err := pgx.CollectExactlyOneRow[struct {
+		User     types.UserAccount
+		Identity types.UserAccountOIDCIdentity
+	}](rows, pgx.RowToStructByPos)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, apierrors.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("could not map UserAccountOIDCIdentity: %w", err)
+	}
+	return &res.User, &res.Identity, nil
+}
+
 func GetUserAccountOIDCIdentities(ctx context.Context, userID uuid.UUID) (
 	[]types.UserAccountOIDCIdentityWithConfiguration,
 	error,
 ) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
-		"SELECT "+userAccountOIDCIdentityOutputExpr+`, c.name, o.name
+		"SELECT "+userAccountOIDCIdentityOutputExpr+`, coalesce(c.name, sc.name), o.name
 		FROM UserAccountOIDCIdentity i
 		LEFT JOIN CustomOIDCConfiguration c ON c.id = i.custom_oidc_configuration_id
-		LEFT JOIN Organization o ON o.id = c.organization_id
+		LEFT JOIN CustomSAMLConfiguration sc ON sc.id = i.custom_saml_configuration_id
+		LEFT JOIN Organization o ON o.id = coalesce(c.organization_id, sc.organization_id)
 		WHERE i.user_account_id = @userId
 		ORDER BY i.created_at`,
 		pgx.NamedArgs{"userId": userID},
@@ -93,7 +135,8 @@ func ExistsUserAccountCustomOIDCIdentity(ctx context.Context, userID uuid.UUID)
 	rows, err := db.Query(ctx,
 		`SELECT EXISTS (
 			SELECT 1 FROM UserAccountOIDCIdentity
-			WHERE user_account_id = @userId AND custom_oidc_configuration_id IS NOT NULL
+			WHERE user_account_id = @userId
+				AND (custom_oidc_configuration_id IS NOT NULL OR custom_saml_configuration_id IS NOT NULL)
 		)`,
 		pgx.NamedArgs{"userId": userID},
 	)
@@ -112,8 +155,9 @@ func ExistsUserAccountCustomOIDCIdentityExcept(ctx context.Context, userID, orga
 	rows, err := db.Query(ctx,
 		`SELECT EXISTS (
 			SELECT 1 FROM UserAccountOIDCIdentity i
-			INNER JOIN CustomOIDCConfiguration c ON c.id = i.custom_oidc_configuration_id
-			WHERE i.user_account_id = @userId AND c.organization_id != @organizationId
+			LEFT JOIN CustomOIDCConfiguration c ON c.id = i.custom_oidc_configuration_id
+			LEFT JOIN CustomSAMLConfiguration sc ON sc.id = i.custom_saml_configuration_id
+			WHERE i.user_account_id = @userId AND coalesce(c.organization_id, sc.organization_id) != @organizationId
 		)`,
 		pgx.NamedArgs{"userId": userID, "organizationId": organizationID},
 	)
@@ -147,8 +191,10 @@ func CreateUserAccountOIDCIdentity(ctx context.Context, identity *types.UserAcco
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
 		`INSERT INTO UserAccountOIDCIdentity AS i
-			(user_account_id, provider, issuer, subject, email, last_login_at, custom_oidc_configuration_id)
-		VALUES (@userId, @provider, @issuer, @subject, @email, current_timestamp, @customOidcConfigurationId)
+			(user_account_id, provider, issuer, subject, email, last_login_at, custom_oidc_configuration_id,
+				custom_saml_configuration_id)
+		VALUES (@userId, @provider, @issuer, @subject, @email, current_timestamp, @customOidcConfigurationId,
+			@customSamlConfigurationId)
 		RETURNING `+userAccountOIDCIdentityOutputExpr,
 		pgx.NamedArgs{
 			"userId":                    identity.UserAccountID,
@@ -157,6 +203,7 @@ func CreateUserAccountOIDCIdentity(ctx context.Context, identity *types.UserAcco
 			"subject":                   identity.Subject,
 			"email":                     identity.Email,
 			"customOidcConfigurationId": identity.CustomOIDCConfigurationID,
+			"customSamlConfigurationId": identity.CustomSAMLConfigurationID,
 		},
 	)
 	if err != nil {
@@ -197,8 +244,9 @@ func DeleteCustomOIDCIdentitiesOfUserInOrg(ctx context.Context, userID, orgID uu
 	db := internalctx.GetDb(ctx)
 	if _, err := db.Exec(ctx,
 		`DELETE FROM UserAccountOIDCIdentity
-		WHERE user_account_id = @userId AND custom_oidc_configuration_id IN (
-			SELECT id FROM CustomOIDCConfiguration WHERE organization_id = @orgId
+		WHERE user_account_id = @userId AND (
+			custom_oidc_configuration_id IN (SELECT id FROM CustomOIDCConfiguration WHERE organization_id = @orgId)
+			OR custom_saml_configuration_id IN (SELECT id FROM CustomSAMLConfiguration WHERE organization_id = @orgId)
 		)`,
 		pgx.NamedArgs{"userId": userID, "orgId": orgID},
 	); err != nil {
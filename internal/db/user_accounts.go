@@ -29,9 +29,10 @@ const (
 		u.mfa_secret,
 		u.mfa_enabled,
 		u.mfa_enabled_at,
-		u.is_super_admin`
+		u.is_super_admin,
+		u.service_account_organization_id`
 	userAccountWithRoleOutputExpr = userAccountOutputExpr +
-		", j.user_role, j.created_at, j.customer_organization_id, j.partner_organization_id "
+		", j.user_role, j.created_at, j.customer_organization_id, j.partner_organization_id, j.custom_role_id "
 	userAccountWithRoleOutputExprWithAlias = userAccountWithRoleOutputExpr + " as joined_org_at "
 )
 
@@ -257,6 +258,23 @@ func UpdateUserAccountOrganizationAssignment(
 	}
 }
 
+// UpdateUserAccountCustomRole assigns a custom role to the membership, or removes it if customRoleID is nil.
+func UpdateUserAccountCustomRole(ctx context.Context, userID, orgID uuid.UUID, customRoleID *uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE Organization_UserAccount SET custom_role_id = @customRoleId
+		WHERE organization_id = @orgId AND user_account_id = @userId`,
+		pgx.NamedArgs{"userId": userID, "orgId": orgID, "customRoleId": customRoleID},
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update Organization_UserAccount: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return fmt.Errorf("%w: user not found in org", apierrors.ErrNotFound)
+	} else {
+		return nil
+	}
+}
+
 func UpdateAllUserAccountOrganizationAssignmentsWithOrganizationID(
 	ctx context.Context,
 	orgID uuid.UUID,
@@ -313,6 +331,7 @@ func GetUserAccountsByOrgID(ctx context.Context, orgID uuid.UUID) (
 		FROM UserAccount u
 		INNER JOIN Organization_UserAccount j ON u.id = j.user_account_id
 		WHERE j.organization_id = @orgId
+			AND u.service_account_organization_id IS NULL
 		ORDER BY u.name, u.email`,
 		pgx.NamedArgs{"orgId": orgID},
 	)
@@ -329,9 +348,11 @@ func CountBillableUserAccountsByOrgID(ctx context.Context, orgID uuid.UUID) (int
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
 		`SELECT count(*)
-		FROM Organization_UserAccount
-		WHERE organization_id = @orgId
-		  	AND customer_organization_id IS NULL`,
+		FROM Organization_UserAccount j
+		INNER JOIN UserAccount u ON u.id = j.user_account_id
+		WHERE j.organization_id = @orgId
+		  	AND j.customer_organization_id IS NULL
+			AND u.service_account_organization_id IS NULL`,
 		pgx.NamedArgs{"orgId": orgID},
 	)
 	if err != nil {
@@ -413,7 +434,9 @@ func GetUserAccountByID(ctx context.Context, id uuid.UUID) (*types.UserAccount,
 func GetUserAccountByEmail(ctx context.Context, email string) (*types.UserAccount, error) {
 	db := internalctx.GetDb(ctx)
 	rows, err := db.Query(ctx,
-		"SELECT "+userAccountOutputExpr+" FROM UserAccount u WHERE u.email = @email",
+		"SELECT "+userAccountOutputExpr+`
+		FROM UserAccount u
+		WHERE u.email = @email AND u.service_account_organization_id IS NULL`,
 		pgx.NamedArgs{"email": email},
 	)
 	if err != nil {
@@ -636,3 +659,42 @@ func DisableUserAccountMFA(ctx context.Context, userID uuid.UUID) error {
 	}
 	return nil
 }
+
+// GetUserAccountOverviews lists the user accounts of the instance for super admins, newest first.
+func GetUserAccountOverviews(
+	ctx context.Context,
+	filter types.UserAccountOverviewFilter,
+) ([]types.UserAccountOverview, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT "+userAccountOutputExpr+`,
+			u.last_logged_in_at,
+			(SELECT count(*) FROM Organization_UserAccount j WHERE j.user_account_id = u.id) AS organization_count
+		FROM UserAccount u
+		WHERE u.created_at < @before
+			AND (@search = ''
+				OR u.id::text = @search
+				OR u.email ILIKE '%' || @search || '%'
+				OR u.name ILIKE '%' || @search || '%')
+			AND (@organizationId::UUID IS NULL OR EXISTS (
+				SELECT 1 FROM Organization_UserAccount j
+				WHERE j.user_account_id = u.id AND j.organization_id = @organizationId
+			))
+		ORDER BY u.created_at DESC
+		LIMIT @count`,
+		pgx.NamedArgs{
+			"before":         filter.Before,
+			"search":         filter.Search,
+			"organizationId": filter.OrganizationID,
+			"count":          filter.Count,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query users: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByPos[types.UserAccountOverview])
+	if err != nil {
+		return nil, fmt.Errorf("could not map users: %w", err)
+	}
+	return result, nil
+}
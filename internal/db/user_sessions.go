@@ -0,0 +1,233 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// UserSessionLastSeenInterval limits how often the last seen timestamp of a session is updated, so that not every
+// authenticated request results in a write.
+const UserSessionLastSeenInterval = time.Minute
+
+const userSessionOutputExpr = `
+	s.id, s.created_at, s.user_account_id, s.organization_id, s.expires_at, s.last_seen_at, s.user_agent,
+	s.remote_address, s.auth_method
+`
+
+func CreateUserSession(ctx context.Context, session *types.UserSession) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO UserSession AS s
+			(id, user_account_id, organization_id, expires_at, user_agent, remote_address, auth_method)
+		VALUES (@id, @userAccountId, @organizationId, @expiresAt, @userAgent, @remoteAddress, @authMethod)
+		RETURNING`+userSessionOutputExpr,
+		pgx.NamedArgs{
+			"id":             session.ID,
+			"userAccountId":  session.UserAccountID,
+			"organizationId": session.OrganizationID,
+			"expiresAt":      session.ExpiresAt,
+			"userAgent":      session.UserAgent,
+			"remoteAddress":  session.RemoteAddress,
+			"authMethod":     session.AuthMethod,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert UserSession: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.UserSession])
+	if err != nil {
+		return fmt.Errorf("could not insert UserSession: %w", err)
+	}
+	*session = created
+	return nil
+}
+
+// UpdateUserSessionToken records the organization and expiration of a new token that was issued for an existing
+// session, like when the user switches to another organization.
+func UpdateUserSessionToken(
+	ctx context.Context,
+	id, userAccountID, organizationID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE UserSession SET organization_id = @organizationId, expires_at = @expiresAt
+		WHERE id = @id AND user_account_id = @userAccountId AND expires_at > current_timestamp`,
+		pgx.NamedArgs{
+			"id":             id,
+			"userAccountId":  userAccountID,
+			"organizationId": organizationID,
+			"expiresAt":      expiresAt,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update UserSession: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// TouchUserSession returns apierrors.ErrNotFound unless the session exists and is not expired. The last seen
+// timestamp is updated at most once per UserSessionLastSeenInterval. The returned session has the last seen
+// timestamp from before the update, so that callers can check for how long it was idle.
+func TouchUserSession(ctx context.Context, id, userAccountID uuid.UUID) (*types.UserSession, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`WITH s AS (
+			SELECT * FROM UserSession
+			WHERE id = @id AND user_account_id = @userAccountId AND expires_at > current_timestamp
+		), touched AS (
+			UPDATE UserSession SET last_seen_at = current_timestamp
+			FROM s
+			WHERE UserSession.id = s.id AND current_timestamp - s.last_seen_at > @interval
+		)
+		SELECT`+userSessionOutputExpr+`FROM s`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID, "interval": UserSessionLastSeenInterval},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not touch UserSession: %w", err)
+	}
+	session, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.UserSession])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not touch UserSession: %w", err)
+	}
+	return &session, nil
+}
+
+func GetUserSession(ctx context.Context, id, userAccountID uuid.UUID) (*types.UserSession, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+userSessionOutputExpr+
+			`FROM UserSession s
+			WHERE s.id = @id AND s.user_account_id = @userAccountId AND s.expires_at > current_timestamp`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query UserSession: %w", err)
+	}
+	session, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.UserSession])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get UserSession: %w", err)
+	}
+	return &session, nil
+}
+
+func GetUserSessions(ctx context.Context, userAccountID uuid.UUID) ([]types.UserSession, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+userSessionOutputExpr+
+			`FROM UserSession s
+			WHERE s.user_account_id = @userAccountId AND s.expires_at > current_timestamp
+			ORDER BY s.last_seen_at DESC`,
+		pgx.NamedArgs{"userAccountId": userAccountID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query UserSession: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.UserSession])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect UserSession: %w", err)
+	}
+	return result, nil
+}
+
+// GetUserSessionsInOrg returns the active sessions of the user whose latest token was issued for the organization.
+func GetUserSessionsInOrg(ctx context.Context, userAccountID, orgID uuid.UUID) ([]types.UserSession, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+userSessionOutputExpr+
+			`FROM UserSession s
+			WHERE s.user_account_id = @userAccountId
+				AND s.organization_id = @orgId
+				AND s.expires_at > current_timestamp
+			ORDER BY s.last_seen_at DESC`,
+		pgx.NamedArgs{"userAccountId": userAccountID, "orgId": orgID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query UserSession: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.UserSession])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect UserSession: %w", err)
+	}
+	return result, nil
+}
+
+func DeleteUserSession(ctx context.Context, id, userAccountID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM UserSession WHERE id = @id AND user_account_id = @userAccountId`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete UserSession: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessionOfUserInOrg revokes a single session of the user, but only if it is in the given organization.
+func DeleteUserSessionOfUserInOrg(ctx context.Context, id, userAccountID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM UserSession
+		WHERE id = @id AND user_account_id = @userAccountId AND organization_id = @orgId`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID, "orgId": orgID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete UserSession: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteUserSessions revokes all sessions of the user except the one with the given ID, if it is not nil.
+func DeleteUserSessions(ctx context.Context, userAccountID uuid.UUID, except *uuid.UUID) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM UserSession
+		WHERE user_account_id = @userAccountId AND (@except::UUID IS NULL OR id <> @except)`,
+		pgx.NamedArgs{"userAccountId": userAccountID, "except": except},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not delete UserSession: %w", err)
+	}
+	return cmd.RowsAffected(), nil
+}
+
+// DeleteUserSessionsOfUserInOrg revokes the sessions of the user whose latest token was issued for the organization.
+func DeleteUserSessionsOfUserInOrg(ctx context.Context, userAccountID, orgID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(ctx,
+		`DELETE FROM UserSession WHERE user_account_id = @userAccountId AND organization_id = @orgId`,
+		pgx.NamedArgs{"userAccountId": userAccountID, "orgId": orgID},
+	); err != nil {
+		return fmt.Errorf("could not delete UserSession: %w", err)
+	}
+	return nil
+}
+
+func CleanupUserSessions(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx, `DELETE FROM UserSession WHERE expires_at <= current_timestamp`)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up UserSession: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -0,0 +1,229 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const volumeBackupStorageOutputExpr = `
+	s.deployment_target_id,
+	s.updated_at,
+	s.type,
+	s.retention,
+	s.s3_endpoint,
+	s.s3_region,
+	s.s3_bucket,
+	s.s3_prefix,
+	s.s3_use_path_style,
+	s.s3_access_key_id,
+	s.s3_secret_access_key
+`
+
+const deploymentVolumeSnapshotOutputExpr = `
+	vs.id,
+	vs.created_at,
+	vs.deployment_id,
+	vs.deployment_revision_id,
+	vs.size_bytes,
+	vs.volumes
+`
+
+func GetVolumeBackupStorage(ctx context.Context, deploymentTargetID uuid.UUID) (*types.VolumeBackupStorage, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+volumeBackupStorageOutputExpr+`
+		FROM DeploymentTargetVolumeBackupStorage s
+		WHERE s.deployment_target_id = @deploymentTargetId`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentTargetVolumeBackupStorage: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.VolumeBackupStorage])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentTargetVolumeBackupStorage: %w", err)
+	}
+	return &result, nil
+}
+
+// SaveVolumeBackupStorage creates or replaces the volume backup storage of a deployment target. The secret access
+// key of an existing storage is kept if storage.S3SecretAccessKey is nil, as long as the access key ID, endpoint,
+// region and bucket are unchanged, see [types.VolumeBackupStorage.KeepsSecretAccessKeyOf].
+func SaveVolumeBackupStorage(ctx context.Context, storage *types.VolumeBackupStorage) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`INSERT INTO DeploymentTargetVolumeBackupStorage AS s (
+			deployment_target_id, type, retention, s3_endpoint, s3_region, s3_bucket, s3_prefix, s3_use_path_style,
+			s3_access_key_id, s3_secret_access_key
+		) VALUES (
+			@deploymentTargetId, @type, @retention, @s3Endpoint, @s3Region, @s3Bucket, @s3Prefix, @s3UsePathStyle,
+			@s3AccessKeyId, @s3SecretAccessKey
+		)
+		ON CONFLICT (deployment_target_id) DO UPDATE SET
+			updated_at = current_timestamp,
+			type = EXCLUDED.type,
+			retention = EXCLUDED.retention,
+			s3_endpoint = EXCLUDED.s3_endpoint,
+			s3_region = EXCLUDED.s3_region,
+			s3_bucket = EXCLUDED.s3_bucket,
+			s3_prefix = EXCLUDED.s3_prefix,
+			s3_use_path_style = EXCLUDED.s3_use_path_style,
+			s3_access_key_id = EXCLUDED.s3_access_key_id,
+			s3_secret_access_key = CASE
+				WHEN EXCLUDED.s3_secret_access_key IS NULL AND EXCLUDED.s3_access_key_id = s.s3_access_key_id
+					AND EXCLUDED.s3_endpoint IS NOT DISTINCT FROM s.s3_endpoint
+					AND EXCLUDED.s3_region IS NOT DISTINCT FROM s.s3_region
+					AND EXCLUDED.s3_bucket IS NOT DISTINCT FROM s.s3_bucket
+					THEN s.s3_secret_access_key
+				ELSE EXCLUDED.s3_secret_access_key
+			END
+		RETURNING`+volumeBackupStorageOutputExpr,
+		pgx.NamedArgs{
+			"deploymentTargetId": storage.DeploymentTargetID,
+			"type":               storage.Type,
+			"retention":          storage.Retention,
+			"s3Endpoint":         storage.S3Endpoint,
+			"s3Region":           storage.S3Region,
+			"s3Bucket":           storage.S3Bucket,
+			"s3Prefix":           storage.S3Prefix,
+			"s3UsePathStyle":     storage.S3UsePathStyle,
+			"s3AccessKeyId":      storage.S3AccessKeyID,
+			"s3SecretAccessKey":  storage.S3SecretAccessKey,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not save DeploymentTargetVolumeBackupStorage: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.VolumeBackupStorage])
+	if err != nil {
+		return fmt.Errorf("could not save DeploymentTargetVolumeBackupStorage: %w", err)
+	}
+	*storage = result
+	return nil
+}
+
+func DeleteVolumeBackupStorage(ctx context.Context, deploymentTargetID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentTargetVolumeBackupStorage WHERE deployment_target_id = @deploymentTargetId`,
+		pgx.NamedArgs{"deploymentTargetId": deploymentTargetID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete DeploymentTargetVolumeBackupStorage: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+// GetDeploymentVolumeSnapshots returns the snapshots of the deployment, newest first.
+func GetDeploymentVolumeSnapshots(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+) ([]types.DeploymentVolumeSnapshot, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentVolumeSnapshotOutputExpr+`
+		FROM DeploymentVolumeSnapshot vs
+		WHERE vs.deployment_id = @deploymentId
+		ORDER BY vs.created_at DESC`,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentVolumeSnapshot: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.DeploymentVolumeSnapshot])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentVolumeSnapshot: %w", err)
+	}
+	return result, nil
+}
+
+func GetDeploymentVolumeSnapshot(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	id uuid.UUID,
+) (*types.DeploymentVolumeSnapshot, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(
+		ctx,
+		`SELECT`+deploymentVolumeSnapshotOutputExpr+`
+		FROM DeploymentVolumeSnapshot vs
+		WHERE vs.deployment_id = @deploymentId AND vs.id = @id`,
+		pgx.NamedArgs{"deploymentId": deploymentID, "id": id},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query DeploymentVolumeSnapshot: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.DeploymentVolumeSnapshot])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not collect DeploymentVolumeSnapshot: %w", err)
+	}
+	return &result, nil
+}
+
+// ReplaceDeploymentVolumeSnapshots replaces the snapshots of the deployment with the ones reported by the agent,
+// which is the only one that knows which snapshots still exist after retention was applied. It must be called in a
+// transaction.
+func ReplaceDeploymentVolumeSnapshots(
+	ctx context.Context,
+	deploymentID uuid.UUID,
+	snapshots []types.DeploymentVolumeSnapshot,
+) error {
+	db := internalctx.GetDb(ctx)
+	if _, err := db.Exec(
+		ctx,
+		`DELETE FROM DeploymentVolumeSnapshot WHERE deployment_id = @deploymentId`,
+		pgx.NamedArgs{"deploymentId": deploymentID},
+	); err != nil {
+		return fmt.Errorf("could not delete DeploymentVolumeSnapshot: %w", err)
+	}
+	for _, snapshot := range snapshots {
+		// the revision must belong to the deployment, which the foreign key alone does not ensure
+		cmd, err := db.Exec(
+			ctx,
+			`INSERT INTO DeploymentVolumeSnapshot (
+				id, created_at, deployment_id, deployment_revision_id, size_bytes, volumes
+			)
+			SELECT @id, @createdAt, dr.deployment_id, dr.id, @sizeBytes, @volumes
+			FROM DeploymentRevision dr
+			WHERE dr.id = @deploymentRevisionId AND dr.deployment_id = @deploymentId`,
+			pgx.NamedArgs{
+				"id":                   snapshot.ID,
+				"createdAt":            snapshot.CreatedAt,
+				"deploymentId":         deploymentID,
+				"deploymentRevisionId": snapshot.DeploymentRevisionID,
+				"sizeBytes":            snapshot.SizeBytes,
+				"volumes":              snapshot.Volumes,
+			},
+		)
+		if pgErr := new(pgconn.PgError); errors.As(err, &pgErr) && pgErr.Code == pgerrcode.UniqueViolation {
+			return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+		} else if err != nil {
+			return fmt.Errorf("could not insert DeploymentVolumeSnapshot: %w", err)
+		} else if cmd.RowsAffected() == 0 {
+			return fmt.Errorf(
+				"%w: DeploymentRevision %v does not belong to Deployment",
+				apierrors.ErrConflict, snapshot.DeploymentRevisionID,
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const WebAuthnSessionMaxAge = OIDCStateMaxAge
+
+const webAuthnCredentialOutputExpr = `
+	c.id, c.created_at, c.user_account_id, c.name, c.credential, c.last_used_at
+`
+
+func GetWebAuthnCredentials(ctx context.Context, userAccountID uuid.UUID) ([]types.WebAuthnCredential, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+webAuthnCredentialOutputExpr+
+			`FROM WebAuthnCredential c
+			WHERE c.user_account_id = @userAccountId
+			ORDER BY c.created_at`,
+		pgx.NamedArgs{"userAccountId": userAccountID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query WebAuthnCredential: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.WebAuthnCredential])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect WebAuthnCredential: %w", err)
+	}
+	return result, nil
+}
+
+func CountWebAuthnCredentials(ctx context.Context, userAccountID uuid.UUID) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`SELECT count(*) FROM WebAuthnCredential WHERE user_account_id = @userAccountId`,
+		pgx.NamedArgs{"userAccountId": userAccountID},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("could not count WebAuthnCredential: %w", err)
+	}
+	count, err := pgx.CollectExactlyOneRow(rows, pgx.RowTo[int64])
+	if err != nil {
+		return 0, fmt.Errorf("could not count WebAuthnCredential: %w", err)
+	}
+	return count, nil
+}
+
+// CreateWebAuthnCredential returns apierrors.ErrConflict if the user already has a credential with the same name
+// or if the credential was registered before.
+func CreateWebAuthnCredential(ctx context.Context, credential *types.WebAuthnCredential) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO WebAuthnCredential AS c (user_account_id, name, credential_id, credential)
+		VALUES (@userAccountId, @name, @credentialId, @credential)
+		RETURNING`+webAuthnCredentialOutputExpr,
+		pgx.NamedArgs{
+			"userAccountId": credential.UserAccountID,
+			"name":          credential.Name,
+			"credentialId":  credential.Credential.ID,
+			"credential":    credential.Credential,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert WebAuthnCredential: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.WebAuthnCredential])
+	if pgErr, ok := errors.AsType[*pgconn.PgError](err); ok && pgErr.Code == pgerrcode.UniqueViolation {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not insert WebAuthnCredential: %w", err)
+	}
+	*credential = created
+	return nil
+}
+
+// UpdateWebAuthnCredentialUsage stores the state of the credential after a successful assertion, most importantly
+// the sign count that is used to detect cloned authenticators.
+func UpdateWebAuthnCredentialUsage(ctx context.Context, credential webauthn.Credential) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE WebAuthnCredential SET credential = @credential, last_used_at = current_timestamp
+		WHERE credential_id = @credentialId`,
+		pgx.NamedArgs{"credentialId": credential.ID, "credential": credential},
+	)
+	if err != nil {
+		return fmt.Errorf("could not update WebAuthnCredential: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func UpdateWebAuthnCredentialName(ctx context.Context, id, userAccountID uuid.UUID, name string) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`UPDATE WebAuthnCredential SET name = @name WHERE id = @id AND user_account_id = @userAccountId`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID, "name": name},
+	)
+	if pgErr, ok := errors.AsType[*pgconn.PgError](err); ok && pgErr.Code == pgerrcode.UniqueViolation {
+		return fmt.Errorf("%w: %w", apierrors.ErrConflict, err)
+	} else if err != nil {
+		return fmt.Errorf("could not update WebAuthnCredential: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+func DeleteWebAuthnCredential(ctx context.Context, id, userAccountID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM WebAuthnCredential WHERE id = @id AND user_account_id = @userAccountId`,
+		pgx.NamedArgs{"id": id, "userAccountId": userAccountID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete WebAuthnCredential: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
+
+type WebAuthnSession struct {
+	ID            uuid.UUID            `db:"id"`
+	CreatedAt     time.Time            `db:"created_at"`
+	UserAccountID *uuid.UUID           `db:"user_account_id"`
+	SessionData   webauthn.SessionData `db:"session_data"`
+}
+
+func (s WebAuthnSession) Expired() bool {
+	return s.CreatedAt.Before(time.Now().UTC().Add(-WebAuthnSessionMaxAge))
+}
+
+func CreateWebAuthnSession(
+	ctx context.Context,
+	userAccountID *uuid.UUID,
+	sessionData webauthn.SessionData,
+) (uuid.UUID, error) {
+	db := internalctx.GetDb(ctx)
+	var id uuid.UUID
+	if err := db.QueryRow(ctx,
+		`INSERT INTO WebAuthnSession (user_account_id, session_data) VALUES (@userAccountId, @sessionData)
+		RETURNING id`,
+		pgx.NamedArgs{"userAccountId": userAccountID, "sessionData": sessionData},
+	).Scan(&id); err != nil {
+		return uuid.Nil, fmt.Errorf("could not insert WebAuthnSession: %w", err)
+	}
+	return id, nil
+}
+
+// DeleteWebAuthnSession removes the session and returns it, so that every challenge can be answered only once.
+func DeleteWebAuthnSession(ctx context.Context, id uuid.UUID) (WebAuthnSession, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`DELETE FROM WebAuthnSession AS s WHERE s.id = @id
+		RETURNING s.id, s.created_at, s.user_account_id, s.session_data`,
+		pgx.NamedArgs{"id": id})
+	if err != nil {
+		return WebAuthnSession{}, fmt.Errorf("could not delete WebAuthnSession: %w", err)
+	}
+	session, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[WebAuthnSession])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return WebAuthnSession{}, apierrors.ErrNotFound
+	} else if err != nil {
+		return WebAuthnSession{}, fmt.Errorf("could not delete WebAuthnSession: %w", err)
+	}
+	return session, nil
+}
+
+func CleanupWebAuthnSessions(ctx context.Context) (int64, error) {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(
+		ctx,
+		`DELETE FROM WebAuthnSession WHERE current_timestamp - created_at > @maxAge`,
+		pgx.NamedArgs{"maxAge": WebAuthnSessionMaxAge},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error cleaning up WebAuthnSession: %w", err)
+	} else {
+		return cmd.RowsAffected(), nil
+	}
+}
@@ -0,0 +1,139 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const workloadIdentityTrustPolicyOutputExpr = `
+	p.id, p.created_at, p.organization_id, p.service_account_id, p.name, p.issuer, p.audience, p.subject_pattern,
+	p.claim_patterns, p.scopes, p.token_lifetime
+`
+
+func GetWorkloadIdentityTrustPolicies(
+	ctx context.Context,
+	serviceAccountID, organizationID uuid.UUID,
+) ([]types.WorkloadIdentityTrustPolicy, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+workloadIdentityTrustPolicyOutputExpr+
+			`FROM WorkloadIdentityTrustPolicy p
+			WHERE p.service_account_id = @serviceAccountId AND p.organization_id = @organizationId
+			ORDER BY p.name`,
+		pgx.NamedArgs{"serviceAccountId": serviceAccountID, "organizationId": organizationID},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query WorkloadIdentityTrustPolicy: %w", err)
+	}
+	result, err := pgx.CollectRows(rows, pgx.RowToStructByName[types.WorkloadIdentityTrustPolicy])
+	if err != nil {
+		return nil, fmt.Errorf("could not collect WorkloadIdentityTrustPolicy: %w", err)
+	}
+	return result, nil
+}
+
+// GetWorkloadIdentityTrustPolicy returns the policy regardless of its organization, because the workload that
+// exchanges an ID token is not authenticated yet.
+func GetWorkloadIdentityTrustPolicy(ctx context.Context, id uuid.UUID) (*types.WorkloadIdentityTrustPolicy, error) {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		"SELECT"+workloadIdentityTrustPolicyOutputExpr+"FROM WorkloadIdentityTrustPolicy p WHERE p.id = @id",
+		pgx.NamedArgs{"id": id},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("could not query WorkloadIdentityTrustPolicy: %w", err)
+	}
+	result, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.WorkloadIdentityTrustPolicy])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apierrors.ErrNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("could not get WorkloadIdentityTrustPolicy: %w", err)
+	}
+	return &result, nil
+}
+
+func CreateWorkloadIdentityTrustPolicy(ctx context.Context, policy *types.WorkloadIdentityTrustPolicy) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`INSERT INTO WorkloadIdentityTrustPolicy AS p (organization_id, service_account_id, name, issuer, audience,
+			subject_pattern, claim_patterns, scopes, token_lifetime)
+		VALUES (@organizationId, @serviceAccountId, @name, @issuer, @audience, @subjectPattern,
+			coalesce(@claimPatterns::JSONB, '{}'), coalesce(@scopes::TEXT[], '{}'), @tokenLifetime)
+		RETURNING`+workloadIdentityTrustPolicyOutputExpr,
+		workloadIdentityTrustPolicyArgs(policy),
+	)
+	if err != nil {
+		return fmt.Errorf("could not insert WorkloadIdentityTrustPolicy: %w", err)
+	}
+	created, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.WorkloadIdentityTrustPolicy])
+	if err != nil {
+		return fmt.Errorf("could not insert WorkloadIdentityTrustPolicy: %w", err)
+	}
+	*policy = created
+	return nil
+}
+
+func UpdateWorkloadIdentityTrustPolicy(ctx context.Context, policy *types.WorkloadIdentityTrustPolicy) error {
+	db := internalctx.GetDb(ctx)
+	rows, err := db.Query(ctx,
+		`UPDATE WorkloadIdentityTrustPolicy AS p SET
+			name = @name,
+			issuer = @issuer,
+			audience = @audience,
+			subject_pattern = @subjectPattern,
+			claim_patterns = coalesce(@claimPatterns::JSONB, '{}'),
+			scopes = coalesce(@scopes::TEXT[], '{}'),
+			token_lifetime = @tokenLifetime
+		WHERE p.id = @id AND p.service_account_id = @serviceAccountId AND p.organization_id = @organizationId
+		RETURNING`+workloadIdentityTrustPolicyOutputExpr,
+		workloadIdentityTrustPolicyArgs(policy),
+	)
+	if err != nil {
+		return fmt.Errorf("could not update WorkloadIdentityTrustPolicy: %w", err)
+	}
+	updated, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[types.WorkloadIdentityTrustPolicy])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return apierrors.ErrNotFound
+	} else if err != nil {
+		return fmt.Errorf("could not update WorkloadIdentityTrustPolicy: %w", err)
+	}
+	*policy = updated
+	return nil
+}
+
+func workloadIdentityTrustPolicyArgs(policy *types.WorkloadIdentityTrustPolicy) pgx.NamedArgs {
+	return pgx.NamedArgs{
+		"id":               policy.ID,
+		"organizationId":   policy.OrganizationID,
+		"serviceAccountId": policy.ServiceAccountID,
+		"name":             policy.Name,
+		"issuer":           policy.Issuer,
+		"audience":         policy.Audience,
+		"subjectPattern":   policy.SubjectPattern,
+		"claimPatterns":    policy.ClaimPatterns,
+		"scopes":           policy.Scopes,
+		"tokenLifetime":    policy.TokenLifetime,
+	}
+}
+
+func DeleteWorkloadIdentityTrustPolicy(ctx context.Context, id, serviceAccountID, organizationID uuid.UUID) error {
+	db := internalctx.GetDb(ctx)
+	cmd, err := db.Exec(ctx,
+		`DELETE FROM WorkloadIdentityTrustPolicy
+		WHERE id = @id AND service_account_id = @serviceAccountId AND organization_id = @organizationId`,
+		pgx.NamedArgs{"id": id, "serviceAccountId": serviceAccountID, "organizationId": organizationID},
+	)
+	if err != nil {
+		return fmt.Errorf("could not delete WorkloadIdentityTrustPolicy: %w", err)
+	} else if cmd.RowsAffected() == 0 {
+		return apierrors.ErrNotFound
+	}
+	return nil
+}
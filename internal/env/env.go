@@ -66,8 +66,22 @@ var (
 	cleanupOrganizationCron                *string
 	cleanupOrganizationTimeout             time.Duration
 	cleanupOrganizationMinAge              time.Duration
+	cleanupAuditLogCron                    *string
+	cleanupAuditLogTimeout                 time.Duration
+	auditLogEntriesMaxAge                  time.Duration
+	cleanupUserSessionCron                 *string
+	cleanupUserSessionTimeout              time.Duration
+	cleanupJobRunCron                      *string
+	cleanupJobRunTimeout                   time.Duration
+	jobRunsMaxAge                          time.Duration
+	cleanupTaskCron                        *string
+	cleanupTaskTimeout                     time.Duration
+	tasksMaxAge                            time.Duration
+	taskWorkerConcurrency                  int
 	deploymentStatusNotificationCron       *string
 	deploymentStatusNotificationTimeout    time.Duration
+	licenseKeyExpiryNotificationCron       *string
+	licenseKeyExpiryNotificationTimeout    time.Duration
 	notificationEmailHourlyQuota           int
 	oidcGithubEnabled                      bool
 	oidcGithubClientID                     *string
@@ -240,9 +254,31 @@ func Initialize() {
 		envparse.PositiveDuration, 0)
 	cleanupOrganizationMinAge = envutil.GetEnvParsedOrDefault("CLEANUP_ORGANIZATION_MIN_AGE",
 		envparse.PositiveDuration, 30*24*time.Hour)
+	cleanupAuditLogCron = envutil.GetEnvOrNil("CLEANUP_AUDIT_LOG_CRON")
+	cleanupAuditLogTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_AUDIT_LOG_TIMEOUT",
+		envparse.PositiveDuration, 0)
+	auditLogEntriesMaxAge = envutil.GetEnvParsedOrDefault("AUDIT_LOG_ENTRIES_MAX_AGE",
+		envparse.PositiveDuration, 365*24*time.Hour)
+	cleanupUserSessionCron = envutil.GetEnvOrNil("CLEANUP_USER_SESSION_CRON")
+	cleanupUserSessionTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_USER_SESSION_TIMEOUT",
+		envparse.PositiveDuration, 0)
+	cleanupJobRunCron = envutil.GetEnvOrNil("CLEANUP_JOB_RUN_CRON")
+	cleanupJobRunTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_JOB_RUN_TIMEOUT",
+		envparse.PositiveDuration, 0)
+	jobRunsMaxAge = envutil.GetEnvParsedOrDefault("JOB_RUNS_MAX_AGE",
+		envparse.PositiveDuration, 30*24*time.Hour)
+	cleanupTaskCron = envutil.GetEnvOrNil("CLEANUP_TASK_CRON")
+	cleanupTaskTimeout = envutil.GetEnvParsedOrDefault("CLEANUP_TASK_TIMEOUT",
+		envparse.PositiveDuration, 0)
+	tasksMaxAge = envutil.GetEnvParsedOrDefault("TASKS_MAX_AGE",
+		envparse.PositiveDuration, 30*24*time.Hour)
+	taskWorkerConcurrency = envutil.GetEnvParsedOrDefault("TASK_WORKER_CONCURRENCY", envparse.PositiveNumber, 4)
 	deploymentStatusNotificationCron = envutil.GetEnvOrNil("DEPLOYMENT_STATUS_NOTIFICATION_CRON")
 	deploymentStatusNotificationTimeout = envutil.GetEnvParsedOrDefault("DEPLOYMENT_STATUS_NOTIFICATION_TIMEOUT",
 		envparse.PositiveDuration, 0)
+	licenseKeyExpiryNotificationCron = envutil.GetEnvOrNil("LICENSE_KEY_EXPIRY_NOTIFICATION_CRON")
+	licenseKeyExpiryNotificationTimeout = envutil.GetEnvParsedOrDefault("LICENSE_KEY_EXPIRY_NOTIFICATION_TIMEOUT",
+		envparse.PositiveDuration, 0)
 	notificationEmailHourlyQuota = envutil.GetEnvParsedOrDefault("NOTIFICATION_EMAIL_HOURLY_QUOTA",
 		envparse.NonNegativeNumber, 120)
 
@@ -499,6 +535,14 @@ func DeploymentStatusNotificationTimeout() time.Duration {
 	return deploymentStatusNotificationTimeout
 }
 
+func LicenseKeyExpiryNotificationCron() *string {
+	return licenseKeyExpiryNotificationCron
+}
+
+func LicenseKeyExpiryNotificationTimeout() time.Duration {
+	return licenseKeyExpiryNotificationTimeout
+}
+
 // NotificationEmailHourlyQuota is the maximum number of status/metrics notification
 // emails sent to a single email address per hour. 0 means unlimited.
 func NotificationEmailHourlyQuota() int {
@@ -537,6 +581,54 @@ func CleanupOrganizationMinAge() time.Duration {
 	return cleanupOrganizationMinAge
 }
 
+func CleanupAuditLogCron() *string {
+	return cleanupAuditLogCron
+}
+
+func CleanupAuditLogTimeout() time.Duration {
+	return cleanupAuditLogTimeout
+}
+
+func AuditLogEntriesMaxAge() time.Duration {
+	return auditLogEntriesMaxAge
+}
+
+func CleanupUserSessionCron() *string {
+	return cleanupUserSessionCron
+}
+
+func CleanupUserSessionTimeout() time.Duration {
+	return cleanupUserSessionTimeout
+}
+
+func CleanupJobRunCron() *string {
+	return cleanupJobRunCron
+}
+
+func CleanupJobRunTimeout() time.Duration {
+	return cleanupJobRunTimeout
+}
+
+func JobRunsMaxAge() time.Duration {
+	return jobRunsMaxAge
+}
+
+func CleanupTaskCron() *string {
+	return cleanupTaskCron
+}
+
+func CleanupTaskTimeout() time.Duration {
+	return cleanupTaskTimeout
+}
+
+func TasksMaxAge() time.Duration {
+	return tasksMaxAge
+}
+
+func TaskWorkerConcurrency() int {
+	return taskWorkerConcurrency
+}
+
 func OIDCGithubEnabled() bool {
 	return oidcGithubEnabled
 }
@@ -15,6 +15,14 @@ func PositiveDuration(value string) (time.Duration, error) {
 	return parsed, err
 }
 
+func NonNegativeDuration(value string) (time.Duration, error) {
+	parsed, err := time.ParseDuration(value)
+	if err == nil && parsed < 0 {
+		err = errors.New("duration must not be negative")
+	}
+	return parsed, err
+}
+
 func ByteSlice(s string) ([]byte, error) {
 	return []byte(s), nil
 }
@@ -39,6 +39,11 @@ func checkMembershipAllowed(ctx context.Context, userID, organizationID uuid.UUI
 	if err != nil {
 		return err
 	}
+	if !hasConfiguration {
+		if hasConfiguration, err = db.ExistsCustomSAMLConfigurationForOrganization(ctx, organizationID); err != nil {
+			return err
+		}
+	}
 	if !hasConfiguration {
 		return nil
 	}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+// AdminRouter is about the Distr instance as a whole rather than a single organization.
+func AdminRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Admin"))
+	r.Use(middleware.RequireSuperAdmin)
+	r.Route("/organizations", adminOrganizationsRouter)
+	r.Route("/users", adminUsersRouter)
+	r.Get("/jobs/leases", getJobLeasesHandler).
+		With(option.Description("List the leases of the scheduled jobs and the hub replicas holding them")).
+		With(option.Response(http.StatusOK, []api.JobLease{}))
+	r.Get("/jobs/runs", getJobRunsHandler).
+		With(option.Description("List the runs of the scheduled jobs, newest first")).
+		With(option.Request(struct {
+			JobName *string    `query:"jobName"`
+			Before  *time.Time `query:"before"`
+			Count   *int       `query:"count"`
+		}{})).
+		With(option.Response(http.StatusOK, []api.JobRun{}))
+	r.Get("/tasks", getAdminTasksHandler).
+		With(option.Description("List the background tasks of all organizations, newest first")).
+		With(option.Request(struct {
+			Status *string    `query:"status" enum:"pending,running,succeeded,dead"`
+			Before *time.Time `query:"before"`
+			Count  *int       `query:"count"`
+		}{})).
+		With(option.Response(http.StatusOK, []api.Task{}))
+	r.Post("/tasks/{taskId}/retry", retryTaskHandler).
+		With(option.Description("Give a dead task another round of attempts")).
+		With(option.Request(taskPathRequest{})).
+		With(option.Response(http.StatusOK, api.Task{}))
+}
+
+func getJobLeasesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if leases, err := db.GetJobLeases(ctx); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(leases, mapping.JobLeaseToAPI))
+	}
+}
+
+func getJobRunsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var jobName *string
+	if name := r.FormValue("jobName"); name != "" {
+		jobName = &name
+	}
+	before, count, ok := parseAdminPagination(w, r)
+	if !ok {
+		return
+	}
+
+	if runs, err := db.GetJobRuns(ctx, jobName, before, count); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(runs, mapping.JobRunToAPI))
+	}
+}
+
+func getAdminTasksHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	var status *types.TaskStatus
+	switch value := types.TaskStatus(r.FormValue("status")); value {
+	case "":
+	case types.TaskStatusPending, types.TaskStatusRunning, types.TaskStatusSucceeded, types.TaskStatusDead:
+		status = &value
+	default:
+		http.Error(w, "invalid status", http.StatusBadRequest)
+		return
+	}
+	before, count, ok := parseAdminPagination(w, r)
+	if !ok {
+		return
+	}
+
+	if tasks, err := db.GetTasks(ctx, status, before, count); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(tasks, mapping.TaskToAPI))
+	}
+}
+
+func retryTaskHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("taskId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if task, err := db.RetryTask(ctx, id); errors.Is(err, apierrors.ErrNotFound) {
+		http.Error(w, "task does not exist or is not dead", http.StatusNotFound)
+	} else if errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, err.Error(), http.StatusConflict)
+	} else if err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.TaskToAPI(*task))
+	}
+}
+
+func parseAdminPagination(w http.ResponseWriter, r *http.Request) (before time.Time, count int, ok bool) {
+	before, count = time.Now(), 50
+	if value, err := QueryParam(r, "before", ParseTimeFunc(time.RFC3339Nano)); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		http.Error(w, "before must be a valid date", http.StatusBadRequest)
+		return before, count, false
+	} else {
+		before = value
+	}
+	if value, err := QueryParam(r, "count", strconv.Atoi); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil || value < 1 || value > 1000 {
+		http.Error(w, "count must be a number between 1 and 1000", http.StatusBadRequest)
+		return before, count, false
+	} else {
+		count = value
+	}
+	return before, count, true
+}
+
+func respondAdminError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case errors.Is(err, apierrors.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, apierrors.ErrBadRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, apierrors.ErrConflict):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		internalctx.GetLogger(ctx).Error("admin request failed", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/limit"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/superadmin"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+)
+
+type adminOrganizationPathRequest struct {
+	OrganizationID uuid.UUID `path:"organizationId"`
+}
+
+type adminOrganizationFeaturePathRequest struct {
+	adminOrganizationPathRequest
+	Feature types.Feature `path:"feature"`
+}
+
+// adminOrganizationsRouter is mounted in AdminRouter. Changes are recorded in the audit log of the changed
+// organization.
+func adminOrganizationsRouter(r chiopenapi.Router) {
+	r.Get("/", getAdminOrganizationsHandler).
+		With(option.Description("List the organizations of the instance, newest first. search matches the ID, " +
+			"name or slug. deleted lists the soft-deleted organizations instead of the active ones")).
+		With(option.Request(struct {
+			Search  *string    `query:"search"`
+			Deleted *bool      `query:"deleted"`
+			Before  *time.Time `query:"before"`
+			Count   *int       `query:"count"`
+		}{})).
+		With(option.Response(http.StatusOK, []api.AdminOrganization{}))
+	r.Route("/{organizationId}", func(r chiopenapi.Router) {
+		r.Get("/", getAdminOrganizationHandler).
+			With(option.Description("Get an organization, even if it was soft-deleted")).
+			With(option.Request(adminOrganizationPathRequest{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+		r.Delete("/", deleteAdminOrganizationHandler).
+			With(option.Description("Soft-delete an organization. It is purged by the Organization cleanup job " +
+				"and can be restored until then")).
+			With(option.Request(adminOrganizationPathRequest{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+		r.Post("/restore", restoreAdminOrganizationHandler).
+			With(option.Description("Restore a soft-deleted organization that was not purged yet")).
+			With(option.Request(adminOrganizationPathRequest{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+		r.Put("/subscription", updateAdminOrganizationSubscriptionHandler).
+			With(option.Description("Change the subscription type and limits of an organization. The features " +
+				"of the new subscription type are granted")).
+			With(option.Request(struct {
+				adminOrganizationPathRequest
+				api.UpdateAdminOrganizationSubscriptionRequest
+			}{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+		r.Put("/features/{feature}", grantAdminOrganizationFeatureHandler).
+			With(option.Description("Grant a feature to an organization, regardless of its subscription")).
+			With(option.Request(adminOrganizationFeaturePathRequest{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+		r.Delete("/features/{feature}", revokeAdminOrganizationFeatureHandler).
+			With(option.Description("Revoke a feature from an organization")).
+			With(option.Request(adminOrganizationFeaturePathRequest{})).
+			With(option.Response(http.StatusOK, api.AdminOrganization{}))
+	})
+}
+
+func getAdminOrganizationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter := types.OrganizationOverviewFilter{
+		Search:  r.FormValue("search"),
+		Deleted: r.FormValue("deleted") == "true",
+	}
+	var ok bool
+	if filter.Before, filter.Count, ok = parseAdminPagination(w, r); !ok {
+		return
+	}
+	if orgs, err := db.GetOrganizationOverviews(ctx, filter); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(orgs, mapping.OrganizationOverviewToAPI))
+	}
+}
+
+func getAdminOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("organizationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if org, err := db.GetOrganizationOverview(ctx, id); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.OrganizationOverviewToAPI(*org))
+	}
+}
+
+func deleteAdminOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("organizationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if org, err := superadmin.DeleteOrganization(ctx, id); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		auditlog.SetOrganization(ctx, id)
+		auditlog.SetResource(ctx, string(types.ResourceOrganization), id.String())
+		respondAdminOrganization(w, r, id, org)
+	}
+}
+
+func restoreAdminOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("organizationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if org, err := superadmin.RestoreOrganization(ctx, id); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		auditlog.SetOrganization(ctx, id)
+		auditlog.SetResource(ctx, string(types.ResourceOrganization), id.String())
+		auditlog.SetAction(ctx, types.AuditLogActionUpdate)
+		respondAdminOrganization(w, r, id, org)
+	}
+}
+
+// respondAdminOrganization responds with the organization after it was deleted or restored, and records the change
+// of its deletion date.
+func respondAdminOrganization(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+	before *types.OrganizationOverview,
+) {
+	ctx := r.Context()
+	if after, err := db.GetOrganizationOverview(ctx, id); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, mapping.OrganizationOverviewToAPI(*before), mapping.OrganizationOverviewToAPI(*after))
+		RespondJSON(w, mapping.OrganizationOverviewToAPI(*after))
+	}
+}
+
+func updateAdminOrganizationSubscriptionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("organizationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.UpdateAdminOrganizationSubscriptionRequest](w, r)
+	if err != nil {
+		return
+	}
+	before, after, err := superadmin.UpdateSubscription(ctx, id, superadmin.Subscription{
+		Type:                    request.SubscriptionType,
+		Period:                  request.SubscriptionPeriod,
+		EndsAt:                  request.SubscriptionEndsAt,
+		CustomerOrganizationQty: limit.New(request.SubscriptionCustomerOrganizationQuantity),
+		UserAccountQty:          limit.New(request.SubscriptionUserAccountQuantity),
+	})
+	respondAdminOrganizationChange(w, r, id, before, after, err)
+}
+
+func grantAdminOrganizationFeatureHandler(w http.ResponseWriter, r *http.Request) {
+	setAdminOrganizationFeature(w, r, true)
+}
+
+func revokeAdminOrganizationFeatureHandler(w http.ResponseWriter, r *http.Request) {
+	setAdminOrganizationFeature(w, r, false)
+}
+
+func setAdminOrganizationFeature(w http.ResponseWriter, r *http.Request, enabled bool) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("organizationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	feature := types.Feature(r.PathValue("feature"))
+	before, after, err := superadmin.SetFeatures(ctx, id, []types.Feature{feature}, enabled)
+	// Granting and revoking are both changes of the organization, not of a feature resource.
+	auditlog.SetAction(ctx, types.AuditLogActionUpdate)
+	respondAdminOrganizationChange(w, r, id, before, after, err)
+}
+
+func respondAdminOrganizationChange(
+	w http.ResponseWriter,
+	r *http.Request,
+	id uuid.UUID,
+	before, after *types.OrganizationOverview,
+	err error,
+) {
+	ctx := r.Context()
+	if err != nil {
+		respondAdminError(w, r, err)
+		return
+	}
+	auditlog.SetOrganization(ctx, id)
+	auditlog.SetResource(ctx, string(types.ResourceOrganization), id.String())
+	auditlog.RecordChange(ctx, mapping.OrganizationOverviewToAPI(*before), mapping.OrganizationOverviewToAPI(*after))
+	RespondJSON(w, mapping.OrganizationOverviewToAPI(*after))
+}
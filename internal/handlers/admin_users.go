@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/handlerutil"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/superadmin"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+)
+
+type adminUserAccountPathRequest struct {
+	UserAccountID uuid.UUID `path:"userId"`
+}
+
+// adminUsersRouter is mounted in AdminRouter.
+func adminUsersRouter(r chiopenapi.Router) {
+	r.Get("/", getAdminUserAccountsHandler).
+		With(option.Description("List the user accounts of the instance, newest first. search matches the ID, " +
+			"email or name")).
+		With(option.Request(struct {
+			Search         *string    `query:"search"`
+			OrganizationID *uuid.UUID `query:"organizationId"`
+			Before         *time.Time `query:"before"`
+			Count          *int       `query:"count"`
+		}{})).
+		With(option.Response(http.StatusOK, []api.AdminUserAccount{}))
+	r.Route("/{userId}", func(r chiopenapi.Router) {
+		r.Post("/impersonate", impersonateUserAccountHandler).
+			With(option.Description("Start a session of one hour in which you act as the user in one of their " +
+				"organizations. It is recorded in the audit log of the organization, and so is every request of " +
+				"the session")).
+			With(option.Request(struct {
+				adminUserAccountPathRequest
+				api.ImpersonateUserAccountRequest
+			}{})).
+			With(option.Response(http.StatusOK, api.ImpersonateUserAccountResponse{}))
+		r.Delete("/mfa", resetUserAccountMFAHandler).
+			With(option.Description("Disable the second factor of a user who lost it and revoke their sessions")).
+			With(option.Request(adminUserAccountPathRequest{}))
+	})
+}
+
+func getAdminUserAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter := types.UserAccountOverviewFilter{Search: r.FormValue("search")}
+	if value := r.FormValue("organizationId"); value != "" {
+		if id, err := uuid.Parse(value); err != nil {
+			http.Error(w, "organizationId must be a valid UUID", http.StatusBadRequest)
+			return
+		} else {
+			filter.OrganizationID = &id
+		}
+	}
+	var ok bool
+	if filter.Before, filter.Count, ok = parseAdminPagination(w, r); !ok {
+		return
+	}
+	if users, err := db.GetUserAccountOverviews(ctx, filter); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(users, mapping.UserAccountOverviewToAPI))
+	}
+}
+
+func impersonateUserAccountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	id, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.ImpersonateUserAccountRequest](w, r)
+	if err != nil {
+		return
+	}
+
+	token, err := superadmin.Impersonate(ctx, *auth.CurrentUser(), id, request.OrganizationID,
+		userauth.ClientFromRequest(r))
+	if err != nil {
+		respondAdminError(w, r, err)
+		return
+	}
+	auditlog.SetOrganization(ctx, request.OrganizationID)
+	auditlog.SetResource(ctx, string(types.ResourceUserAccounts), id.String())
+	auditlog.SetAction(ctx, types.AuditLogActionImpersonate)
+	RespondJSON(w, api.ImpersonateUserAccountResponse{
+		Token:    token,
+		LoginURL: fmt.Sprintf("%v/login?jwt=%v", handlerutil.GetRequestSchemeAndHost(r), token),
+	})
+}
+
+func resetUserAccountMFAHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id, err := uuid.Parse(r.PathValue("userId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := superadmin.ResetMFA(ctx, id); err != nil {
+		respondAdminError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -40,13 +40,13 @@ func findAffectedDeploymentsBySecret(
 	return findAffectedDeployments(ctx, orgID, customerOrgID, updateSecretValuePatchFunc(secretKey, newValue), nil)
 }
 
-func updateLicenseKeyPatchFunc(updatedLicenseKey types.LicenseKey) func([]types.LicenseKey) []types.LicenseKey {
+func updateLicenseKeyPatchFunc(updatedLicenseKeys ...types.LicenseKey) func([]types.LicenseKey) []types.LicenseKey {
 	return func(licenseKeys []types.LicenseKey) []types.LicenseKey {
 		patched := slices.Clone(licenseKeys)
 		for i := range patched {
-			if patched[i].ID == updatedLicenseKey.ID {
-				patched[i] = updatedLicenseKey
-				break
+			idx := slices.IndexFunc(updatedLicenseKeys, func(k types.LicenseKey) bool { return k.ID == patched[i].ID })
+			if idx >= 0 {
+				patched[i] = updatedLicenseKeys[idx]
 			}
 		}
 		return patched
@@ -57,9 +57,9 @@ func findAffectedDeploymentsByLicenseKey(
 	ctx context.Context,
 	orgID uuid.UUID,
 	customerOrgID uuid.UUID,
-	updatedLicenseKey types.LicenseKey,
+	updatedLicenseKeys ...types.LicenseKey,
 ) ([]api.AffectedDeployment, error) {
-	return findAffectedDeployments(ctx, orgID, &customerOrgID, nil, updateLicenseKeyPatchFunc(updatedLicenseKey))
+	return findAffectedDeployments(ctx, orgID, &customerOrgID, nil, updateLicenseKeyPatchFunc(updatedLicenseKeys...))
 }
 
 func findAffectedDeployments(
@@ -256,6 +256,7 @@ func deploymentRequestFromLatestRevision(deployment types.DeploymentWithLatestRe
 		ForceRestart:             deployment.ForceRestart,
 		IgnoreRevisionSkew:       deployment.IgnoreRevisionSkew,
 		HelmOptions:              apiHelmOptionsFromInternal(deployment.HelmOptions),
+		VolumeBackupPolicy:       &deployment.VolumeBackupPolicy,
 	}
 }
 
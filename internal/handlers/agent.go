@@ -3,12 +3,14 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,6 +18,7 @@ import (
 	"github.com/distr-sh/distr/internal/agentclient/useragent"
 	"github.com/distr-sh/distr/internal/agentconnect"
 	"github.com/distr-sh/distr/internal/agentmanifest"
+	"github.com/distr-sh/distr/internal/agentnotify"
 	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/authjwt"
@@ -23,6 +26,7 @@ import (
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/deploymentvalues"
 	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/jobs"
 	"github.com/distr-sh/distr/internal/logstore"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
@@ -78,9 +82,13 @@ func AgentRouter(r chiopenapi.Router) {
 			// agent routes, authenticated via token.
 			// manifest and resources are read-only and served from the read-only db. The auth
 			// middleware above (which may write the reported agent version) stays on the primary.
+			// agentResourcesHandler switches to the read-only db itself, see there.
 			r.With(middleware.UseReadonlyDB).Get("/manifest", agentManifestHandler())
-			r.With(middleware.UseReadonlyDB).Get("/resources", agentResourcesHandler)
+			r.Get("/resources", agentResourcesHandler)
 			r.Post("/status", agentPostStatusHandler)
+			r.Post("/plans", agentPostPlanHandler)
+			r.Put("/metadata", agentPutMetadataHandler)
+			r.Put("/volume-snapshots", agentPutVolumeSnapshotsHandler)
 			r.Post("/metrics", agentPostMetricsHander)
 			r.Put("/logs", agentPutDeploymentLogsHandler())
 			r.Put("/deployment-target-logs", agentPutDeploymentTargetLogsHandler())
@@ -102,8 +110,16 @@ func connectHandler() http.HandlerFunc {
 			return
 		}
 
+		network, err := getAgentNetworkForManifest(ctx, deploymentTarget.ID)
+		if err != nil {
+			log.Error("could not get agent network settings for deployment target", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
 		secret := r.URL.Query().Get("targetSecret")
-		if manifest, err := agentmanifest.Get(ctx, *deploymentTarget, *org, &secret); err != nil {
+		if manifest, err := agentmanifest.Get(ctx, *deploymentTarget, *org, network, &secret); err != nil {
 			log.Error("could not get agent manifest", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -132,7 +148,7 @@ func preConnectHandler() http.HandlerFunc {
 		}
 
 		secret := r.URL.Query().Get("targetSecret")
-		script, err := agentconnect.GenerateConnectScript(ctx, deploymentTarget.ID, *org, secret)
+		script, err := agentconnect.GenerateConnectScript(ctx, deploymentTarget.DeploymentTarget, *org, secret)
 		if err != nil {
 			log.Error("could not generate connect script", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
@@ -186,132 +202,258 @@ func agentLoginHandler() func(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// maxAgentResourceWait limits how long an agent can wait for a change of its resource, so that the request ends
+// before common proxy and client timeouts.
+const maxAgentResourceWait = 2 * time.Minute
+
+// agentResourcesHandler returns the resource of the agent with an ETag. Agents that send the ETag of their current
+// resource in If-None-Match get 304 Not Modified if it did not change. If they also set the query parameter wait
+// to a number of seconds, the response is held back until the resource changes or the time is up.
 func agentResourcesHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
 	log := internalctx.GetLogger(ctx).With(zap.String("deploymentTargetId", deploymentTarget.ID.String()))
+	ifNoneMatch := r.Header.Get("If-None-Match")
 
-	deployments, err := db.GetDeploymentsForDeploymentTarget(ctx, deploymentTarget.ID)
+	var wait time.Duration
+	if value := r.URL.Query().Get("wait"); value != "" {
+		if seconds, err := strconv.Atoi(value); err != nil || seconds < 0 {
+			http.Error(w, "parameter wait is invalid", http.StatusBadRequest)
+			return
+		} else {
+			wait = min(time.Duration(seconds)*time.Second, maxAgentResourceWait)
+		}
+	}
+
+	// subscribe before the resource is read, so that no change is missed
+	var changed <-chan struct{}
+	if notifier := agentnotify.FromContext(ctx); notifier != nil && wait > 0 && ifNoneMatch != "" {
+		var unsubscribe func()
+		changed, unsubscribe = notifier.Subscribe(deploymentTarget.ID, deploymentTarget.OrganizationID)
+		defer unsubscribe()
+	}
+
+	// the resource is read from the read-only db, but after a change it must be read from the primary, as the
+	// read-only db may not have caught up with the change yet
+	readCtx := ctx
+	if readonlyDB := internalctx.GetReadonlyDB(ctx); readonlyDB != nil {
+		readCtx = internalctx.WithDb(ctx, readonlyDB)
+	}
+	resource, etag, err := getAgentResource(readCtx, deploymentTarget)
 	if err != nil {
-		log.Error("failed to get latest Deployment from DB", zap.Error(err))
+		log.Error("failed to get agent resource", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
-	} else {
-		agentResource := api.AgentResource{
-			Version:               deploymentTarget.AgentVersion,
-			MetricsEnabled:        deploymentTarget.MetricsEnabled,
-			DeploymentLogsEnabled: deploymentTarget.DeploymentLogsEnabled,
-			DeploymentLogsAfter:   deploymentTarget.DeploymentLogsAfter,
-		}
-		if deploymentTarget.Namespace != nil {
-			agentResource.Namespace = *deploymentTarget.Namespace
-		}
+	}
 
-		for _, deployment := range deployments {
-			appVersion, err := db.GetApplicationVersion(ctx, deployment.ApplicationVersionID)
-			if err != nil {
-				log.Error("failed to get ApplicationVersion from DB", zap.Error(err))
-				http.Error(w, err.Error(), http.StatusInternalServerError)
+	if changed != nil && etag == ifNoneMatch {
+		collector := internalctx.GetPrometheusCollector(ctx)
+		if collector != nil {
+			collector.IncAgentsConnected()
+			defer collector.DecAgentsConnected()
+		}
+		timeout := time.After(wait)
+	loop:
+		for etag == ifNoneMatch {
+			select {
+			case <-changed:
+				if deploymentTarget, err = db.GetDeploymentTarget(
+					ctx, deploymentTarget.ID, &deploymentTarget.OrganizationID, nil); err != nil {
+					log.Error("failed to get DeploymentTarget", zap.Error(err))
+					sentry.GetHubFromContext(ctx).CaptureException(err)
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				} else if resource, etag, err = getAgentResource(ctx, deploymentTarget); err != nil {
+					log.Error("failed to get agent resource", zap.Error(err))
+					sentry.GetHubFromContext(ctx).CaptureException(err)
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+			case <-timeout:
+				break loop
+			case <-ctx.Done():
 				return
 			}
+		}
+		if etag != ifNoneMatch && collector != nil {
+			collector.IncAgentResourcePushes()
+		}
+	}
 
-			agentDeployment := api.AgentDeployment{
-				ID:         deployment.ID,
-				RevisionID: deployment.DeploymentRevisionID,
-				//nolint:staticcheck // deprecated field kept for agents that don't read AgentResource.DeploymentLogsEnabled yet
-				LogsEnabled:        deploymentTarget.DeploymentLogsEnabled,
-				ForceRestart:       deployment.ForceRestart,
-				IgnoreRevisionSkew: deployment.IgnoreRevisionSkew,
-			}
+	w.Header().Set("ETag", etag)
+	if etag == ifNoneMatch {
+		w.WriteHeader(http.StatusNotModified)
+	} else {
+		RespondJSON(w, resource)
+	}
+}
 
-			if deployment.ApplicationEntitlementID != nil {
-				if entitlement, err := db.GetApplicationEntitlementByID(ctx, *deployment.ApplicationEntitlementID); err != nil {
-					log.Error("failed to get ApplicationEntitlement from DB", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if entitlement.RegistryURL != nil {
-					agentDeployment.RegistryAuth = map[string]api.AgentRegistryAuth{
-						*entitlement.RegistryURL: {
-							Username: *entitlement.RegistryUsername,
-							Password: *entitlement.RegistryPassword,
-						},
-					}
+func getAgentResource(
+	ctx context.Context,
+	deploymentTarget *types.DeploymentTargetFull,
+) (*api.AgentResource, string, error) {
+	deployments, err := db.GetDeploymentsForDeploymentTarget(ctx, deploymentTarget.ID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get latest Deployment from DB: %w", err)
+	}
+
+	agentResource := api.AgentResource{
+		Version:               deploymentTarget.AgentVersion,
+		MetricsEnabled:        deploymentTarget.MetricsEnabled,
+		DeploymentLogsEnabled: deploymentTarget.DeploymentLogsEnabled,
+		DeploymentLogsAfter:   deploymentTarget.DeploymentLogsAfter,
+	}
+	if deploymentTarget.Namespace != nil {
+		agentResource.Namespace = *deploymentTarget.Namespace
+	}
+	if deploymentTarget.Type == types.DeploymentTypeDocker {
+		storage, err := db.GetVolumeBackupStorage(ctx, deploymentTarget.ID)
+		if err != nil && !errors.Is(err, apierrors.ErrNotFound) {
+			return nil, "", fmt.Errorf("failed to get VolumeBackupStorage from DB: %w", err)
+		}
+		agentResource.VolumeBackupStorage = mapping.VolumeBackupStorageToAgentAPI(storage)
+	}
+
+	for _, deployment := range deployments {
+		appVersion, err := db.GetApplicationVersion(ctx, deployment.ApplicationVersionID)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get ApplicationVersion from DB: %w", err)
+		}
+
+		agentDeployment := api.AgentDeployment{
+			ID:         deployment.ID,
+			RevisionID: deployment.DeploymentRevisionID,
+			//nolint:staticcheck // deprecated field kept for agents that don't read AgentResource.DeploymentLogsEnabled yet
+			LogsEnabled:        deploymentTarget.DeploymentLogsEnabled,
+			ForceRestart:       deployment.ForceRestart,
+			IgnoreRevisionSkew: deployment.IgnoreRevisionSkew,
+		}
+
+		if deployment.ApplicationEntitlementID != nil {
+			if entitlement, err := db.GetApplicationEntitlementByID(ctx, *deployment.ApplicationEntitlementID); err != nil {
+				return nil, "", fmt.Errorf("failed to get ApplicationEntitlement from DB: %w", err)
+			} else if entitlement.RegistryURL != nil {
+				agentDeployment.RegistryAuth = map[string]api.AgentRegistryAuth{
+					*entitlement.RegistryURL: {
+						Username: *entitlement.RegistryUsername,
+						Password: *entitlement.RegistryPassword,
+					},
 				}
 			}
+		}
 
-			var secrets []types.SecretWithUpdatedBy
-			if secrets, err = db.GetSecretsForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget); err != nil {
-				log.Error("failed to get secrets from DB", zap.Error(err))
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		secrets, err := db.GetSecretsForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get secrets from DB: %w", err)
+		}
 
-			licenseKeys, err := db.GetLicenseKeysForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget)
-			if err != nil {
-				log.Error("failed to get license keys from DB", zap.Error(err))
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return
-			}
+		licenseKeys, err := db.GetLicenseKeysForDeploymentTarget(ctx, deploymentTarget.DeploymentTarget)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get license keys from DB: %w", err)
+		}
 
-			if deploymentTarget.Type == types.DeploymentTypeDocker {
-				if composeYaml, err := appVersion.ParsedComposeFile(); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if patchedComposeFile, err := patchProjectName(composeYaml, deployment.ID); err != nil {
-					log.Warn("failed to patch project name", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if envFile, err := deploymentvalues.EnvFileReplaceSecrets(&deployment, secrets, licenseKeys); err != nil {
-					log.Warn("failed to replace secrets", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else {
-					agentDeployment.ComposeFile = patchedComposeFile
-					agentDeployment.EnvFile = envFile
-					agentDeployment.DockerType = util.PtrCopy(deployment.DockerType)
-					agentDeployment.ImageCleanupEnabled = deploymentTarget.ImageCleanupEnabled
+		if deploymentTarget.Type == types.DeploymentTypeDocker {
+			if composeYaml, err := appVersion.ParsedComposeFile(); err != nil {
+				return nil, "", fmt.Errorf("parse error: %w", err)
+			} else if patchedComposeFile, err := patchProjectName(composeYaml, deployment.ID); err != nil {
+				return nil, "", fmt.Errorf("failed to patch project name: %w", err)
+			} else if envFile, err := deploymentvalues.EnvFileReplaceSecrets(&deployment, secrets, licenseKeys); err != nil {
+				return nil, "", fmt.Errorf("failed to replace secrets: %w", err)
+			} else {
+				agentDeployment.ComposeFile = patchedComposeFile
+				agentDeployment.EnvFile = envFile
+				agentDeployment.DockerType = util.PtrCopy(deployment.DockerType)
+				agentDeployment.ImageCleanupEnabled = deploymentTarget.ImageCleanupEnabled
+				agentDeployment.ApplicationVersionID = deployment.ApplicationVersionID
+				agentDeployment.VolumeBackupPolicy = deployment.VolumeBackupPolicy
+				agentDeployment.RestoreVolumeSnapshotID = deployment.RestoreVolumeSnapshotID
+			}
+		} else if deploymentTarget.Type == types.DeploymentTypeOpenTofu {
+			agentDeployment.ReleaseName = *deployment.ReleaseName
+			agentDeployment.PackageRef = *appVersion.PackageRef
+			if values, err := mergedDeploymentValues(*appVersion, &deployment, secrets, licenseKeys); err != nil {
+				return nil, "", err
+			} else {
+				agentDeployment.Values = values
+			}
+			if plan, err := db.GetDeploymentRevisionPlan(ctx, deployment.DeploymentRevisionID); err != nil {
+				if !errors.Is(err, apierrors.ErrNotFound) {
+					return nil, "", fmt.Errorf("failed to get DeploymentRevisionPlan from DB: %w", err)
 				}
+			} else if plan.IsApproved() {
+				agentDeployment.ApprovedPlanDigest = plan.Digest
+			}
+		} else if deploymentTarget.Type == types.DeploymentTypeSystemd {
+			agentDeployment.ReleaseName = *deployment.ReleaseName
+			agentDeployment.PackageRef = *appVersion.PackageRef
+			agentDeployment.UnitFile = appVersion.UnitFileData
+			if values, err := mergedDeploymentValues(*appVersion, &deployment, secrets, licenseKeys); err != nil {
+				return nil, "", err
+			} else {
+				agentDeployment.Values = values
+			}
+		} else {
+			agentDeployment.ReleaseName = *deployment.ReleaseName
+			agentDeployment.SourceType = *appVersion.SourceType
+			if appVersion.IsKustomize() {
+				agentDeployment.ManifestBundle = appVersion.ManifestBundleData
 			} else {
-				agentDeployment.ReleaseName = *deployment.ReleaseName
 				agentDeployment.ChartUrl = *appVersion.ChartUrl
 				agentDeployment.ChartVersion = *appVersion.ChartVersion
-				if versionValues, err := appVersion.ParsedValuesFile(); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if deploymentValues, err := deploymentvalues.ParsedValuesFileReplaceSecrets(
-					&deployment,
-					secrets,
-					licenseKeys,
-				); err != nil {
-					log.Warn("parse error", zap.Error(err))
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return
-				} else if merged, err := util.MergeAllRecursive(versionValues, deploymentValues); err != nil {
-					log.Warn("merge error", zap.Error(err))
-					http.Error(w, fmt.Sprintf("error merging values files: %v", err), http.StatusInternalServerError)
-					return
-				} else {
-					agentDeployment.Values = merged
-				}
 				if *appVersion.ChartType == types.HelmChartTypeRepository {
 					agentDeployment.ChartName = *appVersion.ChartName
 				}
-				if deployment.HelmOptions != nil {
-					agentDeployment.HelmOptions = &api.HelmOptions{
-						Timeout:           deployment.HelmOptions.Timeout,
-						WaitStrategy:      deployment.HelmOptions.WaitStrategy,
-						RollbackOnFailure: deployment.HelmOptions.RollbackOnFailure,
-						CleanupOnFailure:  deployment.HelmOptions.CleanupOnFailure,
-						ForceConflicts:    deployment.HelmOptions.ForceConflicts,
-					}
+			}
+			if values, err := mergedDeploymentValues(*appVersion, &deployment, secrets, licenseKeys); err != nil {
+				return nil, "", err
+			} else {
+				agentDeployment.Values = values
+			}
+			if rules, err := db.GetApplicationVersionHealthRules(ctx, appVersion.ID); err != nil {
+				return nil, "", err
+			} else if len(rules) > 0 {
+				agentDeployment.HealthRules = mapping.List(rules, mapping.ApplicationVersionHealthRuleToAgentAPI)
+			}
+			if deployment.HelmOptions != nil {
+				agentDeployment.HelmOptions = &api.HelmOptions{
+					Timeout:           deployment.HelmOptions.Timeout,
+					WaitStrategy:      deployment.HelmOptions.WaitStrategy,
+					RollbackOnFailure: deployment.HelmOptions.RollbackOnFailure,
+					CleanupOnFailure:  deployment.HelmOptions.CleanupOnFailure,
+					ForceConflicts:    deployment.HelmOptions.ForceConflicts,
 				}
 			}
-			agentResource.Deployments = append(agentResource.Deployments, agentDeployment)
 		}
+		agentResource.Deployments = append(agentResource.Deployments, agentDeployment)
+	}
+
+	if data, err := json.Marshal(agentResource); err != nil {
+		return nil, "", err
+	} else {
+		return &agentResource, fmt.Sprintf(`"%x"`, sha256.Sum256(data)), nil
+	}
+}
 
-		RespondJSON(w, agentResource)
+// mergedDeploymentValues returns the values of the application version merged with those of the deployment.
+func mergedDeploymentValues(
+	appVersion types.ApplicationVersion,
+	deployment deploymentvalues.ValuesYAMLAccessor,
+	secrets []types.SecretWithUpdatedBy,
+	licenseKeys []types.LicenseKey,
+) (map[string]any, error) {
+	if versionValues, err := appVersion.ParsedValuesFile(); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	} else if deploymentValues, err := deploymentvalues.ParsedValuesFileReplaceSecrets(
+		deployment,
+		secrets,
+		licenseKeys,
+	); err != nil {
+		return nil, fmt.Errorf("parse error: %w", err)
+	} else if merged, err := util.MergeAllRecursive(versionValues, deploymentValues); err != nil {
+		return nil, fmt.Errorf("error merging values files: %w", err)
+	} else {
+		return merged, nil
 	}
 }
 
@@ -430,31 +572,13 @@ func agentPostStatusHandler(w http.ResponseWriter, r *http.Request) {
 	log := internalctx.GetLogger(ctx).With(zap.Any("status", requestBody))
 	sentry := sentry.GetHubFromContext(ctx)
 
-	deploymentID, err := db.GetDeploymentIDForRevisionID(ctx, requestBody.RevisionID)
-	if err != nil {
-		if errors.Is(err, apierrors.ErrNotFound) {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-		} else {
-			sentry.CaptureException(err)
-			log.Error("failed to get deployment ID", zap.Error(err))
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		}
-		return
-	}
-
 	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
-	var deployment types.DeploymentWithLatestRevision
-	if i := slices.IndexFunc(
-		deploymentTarget.Deployments,
-		func(d types.DeploymentWithLatestRevision) bool { return d.ID == deploymentID },
-	); i < 0 {
-		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+	deployment, ok := getAgentDeploymentForRevision(w, r, requestBody.RevisionID)
+	if !ok {
 		return
-	} else {
-		deployment = deploymentTarget.Deployments[i]
 	}
 
-	previousStatus, err := db.GetLatestDeploymentRevisionStatus(ctx, deploymentID)
+	previousStatus, err := db.GetLatestDeploymentRevisionStatus(ctx, deployment.ID)
 	if err != nil {
 		sentry.CaptureException(err)
 		log.Error("failed to get latest deployment revision status", zap.Error(err))
@@ -466,6 +590,7 @@ func agentPostStatusHandler(w http.ResponseWriter, r *http.Request) {
 		DeploymentRevisionID: requestBody.RevisionID,
 		Type:                 requestBody.Type,
 		Message:              requestBody.Message,
+		CreatedAt:            util.PtrDerefOrDefault(requestBody.CreatedAt),
 	}
 
 	if err := db.CreateDeploymentRevisionStatus(ctx, &status); err != nil {
@@ -479,23 +604,171 @@ func agentPostStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go func(ctx context.Context) {
-		asyncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
+	if !notification.ShouldNotifyDeploymentStatus(previousStatus, status) {
+		// nothing to notify about
+	} else if _, err := jobs.Enqueue(
+		ctx,
+		notification.DeploymentStatusTaskType,
+		notification.DeploymentStatusTask{
+			DeploymentTargetID: deploymentTarget.ID,
+			DeploymentID:       deployment.ID,
+			PreviousStatus:     previousStatus,
+			CurrentStatus:      status,
+		},
+		jobs.WithTaskOrganization(deploymentTarget.OrganizationID),
+	); err != nil {
+		// the status itself was saved, so the agent must not retry
+		sentry.CaptureException(err)
+		log.Error("failed to enqueue deployment status notification", zap.Error(err))
+	}
 
-		if err := notification.SendDeploymentStatusNotifications(
-			asyncCtx,
-			*deploymentTarget,
-			deployment,
-			previousStatus,
-			status,
-		); err != nil {
-			sentry.CaptureException(err)
-			log.Error("failed to dispatch deployment status notification", zap.Error(err))
+	w.WriteHeader(http.StatusOK)
+}
+
+func agentPostPlanHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := JsonBody[api.AgentDeploymentPlan](w, r)
+	if err != nil {
+		return
+	} else if requestBody.Digest == "" {
+		http.Error(w, "plan must have a digest", http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	if _, ok := getAgentDeploymentForRevision(w, r, requestBody.RevisionID); !ok {
+		return
+	}
+
+	plan := types.DeploymentRevisionPlan{
+		DeploymentRevisionID: requestBody.RevisionID,
+		Digest:               requestBody.Digest,
+		HasChanges:           requestBody.HasChanges,
+		Output:               requestBody.Output,
+	}
+	if err := db.SaveDeploymentRevisionPlan(ctx, &plan); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		} else {
+			internalctx.GetLogger(ctx).Error("failed to save deployment revision plan", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		}
-	}(context.WithoutCancel(ctx))
+		return
+	}
 
-	w.WriteHeader(http.StatusOK)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func agentPutMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := JsonBody[api.AgentDeploymentMetadata](w, r)
+	if err != nil {
+		return
+	}
+
+	ctx := r.Context()
+	deployment, ok := getAgentDeploymentForRevision(w, r, requestBody.RevisionID)
+	if !ok {
+		return
+	}
+
+	metadata := types.DeploymentMetadata{
+		DeploymentID:         deployment.ID,
+		DeploymentRevisionID: requestBody.RevisionID,
+		Outputs:              requestBody.Outputs,
+	}
+	if metadata.Outputs == nil {
+		metadata.Outputs = map[string]types.DeploymentOutput{}
+	}
+	if err := db.SaveDeploymentMetadata(ctx, &metadata); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		} else {
+			internalctx.GetLogger(ctx).Error("failed to save deployment metadata", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func agentPutVolumeSnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	requestBody, err := JsonBody[api.AgentVolumeSnapshots](w, r)
+	if err != nil {
+		return
+	}
+
+	ctx := r.Context()
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+	if !slices.ContainsFunc(
+		deploymentTarget.Deployments,
+		func(d types.DeploymentWithLatestRevision) bool { return d.ID == requestBody.DeploymentID },
+	) {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	snapshots := make([]types.DeploymentVolumeSnapshot, len(requestBody.Snapshots))
+	for i, snapshot := range requestBody.Snapshots {
+		snapshots[i] = types.DeploymentVolumeSnapshot{
+			ID:                   snapshot.ID,
+			CreatedAt:            snapshot.CreatedAt,
+			DeploymentRevisionID: snapshot.RevisionID,
+			SizeBytes:            snapshot.SizeBytes,
+			Volumes:              snapshot.Volumes,
+		}
+		if snapshots[i].Volumes == nil {
+			snapshots[i].Volumes = []string{}
+		}
+	}
+
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		return db.ReplaceDeploymentVolumeSnapshots(ctx, requestBody.DeploymentID, snapshots)
+	}); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			internalctx.GetLogger(ctx).Error("failed to save volume snapshots", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getAgentDeploymentForRevision returns the deployment of the revision if it belongs to the deployment target of the
+// agent. Otherwise, an error is written to the response.
+func getAgentDeploymentForRevision(
+	w http.ResponseWriter,
+	r *http.Request,
+	revisionID uuid.UUID,
+) (*types.DeploymentWithLatestRevision, bool) {
+	ctx := r.Context()
+	deploymentID, err := db.GetDeploymentIDForRevisionID(ctx, revisionID)
+	if err != nil {
+		if errors.Is(err, apierrors.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			internalctx.GetLogger(ctx).Error("failed to get deployment ID", zap.Error(err))
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return nil, false
+	}
+
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+	if i := slices.IndexFunc(
+		deploymentTarget.Deployments,
+		func(d types.DeploymentWithLatestRevision) bool { return d.ID == deploymentID },
+	); i < 0 {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return nil, false
+	} else {
+		return &deploymentTarget.Deployments[i], true
+	}
 }
 
 func agentPostMetricsHander(w http.ResponseWriter, r *http.Request) {
@@ -518,8 +791,14 @@ func agentPostMetricsHander(w http.ResponseWriter, r *http.Request) {
 	}
 
 	metrics := mapping.DeploymentTargetMetricsRequestToInternal(dt.ID, body)
+	// queued metrics may still contain containers of deployments that were deleted in the meantime
+	metrics.ContainerMetrics = slices.DeleteFunc(metrics.ContainerMetrics, func(m types.DeploymentContainerMetric) bool {
+		return !slices.ContainsFunc(dt.Deployments, func(d types.DeploymentWithLatestRevision) bool {
+			return d.ID == m.DeploymentID
+		})
+	})
 
-	if err := db.CreateDeploymentTargetMetrics(ctx, &metrics); err != nil {
+	if err := db.CreateDeploymentTargetMetrics(ctx, &metrics, body.CreatedAt); err != nil {
 		if errors.Is(err, apierrors.ErrConflict) {
 			http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
 		} else {
@@ -530,15 +809,24 @@ func agentPostMetricsHander(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	go func(ctx context.Context) {
-		asyncCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-		defer cancel()
-
-		if err := notification.SendDeploymentTargetMetricsNotifications(asyncCtx, *dt, previousMetrics, metrics); err != nil {
-			sentry.GetHubFromContext(asyncCtx).CaptureException(err)
-			log.Error("send metrics alerts failed", zap.Error(err))
-		}
-	}(context.WithoutCancel(ctx))
+	if notify, err := notification.ShouldNotifyDeploymentTargetMetrics(ctx, *dt, previousMetrics, metrics); err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to check metrics alerts", zap.Error(err))
+	} else if !notify {
+		// nothing to notify about
+	} else if _, err := jobs.Enqueue(
+		ctx,
+		notification.DeploymentTargetMetricsTaskType,
+		notification.DeploymentTargetMetricsTask{
+			DeploymentTargetID: dt.ID,
+			PreviousMetrics:    previousMetrics,
+			CurrentMetrics:     metrics,
+		},
+		jobs.WithTaskOrganization(dt.OrganizationID),
+	); err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to enqueue metrics alerts", zap.Error(err))
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -583,7 +871,11 @@ func agentManifestHandler() http.HandlerFunc {
 			log.Error("could not get org for deployment target", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
-		} else if manifest, err := agentmanifest.Get(ctx, *deploymentTarget, *org, nil); err != nil {
+		} else if network, err := getAgentNetworkForManifest(ctx, deploymentTarget.ID); err != nil {
+			log.Error("could not get agent network settings for deployment target", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		} else if manifest, err := agentmanifest.Get(ctx, *deploymentTarget, *org, network, nil); err != nil {
 			log.Error("could not get agent manifest", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func getDeploymentTargetAgentNetworkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+	network, err := db.GetDeploymentTargetAgentNetwork(ctx, deploymentTarget.ID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		// targets without settings connect directly
+		RespondJSON(w, api.DeploymentTargetAgentNetwork{})
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get agent network settings", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.DeploymentTargetAgentNetworkToAPI(*network))
+	}
+}
+
+func putDeploymentTargetAgentNetworkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+
+	request, err := JsonBody[api.DeploymentTargetAgentNetworkRequest](w, r)
+	if err != nil {
+		return
+	}
+
+	// only these manifests render the settings
+	if deploymentTarget.Type != types.DeploymentTypeKubernetes && (deploymentTarget.Type != types.DeploymentTypeDocker ||
+		deploymentTarget.GetContainerRuntime() == types.ContainerRuntimeQuadlet) {
+		http.Error(w, "agent network settings are only supported for Docker Compose and Kubernetes deployment targets",
+			http.StatusBadRequest)
+		return
+	}
+
+	network := types.DeploymentTargetAgentNetwork{
+		DeploymentTargetID: deploymentTarget.ID,
+		ProxyURL:           emptyToNil(request.ProxyURL),
+		ProxyUsername:      emptyToNil(request.ProxyUsername),
+		ProxyPassword:      emptyToNil(request.ProxyPassword),
+		NoProxy:            emptyToNil(request.NoProxy),
+		CACertificates:     emptyToNil(request.CACertificates),
+		ClientCertificate:  emptyToNil(request.ClientCertificate),
+		ClientKey:          emptyToNil(request.ClientKey),
+	}
+
+	previous, err := db.GetDeploymentTargetAgentNetwork(ctx, deploymentTarget.ID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		previous = nil
+	} else if err != nil {
+		log.Error("failed to get agent network settings", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// the proxy password and the client key are never returned, so they may be omitted to keep the existing ones
+	validationNetwork := network
+	if previous != nil {
+		if network.KeepsProxyPasswordOf(*previous) {
+			validationNetwork.ProxyPassword = previous.ProxyPassword
+		}
+		if network.ClientKey == nil && network.ClientCertificate != nil &&
+			util.PtrEq(network.ClientCertificate, previous.ClientCertificate) {
+			validationNetwork.ClientKey = previous.ClientKey
+		}
+	}
+	if err := validationNetwork.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SaveDeploymentTargetAgentNetwork(ctx, &network); err != nil {
+		log.Error("failed to save agent network settings", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := mapping.DeploymentTargetAgentNetworkToAPI(network)
+	if previous != nil {
+		auditlog.RecordChange(ctx, mapping.DeploymentTargetAgentNetworkToAPI(*previous), result)
+	} else {
+		auditlog.RecordChange(ctx, nil, result)
+	}
+	RespondJSON(w, result)
+}
+
+func deleteDeploymentTargetAgentNetworkHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+	if err := db.DeleteDeploymentTargetAgentNetwork(ctx, deploymentTarget.ID); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to delete agent network settings", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getAgentNetworkForManifest returns nil if the deployment target has no agent network settings.
+func getAgentNetworkForManifest(
+	ctx context.Context,
+	deploymentTargetID uuid.UUID,
+) (*types.DeploymentTargetAgentNetwork, error) {
+	network, err := db.GetDeploymentTargetAgentNetwork(ctx, deploymentTargetID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		return nil, nil
+	}
+	return network, err
+}
+
+func emptyToNil(s *string) *string {
+	if s != nil && *s == "" {
+		return nil
+	}
+	return s
+}
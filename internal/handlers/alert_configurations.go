@@ -20,19 +20,19 @@ import (
 func AlertConfigurationsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Notifications"))
 
-	r.Use(middleware.ProFeature)
+	r.Use(middleware.ProFeature, middleware.RequirePermission(types.ResourceAlertConfigurations, types.ActionRead))
 
 	r.Get("/", getAlertConfigurationsHandler()).
 		With(option.Description("list all alert configurations")).
 		With(option.Response(http.StatusOK, []types.AlertConfiguration{}))
 
-	r.With(middleware.RequireReadWriteOrAdmin).
+	r.With(middleware.RequirePermission(types.ResourceAlertConfigurations, types.ActionWrite)).
 		Post("/", createAlertConfigurationHandler()).
 		With(option.Description("create a new alert configuration")).
 		With(option.Request(types.AlertConfiguration{})).
 		With(option.Response(http.StatusOK, types.AlertConfiguration{}))
 
-	r.With(middleware.RequireReadWriteOrAdmin).
+	r.With(middleware.RequirePermission(types.ResourceAlertConfigurations, types.ActionWrite)).
 		Route("/{id}", func(r chiopenapi.Router) {
 			type IDRequest struct {
 				ID string `path:"id"`
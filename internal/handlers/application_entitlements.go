@@ -25,11 +25,19 @@ import (
 
 func ApplicationEntitlementsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Applications", "Licensing"))
-	r.Use(middleware.RequireOrgAndRole, middleware.LicensingFeatureFlagEnabledMiddleware)
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceEntitlements, types.ActionRead),
+		middleware.LicensingFeatureFlagEnabledMiddleware,
+	)
 	r.Get("/", getApplicationEntitlements).
 		With(option.Description("List all application entitlements")).
 		With(option.Response(http.StatusOK, []types.ApplicationEntitlement{}))
-	r.With(middleware.RequireVendorOrPartner, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(
+		middleware.RequireVendorOrPartner,
+		middleware.RequirePermission(types.ResourceEntitlements, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
 		Post("/", createApplicationEntitlement).
 		With(option.Description("Create a new application entitlement")).
 		With(option.Request(types.ApplicationEntitlementWithVersions{}))
@@ -42,7 +50,11 @@ func ApplicationEntitlementsRouter(r chiopenapi.Router) {
 			With(option.Description("Get an application entitlement")).
 			With(option.Request(ApplicationEntitlementRequest{})).
 			With(option.Response(http.StatusOK, types.ApplicationEntitlement{}))
-		r.With(middleware.RequireVendorOrPartner, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(
+			middleware.RequireVendorOrPartner,
+			middleware.RequirePermission(types.ResourceEntitlements, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).
 			Group(func(r chiopenapi.Router) {
 				r.Delete("/", deleteApplicationEntitlement).
 					With(option.Description("Delete an application entitlement")).
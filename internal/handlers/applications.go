@@ -6,13 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/contenttype"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/manifestbundle"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/types"
@@ -27,13 +30,17 @@ import (
 
 func ApplicationsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Applications"))
-	r.Use(middleware.RequireOrgAndRole)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceApplications, types.ActionRead))
 
 	r.Get("/", getApplications).
 		With(option.Description("List all applications")).
 		With(option.Response(http.StatusOK, []api.ApplicationResponse{}))
 
-	r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceApplications, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
 		Post("/", createApplication).
 		With(option.Description("Create a new application")).
 		With(option.Response(http.StatusOK, api.ApplicationResponse{}))
@@ -48,7 +55,11 @@ func ApplicationsRouter(r chiopenapi.Router) {
 				With(option.Description("Get an application by ID")).
 				With(option.Request(ApplicationRequest{})).
 				With(option.Response(http.StatusOK, api.ApplicationResponse{}))
-			r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+			r.With(
+				middleware.RequireVendor,
+				middleware.RequirePermission(types.ResourceApplications, types.ActionWrite),
+				middleware.BlockSuperAdmin,
+			).
 				Group(func(r chiopenapi.Router) {
 					r.Delete("/", deleteApplication).
 						With(option.Description("Delete an application")).
@@ -84,7 +95,7 @@ func ApplicationsRouter(r chiopenapi.Router) {
 			r.With(applicationMiddleware).
 				Group(func(r chiopenapi.Router) {
 					r.With(middleware.RequireVendor).
-						With(middleware.RequireReadWriteOrAdmin).
+						With(middleware.RequirePermission(types.ResourceApplications, types.ActionWrite)).
 						With(middleware.BlockSuperAdmin).
 						Post("/", createApplicationVersion).
 						With(option.Description("Create a new application version")).
@@ -105,7 +116,7 @@ func ApplicationsRouter(r chiopenapi.Router) {
 					With(option.Request(ApplicationVersionRequest{})).
 					With(option.Response(http.StatusOK, types.ApplicationVersion{}))
 				r.With(middleware.RequireVendor).
-					With(middleware.RequireReadWriteOrAdmin).
+					With(middleware.RequirePermission(types.ResourceApplications, types.ActionWrite)).
 					With(middleware.BlockSuperAdmin).
 					With(applicationMiddleware).
 					Put("/", updateApplicationVersion).
@@ -127,10 +138,18 @@ func ApplicationsRouter(r chiopenapi.Router) {
 					With(option.Description("Get application version values file")).
 					With(option.Request(ApplicationVersionRequest{})).
 					With(option.Response(http.StatusOK, map[string]any{}, option.ContentType("application/yaml")))
+				r.Get("/unit-file", getApplicationVersionUnitFile).
+					With(option.Description("Get application version systemd unit file")).
+					With(option.Request(ApplicationVersionRequest{})).
+					With(option.Response(http.StatusOK, nil, option.ContentType("text/plain")))
 				r.Get("/resources", getApplicationVersionResources).
 					With(option.Description("Get application version resources")).
 					With(option.Request(ApplicationVersionRequest{})).
 					With(option.Response(http.StatusOK, []types.ApplicationVersionResource{}))
+				r.Get("/health-rules", getApplicationVersionHealthRules).
+					With(option.Description("Get application version health rules")).
+					With(option.Request(ApplicationVersionRequest{})).
+					With(option.Response(http.StatusOK, []types.ApplicationVersionHealthRule{}))
 			})
 		})
 	})
@@ -360,6 +379,10 @@ func createApplicationVersion(w http.ResponseWriter, r *http.Request) {
 
 	application := internalctx.GetApplication(ctx)
 	applicationVersion.ApplicationID = application.ID
+	if application.Type == types.DeploymentTypeKubernetes && applicationVersion.SourceType == nil {
+		// clients that do not know about kustomize versions create Helm chart versions
+		applicationVersion.SourceType = new(types.KubernetesSourceTypeHelm)
+	}
 
 	if application.Type == types.DeploymentTypeDocker {
 		if data, ok := readMultipartFile(w, r, "composefile"); !ok {
@@ -376,6 +399,41 @@ func createApplicationVersion(w http.ResponseWriter, r *http.Request) {
 		} else {
 			applicationVersion.TemplateFileData = data
 		}
+	} else if applicationVersion.IsKustomize() {
+		if data, ok := readMultipartFileWithMediaType(w, r, "manifestfile", contenttype.IsYamlOrGzip); !ok {
+			return
+		} else if data != nil {
+			applicationVersion.ManifestBundleData = data
+			if err := manifestbundle.Validate(data); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if data, ok := readMultipartFile(w, r, "templatefile"); !ok {
+			return
+		} else {
+			applicationVersion.TemplateFileData = data
+		}
+	} else if application.Type == types.DeploymentTypeSystemd {
+		if data, ok := readMultipartFileWithMediaType(w, r, "unitfile", contenttype.IsPlainText); !ok {
+			return
+		} else {
+			applicationVersion.UnitFileData = data
+		}
+		if data, ok := readMultipartFile(w, r, "valuesfile"); !ok {
+			return
+		} else {
+			applicationVersion.ValuesFileData = data
+			if _, err := applicationVersion.ParsedValuesFile(); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if data, ok := readMultipartFile(w, r, "templatefile"); !ok {
+			return
+		} else {
+			applicationVersion.TemplateFileData = data
+		}
 	} else {
 		if data, ok := readMultipartFile(w, r, "valuesfile"); !ok {
 			return
@@ -402,6 +460,7 @@ func createApplicationVersion(w http.ResponseWriter, r *http.Request) {
 	}
 
 	resources := applicationVersion.Resources
+	healthRules := applicationVersion.HealthRules
 	if err := db.RunTx(ctx, func(ctx context.Context) error {
 		if err := db.CreateApplicationVersion(ctx, &applicationVersion); err != nil {
 			return err
@@ -409,6 +468,9 @@ func createApplicationVersion(w http.ResponseWriter, r *http.Request) {
 		if err := db.CreateApplicationVersionResources(ctx, applicationVersion.ID, resources); err != nil {
 			return err
 		}
+		if err := db.CreateApplicationVersionHealthRules(ctx, applicationVersion.ID, healthRules); err != nil {
+			return err
+		}
 		return nil
 	}); err != nil {
 		if errors.Is(err, apierrors.ErrNotFound) {
@@ -481,6 +543,9 @@ var (
 	getApplicationVersionTemplateFile = getApplicationVersionFileHandler(func(av types.ApplicationVersion) []byte {
 		return av.TemplateFileData
 	})
+	getApplicationVersionUnitFile = getApplicationVersionFileHandler(func(av types.ApplicationVersion) []byte {
+		return av.UnitFileData
+	})
 )
 
 func getApplicationVersionFileHandler(fileAccessor func(types.ApplicationVersion) []byte) http.HandlerFunc {
@@ -535,6 +600,26 @@ func getApplicationVersionResources(w http.ResponseWriter, r *http.Request) {
 	RespondJSON(w, resources)
 }
 
+func getApplicationVersionHealthRules(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	applicationVersionID, err := uuid.Parse(r.PathValue("applicationVersionId"))
+	if err != nil || !slices.ContainsFunc(internalctx.GetApplication(ctx).Versions,
+		func(v types.ApplicationVersion) bool { return v.ID == applicationVersionID }) {
+		http.NotFound(w, r)
+		return
+	}
+
+	rules, err := db.GetApplicationVersionHealthRules(ctx, applicationVersionID)
+	if err != nil {
+		log.Error("failed to get application version health rules", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	RespondJSON(w, rules)
+}
+
 func deleteApplication(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := internalctx.GetLogger(ctx)
@@ -22,12 +22,19 @@ import (
 
 func ArtifactEntitlementsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Artifacts", "Licensing"))
-	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendorOrPartner,
-		middleware.LicensingFeatureFlagEnabledMiddleware)
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceEntitlements, types.ActionRead),
+		middleware.RequireVendorOrPartner,
+		middleware.LicensingFeatureFlagEnabledMiddleware,
+	)
 	r.Get("/", getArtifactEntitlements).
 		With(option.Description("List all artifact entitlements")).
 		With(option.Response(http.StatusOK, []types.ArtifactEntitlement{}))
-	r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequirePermission(types.ResourceEntitlements, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).Group(func(r chiopenapi.Router) {
 		r.Post("/", createArtifactEntitlement).
 			With(option.Description("Create a new artifact entitlement")).
 			With(option.Request(types.ArtifactEntitlement{})).
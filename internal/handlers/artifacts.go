@@ -4,13 +4,16 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/jobs"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/registry/upstream"
@@ -28,11 +31,15 @@ type UpstreamTagSyncer interface {
 
 func ArtifactsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Artifacts"))
-	r.Use(middleware.RequireOrgAndRole)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceArtifacts, types.ActionRead))
 	r.Get("/", getArtifacts).
 		With(option.Description("List all artifacts")).
 		With(option.Response(http.StatusOK, []api.ArtifactsResponse{}))
-	r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceArtifacts, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
 		Post("/", createArtifactHandler()).
 		With(option.Description("Create an artifact")).
 		With(option.Request(api.CreateArtifactRequest{})).
@@ -46,7 +53,12 @@ func ArtifactsRouter(r chiopenapi.Router) {
 			With(option.Description("Get an artifact by ID")).
 			With(option.Request(ArtifactRequest{})).
 			With(option.Response(http.StatusOK, []api.ArtifactResponse{}))
-		r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(
+			middleware.RequireVendor,
+			middleware.RequirePermission(types.ResourceArtifacts, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+			requireArtifactScope(types.ActionWrite),
+		).
 			Group(func(r chiopenapi.Router) {
 				r.Patch("/image", patchImageArtifactHandler).
 					With(option.Description("Update artifact image")).
@@ -62,10 +74,11 @@ func ArtifactsRouter(r chiopenapi.Router) {
 						api.PatchArtifactUpstreamRequest
 					}{})).
 					With(option.Response(http.StatusOK, api.ArtifactResponse{}))
-				r.Post("/sync", syncArtifactHandler()).
-					With(option.Description("Trigger upstream sync for a pull-through artifact")).
+				r.Post("/sync", syncArtifactHandler).
+					With(option.Description("Trigger upstream sync for a pull-through artifact. The sync runs in the " +
+						"background, follow its progress with the returned task")).
 					With(option.Request(ArtifactRequest{})).
-					With(option.Response(http.StatusOK, api.ArtifactResponse{}))
+					With(option.Response(http.StatusAccepted, api.Task{}))
 				r.Delete("/", deleteArtifactHandler).
 					With(option.Description("Delete an artifact")).
 					With(option.Request(ArtifactRequest{}))
@@ -93,6 +106,10 @@ func createArtifactHandler() http.HandlerFunc {
 			http.Error(w, "name is required", http.StatusBadRequest)
 			return
 		}
+		if !authentication.CurrentAccessTokenScopes().AllowsArtifact(body.Name, types.ActionWrite) {
+			http.Error(w, "access token scopes do not allow creating this artifact", http.StatusForbidden)
+			return
+		}
 		if body.UpstreamURL != nil && strings.TrimSpace(*body.UpstreamURL) == "" {
 			http.Error(w, "upstreamUrl must not be empty", http.StatusBadRequest)
 			return
@@ -142,34 +159,28 @@ func createArtifactHandler() http.HandlerFunc {
 	}
 }
 
-func syncArtifactHandler() http.HandlerFunc {
-	syncer := new(upstream.Syncer)
-	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
-		log := internalctx.GetLogger(ctx)
-		artifact := internalctx.GetArtifact(ctx)
-
-		if artifact.UpstreamURL == nil {
-			http.Error(w, "artifact is not a pull-through cache", http.StatusBadRequest)
-			return
-		}
-
-		if err := syncer.SyncArtifactTags(ctx, &artifact.Artifact, false); err != nil {
-			log.Error("upstream sync failed", zap.Error(err))
-			sentry.GetHubFromContext(ctx).CaptureException(err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+func syncArtifactHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	artifact := internalctx.GetArtifact(ctx)
 
-		result, err := db.GetArtifactByID(ctx, artifact.OrganizationID, artifact.ID, nil)
-		if err != nil {
-			log.Error("failed to fetch artifact after sync", zap.Error(err))
-			sentry.GetHubFromContext(ctx).CaptureException(err)
-			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-			return
-		}
+	if artifact.UpstreamURL == nil {
+		http.Error(w, "artifact is not a pull-through cache", http.StatusBadRequest)
+		return
+	}
 
-		RespondJSON(w, mapping.ArtifactToAPI(*result))
+	if task, err := jobs.Enqueue(
+		ctx,
+		upstream.SyncArtifactTaskType,
+		upstream.SyncArtifactTask{OrganizationID: artifact.OrganizationID, ArtifactID: artifact.ID},
+		jobs.WithTaskOrganization(artifact.OrganizationID),
+		// a sync that is already pending or running covers this request as well
+		jobs.WithTaskDedupKey(upstream.SyncArtifactTaskType+":"+artifact.ID.String()),
+	); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to enqueue upstream sync", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSONWithStatus(w, http.StatusAccepted, mapping.TaskToAPI(*task))
 	}
 }
 
@@ -200,6 +211,9 @@ func getArtifacts(w http.ResponseWriter, r *http.Request) {
 		sentry.GetHubFromContext(ctx).CaptureException(err)
 		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 	} else {
+		artifacts = slices.DeleteFunc(artifacts, func(artifact types.ArtifactWithDownloads) bool {
+			return !auth.CurrentAccessTokenScopes().AllowsArtifact(artifact.Name, types.ActionRead)
+		})
 		RespondJSON(w, mapping.List(artifacts, mapping.ArtifactsWithDownloadsToAPI))
 	}
 }
@@ -319,6 +333,7 @@ func deleteArtifactTagHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	auditlog.SetResource(ctx, auditlog.ResourceArtifactTags, artifact.Name+":"+tagName)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -416,8 +431,26 @@ func artifactMiddleware(h http.Handler) http.Handler {
 				sentry.GetHubFromContext(ctx).CaptureException(err)
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			}
+		} else if !auth.CurrentAccessTokenScopes().AllowsArtifact(artifact.Name, types.ActionRead) {
+			http.NotFound(w, r)
 		} else {
 			h.ServeHTTP(w, r.WithContext(internalctx.WithArtifact(ctx, artifact)))
 		}
 	})
 }
+
+// requireArtifactScope rejects the request unless the scopes of the access token allow the action on the artifact
+// of the request. It must run after artifactMiddleware.
+func requireArtifactScope(action types.Action) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			scopes := auth.Authentication.Require(ctx).CurrentAccessTokenScopes()
+			if !scopes.AllowsArtifact(internalctx.GetArtifact(ctx).Name, action) {
+				http.Error(w, "access token scopes do not allow this action on the artifact", http.StatusForbidden)
+				return
+			}
+			h.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/subscription"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type auditLogFilterRequest struct {
+	Before                 *time.Time `query:"before"`
+	After                  *time.Time `query:"after"`
+	Count                  *int       `query:"count"`
+	ActorUserAccountID     *string    `query:"actorUserAccountId"`
+	AuthMethod             *string    `query:"authMethod"`
+	CustomerOrganizationID *string    `query:"customerOrganizationId"`
+	PartnerOrganizationID  *string    `query:"partnerOrganizationId"`
+	Resource               *string    `query:"resource"`
+	ResourceID             *string    `query:"resourceId"`
+	Action                 *string    `query:"action"`
+}
+
+func AuditLogRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Audit Log"))
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceAuditLog, types.ActionRead),
+	)
+	r.Get("/", getAuditLogEntriesHandler).
+		With(option.Description("List the audit log entries of the current organization, newest first")).
+		With(option.Request(auditLogFilterRequest{})).
+		With(option.Response(http.StatusOK, []api.AuditLogEntry{}))
+	r.Get("/export", exportAuditLogEntriesHandler).
+		With(option.Description("Export the audit log entries of the current organization as CSV or JSON")).
+		With(option.Request(struct {
+			auditLogFilterRequest
+			Format *string `query:"format" enum:"csv,json"`
+		}{})).
+		With(option.Response(http.StatusOK, []byte{}))
+}
+
+func parseAuditLogFilter(w http.ResponseWriter, r *http.Request) (types.AuditLogFilter, error) {
+	ctx := r.Context()
+	authInfo := auth.Authentication.Require(ctx)
+	filter := types.AuditLogFilter{
+		OrgID:  *authInfo.CurrentOrgID(),
+		Before: time.Now(),
+		Count:  50,
+	}
+	fail := func(err error) (types.AuditLogFilter, error) {
+		respondAuditLogError(w, ctx, err)
+		return filter, err
+	}
+
+	if before, err := QueryParam(r, "before", ParseTimeFunc(time.RFC3339Nano)); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("before must be a valid date"))
+	} else {
+		filter.Before = before
+	}
+
+	if after, err := QueryParam(r, "after", ParseTimeFunc(time.RFC3339Nano)); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("after must be a valid date"))
+	} else {
+		filter.After = after
+	}
+
+	if count, err := QueryParam(r, "count", strconv.Atoi); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("count must be a number"))
+	} else if count < 1 || count > 1000 {
+		return fail(apierrors.NewBadRequest("count must be between 1 and 1000"))
+	} else {
+		filter.Count = count
+	}
+
+	if id, err := QueryParam(r, "actorUserAccountId", uuid.Parse); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("actorUserAccountId must be a valid UUID"))
+	} else {
+		filter.ActorUserAccountID = &id
+	}
+
+	if method := r.FormValue("authMethod"); method == "" {
+		// use default
+	} else if method := types.AuditLogAuthMethod(method); method != types.AuditLogAuthMethodSession &&
+		method != types.AuditLogAuthMethodAccessToken && method != types.AuditLogAuthMethodServiceAccount {
+		return fail(apierrors.NewBadRequest("authMethod must be one of session, access_token or service_account"))
+	} else {
+		filter.AuthMethod = &method
+	}
+
+	if id, err := QueryParam(r, "customerOrganizationId", uuid.Parse); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("customerOrganizationId must be a valid UUID"))
+	} else {
+		filter.CustomerOrganizationID = &id
+	}
+
+	if id, err := QueryParam(r, "partnerOrganizationId", uuid.Parse); errors.Is(err, ErrParamNotDefined) {
+		// use default
+	} else if err != nil {
+		return fail(apierrors.NewBadRequest("partnerOrganizationId must be a valid UUID"))
+	} else {
+		filter.PartnerOrganizationID = &id
+	}
+
+	if resource := r.FormValue("resource"); resource != "" {
+		filter.Resource = &resource
+	}
+
+	if resourceID := r.FormValue("resourceId"); resourceID != "" {
+		filter.ResourceID = &resourceID
+	}
+
+	if action := r.FormValue("action"); action != "" {
+		filter.Action = new(types.AuditLogAction(action))
+	}
+
+	return filter, nil
+}
+
+func respondAuditLogError(w http.ResponseWriter, ctx context.Context, err error) {
+	switch {
+	case errors.Is(err, apierrors.ErrBadRequest):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		internalctx.GetLogger(ctx).Warn("audit log request failed", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+func getAuditLogEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	filter, err := parseAuditLogFilter(w, r)
+	if err != nil {
+		return
+	}
+
+	if entries, err := db.GetAuditLogEntries(ctx, filter); err != nil {
+		respondAuditLogError(w, ctx, err)
+	} else {
+		RespondJSON(w, mapping.List(entries, mapping.AuditLogEntryToAPI))
+	}
+}
+
+func exportAuditLogEntriesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	format := r.FormValue("format")
+	if format == "" {
+		format = "csv"
+	} else if format != "csv" && format != "json" {
+		http.Error(w, "format must be csv or json", http.StatusBadRequest)
+		return
+	}
+
+	filter, err := parseAuditLogFilter(w, r)
+	if err != nil {
+		return
+	}
+
+	filter.Count = int(subscription.MaxAuditLogExportRows)
+
+	entries, err := db.GetAuditLogEntries(ctx, filter)
+	if err != nil {
+		respondAuditLogError(w, ctx, err)
+		return
+	}
+
+	filename := fmt.Sprintf("%s_audit_log.%s", time.Now().Format("2006-01-02"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", filename))
+
+	if format == "json" {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(mapping.List(entries, mapping.AuditLogEntryToAPI)); err != nil {
+			log.Warn("could not write JSON export", zap.Error(err))
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	csvWriter := csv.NewWriter(w)
+	header := []string{
+		"Date", "Actor", "Auth Method", "Customer", "Partner", "Resource", "Resource ID", "Action", "Method", "Path",
+		"Status", "Changes", "Address", "Request ID", "Impersonator",
+	}
+	if err := csvWriter.Write(header); err != nil {
+		log.Warn("could not write CSV header", zap.Error(err))
+		return
+	}
+
+	for _, entry := range entries {
+		changes := ""
+		if len(entry.Changes) > 0 {
+			if data, err := json.Marshal(entry.Changes); err != nil {
+				log.Warn("could not marshal audit log changes", zap.Error(err))
+			} else {
+				changes = string(data)
+			}
+		}
+		statusCode := ""
+		if entry.StatusCode != nil {
+			statusCode = strconv.Itoa(*entry.StatusCode)
+		}
+		if err := csvWriter.Write([]string{
+			entry.CreatedAt.Format(time.RFC3339),
+			util.PtrDerefOrDefault(entry.ActorEmail),
+			string(entry.AuthMethod),
+			uuidPtrToString(entry.CustomerOrganizationID),
+			uuidPtrToString(entry.PartnerOrganizationID),
+			entry.Resource,
+			util.PtrDerefOrDefault(entry.ResourceID),
+			string(entry.Action),
+			util.PtrDerefOrDefault(entry.HTTPMethod),
+			util.PtrDerefOrDefault(entry.Path),
+			statusCode,
+			changes,
+			util.PtrDerefOrDefault(entry.RemoteAddress),
+			util.PtrDerefOrDefault(entry.RequestID),
+			util.PtrDerefOrDefault(entry.ImpersonatorEmail),
+		}); err != nil {
+			log.Warn("could not write CSV row", zap.Error(err))
+			return
+		}
+	}
+
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		log.Warn("CSV flush error", zap.Error(err))
+	}
+}
+
+func uuidPtrToString(id *uuid.UUID) string {
+	if id == nil {
+		return ""
+	}
+	return id.String()
+}
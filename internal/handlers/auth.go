@@ -20,6 +20,7 @@ import (
 	"github.com/distr-sh/distr/internal/mailtemplates"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/security"
+	"github.com/distr-sh/distr/internal/securitypolicy"
 	"github.com/distr-sh/distr/internal/subscription"
 	"github.com/distr-sh/distr/internal/turnstile"
 	"github.com/distr-sh/distr/internal/types"
@@ -48,6 +49,8 @@ func AuthRouter(r chiopenapi.Router) {
 	// The login methods available on a host are part of the host-resolved GET /api/public/v1/portal response.
 	r.Post("/login", authLoginHandler)
 	r.Route("/oidc", AuthOIDCRouter)
+	r.Route("/saml", AuthSAMLRouter)
+	r.Route("/webauthn", AuthWebAuthnRouter)
 	r.Post("/register", authRegisterHandler)
 	r.Post("/reset", authResetPasswordHandler)
 	r.With(
@@ -55,6 +58,8 @@ func AuthRouter(r chiopenapi.Router) {
 		middleware.SetSentryUserFromUserAuth,
 		middleware.RequireEmailVerified,
 		middleware.RequireOrgAndRole,
+		// An impersonation is bound to the organization it was started for.
+		middleware.BlockImpersonation,
 	).Post("/switch-context", authSwitchContextHandler())
 	r.Group(func(r chiopenapi.Router) {
 		r.Use(auth.Authentication.Middleware, middleware.SetSentryUserFromUserAuth)
@@ -84,6 +89,7 @@ func AuthRouter(r chiopenapi.Router) {
 		})
 
 		r.Get("/status", authStatusHandler).With(option.Hidden(true))
+		r.Post("/logout", authLogoutHandler)
 	})
 }
 
@@ -96,6 +102,22 @@ func authStatusHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// authLogoutHandler revokes the session of the login token, so that it can no longer be used even if it was leaked.
+func authLogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	if sessionID := auth.CurrentSessionID(); sessionID == nil {
+		w.WriteHeader(http.StatusNoContent)
+	} else if err := db.DeleteUserSession(
+		ctx, *sessionID, auth.CurrentUserID()); err != nil && !errors.Is(err, apierrors.ErrNotFound) {
+		internalctx.GetLogger(ctx).Error("failed to revoke session", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
 func authVerifyRequestHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := internalctx.GetLogger(ctx)
@@ -172,6 +194,8 @@ func setPasswordAndLogin(w http.ResponseWriter, r *http.Request, password string
 	err := db.RunTx(ctx, func(ctx context.Context) error {
 		if err := userauth.SetUserPassword(ctx, user, password, name); err != nil {
 			return err
+		} else if err := userauth.RevokeSessions(ctx, user.ID, nil); err != nil {
+			return err
 		}
 		if authn.CurrentUserEmailVerified() {
 			if err := userauth.VerifyUserEmail(ctx, user, authn.CurrentUserEmail()); err != nil {
@@ -182,7 +206,8 @@ func setPasswordAndLogin(w http.ResponseWriter, r *http.Request, password string
 			return err
 		}
 		var err error
-		token, err = userauth.GenerateLoginToken(ctx, *user)
+		token, err = userauth.GenerateLoginToken(
+			ctx, *user, types.SessionAuthMethodPassword, userauth.ClientFromRequest(r))
 		return err
 	})
 	if err != nil {
@@ -191,6 +216,8 @@ func setPasswordAndLogin(w http.ResponseWriter, r *http.Request, password string
 		} else if errors.Is(err, subscription.ErrGlobalOrganizationLimitReached) {
 			log.Warn("could not set password, global organization limit reached")
 			http.Error(w, subscription.GlobalOrganizationLimitReachedMessage, http.StatusBadRequest)
+		} else if errors.Is(err, securitypolicy.ErrViolation) {
+			http.Error(w, err.Error(), http.StatusForbidden)
 		} else {
 			log.Error("failed to set password", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
@@ -232,6 +259,16 @@ func authSwitchContextHandler() func(writer http.ResponseWriter, request *http.R
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
+		// The new token belongs to the same session, so revoking the session also revokes it.
+		sessionID := auth.CurrentSessionID()
+		if sessionID == nil {
+			http.Error(w, "only login sessions can switch the organization", http.StatusBadRequest)
+			return
+		}
+		generateToken := authjwt.GenerateDefaultToken
+		if authjwt.IsWebAuthnToken(auth.Token()) {
+			generateToken = authjwt.GenerateWebAuthnToken
+		}
 
 		// Super admins can switch to any organization
 		if auth.IsSuperAdmin() {
@@ -252,12 +289,15 @@ func authSwitchContextHandler() func(writer http.ResponseWriter, request *http.R
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 				return
 			}
-			_, tokenString, err := authjwt.GenerateDefaultToken(*user, types.OrganizationWithUserRole{
+			tokenString, err := userauth.RefreshSessionToken(ctx, *user, types.OrganizationWithUserRole{
 				Organization:           *org,
 				UserRole:               types.UserRole(""), // Super admins don't have a role
 				CustomerOrganizationID: nil,
-			})
-			if err != nil {
+			}, *sessionID, generateToken)
+			if errors.Is(err, apierrors.ErrNotFound) {
+				http.Error(w, "session was revoked", http.StatusUnauthorized)
+				return
+			} else if err != nil {
 				sentry.GetHubFromContext(ctx).CaptureException(err)
 				log.Error("failed to generate token", zap.Error(err))
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -282,12 +322,17 @@ func authSwitchContextHandler() func(writer http.ResponseWriter, request *http.R
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			log.Error("context switch failed", zap.Error(err))
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
-		} else if _, tokenString, err := authjwt.GenerateDefaultToken(user.AsUserAccount(), types.OrganizationWithUserRole{
-			Organization:           org.Organization,
-			UserRole:               user.UserRole,
-			CustomerOrganizationID: user.CustomerOrganizationID,
-			PartnerOrganizationID:  user.PartnerOrganizationID,
-		}); err != nil {
+		} else if tokenString, err := userauth.RefreshSessionToken(ctx, user.AsUserAccount(),
+			types.OrganizationWithUserRole{
+				Organization:           org.Organization,
+				UserRole:               user.UserRole,
+				CustomerOrganizationID: user.CustomerOrganizationID,
+				PartnerOrganizationID:  user.PartnerOrganizationID,
+			}, *sessionID, generateToken); errors.Is(err, apierrors.ErrNotFound) {
+			http.Error(w, "session was revoked", http.StatusUnauthorized)
+		} else if errors.Is(err, securitypolicy.ErrViolation) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else if err != nil {
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			log.Error("failed to generate token", zap.Error(err))
 			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
@@ -323,48 +368,42 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 			return nil
 		}
 
-		if user.MFAEnabled {
-			if request.MFACode == nil {
-				RespondJSON(w, api.AuthLoginResponse{RequiresMFA: true})
-				return nil
-			}
+		generateToken := func(ctx context.Context, user types.UserAccount, client userauth.Client) (string, error) {
+			return userauth.GenerateLoginToken(ctx, user, types.SessionAuthMethodPassword, client)
+		}
 
-			if user.MFASecret == nil {
-				// this can never happen because we guard against it with a db constraint
-				sentry.GetHubFromContext(ctx).CaptureException(errors.New("user has mfa enabled but no secret"))
-				http.Error(w, "MFA configuration error", http.StatusInternalServerError)
-				return nil
+		// With MFA enabled, registered passkeys are a second factor like TOTP. A passkey answering the challenge
+		// makes this a passkey login, which also satisfies a policy that requires passkeys for admins.
+		if user.MFAEnabled {
+			credentials, err := db.GetWebAuthnCredentials(ctx, user.ID)
+			if err != nil {
+				return err
 			}
-
-			valid := totp.Validate(*request.MFACode, *user.MFASecret)
-
-			if !valid {
-				normalized := security.NormalizeRecoveryCode(*request.MFACode)
-				codes, err := db.GetUnusedMFARecoveryCodes(ctx, user.ID)
-				if err != nil {
-					return fmt.Errorf("failed to get recovery codes: %w", err)
-				}
-
-				var matchedCodeID *uuid.UUID
-				for _, code := range codes {
-					if security.VerifyRecoveryCode(normalized, code.CodeSalt, code.CodeHash) {
-						matchedCodeID = &code.ID
-						break
-					}
-				}
-
-				if matchedCodeID == nil {
-					http.Error(w, "invalid MFA code or recovery code", http.StatusUnauthorized)
-					return nil
+			if request.WebAuthnSessionID != nil && len(credentials) > 0 {
+				if ok, err := verifyWebAuthnSecondFactor(ctx, w, *user, credentials, request); err != nil || !ok {
+					return err
 				}
-
-				if err := db.MarkMFARecoveryCodeAsUsed(ctx, *matchedCodeID); err != nil {
+				generateToken = userauth.GenerateWebAuthnLoginToken
+			} else if request.MFACode != nil {
+				if ok, err := verifyMFACode(ctx, w, *user, *request.MFACode); err != nil || !ok {
 					return err
 				}
+			} else {
+				response := api.AuthLoginResponse{RequiresMFA: true}
+				if len(credentials) > 0 {
+					if response.WebAuthn, err = beginWebAuthnSecondFactor(ctx, *user, credentials); err != nil {
+						return err
+					}
+				}
+				RespondJSON(w, response)
+				return nil
 			}
 		}
 
-		if tokenString, err := userauth.GenerateLoginToken(ctx, *user); err != nil {
+		if tokenString, err := generateToken(ctx, *user, userauth.ClientFromRequest(r)); errors.Is(
+			err, securitypolicy.ErrViolation) {
+			return err
+		} else if err != nil {
 			return fmt.Errorf("token creation failed: %w", err)
 		} else if err = db.UpdateUserAccountLastLoggedIn(ctx, user.ID); err != nil {
 			return err
@@ -379,6 +418,9 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, subscription.ErrGlobalOrganizationLimitReached) {
 		log.Warn("user login rejected, global organization limit reached")
 		http.Error(w, subscription.GlobalOrganizationLimitReachedMessage, http.StatusBadRequest)
+	} else if errors.Is(err, securitypolicy.ErrViolation) {
+		log.Info("user login rejected by security policy", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusForbidden)
 	} else if err != nil {
 		sentry.GetHubFromContext(ctx).CaptureException(err)
 		log.Warn("user login failed", zap.Error(err))
@@ -386,6 +428,30 @@ func authLoginHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// verifyMFACode checks code as a TOTP code and then as an unused recovery code, which is marked as used. It writes
+// the error response if the code is invalid.
+func verifyMFACode(ctx context.Context, w http.ResponseWriter, user types.UserAccount, code string) (bool, error) {
+	if user.MFASecret == nil {
+		// this can never happen because we guard against it with a db constraint
+		return false, errors.New("user has mfa enabled but no secret")
+	} else if totp.Validate(code, *user.MFASecret) {
+		return true, nil
+	}
+
+	normalized := security.NormalizeRecoveryCode(code)
+	codes, err := db.GetUnusedMFARecoveryCodes(ctx, user.ID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get recovery codes: %w", err)
+	}
+	for _, code := range codes {
+		if security.VerifyRecoveryCode(normalized, code.CodeSalt, code.CodeHash) {
+			return true, db.MarkMFARecoveryCodeAsUsed(ctx, code.ID)
+		}
+	}
+	http.Error(w, "invalid MFA code or recovery code", http.StatusUnauthorized)
+	return false, nil
+}
+
 func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := internalctx.GetLogger(ctx)
@@ -443,7 +509,8 @@ func authRegisterHandler(w http.ResponseWriter, r *http.Request) {
 					w.WriteHeader(http.StatusInternalServerError)
 				}
 				return err
-			} else if token, err = userauth.GenerateLoginToken(ctx, userAccount); err != nil {
+			} else if token, err = userauth.GenerateLoginToken(
+				ctx, userAccount, types.SessionAuthMethodPassword, userauth.ClientFromRequest(r)); err != nil {
 				sentry.GetHubFromContext(ctx).CaptureException(err)
 				w.WriteHeader(http.StatusInternalServerError)
 				return err
@@ -15,6 +15,7 @@ import (
 	"github.com/distr-sh/distr/internal/env"
 	"github.com/distr-sh/distr/internal/handlerutil"
 	"github.com/distr-sh/distr/internal/oidc"
+	"github.com/distr-sh/distr/internal/securitypolicy"
 	"github.com/distr-sh/distr/internal/subscription"
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/distr-sh/distr/internal/userauth"
@@ -34,6 +35,7 @@ const (
 	redirectToLoginOIDCUserLimit            = "/login?reason=oidc-user-limit"
 	redirectToLoginOIDCOrgLimit             = "/login?reason=oidc-org-limit"
 	redirectToLoginOIDCNotExclusive         = "/login?reason=oidc-account-not-exclusive"
+	redirectToLoginOIDCSecurityPolicy       = "/login?reason=oidc-security-policy"
 )
 
 func AuthOIDCRouter(r chiopenapi.Router) {
@@ -134,7 +136,8 @@ func authLoginOidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		if tokenString, err := userauth.GenerateLoginToken(ctx, *user); err != nil {
+		if tokenString, err := userauth.GenerateLoginToken(
+			ctx, *user, types.SessionAuthMethodOIDC, userauth.ClientFromRequest(r)); err != nil {
 			return fmt.Errorf("token creation failed: %w", err)
 		} else if err = db.UpdateUserAccountLastLoggedIn(ctx, user.ID); err != nil {
 			return err
@@ -146,6 +149,9 @@ func authLoginOidcCallbackHandler(w http.ResponseWriter, r *http.Request) {
 	if errors.Is(err, subscription.ErrGlobalOrganizationLimitReached) {
 		log.Info("rejecting OIDC login, global organization limit reached")
 		http.Redirect(w, r, redirectToLoginOIDCOrgLimit, http.StatusFound)
+	} else if errors.Is(err, securitypolicy.ErrViolation) {
+		log.Info("rejecting OIDC login", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCSecurityPolicy, http.StatusFound)
 	} else if err != nil {
 		sentry.GetHubFromContext(ctx).CaptureException(err)
 		log.Warn("user login failed", zap.Error(err))
@@ -232,7 +238,8 @@ func authLoginCustomOidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 				return err
 			}
 		}
-		tokenString, err := userauth.GenerateLoginTokenForOrganization(ctx, *user, configuration.OrganizationID)
+		tokenString, err := userauth.GenerateLoginTokenForOrganization(
+			ctx, *user, configuration.OrganizationID, userauth.ClientFromRequest(r))
 		if err != nil {
 			return fmt.Errorf("token creation failed: %w", err)
 		}
@@ -244,29 +251,40 @@ func authLoginCustomOidcCallbackHandler(w http.ResponseWriter, r *http.Request)
 			http.StatusFound)
 		return nil
 	})
-	if err != nil {
+	if errors.Is(err, securitypolicy.ErrViolation) {
+		log.Info("rejecting custom OIDC login", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCSecurityPolicy, http.StatusFound)
+	} else if err != nil {
 		sentry.GetHubFromContext(ctx).CaptureException(err)
 		log.Warn("custom OIDC login failed", zap.Error(err))
 		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
 	}
 }
 
-type customOIDCLogin struct {
-	configuration types.CustomOIDCConfiguration
+// customLoginScope is what a login through one of an organization's own identity providers, OIDC or SAML, is
+// bound to.
+type customLoginScope struct {
+	organizationID uuid.UUID
 	// domain is the CustomDomain the provider hangs off. Its customer organization is the scope every
 	// membership check and every provisioned account is bound to.
-	domain      types.CustomDomain
-	redirectURL string
+	domain types.CustomDomain
 }
 
-func (l customOIDCLogin) customerOrgID() *uuid.UUID {
-	return l.domain.CustomerOrganizationID
+func (s customLoginScope) customerOrgID() *uuid.UUID {
+	return s.domain.CustomerOrganizationID
 }
 
 // sharedCustomerPortal reports whether the provider is offered on the vendor's portal domain for all of
-// its customers, where nothing in the OIDC response says which customer a new user would belong to.
-func (l customOIDCLogin) sharedCustomerPortal() bool {
-	return l.domain.Type == types.DomainTypeCustomerPortal && l.domain.CustomerOrganizationID == nil
+// its customers, where nothing in the identity provider's response says which customer a new user would
+// belong to.
+func (s customLoginScope) sharedCustomerPortal() bool {
+	return s.domain.Type == types.DomainTypeCustomerPortal && s.domain.CustomerOrganizationID == nil
+}
+
+type customOIDCLogin struct {
+	customLoginScope
+	configuration types.CustomOIDCConfiguration
+	redirectURL   string
 }
 
 func resolveCustomOIDCConfigurationForHost(
@@ -279,14 +297,6 @@ func resolveCustomOIDCConfigurationForHost(
 	organizationSlug := validation.NormalizeSlug(r.PathValue("organizationSlug"))
 	providerSlug := validation.NormalizeSlug(r.PathValue("providerSlug"))
 
-	host, err := resolvePortalHost(ctx, validation.NormalizeHostname(r.Host))
-	if err != nil {
-		sentry.GetHubFromContext(ctx).CaptureException(err)
-		log.Error("could not resolve host for custom OIDC login", zap.Error(err))
-		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
-		return customOIDCLogin{}, false
-	}
-
 	configuration, err := db.GetCustomOIDCConfigurationBySlug(ctx, organizationSlug, providerSlug)
 	if errors.Is(err, apierrors.ErrNotFound) {
 		http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
@@ -298,26 +308,56 @@ func resolveCustomOIDCConfigurationForHost(
 		return customOIDCLogin{}, false
 	}
 
-	if !configuration.Enabled ||
-		host.customDomainRow == nil || host.customDomainRow.ID != configuration.CustomDomainID {
-		log.Info("rejecting custom OIDC login on foreign host",
-			zap.Any("customOidcConfigurationId", configuration.ID), zap.String("host", r.Host))
-		http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
+	scope, ok := resolveCustomLoginScope(w, r, log.With(zap.Any("customOidcConfigurationId", configuration.ID)),
+		configuration.OrganizationID, configuration.CustomDomainID, configuration.Enabled)
+	if !ok {
 		return customOIDCLogin{}, false
 	}
+	return customOIDCLogin{
+		customLoginScope: scope,
+		configuration:    *configuration,
+		redirectURL:      oidc.CustomRedirectURL(r, organizationSlug, configuration.Slug),
+	}, true
+}
+
+// resolveCustomLoginScope checks that a provider may be used for a login on the host of the request: it must
+// be enabled, be bound to exactly this host, and its organization must still have the feature.
+func resolveCustomLoginScope(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *zap.Logger,
+	organizationID uuid.UUID,
+	customDomainID uuid.UUID,
+	enabled bool,
+) (customLoginScope, bool) {
+	ctx := r.Context()
 
-	organization, err := db.GetOrganizationByID(ctx, configuration.OrganizationID)
+	host, err := resolvePortalHost(ctx, validation.NormalizeHostname(r.Host))
 	if err != nil {
 		sentry.GetHubFromContext(ctx).CaptureException(err)
-		log.Error("could not get organization of custom OIDC configuration", zap.Error(err))
+		log.Error("could not resolve host for custom login", zap.Error(err))
 		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
-		return customOIDCLogin{}, false
+		return customLoginScope{}, false
+	}
+
+	if !enabled || host.customDomainRow == nil || host.customDomainRow.ID != customDomainID {
+		log.Info("rejecting custom login on foreign host", zap.String("host", r.Host))
+		http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
+		return customLoginScope{}, false
+	}
+
+	organization, err := db.GetOrganizationByID(ctx, organizationID)
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("could not get organization of custom login", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return customLoginScope{}, false
 	}
 	if !organization.HasFeature(types.FeatureCustomOidcProviders) {
-		log.Info("rejecting custom OIDC login without the feature",
+		log.Info("rejecting custom login without the feature",
 			zap.Any("organizationId", organization.ID))
 		http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
-		return customOIDCLogin{}, false
+		return customLoginScope{}, false
 	}
 
 	// A customer's provider stops working the moment the vendor revokes the feature, so a login is
@@ -327,23 +367,19 @@ func resolveCustomOIDCConfigurationForHost(
 		customerOrganization, err := db.GetCustomerOrganizationByID(ctx, *customerOrgID)
 		if err != nil {
 			sentry.GetHubFromContext(ctx).CaptureException(err)
-			log.Error("could not get customer organization of custom OIDC configuration", zap.Error(err))
+			log.Error("could not get customer organization of custom login", zap.Error(err))
 			http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
-			return customOIDCLogin{}, false
+			return customLoginScope{}, false
 		}
 		if !customerOrganization.HasFeature(types.CustomerOrganizationFeatureOidcProviders) {
-			log.Info("rejecting custom OIDC login without the customer feature",
+			log.Info("rejecting custom login without the customer feature",
 				zap.Any("customerOrganizationId", *customerOrgID))
 			http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
-			return customOIDCLogin{}, false
+			return customLoginScope{}, false
 		}
 	}
 
-	return customOIDCLogin{
-		configuration: *configuration,
-		domain:        *host.customDomainRow,
-		redirectURL:   oidc.CustomRedirectURL(r, organizationSlug, configuration.Slug),
-	}, true
+	return customLoginScope{organizationID: organizationID, domain: *host.customDomainRow}, true
 }
 
 func resolveCustomOIDCUser(
@@ -356,7 +392,7 @@ func resolveCustomOIDCUser(
 	user, existingIdentity, err := db.GetUserAccountWithOIDCIdentity(
 		ctx, &configuration.ID, identity.Issuer, identity.Subject)
 	if err == nil {
-		if failure, err := checkCustomOIDCLoginAllowed(ctx, *user, login); err != nil {
+		if failure, err := checkCustomLoginAllowed(ctx, *user, login.customLoginScope); err != nil {
 			return nil, "", err
 		} else if failure != "" {
 			return nil, failure, nil
@@ -379,7 +415,7 @@ func resolveCustomOIDCUser(
 		return nil, "", err
 	}
 
-	if failure, err := checkCustomOIDCLoginAllowed(ctx, *user, login); err != nil {
+	if failure, err := checkCustomLoginAllowed(ctx, *user, login.customLoginScope); err != nil {
 		return nil, "", err
 	} else if failure != "" {
 		return nil, failure, nil
@@ -389,25 +425,24 @@ func resolveCustomOIDCUser(
 	return user, "", linkCustomOIDCIdentity(ctx, configuration, identity, *user)
 }
 
-// checkCustomOIDCLoginAllowed returns the login redirect to answer with when the user must not sign in
+// checkCustomLoginAllowed returns the login redirect to answer with when the user must not sign in
 // through the organization's identity provider, or an empty string when the login may proceed. Membership
 // is required for every login, so an identity that outlives the membership does not keep access alive.
 //
 // A provider bound to one customer only ever matches a membership in that customer. Customer memberships
 // all live on the vendor organization, so without the customer scope every customer's provider would
 // authenticate every other customer's users.
-func checkCustomOIDCLoginAllowed(
+func checkCustomLoginAllowed(
 	ctx context.Context,
 	user types.UserAccount,
-	login customOIDCLogin,
+	scope customLoginScope,
 ) (string, error) {
-	organizationID := login.configuration.OrganizationID
-	if exclusive, err := isExclusiveToOrganization(ctx, user, organizationID); err != nil {
+	if exclusive, err := isExclusiveToOrganization(ctx, user, scope.organizationID); err != nil {
 		return "", err
 	} else if !exclusive {
 		return redirectToLoginOIDCNotExclusive, nil
 	}
-	if member, err := isOrganizationMember(ctx, user, login); err != nil {
+	if member, err := isOrganizationMember(ctx, user, scope); err != nil {
 		return "", err
 	} else if !member {
 		return redirectToLoginOIDCNoAccount, nil
@@ -425,23 +460,38 @@ func provisionCustomOIDCUser(
 	if !configuration.CreateUnknownUsers {
 		return nil, redirectToLoginOIDCNoAccount, nil
 	}
-	// Refused at configuration time as well; this is the guard that survives a domain being re-pointed.
-	if login.sharedCustomerPortal() {
-		log.Info("rejecting custom OIDC provisioning on the shared customer portal domain",
-			zap.Any("customOidcConfigurationId", configuration.ID))
+	if !emailDomainAllowed(configuration.AllowedEmailDomains, identity.Email) {
+		log.Info("rejecting custom OIDC login for a disallowed email domain")
 		return nil, redirectToLoginOIDCNoAccount, nil
 	}
-	if !emailDomainAllowed(configuration, identity.Email) {
-		log.Info("rejecting custom OIDC login for a disallowed email domain")
+	user := types.UserAccount{Email: identity.Email}
+	if identity.EmailVerified {
+		user.EmailVerifiedAt = new(time.Now())
+	}
+	return provisionCustomLoginUser(ctx, log, login.customLoginScope, user, configuration.DefaultUserRole)
+}
+
+// provisionCustomLoginUser creates the account of a user that signs in through an identity provider of the
+// organization for the first time, with a membership in the scope of the provider.
+func provisionCustomLoginUser(
+	ctx context.Context,
+	log *zap.Logger,
+	scope customLoginScope,
+	user types.UserAccount,
+	userRole types.UserRole,
+) (*types.UserAccount, string, error) {
+	// Refused at configuration time as well; this is the guard that survives a domain being re-pointed.
+	if scope.sharedCustomerPortal() {
+		log.Info("rejecting custom login provisioning on the shared customer portal domain")
 		return nil, redirectToLoginOIDCNoAccount, nil
 	}
 
-	organization, err := db.GetOrganizationByID(ctx, configuration.OrganizationID)
+	organization, err := db.GetOrganizationByID(ctx, scope.organizationID)
 	if err != nil {
 		return nil, "", err
 	}
 	// A customer user counts against the per-customer limit, not the vendor's billable seats.
-	customerOrgID := login.customerOrgID()
+	customerOrgID := scope.customerOrgID()
 	var limitReached bool
 	if customerOrgID != nil {
 		customerOrganization, err := db.GetCustomerOrganizationByID(ctx, *customerOrgID)
@@ -456,23 +506,19 @@ func provisionCustomOIDCUser(
 		return nil, "", err
 	}
 	if limitReached {
-		log.Info("rejecting custom OIDC login, user account limit reached",
+		log.Info("rejecting custom login, user account limit reached",
 			zap.Any("organizationId", organization.ID), zap.Any("customerOrganizationId", customerOrgID))
 		return nil, redirectToLoginOIDCUserLimit, nil
 	}
 
-	user := types.UserAccount{Email: identity.Email}
-	if identity.EmailVerified {
-		user.EmailVerifiedAt = new(time.Now())
-	}
 	if err := db.CreateUserAccount(ctx, &user); err != nil {
 		return nil, "", err
 	}
 	if err := db.CreateUserAccountOrganizationAssignment(
-		ctx, user.ID, configuration.OrganizationID, configuration.DefaultUserRole, customerOrgID, nil); err != nil {
+		ctx, user.ID, scope.organizationID, userRole, customerOrgID, nil); err != nil {
 		return nil, "", err
 	}
-	log.Info("provisioned user account for custom OIDC identity", zap.Any("userId", user.ID))
+	log.Info("provisioned user account for custom login identity", zap.Any("userId", user.ID))
 	return &user, "", nil
 }
 
@@ -506,26 +552,25 @@ func isExclusiveToOrganization(ctx context.Context, user types.UserAccount, orgI
 
 // isOrganizationMember also tells an app-domain membership apart from a shared-portal one:
 // GetUserAccountWithRole's customerOrgID leaves the customer scope unfiltered when nil, which both pass.
-func isOrganizationMember(ctx context.Context, user types.UserAccount, login customOIDCLogin) (bool, error) {
-	member, err := db.GetUserAccountWithRole(
-		ctx, user.ID, login.configuration.OrganizationID, login.customerOrgID(), nil)
+func isOrganizationMember(ctx context.Context, user types.UserAccount, scope customLoginScope) (bool, error) {
+	member, err := db.GetUserAccountWithRole(ctx, user.ID, scope.organizationID, scope.customerOrgID(), nil)
 	if errors.Is(err, apierrors.ErrNotFound) {
 		return false, nil
 	} else if err != nil {
 		return false, err
 	}
-	if login.customerOrgID() != nil {
+	if scope.customerOrgID() != nil {
 		return true, nil
 	}
-	if login.sharedCustomerPortal() {
+	if scope.sharedCustomerPortal() {
 		return member.CustomerOrganizationID != nil, nil
 	}
 	return member.CustomerOrganizationID == nil, nil
 }
 
-func emailDomainAllowed(configuration types.CustomOIDCConfiguration, email string) bool {
+func emailDomainAllowed(allowedEmailDomains []string, email string) bool {
 	_, domain, found := strings.Cut(strings.ToLower(email), "@")
-	return found && slices.Contains(configuration.AllowedEmailDomains, domain)
+	return found && slices.Contains(allowedEmailDomains, domain)
 }
 
 // resolveOIDCUser returns the user account for the given OIDC identity, linking or registering it as needed.
@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/handlerutil"
+	"github.com/distr-sh/distr/internal/saml"
+	"github.com/distr-sh/distr/internal/securitypolicy"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/getsentry/sentry-go"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+// AuthSAMLRouter serves the service provider side of the SAML providers of an organization. A login starts with
+// the HTTP-Redirect binding and the identity provider answers with the HTTP-POST binding.
+func AuthSAMLRouter(r chiopenapi.Router) {
+	type SAMLRequest struct {
+		OrganizationSlug string `path:"organizationSlug"`
+		ProviderSlug     string `path:"providerSlug"`
+	}
+
+	r.Get("/{organizationSlug}/{providerSlug}", authLoginSAMLHandler).
+		With(option.Request(SAMLRequest{}))
+	r.Post("/{organizationSlug}/{providerSlug}/acs", authLoginSAMLACSHandler).
+		With(option.Request(SAMLRequest{}))
+	r.Get("/{organizationSlug}/{providerSlug}/metadata", authSAMLMetadataHandler).
+		With(option.Request(SAMLRequest{}))
+}
+
+func authLoginSAMLHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	login, ok := resolveCustomSAMLConfigurationForHost(w, r)
+	if !ok {
+		return
+	}
+	log = log.With(zap.Any("customSamlConfigurationId", login.configuration.ID))
+
+	redirectURL, requestID, err := login.serviceProvider.AuthnRequestURL()
+	if err != nil {
+		log.Warn("could not create SAML authentication request", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return
+	}
+	if err := db.CreateSAMLRequest(ctx, requestID, login.configuration.ID); err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("SAML request creation failed", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return
+	}
+	http.Redirect(w, r, redirectURL, http.StatusFound)
+}
+
+func authLoginSAMLACSHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	login, ok := resolveCustomSAMLConfigurationForHost(w, r)
+	if !ok {
+		return
+	}
+	configuration := login.configuration
+	log = log.With(zap.Any("customSamlConfigurationId", configuration.ID))
+
+	if err := r.ParseForm(); err != nil {
+		log.Warn("could not parse SAML response form", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return
+	}
+	samlResponse := r.PostForm.Get("SAMLResponse")
+	if samlResponse == "" {
+		log.Warn("SAML response is missing, the artifact binding is not supported")
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return
+	}
+
+	requestID, ok := consumeSAMLRequest(w, r, log, configuration, r.PostForm.Get("RelayState"))
+	if !ok {
+		return
+	}
+	identity, err := login.serviceProvider.IdentityForResponse(samlResponse, requestID)
+	if err != nil {
+		log.Warn("SAML identity extraction failed", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return
+	}
+
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		user, failure, err := resolveCustomSAMLUser(ctx, log, login, identity)
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			log.Info("SAML login refused", zap.String("redirect", failure))
+			http.Redirect(w, r, failure, http.StatusFound)
+			return nil
+		}
+		log = log.With(zap.Any("userId", user.ID))
+
+		if user.EmailVerifiedAt == nil {
+			if err := db.UpdateUserAccountEmailVerified(ctx, user); err != nil {
+				return err
+			}
+		}
+		tokenString, err := userauth.GenerateLoginTokenForOrganization(
+			ctx, *user, configuration.OrganizationID, userauth.ClientFromRequest(r))
+		if err != nil {
+			return fmt.Errorf("token creation failed: %w", err)
+		}
+		if err := db.UpdateUserAccountLastLoggedIn(ctx, user.ID); err != nil {
+			return err
+		}
+		// The response is posted by the browser, so the redirect turns it back into a GET of the app.
+		http.Redirect(w, r,
+			fmt.Sprintf("%v/login?jwt=%v", handlerutil.GetRequestSchemeAndHost(r), tokenString),
+			http.StatusSeeOther)
+		return nil
+	})
+	if errors.Is(err, securitypolicy.ErrViolation) {
+		log.Info("rejecting SAML login", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCSecurityPolicy, http.StatusFound)
+	} else if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Warn("SAML login failed", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+	}
+}
+
+func authSAMLMetadataHandler(w http.ResponseWriter, r *http.Request) {
+	login, ok := resolveCustomSAMLConfigurationForHost(w, r)
+	if !ok {
+		return
+	}
+	if metadata, err := login.serviceProvider.Metadata(); err != nil {
+		internalctx.GetLogger(r.Context()).Error("could not create SAML metadata", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	} else {
+		w.Header().Set("Content-Type", "application/samlmetadata+xml")
+		_, _ = w.Write(metadata)
+	}
+}
+
+type customSAMLLogin struct {
+	customLoginScope
+	configuration   types.CustomSAMLConfiguration
+	serviceProvider *saml.ServiceProvider
+}
+
+func resolveCustomSAMLConfigurationForHost(w http.ResponseWriter, r *http.Request) (customSAMLLogin, bool) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	organizationSlug := validation.NormalizeSlug(r.PathValue("organizationSlug"))
+	providerSlug := validation.NormalizeSlug(r.PathValue("providerSlug"))
+
+	configuration, err := db.GetCustomSAMLConfigurationBySlug(ctx, organizationSlug, providerSlug)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.Redirect(w, r, redirectToLoginOIDCUnavailable, http.StatusFound)
+		return customSAMLLogin{}, false
+	} else if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("could not get custom SAML configuration", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return customSAMLLogin{}, false
+	}
+	log = log.With(zap.Any("customSamlConfigurationId", configuration.ID))
+
+	scope, ok := resolveCustomLoginScope(
+		w, r, log, configuration.OrganizationID, configuration.CustomDomainID, configuration.Enabled)
+	if !ok {
+		return customSAMLLogin{}, false
+	}
+
+	// The service provider is built on the domain the configuration is bound to rather than the raw host of
+	// the request, so its entity ID and ACS URL are exactly the ones shown to the administrator.
+	serviceProvider, err := saml.ServiceProviderForConfiguration(
+		*configuration, saml.BaseURL(scope.domain.Domain), organizationSlug)
+	if err != nil {
+		log.Warn("could not build SAML service provider", zap.Error(err))
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return customSAMLLogin{}, false
+	}
+	return customSAMLLogin{
+		customLoginScope: scope,
+		configuration:    *configuration,
+		serviceProvider:  serviceProvider,
+	}, true
+}
+
+// consumeSAMLRequest returns the ID of the authentication request a response answers, as identified by the
+// RelayState, or an empty string for an IdP-initiated login that does not answer a request of ours.
+func consumeSAMLRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	log *zap.Logger,
+	configuration types.CustomSAMLConfiguration,
+	relayState string,
+) (string, bool) {
+	ctx := r.Context()
+	if relayState != "" {
+		request, err := db.DeleteSAMLRequest(ctx, relayState)
+		if err == nil {
+			if request.Expired() || request.CustomSAMLConfigurationID != configuration.ID {
+				log.Warn("rejecting SAML response to an expired or foreign request")
+				http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+				return "", false
+			}
+			return request.ID, true
+		} else if !errors.Is(err, apierrors.ErrNotFound) {
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			log.Error("could not verify SAML request", zap.Error(err))
+			http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+			return "", false
+		}
+	}
+	if !configuration.AllowIDPInitiated {
+		log.Warn("rejecting SAML response without a matching request")
+		http.Redirect(w, r, redirectToLoginOIDCFailed, http.StatusFound)
+		return "", false
+	}
+	return "", true
+}
+
+func resolveCustomSAMLUser(
+	ctx context.Context,
+	log *zap.Logger,
+	login customSAMLLogin,
+	identity saml.Identity,
+) (*types.UserAccount, string, error) {
+	configuration := login.configuration
+	user, existingIdentity, err := db.GetUserAccountWithSAMLIdentity(
+		ctx, configuration.ID, identity.Issuer, identity.Subject)
+	if err == nil {
+		if failure, err := checkCustomLoginAllowed(ctx, *user, login.customLoginScope); err != nil {
+			return nil, "", err
+		} else if failure != "" {
+			return nil, failure, nil
+		}
+		if err := syncCustomSAMLUserRole(ctx, login, identity, *user); err != nil {
+			return nil, "", err
+		}
+		return user, "", db.UpdateUserAccountOIDCIdentityOnLogin(ctx, existingIdentity.ID, new(identity.Email))
+	} else if !errors.Is(err, apierrors.ErrNotFound) {
+		return nil, "", err
+	}
+
+	user, err = db.GetUserAccountByEmail(ctx, identity.Email)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		if user, failure, err := provisionCustomSAMLUser(ctx, log, login, identity); err != nil {
+			return nil, "", err
+		} else if user == nil {
+			return nil, failure, nil
+		} else {
+			return user, "", linkCustomSAMLIdentity(ctx, configuration, identity, *user)
+		}
+	} else if err != nil {
+		return nil, "", err
+	}
+
+	if failure, err := checkCustomLoginAllowed(ctx, *user, login.customLoginScope); err != nil {
+		return nil, "", err
+	} else if failure != "" {
+		return nil, failure, nil
+	}
+	if err := syncCustomSAMLUserRole(ctx, login, identity, *user); err != nil {
+		return nil, "", err
+	}
+	log.Info("linking SAML identity to existing user account matched by email", zap.Any("userId", user.ID))
+	return user, "", linkCustomSAMLIdentity(ctx, configuration, identity, *user)
+}
+
+func provisionCustomSAMLUser(
+	ctx context.Context,
+	log *zap.Logger,
+	login customSAMLLogin,
+	identity saml.Identity,
+) (*types.UserAccount, string, error) {
+	configuration := login.configuration
+	if !configuration.CreateUnknownUsers {
+		return nil, redirectToLoginOIDCNoAccount, nil
+	}
+	if !emailDomainAllowed(configuration.AllowedEmailDomains, identity.Email) {
+		log.Info("rejecting SAML login for a disallowed email domain")
+		return nil, redirectToLoginOIDCNoAccount, nil
+	}
+	userRole := configuration.DefaultUserRole
+	if identity.Role != nil {
+		userRole = *identity.Role
+	}
+	// Assertions are only accepted with a valid signature of the identity provider, which vouches for the
+	// email address the same way an OIDC provider does with email_verified.
+	user := types.UserAccount{Email: identity.Email, Name: identity.Name, EmailVerifiedAt: new(time.Now())}
+	return provisionCustomLoginUser(ctx, log, login.customLoginScope, user, userRole)
+}
+
+// syncCustomSAMLUserRole applies the role mapped from the assertion on every login, so that a change of the
+// user's groups at the identity provider takes effect without an administrator. A user without a mapped role
+// keeps the role it has.
+func syncCustomSAMLUserRole(
+	ctx context.Context,
+	login customSAMLLogin,
+	identity saml.Identity,
+	user types.UserAccount,
+) error {
+	if identity.Role == nil {
+		return nil
+	}
+	member, err := db.GetUserAccountWithRole(ctx, user.ID, login.organizationID, login.customerOrgID(), nil)
+	if err != nil {
+		return err
+	} else if member.UserRole == *identity.Role {
+		return nil
+	}
+	return db.UpdateUserAccountOrganizationAssignment(
+		ctx, user.ID, login.organizationID, *identity.Role, member.CustomerOrganizationID, member.PartnerOrganizationID)
+}
+
+func linkCustomSAMLIdentity(
+	ctx context.Context,
+	configuration types.CustomSAMLConfiguration,
+	identity saml.Identity,
+	user types.UserAccount,
+) error {
+	newIdentity := types.UserAccountOIDCIdentity{
+		UserAccountID:             user.ID,
+		Provider:                  types.OIDCProviderSAML,
+		Issuer:                    identity.Issuer,
+		Subject:                   identity.Subject,
+		Email:                     new(identity.Email),
+		CustomSAMLConfigurationID: new(configuration.ID),
+	}
+	return db.CreateUserAccountOIDCIdentity(ctx, &newIdentity)
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/securitypolicy"
+	"github.com/distr-sh/distr/internal/subscription"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
+	"github.com/distr-sh/distr/internal/webauthn"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-webauthn/webauthn/protocol"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"go.uber.org/zap"
+)
+
+// AuthWebAuthnRouter serves the passkey login: the authenticator chooses the credential and returns the user
+// handle with the assertion, so no email address or password is needed.
+func AuthWebAuthnRouter(r chiopenapi.Router) {
+	r.Post("/login/begin", authWebAuthnLoginBeginHandler)
+	r.Post("/login/finish", authWebAuthnLoginFinishHandler)
+}
+
+func authWebAuthnLoginBeginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to configure WebAuthn", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	assertion, session, err := rp.BeginDiscoverableLogin(gowebauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to begin WebAuthn login", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID, err := db.CreateWebAuthnSession(ctx, nil, *session); err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to save WebAuthn session", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, api.BeginWebAuthnLoginResponse{SessionID: sessionID, Options: assertion})
+	}
+}
+
+func authWebAuthnLoginFinishHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+
+	request, err := JsonBody[api.FinishWebAuthnLoginRequest](w, r)
+	if err != nil {
+		return
+	} else if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to configure WebAuthn", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		session, err := db.DeleteWebAuthnSession(ctx, request.SessionID)
+		if errors.Is(err, apierrors.ErrNotFound) ||
+			(err == nil && (session.Expired() || session.UserAccountID != nil)) {
+			http.Error(w, "login session is invalid or expired", http.StatusBadRequest)
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		parsed, err := protocol.ParseCredentialRequestResponseBytes(request.Credential)
+		if err != nil {
+			http.Error(w, "invalid credential: "+err.Error(), http.StatusBadRequest)
+			return nil
+		}
+
+		var user *types.UserAccount
+		var lookupErr error
+		_, credential, err := rp.ValidatePasskeyLogin(
+			func(rawID, userHandle []byte) (gowebauthn.User, error) {
+				user, lookupErr = getWebAuthnUser(ctx, userHandle)
+				if lookupErr != nil {
+					return nil, lookupErr
+				}
+				credentials, err := db.GetWebAuthnCredentials(ctx, user.ID)
+				if err != nil {
+					lookupErr = err
+					return nil, err
+				}
+				return &webauthn.User{Account: *user, Credentials: credentials}, nil
+			},
+			session.SessionData,
+			parsed,
+		)
+		if lookupErr != nil && !errors.Is(lookupErr, apierrors.ErrNotFound) {
+			return lookupErr
+		} else if err != nil {
+			log.Info("WebAuthn login failed", zap.Error(err))
+			http.Error(w, "invalid passkey", http.StatusUnauthorized)
+			return nil
+		} else if credential.Authenticator.CloneWarning {
+			log.Warn("WebAuthn login rejected, the sign count indicates a cloned authenticator",
+				zap.Any("userId", user.ID))
+			http.Error(w, "invalid passkey", http.StatusUnauthorized)
+			return nil
+		}
+		log = log.With(zap.Any("userId", user.ID))
+
+		if err := db.UpdateWebAuthnCredentialUsage(ctx, *credential); err != nil {
+			return err
+		} else if tokenString, err := userauth.GenerateWebAuthnLoginToken(
+			ctx, *user, userauth.ClientFromRequest(r)); errors.Is(err, securitypolicy.ErrViolation) {
+			return err
+		} else if err != nil {
+			return fmt.Errorf("token creation failed: %w", err)
+		} else if err = db.UpdateUserAccountLastLoggedIn(ctx, user.ID); err != nil {
+			return err
+		} else {
+			RespondJSON(w, api.AuthLoginResponse{
+				Token:       tokenString,
+				RedirectURL: loginAppDomainRedirect(ctx, r, *user, tokenString),
+			})
+			return nil
+		}
+	})
+	if errors.Is(err, subscription.ErrGlobalOrganizationLimitReached) {
+		log.Warn("user login rejected, global organization limit reached")
+		http.Error(w, subscription.GlobalOrganizationLimitReachedMessage, http.StatusBadRequest)
+	} else if errors.Is(err, securitypolicy.ErrViolation) {
+		log.Info("user login rejected by security policy", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusForbidden)
+	} else if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Warn("user login failed", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	}
+}
+
+// beginWebAuthnSecondFactor starts a passkey challenge for a user who already signed in with their password. Unlike
+// the passkey login, the challenge is bound to the user and only allows their registered credentials.
+func beginWebAuthnSecondFactor(
+	ctx context.Context,
+	user types.UserAccount,
+	credentials []types.WebAuthnCredential,
+) (*api.BeginWebAuthnLoginResponse, error) {
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure WebAuthn: %w", err)
+	}
+	assertion, session, err := rp.BeginLogin(
+		&webauthn.User{Account: user, Credentials: credentials},
+		gowebauthn.WithUserVerification(protocol.VerificationPreferred),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin WebAuthn login: %w", err)
+	}
+	if sessionID, err := db.CreateWebAuthnSession(ctx, &user.ID, *session); err != nil {
+		return nil, err
+	} else {
+		return &api.BeginWebAuthnLoginResponse{SessionID: sessionID, Options: assertion}, nil
+	}
+}
+
+// verifyWebAuthnSecondFactor checks the answer to a challenge of beginWebAuthnSecondFactor. It writes the error
+// response if the session or the passkey is invalid.
+func verifyWebAuthnSecondFactor(
+	ctx context.Context,
+	w http.ResponseWriter,
+	user types.UserAccount,
+	credentials []types.WebAuthnCredential,
+	request api.AuthLoginRequest,
+) (bool, error) {
+	log := internalctx.GetLogger(ctx)
+
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		return false, fmt.Errorf("failed to configure WebAuthn: %w", err)
+	}
+
+	session, err := db.DeleteWebAuthnSession(ctx, *request.WebAuthnSessionID)
+	if errors.Is(err, apierrors.ErrNotFound) ||
+		(err == nil && (session.Expired() || session.UserAccountID == nil || *session.UserAccountID != user.ID)) {
+		http.Error(w, "login session is invalid or expired", http.StatusBadRequest)
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(request.WebAuthnCredential)
+	if err != nil {
+		http.Error(w, "invalid credential: "+err.Error(), http.StatusBadRequest)
+		return false, nil
+	}
+
+	credential, err := rp.ValidateLogin(
+		&webauthn.User{Account: user, Credentials: credentials}, session.SessionData, parsed)
+	if err != nil {
+		log.Info("WebAuthn second factor failed", zap.Error(err))
+		http.Error(w, "invalid passkey", http.StatusUnauthorized)
+		return false, nil
+	} else if credential.Authenticator.CloneWarning {
+		log.Warn("WebAuthn second factor rejected, the sign count indicates a cloned authenticator")
+		http.Error(w, "invalid passkey", http.StatusUnauthorized)
+		return false, nil
+	}
+	return true, db.UpdateWebAuthnCredentialUsage(ctx, *credential)
+}
+
+func getWebAuthnUser(ctx context.Context, userHandle []byte) (*types.UserAccount, error) {
+	if userID, err := webauthn.UserAccountIDFromHandle(userHandle); err != nil {
+		return nil, fmt.Errorf("%w: invalid user handle", apierrors.ErrNotFound)
+	} else {
+		return db.GetUserAccountByID(ctx, userID)
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/oaswrap/spec/adapter/chiopenapi"
 	"github.com/oaswrap/spec/option"
 )
@@ -12,13 +13,13 @@ func BillingRouter(r chiopenapi.Router) {
 	r.Route("/subscription", func(r chiopenapi.Router) {
 		r.Get("/", GetSubscriptionHandler)
 		r.Group(func(r chiopenapi.Router) {
-			r.Use(middleware.RequireAdmin, middleware.BlockSuperAdmin)
+			r.Use(middleware.RequirePermission(types.ResourceBilling, types.ActionWrite), middleware.BlockSuperAdmin)
 			r.Post("/", CreateSubscriptionHandler)
 			r.Put("/", UpdateSubscriptionHandler)
 		})
 	})
 	r.Group(func(r chiopenapi.Router) {
-		r.Use(middleware.RequireAdmin, middleware.BlockSuperAdmin)
+		r.Use(middleware.RequirePermission(types.ResourceBilling, types.ActionWrite), middleware.BlockSuperAdmin)
 		r.Post("/portal", CreateBillingPortalSessionHandler)
 	})
 }
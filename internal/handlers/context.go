@@ -53,6 +53,7 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 	var userRole *types.UserRole
 	var customerOrgID *uuid.UUID
 	var partnerOrgID *uuid.UUID
+	var customRoleID *uuid.UUID
 
 	if auth.IsSuperAdmin() {
 		// Super admins: use current org's creation time as join date, no role
@@ -66,6 +67,7 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 				userRole = &org.UserRole
 				customerOrgID = org.CustomerOrganizationID
 				partnerOrgID = org.PartnerOrganizationID
+				customRoleID = org.CustomRoleID
 				break
 			}
 		}
@@ -136,9 +138,8 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	RespondJSON(w, api.ContextResponse{
-		User: mapping.UserAccountToAPI(
-			auth.CurrentUser().AsUserAccountWithRole(*userRole, customerOrgID, auth.CurrentPartnerOrgID(), joinDate),
-		),
+		User: mapping.UserAccountToAPI(auth.CurrentUser().AsUserAccountWithRole(
+			*userRole, customerOrgID, auth.CurrentPartnerOrgID(), customRoleID, joinDate)),
 		Organization:          mapping.OrganizationToAPI(*auth.CurrentOrg(), billableUserCount, customerOrgCount),
 		CustomerOrganization:  customerOrg,
 		PartnerOrganization:   partnerOrg,
@@ -146,5 +147,8 @@ func getContextHandler(w http.ResponseWriter, r *http.Request) {
 		AvailableContexts:     orgs,
 		RegistryHost:          registryHost,
 		CanCreateOrganization: !governedByCustomOIDC,
+		Permissions:           auth.CurrentPermissions(),
+		WebAuthnRequired:      auth.WebAuthnRequired(),
+		MFARequired:           auth.MFARequired(),
 	})
 }
@@ -28,12 +28,15 @@ import (
 
 func CustomDomainsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Custom Domains"))
-	r.Use(middleware.RequireOrgAndRole, middleware.RequireAdmin)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceCustomDomains, types.ActionRead))
 	r.Get("/", getCustomDomainsHandler).
 		With(option.Description("List the custom domains within the caller's scope: every domain for a " +
 			"vendor, one customer's own for a customer, or the domains of the customers assigned to a partner")).
 		With(option.Response(http.StatusOK, []api.CustomDomainWithVerification{}))
-	r.With(middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.BlockSuperAdmin,
+		middleware.RequirePermission(types.ResourceCustomDomains, types.ActionWrite),
+	).Group(func(r chiopenapi.Router) {
 		r.With(middleware.RequireCustomDomainsConfigured).Post("/", createCustomDomainsHandler).
 			With(option.Description("Register new custom domains for the caller's organization, or for a " +
 				"customer named in the request")).
@@ -25,11 +25,18 @@ import (
 
 func CustomEmailsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Custom Email"))
-	r.Use(middleware.RequireVendor, middleware.RequireOrgAndRole, middleware.RequireAdmin)
+	r.Use(
+		middleware.RequireVendor,
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceCustomEmails, types.ActionRead),
+	)
 	r.Get("/", getCustomEmailConfigurationHandler).
 		With(option.Description("Get the email configuration of the current organization")).
 		With(option.Response(http.StatusOK, api.CustomEmailConfiguration{}))
-	r.With(middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.BlockSuperAdmin,
+		middleware.RequirePermission(types.ResourceCustomEmails, types.ActionWrite),
+	).Group(func(r chiopenapi.Router) {
 		r.Put("/", updateCustomEmailConfigurationHandler).
 			With(option.Description("Create or update the email configuration of the current organization")).
 			With(option.Request(api.UpdateCustomEmailConfigurationRequest{})).
@@ -33,12 +33,15 @@ type customOIDCConfigurationRequest struct {
 
 func CustomOIDCConfigurationsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Custom OIDC Providers"))
-	r.Use(middleware.RequireOrgAndRole, middleware.RequireAdmin)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionRead))
 	r.Get("/", getCustomOIDCConfigurationsHandler).
 		With(option.Description("List the OIDC providers within the caller's scope: every provider for " +
 			"a vendor, one customer's own for a customer, or the providers of the customers assigned to a partner")).
 		With(option.Response(http.StatusOK, api.CustomOIDCConfigurationsResponse{}))
-	r.With(middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.BlockSuperAdmin,
+		middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionWrite),
+	).Group(func(r chiopenapi.Router) {
 		r.Post("/", createCustomOIDCConfigurationHandler).
 			With(option.Description("Configure a new OIDC provider for the current organization")).
 			With(option.Request(api.CustomOIDCConfigurationRequest{})).
@@ -264,19 +267,33 @@ func validateCustomOIDCConfigurationRequest(
 	customerOrgID, partnerOrgID *uuid.UUID,
 	enforceExactScope bool,
 ) bool {
-	ctx := r.Context()
-	auth := auth.Authentication.Require(ctx)
-	orgID := *auth.CurrentOrgID()
-
 	request.Normalize()
 	if err := request.Validate(); err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return false
 	}
 
+	return validateCustomLoginDomain(
+		w, r, request.CustomDomainID, request.CreateUnknownUsers, customerOrgID, partnerOrgID, enforceExactScope)
+}
+
+// validateCustomLoginDomain checks the custom domain an OIDC or SAML provider is bound to. See
+// validateCustomOIDCConfigurationRequest for enforceExactScope.
+func validateCustomLoginDomain(
+	w http.ResponseWriter,
+	r *http.Request,
+	customDomainID uuid.UUID,
+	createUnknownUsers bool,
+	customerOrgID, partnerOrgID *uuid.UUID,
+	enforceExactScope bool,
+) bool {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+
 	// The login and callback URL of every provider start with the organization slug.
 	if slug := auth.CurrentOrg().Slug; slug == nil || *slug == "" {
-		http.Error(w, "the organization needs a slug before an OIDC provider can be configured",
+		http.Error(w, "the organization needs a slug before an identity provider can be configured",
 			http.StatusBadRequest)
 		return false
 	}
@@ -288,7 +305,7 @@ func validateCustomOIDCConfigurationRequest(
 		respondCustomOIDCConfigurationError(w, r, err)
 		return false
 	}
-	domain, ok := domains[request.CustomDomainID]
+	domain, ok := domains[customDomainID]
 	if !ok {
 		http.Error(w, "unknown custom domain", http.StatusBadRequest)
 		return false
@@ -303,13 +320,13 @@ func validateCustomOIDCConfigurationRequest(
 		return false
 	}
 	if domain.Type == types.DomainTypeRegistry {
-		http.Error(w, "the OIDC provider must be bound to an app or customer portal domain",
+		http.Error(w, "the identity provider must be bound to an app or customer portal domain",
 			http.StatusBadRequest)
 		return false
 	}
-	// The shared customer portal domain serves every customer of the vendor, and nothing in an OIDC
-	// response says which one a new user belongs to, so there is no organization to provision into.
-	if request.CreateUnknownUsers &&
+	// The shared customer portal domain serves every customer of the vendor, and nothing in an identity
+	// provider's response says which one a new user belongs to, so there is no organization to provision into.
+	if createUnknownUsers &&
 		domain.Type == types.DomainTypeCustomerPortal && domain.CustomerOrganizationID == nil {
 		http.Error(w, "users cannot be created automatically on the shared customer portal domain, "+
 			"because the provider does not say which customer they belong to", http.StatusBadRequest)
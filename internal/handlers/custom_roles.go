@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type customRolePathRequest struct {
+	CustomRoleID uuid.UUID `path:"customRoleId"`
+}
+
+func CustomRolesRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Roles"))
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceRoles, types.ActionRead),
+	)
+	r.Get("/", getCustomRolesHandler).
+		With(option.Description("List the custom roles of the current organization")).
+		With(option.Response(http.StatusOK, []api.CustomRole{}))
+	r.Get("/permissions", getPermissionsHandler).
+		With(option.Description("List the resources and actions a custom role can combine, and the permissions " +
+			"of the built-in roles")).
+		With(option.Response(http.StatusOK, api.PermissionsResponse{}))
+	r.With(
+		middleware.RequirePermission(types.ResourceRoles, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+		middleware.ProFeature,
+	).Group(func(r chiopenapi.Router) {
+		r.Post("/", createCustomRoleHandler).
+			With(option.Description("Create a custom role")).
+			With(option.Request(api.CustomRoleRequest{})).
+			With(option.Response(http.StatusOK, api.CustomRole{}))
+		r.Put("/{customRoleId}", updateCustomRoleHandler).
+			With(option.Description("Update a custom role. The change applies to every user and access token it " +
+				"is assigned to with their next request")).
+			With(option.Request(struct {
+				customRolePathRequest
+				api.CustomRoleRequest
+			}{})).
+			With(option.Response(http.StatusOK, api.CustomRole{}))
+		r.Delete("/{customRoleId}", deleteCustomRoleHandler).
+			With(option.Description("Delete a custom role that is no longer assigned")).
+			With(option.Request(customRolePathRequest{}))
+	})
+}
+
+func getCustomRolesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	if roles, err := db.GetCustomRoles(ctx, *auth.CurrentOrgID()); err != nil {
+		respondCustomRoleError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(roles, mapping.CustomRoleToAPI))
+	}
+}
+
+func getPermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	builtInRoles := []api.BuiltInRole{}
+	for _, role := range []types.UserRole{types.UserRoleReadOnly, types.UserRoleReadWrite, types.UserRoleAdmin} {
+		builtInRoles = append(builtInRoles, api.BuiltInRole{UserRole: role, Permissions: role.Permissions()})
+	}
+	RespondJSON(w, api.PermissionsResponse{
+		Resources:    types.Resources(),
+		Actions:      types.Actions(),
+		BuiltInRoles: builtInRoles,
+	})
+}
+
+func createCustomRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request, err := JsonBody[api.CustomRoleRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateCustomRoleRequest(w, r, &request) {
+		return
+	}
+
+	role := types.CustomRole{OrganizationID: *auth.CurrentOrgID()}
+	applyCustomRoleRequest(&role, request)
+	if err := db.CreateCustomRole(ctx, &role); err != nil {
+		respondCustomRoleError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, nil, mapping.CustomRoleToAPI(role))
+		RespondJSON(w, mapping.CustomRoleToAPI(role))
+	}
+}
+
+func updateCustomRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("customRoleId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.CustomRoleRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateCustomRoleRequest(w, r, &request) {
+		return
+	}
+
+	existing, err := db.GetCustomRole(ctx, id, *auth.CurrentOrgID())
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		respondCustomRoleError(w, r, err)
+		return
+	} else if !auth.CurrentPermissions().Contains(existing.Permissions) {
+		// Otherwise, removing permissions from a role would allow to take them away from more privileged users.
+		http.Error(w, "the role grants permissions that you do not have", http.StatusForbidden)
+		return
+	}
+
+	role := *existing
+	applyCustomRoleRequest(&role, request)
+	if err := db.UpdateCustomRole(ctx, &role); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondCustomRoleError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, mapping.CustomRoleToAPI(*existing), mapping.CustomRoleToAPI(role))
+		RespondJSON(w, mapping.CustomRoleToAPI(role))
+	}
+}
+
+func deleteCustomRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("customRoleId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := db.DeleteCustomRole(ctx, id, *auth.CurrentOrgID()); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, "the role is still assigned to a user or an access token", http.StatusConflict)
+	} else if err != nil {
+		respondCustomRoleError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validateCustomRoleRequest also makes sure that callers can only grant permissions they have themselves, so
+// that a custom role can never be used to escalate privileges.
+func validateCustomRoleRequest(w http.ResponseWriter, r *http.Request, request *api.CustomRoleRequest) bool {
+	request.Normalize()
+	if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	auth := auth.Authentication.Require(r.Context())
+	if !auth.CurrentPermissions().Contains(request.Permissions) {
+		http.Error(w, "a role cannot grant permissions that you do not have", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func applyCustomRoleRequest(role *types.CustomRole, request api.CustomRoleRequest) {
+	role.Name = request.Name
+	role.Description = request.Description
+	role.Permissions = request.Permissions
+}
+
+func respondCustomRoleError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case errors.Is(err, apierrors.ErrConflict):
+		http.Error(w, "a role with this name already exists", http.StatusConflict)
+	default:
+		internalctx.GetLogger(ctx).Error("custom role request failed", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
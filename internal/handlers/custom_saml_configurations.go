@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/saml"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type customSAMLConfigurationPathRequest struct {
+	CustomSAMLConfigurationID uuid.UUID `path:"customSamlConfigurationId"`
+}
+
+type customSAMLConfigurationRequest struct {
+	customSAMLConfigurationPathRequest
+	api.CustomSAMLConfigurationRequest
+}
+
+func CustomSAMLConfigurationsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Custom SAML Providers"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionRead))
+	r.Get("/", getCustomSAMLConfigurationsHandler).
+		With(option.Description("List the SAML providers within the caller's scope, the same way as the OIDC " +
+			"providers")).
+		With(option.Response(http.StatusOK, []api.CustomSAMLConfiguration{}))
+	r.With(
+		middleware.BlockSuperAdmin,
+		middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionWrite),
+	).Group(func(r chiopenapi.Router) {
+		r.Post("/", createCustomSAMLConfigurationHandler).
+			With(option.Description("Configure a new SAML provider for the current organization. The key pair " +
+				"of the service provider is generated on creation")).
+			With(option.Request(api.CustomSAMLConfigurationRequest{})).
+			With(option.Response(http.StatusOK, api.CustomSAMLConfiguration{}))
+		r.Put("/{customSamlConfigurationId}", updateCustomSAMLConfigurationHandler).
+			With(option.Description("Update a SAML provider configuration")).
+			With(option.Request(customSAMLConfigurationRequest{})).
+			With(option.Response(http.StatusOK, api.CustomSAMLConfiguration{}))
+		r.Delete("/{customSamlConfigurationId}", deleteCustomSAMLConfigurationHandler).
+			With(option.Description("Delete a SAML provider configuration and every identity linked to it")).
+			With(option.Request(customSAMLConfigurationPathRequest{}))
+	})
+}
+
+func getCustomSAMLConfigurationsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+	customerOrgID, partnerOrgID := auth.CurrentCustomerOrgID(), auth.CurrentPartnerOrgID()
+
+	configurations, err := db.GetCustomSAMLConfigurations(ctx, orgID, customerOrgID, partnerOrgID)
+	if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+	domains, err := customDomainsByID(ctx, orgID, customerOrgID, partnerOrgID)
+	if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+	RespondJSON(w, mapping.List(configurations,
+		func(c types.CustomSAMLConfiguration) api.CustomSAMLConfiguration {
+			return mapping.CustomSAMLConfigurationToDTO(c, auth.CurrentOrg().Slug, domains[c.CustomDomainID].Domain)
+		}))
+}
+
+func createCustomSAMLConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+
+	request, err := JsonBody[api.CustomSAMLConfigurationRequest](w, r)
+	if err != nil {
+		return
+	}
+	customerOrgID, ok := resolveCustomerScopeForWrite(w, r, request.CustomerOrganizationID)
+	if !ok {
+		return
+	}
+	if !validateCustomSAMLConfigurationRequest(w, r, &request, customerOrgID, nil, true) {
+		return
+	}
+
+	configuration := types.CustomSAMLConfiguration{
+		OrganizationID:         orgID,
+		UpdatedByUserAccountID: new(auth.CurrentUserID()),
+	}
+	applyCustomSAMLConfigurationRequest(&configuration, request)
+	configuration.SPCertificate, configuration.SPPrivateKey, err =
+		saml.GenerateServiceProviderKeyPair(configuration.Name)
+	if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+
+	if err := db.CreateCustomSAMLConfiguration(ctx, &configuration); err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+	respondCustomSAMLConfiguration(w, r, configuration, customerOrgID, nil)
+}
+
+// updateCustomSAMLConfigurationHandler and deleteCustomSAMLConfigurationHandler are authorized by the caller's
+// own auth scope, the same way as updateCustomOIDCConfigurationHandler.
+func updateCustomSAMLConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+	customerOrgID, partnerOrgID := auth.CurrentCustomerOrgID(), auth.CurrentPartnerOrgID()
+
+	id, err := uuid.Parse(r.PathValue("customSamlConfigurationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.CustomSAMLConfigurationRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateCustomSAMLConfigurationRequest(w, r, &request, customerOrgID, partnerOrgID, false) {
+		return
+	}
+
+	existing, err := db.GetCustomSAMLConfigurationOfOrganization(ctx, id, orgID, customerOrgID, partnerOrgID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+
+	configuration := *existing
+	configuration.UpdatedByUserAccountID = new(auth.CurrentUserID())
+	applyCustomSAMLConfigurationRequest(&configuration, request)
+
+	if err := db.UpdateCustomSAMLConfiguration(ctx, &configuration); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+	} else {
+		respondCustomSAMLConfiguration(w, r, configuration, customerOrgID, partnerOrgID)
+	}
+}
+
+func deleteCustomSAMLConfigurationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+
+	id, err := uuid.Parse(r.PathValue("customSamlConfigurationId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	err = db.DeleteCustomSAMLConfiguration(ctx, id, orgID, auth.CurrentCustomerOrgID(), auth.CurrentPartnerOrgID())
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func applyCustomSAMLConfigurationRequest(
+	configuration *types.CustomSAMLConfiguration,
+	request api.CustomSAMLConfigurationRequest,
+) {
+	configuration.CustomDomainID = request.CustomDomainID
+	configuration.Name = request.Name
+	configuration.Slug = request.Slug
+	configuration.Enabled = request.Enabled
+	configuration.IDPMetadata = request.IDPMetadata
+	configuration.IDPEntityID = request.IDPEntityID
+	configuration.AllowIDPInitiated = request.AllowIDPInitiated
+	configuration.EmailAttribute = request.EmailAttribute
+	configuration.NameAttribute = request.NameAttribute
+	configuration.RoleAttribute = request.RoleAttribute
+	configuration.RoleMapping = request.RoleMapping
+	configuration.CreateUnknownUsers = request.CreateUnknownUsers
+	configuration.DefaultUserRole = request.DefaultUserRole
+	configuration.AllowedEmailDomains = request.AllowedEmailDomains
+}
+
+// validateCustomSAMLConfigurationRequest applies the same domain rules as
+// validateCustomOIDCConfigurationRequest, including the meaning of enforceExactScope.
+func validateCustomSAMLConfigurationRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	request *api.CustomSAMLConfigurationRequest,
+	customerOrgID, partnerOrgID *uuid.UUID,
+	enforceExactScope bool,
+) bool {
+	request.Normalize()
+	if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return validateCustomLoginDomain(
+		w, r, request.CustomDomainID, request.CreateUnknownUsers, customerOrgID, partnerOrgID, enforceExactScope)
+}
+
+func respondCustomSAMLConfiguration(
+	w http.ResponseWriter,
+	r *http.Request,
+	configuration types.CustomSAMLConfiguration,
+	customerOrgID, partnerOrgID *uuid.UUID,
+) {
+	ctx := r.Context()
+	domains, err := customDomainsByID(ctx, configuration.OrganizationID, customerOrgID, partnerOrgID)
+	if err != nil {
+		respondCustomSAMLConfigurationError(w, r, err)
+		return
+	}
+	organizationSlug := auth.Authentication.Require(ctx).CurrentOrg().Slug
+	RespondJSON(w, mapping.CustomSAMLConfigurationToDTO(
+		configuration, organizationSlug, domains[configuration.CustomDomainID].Domain))
+}
+
+func respondCustomSAMLConfigurationError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case errors.Is(err, apierrors.ErrConflict):
+		http.Error(w, "a provider with this name or slug already exists", http.StatusConflict)
+	case errors.Is(err, apierrors.ErrBadRequest):
+		http.Error(w, "invalid custom SAML configuration", http.StatusBadRequest)
+	default:
+		internalctx.GetLogger(ctx).Error("custom SAML configuration request failed", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -25,7 +25,11 @@ import (
 
 func CustomerOrganizationsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Customers"))
-	r.With(middleware.RequireVendorOrPartner, middleware.RequireOrgAndRole).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequireVendorOrPartner,
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionRead),
+	).Group(func(r chiopenapi.Router) {
 		r.Get("/", getCustomerOrganizationsHandler()).
 			With(option.Description("List all customer organizations")).
 			With(option.Response(http.StatusOK, []api.CustomerOrganizationWithUsage{}))
@@ -39,7 +43,7 @@ func CustomerOrganizationsRouter(r chiopenapi.Router) {
 
 			r.With(middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
 				r.With(middleware.RequireVendorOrPartner).Group(func(r chiopenapi.Router) {
-					r.With(middleware.RequireReadWriteOrAdmin).
+					r.With(middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionWrite)).
 						Put("/", updateCustomerOrganizationHandler()).
 						With(option.Description("Update a customer organization")).
 						With(option.Request(struct {
@@ -48,13 +52,17 @@ func CustomerOrganizationsRouter(r chiopenapi.Router) {
 						}{})).
 						With(option.Response(http.StatusOK, api.CustomerOrganization{}))
 
-					r.With(middleware.RequireAdmin).
+					r.With(middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionManage)).
 						Delete("/", deleteCustomerOrganizationHandler()).
 						With(option.Description("Delete a customer organization")).
 						With(option.Request(CustomerOrganizationIDRequest{}))
 				})
 
-				r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.PartnerManagementFeatureMiddleware).
+				r.With(
+					middleware.RequireVendor,
+					middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionWrite),
+					middleware.PartnerManagementFeatureMiddleware,
+				).
 					Put("/partner", assignCustomerToPartnerHandler()).
 					With(option.Description("Assign or unassign a partner organization for a customer organization")).
 					With(option.Request(struct {
@@ -65,7 +73,10 @@ func CustomerOrganizationsRouter(r chiopenapi.Router) {
 			})
 		})
 
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(
+			middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).
 			Post("/", createCustomerOrganizationHandler()).
 			With(option.Description("Create a new customer organization")).
 			With(option.Request(api.CreateUpdateCustomerOrganizationRequest{})).
@@ -27,11 +27,11 @@ import (
 
 func DeploymentTargetsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Agents"))
-	r.Use(middleware.RequireOrgAndRole)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionRead))
 	r.Get("/", getDeploymentTargets).
 		With(option.Description("List all deployment targets")).
 		With(option.Response(http.StatusOK, []types.DeploymentTargetFull{}))
-	r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionWrite), middleware.BlockSuperAdmin).
 		Post("/", createDeploymentTarget).
 		With(option.Description("Create a new deployment target")).
 		With(option.Response(http.StatusOK, []types.DeploymentTargetFull{}))
@@ -50,7 +50,10 @@ func DeploymentTargetsRouter(r chiopenapi.Router) {
 			With(option.Description("Get a deployment target")).
 			With(option.Request(DeploymentTargetIDRequest{})).
 			With(option.Response(http.StatusOK, []types.DeploymentTargetFull{}))
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+		r.With(
+			middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).Group(func(r chiopenapi.Router) {
 			r.Put("/", updateDeploymentTarget).
 				With(option.Description("Update a deployment target")).
 				With(option.Request(struct {
@@ -71,7 +74,7 @@ func DeploymentTargetsRouter(r chiopenapi.Router) {
 				With(option.Description("Get notes for this deployment target")).
 				With(option.Request(DeploymentTargetIDRequest{})).
 				With(option.Response(http.StatusOK, api.DeploymentTargetNotes{}))
-			r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+			r.With(middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionWrite), middleware.BlockSuperAdmin).
 				Put("/", putDeploymentTargetNotesHandler()).
 				With(option.Description("Set notes for this deployment target")).
 				With(option.Request(struct {
@@ -80,6 +83,48 @@ func DeploymentTargetsRouter(r chiopenapi.Router) {
 				}{})).
 				With(option.Response(http.StatusOK, api.DeploymentTargetNotes{}))
 		})
+		r.Route("/volume-backup-storage", func(r chiopenapi.Router) {
+			r.Get("/", getVolumeBackupStorageHandler).
+				With(option.Description("Get the storage of volume snapshots for this deployment target")).
+				With(option.Request(DeploymentTargetIDRequest{})).
+				With(option.Response(http.StatusOK, api.VolumeBackupStorage{}))
+			r.With(
+				middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionWrite),
+				middleware.BlockSuperAdmin,
+			).Group(func(r chiopenapi.Router) {
+				r.Put("/", putVolumeBackupStorageHandler).
+					With(option.Description("Set the storage of volume snapshots for this deployment target")).
+					With(option.Request(struct {
+						DeploymentTargetIDRequest
+						api.VolumeBackupStorageRequest
+					}{})).
+					With(option.Response(http.StatusOK, api.VolumeBackupStorage{}))
+				r.Delete("/", deleteVolumeBackupStorageHandler).
+					With(option.Description("Reset the storage of volume snapshots for this deployment target to local")).
+					With(option.Request(DeploymentTargetIDRequest{}))
+			})
+		})
+		r.Route("/agent-network", func(r chiopenapi.Router) {
+			r.Get("/", getDeploymentTargetAgentNetworkHandler).
+				With(option.Description("Get the proxy and TLS settings of the agent of this deployment target")).
+				With(option.Request(DeploymentTargetIDRequest{})).
+				With(option.Response(http.StatusOK, api.DeploymentTargetAgentNetwork{}))
+			r.With(
+				middleware.RequirePermission(types.ResourceDeploymentTargets, types.ActionWrite),
+				middleware.BlockSuperAdmin,
+			).Group(func(r chiopenapi.Router) {
+				r.Put("/", putDeploymentTargetAgentNetworkHandler).
+					With(option.Description("Set the proxy and TLS settings of the agent of this deployment target")).
+					With(option.Request(struct {
+						DeploymentTargetIDRequest
+						api.DeploymentTargetAgentNetworkRequest
+					}{})).
+					With(option.Response(http.StatusOK, api.DeploymentTargetAgentNetwork{}))
+				r.Delete("/", deleteDeploymentTargetAgentNetworkHandler).
+					With(option.Description("Remove the proxy and TLS settings of the agent of this deployment target")).
+					With(option.Request(DeploymentTargetIDRequest{}))
+			})
+		})
 		// These are read-only, agent-pushed logs that are safe to serve from the read-only db.
 		r.With(middleware.UseReadonlyDB).Group(func(r chiopenapi.Router) {
 			r.Get("/logs", getDeploymentTargetLogRecordsHandler()).
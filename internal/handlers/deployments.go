@@ -9,10 +9,12 @@ import (
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/deploymentvalues"
+	"github.com/distr-sh/distr/internal/manifestbundle"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/subscription"
@@ -27,8 +29,8 @@ import (
 
 func DeploymentsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Deployments"))
-	r.Use(middleware.RequireOrgAndRole)
-	r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceDeployments, types.ActionRead))
+	r.With(middleware.RequirePermission(types.ResourceDeployments, types.ActionWrite), middleware.BlockSuperAdmin).
 		Put("/", putDeployment).
 		With(option.Description("Create or update a deployment")).
 		With(option.Request(api.DeploymentRequest{}))
@@ -50,6 +52,18 @@ func DeploymentsRouter(r chiopenapi.Router) {
 			With(option.Description("Get deployment revisions")).
 			With(option.Request(DeploymentIDRequest{})).
 			With(option.Response(http.StatusOK, []api.DeploymentRevisionResponse{}))
+		r.Get("/plan", getDeploymentPlan).
+			With(option.Description("Get the plan of the current revision of an OpenTofu deployment")).
+			With(option.Request(DeploymentIDRequest{})).
+			With(option.Response(http.StatusOK, api.DeploymentRevisionPlan{}))
+		r.Get("/volume-snapshots", getDeploymentVolumeSnapshots).
+			With(option.Description("Get the volume snapshots that the agent keeps for a Docker Compose deployment")).
+			With(option.Request(DeploymentIDRequest{})).
+			With(option.Response(http.StatusOK, []api.DeploymentVolumeSnapshot{}))
+		r.Get("/metadata", getDeploymentMetadata).
+			With(option.Description("Get the metadata reported by the agent, e.g. the outputs of an OpenTofu deployment")).
+			With(option.Request(DeploymentIDRequest{})).
+			With(option.Response(http.StatusOK, api.DeploymentMetadata{}))
 		// These are read-only, agent-pushed timeseries that are safe to serve from the read-only db.
 		r.With(middleware.UseReadonlyDB).Group(func(r chiopenapi.Router) {
 			r.Get("/status", getDeploymentStatus).
@@ -83,88 +97,154 @@ func DeploymentsRouter(r chiopenapi.Router) {
 				}{})).
 				With(option.Response(http.StatusOK, nil, option.ContentType("text/plain")))
 		})
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+		r.With(
+			middleware.RequirePermission(types.ResourceDeployments, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).Group(func(r chiopenapi.Router) {
 			r.Delete("/", deleteDeploymentHandler()).
 				With(option.Description("Delete a deployment")).
 				With(option.Request(DeploymentIDRequest{}))
+			r.Post("/plan/approve", approveDeploymentPlan).
+				With(option.Description("Approve the plan of the current revision of an OpenTofu deployment")).
+				With(option.Request(struct {
+					DeploymentIDRequest
+					api.ApproveDeploymentPlanRequest
+				}{})).
+				With(option.Response(http.StatusOK, api.DeploymentRevisionPlan{}))
+			r.Post("/volume-snapshots/{snapshotId}/restore", restoreDeploymentVolumeSnapshot).
+				With(option.Description(
+					"Roll a deployment back to the revision of a volume snapshot and restore its volumes",
+				)).
+				With(option.Request(struct {
+					DeploymentIDRequest
+					SnapshotID uuid.UUID `path:"snapshotId"`
+				}{}))
 		})
 	})
 }
 
 func putDeployment(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-	log := internalctx.GetLogger(ctx)
-
 	deploymentRequest, err := JsonBody[api.DeploymentRequest](w, r)
 	if err != nil {
 		return
 	}
 
-	_ = db.RunTx(ctx, func(ctx context.Context) error {
-		validationResult, err := validateDeploymentRequest(ctx, w, deploymentRequest)
+	_ = db.RunTx(r.Context(), func(ctx context.Context) error {
+		return saveDeploymentRequest(ctx, w, deploymentRequest)
+	})
+}
+
+// saveDeploymentRequest creates a new revision of the deployment, or the deployment itself if the request has no
+// deployment ID. It must be called in a transaction and writes the response.
+func saveDeploymentRequest(ctx context.Context, w http.ResponseWriter, deploymentRequest api.DeploymentRequest) error {
+	log := internalctx.GetLogger(ctx)
+	validationResult, err := validateDeploymentRequest(ctx, w, deploymentRequest)
+	if err != nil {
+		return err
+	}
+	if err := setDeploymentRequestValuesHash(
+		&deploymentRequest,
+		validationResult.Secrets,
+		validationResult.LicenseKeys,
+	); err != nil {
+		return deploymentValuesError(ctx, w, err, "invalid deployment values")
+	}
+
+	var previous *deploymentAuditState
+	if deploymentRequest.DeploymentID == nil {
+		if err = db.CreateDeployment(ctx, &deploymentRequest); errors.Is(err, apierrors.ErrConflict) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return err
+		} else if err != nil {
+			log.Warn("could not create deployment", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		}
+	} else {
+		authInfo := auth.Authentication.Require(ctx)
+		deployment, err := db.GetDeployment(
+			ctx,
+			*deploymentRequest.DeploymentID,
+			authInfo.CurrentUserID(),
+			*authInfo.CurrentOrgID(),
+			authInfo.CurrentCustomerOrgID(),
+			authInfo.CurrentPartnerOrgID(),
+		)
 		if err != nil {
+			log.Warn("could not get deployment", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return err
 		}
-		if err := setDeploymentRequestValuesHash(
-			&deploymentRequest,
-			validationResult.Secrets,
-			validationResult.LicenseKeys,
-		); err != nil {
-			return deploymentValuesError(ctx, w, err, "invalid deployment values")
+		if revision, err := db.GetLatestDeploymentRevision(ctx, deployment.ID); err != nil {
+			log.Warn("could not get deployment revision", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return err
+		} else {
+			previous = &deploymentAuditState{
+				ID:                       deployment.ID,
+				DeploymentTargetID:       deployment.DeploymentTargetID,
+				ApplicationVersionID:     revision.ApplicationVersionID,
+				ApplicationEntitlementID: deployment.ApplicationEntitlementID,
+				ValuesHash:               fmt.Sprintf("%x", revision.ValuesHash),
+				ForceRestart:             revision.ForceRestart,
+				VolumeBackupPolicy:       revision.VolumeBackupPolicy,
+			}
+			if deploymentRequest.VolumeBackupPolicy == nil {
+				deploymentRequest.VolumeBackupPolicy = &revision.VolumeBackupPolicy
+			}
 		}
 
-		if deploymentRequest.DeploymentID == nil {
-			if err = db.CreateDeployment(ctx, &deploymentRequest); errors.Is(err, apierrors.ErrConflict) {
-				http.Error(w, err.Error(), http.StatusBadRequest)
-				return err
-			} else if err != nil {
-				log.Warn("could not create deployment", zap.Error(err))
+		if deployment.ApplicationEntitlementID == nil && deploymentRequest.ApplicationEntitlementID != nil {
+			deployment.ApplicationEntitlementID = deploymentRequest.ApplicationEntitlementID
+			if err := db.UpdateDeploymentEntitlement(ctx, deployment); err != nil {
+				log.Warn("could not set entitlement for deployment", zap.Error(err))
 				sentry.GetHubFromContext(ctx).CaptureException(err)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return err
 			}
-		} else {
-			authInfo := auth.Authentication.Require(ctx)
-			deployment, err := db.GetDeployment(
-				ctx,
-				*deploymentRequest.DeploymentID,
-				authInfo.CurrentUserID(),
-				*authInfo.CurrentOrgID(),
-				authInfo.CurrentCustomerOrgID(),
-				authInfo.CurrentPartnerOrgID(),
-			)
-			if err != nil {
-				log.Warn("could not get deployment", zap.Error(err))
-				sentry.GetHubFromContext(ctx).CaptureException(err)
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return err
-			}
-
-			if deployment.ApplicationEntitlementID == nil && deploymentRequest.ApplicationEntitlementID != nil {
-				deployment.ApplicationEntitlementID = deploymentRequest.ApplicationEntitlementID
-				if err := db.UpdateDeploymentEntitlement(ctx, deployment); err != nil {
-					log.Warn("could not set entitlement for deployment", zap.Error(err))
-					sentry.GetHubFromContext(ctx).CaptureException(err)
-					http.Error(w, err.Error(), http.StatusInternalServerError)
-					return err
-				}
-			}
 		}
+	}
 
-		createdByUserID := auth.Authentication.Require(ctx).CurrentUserID()
-		deploymentRequest.CreatedByUserAccountID = &createdByUserID
+	createdByUserID := auth.Authentication.Require(ctx).CurrentUserID()
+	deploymentRequest.CreatedByUserAccountID = &createdByUserID
 
-		if _, err := db.CreateDeploymentRevision(ctx, &deploymentRequest); err != nil {
-			log.Warn("could not create deployment revision", zap.Error(err))
-			sentry.GetHubFromContext(ctx).CaptureException(err)
-			http.Error(w, err.Error(), http.StatusInternalServerError)
-			return err
-		}
+	if _, err := db.CreateDeploymentRevision(ctx, &deploymentRequest); err != nil {
+		log.Warn("could not create deployment revision", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return err
+	}
 
-		// TODO: We might need to send a proper deployment object back, but not sure yet what it looks like
-		w.WriteHeader(http.StatusNoContent)
-		return nil
+	auditlog.RecordChange(ctx, previous, deploymentAuditState{
+		ID:                       *deploymentRequest.DeploymentID,
+		DeploymentTargetID:       deploymentRequest.DeploymentTargetID,
+		ApplicationVersionID:     deploymentRequest.ApplicationVersionID,
+		ApplicationEntitlementID: deploymentRequest.ApplicationEntitlementID,
+		ValuesHash:               fmt.Sprintf("%x", deploymentRequest.ValuesHash),
+		ForceRestart:             deploymentRequest.ForceRestart,
+		VolumeBackupPolicy:       util.PtrDerefOr(deploymentRequest.VolumeBackupPolicy, types.VolumeBackupPolicyNone),
+		RestoreVolumeSnapshotID:  deploymentRequest.RestoreVolumeSnapshotID,
 	})
+
+	// TODO: We might need to send a proper deployment object back, but not sure yet what it looks like
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// deploymentAuditState is what the audit log compares when a deployment is updated. Values and env files may
+// contain credentials, so they are represented by their hash, which tells whether they changed.
+type deploymentAuditState struct {
+	ID                       uuid.UUID                `json:"id"`
+	DeploymentTargetID       uuid.UUID                `json:"deploymentTargetId"`
+	ApplicationVersionID     uuid.UUID                `json:"applicationVersionId"`
+	ApplicationEntitlementID *uuid.UUID               `json:"applicationEntitlementId,omitempty"`
+	ValuesHash               string                   `json:"valuesHash,omitempty"`
+	ForceRestart             bool                     `json:"forceRestart"`
+	VolumeBackupPolicy       types.VolumeBackupPolicy `json:"volumeBackupPolicy"`
+	RestoreVolumeSnapshotID  *uuid.UUID               `json:"restoreVolumeSnapshotId,omitempty"`
 }
 
 func deleteDeploymentHandler() http.HandlerFunc {
@@ -309,6 +389,8 @@ func validateDeploymentRequest(
 		return nil, err
 	} else if err = validateDeploymentRequestDeploymentTarget(ctx, w, request, target); err != nil {
 		return nil, err
+	} else if err = validateDeploymentRequestVolumeBackup(w, request, target, existingDeployment); err != nil {
+		return nil, err
 	} else if err = validateDeploymentRequestValues(ctx, w, request, version, secrets, licenseKeys); err != nil {
 		return nil, err
 	} else {
@@ -470,6 +552,49 @@ func validateDeploymentRequestDeploymentTarget(
 		return badRequestError(w, "IgnoreRevisionSkew is only supported for Kubernetes deployments")
 	}
 
+	if target.Type == types.DeploymentTypeSystemd || target.Type == types.DeploymentTypeOpenTofu {
+		if request.ReleaseName == nil {
+			return badRequestError(w, fmt.Sprintf("%v deployments must have a release name", target.Type))
+		} else if err := types.ValidateReleaseName(*request.ReleaseName); err != nil {
+			return badRequestError(w, err.Error())
+		}
+		for _, deployment := range target.Deployments {
+			if util.PtrEq(deployment.ReleaseName, request.ReleaseName) &&
+				(request.DeploymentID == nil || deployment.ID != *request.DeploymentID) {
+				return badRequestError(w, "release name is already used by another deployment on this target")
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateDeploymentRequestVolumeBackup checks that volume backups are only used for Docker Compose deployments,
+// as the agent can only snapshot the volumes of a compose project.
+func validateDeploymentRequestVolumeBackup(
+	w http.ResponseWriter,
+	request api.DeploymentRequest,
+	target *types.DeploymentTargetFull,
+	deployment *types.DeploymentWithLatestRevision,
+) error {
+	policy := util.PtrDerefOr(request.VolumeBackupPolicy, types.VolumeBackupPolicyNone)
+	switch policy {
+	case types.VolumeBackupPolicyNone, types.VolumeBackupPolicyBeforeUpdate, types.VolumeBackupPolicyBeforeVersionUpdate:
+	default:
+		return badRequestError(w, fmt.Sprintf("invalid volume backup policy %q", policy))
+	}
+	if policy == types.VolumeBackupPolicyNone && request.RestoreVolumeSnapshotID == nil {
+		return nil
+	}
+	dockerType := request.DockerType
+	if deployment != nil {
+		dockerType = deployment.DockerType
+	}
+	if target.Type != types.DeploymentTypeDocker || util.PtrDerefOrDefault(dockerType) == types.DockerTypeSwarm {
+		return badRequestError(w, "volume backups are only supported for Docker Compose deployments")
+	} else if target.GetContainerRuntime() == types.ContainerRuntimeQuadlet {
+		return badRequestError(w, "volume backups are not supported for Quadlet deployments")
+	}
 	return nil
 }
 
@@ -489,12 +614,24 @@ func validateDeploymentRequestValues(
 		return err
 	} else if _, err := util.MergeAllRecursive(appVersionValues, deploymentValues); err != nil {
 		return badRequestError(w, fmt.Sprintf("values cannot be merged with base: %v", err))
+	} else if err := validateDeploymentRequestPatches(appVersion, deploymentValues); err != nil {
+		return badRequestError(w, fmt.Sprintf("invalid patches: %v", err))
 	} else if _, err := deploymentvalues.EnvFileReplaceSecrets(&deploymentRequest, secrets, licenseKeys); err != nil {
 		return deploymentValuesError(ctx, w, err, "invalid env file")
 	}
 	return nil
 }
 
+// validateDeploymentRequestPatches checks the values of a deployment of a kustomize application version, which are
+// the overlay that the agent builds the manifest bundle with. The bundle itself is only built by the agent, as its
+// kustomization may refer to remote resources.
+func validateDeploymentRequestPatches(appVersion *types.ApplicationVersion, values map[string]any) error {
+	if !appVersion.IsKustomize() {
+		return nil
+	}
+	return manifestbundle.ValidateOverlay(values)
+}
+
 func deploymentValuesError(ctx context.Context, w http.ResponseWriter, err error, clientMsg string) error {
 	if errors.Is(err, deploymentvalues.ErrInvalidTemplate) {
 		return badRequestError(w, fmt.Sprintf("%s: %v", clientMsg, err.Error()))
@@ -521,6 +658,59 @@ func getDeploymentRevisions(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getDeploymentPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deployment := internalctx.GetDeployment(ctx)
+	if plan, err := db.GetLatestDeploymentRevisionPlan(ctx, deployment.ID); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get deployment plan", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.DeploymentRevisionPlanToAPI(*plan))
+	}
+}
+
+func approveDeploymentPlan(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deployment := internalctx.GetDeployment(ctx)
+	request, err := JsonBody[api.ApproveDeploymentPlanRequest](w, r)
+	if err != nil {
+		return
+	} else if request.Digest == "" {
+		http.Error(w, "digest is required", http.StatusBadRequest)
+		return
+	}
+
+	userID := auth.Authentication.Require(ctx).CurrentUserID()
+	if plan, err := db.ApproveLatestDeploymentRevisionPlan(ctx, deployment.ID, request.Digest, userID); err != nil {
+		if errors.Is(err, apierrors.ErrConflict) {
+			http.Error(w, err.Error(), http.StatusConflict)
+		} else {
+			internalctx.GetLogger(ctx).Error("failed to approve deployment plan", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	} else {
+		RespondJSON(w, mapping.DeploymentRevisionPlanToAPI(*plan))
+	}
+}
+
+func getDeploymentMetadata(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deployment := internalctx.GetDeployment(ctx)
+	if metadata, err := db.GetDeploymentMetadata(ctx, deployment.ID); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get deployment metadata", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.DeploymentMetadataToAPI(*metadata))
+	}
+}
+
 func getDeploymentStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	deployment := internalctx.GetDeployment(ctx)
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"strings"
 	"time"
@@ -28,18 +29,41 @@ import (
 
 func LicenseKeysRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Licensing"))
-	r.Use(middleware.RequireOrgAndRole, middleware.LicensingFeatureFlagEnabledMiddleware)
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceLicenseKeys, types.ActionRead),
+		middleware.LicensingFeatureFlagEnabledMiddleware,
+	)
 
 	r.Get("/", getLicenseKeys).
 		With(option.Description("List all license keys")).
 		With(option.Response(http.StatusOK, []types.LicenseKey{}))
 
-	r.With(middleware.RequireVendorOrPartner, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(
+		middleware.RequireVendorOrPartner,
+		middleware.RequirePermission(types.ResourceLicenseKeys, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
 		Post("/", createLicenseKey).
 		With(option.Description("Create a new license key")).
 		With(option.Request(api.CreateLicenseKeyRequest{})).
 		With(option.Response(http.StatusOK, types.LicenseKey{}))
 
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceLicenseKeys, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
+		Post("/bulk-revision", bulkReviseLicenseKeys).
+		With(option.Description("Create a new revision for all license keys matching a filter, " +
+			"e.g. to renew all licenses of a customer or template at once")).
+		With(option.Request(struct {
+			api.BulkLicenseKeyRevisionRequest
+			bulkLicenseKeyRevisionQuery
+		}{})).
+		With(option.Response(http.StatusOK, api.BulkLicenseKeyRevisionResponse{})).
+		With(option.Response(http.StatusConflict, api.AffectedDeploymentsConflictResponse{}))
+
 	r.With(licenseKeyMiddleware).Route("/{licenseKeyId}", func(r chiopenapi.Router) {
 		type LicenseKeyIDRequest struct {
 			LicenseKeyID uuid.UUID `path:"licenseKeyId"`
@@ -55,7 +79,11 @@ func LicenseKeysRouter(r chiopenapi.Router) {
 			With(option.Request(LicenseKeyIDRequest{})).
 			With(option.Response(http.StatusOK, []api.LicenseKeyRevision{}))
 
-		r.With(middleware.RequireVendorOrPartner, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(
+			middleware.RequireVendorOrPartner,
+			middleware.RequirePermission(types.ResourceLicenseKeys, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).
 			Group(func(r chiopenapi.Router) {
 				r.Put("/", updateLicenseKey).
 					With(option.Description("Update license key metadata and optionally create a new revision")).
@@ -423,6 +451,161 @@ func updatedLicenseKeyForRequest(
 	return result, nil
 }
 
+// bulkLicenseKeyRevisionQuery only returns the revisions if Preview is set. Confirm applies them even if they affect
+// deployments.
+type bulkLicenseKeyRevisionQuery struct {
+	Preview bool `query:"preview"`
+	Confirm bool `query:"confirm"`
+}
+
+func bulkReviseLicenseKeys(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	authCtx := auth.Authentication.Require(ctx)
+
+	body, err := JsonBody[api.BulkLicenseKeyRevisionRequest](w, r)
+	if err != nil {
+		return
+	}
+
+	if err := body.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if body.Payload != nil {
+		if err := licensekey.ValidatePayload(*body.Payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	existing, err := db.GetLicenseKeysMatching(ctx, types.LicenseKeyFilter{
+		OrgID:                  *authCtx.CurrentOrgID(),
+		LicenseTemplateID:      body.Filter.LicenseTemplateID,
+		CustomerOrganizationID: body.Filter.CustomerOrganizationID,
+		ExpiresAfter:           body.Filter.ExpiresAfter,
+		ExpiresBefore:          body.Filter.ExpiresBefore,
+	})
+	if err != nil {
+		log.Error("failed to get license keys", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	response := api.BulkLicenseKeyRevisionResponse{
+		Revisions:           make([]api.BulkLicenseKeyRevision, 0, len(existing)),
+		AffectedDeployments: []api.AffectedDeployment{},
+	}
+	revised := make([]types.LicenseKey, 0, len(existing))
+	revisedByCustomerOrg := make(map[uuid.UUID][]types.LicenseKey)
+	for _, lk := range existing {
+		updated, err := bulkRevisedLicenseKey(lk, body)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("license key %v: %v", lk.Name, err), http.StatusBadRequest)
+			return
+		}
+		revised = append(revised, updated)
+		if lk.CustomerOrganizationID != nil {
+			revisedByCustomerOrg[*lk.CustomerOrganizationID] = append(
+				revisedByCustomerOrg[*lk.CustomerOrganizationID], updated)
+		}
+		response.Revisions = append(response.Revisions, api.BulkLicenseKeyRevision{
+			LicenseKeyID:           lk.ID,
+			Name:                   lk.Name,
+			CustomerOrganizationID: lk.CustomerOrganizationID,
+			PreviousNotBefore:      *lk.NotBefore,
+			PreviousExpiresAt:      *lk.ExpiresAt,
+			NotBefore:              *updated.NotBefore,
+			ExpiresAt:              *updated.ExpiresAt,
+			PayloadChanged:         !bytes.Equal(lk.Payload, updated.Payload),
+		})
+	}
+
+	for customerOrgID, licenseKeys := range revisedByCustomerOrg {
+		affected, err := findAffectedDeploymentsByLicenseKey(ctx, *authCtx.CurrentOrgID(), customerOrgID, licenseKeys...)
+		if err != nil {
+			_ = deploymentValuesError(ctx, w, err, "invalid deployment values")
+			return
+		}
+		response.AffectedDeployments = append(response.AffectedDeployments, affected...)
+	}
+
+	if r.URL.Query().Get("preview") == "true" {
+		RespondJSON(w, response)
+		return
+	}
+
+	confirm := r.URL.Query().Get("confirm") == "true"
+	if !confirm && len(response.AffectedDeployments) > 0 {
+		RespondJSONWithStatus(w, http.StatusConflict,
+			api.AffectedDeploymentsConflictResponse{AffectedDeployments: response.AffectedDeployments})
+		return
+	}
+
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		for _, lk := range revised {
+			revision := types.LicenseKeyRevision{
+				LicenseKeyID: lk.ID,
+				NotBefore:    *lk.NotBefore,
+				ExpiresAt:    *lk.ExpiresAt,
+				Payload:      lk.Payload,
+			}
+			if err := db.CreateLicenseKeyRevision(ctx, &revision); err != nil {
+				return err
+			}
+		}
+		return triggerAffectedDeployments(ctx, response.AffectedDeployments, new(authCtx.CurrentUserID()))
+	})
+	if err != nil {
+		log.Warn("bulk license key revision error", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response.Applied = true
+	RespondJSON(w, response)
+}
+
+// bulkRevisedLicenseKey returns a copy of the license key with the changes of the bulk revision request applied
+// to its latest revision.
+func bulkRevisedLicenseKey(
+	existing types.LicenseKey,
+	body api.BulkLicenseKeyRevisionRequest,
+) (types.LicenseKey, error) {
+	result := existing
+	if existing.NotBefore == nil || existing.ExpiresAt == nil || existing.Payload == nil {
+		return result, errors.New("license key has no revision yet")
+	}
+
+	notBefore := *existing.NotBefore
+	if body.NotBefore != nil {
+		notBefore = *body.NotBefore
+	}
+	expiresAt := *existing.ExpiresAt
+	if body.ExpiresAt != nil {
+		expiresAt = *body.ExpiresAt
+	} else if body.ExtendByDays != nil {
+		expiresAt = expiresAt.AddDate(0, 0, *body.ExtendByDays)
+	}
+	if !expiresAt.After(notBefore) {
+		return result, errors.New("expiresAt must be after notBefore")
+	}
+	payload := existing.Payload
+	if body.Payload != nil {
+		payload = *body.Payload
+	}
+
+	revisedAt := time.Now().UTC().Truncate(time.Microsecond)
+	result.NotBefore = &notBefore
+	result.ExpiresAt = &expiresAt
+	result.Payload = payload
+	result.LastRevisedAt = &revisedAt
+	return result, nil
+}
+
 func deleteLicenseKey(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	log := internalctx.GetLogger(ctx)
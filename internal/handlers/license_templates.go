@@ -23,13 +23,21 @@ import (
 
 func LicenseTemplatesRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Billing"))
-	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor, middleware.VendorBillingFeatureMiddleware)
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceLicenseTemplates, types.ActionRead),
+		middleware.RequireVendor,
+		middleware.VendorBillingFeatureMiddleware,
+	)
 
 	r.Get("/", getLicenseTemplates).
 		With(option.Description("List all license templates")).
 		With(option.Response(http.StatusOK, []types.LicenseTemplate{}))
 
-	r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequirePermission(types.ResourceLicenseTemplates, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).Group(func(r chiopenapi.Router) {
 		r.Post("/", createLicenseTemplate).
 			With(option.Description("Create a new license template")).
 			With(option.Request(api.CreateLicenseTemplateRequest{})).
@@ -5,11 +5,14 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"time"
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authjwt"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/mapping"
@@ -19,6 +22,7 @@ import (
 	"github.com/distr-sh/distr/internal/util"
 	"github.com/distr-sh/distr/internal/validation"
 	"github.com/getsentry/sentry-go"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
 	"github.com/oaswrap/spec/adapter/chiopenapi"
 	"github.com/oaswrap/spec/option"
@@ -34,22 +38,35 @@ func OrganizationRouter(r chiopenapi.Router) {
 		With(option.Response(http.StatusOK, api.OrganizationResponse{}))
 
 	r.With(middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
-		r.Post("/", createOrganization).
+		r.With(middleware.BlockServiceAccount).Post("/", createOrganization).
 			With(option.Description("Create a new organization")).
 			With(option.Request(api.CreateUpdateOrganizationRequest{})).
 			With(option.Response(http.StatusOK, types.OrganizationWithUserRole{}))
 
-		r.With(middleware.RequireVendor, middleware.RequireAdmin).
+		r.With(middleware.RequireVendor, middleware.RequirePermission(types.ResourceOrganization, types.ActionWrite)).
 			Put("/", updateOrganization).
 			With(option.Description("Update current organization")).
 			With(option.Request(api.CreateUpdateOrganizationRequest{})).
 			With(option.Response(http.StatusOK, types.Organization{}))
 	})
 
-	r.With(middleware.RequireVendor, middleware.RequireAdmin, middleware.BlockSuperAdminUnlessOrganizationExpired).
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceOrganization, types.ActionManage),
+		middleware.BlockSuperAdminUnlessOrganizationExpired,
+	).
 		Delete("/", deleteOrganizationHandler()).
 		With(option.Description("Delete current organization"))
 
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceOrganization, types.ActionManage),
+		middleware.BlockImpersonation,
+	).
+		Post("/export", exportOrganizationHandler).
+		With(option.Description("Export current organization into an archive that can be imported by another Distr " +
+			"instance. Secrets are encrypted with the key in the X-Distr-Export-Key header."))
+
 	r.Route("/branding", OrganizationBrandingRouter)
 
 	r.Route("/webhook", func(r chiopenapi.Router) {
@@ -59,11 +76,39 @@ func OrganizationRouter(r chiopenapi.Router) {
 			With(option.Description("Get vendor webhook configuration status")).
 			With(option.Response(http.StatusOK, api.OrganizationWebhookResponse{}))
 
-		r.With(middleware.RequireAdmin, middleware.BlockSuperAdmin).
+		r.With(middleware.RequirePermission(types.ResourceBilling, types.ActionWrite), middleware.BlockSuperAdmin).
 			Put("/", updateOrganizationWebhook).
 			With(option.Description("Set vendor Stripe webhook secret")).
 			With(option.Request(api.UpdateOrganizationWebhookRequest{}))
 	})
+
+	r.Route("/security-policy", func(r chiopenapi.Router) {
+		r.Use(middleware.RequireVendor, middleware.RequirePermission(types.ResourceOrganization, types.ActionRead))
+
+		r.Get("/", getOrganizationSecurityPolicy).
+			With(option.Description("Get the security policy of the current organization")).
+			With(option.Response(http.StatusOK, api.OrganizationSecurityPolicy{}))
+
+		r.With(middleware.RequirePermission(types.ResourceOrganization, types.ActionManage), middleware.BlockSuperAdmin).
+			Put("/", updateOrganizationSecurityPolicy).
+			With(option.Description("Update the security policy of the current organization")).
+			With(option.Request(api.OrganizationSecurityPolicy{})).
+			With(option.Response(http.StatusOK, api.OrganizationSecurityPolicy{}))
+	})
+
+	r.Route("/license-key-expiration-reminders", func(r chiopenapi.Router) {
+		r.Use(middleware.RequireVendor, middleware.LicensingFeatureFlagEnabledMiddleware)
+
+		r.Get("/", getOrganizationLicenseKeyExpirationReminders).
+			With(option.Description("Get license key expiration reminder configuration")).
+			With(option.Response(http.StatusOK, api.LicenseKeyExpirationReminders{}))
+
+		r.With(middleware.RequirePermission(types.ResourceLicenseKeys, types.ActionManage), middleware.BlockSuperAdmin).
+			Put("/", updateOrganizationLicenseKeyExpirationReminders).
+			With(option.Description("Set the number of days before expiry at which license key reminders are sent")).
+			With(option.Request(api.LicenseKeyExpirationReminders{})).
+			With(option.Response(http.StatusOK, api.LicenseKeyExpirationReminders{}))
+	})
 }
 
 func getOrganization(w http.ResponseWriter, r *http.Request) {
@@ -330,3 +375,114 @@ func updateOrganizationWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
+
+func getOrganizationLicenseKeyExpirationReminders(w http.ResponseWriter, r *http.Request) {
+	authCtx := auth.Authentication.Require(r.Context())
+	reminderDays := authCtx.CurrentOrg().LicenseKeyExpirationReminderDays
+	if reminderDays == nil {
+		reminderDays = []int{}
+	}
+	RespondJSON(w, api.LicenseKeyExpirationReminders{ReminderDays: reminderDays})
+}
+
+func updateOrganizationLicenseKeyExpirationReminders(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	authCtx := auth.Authentication.Require(ctx)
+
+	body, err := JsonBody[api.LicenseKeyExpirationReminders](w, r)
+	if err != nil {
+		return
+	}
+
+	if err := body.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	reminderDays := slices.Compact(slices.Sorted(slices.Values(body.ReminderDays)))
+	if err := db.SetOrganizationLicenseKeyExpirationReminderDays(ctx, *authCtx.CurrentOrgID(), reminderDays); err != nil {
+		log.Error("failed to update license key expiration reminders", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	RespondJSON(w, api.LicenseKeyExpirationReminders{ReminderDays: reminderDays})
+}
+
+func getOrganizationSecurityPolicy(w http.ResponseWriter, r *http.Request) {
+	org := auth.Authentication.Require(r.Context()).CurrentOrg()
+	RespondJSON(w, mapping.SecurityPolicyToAPI(org.SecurityPolicy))
+}
+
+func updateOrganizationSecurityPolicy(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	authCtx := auth.Authentication.Require(ctx)
+	org := authCtx.CurrentOrg()
+
+	body, err := JsonBody[api.OrganizationSecurityPolicy](w, r)
+	if err != nil {
+		return
+	} else if err := body.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	policy := types.SecurityPolicy{
+		RequireWebAuthnForAdmins: body.RequireWebAuthnForAdmins,
+		RequireMFA:               body.RequireMFA,
+		IPAllowlist:              body.IPAllowlist,
+		MaxAccessTokenLifetime:   body.MaxAccessTokenLifetime,
+		DisablePasswordLogin:     body.DisablePasswordLogin,
+		SessionIdleTimeout:       body.SessionIdleTimeout,
+	}
+
+	// Otherwise, admins could lock themselves out of the organization by requiring something they do not comply with.
+	var session *types.UserSession
+	if authCtx.CurrentSessionID() != nil {
+		if session, err = db.GetUserSession(ctx, *authCtx.CurrentSessionID(), authCtx.CurrentUserID()); err != nil {
+			log.Error("failed to get user session", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+	}
+	if policy.RequireWebAuthnForAdmins && !org.SecurityPolicy.RequireWebAuthnForAdmins &&
+		!authjwt.IsWebAuthnToken(authCtx.Token()) {
+		http.Error(w, "sign in with a passkey before requiring passkeys for admins", http.StatusBadRequest)
+		return
+	} else if !policy.AllowsAddress(chimiddleware.GetClientIP(ctx)) {
+		http.Error(w, "the IP allowlist must include your current IP address", http.StatusBadRequest)
+		return
+	} else if policy.RequireMFA && !org.SecurityPolicy.RequireMFA &&
+		session != nil && !session.AuthMethod.SatisfiesMFA(authCtx.CurrentUser().MFAEnabled) {
+		http.Error(w, "enable multi-factor authentication before requiring it", http.StatusBadRequest)
+		return
+	} else if policy.DisablePasswordLogin && !org.SecurityPolicy.DisablePasswordLogin {
+		if exists, err := db.ExistsEnabledCustomSSOConfigurationForOrganization(ctx, org.ID); err != nil {
+			log.Error("failed to check SSO configuration", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		} else if !exists {
+			http.Error(w, "configure an identity provider before disabling password logins", http.StatusBadRequest)
+			return
+		} else if session != nil && session.AuthMethod == types.SessionAuthMethodPassword {
+			http.Error(w, "sign in with the identity provider before disabling password logins",
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	if err := db.SetOrganizationSecurityPolicy(ctx, org.ID, policy); err != nil {
+		log.Error("failed to update organization security policy", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	auditlog.RecordChange(ctx, mapping.SecurityPolicyToAPI(org.SecurityPolicy), body)
+	RespondJSON(w, body)
+}
@@ -23,11 +23,15 @@ import (
 )
 
 func OrganizationBrandingRouter(r chiopenapi.Router) {
-	r.Use(middleware.RequireOrgAndRole)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceBranding, types.ActionRead))
 	r.Get("/", getOrganizationBranding).
 		With(option.Description("Get organization branding")).
 		With(option.Response(http.StatusOK, types.OrganizationBranding{}))
-	r.With(middleware.RequireVendor, middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceBranding, types.ActionWrite),
+		middleware.BlockSuperAdmin,
+	).
 		Put("/", upsertOrganizationBranding).
 		With(option.Description("Create or update organization branding")).
 		With(option.Request(api.UpsertOrganizationBrandingRequest{})).
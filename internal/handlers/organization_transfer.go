@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/orgtransfer"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+// OrganizationImportsRouter is mounted without the request size limit of the other API routes, as archives
+// contain the registry blobs of the organization.
+func OrganizationImportsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Organizations"))
+	r.With(middleware.BlockSuperAdmin, middleware.BlockServiceAccount).
+		Post("/", importOrganizationHandler).
+		With(option.Description("Create an organization from the export of another Distr instance")).
+		With(option.Response(http.StatusOK, api.OrganizationImportResponse{}))
+}
+
+func exportOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	log := internalctx.GetLogger(ctx)
+	auditlog.SetAction(ctx, types.AuditLogActionExport)
+
+	orgID := *auth.CurrentOrgID()
+	aw := &archiveWriter{
+		w:        w,
+		filename: fmt.Sprintf("distr-%v-%v.tar.gz", orgID, time.Now().UTC().Format("20060102-150405")),
+	}
+	if err := orgtransfer.Export(ctx, orgID, r.Header.Get(api.OrganizationExportKeyHeader), aw); err != nil {
+		if aw.written {
+			// the status has already been sent, the client will fail to read the truncated archive
+			log.Error("failed to write organization export", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		} else if errors.Is(err, apierrors.ErrBadRequest) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		} else {
+			log.Error("failed to export organization", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+	}
+}
+
+func importOrganizationHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	log := internalctx.GetLogger(ctx)
+
+	dryRun := false
+	if value := r.URL.Query().Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			http.Error(w, "parameter dryRun is invalid", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if ok, err := db.ExistsVendorOrganizationWithUserID(ctx, auth.CurrentUserID()); err != nil {
+		log.Error("failed to check if user is vendor", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	} else if !ok {
+		http.Error(w, "only vendors can create organizations", http.StatusForbidden)
+		return
+	}
+
+	if err := checkOrganizationCreationAllowed(ctx, auth.CurrentUserID()); errors.Is(err, apierrors.ErrBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Error("failed to check organization creation", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result, err := orgtransfer.Import(ctx, r.Body, orgtransfer.ImportOptions{
+		Key:     r.Header.Get(api.OrganizationExportKeyHeader),
+		OwnerID: auth.CurrentUserID(),
+		Name:    r.URL.Query().Get("name"),
+		DryRun:  dryRun,
+	})
+	if errors.Is(err, apierrors.ErrBadRequest) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	} else if err != nil {
+		log.Error("failed to import organization", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	auditlog.SetAction(ctx, types.AuditLogActionImport)
+	if !result.DryRun {
+		auditlog.SetOrganization(ctx, result.OrganizationID)
+		auditlog.SetResource(ctx, "organization", result.OrganizationID.String())
+	}
+	RespondJSON(w, mapping.OrganizationImportResultToAPI(*result))
+}
+
+// archiveWriter sets the download headers right before the first write, so that an error response can still be
+// sent as long as nothing has been written yet.
+type archiveWriter struct {
+	w        http.ResponseWriter
+	filename string
+	written  bool
+}
+
+func (a *archiveWriter) Write(p []byte) (int, error) {
+	if !a.written {
+		a.w.Header().Set("Content-Type", "application/gzip")
+		a.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", a.filename))
+		a.written = true
+	}
+	return a.w.Write(p)
+}
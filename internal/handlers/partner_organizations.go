@@ -21,7 +21,11 @@ import (
 
 func PartnerOrganizationsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Partners"))
-	r.With(middleware.RequireVendor, middleware.RequireOrgAndRole).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourcePartnerOrganizations, types.ActionRead),
+	).Group(func(r chiopenapi.Router) {
 		r.Get("/", getPartnerOrganizationsHandler()).
 			With(option.Description("List all partner organizations")).
 			With(option.Response(http.StatusOK, []api.PartnerOrganizationWithUsage{}))
@@ -31,7 +35,10 @@ func PartnerOrganizationsRouter(r chiopenapi.Router) {
 				PartnerOrganizationID uuid.UUID `path:"partnerOrganizationId"`
 			}
 
-			r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+			r.With(
+				middleware.RequirePermission(types.ResourcePartnerOrganizations, types.ActionWrite),
+				middleware.BlockSuperAdmin,
+			).Group(func(r chiopenapi.Router) {
 				r.Put("/", updatePartnerOrganizationHandler()).
 					With(option.Description("Update a partner organization")).
 					With(option.Request(struct {
@@ -45,7 +52,10 @@ func PartnerOrganizationsRouter(r chiopenapi.Router) {
 			})
 		})
 
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(
+			middleware.RequirePermission(types.ResourcePartnerOrganizations, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).
 			Post("/", createPartnerOrganizationHandler()).
 			With(option.Description("Create a new partner organization")).
 			With(option.Request(api.CreateUpdatePartnerOrganizationRequest{})).
@@ -12,6 +12,7 @@ import (
 	"github.com/distr-sh/distr/internal/env"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/oidc"
+	"github.com/distr-sh/distr/internal/saml"
 	"github.com/distr-sh/distr/internal/types"
 	"github.com/distr-sh/distr/internal/validation"
 	"github.com/getsentry/sentry-go"
@@ -131,13 +132,29 @@ func portalOIDCProviders(ctx context.Context, host portalHost) []api.PortalOIDCP
 		sentry.GetHubFromContext(ctx).CaptureException(err)
 		return nil
 	}
-	return mapping.List(configurations, func(c types.CustomOIDCConfiguration) api.PortalOIDCProvider {
-		return api.PortalOIDCProvider{
-			Name:        c.Name,
-			LoginPath:   oidc.CustomLoginPath(*organization.Slug, c.Slug),
-			SPInitiated: c.SPInitiated,
-		}
-	})
+	samlConfigurations, err := db.GetCustomSAMLConfigurationsForDomain(ctx, host.customDomainRow.ID)
+	if err != nil {
+		internalctx.GetLogger(ctx).Warn("failed to resolve portal SAML providers", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		return nil
+	}
+	return append(
+		mapping.List(configurations, func(c types.CustomOIDCConfiguration) api.PortalOIDCProvider {
+			return api.PortalOIDCProvider{
+				Name:        c.Name,
+				LoginPath:   oidc.CustomLoginPath(*organization.Slug, c.Slug),
+				SPInitiated: c.SPInitiated,
+			}
+		}),
+		// A SAML login always starts at the service provider, with IdP-initiated logins allowed in addition.
+		mapping.List(samlConfigurations, func(c types.CustomSAMLConfiguration) api.PortalOIDCProvider {
+			return api.PortalOIDCProvider{
+				Name:        c.Name,
+				LoginPath:   saml.LoginPath(*organization.Slug, c.Slug),
+				SPInitiated: true,
+			}
+		})...,
+	)
 }
 
 // resolvePortalHost resolves the (normalized) host to the organization it belongs to: self-service CustomDomain
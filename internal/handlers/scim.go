@@ -0,0 +1,945 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/mailsending"
+	"github.com/distr-sh/distr/internal/scim"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+// ScimRouter implements the SCIM 2.0 protocol for identity providers. Every SCIM token is scoped to either the
+// vendor organization or one of its customer organizations, and users and groups are only ever visible in the
+// scope they were provisioned in.
+func ScimRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("SCIM"), option.GroupSecurity("bearer"))
+	r.Use(auth.ScimAuthentication.Middleware, scimFeatureMiddleware)
+
+	r.Get("/ServiceProviderConfig", getScimServiceProviderConfig).
+		With(option.Description("Get the SCIM service provider configuration"))
+	r.Get("/ResourceTypes", getScimResourceTypes).
+		With(option.Description("List the supported SCIM resource types"))
+
+	r.Route("/Users", func(r chiopenapi.Router) {
+		type ScimUserRequest struct {
+			ScimUserID uuid.UUID `path:"scimUserId"`
+		}
+
+		r.Get("/", getScimUsers).
+			With(option.Description("List provisioned users")).
+			With(option.Response(http.StatusOK, scim.ListResponse{}))
+		r.Post("/", createScimUser).
+			With(option.Description("Provision a user")).
+			With(option.Request(scim.User{})).
+			With(option.Response(http.StatusCreated, scim.User{}))
+		r.Get("/{scimUserId}", getScimUser).
+			With(option.Description("Get a provisioned user")).
+			With(option.Request(ScimUserRequest{})).
+			With(option.Response(http.StatusOK, scim.User{}))
+		r.Put("/{scimUserId}", replaceScimUser).
+			With(option.Description("Replace a provisioned user")).
+			With(option.Request(struct {
+				ScimUserRequest
+				scim.User
+			}{})).
+			With(option.Response(http.StatusOK, scim.User{}))
+		r.Patch("/{scimUserId}", patchScimUser).
+			With(option.Description("Partially update a provisioned user, e.g. to deactivate it")).
+			With(option.Request(struct {
+				ScimUserRequest
+				scim.PatchRequest
+			}{})).
+			With(option.Response(http.StatusOK, scim.User{}))
+		r.Delete("/{scimUserId}", deleteScimUser).
+			With(option.Description("Deprovision a user")).
+			With(option.Request(ScimUserRequest{}))
+	})
+
+	r.Route("/Groups", func(r chiopenapi.Router) {
+		type ScimGroupRequest struct {
+			ScimGroupID uuid.UUID `path:"scimGroupId"`
+		}
+
+		r.Get("/", getScimGroups).
+			With(option.Description("List provisioned groups")).
+			With(option.Response(http.StatusOK, scim.ListResponse{}))
+		r.Post("/", createScimGroup).
+			With(option.Description("Provision a group")).
+			With(option.Request(scim.Group{})).
+			With(option.Response(http.StatusCreated, scim.Group{}))
+		r.Get("/{scimGroupId}", getScimGroup).
+			With(option.Description("Get a provisioned group")).
+			With(option.Request(ScimGroupRequest{})).
+			With(option.Response(http.StatusOK, scim.Group{}))
+		r.Put("/{scimGroupId}", replaceScimGroup).
+			With(option.Description("Replace a provisioned group")).
+			With(option.Request(struct {
+				ScimGroupRequest
+				scim.Group
+			}{})).
+			With(option.Response(http.StatusOK, scim.Group{}))
+		r.Patch("/{scimGroupId}", patchScimGroup).
+			With(option.Description("Partially update a provisioned group, e.g. to add or remove members")).
+			With(option.Request(struct {
+				ScimGroupRequest
+				scim.PatchRequest
+			}{})).
+			With(option.Response(http.StatusOK, scim.Group{}))
+		r.Delete("/{scimGroupId}", deleteScimGroup).
+			With(option.Description("Deprovision a group")).
+			With(option.Request(ScimGroupRequest{}))
+	})
+}
+
+// scimFeatureMiddleware rejects requests of organizations (and customer organizations) that are not allowed to
+// connect their own identity provider.
+func scimFeatureMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		token := auth.ScimAuthentication.Require(ctx)
+
+		org, err := db.GetOrganizationByID(ctx, token.OrganizationID)
+		if errors.Is(err, apierrors.ErrNotFound) {
+			respondScimError(w, http.StatusUnauthorized, "", "organization not found")
+			return
+		} else if err != nil {
+			respondScimInternalError(w, r, err)
+			return
+		} else if !org.HasFeature(types.FeatureCustomOidcProviders) {
+			respondScimError(w, http.StatusForbidden, "", "SCIM is not enabled for this organization")
+			return
+		}
+
+		if token.CustomerOrganizationID != nil {
+			customerOrg, err := db.GetCustomerOrganizationByID(ctx, *token.CustomerOrganizationID)
+			if err != nil {
+				respondScimInternalError(w, r, err)
+				return
+			} else if !customerOrg.HasFeature(types.CustomerOrganizationFeatureOidcProviders) {
+				respondScimError(w, http.StatusForbidden, "", "SCIM is not enabled for this customer")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func getScimServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	type supported struct {
+		Supported bool `json:"supported"`
+	}
+	type filterSupported struct {
+		Supported  bool `json:"supported"`
+		MaxResults int  `json:"maxResults"`
+	}
+	type bulkSupported struct {
+		Supported      bool `json:"supported"`
+		MaxOperations  int  `json:"maxOperations"`
+		MaxPayloadSize int  `json:"maxPayloadSize"`
+	}
+	type authenticationScheme struct {
+		Type        string `json:"type"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Primary     bool   `json:"primary"`
+	}
+	respondScim(w, http.StatusOK, struct {
+		Schemas               []string               `json:"schemas"`
+		Patch                 supported              `json:"patch"`
+		Bulk                  bulkSupported          `json:"bulk"`
+		Filter                filterSupported        `json:"filter"`
+		ChangePassword        supported              `json:"changePassword"`
+		Sort                  supported              `json:"sort"`
+		ETag                  supported              `json:"etag"`
+		AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	}{
+		Schemas: []string{scim.SchemaServiceProviderConfig},
+		Patch:   supported{Supported: true},
+		Filter:  filterSupported{Supported: true, MaxResults: 1000},
+		AuthenticationSchemes: []authenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "SCIM Token",
+			Description: "Authentication with a SCIM token created in the Distr organization settings",
+			Primary:     true,
+		}},
+	})
+}
+
+func getScimResourceTypes(w http.ResponseWriter, r *http.Request) {
+	type resourceType struct {
+		Schemas  []string `json:"schemas"`
+		ID       string   `json:"id"`
+		Name     string   `json:"name"`
+		Endpoint string   `json:"endpoint"`
+		Schema   string   `json:"schema"`
+	}
+	respondScim(w, http.StatusOK, scim.NewListResponse([]resourceType{
+		{
+			Schemas:  []string{scim.SchemaResourceType},
+			ID:       scim.ResourceTypeUser,
+			Name:     scim.ResourceTypeUser,
+			Endpoint: "/Users",
+			Schema:   scim.SchemaUser,
+		},
+		{
+			Schemas:  []string{scim.SchemaResourceType},
+			ID:       scim.ResourceTypeGroup,
+			Name:     scim.ResourceTypeGroup,
+			Endpoint: "/Groups",
+			Schema:   scim.SchemaGroup,
+		},
+	}, 1, -1))
+}
+
+func getScimUsers(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	var email, externalID *string
+	if filter, ok := parseScimFilter(w, r); !ok {
+		return
+	} else if filter != nil {
+		switch filter.Attribute {
+		case "username", "emails.value":
+			email = &filter.Value
+		case "externalid":
+			externalID = &filter.Value
+		default:
+			respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidFilter,
+				fmt.Sprintf("filtering by %v is not supported", filter.Attribute))
+			return
+		}
+	}
+
+	startIndex, count, ok := parseScimPagination(w, r)
+	if !ok {
+		return
+	}
+
+	users, err := db.GetScimUsers(ctx, token.OrganizationID, token.CustomerOrganizationID, email, externalID)
+	if err != nil {
+		respondScimInternalError(w, r, err)
+		return
+	}
+
+	resources := make([]scim.User, len(users))
+	for i, user := range users {
+		resources[i] = scimUserToResource(user)
+	}
+	respondScim(w, http.StatusOK, scim.NewListResponse(resources, startIndex, count))
+}
+
+func getScimUser(w http.ResponseWriter, r *http.Request) {
+	if user, ok := getScimUserFromPath(w, r); ok {
+		respondScim(w, http.StatusOK, scimUserToResource(*user))
+	}
+}
+
+func createScimUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	token := auth.ScimAuthentication.Require(ctx)
+
+	body, ok := scimBody[scim.User](w, r)
+	if !ok {
+		return
+	}
+	email := strings.TrimSpace(body.Email())
+	if email == "" {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, "userName is required")
+		return
+	}
+
+	var userID uuid.UUID
+	var inviteUser *types.UserAccount
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		userAccount, err := db.GetUserAccountByEmail(ctx, email)
+		if errors.Is(err, apierrors.ErrNotFound) {
+			userAccount = &types.UserAccount{Email: email, Name: body.FullName()}
+			if err := db.CreateUserAccount(ctx, userAccount); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
+		}
+		userID = userAccount.ID
+
+		if err := db.CreateScimUser(
+			ctx, token.OrganizationID, token.CustomerOrganizationID, userAccount.ID, body.ExternalID, body.IsActive(),
+		); errors.Is(err, apierrors.ErrAlreadyExists) {
+			return apierrors.NewConflict("user is already provisioned")
+		} else if err != nil {
+			return err
+		}
+
+		if body.IsActive() {
+			if invite, err := activateScimUser(ctx, *token, *userAccount); err != nil {
+				return err
+			} else if invite {
+				inviteUser = userAccount
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	if inviteUser != nil {
+		if err := sendScimUserInvite(ctx, *token, *inviteUser); err != nil {
+			log.Warn("failed to send invite mail to provisioned user", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		}
+	}
+
+	if user, err := db.GetScimUser(ctx, token.OrganizationID, token.CustomerOrganizationID, userID); err != nil {
+		respondScimInternalError(w, r, err)
+	} else {
+		respondScim(w, http.StatusCreated, scimUserToResource(*user))
+	}
+}
+
+func replaceScimUser(w http.ResponseWriter, r *http.Request) {
+	current, ok := getScimUserFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := scimBody[scim.User](w, r)
+	if !ok {
+		return
+	}
+	updateScimUser(w, r, *current, body)
+}
+
+func patchScimUser(w http.ResponseWriter, r *http.Request) {
+	current, ok := getScimUserFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := scimBody[scim.PatchRequest](w, r)
+	if !ok {
+		return
+	}
+	desired := scimUserToResource(*current)
+	if err := body.ApplyToUser(&desired); err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		return
+	}
+	updateScimUser(w, r, *current, desired)
+}
+
+// updateScimUser changes the provisioned user to match the desired resource. Deactivating a user removes it from
+// the organization, which revokes its sessions and access tokens, while the provisioned user itself is kept so
+// that it can be reactivated.
+func updateScimUser(w http.ResponseWriter, r *http.Request, current types.ScimUser, desired scim.User) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	token := auth.ScimAuthentication.Require(ctx)
+
+	if !strings.EqualFold(strings.TrimSpace(desired.Email()), current.Email) {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeMutability, "userName cannot be changed")
+		return
+	}
+
+	var inviteUser *types.UserAccount
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		userAccount, err := db.GetUserAccountByID(ctx, current.UserAccountID)
+		if err != nil {
+			return err
+		}
+		if name := desired.FullName(); name != "" && name != userAccount.Name {
+			userAccount.Name = name
+			if err := db.UpdateUserAccount(ctx, userAccount); err != nil {
+				return err
+			}
+		}
+
+		if err := db.UpdateScimUser(
+			ctx, token.OrganizationID, current.UserAccountID, desired.ExternalID, desired.IsActive(),
+		); err != nil {
+			return err
+		}
+
+		if desired.IsActive() && current.UserRole == nil {
+			if invite, err := activateScimUser(ctx, *token, *userAccount); err != nil {
+				return err
+			} else if invite {
+				inviteUser = userAccount
+			}
+		} else if !desired.IsActive() && current.UserRole != nil {
+			if err := removeUserAccountFromOrganization(ctx, current.UserAccountID, token.OrganizationID); err != nil &&
+				!errors.Is(err, apierrors.ErrNotFound) {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	if inviteUser != nil {
+		if err := sendScimUserInvite(ctx, *token, *inviteUser); err != nil {
+			log.Warn("failed to send invite mail to provisioned user", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+		}
+	}
+
+	if user, err := db.GetScimUser(
+		ctx, token.OrganizationID, token.CustomerOrganizationID, current.UserAccountID,
+	); err != nil {
+		respondScimInternalError(w, r, err)
+	} else {
+		respondScim(w, http.StatusOK, scimUserToResource(*user))
+	}
+}
+
+func deleteScimUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	current, ok := getScimUserFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := removeUserAccountFromOrganization(ctx, current.UserAccountID, token.OrganizationID); err != nil &&
+			!errors.Is(err, apierrors.ErrNotFound) {
+			return err
+		}
+		return db.DeleteScimUser(ctx, token.OrganizationID, current.UserAccountID)
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// activateScimUser adds the provisioned user to the scope of the token. The user gets the highest role of its
+// groups, or the read only role if it is not a member of any group with a role. It returns whether the user has
+// to be invited because it has not verified its email address yet.
+func activateScimUser(ctx context.Context, token types.ScimToken, userAccount types.UserAccount) (bool, error) {
+	if existing, err := db.GetUserAccountWithRole(ctx, userAccount.ID, token.OrganizationID, nil, nil); err == nil {
+		if existing.CustomerOrganizationID != nil && token.CustomerOrganizationID != nil &&
+			*existing.CustomerOrganizationID == *token.CustomerOrganizationID ||
+			existing.CustomerOrganizationID == nil && token.CustomerOrganizationID == nil &&
+				existing.PartnerOrganizationID == nil {
+			// the user has been added to the organization manually before it was provisioned
+			return false, syncScimUserRole(ctx, token.OrganizationID, token.CustomerOrganizationID, userAccount.ID)
+		}
+		return false, apierrors.NewConflict("user is already a member of this organization in a different scope")
+	} else if !errors.Is(err, apierrors.ErrNotFound) {
+		return false, err
+	}
+
+	if err := checkMembershipAllowed(ctx, userAccount.ID, token.OrganizationID); err != nil {
+		return false, err
+	}
+
+	org, err := db.GetOrganizationByID(ctx, token.OrganizationID)
+	if err != nil {
+		return false, err
+	}
+	var customerOrg *types.CustomerOrganizationWithUsage
+	if token.CustomerOrganizationID != nil {
+		if customerOrg, err = db.GetCustomerOrganizationByID(ctx, *token.CustomerOrganizationID); err != nil {
+			return false, err
+		}
+	}
+	if limitReached, err := checkUserCreationLimits(ctx, *org, customerOrg); err != nil {
+		return false, err
+	} else if limitReached {
+		return false, apierrors.NewForbidden("user limit reached")
+	}
+
+	role := types.UserRoleReadOnly
+	groupRole, err := scimGroupUserRole(ctx, token.OrganizationID, token.CustomerOrganizationID, userAccount.ID)
+	if err != nil {
+		return false, err
+	} else if groupRole != nil {
+		role = *groupRole
+	}
+
+	if err := db.CreateUserAccountOrganizationAssignment(
+		ctx, userAccount.ID, token.OrganizationID, role, token.CustomerOrganizationID, nil,
+	); err != nil {
+		return false, err
+	}
+	return userAccount.EmailVerifiedAt == nil, nil
+}
+
+// syncScimUserRole sets the role of the user to the highest role of its groups. Roles are only managed through
+// groups if at least one group in the scope is mapped to a role, so that roles can still be managed in Distr
+// otherwise. Users that are not a member of any mapped group get the read only role.
+func syncScimUserRole(ctx context.Context, orgID uuid.UUID, customerOrgID *uuid.UUID, userID uuid.UUID) error {
+	role, err := scimGroupUserRole(ctx, orgID, customerOrgID, userID)
+	if err != nil || role == nil {
+		return err
+	}
+	userAccount, err := db.GetUserAccountWithRole(ctx, userID, orgID, nil, nil)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		// deactivated users are not a member of the organization
+		return nil
+	} else if err != nil {
+		return err
+	} else if userAccount.UserRole == *role {
+		return nil
+	}
+	return db.UpdateUserAccountOrganizationAssignment(
+		ctx, userID, orgID, *role, userAccount.CustomerOrganizationID, userAccount.PartnerOrganizationID)
+}
+
+// scimGroupUserRole returns the role the user should have according to its group memberships, or nil if roles
+// are not managed through groups in the given scope.
+func scimGroupUserRole(
+	ctx context.Context,
+	orgID uuid.UUID,
+	customerOrgID *uuid.UUID,
+	userID uuid.UUID,
+) (*types.UserRole, error) {
+	if managed, err := db.ExistsScimGroupWithUserRole(ctx, orgID, customerOrgID); err != nil {
+		return nil, err
+	} else if !managed {
+		return nil, nil
+	}
+	roles, err := db.GetScimGroupUserRoles(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	} else if len(roles) == 0 {
+		return new(types.UserRoleReadOnly), nil
+	}
+	return new(slices.MaxFunc(roles, func(a, b types.UserRole) int { return a.Rank() - b.Rank() })), nil
+}
+
+func sendScimUserInvite(ctx context.Context, token types.ScimToken, userAccount types.UserAccount) error {
+	organization, err := db.GetOrganizationWithBranding(ctx, token.OrganizationID)
+	if err != nil {
+		return err
+	}
+	inviteURL, err := generateUserInviteUrl(ctx, userAccount, *organization, token.CustomerOrganizationID, true)
+	if err != nil {
+		return err
+	}
+	return mailsending.SendUserInviteMail(ctx, userAccount, *organization, token.CustomerOrganizationID, inviteURL)
+}
+
+func getScimGroups(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	var displayName, externalID *string
+	if filter, ok := parseScimFilter(w, r); !ok {
+		return
+	} else if filter != nil {
+		switch filter.Attribute {
+		case "displayname":
+			displayName = &filter.Value
+		case "externalid":
+			externalID = &filter.Value
+		default:
+			respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidFilter,
+				fmt.Sprintf("filtering by %v is not supported", filter.Attribute))
+			return
+		}
+	}
+
+	startIndex, count, ok := parseScimPagination(w, r)
+	if !ok {
+		return
+	}
+
+	groups, err := db.GetScimGroups(ctx, token.OrganizationID, token.CustomerOrganizationID, displayName, externalID)
+	if err != nil {
+		respondScimInternalError(w, r, err)
+		return
+	}
+
+	// Okta and Entra ID request groups without members to avoid loading large groups.
+	excludeMembers := strings.EqualFold(r.FormValue("excludedAttributes"), "members")
+	resources := make([]scim.Group, len(groups))
+	for i, group := range groups {
+		resources[i] = scimGroupToResource(group)
+		if excludeMembers {
+			resources[i].Members = nil
+		}
+	}
+	respondScim(w, http.StatusOK, scim.NewListResponse(resources, startIndex, count))
+}
+
+func getScimGroup(w http.ResponseWriter, r *http.Request) {
+	if group, ok := getScimGroupFromPath(w, r); ok {
+		respondScim(w, http.StatusOK, scimGroupToResource(*group))
+	}
+}
+
+func createScimGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	body, ok := scimBody[scim.Group](w, r)
+	if !ok {
+		return
+	}
+	if strings.TrimSpace(body.DisplayName) == "" {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName is required")
+		return
+	}
+	memberIDs, err := parseScimMemberIDs(body.MemberValues())
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	group := types.ScimGroup{
+		OrganizationID:         token.OrganizationID,
+		CustomerOrganizationID: token.CustomerOrganizationID,
+		DisplayName:            strings.TrimSpace(body.DisplayName),
+		ExternalID:             body.ExternalID,
+	}
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.CreateScimGroup(ctx, &group); errors.Is(err, apierrors.ErrAlreadyExists) {
+			return apierrors.NewConflict("a group with this displayName already exists")
+		} else if err != nil {
+			return err
+		}
+		// new groups are not mapped to a role yet, so the roles of their members do not change
+		return db.AddScimGroupMembers(ctx, group, memberIDs)
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	if result, err := db.GetScimGroup(ctx, token.OrganizationID, token.CustomerOrganizationID, group.ID); err != nil {
+		respondScimInternalError(w, r, err)
+	} else {
+		respondScim(w, http.StatusCreated, scimGroupToResource(*result))
+	}
+}
+
+func replaceScimGroup(w http.ResponseWriter, r *http.Request) {
+	current, ok := getScimGroupFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := scimBody[scim.Group](w, r)
+	if !ok {
+		return
+	}
+	updateScimGroup(w, r, *current, body)
+}
+
+func patchScimGroup(w http.ResponseWriter, r *http.Request) {
+	current, ok := getScimGroupFromPath(w, r)
+	if !ok {
+		return
+	}
+	body, ok := scimBody[scim.PatchRequest](w, r)
+	if !ok {
+		return
+	}
+	desired := scimGroupToResource(*current)
+	if err := body.ApplyToGroup(&desired); err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		return
+	}
+	updateScimGroup(w, r, *current, desired)
+}
+
+// updateScimGroup changes the provisioned group to match the desired resource and updates the roles of all
+// users whose membership has changed.
+func updateScimGroup(w http.ResponseWriter, r *http.Request, current types.ScimGroup, desired scim.Group) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	if strings.TrimSpace(desired.DisplayName) == "" {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, "displayName is required")
+		return
+	}
+	memberIDs, err := parseScimMemberIDs(desired.MemberValues())
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	var added, removed []uuid.UUID
+	for _, id := range memberIDs {
+		if !slices.Contains(current.MemberIDs, id) {
+			added = append(added, id)
+		}
+	}
+	for _, id := range current.MemberIDs {
+		if !slices.Contains(memberIDs, id) {
+			removed = append(removed, id)
+		}
+	}
+
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		current.DisplayName = strings.TrimSpace(desired.DisplayName)
+		current.ExternalID = desired.ExternalID
+		if err := db.UpdateScimGroup(ctx, &current); errors.Is(err, apierrors.ErrAlreadyExists) {
+			return apierrors.NewConflict("a group with this displayName already exists")
+		} else if err != nil {
+			return err
+		}
+		if err := db.AddScimGroupMembers(ctx, current, added); err != nil {
+			return err
+		}
+		if len(removed) > 0 {
+			if err := db.RemoveScimGroupMembers(ctx, current.ID, removed); err != nil {
+				return err
+			}
+		}
+		if current.UserRole != nil {
+			for _, id := range slices.Concat(added, removed) {
+				if err := syncScimUserRole(ctx, token.OrganizationID, token.CustomerOrganizationID, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+		return
+	}
+
+	if result, err := db.GetScimGroup(ctx, token.OrganizationID, token.CustomerOrganizationID, current.ID); err != nil {
+		respondScimInternalError(w, r, err)
+	} else {
+		respondScim(w, http.StatusOK, scimGroupToResource(*result))
+	}
+}
+
+func deleteScimGroup(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+
+	current, ok := getScimGroupFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.DeleteScimGroup(ctx, current.ID); err != nil {
+			return err
+		}
+		if current.UserRole != nil {
+			for _, id := range current.MemberIDs {
+				if err := syncScimUserRole(ctx, token.OrganizationID, token.CustomerOrganizationID, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		respondScimErr(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getScimUserFromPath(w http.ResponseWriter, r *http.Request) (*types.ScimUser, bool) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+	id, err := uuid.Parse(r.PathValue("scimUserId"))
+	if err != nil {
+		respondScimError(w, http.StatusNotFound, "", "user not found")
+		return nil, false
+	}
+	user, err := db.GetScimUser(ctx, token.OrganizationID, token.CustomerOrganizationID, id)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		respondScimError(w, http.StatusNotFound, "", "user not found")
+		return nil, false
+	} else if err != nil {
+		respondScimInternalError(w, r, err)
+		return nil, false
+	}
+	return user, true
+}
+
+func getScimGroupFromPath(w http.ResponseWriter, r *http.Request) (*types.ScimGroup, bool) {
+	ctx := r.Context()
+	token := auth.ScimAuthentication.Require(ctx)
+	id, err := uuid.Parse(r.PathValue("scimGroupId"))
+	if err != nil {
+		respondScimError(w, http.StatusNotFound, "", "group not found")
+		return nil, false
+	}
+	group, err := db.GetScimGroup(ctx, token.OrganizationID, token.CustomerOrganizationID, id)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		respondScimError(w, http.StatusNotFound, "", "group not found")
+		return nil, false
+	} else if err != nil {
+		respondScimInternalError(w, r, err)
+		return nil, false
+	}
+	return group, true
+}
+
+func scimUserToResource(user types.ScimUser) scim.User {
+	id := user.UserAccountID.String()
+	result := scim.User{
+		Schemas:     []string{scim.SchemaUser},
+		ID:          id,
+		ExternalID:  user.ExternalID,
+		UserName:    user.Email,
+		DisplayName: user.Name,
+		Emails:      []scim.MultiValuedAttribute{{Value: user.Email, Primary: true}},
+		Active:      &user.Active,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeUser,
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+			Location:     scimLocation("Users", id),
+		},
+	}
+	if user.Name != "" {
+		result.Name = &scim.Name{Formatted: user.Name}
+	}
+	for _, groupID := range user.GroupIDs {
+		result.Groups = append(result.Groups, scim.MultiValuedAttribute{
+			Value: groupID.String(),
+			Ref:   scimLocation("Groups", groupID.String()),
+		})
+	}
+	return result
+}
+
+func scimGroupToResource(group types.ScimGroup) scim.Group {
+	id := group.ID.String()
+	result := scim.Group{
+		Schemas:     []string{scim.SchemaGroup},
+		ID:          id,
+		ExternalID:  group.ExternalID,
+		DisplayName: group.DisplayName,
+		Meta: &scim.Meta{
+			ResourceType: scim.ResourceTypeGroup,
+			Created:      &group.CreatedAt,
+			LastModified: &group.UpdatedAt,
+			Location:     scimLocation("Groups", id),
+		},
+	}
+	for _, memberID := range group.MemberIDs {
+		result.Members = append(result.Members, scim.MultiValuedAttribute{
+			Value: memberID.String(),
+			Ref:   scimLocation("Users", memberID.String()),
+		})
+	}
+	return result
+}
+
+func scimLocation(endpoint, id string) string {
+	return fmt.Sprintf("%v/api/scim/v2/%v/%v", env.Host(), endpoint, id)
+}
+
+func parseScimMemberIDs(values []string) ([]uuid.UUID, error) {
+	result := make([]uuid.UUID, 0, len(values))
+	for _, value := range values {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf("invalid member %q", value))
+		}
+		if !slices.Contains(result, id) {
+			result = append(result, id)
+		}
+	}
+	return result, nil
+}
+
+func parseScimFilter(w http.ResponseWriter, r *http.Request) (*scim.Filter, bool) {
+	expr := r.FormValue("filter")
+	if expr == "" {
+		return nil, true
+	}
+	filter, err := scim.ParseFilter(expr)
+	if err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidFilter, err.Error())
+		return nil, false
+	}
+	return filter, true
+}
+
+func parseScimPagination(w http.ResponseWriter, r *http.Request) (startIndex int, count int, ok bool) {
+	startIndex, err := QueryParam(r, "startIndex", strconv.Atoi)
+	if errors.Is(err, ErrParamNotDefined) {
+		startIndex = 1
+	} else if err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		return 0, 0, false
+	}
+	count, err = QueryParam(r, "count", strconv.Atoi, Min(0))
+	if errors.Is(err, ErrParamNotDefined) {
+		count = -1
+	} else if err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+		return 0, 0, false
+	}
+	return startIndex, count, true
+}
+
+func scimBody[T any](w http.ResponseWriter, r *http.Request) (T, bool) {
+	var t T
+	if err := json.NewDecoder(r.Body).Decode(&t); err != nil {
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidSyntax, err.Error())
+		return t, false
+	}
+	return t, true
+}
+
+func respondScim(w http.ResponseWriter, status int, data any) {
+	w.Header().Set("Content-Type", scim.ContentType)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+func respondScimError(w http.ResponseWriter, status int, scimType string, detail string) {
+	respondScim(w, status, scim.NewError(status, scimType, detail))
+}
+
+func respondScimErr(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, apierrors.ErrNotFound):
+		respondScimError(w, http.StatusNotFound, "", err.Error())
+	case errors.Is(err, apierrors.ErrConflict), errors.Is(err, apierrors.ErrAlreadyExists):
+		respondScimError(w, http.StatusConflict, scim.ErrorTypeUniqueness, err.Error())
+	case errors.Is(err, apierrors.ErrBadRequest):
+		respondScimError(w, http.StatusBadRequest, scim.ErrorTypeInvalidValue, err.Error())
+	case errors.Is(err, apierrors.ErrForbidden):
+		respondScimError(w, http.StatusForbidden, "", err.Error())
+	default:
+		respondScimInternalError(w, r, err)
+	}
+}
+
+func respondScimInternalError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	internalctx.GetLogger(ctx).Error("SCIM request failed", zap.Error(err))
+	sentry.GetHubFromContext(ctx).CaptureException(err)
+	respondScim(w, http.StatusInternalServerError, scim.NewErrorFromStatus(http.StatusInternalServerError))
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authkey"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type scimScopeRequest struct {
+	CustomerOrganizationID *uuid.UUID `query:"customerOrganizationId"`
+}
+
+// ScimSettingsRouter manages the SCIM tokens used by identity providers and the mapping of provisioned groups to
+// roles. The SCIM protocol itself is served by ScimRouter.
+func ScimSettingsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("SCIM"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionRead))
+	r.Get("/tokens", getScimTokensHandler).
+		With(option.Description("List the SCIM tokens of the current organization or one of its customers")).
+		With(option.Request(scimScopeRequest{})).
+		With(option.Response(http.StatusOK, []api.ScimToken{}))
+	r.Get("/groups", getScimGroupsHandler).
+		With(option.Description("List the groups provisioned for the current organization or one of its customers")).
+		With(option.Request(scimScopeRequest{})).
+		With(option.Response(http.StatusOK, []api.ScimGroup{}))
+	r.With(
+		middleware.BlockSuperAdmin,
+		middleware.RequirePermission(types.ResourceIdentityProviders, types.ActionWrite),
+	).Group(func(r chiopenapi.Router) {
+		r.Post("/tokens", createScimTokenHandler).
+			With(option.Description("Create a SCIM token for an identity provider")).
+			With(option.Request(api.CreateScimTokenRequest{})).
+			With(option.Response(http.StatusOK, api.ScimTokenWithKey{}))
+		r.Delete("/tokens/{scimTokenId}", deleteScimTokenHandler).
+			With(option.Description("Delete a SCIM token")).
+			With(option.Request(struct {
+				scimScopeRequest
+				ScimTokenID uuid.UUID `path:"scimTokenId"`
+			}{}))
+		r.Put("/groups/{scimGroupId}/role", updateScimGroupUserRoleHandler).
+			With(option.Description("Map a provisioned group to a role, or remove the mapping")).
+			With(option.Request(struct {
+				ScimGroupID uuid.UUID `path:"scimGroupId"`
+				api.UpdateScimGroupUserRoleRequest
+			}{})).
+			With(option.Response(http.StatusOK, api.ScimGroup{}))
+	})
+}
+
+func getScimTokensHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	customerOrgID, ok := resolveScimScopeFromQuery(w, r)
+	if !ok {
+		return
+	}
+	if tokens, err := db.GetScimTokens(ctx, *auth.CurrentOrgID(), customerOrgID); err != nil {
+		respondScimSettingsError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(tokens, mapping.ScimTokenToDTO))
+	}
+}
+
+func createScimTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	request, err := JsonBody[api.CreateScimTokenRequest](w, r)
+	if err != nil {
+		return
+	}
+	customerOrgID, ok := resolveCustomerScopeForWrite(w, r, request.CustomerOrganizationID)
+	if !ok {
+		return
+	}
+
+	key, err := authkey.NewKey()
+	if err != nil {
+		respondScimSettingsError(w, r, err)
+		return
+	}
+	token := types.ScimToken{
+		Label:                  request.Label,
+		Key:                    key,
+		OrganizationID:         *auth.CurrentOrgID(),
+		CustomerOrganizationID: customerOrgID,
+	}
+	if err := db.CreateScimToken(ctx, &token); err != nil {
+		respondScimSettingsError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.ScimTokenToDTO(token).WithKey(token.Key))
+	}
+}
+
+func deleteScimTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	id, err := uuid.Parse(r.PathValue("scimTokenId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	customerOrgID, ok := resolveScimScopeFromQuery(w, r)
+	if !ok {
+		return
+	}
+	if err := db.DeleteScimToken(ctx, id, *auth.CurrentOrgID(), customerOrgID); err != nil {
+		respondScimSettingsError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getScimGroupsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	customerOrgID, ok := resolveScimScopeFromQuery(w, r)
+	if !ok {
+		return
+	}
+	if groups, err := db.GetScimGroups(ctx, *auth.CurrentOrgID(), customerOrgID, nil, nil); err != nil {
+		respondScimSettingsError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(groups, mapping.ScimGroupToDTO))
+	}
+}
+
+// updateScimGroupUserRoleHandler changes the role mapping of a group and then updates the roles of all
+// provisioned users in the scope, because mapping the first group to a role changes the role of every user.
+func updateScimGroupUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	orgID := *auth.CurrentOrgID()
+	id, err := uuid.Parse(r.PathValue("scimGroupId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.UpdateScimGroupUserRoleRequest](w, r)
+	if err != nil {
+		return
+	}
+	customerOrgID, ok := resolveCustomerScopeForWrite(w, r, request.CustomerOrganizationID)
+	if !ok {
+		return
+	}
+
+	var group *types.ScimGroup
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		if _, err := db.GetScimGroup(ctx, orgID, customerOrgID, id); err != nil {
+			return err
+		}
+		if err := db.UpdateScimGroupUserRole(ctx, id, request.UserRole); err != nil {
+			return err
+		}
+		users, err := db.GetScimUsers(ctx, orgID, customerOrgID, nil, nil)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			if err := syncScimUserRole(ctx, orgID, customerOrgID, user.UserAccountID); err != nil {
+				return err
+			}
+		}
+		group, err = db.GetScimGroup(ctx, orgID, customerOrgID, id)
+		return err
+	})
+	if err != nil {
+		respondScimSettingsError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.ScimGroupToDTO(*group))
+	}
+}
+
+func resolveScimScopeFromQuery(w http.ResponseWriter, r *http.Request) (*uuid.UUID, bool) {
+	customerOrgID, err := QueryParam(r, "customerOrganizationId", uuid.Parse)
+	if errors.Is(err, ErrParamNotDefined) {
+		return resolveCustomerScopeForWrite(w, r, nil)
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, false
+	}
+	return resolveCustomerScopeForWrite(w, r, &customerOrgID)
+}
+
+func respondScimSettingsError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	switch {
+	case errors.Is(err, apierrors.ErrNotFound):
+		http.NotFound(w, r)
+	default:
+		internalctx.GetLogger(ctx).Error("SCIM settings request failed", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
@@ -24,14 +25,14 @@ import (
 func SecretsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Secrets"))
 
-	r.Use(middleware.RequireOrgAndRole)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequirePermission(types.ResourceSecrets, types.ActionRead))
 
 	r.Get("/", getSecretsHandler()).
 		With(option.Description("List all secrets")).
 		With(option.Response(http.StatusOK, []api.SecretWithoutValue{}))
 
 	r.Group(func(r chiopenapi.Router) {
-		r.Use(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin)
+		r.Use(middleware.RequirePermission(types.ResourceSecrets, types.ActionWrite), middleware.BlockSuperAdmin)
 
 		r.Post("/", createSecretHandler()).
 			With(option.Description("Create a secret")).
@@ -137,6 +138,7 @@ func createSecretHandler() http.HandlerFunc {
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			}
 		} else {
+			auditlog.RecordChange(ctx, nil, secretAuditState(secret.Secret), "value")
 			w.WriteHeader(http.StatusCreated)
 			RespondJSON(w, mapping.SecretToAPI(*secret))
 		}
@@ -216,6 +218,7 @@ func updateSecretHandler() http.HandlerFunc {
 			return
 		}
 
+		auditlog.RecordChange(ctx, secretAuditState(existing.Secret), secretAuditState(secret.Secret), "value")
 		RespondJSON(w, api.UpdateSecretResponse{
 			SecretWithoutValue:  *mapping.SecretToAPI(*secret),
 			AffectedDeployments: affected,
@@ -269,7 +272,18 @@ func deleteSecretHandler() http.HandlerFunc {
 				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 			}
 		} else {
+			auditlog.RecordChange(ctx, secretAuditState(existing.Secret), nil, "value")
 			w.WriteHeader(http.StatusNoContent)
 		}
 	}
 }
+
+// secretAuditState lets the audit log record that the value of a secret changed, without the value itself.
+func secretAuditState(secret types.Secret) map[string]any {
+	return map[string]any{
+		"id":                     secret.ID,
+		"key":                    secret.Key,
+		"customerOrganizationId": secret.CustomerOrganizationID,
+		"value":                  secret.Value,
+	}
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/auth"
+	"github.com/distr-sh/distr/internal/authkey"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type serviceAccountPathRequest struct {
+	ServiceAccountID uuid.UUID `path:"serviceAccountId"`
+}
+
+type serviceAccountTokenPathRequest struct {
+	serviceAccountPathRequest
+	AccessTokenID uuid.UUID `path:"accessTokenId"`
+}
+
+func ServiceAccountsRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Service Accounts"))
+	r.Use(
+		middleware.RequireOrgAndRole,
+		middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceUserAccounts, types.ActionRead),
+		// Otherwise, a leaked token could be used to mint new ones that outlive its revocation.
+		middleware.BlockServiceAccount,
+	)
+	r.Get("/", getServiceAccountsHandler).
+		With(option.Description("List the service accounts of the current organization")).
+		With(option.Response(http.StatusOK, []api.ServiceAccount{}))
+	r.With(
+		middleware.RequirePermission(types.ResourceUserAccounts, types.ActionManage),
+		middleware.BlockSuperAdmin,
+	).Group(func(r chiopenapi.Router) {
+		r.Post("/", createServiceAccountHandler).
+			With(option.Description("Create a service account")).
+			With(option.Request(api.ServiceAccountRequest{})).
+			With(option.Response(http.StatusOK, api.ServiceAccount{}))
+		r.Route("/{serviceAccountId}", func(r chiopenapi.Router) {
+			r.Use(serviceAccountMiddleware)
+			r.Put("/", updateServiceAccountHandler).
+				With(option.Description("Update the name and role of a service account")).
+				With(option.Request(struct {
+					serviceAccountPathRequest
+					api.ServiceAccountRequest
+				}{})).
+				With(option.Response(http.StatusOK, api.ServiceAccount{}))
+			r.Delete("/", deleteServiceAccountHandler).
+				With(option.Description("Delete a service account and all of its access tokens")).
+				With(option.Request(serviceAccountPathRequest{}))
+			r.Get("/tokens", getServiceAccountTokensHandler).
+				With(option.Description("List the access tokens of a service account")).
+				With(option.Request(serviceAccountPathRequest{})).
+				With(option.Response(http.StatusOK, []api.AccessToken{}))
+			r.Post("/tokens", createServiceAccountTokenHandler).
+				With(option.Description("Create an access token for a service account")).
+				With(option.Request(struct {
+					serviceAccountPathRequest
+					api.CreateAccessTokenRequest
+				}{})).
+				With(option.Response(http.StatusOK, api.AccessTokenWithKey{}))
+			r.Post("/tokens/{accessTokenId}/rotate", rotateServiceAccountTokenHandler).
+				With(option.Description("Replace the key of an access token of a service account. The old key " +
+					"stops working immediately")).
+				With(option.Request(serviceAccountTokenPathRequest{})).
+				With(option.Response(http.StatusOK, api.AccessTokenWithKey{}))
+			r.Delete("/tokens/{accessTokenId}", deleteServiceAccountTokenHandler).
+				With(option.Description("Delete an access token of a service account")).
+				With(option.Request(serviceAccountTokenPathRequest{}))
+			r.Route("/trust-policies", serviceAccountTrustPoliciesRouter)
+		})
+	})
+}
+
+func getServiceAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	if serviceAccounts, err := db.GetServiceAccounts(ctx, *auth.CurrentOrgID()); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(serviceAccounts, mapping.ServiceAccountToAPI))
+	}
+}
+
+func createServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request, err := JsonBody[api.ServiceAccountRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateServiceAccountRequest(w, r, &request) {
+		return
+	}
+
+	orgID := *auth.CurrentOrgID()
+	userAccount := types.UserAccount{Name: request.Name}
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.CreateServiceAccount(ctx, &userAccount, orgID); err != nil {
+			return err
+		} else if err := db.CreateUserAccountOrganizationAssignment(
+			ctx, userAccount.ID, orgID, request.UserRole, nil, nil,
+		); err != nil {
+			return err
+		} else if request.CustomRoleID != nil {
+			return db.UpdateUserAccountCustomRole(ctx, userAccount.ID, orgID, request.CustomRoleID)
+		}
+		return nil
+	}); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else if serviceAccount, err := db.GetServiceAccount(ctx, userAccount.ID, orgID); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, nil, mapping.ServiceAccountToAPI(*serviceAccount))
+		RespondJSON(w, mapping.ServiceAccountToAPI(*serviceAccount))
+	}
+}
+
+func updateServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	existing := internalctx.GetUserAccount(ctx)
+
+	request, err := JsonBody[api.ServiceAccountRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateServiceAccountRequest(w, r, &request) {
+		return
+	}
+
+	orgID := *existing.ServiceAccountOrganizationID
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.UpdateServiceAccountName(ctx, existing.ID, orgID, request.Name); err != nil {
+			return err
+		} else if err := db.UpdateUserAccountOrganizationAssignment(
+			ctx, existing.ID, orgID, request.UserRole, nil, nil,
+		); err != nil {
+			return err
+		} else {
+			return db.UpdateUserAccountCustomRole(ctx, existing.ID, orgID, request.CustomRoleID)
+		}
+	}); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else if serviceAccount, err := db.GetServiceAccount(ctx, existing.ID, orgID); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, mapping.ServiceAccountToAPI(*existing), mapping.ServiceAccountToAPI(*serviceAccount))
+		RespondJSON(w, mapping.ServiceAccountToAPI(*serviceAccount))
+	}
+}
+
+func deleteServiceAccountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+	if err := db.DeleteServiceAccount(
+		ctx, serviceAccount.ID, *serviceAccount.ServiceAccountOrganizationID,
+	); errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, "the service account is still referenced by other resources", http.StatusConflict)
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, mapping.ServiceAccountToAPI(*serviceAccount), nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getServiceAccountTokensHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+	if tokens, err := db.GetAccessTokens(
+		ctx, serviceAccount.ID, *serviceAccount.ServiceAccountOrganizationID,
+	); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(tokens, mapping.AccessTokenToDTO))
+	}
+}
+
+func createServiceAccountTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	request, err := JsonBody[api.CreateAccessTokenRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateCreateAccessTokenRequest(w, r, request) {
+		return
+	}
+
+	key, err := authkey.NewKey()
+	if err != nil {
+		respondServiceAccountError(w, r, err)
+		return
+	}
+	token := types.AccessToken{
+		ExpiresAt:        request.ExpiresAt,
+		Label:            request.Label,
+		UserAccountID:    serviceAccount.ID,
+		Key:              key,
+		OrganizationID:   *serviceAccount.ServiceAccountOrganizationID,
+		UserRole:         request.UserRole,
+		CustomRoleID:     request.CustomRoleID,
+		Scopes:           request.Scopes,
+		UnusedExpiryDays: request.UnusedExpiryDays,
+	}
+	if err := db.CreateAccessToken(ctx, &token); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, nil, mapping.AccessTokenToDTO(token))
+		RespondJSON(w, mapping.AccessTokenToDTO(token).WithKey(token.Key))
+	}
+}
+
+func rotateServiceAccountTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	tokenID, err := uuid.Parse(r.PathValue("accessTokenId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	key, err := authkey.NewKey()
+	if err != nil {
+		respondServiceAccountError(w, r, err)
+		return
+	}
+	if token, err := db.RotateAccessTokenKey(ctx, tokenID, serviceAccount.ID, key); errors.Is(
+		err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, nil, mapping.AccessTokenToDTO(*token))
+		RespondJSON(w, mapping.AccessTokenToDTO(*token).WithKey(token.Key))
+	}
+}
+
+func deleteServiceAccountTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	tokenID, err := uuid.Parse(r.PathValue("accessTokenId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := db.DeleteAccessToken(ctx, tokenID, serviceAccount.ID); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func serviceAccountMiddleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		auth := auth.Authentication.Require(ctx)
+		if id, err := uuid.Parse(r.PathValue("serviceAccountId")); err != nil {
+			http.NotFound(w, r)
+		} else if serviceAccount, err := db.GetServiceAccount(ctx, id, *auth.CurrentOrgID()); errors.Is(
+			err, apierrors.ErrNotFound) {
+			http.NotFound(w, r)
+		} else if err != nil {
+			respondServiceAccountError(w, r, err)
+		} else {
+			h.ServeHTTP(w, r.WithContext(internalctx.WithUserAccount(ctx, serviceAccount)))
+		}
+	})
+}
+
+// validateServiceAccountRequest makes sure that callers can only give a service account permissions they have
+// themselves. Like for users, roles other than admin require a pro subscription.
+func validateServiceAccountRequest(w http.ResponseWriter, r *http.Request, request *api.ServiceAccountRequest) bool {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request.Normalize()
+	if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if (request.UserRole != types.UserRoleAdmin || request.CustomRoleID != nil) &&
+		!auth.CurrentOrg().SubscriptionType.IsPro() {
+		http.Error(w, "service accounts with a role other than admin require a pro subscription", http.StatusForbidden)
+		return false
+	}
+
+	permissions := request.UserRole.Permissions()
+	if request.CustomRoleID != nil {
+		if customRole, err := db.GetCustomRole(ctx, *request.CustomRoleID, *auth.CurrentOrgID()); errors.Is(
+			err, apierrors.ErrNotFound) {
+			http.Error(w, "custom role does not exist", http.StatusBadRequest)
+			return false
+		} else if err != nil {
+			respondServiceAccountError(w, r, err)
+			return false
+		} else {
+			permissions = customRole.Permissions
+		}
+	}
+	if !auth.CurrentPermissions().Contains(permissions) {
+		http.Error(w, "a service account cannot have permissions that you do not have", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+func respondServiceAccountError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	internalctx.GetLogger(ctx).Error("service account request failed", zap.Error(err))
+	sentry.GetHubFromContext(ctx).CaptureException(err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
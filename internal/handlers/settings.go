@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/distr-sh/distr/internal/middleware"
 	"github.com/distr-sh/distr/internal/security"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
 	"github.com/distr-sh/distr/internal/util"
 	"github.com/getsentry/sentry-go"
 	"github.com/go-mailx/mailx"
@@ -33,7 +35,7 @@ func SettingsRouter(r chiopenapi.Router) {
 			With(option.Request(api.UpdateUserAccountRequest{})).
 			With(option.Response(http.StatusOK, types.UserAccount{}))
 
-		r.Post("/email", userSettingsUpdateEmailHandler()).
+		r.With(middleware.BlockImpersonation).Post("/email", userSettingsUpdateEmailHandler()).
 			With(option.Description("Update current user email address")).
 			With(option.Request(api.UpdateUserAccountEmailRequest{})).
 			With(option.Response(http.StatusAccepted, nil))
@@ -47,7 +49,7 @@ func SettingsRouter(r chiopenapi.Router) {
 				With(option.Description("List the identity provider accounts connected to the current user")).
 				With(option.Response(http.StatusOK, []api.UserAccountOIDCIdentity{}))
 
-			r.Delete("/{oidcIdentityId}", deleteOIDCIdentityHandler).
+			r.With(middleware.BlockImpersonation).Delete("/{oidcIdentityId}", deleteOIDCIdentityHandler).
 				With(option.Description("Disconnect an identity provider account from the current user")).
 				With(option.Request(OIDCIdentityIDRequest{}))
 		})
@@ -55,6 +57,7 @@ func SettingsRouter(r chiopenapi.Router) {
 
 	r.Route("/mfa", func(r chiopenapi.Router) {
 		r.WithOptions(option.GroupTags("Security"))
+		r.Use(middleware.BlockImpersonation)
 
 		r.Post("/setup", mfaSetupHandler).
 			With(option.Description("Setup a new TOTP secret for the current user. MFA must still be enabled afterwards")).
@@ -78,6 +81,54 @@ func SettingsRouter(r chiopenapi.Router) {
 		r.Get("/recovery-codes/status", mfaRecoveryCodesStatusHandler).
 			With(option.Description("Get the count of remaining unused recovery codes")).
 			With(option.Response(http.StatusOK, api.MFARecoveryCodesStatusResponse{}))
+
+		r.Route("/webauthn", func(r chiopenapi.Router) {
+			type WebAuthnCredentialIDRequest struct {
+				WebAuthnCredentialID uuid.UUID `path:"webAuthnCredentialId"`
+			}
+
+			r.Get("/", getWebAuthnCredentialsHandler).
+				With(option.Description("List the passkeys of the current user")).
+				With(option.Response(http.StatusOK, []api.WebAuthnCredential{}))
+
+			r.Post("/registration/begin", webAuthnRegistrationBeginHandler).
+				With(option.Description("Begin the registration of a passkey and receive the options for " +
+					"navigator.credentials.create()")).
+				With(option.Response(http.StatusOK, api.BeginWebAuthnRegistrationResponse{}))
+
+			r.Post("/registration/finish", webAuthnRegistrationFinishHandler).
+				With(option.Description("Verify the created credential and save it as a passkey of the current user")).
+				With(option.Request(api.FinishWebAuthnRegistrationRequest{})).
+				With(option.Response(http.StatusOK, api.WebAuthnCredential{}))
+
+			r.Put("/{webAuthnCredentialId}", updateWebAuthnCredentialHandler).
+				With(option.Description("Rename a passkey of the current user")).
+				With(option.Request(struct {
+					WebAuthnCredentialIDRequest
+					api.UpdateWebAuthnCredentialRequest
+				}{}))
+
+			r.Delete("/{webAuthnCredentialId}", deleteWebAuthnCredentialHandler).
+				With(option.Description("Remove a passkey of the current user")).
+				With(option.Request(WebAuthnCredentialIDRequest{}))
+		})
+	})
+
+	r.Route("/sessions", func(r chiopenapi.Router) {
+		r.WithOptions(option.GroupTags("Security"))
+
+		r.Get("/", getUserSessionsHandler).
+			With(option.Description("List the active sessions of the current user")).
+			With(option.Response(http.StatusOK, []api.UserSession{}))
+
+		r.With(middleware.BlockImpersonation).Delete("/", deleteUserSessionsHandler).
+			With(option.Description("Revoke all sessions of the current user except for the current one"))
+
+		r.With(middleware.BlockImpersonation).Delete("/{sessionId}", deleteUserSessionHandler).
+			With(option.Description("Revoke a session of the current user")).
+			With(option.Request(struct {
+				SessionID uuid.UUID `path:"sessionId"`
+			}{}))
 	})
 
 	r.Route("/tokens", func(r chiopenapi.Router) {
@@ -89,7 +140,7 @@ func SettingsRouter(r chiopenapi.Router) {
 			With(option.Description("List all access tokens")).
 			With(option.Response(http.StatusOK, []api.AccessToken{}))
 
-		r.With(middleware.BlockSuperAdmin).Post("/", createAccessTokenHandler()).
+		r.With(middleware.BlockSuperAdmin, middleware.BlockImpersonation).Post("/", createAccessTokenHandler()).
 			With(option.Description("Create a new access token")).
 			With(option.Request(api.CreateAccessTokenRequest{})).
 			With(option.Response(http.StatusCreated, api.AccessTokenWithKey{}))
@@ -129,6 +180,11 @@ func userSettingsUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if body.Password != nil {
+		// Like the endpoints behind middleware.BlockImpersonation, an impersonation must not take over the account.
+		if auth.CurrentImpersonator() != nil {
+			http.Error(w, "impersonating super admins cannot change the password", http.StatusForbidden)
+			return
+		}
 		user.Password = *body.Password
 		if err := security.HashPassword(user); err != nil {
 			sentry.GetHubFromContext(ctx).CaptureException(err)
@@ -145,7 +201,15 @@ func userSettingsUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if isUpdateNeeded {
-		if err := db.UpdateUserAccount(ctx, user); err != nil {
+		if err := db.RunTx(ctx, func(ctx context.Context) error {
+			if err := db.UpdateUserAccount(ctx, user); err != nil {
+				return err
+			} else if body.Password != nil {
+				// Sign out everywhere else, a stolen token must not survive a password change.
+				return userauth.RevokeSessions(ctx, user.ID, auth.CurrentSessionID())
+			}
+			return nil
+		}); err != nil {
 			if errors.Is(err, apierrors.ErrNotFound) {
 				http.Error(w, err.Error(), http.StatusBadRequest)
 			} else {
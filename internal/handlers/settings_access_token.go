@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/authkey"
 	internalctx "github.com/distr-sh/distr/internal/context"
@@ -36,17 +40,22 @@ func createAccessTokenHandler() http.HandlerFunc {
 		ctx := r.Context()
 		log := internalctx.GetLogger(ctx)
 		auth := auth.Authentication.Require(ctx)
+		if auth.WebAuthnRequired() {
+			// Access tokens are not subject to the policy, so they must not be created by a session that is.
+			http.Error(w, "the organization requires you to sign in with a passkey", http.StatusForbidden)
+			return
+		} else if auth.MFARequired() {
+			http.Error(w, "the organization requires you to sign in with multi-factor authentication",
+				http.StatusForbidden)
+			return
+		}
 		request, err := JsonBody[api.CreateAccessTokenRequest](w, r)
 		if err != nil {
 			return
 		}
 
-		if request.UserRole != nil {
-			callerRole := auth.CurrentUserRole()
-			if callerRole == nil || request.UserRole.GreaterThan(*callerRole) {
-				http.Error(w, "token role cannot exceed your own role", http.StatusBadRequest)
-				return
-			}
+		if !validateCreateAccessTokenRequest(w, r, request) {
+			return
 		}
 
 		key, err := authkey.NewKey()
@@ -58,12 +67,15 @@ func createAccessTokenHandler() http.HandlerFunc {
 		}
 
 		token := types.AccessToken{
-			ExpiresAt:      request.ExpiresAt,
-			Label:          request.Label,
-			UserAccountID:  auth.CurrentUserID(),
-			Key:            key,
-			OrganizationID: *auth.CurrentOrgID(),
-			UserRole:       request.UserRole,
+			ExpiresAt:        request.ExpiresAt,
+			Label:            request.Label,
+			UserAccountID:    auth.CurrentUserID(),
+			Key:              key,
+			OrganizationID:   *auth.CurrentOrgID(),
+			UserRole:         request.UserRole,
+			CustomRoleID:     request.CustomRoleID,
+			Scopes:           request.Scopes,
+			UnusedExpiryDays: request.UnusedExpiryDays,
 		}
 		if err := db.CreateAccessToken(ctx, &token); err != nil {
 			log.Warn("error creating token", zap.Error(err))
@@ -94,3 +106,72 @@ func deleteAccessTokenHandler() http.HandlerFunc {
 		}
 	}
 }
+
+// validateCreateAccessTokenRequest enforces the security policy of the organization and makes sure that the role
+// or scopes of a new token never exceed the permissions of the caller.
+func validateCreateAccessTokenRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	request api.CreateAccessTokenRequest,
+) bool {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	auth := auth.Authentication.Require(ctx)
+	if maxLifetime := auth.CurrentOrg().SecurityPolicy.MaxAccessTokenLifetime; maxLifetime != nil {
+		if request.ExpiresAt == nil {
+			http.Error(w, "the organization requires access tokens to expire", http.StatusBadRequest)
+			return false
+		} else if request.ExpiresAt.After(time.Now().Add(time.Duration(*maxLifetime))) {
+			http.Error(w, fmt.Sprintf("the organization allows access tokens to be valid for at most %v",
+				maxLifetime), http.StatusBadRequest)
+			return false
+		}
+	}
+
+	if len(auth.CurrentAccessTokenScopes()) > 0 {
+		// Otherwise, the new token could reach artifacts that the scopes of this one do not.
+		http.Error(w, "access tokens with scopes cannot create access tokens", http.StatusForbidden)
+		return false
+	} else if request.UnusedExpiryDays != nil && *request.UnusedExpiryDays <= 0 {
+		http.Error(w, "unusedExpiryDays must be positive", http.StatusBadRequest)
+		return false
+	}
+
+	if (request.UserRole != nil || request.CustomRoleID != nil) && len(request.Scopes) > 0 {
+		http.Error(w, "scopes cannot be combined with userRole or customRoleId", http.StatusBadRequest)
+		return false
+	} else if request.UserRole != nil && request.CustomRoleID != nil {
+		http.Error(w, "userRole and customRoleId cannot be combined", http.StatusBadRequest)
+		return false
+	} else if len(request.Scopes) > 0 {
+		if !auth.CurrentPermissions().Contains(request.Scopes.Permissions()) {
+			http.Error(w, "token scopes cannot exceed your own role", http.StatusBadRequest)
+			return false
+		}
+	} else if request.UserRole != nil {
+		if !auth.CurrentPermissions().Contains(request.UserRole.Permissions()) {
+			http.Error(w, "token role cannot exceed your own role", http.StatusBadRequest)
+			return false
+		}
+	} else if request.CustomRoleID != nil {
+		if auth.CurrentCustomerOrgID() != nil || auth.CurrentPartnerOrgID() != nil {
+			http.Error(w, "custom roles are only available to members of the vendor organization",
+				http.StatusBadRequest)
+			return false
+		}
+		customRole, err := db.GetCustomRole(ctx, *request.CustomRoleID, *auth.CurrentOrgID())
+		if errors.Is(err, apierrors.ErrNotFound) {
+			http.Error(w, "custom role does not exist", http.StatusBadRequest)
+			return false
+		} else if err != nil {
+			log.Warn("error getting custom role", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return false
+		} else if !auth.CurrentPermissions().Contains(customRole.Permissions) {
+			http.Error(w, "token role cannot exceed your own role", http.StatusBadRequest)
+			return false
+		}
+	}
+	return true
+}
@@ -15,6 +15,7 @@ import (
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/security"
 	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
 	"github.com/getsentry/sentry-go"
 	"github.com/pquerna/otp"
 	"github.com/pquerna/otp/totp"
@@ -154,6 +155,8 @@ func mfaEnableHandler(w http.ResponseWriter, r *http.Request) {
 	err = db.RunTx(ctx, func(ctx context.Context) error {
 		if err := db.EnableUserAccountMFA(ctx, userID); err != nil {
 			return err
+		} else if err := userauth.RevokeSessions(ctx, userID, authInfo.CurrentSessionID()); err != nil {
+			return err
 		}
 		return db.CreateMFARecoveryCodes(ctx, userID, recoveryCodeRecords)
 	})
@@ -210,6 +213,8 @@ func mfaDisableHandler(w http.ResponseWriter, r *http.Request) {
 	err = db.RunTx(ctx, func(ctx context.Context) error {
 		if err := db.DisableUserAccountMFA(ctx, userID); err != nil {
 			return err
+		} else if err := userauth.RevokeSessions(ctx, userID, authInfo.CurrentSessionID()); err != nil {
+			return err
 		}
 		return db.DeleteAllMFARecoveryCodes(ctx, userID)
 	})
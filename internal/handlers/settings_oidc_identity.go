@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"errors"
 	"net/http"
 
@@ -43,8 +44,8 @@ func deleteOIDCIdentityHandler(w http.ResponseWriter, r *http.Request) {
 	// Accounts created via OIDC have no password, so disconnecting the only identity
 	// would leave the user without any way to sign in.
 	if len(user.PasswordHash) == 0 {
-		if count, err := db.CountUserAccountOIDCIdentities(ctx, user.ID); err != nil {
-			log.Warn("error counting OIDC identities", zap.Error(err))
+		if count, err := countPasswordlessSignInMethods(ctx, user.ID); err != nil {
+			log.Warn("error counting sign in methods", zap.Error(err))
 			sentry.GetHubFromContext(ctx).CaptureException(err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -68,3 +69,14 @@ func deleteOIDCIdentityHandler(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNoContent)
 	}
 }
+
+// countPasswordlessSignInMethods counts the connected identity provider accounts and the passkeys of the user.
+func countPasswordlessSignInMethods(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if identities, err := db.CountUserAccountOIDCIdentities(ctx, userID); err != nil {
+		return 0, err
+	} else if credentials, err := db.CountWebAuthnCredentials(ctx, userID); err != nil {
+		return 0, err
+	} else {
+		return identities + credentials, nil
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
+	"github.com/distr-sh/distr/internal/webauthn"
+	"github.com/getsentry/sentry-go"
+	"github.com/go-webauthn/webauthn/protocol"
+	gowebauthn "github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func getWebAuthnCredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	if credentials, err := db.GetWebAuthnCredentials(ctx, auth.CurrentUserID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get WebAuthn credentials", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.List(credentials, mapping.WebAuthnCredentialToAPI))
+	}
+}
+
+func webAuthnRegistrationBeginHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	auth := auth.Authentication.Require(ctx)
+	user := auth.CurrentUser()
+
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to configure WebAuthn", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	credentials, err := db.GetWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to get WebAuthn credentials", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	webAuthnUser := &webauthn.User{Account: *user, Credentials: credentials}
+
+	// Passkey logins do not ask for an email address, so the credential must be discoverable, and as it is the
+	// only factor, the authenticator must verify the user.
+	creation, session, err := rp.BeginRegistration(webAuthnUser,
+		gowebauthn.WithExclusions(gowebauthn.Credentials(webAuthnUser.WebAuthnCredentials()).CredentialDescriptors()),
+		gowebauthn.WithAuthenticatorSelection(protocol.AuthenticatorSelection{
+			ResidentKey:        protocol.ResidentKeyRequirementRequired,
+			RequireResidentKey: new(true),
+			UserVerification:   protocol.VerificationRequired,
+		}),
+	)
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to begin WebAuthn registration", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if sessionID, err := db.CreateWebAuthnSession(ctx, &user.ID, *session); err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to save WebAuthn session", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, api.BeginWebAuthnRegistrationResponse{SessionID: sessionID, Options: creation})
+	}
+}
+
+func webAuthnRegistrationFinishHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	auth := auth.Authentication.Require(ctx)
+	user := auth.CurrentUser()
+
+	request, err := JsonBody[api.FinishWebAuthnRegistrationRequest](w, r)
+	if err != nil {
+		return
+	} else if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rp, err := webauthn.RelyingParty()
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to configure WebAuthn", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	session, err := db.DeleteWebAuthnSession(ctx, request.SessionID)
+	if errors.Is(err, apierrors.ErrNotFound) ||
+		(err == nil && (session.Expired() || session.UserAccountID == nil || *session.UserAccountID != user.ID)) {
+		http.Error(w, "registration session is invalid or expired", http.StatusBadRequest)
+		return
+	} else if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to get WebAuthn session", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(request.Credential)
+	if err != nil {
+		http.Error(w, "invalid credential: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	credentials, err := db.GetWebAuthnCredentials(ctx, user.ID)
+	if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to get WebAuthn credentials", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	credential, err := rp.CreateCredential(
+		&webauthn.User{Account: *user, Credentials: credentials}, session.SessionData, parsed)
+	if err != nil {
+		log.Info("WebAuthn registration failed", zap.Error(err))
+		http.Error(w, "invalid credential: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Like enabling MFA, adding a sign in method signs the user out everywhere else.
+	record := types.WebAuthnCredential{UserAccountID: user.ID, Name: request.Name, Credential: *credential}
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.CreateWebAuthnCredential(ctx, &record); err != nil {
+			return err
+		}
+		return userauth.RevokeSessions(ctx, user.ID, auth.CurrentSessionID())
+	}); errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, "a passkey with this name already exists or the passkey is already registered",
+			http.StatusConflict)
+	} else if err != nil {
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		log.Error("failed to save WebAuthn credential", zap.Error(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.WebAuthnCredentialToAPI(record))
+	}
+}
+
+func updateWebAuthnCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("webAuthnCredentialId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.UpdateWebAuthnCredentialRequest](w, r)
+	if err != nil {
+		return
+	} else if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := db.UpdateWebAuthnCredentialName(ctx, id, auth.CurrentUserID(), request.Name); errors.Is(
+		err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if errors.Is(err, apierrors.ErrConflict) {
+		http.Error(w, "a passkey with this name already exists", http.StatusConflict)
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to update WebAuthn credential", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func deleteWebAuthnCredentialHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	auth := auth.Authentication.Require(ctx)
+	user := auth.CurrentUser()
+
+	id, err := uuid.Parse(r.PathValue("webAuthnCredentialId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Like for identity provider accounts, users without a password must keep at least one way to sign in.
+	if len(user.PasswordHash) == 0 {
+		if count, err := countPasswordlessSignInMethods(ctx, user.ID); err != nil {
+			log.Warn("error counting sign in methods", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		} else if count <= 1 {
+			http.Error(w,
+				"this is the only way to sign in to your account. Set a password before removing it",
+				http.StatusConflict)
+			return
+		}
+	}
+
+	if err := db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.DeleteWebAuthnCredential(ctx, id, user.ID); err != nil {
+			return err
+		}
+		return userauth.RevokeSessions(ctx, user.ID, auth.CurrentSessionID())
+	}); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		log.Error("failed to delete WebAuthn credential", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/mapping"
 	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/distr-sh/distr/internal/util"
 	"github.com/getsentry/sentry-go"
 	"github.com/google/uuid"
@@ -34,7 +35,10 @@ func SidebarLinksRouter(r chiopenapi.Router) {
 		With(option.Response(http.StatusOK, []api.SidebarLink{}))
 
 	r.Group(func(r chiopenapi.Router) {
-		r.Use(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin)
+		r.Use(
+			middleware.RequirePermission(types.ResourceCustomerOrganizations, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		)
 
 		r.Post("/", createSidebarLinkHandler()).
 			With(option.Description("Create a sidebar link for a customer organization")).
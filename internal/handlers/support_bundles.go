@@ -31,12 +31,19 @@ import (
 func SupportBundlesRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Support Bundles"))
 
-	r.With(middleware.RequireVendor, middleware.RequireOrgAndRole).Route("/configuration", func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequireVendor,
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceSupportBundles, types.ActionRead),
+	).Route("/configuration", func(r chiopenapi.Router) {
 		r.Get("/", getSupportBundleConfigurationHandler()).
 			With(option.Description("Get support bundle configuration")).
 			With(option.Response(http.StatusOK, []api.SupportBundleConfigurationEnvVar{}))
 
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Group(func(r chiopenapi.Router) {
+		r.With(
+			middleware.RequirePermission(types.ResourceSupportBundles, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).Group(func(r chiopenapi.Router) {
 			r.Put("/", createOrUpdateSupportBundleConfigurationHandler()).
 				With(option.Description("Create or update support bundle configuration")).
 				With(option.Request(api.CreateUpdateSupportBundleConfigurationRequest{})).
@@ -44,12 +51,15 @@ func SupportBundlesRouter(r chiopenapi.Router) {
 		})
 	})
 
-	r.With(middleware.RequireOrgAndRole).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceSupportBundles, types.ActionRead),
+	).Group(func(r chiopenapi.Router) {
 		r.Get("/", getSupportBundlesHandler()).
 			With(option.Description("List support bundles")).
 			With(option.Response(http.StatusOK, []api.SupportBundle{}))
 
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(middleware.RequirePermission(types.ResourceSupportBundles, types.ActionWrite), middleware.BlockSuperAdmin).
 			Post("/", createSupportBundleHandler()).
 			With(option.Description("Create a new support bundle")).
 			With(option.Request(api.CreateSupportBundleRequest{})).
@@ -70,7 +80,7 @@ func SupportBundlesRouter(r chiopenapi.Router) {
 				With(option.Request(BundleIDRequest{})).
 				With(option.Response(http.StatusOK, nil, option.ContentType("application/zip")))
 
-			r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+			r.With(middleware.RequirePermission(types.ResourceSupportBundles, types.ActionWrite), middleware.BlockSuperAdmin).
 				Patch("/status", updateSupportBundleStatusHandler()).
 				With(option.Description("Update support bundle status")).
 				With(option.Request(struct {
@@ -78,7 +88,7 @@ func SupportBundlesRouter(r chiopenapi.Router) {
 					api.UpdateSupportBundleStatusRequest
 				}{}))
 
-			r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+			r.With(middleware.RequirePermission(types.ResourceSupportBundles, types.ActionWrite), middleware.BlockSuperAdmin).
 				Post("/comments", createSupportBundleCommentHandler()).
 				With(option.Description("Create a support bundle comment")).
 				With(option.Request(struct {
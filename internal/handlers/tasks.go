@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/middleware"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type taskPathRequest struct {
+	TaskID uuid.UUID `path:"taskId"`
+}
+
+// TasksRouter lets clients follow the background tasks that endpoints like the artifact sync enqueue.
+func TasksRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Tasks"))
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor)
+	r.Get("/{taskId}", getTaskHandler).
+		With(option.Description("Get the status, progress and result of a background task")).
+		With(option.Request(taskPathRequest{})).
+		With(option.Response(http.StatusOK, api.Task{}))
+}
+
+func getTaskHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+	id, err := uuid.Parse(r.PathValue("taskId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if task, err := db.GetTask(ctx, id, auth.CurrentOrgID()); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondTaskError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.TaskToAPI(*task))
+	}
+}
+
+func respondTaskError(w http.ResponseWriter, r *http.Request, err error) {
+	ctx := r.Context()
+	internalctx.GetLogger(ctx).Error("task request failed", zap.Error(err))
+	sentry.GetHubFromContext(ctx).CaptureException(err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
@@ -25,7 +25,8 @@ import (
 
 func TutorialsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupHidden(true))
-	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor, middleware.RequireAdmin)
+	r.Use(middleware.RequireOrgAndRole, middleware.RequireVendor,
+		middleware.RequirePermission(types.ResourceOrganization, types.ActionWrite))
 	r.Get("/", getTutorialProgresses)
 	r.Route("/{tutorial}", func(r chiopenapi.Router) {
 		r.Get("/", getTutorialProgress)
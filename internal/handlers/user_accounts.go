@@ -10,6 +10,7 @@ import (
 
 	"github.com/distr-sh/distr/api"
 	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/authjwt"
 	internalctx "github.com/distr-sh/distr/internal/context"
@@ -31,16 +32,22 @@ import (
 
 func UserAccountsRouter(r chiopenapi.Router) {
 	r.WithOptions(option.GroupTags("Users"))
-	r.With(middleware.RequireOrgAndRole).Group(func(r chiopenapi.Router) {
+	r.With(
+		middleware.RequireOrgAndRole,
+		middleware.RequirePermission(types.ResourceUserAccounts, types.ActionRead),
+	).Group(func(r chiopenapi.Router) {
 		r.Get("/", getUserAccountsHandler).
 			With(option.Description("List all user accounts")).
 			With(option.Response(http.StatusOK, []api.UserAccountResponse{}))
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).
+		r.With(middleware.RequirePermission(types.ResourceUserAccounts, types.ActionWrite), middleware.BlockSuperAdmin).
 			Post("/", createUserAccountHandler).
 			With(option.Description("Create a new user account")).
 			With(option.Request(api.CreateUserAccountRequest{})).
 			With(option.Response(http.StatusOK, api.CreateUserAccountResponse{}))
-		r.With(middleware.RequireReadWriteOrAdmin, middleware.BlockSuperAdmin).Route("/{userId}", func(r chiopenapi.Router) {
+		r.With(
+			middleware.RequirePermission(types.ResourceUserAccounts, types.ActionWrite),
+			middleware.BlockSuperAdmin,
+		).Route("/{userId}", func(r chiopenapi.Router) {
 			type UserAccountRequest struct {
 				UserId string `json:"-" path:"userId"`
 			}
@@ -70,6 +77,19 @@ func UserAccountsRouter(r chiopenapi.Router) {
 					api.PatchImageRequest
 				}{})).
 				With(option.Response(http.StatusOK, api.UserAccountResponse{}))
+			r.Get("/sessions", getUserAccountSessionsHandler).
+				With(option.Description("List the active sessions of a user account in the current organization")).
+				With(option.Request(UserAccountRequest{})).
+				With(option.Response(http.StatusOK, []api.UserSession{}))
+			r.Delete("/sessions", deleteUserAccountSessionsHandler).
+				With(option.Description("Revoke all sessions of a user account in the current organization")).
+				With(option.Request(UserAccountRequest{}))
+			r.Delete("/sessions/{sessionId}", deleteUserAccountSessionHandler).
+				With(option.Description("Revoke a session of a user account in the current organization")).
+				With(option.Request(struct {
+					UserAccountRequest
+					SessionID uuid.UUID `path:"sessionId"`
+				}{}))
 			r.With(inviteUserRateLimiter).
 				Post("/invite", resendUserInviteHandler()).
 				With(option.Description("Resend user invite")).
@@ -160,7 +180,7 @@ func createUserAccountHandler(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
-		if body.UserRole != types.UserRoleAdmin && !organization.SubscriptionType.IsPro() {
+		if (body.UserRole != types.UserRoleAdmin || body.CustomRoleID != nil) && !organization.SubscriptionType.IsPro() {
 			err = errors.New("creating non-admin users requires a pro subscription")
 			http.Error(w, err.Error(), http.StatusForbidden)
 			return err
@@ -223,6 +243,14 @@ func createUserAccountHandler(w http.ResponseWriter, r *http.Request) {
 			return err
 		}
 
+		if body.CustomRoleID != nil {
+			if err := db.UpdateUserAccountCustomRole(ctx, userAccount.ID, organization.ID, body.CustomRoleID); err != nil {
+				sentry.GetHubFromContext(ctx).CaptureException(err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return err
+			}
+		}
+
 		if !userHasExisted || userAccount.EmailVerifiedAt == nil {
 			if emailInviteURL, err = generateUserInviteUrl(
 				ctx, userAccount, *organization, body.CustomerOrganizationID, true); err != nil {
@@ -258,38 +286,38 @@ func createUserAccountHandler(w http.ResponseWriter, r *http.Request) {
 
 	RespondJSON(w, api.CreateUserAccountResponse{
 		User: userAccount.AsUserAccountWithRole(
-			body.UserRole, body.CustomerOrganizationID, body.PartnerOrganizationID, time.Now()),
+			body.UserRole, body.CustomerOrganizationID, body.PartnerOrganizationID, body.CustomRoleID, time.Now()),
 		InviteURL: responseInviteURL,
 	})
 }
 
 func validateCreateUserAccount(ctx context.Context, body *api.CreateUserAccountRequest) error {
 	auth := auth.Authentication.Require(ctx)
+	canManageUsers := auth.CurrentPermissions().Has(types.ResourceUserAccounts, types.ActionManage)
 
 	if customerOrgID := auth.CurrentCustomerOrgID(); customerOrgID != nil {
-		if *auth.CurrentUserRole() != types.UserRoleAdmin {
-			return apierrors.NewForbidden("must be admin to create users")
+		if !canManageUsers {
+			return apierrors.NewForbidden("must be allowed to manage users to create users")
 		}
 		body.CustomerOrganizationID = customerOrgID
 	} else if partnerOrgID := auth.CurrentPartnerOrgID(); partnerOrgID != nil {
 		// Partners can only create users in their own partner org or their assigned customers.
 		if body.PartnerOrganizationID != nil {
-			if *auth.CurrentUserRole() != types.UserRoleAdmin {
-				return apierrors.NewForbidden("must be admin to create users in the given scope")
+			if !canManageUsers {
+				return apierrors.NewForbidden("must be allowed to manage users to create users in the given scope")
 			}
 			if *body.PartnerOrganizationID != *partnerOrgID {
 				return apierrors.NewForbidden("cannot create users for a different partner organization")
 			}
 		}
 		if body.CustomerOrganizationID == nil {
-			if *auth.CurrentUserRole() != types.UserRoleAdmin {
-				return apierrors.NewForbidden("must be admin to create users in the given scope")
+			if !canManageUsers {
+				return apierrors.NewForbidden("must be allowed to manage users to create users in the given scope")
 			}
 			body.PartnerOrganizationID = partnerOrgID
 		}
-	} else if *auth.CurrentUserRole() != types.UserRoleAdmin &&
-		body.CustomerOrganizationID == nil && body.PartnerOrganizationID == nil {
-		return apierrors.NewForbidden("must be admin to create users in the given scope")
+	} else if !canManageUsers && body.CustomerOrganizationID == nil && body.PartnerOrganizationID == nil {
+		return apierrors.NewForbidden("must be allowed to manage users to create users in the given scope")
 	}
 
 	if body.CustomerOrganizationID != nil && body.PartnerOrganizationID != nil {
@@ -305,6 +333,41 @@ func validateCreateUserAccount(ctx context.Context, body *api.CreateUserAccountR
 		}
 	}
 
+	return checkRoleAssignable(
+		ctx, body.UserRole, body.CustomRoleID, body.CustomerOrganizationID, body.PartnerOrganizationID)
+}
+
+// checkRoleAssignable verifies that the caller may assign the role to a user in the given scope. Custom roles
+// are only available to members of the vendor organization, and members of the vendor organization can only be
+// assigned roles that grant no permissions beyond the caller's own.
+func checkRoleAssignable(
+	ctx context.Context,
+	userRole types.UserRole,
+	customRoleID *uuid.UUID,
+	customerOrganizationID, partnerOrganizationID *uuid.UUID,
+) error {
+	if customerOrganizationID != nil || partnerOrganizationID != nil {
+		if customRoleID != nil {
+			return apierrors.NewBadRequest("custom roles cannot be assigned to customer or partner users")
+		}
+		return nil
+	}
+
+	auth := auth.Authentication.Require(ctx)
+	permissions := userRole.Permissions()
+	if customRoleID != nil {
+		if customRole, err := db.GetCustomRole(ctx, *customRoleID, *auth.CurrentOrgID()); errors.Is(
+			err, apierrors.ErrNotFound) {
+			return apierrors.NewBadRequest("custom role does not exist")
+		} else if err != nil {
+			return fmt.Errorf("failed to get custom role: %w", err)
+		} else {
+			permissions = customRole.Permissions
+		}
+	}
+	if !auth.CurrentPermissions().Contains(permissions) {
+		return apierrors.NewForbidden("cannot assign a role with permissions that you do not have")
+	}
 	return nil
 }
 
@@ -349,6 +412,25 @@ func checkUserCreationLimits(
 	return limitReached, nil
 }
 
+// patchedRoleAssignment returns the role assignment of userAccount after applying body and whether it changed.
+// The custom role is only changed if body sets CustomRoleID or RemoveCustomRole.
+func patchedRoleAssignment(
+	userAccount types.UserAccountWithUserRole,
+	body api.PatchUserAccountRequest,
+) (types.UserRole, *uuid.UUID, bool) {
+	userRole, customRoleID := userAccount.UserRole, userAccount.CustomRoleID
+	if body.UserRole != nil {
+		userRole = *body.UserRole
+	}
+	if body.CustomRoleID != nil {
+		customRoleID = body.CustomRoleID
+	} else if body.RemoveCustomRole {
+		customRoleID = nil
+	}
+	return userRole, customRoleID,
+		userRole != userAccount.UserRole || !util.PtrEq(customRoleID, userAccount.CustomRoleID)
+}
+
 func patchUserAccountHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -361,6 +443,10 @@ func patchUserAccountHandler() http.HandlerFunc {
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
+		if err := body.Validate(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 
 		if err := checkUserAccountWritability(ctx, *userAccount); err != nil {
 			if errors.Is(err, apierrors.ErrForbidden) {
@@ -373,6 +459,7 @@ func patchUserAccountHandler() http.HandlerFunc {
 			return
 		}
 
+		before := mapping.UserAccountToAPI(*userAccount)
 		isUpdateNeeded := false
 
 		if body.Name != nil && *body.Name != userAccount.Name {
@@ -380,19 +467,37 @@ func patchUserAccountHandler() http.HandlerFunc {
 			isUpdateNeeded = true
 		}
 
-		if body.UserRole != nil && *body.UserRole != userAccount.UserRole {
+		if userRole, customRoleID, changed := patchedRoleAssignment(*userAccount, body); changed {
 			if userAccount.ID == auth.CurrentUserID() {
 				http.Error(w, "users cannot change their own role", http.StatusForbidden)
 				return
 			}
-			err = db.UpdateUserAccountOrganizationAssignment(
-				ctx,
-				userAccount.ID,
-				*auth.CurrentOrgID(),
-				*body.UserRole,
-				userAccount.CustomerOrganizationID,
-				userAccount.PartnerOrganizationID,
-			)
+			if err := checkRoleAssignable(ctx, userRole, customRoleID,
+				userAccount.CustomerOrganizationID, userAccount.PartnerOrganizationID); err != nil {
+				if errors.Is(err, apierrors.ErrBadRequest) {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+				} else if errors.Is(err, apierrors.ErrForbidden) {
+					http.Error(w, err.Error(), http.StatusForbidden)
+				} else {
+					log.Error("failed to check role assignment", zap.Error(err))
+					sentry.GetHubFromContext(ctx).CaptureException(err)
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+				return
+			}
+			err = db.RunTx(ctx, func(ctx context.Context) error {
+				if err := db.UpdateUserAccountOrganizationAssignment(
+					ctx,
+					userAccount.ID,
+					*auth.CurrentOrgID(),
+					userRole,
+					userAccount.CustomerOrganizationID,
+					userAccount.PartnerOrganizationID,
+				); err != nil {
+					return err
+				}
+				return db.UpdateUserAccountCustomRole(ctx, userAccount.ID, *auth.CurrentOrgID(), customRoleID)
+			})
 			if errors.Is(err, apierrors.ErrNotFound) {
 				http.NotFound(w, r)
 				return
@@ -402,7 +507,8 @@ func patchUserAccountHandler() http.HandlerFunc {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			} else {
-				userAccount.UserRole = *body.UserRole
+				userAccount.UserRole = userRole
+				userAccount.CustomRoleID = customRoleID
 			}
 		}
 
@@ -418,11 +524,14 @@ func patchUserAccountHandler() http.HandlerFunc {
 				userAccount.UserRole,
 				userAccount.CustomerOrganizationID,
 				userAccount.PartnerOrganizationID,
+				userAccount.CustomRoleID,
 				userAccount.JoinedOrgAt,
 			)
 		}
 
-		RespondJSON(w, mapping.UserAccountToAPI(*userAccount))
+		after := mapping.UserAccountToAPI(*userAccount)
+		auditlog.RecordChange(ctx, before, after)
+		RespondJSON(w, after)
 	}
 }
 
@@ -511,20 +620,13 @@ func deleteUserAccountHandler(w http.ResponseWriter, r *http.Request) {
 	if userAccount.ID == auth.CurrentUserID() {
 		http.Error(w, "UserAccount deleting themselves is not allowed", http.StatusForbidden)
 	} else if err := db.RunTx(ctx, func(ctx context.Context) error {
-		if err := db.DeleteUserAccountFromOrganization(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
+		if err := removeUserAccountFromOrganization(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
 			if errors.Is(err, apierrors.ErrNotFound) {
 				w.WriteHeader(http.StatusNoContent)
 				return nil
 			} else {
 				return err
 			}
-		} else if err := db.DeleteAccessTokensOfUserInOrg(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
-			return err
-		} else if err := db.DeleteTutorialProgressesOfUserInOrg(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
-			return err
-		} else if err := db.DeleteCustomOIDCIdentitiesOfUserInOrg(
-			ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
-			return err
 		} else {
 			w.WriteHeader(http.StatusNoContent)
 			return nil
@@ -536,6 +638,100 @@ func deleteUserAccountHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func getUserAccountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userAccount := internalctx.GetUserAccount(ctx)
+	auth := auth.Authentication.Require(ctx)
+	if sessions, err := db.GetUserSessionsInOrg(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get sessions", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.List(sessions, mapping.UserSessionToAPI(auth.CurrentSessionID())))
+	}
+}
+
+// deleteUserAccountSessionsHandler signs the user out of the current organization. Sessions in other organizations
+// of the user are not affected.
+func deleteUserAccountSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	userAccount := internalctx.GetUserAccount(ctx)
+	auth := auth.Authentication.Require(ctx)
+
+	if err := checkUserAccountWritability(ctx, *userAccount); err != nil {
+		if errors.Is(err, apierrors.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			log.Error("failed to check user account writability", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := db.DeleteUserSessionsOfUserInOrg(ctx, userAccount.ID, *auth.CurrentOrgID()); err != nil {
+		log.Error("failed to revoke sessions", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// deleteUserAccountSessionHandler revokes a single session of the user. Like deleteUserAccountSessionsHandler, it
+// only reaches sessions in the current organization.
+func deleteUserAccountSessionHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	userAccount := internalctx.GetUserAccount(ctx)
+	auth := auth.Authentication.Require(ctx)
+
+	id, err := uuid.Parse(r.PathValue("sessionId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if err := checkUserAccountWritability(ctx, *userAccount); err != nil {
+		if errors.Is(err, apierrors.ErrForbidden) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+		} else {
+			log.Error("failed to check user account writability", zap.Error(err))
+			sentry.GetHubFromContext(ctx).CaptureException(err)
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := db.DeleteUserSessionOfUserInOrg(ctx, id, userAccount.ID, *auth.CurrentOrgID()); errors.Is(
+		err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		log.Error("failed to revoke session", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// removeUserAccountFromOrganization revokes all access of the user to the organization, including the sessions
+// that are currently in the organization. It must be called in a transaction.
+func removeUserAccountFromOrganization(ctx context.Context, userID, orgID uuid.UUID) error {
+	if err := db.DeleteUserAccountFromOrganization(ctx, userID, orgID); err != nil {
+		return err
+	} else if err := db.DeleteAccessTokensOfUserInOrg(ctx, userID, orgID); err != nil {
+		return err
+	} else if err := db.DeleteUserSessionsOfUserInOrg(ctx, userID, orgID); err != nil {
+		return err
+	} else if err := db.DeleteTutorialProgressesOfUserInOrg(ctx, userID, orgID); err != nil {
+		return err
+	} else {
+		return db.DeleteCustomOIDCIdentitiesOfUserInOrg(ctx, userID, orgID)
+	}
+}
+
 var patchImageUserAccount = patchImageHandler(func(ctx context.Context, body api.PatchImageRequest) (any, error) {
 	user := internalctx.GetUserAccount(ctx)
 	if err := db.UpdateUserAccountImage(ctx, user, body.ImageID); err != nil {
@@ -566,6 +762,9 @@ func userAccountMiddleware(h http.Handler) http.Handler {
 				sentry.GetHubFromContext(ctx).CaptureException(err)
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 			}
+		} else if userAccount.ServiceAccountOrganizationID != nil {
+			// service accounts are managed in ServiceAccountsRouter
+			http.NotFound(w, r)
 		} else {
 			h.ServeHTTP(w, r.WithContext(internalctx.WithUserAccount(ctx, userAccount)))
 		}
@@ -575,10 +774,11 @@ func userAccountMiddleware(h http.Handler) http.Handler {
 func checkUserAccountWritability(ctx context.Context, userAccount types.UserAccountWithUserRole) error {
 	auth := auth.Authentication.Require(ctx)
 
-	if *auth.CurrentUserRole() != types.UserRoleAdmin && (auth.CurrentCustomerOrgID() != nil ||
-		(auth.CurrentPartnerOrgID() != nil && userAccount.CustomerOrganizationID == nil) ||
-		(userAccount.CustomerOrganizationID == nil && userAccount.PartnerOrganizationID == nil)) {
-		return apierrors.NewForbidden("admin role needed to patch user in the given scope")
+	if !auth.CurrentPermissions().Has(types.ResourceUserAccounts, types.ActionManage) &&
+		(auth.CurrentCustomerOrgID() != nil ||
+			(auth.CurrentPartnerOrgID() != nil && userAccount.CustomerOrganizationID == nil) ||
+			(userAccount.CustomerOrganizationID == nil && userAccount.PartnerOrganizationID == nil)) {
+		return apierrors.NewForbidden("permission to manage users needed to patch user in the given scope")
 	}
 
 	return nil
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestPatchedRoleAssignment(t *testing.T) {
+	customRoleID := uuid.New()
+	userAccount := types.UserAccountWithUserRole{UserRole: types.UserRoleReadOnly, CustomRoleID: &customRoleID}
+
+	t.Run("role only keeps the custom role", func(t *testing.T) {
+		g := NewWithT(t)
+		userRole, roleID, changed := patchedRoleAssignment(userAccount, api.PatchUserAccountRequest{
+			UserRole: new(types.UserRoleReadWrite),
+		})
+		g.Expect(changed).To(BeTrue())
+		g.Expect(userRole).To(Equal(types.UserRoleReadWrite))
+		g.Expect(roleID).To(HaveValue(Equal(customRoleID)))
+	})
+
+	t.Run("same role is not a change", func(t *testing.T) {
+		g := NewWithT(t)
+		_, roleID, changed := patchedRoleAssignment(userAccount, api.PatchUserAccountRequest{
+			UserRole: new(types.UserRoleReadOnly),
+		})
+		g.Expect(changed).To(BeFalse())
+		g.Expect(roleID).To(HaveValue(Equal(customRoleID)))
+	})
+
+	t.Run("name only is not a change", func(t *testing.T) {
+		g := NewWithT(t)
+		_, _, changed := patchedRoleAssignment(userAccount, api.PatchUserAccountRequest{Name: new("name")})
+		g.Expect(changed).To(BeFalse())
+	})
+
+	t.Run("custom role only keeps the role", func(t *testing.T) {
+		g := NewWithT(t)
+		otherRoleID := uuid.New()
+		userRole, roleID, changed := patchedRoleAssignment(userAccount, api.PatchUserAccountRequest{
+			CustomRoleID: &otherRoleID,
+		})
+		g.Expect(changed).To(BeTrue())
+		g.Expect(userRole).To(Equal(types.UserRoleReadOnly))
+		g.Expect(roleID).To(HaveValue(Equal(otherRoleID)))
+	})
+
+	t.Run("remove custom role", func(t *testing.T) {
+		g := NewWithT(t)
+		_, roleID, changed := patchedRoleAssignment(userAccount, api.PatchUserAccountRequest{RemoveCustomRole: true})
+		g.Expect(changed).To(BeTrue())
+		g.Expect(roleID).To(BeNil())
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"html"
 	"io"
 	"net/http"
+	"net/textproto"
 	"strconv"
 	"time"
 
@@ -104,6 +105,15 @@ func SetFileDownloadHeaders(w http.ResponseWriter, filename string) {
 }
 
 func readMultipartFile(w http.ResponseWriter, r *http.Request, formKey string) ([]byte, bool) {
+	return readMultipartFileWithMediaType(w, r, formKey, contenttype.IsYaml)
+}
+
+func readMultipartFileWithMediaType(
+	w http.ResponseWriter,
+	r *http.Request,
+	formKey string,
+	checkMediaType func(textproto.MIMEHeader) error,
+) ([]byte, bool) {
 	log := internalctx.GetLogger(r.Context())
 	if file, head, err := r.FormFile(formKey); err != nil {
 		if !errors.Is(err, http.ErrMissingFile) {
@@ -122,7 +132,7 @@ func readMultipartFile(w http.ResponseWriter, r *http.Request, formKey string) (
 			w.WriteHeader(http.StatusRequestEntityTooLarge)
 			fmt.Fprintln(w, "file too large (max 100 KiB)")
 			return nil, false
-		} else if err := contenttype.IsYaml(head.Header); err != nil {
+		} else if err := checkMediaType(head.Header); err != nil {
 			w.WriteHeader(http.StatusUnsupportedMediaType)
 			fmt.Fprint(w, html.EscapeString(err.Error()))
 			return nil, false
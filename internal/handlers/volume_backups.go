@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/getsentry/sentry-go"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+func getVolumeBackupStorageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+	if storage, err := db.GetVolumeBackupStorage(ctx, deploymentTarget.ID); errors.Is(err, apierrors.ErrNotFound) {
+		// targets without a storage keep their snapshots locally
+		RespondJSON(w, api.VolumeBackupStorage{
+			Type:      types.VolumeBackupStorageLocal,
+			Retention: types.DefaultVolumeBackupRetention,
+		})
+	} else if err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get volume backup storage", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.VolumeBackupStorageToAPI(*storage))
+	}
+}
+
+func putVolumeBackupStorageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+
+	request, err := JsonBody[api.VolumeBackupStorageRequest](w, r)
+	if err != nil {
+		return
+	}
+
+	if deploymentTarget.Type != types.DeploymentTypeDocker {
+		http.Error(w, "volume backups are only supported for Docker deployment targets", http.StatusBadRequest)
+		return
+	}
+
+	storage := types.VolumeBackupStorage{
+		DeploymentTargetID: deploymentTarget.ID,
+		Type:               request.Type,
+		Retention:          request.Retention,
+	}
+	if request.Type == types.VolumeBackupStorageS3 {
+		storage.S3Endpoint = request.S3Endpoint
+		storage.S3Region = request.S3Region
+		storage.S3Bucket = request.S3Bucket
+		storage.S3Prefix = request.S3Prefix
+		storage.S3UsePathStyle = request.S3UsePathStyle
+		storage.S3AccessKeyID = request.S3AccessKeyID
+		storage.S3SecretAccessKey = request.S3SecretAccessKey
+	}
+
+	previous, err := db.GetVolumeBackupStorage(ctx, deploymentTarget.ID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		previous = nil
+	} else if err != nil {
+		log.Error("failed to get volume backup storage", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	// the secret access key is never returned, so it may be omitted to keep the existing one
+	validationStorage := storage
+	if previous != nil && storage.KeepsSecretAccessKeyOf(*previous) {
+		validationStorage.S3SecretAccessKey = previous.S3SecretAccessKey
+	}
+	if err := validationStorage.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := db.SaveVolumeBackupStorage(ctx, &storage); err != nil {
+		log.Error("failed to save volume backup storage", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	result := mapping.VolumeBackupStorageToAPI(storage)
+	if previous != nil {
+		auditlog.RecordChange(ctx, mapping.VolumeBackupStorageToAPI(*previous), result)
+	} else {
+		auditlog.RecordChange(ctx, nil, result)
+	}
+	RespondJSON(w, result)
+}
+
+func deleteVolumeBackupStorageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	deploymentTarget := internalctx.GetDeploymentTarget(ctx)
+
+	previous, err := db.GetVolumeBackupStorage(ctx, deploymentTarget.ID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Error("failed to get volume backup storage", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	if err := db.DeleteVolumeBackupStorage(ctx, deploymentTarget.ID); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		log.Error("failed to delete volume backup storage", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		auditlog.RecordChange(ctx, mapping.VolumeBackupStorageToAPI(*previous), nil)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func getDeploymentVolumeSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	deployment := internalctx.GetDeployment(ctx)
+	if snapshots, err := db.GetDeploymentVolumeSnapshots(ctx, deployment.ID); err != nil {
+		internalctx.GetLogger(ctx).Error("failed to get volume snapshots", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+	} else {
+		RespondJSON(w, mapping.List(snapshots, mapping.DeploymentVolumeSnapshotToAPI))
+	}
+}
+
+// restoreDeploymentVolumeSnapshot rolls the deployment back to the revision that was running when the snapshot was
+// created and tells the agent to restore the volumes from the snapshot before that revision is applied.
+func restoreDeploymentVolumeSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := internalctx.GetLogger(ctx)
+	deployment := internalctx.GetDeployment(ctx)
+
+	snapshotID, err := uuid.Parse(r.PathValue("snapshotId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	snapshot, err := db.GetDeploymentVolumeSnapshot(ctx, deployment.ID, snapshotID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		log.Error("failed to get volume snapshot", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	revisions, err := db.GetDeploymentRevisions(ctx, deployment.ID)
+	if err != nil {
+		log.Error("failed to get deployment revisions", zap.Error(err))
+		sentry.GetHubFromContext(ctx).CaptureException(err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	var revision *types.DeploymentRevisionWithCreator
+	for i := range revisions {
+		if revisions[i].ID == snapshot.DeploymentRevisionID {
+			revision = &revisions[i]
+			break
+		}
+	}
+	if revision == nil {
+		http.Error(w, "the revision of the snapshot does not exist anymore", http.StatusBadRequest)
+		return
+	}
+
+	deploymentRequest := api.DeploymentRequest{
+		DeploymentID:             &deployment.ID,
+		DeploymentTargetID:       deployment.DeploymentTargetID,
+		ApplicationVersionID:     revision.ApplicationVersionID,
+		ApplicationEntitlementID: deployment.ApplicationEntitlementID,
+		ReleaseName:              deployment.ReleaseName,
+		ValuesYaml:               revision.ValuesYaml,
+		DockerType:               deployment.DockerType,
+		EnvFileData:              revision.EnvFileData,
+		ForceRestart:             revision.ForceRestart,
+		RestoreVolumeSnapshotID:  &snapshot.ID,
+	}
+
+	_ = db.RunTx(ctx, func(ctx context.Context) error {
+		return saveDeploymentRequest(ctx, w, deploymentRequest)
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/auditlog"
+	"github.com/distr-sh/distr/internal/auth"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/oidc"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/workloadidentity"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/httprate"
+	"github.com/google/uuid"
+	"github.com/oaswrap/spec/adapter/chiopenapi"
+	"github.com/oaswrap/spec/option"
+	"go.uber.org/zap"
+)
+
+type workloadIdentityTrustPolicyPathRequest struct {
+	serviceAccountPathRequest
+	TrustPolicyID uuid.UUID `path:"trustPolicyId"`
+}
+
+// WorkloadIdentityRouter is public, because the workloads exchange their ID token for an access token here.
+func WorkloadIdentityRouter(r chiopenapi.Router) {
+	r.WithOptions(option.GroupTags("Service Accounts"))
+	r.Use(httprate.LimitBy(
+		60,
+		1*time.Minute,
+		httprate.JoinKeys(func(r *http.Request) (string, error) {
+			return chimiddleware.GetClientIP(r.Context()), nil
+		}, httprate.KeyByEndpoint),
+	))
+	r.Post("/token", workloadIdentityTokenHandler).
+		With(option.Description("Exchange an ID token that satisfies a workload identity trust policy for a " +
+			"short-lived access token of its service account")).
+		With(option.Request(api.WorkloadIdentityTokenRequest{})).
+		With(option.Response(http.StatusOK, api.AccessTokenWithKey{}))
+}
+
+// serviceAccountTrustPoliciesRouter is mounted below a service account in ServiceAccountsRouter.
+func serviceAccountTrustPoliciesRouter(r chiopenapi.Router) {
+	r.Get("/", getWorkloadIdentityTrustPoliciesHandler).
+		With(option.Description("List the workload identity trust policies of a service account")).
+		With(option.Request(serviceAccountPathRequest{})).
+		With(option.Response(http.StatusOK, []api.WorkloadIdentityTrustPolicy{}))
+	r.Post("/", createWorkloadIdentityTrustPolicyHandler).
+		With(option.Description("Trust ID tokens of an issuer that match the given patterns to act as the " +
+			"service account")).
+		With(option.Request(struct {
+			serviceAccountPathRequest
+			api.WorkloadIdentityTrustPolicyRequest
+		}{})).
+		With(option.Response(http.StatusOK, api.WorkloadIdentityTrustPolicy{}))
+	r.Put("/{trustPolicyId}", updateWorkloadIdentityTrustPolicyHandler).
+		With(option.Description("Update a workload identity trust policy")).
+		With(option.Request(struct {
+			workloadIdentityTrustPolicyPathRequest
+			api.WorkloadIdentityTrustPolicyRequest
+		}{})).
+		With(option.Response(http.StatusOK, api.WorkloadIdentityTrustPolicy{}))
+	r.Delete("/{trustPolicyId}", deleteWorkloadIdentityTrustPolicyHandler).
+		With(option.Description("Delete a workload identity trust policy")).
+		With(option.Request(workloadIdentityTrustPolicyPathRequest{}))
+}
+
+func workloadIdentityTokenHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	request, err := JsonBody[api.WorkloadIdentityTokenRequest](w, r)
+	if err != nil {
+		return
+	}
+	policy, err := workloadidentity.Verify(ctx, request.TrustPolicyID, request.Token)
+	if errors.Is(err, workloadidentity.ErrUntrusted) {
+		internalctx.GetLogger(ctx).Info("workload identity token rejected", zap.Error(err))
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+	} else if token, err := workloadidentity.IssueAccessToken(ctx, *policy); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.AccessTokenToDTO(*token).WithKey(token.Key))
+	}
+}
+
+func getWorkloadIdentityTrustPoliciesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+	if policies, err := db.GetWorkloadIdentityTrustPolicies(
+		ctx, serviceAccount.ID, *serviceAccount.ServiceAccountOrganizationID,
+	); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		RespondJSON(w, mapping.List(policies, mapping.WorkloadIdentityTrustPolicyToAPI))
+	}
+}
+
+func createWorkloadIdentityTrustPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	request, err := JsonBody[api.WorkloadIdentityTrustPolicyRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateWorkloadIdentityTrustPolicyRequest(w, r, &request) {
+		return
+	}
+
+	policy := types.WorkloadIdentityTrustPolicy{
+		OrganizationID:   *serviceAccount.ServiceAccountOrganizationID,
+		ServiceAccountID: serviceAccount.ID,
+	}
+	applyWorkloadIdentityTrustPolicyRequest(&policy, request)
+	if err := db.CreateWorkloadIdentityTrustPolicy(ctx, &policy); err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx, nil, mapping.WorkloadIdentityTrustPolicyToAPI(policy))
+		RespondJSON(w, mapping.WorkloadIdentityTrustPolicyToAPI(policy))
+	}
+}
+
+func updateWorkloadIdentityTrustPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	id, err := uuid.Parse(r.PathValue("trustPolicyId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	request, err := JsonBody[api.WorkloadIdentityTrustPolicyRequest](w, r)
+	if err != nil {
+		return
+	}
+	if !validateWorkloadIdentityTrustPolicyRequest(w, r, &request) {
+		return
+	}
+
+	existing, err := db.GetWorkloadIdentityTrustPolicy(ctx, id)
+	if errors.Is(err, apierrors.ErrNotFound) ||
+		(err == nil && existing.ServiceAccountID != serviceAccount.ID) {
+		http.NotFound(w, r)
+		return
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+		return
+	}
+
+	policy := *existing
+	applyWorkloadIdentityTrustPolicyRequest(&policy, request)
+	if err := db.UpdateWorkloadIdentityTrustPolicy(ctx, &policy); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		auditlog.RecordChange(ctx,
+			mapping.WorkloadIdentityTrustPolicyToAPI(*existing), mapping.WorkloadIdentityTrustPolicyToAPI(policy))
+		RespondJSON(w, mapping.WorkloadIdentityTrustPolicyToAPI(policy))
+	}
+}
+
+func deleteWorkloadIdentityTrustPolicyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	serviceAccount := internalctx.GetUserAccount(ctx)
+
+	id, err := uuid.Parse(r.PathValue("trustPolicyId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	if err := db.DeleteWorkloadIdentityTrustPolicy(
+		ctx, id, serviceAccount.ID, *serviceAccount.ServiceAccountOrganizationID,
+	); errors.Is(err, apierrors.ErrNotFound) {
+		http.NotFound(w, r)
+	} else if err != nil {
+		respondServiceAccountError(w, r, err)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// validateWorkloadIdentityTrustPolicyRequest also checks that the issuer can be reached and that the scopes do
+// not exceed the permissions of the caller.
+func validateWorkloadIdentityTrustPolicyRequest(
+	w http.ResponseWriter,
+	r *http.Request,
+	request *api.WorkloadIdentityTrustPolicyRequest,
+) bool {
+	ctx := r.Context()
+	auth := auth.Authentication.Require(ctx)
+
+	request.Normalize()
+	if err := request.Validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	if !auth.CurrentPermissions().Contains(request.Scopes.Permissions()) {
+		http.Error(w, "scopes cannot exceed your own role", http.StatusForbidden)
+		return false
+	}
+	if maxLifetime := auth.CurrentOrg().SecurityPolicy.MaxAccessTokenLifetime; maxLifetime != nil &&
+		*request.TokenLifetime > *maxLifetime {
+		http.Error(w, "the organization allows access tokens to be valid for at most "+maxLifetime.String(),
+			http.StatusBadRequest)
+		return false
+	}
+	if err := oidc.DiscoverWorkloadIssuer(ctx, request.Issuer, request.Audience); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func applyWorkloadIdentityTrustPolicyRequest(
+	policy *types.WorkloadIdentityTrustPolicy,
+	request api.WorkloadIdentityTrustPolicyRequest,
+) {
+	policy.Name = request.Name
+	policy.Issuer = request.Issuer
+	policy.Audience = request.Audience
+	policy.SubjectPattern = request.SubjectPattern
+	policy.ClaimPatterns = request.ClaimPatterns
+	policy.Scopes = request.Scopes
+	policy.TokenLifetime = *request.TokenLifetime
+}
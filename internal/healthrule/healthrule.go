@@ -0,0 +1,83 @@
+// Package healthrule evaluates the health rules of application versions. A rule is a JSONPath expression in the
+// template syntax of kubectl, e.g. {.status.loadBalancer.ingress[0].ip}, that is evaluated on a Kubernetes resource.
+package healthrule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var ErrNotSatisfied = errors.New("health rule not satisfied")
+
+// Parse returns the compiled expression. Keys that are missing in a resource are not an error, as status fields are
+// usually only set once they are known.
+func Parse(expression string) (*jsonpath.JSONPath, error) {
+	if !strings.Contains(expression, "{") {
+		return nil, fmt.Errorf("expression %q must be enclosed in braces, e.g. {.status.phase}", expression)
+	}
+	j := jsonpath.New("").AllowMissingKeys(true)
+	if err := j.Parse(expression); err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+	return j, nil
+}
+
+// Check evaluates expression on obj. Without a value, the rule is satisfied if the expression finds at least one
+// value that is neither empty nor false. Otherwise one of the values found must be equal to value. If the rule is not
+// satisfied, the returned error wraps ErrNotSatisfied.
+func Check(expression, value string, obj map[string]any) error {
+	j, err := Parse(expression)
+	if err != nil {
+		return err
+	}
+	results, err := j.FindResults(obj)
+	if err != nil {
+		return fmt.Errorf("could not evaluate expression %q: %w", expression, err)
+	}
+	var found []string
+	for _, result := range results {
+		for _, v := range result {
+			s := format(v)
+			if value == "" && s != "" && s != "false" && s != "null" || value != "" && s == value {
+				return nil
+			} else if s != "" {
+				found = append(found, s)
+			}
+		}
+	}
+	if value == "" && len(found) == 0 {
+		return fmt.Errorf("%w: %v found no value", ErrNotSatisfied, expression)
+	} else if value == "" {
+		return fmt.Errorf("%w: %v is %v", ErrNotSatisfied, expression, strings.Join(found, ", "))
+	} else if len(found) == 0 {
+		return fmt.Errorf("%w: %v found no value, expected %v", ErrNotSatisfied, expression, value)
+	} else {
+		return fmt.Errorf("%w: %v is %v, expected %v", ErrNotSatisfied, expression, strings.Join(found, ", "), value)
+	}
+}
+
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Map, reflect.Slice:
+		if v.Len() == 0 {
+			return ""
+		}
+	}
+	if data, err := json.Marshal(v.Interface()); err == nil {
+		return string(data)
+	}
+	return fmt.Sprint(v.Interface())
+}
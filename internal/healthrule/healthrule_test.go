@@ -0,0 +1,55 @@
+package healthrule_test
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/healthrule"
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(healthrule.Parse("{.status.phase}")).NotTo(BeNil())
+	g.Expect(healthrule.Parse(`{.status.conditions[?(@.type=="Ready")].status}`)).NotTo(BeNil())
+	_, err := healthrule.Parse(".status.phase")
+	g.Expect(err).To(HaveOccurred())
+	_, err = healthrule.Parse("{.status.phase")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestCheck(t *testing.T) {
+	g := NewWithT(t)
+	ingress := map[string]any{
+		"status": map[string]any{
+			"loadBalancer": map[string]any{
+				"ingress": []any{map[string]any{"ip": "10.0.0.1"}},
+			},
+		},
+	}
+	g.Expect(healthrule.Check("{.status.loadBalancer.ingress}", "", ingress)).To(Succeed())
+	g.Expect(healthrule.Check("{.status.loadBalancer.ingress[*].ip}", "10.0.0.1", ingress)).To(Succeed())
+	err := healthrule.Check("{.status.loadBalancer.ingress[*].ip}", "10.0.0.2", ingress)
+	g.Expect(err).To(MatchError(healthrule.ErrNotSatisfied))
+	g.Expect(err).To(MatchError(ContainSubstring("is 10.0.0.1, expected 10.0.0.2")))
+
+	pending := map[string]any{"status": map[string]any{"loadBalancer": map[string]any{}}}
+	g.Expect(healthrule.Check("{.status.loadBalancer.ingress}", "", pending)).
+		To(MatchError(healthrule.ErrNotSatisfied))
+
+	resource := map[string]any{
+		"status": map[string]any{
+			"ready": false,
+			"conditions": []any{
+				map[string]any{"type": "Synced", "status": "True"},
+				map[string]any{"type": "Ready", "status": "False"},
+			},
+		},
+	}
+	g.Expect(healthrule.Check("{.status.ready}", "", resource)).To(MatchError(healthrule.ErrNotSatisfied))
+	g.Expect(healthrule.Check("{.status.ready}", "false", resource)).To(Succeed())
+	g.Expect(healthrule.Check(`{.status.conditions[?(@.type=="Synced")].status}`, "True", resource)).To(Succeed())
+	g.Expect(healthrule.Check(`{.status.conditions[?(@.type=="Ready")].status}`, "True", resource)).
+		To(MatchError(healthrule.ErrNotSatisfied))
+	g.Expect(healthrule.Check(".status.ready", "", resource)).
+		NotTo(MatchError(healthrule.ErrNotSatisfied))
+}
@@ -2,20 +2,37 @@ package jobs
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/db/queryable"
+	"github.com/distr-sh/distr/internal/types"
 	"github.com/go-mailx/mailx"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+var errTaskLeaseLost = errors.New("task lease lost")
+
 const (
 	tracerScope = "github.com/distr-sh/distr/internal/jobs"
+	// leaseDuration is how long a replica claims a cron tick. Other replicas fire the same tick a little earlier or
+	// later, depending on their clocks, and must not enqueue the job a second time. It is shorter than the interval
+	// of the most frequent cron schedule, which is one minute.
+	leaseDuration = 30 * time.Second
+	// taskLeaseDuration is extended while the task runs, so it only limits how long it takes until another replica
+	// picks up a task whose worker stopped.
+	taskLeaseDuration = time.Minute
 )
 
 type runner struct {
@@ -24,6 +41,8 @@ type runner struct {
 	logger   *zap.Logger
 	tracer   trace.Tracer
 	s3Client *s3.Client
+	// holder identifies this replica in job leases and runs.
+	holder string
 }
 
 func NewRunner(
@@ -39,6 +58,7 @@ func NewRunner(
 		logger:   logger,
 		tracer:   traceProvider.Tracer(tracerScope, trace.WithInstrumentationVersion(buildconfig.Version())),
 		s3Client: s3Client,
+		holder:   newHolder(),
 	}
 	return &runner
 }
@@ -47,12 +67,35 @@ func (runner *runner) RunJobFunc(job Job) func(ctx context.Context) {
 	return func(ctx context.Context) { runner.Run(ctx, job) }
 }
 
+// Run enqueues a task for the job unless another replica already did so for the same cron tick.
 func (runner *runner) Run(ctx context.Context, job Job) {
 	log := runner.logger.With(zap.String("job", job.name))
 
-	ctx = runner.jobCtx(ctx, job)
-	ctx, span := runner.tracer.Start(ctx, job.name, trace.WithSpanKind(trace.SpanKindInternal))
-	defer span.End()
+	ctx = runner.jobCtx(ctx, job.name)
+	if acquired, err := db.TryAcquireJobLease(ctx, job.name, runner.holder, leaseDuration); err != nil {
+		log.Warn("job skipped, could not acquire lease", zap.Error(err))
+	} else if !acquired {
+		log.Debug("job skipped, another replica holds the lease")
+	} else if task, err := Enqueue(ctx, jobTaskType(job.name), nil, WithTaskDedupKey(jobTaskType(job.name))); err != nil {
+		log.Warn("job skipped, could not enqueue task", zap.Error(err))
+	} else {
+		log.Debug("job enqueued", zap.Stringer("taskId", task.ID))
+	}
+}
+
+// runJob is the TaskFunc of the tasks enqueued by Run. Every attempt is recorded in the job run history.
+func (runner *runner) runJob(ctx context.Context, job Job) error {
+	log := internalctx.GetLogger(ctx)
+
+	// The dedup key of the job tasks ensures that no other run of this job is in progress.
+	if err := db.AbandonJobRuns(ctx, job.name); err != nil {
+		log.Warn("could not update abandoned job runs", zap.Error(err))
+	}
+	run := types.JobRun{JobName: job.name, Holder: runner.holder}
+	if err := db.CreateJobRun(ctx, &run); err != nil {
+		log.Warn("could not record job run", zap.Error(err))
+	}
+	historyCtx := context.WithoutCancel(ctx)
 
 	startedAt := time.Now()
 	log.Info("job started")
@@ -66,18 +109,106 @@ func (runner *runner) Run(ctx context.Context, job Job) {
 	err := job.Run(ctx)
 	elapsed := time.Since(startedAt)
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "job error")
 		log.Warn("job failed", zap.Duration("elapsed", elapsed), zap.Error(err))
 	} else {
-		span.SetStatus(codes.Ok, "job finished")
 		log.Info("job finished", zap.Duration("elapsed", elapsed))
 	}
-	// TODO: save result to DB
+	if run.ID != uuid.Nil {
+		if err := db.FinishJobRun(historyCtx, run.ID, err); err != nil {
+			log.Warn("could not record job run result", zap.Error(err))
+		}
+	}
+	return err
 }
 
-func (runner *runner) jobCtx(ctx context.Context, job Job) context.Context {
-	ctx = internalctx.WithLogger(ctx, runner.logger.With(zap.String("job", job.name)))
+// RunTask runs a task that was leased by this replica. The lease is extended while the task runs, and the task is
+// canceled if the lease is lost nevertheless, because another replica may pick it up then.
+func (runner *runner) RunTask(ctx context.Context, task types.Task, f TaskFunc) {
+	log := runner.logger.With(
+		zap.Stringer("taskId", task.ID),
+		zap.String("taskType", task.Type),
+		zap.Int("attempt", task.Attempts),
+	)
+
+	workerCtx := ctx
+	ctx = runner.jobCtx(ctx, task.Type)
+	ctx = internalctx.WithLogger(ctx, log)
+	finishCtx := context.WithoutCancel(ctx)
+	ctx = context.WithValue(ctx, taskContextKey{}, task.ID)
+	ctx, span := runner.tracer.Start(ctx, task.Type, trace.WithSpanKind(trace.SpanKindInternal))
+	defer span.End()
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	heartbeatDone := make(chan struct{})
+	go func() {
+		defer close(heartbeatDone)
+		ticker := time.NewTicker(taskLeaseDuration / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := db.ExtendTaskLease(ctx, task.ID, runner.holder, taskLeaseDuration); errors.Is(
+					err, apierrors.ErrNotFound,
+				) {
+					cancel(errTaskLeaseLost)
+					return
+				} else if err != nil {
+					log.Warn("could not extend task lease", zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	startedAt := time.Now()
+	log.Debug("task started")
+	result, err := f(ctx, task.Payload)
+	cancel(nil)
+	<-heartbeatDone
+	elapsed := time.Since(startedAt)
+
+	if cause := context.Cause(ctx); errors.Is(cause, errTaskLeaseLost) {
+		span.SetStatus(codes.Error, "task lease lost")
+		log.Warn("task lease lost", zap.Duration("elapsed", elapsed))
+		return
+	} else if workerCtx.Err() != nil {
+		// The worker is shutting down, which should not count as a failed attempt.
+		span.SetStatus(codes.Error, "task interrupted")
+		log.Info("task interrupted", zap.Duration("elapsed", elapsed))
+		if err := db.ReleaseTask(finishCtx, task.ID, runner.holder); err != nil {
+			log.Warn("could not release task", zap.Error(err))
+		}
+		return
+	}
+
+	var resultData []byte
+	if err == nil && result != nil {
+		if resultData, err = json.Marshal(result); err != nil {
+			err = fmt.Errorf("could not encode task result: %w", err)
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "task error")
+		if task.Attempts < task.MaxAttempts {
+			log.Warn("task failed, will be retried", zap.Duration("elapsed", elapsed), zap.Error(err))
+		} else {
+			log.Error("task failed on its last attempt", zap.Duration("elapsed", elapsed), zap.Error(err))
+		}
+		err = db.FailTask(finishCtx, task.ID, runner.holder, err, taskRetryDelay(task.Attempts))
+	} else {
+		span.SetStatus(codes.Ok, "task finished")
+		log.Debug("task finished", zap.Duration("elapsed", elapsed))
+		err = db.CompleteTask(finishCtx, task.ID, runner.holder, resultData)
+	}
+	if err != nil {
+		log.Warn("could not record task result", zap.Error(err))
+	}
+}
+
+func (runner *runner) jobCtx(ctx context.Context, name string) context.Context {
+	ctx = internalctx.WithLogger(ctx, runner.logger.With(zap.String("job", name)))
 	ctx = internalctx.WithDb(ctx, runner.db)
 	ctx = internalctx.WithMailer(ctx, runner.mailer)
 	if runner.s3Client != nil {
@@ -85,3 +216,15 @@ func (runner *runner) jobCtx(ctx context.Context, job Job) context.Context {
 	}
 	return ctx
 }
+
+func jobTaskType(jobName string) string {
+	return "job:" + jobName
+}
+
+func newHolder() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return fmt.Sprintf("%v/%v", hostname, uuid.NewString()[:8])
+}
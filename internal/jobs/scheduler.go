@@ -1,6 +1,9 @@
 package jobs
 
 import (
+	"context"
+	"encoding/json"
+
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/distr-sh/distr/internal/db/queryable"
 	"github.com/go-co-op/gocron/v2"
@@ -9,10 +12,12 @@ import (
 	"go.uber.org/zap"
 )
 
+// Scheduler enqueues the cron jobs as tasks and runs the task worker, which picks up the tasks of all replicas.
 type Scheduler struct {
 	scheduler gocron.Scheduler
 	logger    *zap.Logger
 	runner    *runner
+	worker    *taskWorker
 }
 
 func NewScheduler(
@@ -21,21 +26,32 @@ func NewScheduler(
 	mailer *mailx.Mailer,
 	traceProvider trace.TracerProvider,
 	s3Client *s3.Client,
+	workerConcurrency int,
 ) (*Scheduler, error) {
 	if scheduler, err := gocron.NewScheduler(
 		gocron.WithLogger(&gocronLoggerAdapter{logger: logger.Sugar()}),
 	); err != nil {
 		return nil, err
 	} else {
+		runner := NewRunner(logger, db, mailer, traceProvider, s3Client)
 		return &Scheduler{
 			scheduler: scheduler,
 			logger:    logger,
-			runner:    NewRunner(logger, db, mailer, traceProvider, s3Client),
+			runner:    runner,
+			worker: &taskWorker{
+				runner:      runner,
+				logger:      logger,
+				concurrency: workerConcurrency,
+				handlers:    map[string]TaskFunc{},
+			},
 		}, nil
 	}
 }
 
 func (s *Scheduler) RegisterCronJob(cron string, job Job) error {
+	s.RegisterTaskFunc(jobTaskType(job.name), func(ctx context.Context, _ json.RawMessage) (any, error) {
+		return nil, s.runner.runJob(ctx, job)
+	})
 	_, err := s.scheduler.NewJob(
 		gocron.CronJob(cron, false),
 		gocron.NewTask(s.runner.RunJobFunc(job)),
@@ -45,12 +61,23 @@ func (s *Scheduler) RegisterCronJob(cron string, job Job) error {
 	return err
 }
 
+// RegisterTaskFunc must be called before Start for every task type that is enqueued with Enqueue.
+func (s *Scheduler) RegisterTaskFunc(taskType string, f TaskFunc) {
+	s.worker.register(taskType, f)
+}
+
 func (s *Scheduler) Start() {
-	s.logger.Info("job scheduler starting", zap.Int("jobs", len(s.scheduler.Jobs())))
+	s.logger.Info("job scheduler starting",
+		zap.Int("jobs", len(s.scheduler.Jobs())),
+		zap.Int("taskTypes", len(s.worker.handlers)),
+		zap.Int("workers", s.worker.concurrency))
 	s.scheduler.Start()
+	s.worker.start()
 }
 
 func (s *Scheduler) Shutdown() error {
 	s.logger.Info("job scheduler shutting down")
-	return s.scheduler.Shutdown()
+	err := s.scheduler.Shutdown()
+	s.worker.stop()
+	return err
 }
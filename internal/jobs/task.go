@@ -0,0 +1,88 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultTaskMaxAttempts = 3
+	taskRetryBaseDelay     = 30 * time.Second
+	taskRetryMaxDelay      = time.Hour
+)
+
+// TaskFunc runs a task with its stored payload. Its result is stored as JSON and exposed with the task.
+type TaskFunc func(ctx context.Context, payload json.RawMessage) (any, error)
+
+// NewTaskFunc returns a TaskFunc that decodes the payload into T before calling f.
+func NewTaskFunc[T any](f func(ctx context.Context, payload T) (any, error)) TaskFunc {
+	return func(ctx context.Context, payload json.RawMessage) (any, error) {
+		var decoded T
+		if err := json.Unmarshal(payload, &decoded); err != nil {
+			return nil, fmt.Errorf("could not decode task payload: %w", err)
+		}
+		return f(ctx, decoded)
+	}
+}
+
+type TaskOption func(task *types.Task)
+
+// WithTaskOrganization makes the task visible to the members of the organization.
+func WithTaskOrganization(orgID uuid.UUID) TaskOption {
+	return func(task *types.Task) { task.OrganizationID = &orgID }
+}
+
+// WithTaskDedupKey prevents enqueueing the task while another one with the same key is pending or running.
+func WithTaskDedupKey(key string) TaskOption {
+	return func(task *types.Task) { task.DedupKey = &key }
+}
+
+func WithTaskMaxAttempts(maxAttempts int) TaskOption {
+	return func(task *types.Task) { task.MaxAttempts = maxAttempts }
+}
+
+// Enqueue stores a task that is run by the task worker of any hub replica. If the task has a dedup key and another
+// task with the same key is pending or running, that task is returned instead.
+func Enqueue(ctx context.Context, taskType string, payload any, opts ...TaskOption) (*types.Task, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode task payload: %w", err)
+	}
+	task := types.Task{Type: taskType, Payload: data, MaxAttempts: defaultTaskMaxAttempts}
+	for _, opt := range opts {
+		opt(&task)
+	}
+	if err := db.CreateTask(ctx, &task); errors.Is(err, apierrors.ErrAlreadyExists) {
+		return db.GetActiveTaskByDedupKey(ctx, *task.DedupKey)
+	} else if err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+type taskContextKey struct{}
+
+// SetTaskProgress reports the progress of the current task in percent. It does nothing outside of a task.
+func SetTaskProgress(ctx context.Context, progress int, message string) error {
+	if id, ok := ctx.Value(taskContextKey{}).(uuid.UUID); ok {
+		return db.UpdateTaskProgress(ctx, id, min(max(progress, 0), 100), &message)
+	}
+	return nil
+}
+
+// taskRetryDelay doubles with every failed attempt.
+func taskRetryDelay(attempts int) time.Duration {
+	delay := taskRetryBaseDelay
+	for i := 1; i < attempts && delay < taskRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, taskRetryMaxDelay)
+}
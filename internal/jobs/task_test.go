@@ -0,0 +1,34 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTaskRetryDelay(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(taskRetryDelay(1)).To(Equal(30 * time.Second))
+	g.Expect(taskRetryDelay(2)).To(Equal(time.Minute))
+	g.Expect(taskRetryDelay(3)).To(Equal(2 * time.Minute))
+	g.Expect(taskRetryDelay(8)).To(Equal(time.Hour))
+	g.Expect(taskRetryDelay(100)).To(Equal(time.Hour))
+}
+
+func TestNewTaskFunc(t *testing.T) {
+	g := NewWithT(t)
+	type payload struct {
+		Name string `json:"name"`
+	}
+	f := NewTaskFunc(func(ctx context.Context, p payload) (any, error) { return "hello " + p.Name, nil })
+
+	result, err := f(t.Context(), json.RawMessage(`{"name":"distr"}`))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(result).To(Equal("hello distr"))
+
+	_, err = f(t.Context(), json.RawMessage(`[]`))
+	g.Expect(err).To(MatchError(ContainSubstring("could not decode task payload")))
+}
@@ -0,0 +1,81 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"go.uber.org/zap"
+)
+
+// taskPollInterval is how long an idle worker waits before it looks for due tasks again.
+const taskPollInterval = 5 * time.Second
+
+type taskWorker struct {
+	runner      *runner
+	logger      *zap.Logger
+	concurrency int
+	handlers    map[string]TaskFunc
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+}
+
+func (w *taskWorker) register(taskType string, f TaskFunc) {
+	w.handlers[taskType] = f
+}
+
+func (w *taskWorker) start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	taskTypes := slices.Sorted(maps.Keys(w.handlers))
+	for range w.concurrency {
+		w.wg.Go(func() {
+			for ctx.Err() == nil {
+				if !w.runNext(ctx, taskTypes) {
+					select {
+					case <-ctx.Done():
+					case <-time.After(taskPollInterval):
+					}
+				}
+			}
+		})
+	}
+}
+
+// stop waits until the running tasks returned after their context was canceled.
+func (w *taskWorker) stop() {
+	if w.cancel != nil {
+		w.cancel()
+		w.wg.Wait()
+	}
+}
+
+// runNext reports whether a task was run, so that the worker only waits if the queue is empty. Tasks that were
+// abandoned in their last attempt are failed whenever the queue is empty.
+func (w *taskWorker) runNext(ctx context.Context, taskTypes []string) bool {
+	ctx = internalctx.WithDb(ctx, w.runner.db)
+	task, err := db.LeaseTask(ctx, taskTypes, w.runner.holder, taskLeaseDuration)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		if count, err := db.FailAbandonedTasks(ctx, taskTypes); err != nil {
+			if ctx.Err() == nil {
+				w.logger.Warn("could not fail abandoned tasks", zap.Error(err))
+			}
+		} else if count > 0 {
+			w.logger.Warn("failed tasks whose lease expired in their last attempt", zap.Int64("count", count))
+		}
+		return false
+	} else if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Warn("could not lease task", zap.Error(err))
+		}
+		return false
+	}
+	w.runner.RunTask(ctx, *task, w.handlers[task.Type])
+	return true
+}
@@ -17,13 +17,14 @@ func DeploymentTargetMetricsNotificationAlert(
 	metricType string,
 	diskDevice string,
 	diskPath string,
+	containerName string,
 	threshold int,
 	usagePercent int64,
 ) error {
 	return sendNotificationWithQuota(ctx, organization.ID, user.Email,
 		mailx.Subject(getDeploymentTargetMetricsNotificationSubject("Alert", metricType, organization, deploymentTarget)),
 		mailx.HtmlBodyTemplate(mailtemplates.DeploymentTargetMetricsNotificationAlert(
-			deploymentTarget, metricType, diskDevice, diskPath, threshold, usagePercent,
+			deploymentTarget, metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 		)),
 	)
 }
@@ -36,13 +37,14 @@ func DeploymentTargetMetricsNotificationResolved(
 	metricType string,
 	diskDevice string,
 	diskPath string,
+	containerName string,
 	threshold int,
 	usagePercent int64,
 ) error {
 	return sendNotificationWithQuota(ctx, organization.ID, user.Email,
 		mailx.Subject(getDeploymentTargetMetricsNotificationSubject("Resolved", metricType, organization, deploymentTarget)),
 		mailx.HtmlBodyTemplate(mailtemplates.DeploymentTargetMetricsNotificationResolved(
-			deploymentTarget, metricType, diskDevice, diskPath, threshold, usagePercent,
+			deploymentTarget, metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 		)),
 	)
 }
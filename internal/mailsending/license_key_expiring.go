@@ -0,0 +1,39 @@
+package mailsending
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/mailtemplates"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/go-mailx/mailx"
+)
+
+func SendLicenseKeyExpiringCustomer(
+	ctx context.Context,
+	user types.UserAccount,
+	licenseKey types.LicenseKey,
+	daysLeft int,
+) error {
+	return sendNotificationWithQuota(ctx, licenseKey.OrganizationID, user.Email,
+		mailx.Subject(fmt.Sprintf("License key expires in %d day(s): %s", daysLeft, licenseKey.Name)),
+		mailx.HtmlBodyTemplate(mailtemplates.LicenseKeyExpiringCustomer(licenseKey, daysLeft)),
+	)
+}
+
+func SendLicenseKeyExpiringVendor(
+	ctx context.Context,
+	user types.UserAccount,
+	licenseKey types.LicenseKey,
+	daysLeft int,
+	customerOrgName string,
+) error {
+	subject := fmt.Sprintf("License key expires in %d day(s): %s", daysLeft, licenseKey.Name)
+	if customerOrgName != "" {
+		subject = fmt.Sprintf("License key for %s expires in %d day(s): %s", customerOrgName, daysLeft, licenseKey.Name)
+	}
+	return sendNotificationWithQuota(ctx, licenseKey.OrganizationID, user.Email,
+		mailx.Subject(subject),
+		mailx.HtmlBodyTemplate(mailtemplates.LicenseKeyExpiringVendor(licenseKey, daysLeft, customerOrgName)),
+	)
+}
@@ -178,11 +178,12 @@ func DeploymentTargetMetricsNotificationAlert(
 	metricType string,
 	diskDevice string,
 	diskPath string,
+	containerName string,
 	threshold int,
 	usagePercent int64,
 ) (*template.Template, any) {
 	return deploymentTargetMetricsNotification(
-		false, deploymentTarget, metricType, diskDevice, diskPath, threshold, usagePercent,
+		false, deploymentTarget, metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 	)
 }
 
@@ -191,11 +192,12 @@ func DeploymentTargetMetricsNotificationResolved(
 	metricType string,
 	diskDevice string,
 	diskPath string,
+	containerName string,
 	threshold int,
 	usagePercent int64,
 ) (*template.Template, any) {
 	return deploymentTargetMetricsNotification(
-		true, deploymentTarget, metricType, diskDevice, diskPath, threshold, usagePercent,
+		true, deploymentTarget, metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 	)
 }
 
@@ -205,6 +207,7 @@ func deploymentTargetMetricsNotification(
 	metricType string,
 	diskDevice string,
 	diskPath string,
+	containerName string,
 	threshold int,
 	usagePercent int64,
 ) (*template.Template, any) {
@@ -214,6 +217,7 @@ func deploymentTargetMetricsNotification(
 		"MetricType":       metricType,
 		"DiskDevice":       diskDevice,
 		"DiskPath":         diskPath,
+		"ContainerName":    containerName,
 		"Threshold":        threshold,
 		"UsagePercent":     usagePercent,
 	}
@@ -271,6 +275,28 @@ func LicenseKeyRevisedVendor(
 	}
 }
 
+func LicenseKeyExpiringCustomer(
+	licenseKey types.LicenseKey,
+	daysLeft int,
+) (*template.Template, any) {
+	return templates.Lookup("license-key-expiring-customer.html"), map[string]any{
+		"LicenseKey": licenseKey,
+		"DaysLeft":   daysLeft,
+	}
+}
+
+func LicenseKeyExpiringVendor(
+	licenseKey types.LicenseKey,
+	daysLeft int,
+	customerOrgName string,
+) (*template.Template, any) {
+	return templates.Lookup("license-key-expiring-vendor.html"), map[string]any{
+		"LicenseKey":      licenseKey,
+		"DaysLeft":        daysLeft,
+		"CustomerOrgName": customerOrgName,
+	}
+}
+
 func deploymentStatusNotification(
 	eventType string,
 	deploymentTarget types.DeploymentTargetFull,
@@ -0,0 +1,176 @@
+// Package manifestbundle renders the resources of Kubernetes application versions that are packaged as plain
+// manifests or as a Kustomize base instead of a Helm chart.
+//
+// A bundle is either a single YAML file with any number of documents or a gzipped tar archive. If the archive has a
+// kustomization.yaml at its root, it is built as a Kustomize base, otherwise all YAML and JSON files it contains are
+// the resources. The resources of a deployment are built with an overlay that contains the patches of the customer.
+package manifestbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/kustomize/api/konfig"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+)
+
+// maxSize limits the size of the files in a bundle after decompression.
+const maxSize = 10 * 1024 * 1024
+
+const (
+	baseDir      = "/base"
+	overlayDir   = "/overlay"
+	manifestFile = "resources.yaml"
+)
+
+// OverlayFields are the fields of a kustomization that the overlay of a deployment may contain. Fields that refer to
+// other files, like resources, are not allowed, as the overlay can not bring any.
+var OverlayFields = []string{
+	"patches",
+	"images",
+	"replicas",
+	"labels",
+	"commonLabels",
+	"commonAnnotations",
+	"namePrefix",
+	"nameSuffix",
+}
+
+var ErrInvalidBundle = errors.New("invalid manifest bundle")
+
+// Validate checks that bundle can be read and contains resources, without building it.
+func Validate(bundle []byte) error {
+	_, err := load(bundle)
+	return err
+}
+
+// ValidateOverlay checks that overlay only contains OverlayFields.
+func ValidateOverlay(overlay map[string]any) error {
+	for key := range overlay {
+		if !slices.Contains(OverlayFields, key) {
+			return fmt.Errorf("field %v is not supported, supported fields are %v",
+				key, strings.Join(OverlayFields, ", "))
+		}
+	}
+	return nil
+}
+
+// Build returns the resources of bundle with overlay applied as a multi-document YAML.
+func Build(bundle []byte, overlay map[string]any) ([]byte, error) {
+	if err := ValidateOverlay(overlay); err != nil {
+		return nil, err
+	}
+	fs, err := load(bundle)
+	if err != nil {
+		return nil, err
+	}
+	kustomization := map[string]any{"resources": []string{"../" + path.Base(baseDir)}}
+	for key, value := range overlay {
+		kustomization[key] = value
+	}
+	if data, err := yaml.Marshal(kustomization); err != nil {
+		return nil, err
+	} else if err := fs.WriteFile(path.Join(overlayDir, konfig.DefaultKustomizationFileName()), data); err != nil {
+		return nil, err
+	}
+	resources, err := krusty.MakeKustomizer(krusty.MakeDefaultOptions()).Run(fs, overlayDir)
+	if err != nil {
+		return nil, fmt.Errorf("kustomize build failed: %w", err)
+	}
+	return resources.AsYaml()
+}
+
+// load returns a file system with the bundle as a Kustomize base in baseDir.
+func load(bundle []byte) (filesys.FileSystem, error) {
+	fs := filesys.MakeFsInMemory()
+	if len(bundle) == 0 {
+		return nil, fmt.Errorf("%w: bundle is empty", ErrInvalidBundle)
+	} else if !isGzip(bundle) {
+		var resources any
+		if err := yaml.Unmarshal(bundle, &resources); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+		} else if err := fs.WriteFile(path.Join(baseDir, manifestFile), bundle); err != nil {
+			return nil, err
+		}
+		return fs, writeKustomization(fs, []string{manifestFile})
+	}
+
+	files, err := extract(fs, bundle)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidBundle, err)
+	}
+	for _, name := range konfig.RecognizedKustomizationFileNames() {
+		if slices.Contains(files, name) {
+			return fs, nil
+		}
+	}
+	var resources []string
+	for _, file := range files {
+		switch path.Ext(file) {
+		case ".yaml", ".yml", ".json":
+			resources = append(resources, file)
+		}
+	}
+	if len(resources) == 0 {
+		return nil, fmt.Errorf("%w: archive contains neither a kustomization nor any YAML or JSON files",
+			ErrInvalidBundle)
+	}
+	slices.Sort(resources)
+	return fs, writeKustomization(fs, resources)
+}
+
+// extract writes the regular files of the archive into baseDir and returns their paths relative to it.
+func extract(fs filesys.FileSystem, bundle []byte) ([]string, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = gz.Close() }()
+	var files []string
+	var size int64
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		} else if err != nil {
+			return nil, err
+		} else if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Clean(strings.TrimPrefix(header.Name, "./"))
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return nil, fmt.Errorf("archive contains file %v outside of its root", header.Name)
+		}
+		if size += header.Size; size > maxSize {
+			return nil, fmt.Errorf("archive is larger than %v bytes", maxSize)
+		}
+		if data, err := io.ReadAll(io.LimitReader(tr, header.Size)); err != nil {
+			return nil, err
+		} else if err := fs.WriteFile(path.Join(baseDir, name), data); err != nil {
+			return nil, err
+		}
+		files = append(files, name)
+	}
+}
+
+func writeKustomization(fs filesys.FileSystem, resources []string) error {
+	if data, err := yaml.Marshal(map[string]any{"resources": resources}); err != nil {
+		return err
+	} else {
+		return fs.WriteFile(path.Join(baseDir, konfig.DefaultKustomizationFileName()), data)
+	}
+}
+
+func isGzip(data []byte) bool {
+	return len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b
+}
@@ -0,0 +1,123 @@
+package manifestbundle_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/manifestbundle"
+	. "github.com/onsi/gomega"
+)
+
+const deployment = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.27
+`
+
+const service = `apiVersion: v1
+kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - port: 80
+`
+
+func archive(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		} else if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	} else if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBuildManifests(t *testing.T) {
+	g := NewWithT(t)
+	bundle := []byte(deployment + "---\n" + service)
+	g.Expect(manifestbundle.Validate(bundle)).To(Succeed())
+	result, err := manifestbundle.Build(bundle, map[string]any{
+		"images":   []any{map[string]any{"name": "nginx", "newTag": "1.28"}},
+		"replicas": []any{map[string]any{"name": "web", "count": 3}},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(result)).To(And(
+		ContainSubstring("image: nginx:1.28"),
+		ContainSubstring("replicas: 3"),
+		ContainSubstring("kind: Service"),
+	))
+}
+
+func TestBuildKustomization(t *testing.T) {
+	g := NewWithT(t)
+	bundle := archive(t, map[string]string{
+		"kustomization.yaml":  "resources:\n- app/deployment.yaml\ncommonLabels:\n  app: web\n",
+		"app/deployment.yaml": deployment,
+		// not part of the kustomization
+		"app/service.yaml": service,
+	})
+	g.Expect(manifestbundle.Validate(bundle)).To(Succeed())
+	result, err := manifestbundle.Build(bundle, map[string]any{
+		"patches": []any{map[string]any{
+			"target": map[string]any{"kind": "Deployment", "name": "web"},
+			"patch":  "- op: replace\n  path: /spec/replicas\n  value: 2\n",
+		}},
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(result)).To(And(
+		ContainSubstring("app: web"),
+		ContainSubstring("replicas: 2"),
+		Not(ContainSubstring("kind: Service")),
+	))
+}
+
+func TestBuildArchiveWithoutKustomization(t *testing.T) {
+	g := NewWithT(t)
+	bundle := archive(t, map[string]string{
+		"./deployment.yaml": deployment,
+		"service.yml":       service,
+		"README.md":         "# web",
+	})
+	result, err := manifestbundle.Build(bundle, nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(result)).To(And(ContainSubstring("kind: Deployment"), ContainSubstring("kind: Service")))
+}
+
+func TestInvalidBundle(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(manifestbundle.Validate(nil)).To(MatchError(manifestbundle.ErrInvalidBundle))
+	g.Expect(manifestbundle.Validate([]byte("a: b: c"))).To(MatchError(manifestbundle.ErrInvalidBundle))
+	g.Expect(manifestbundle.Validate(archive(t, map[string]string{"README.md": "# web"}))).
+		To(MatchError(manifestbundle.ErrInvalidBundle))
+	g.Expect(manifestbundle.Validate(archive(t, map[string]string{"../deployment.yaml": deployment}))).
+		To(MatchError(manifestbundle.ErrInvalidBundle))
+}
+
+func TestValidateOverlay(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(manifestbundle.ValidateOverlay(nil)).To(Succeed())
+	g.Expect(manifestbundle.ValidateOverlay(map[string]any{"namePrefix": "acme-"})).To(Succeed())
+	g.Expect(manifestbundle.ValidateOverlay(map[string]any{"resources": []any{"https://example.com/x.yaml"}})).
+		To(MatchError(ContainSubstring("resources is not supported")))
+	_, err := manifestbundle.Build([]byte(deployment), map[string]any{"resources": []any{"x.yaml"}})
+	g.Expect(err).To(HaveOccurred())
+}
@@ -7,11 +7,15 @@ import (
 
 func AccessTokenToDTO(model types.AccessToken) api.AccessToken {
 	return api.AccessToken{
-		ID:         model.ID,
-		CreatedAt:  model.CreatedAt,
-		ExpiresAt:  model.ExpiresAt,
-		LastUsedAt: model.LastUsedAt,
-		Label:      model.Label,
-		UserRole:   model.UserRole,
+		ID:               model.ID,
+		CreatedAt:        model.CreatedAt,
+		ExpiresAt:        model.ExpiresAt,
+		LastUsedAt:       model.LastUsedAt,
+		LastUsedIP:       model.LastUsedIP,
+		Label:            model.Label,
+		UserRole:         model.UserRole,
+		CustomRoleID:     model.CustomRoleID,
+		Scopes:           model.Scopes,
+		UnusedExpiryDays: model.UnusedExpiryDays,
 	}
 }
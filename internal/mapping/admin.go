@@ -0,0 +1,38 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func OrganizationOverviewToAPI(model types.OrganizationOverview) api.AdminOrganization {
+	return api.AdminOrganization{
+		ID:                                       model.ID,
+		CreatedAt:                                model.CreatedAt,
+		DeletedAt:                                model.DeletedAt,
+		Name:                                     model.Name,
+		Slug:                                     model.Slug,
+		Features:                                 model.Features,
+		SubscriptionType:                         model.SubscriptionType,
+		SubscriptionPeriod:                       model.SubscriptionPeriod,
+		SubscriptionEndsAt:                       model.SubscriptionEndsAt,
+		SubscriptionCustomerOrganizationQuantity: model.SubscriptionCustomerOrganizationQty.Value(),
+		SubscriptionUserAccountQuantity:          model.SubscriptionUserAccountQty.Value(),
+		UserAccountCount:                         model.UserAccountCount,
+	}
+}
+
+func UserAccountOverviewToAPI(model types.UserAccountOverview) api.AdminUserAccount {
+	return api.AdminUserAccount{
+		ID:                           model.ID,
+		CreatedAt:                    model.CreatedAt,
+		Email:                        model.Email,
+		Name:                         model.Name,
+		EmailVerified:                model.EmailVerified,
+		MFAEnabled:                   model.MFAEnabled,
+		IsSuperAdmin:                 model.IsSuperAdmin,
+		ServiceAccountOrganizationID: model.ServiceAccountOrganizationID,
+		LastLoggedInAt:               model.LastLoggedInAt,
+		OrganizationCount:            model.OrganizationCount,
+	}
+}
@@ -0,0 +1,19 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func DeploymentTargetAgentNetworkToAPI(network types.DeploymentTargetAgentNetwork) api.DeploymentTargetAgentNetwork {
+	return api.DeploymentTargetAgentNetwork{
+		UpdatedAt:         &network.UpdatedAt,
+		ProxyURL:          network.ProxyURL,
+		ProxyUsername:     network.ProxyUsername,
+		ProxyPasswordSet:  network.ProxyPassword != nil,
+		NoProxy:           network.NoProxy,
+		CACertificates:    network.CACertificates,
+		ClientCertificate: network.ClientCertificate,
+		ClientKeySet:      network.ClientKey != nil,
+	}
+}
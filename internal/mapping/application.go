@@ -11,3 +11,11 @@ func ApplicationToAPI(a types.Application) api.ApplicationResponse {
 		ImageUrl:    CreateImageURL(a.ImageID),
 	}
 }
+
+func ApplicationVersionHealthRuleToAgentAPI(r types.ApplicationVersionHealthRule) api.AgentHealthRule {
+	return api.AgentHealthRule{
+		Kind:     r.Kind,
+		JSONPath: r.JSONPath,
+		Value:    r.Value,
+	}
+}
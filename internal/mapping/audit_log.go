@@ -0,0 +1,29 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func AuditLogEntryToAPI(model types.AuditLogEntry) api.AuditLogEntry {
+	return api.AuditLogEntry{
+		ID:                        model.ID,
+		CreatedAt:                 model.CreatedAt,
+		ActorUserAccountID:        model.ActorUserAccountID,
+		ActorEmail:                model.ActorEmail,
+		AuthMethod:                model.AuthMethod,
+		CustomerOrganizationID:    model.CustomerOrganizationID,
+		PartnerOrganizationID:     model.PartnerOrganizationID,
+		Resource:                  model.Resource,
+		ResourceID:                model.ResourceID,
+		Action:                    model.Action,
+		HTTPMethod:                model.HTTPMethod,
+		Path:                      model.Path,
+		StatusCode:                model.StatusCode,
+		Changes:                   model.Changes,
+		RemoteAddress:             model.RemoteAddress,
+		RequestID:                 model.RequestID,
+		ImpersonatorUserAccountID: model.ImpersonatorUserAccountID,
+		ImpersonatorEmail:         model.ImpersonatorEmail,
+	}
+}
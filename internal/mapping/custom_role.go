@@ -0,0 +1,17 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func CustomRoleToAPI(model types.CustomRole) api.CustomRole {
+	return api.CustomRole{
+		ID:          model.ID,
+		CreatedAt:   model.CreatedAt,
+		UpdatedAt:   model.UpdatedAt,
+		Name:        model.Name,
+		Description: model.Description,
+		Permissions: model.Permissions,
+	}
+}
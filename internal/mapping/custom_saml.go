@@ -0,0 +1,42 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/saml"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func CustomSAMLConfigurationToDTO(
+	model types.CustomSAMLConfiguration,
+	organizationSlug *string,
+	domain string,
+) api.CustomSAMLConfiguration {
+	result := api.CustomSAMLConfiguration{
+		ID:                  model.ID,
+		CreatedAt:           model.CreatedAt,
+		UpdatedAt:           model.UpdatedAt,
+		CustomDomainID:      model.CustomDomainID,
+		Name:                model.Name,
+		Slug:                model.Slug,
+		Enabled:             model.Enabled,
+		IDPMetadata:         model.IDPMetadata,
+		IDPEntityID:         model.IDPEntityID,
+		AllowIDPInitiated:   model.AllowIDPInitiated,
+		EmailAttribute:      model.EmailAttribute,
+		NameAttribute:       model.NameAttribute,
+		RoleAttribute:       model.RoleAttribute,
+		RoleMapping:         model.RoleMapping,
+		CreateUnknownUsers:  model.CreateUnknownUsers,
+		DefaultUserRole:     model.DefaultUserRole,
+		AllowedEmailDomains: model.AllowedEmailDomains,
+		SPCertificate:       model.SPCertificate,
+	}
+	// The URLs are left empty while a part of them is still missing.
+	if domain != "" && organizationSlug != nil && *organizationSlug != "" {
+		baseURL := saml.BaseURL(domain)
+		result.SPMetadataURL = saml.MetadataURL(baseURL, *organizationSlug, model.Slug)
+		result.SPEntityID = result.SPMetadataURL
+		result.SPACSURL = saml.ACSURL(baseURL, *organizationSlug, model.Slug)
+	}
+	return result
+}
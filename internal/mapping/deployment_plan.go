@@ -0,0 +1,25 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func DeploymentRevisionPlanToAPI(plan types.DeploymentRevisionPlan) api.DeploymentRevisionPlan {
+	return api.DeploymentRevisionPlan{
+		DeploymentRevisionID: plan.DeploymentRevisionID,
+		CreatedAt:            plan.CreatedAt,
+		Digest:               plan.Digest,
+		HasChanges:           plan.HasChanges,
+		Output:               plan.Output,
+		ApprovedAt:           plan.ApprovedAt,
+	}
+}
+
+func DeploymentMetadataToAPI(metadata types.DeploymentMetadata) api.DeploymentMetadata {
+	return api.DeploymentMetadata{
+		DeploymentRevisionID: metadata.DeploymentRevisionID,
+		UpdatedAt:            metadata.UpdatedAt,
+		Outputs:              metadata.Outputs,
+	}
+}
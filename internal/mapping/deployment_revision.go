@@ -34,17 +34,19 @@ func DeploymentRevisionToAPI(
 	viewerRank := organizationKindRank(viewerCustomerOrgID, viewerPartnerOrgID)
 	return func(r types.DeploymentRevisionWithCreator) *api.DeploymentRevisionResponse {
 		response := &api.DeploymentRevisionResponse{
-			ID:                     r.ID,
-			CreatedAt:              r.CreatedAt,
-			ApplicationVersionID:   r.ApplicationVersionID,
-			ApplicationVersionName: r.ApplicationVersionName,
-			ReleaseName:            r.ReleaseName,
-			DockerType:             r.DockerType,
-			ValuesYaml:             r.ValuesYaml,
-			EnvFileData:            r.EnvFileData,
-			ForceRestart:           r.ForceRestart,
-			IgnoreRevisionSkew:     r.IgnoreRevisionSkew,
-			HelmOptions:            r.HelmOptions,
+			ID:                      r.ID,
+			CreatedAt:               r.CreatedAt,
+			ApplicationVersionID:    r.ApplicationVersionID,
+			ApplicationVersionName:  r.ApplicationVersionName,
+			ReleaseName:             r.ReleaseName,
+			DockerType:              r.DockerType,
+			ValuesYaml:              r.ValuesYaml,
+			EnvFileData:             r.EnvFileData,
+			ForceRestart:            r.ForceRestart,
+			IgnoreRevisionSkew:      r.IgnoreRevisionSkew,
+			HelmOptions:             r.HelmOptions,
+			VolumeBackupPolicy:      r.VolumeBackupPolicy,
+			RestoreVolumeSnapshotID: r.RestoreVolumeSnapshotID,
 		}
 
 		if r.CreatedByID != nil {
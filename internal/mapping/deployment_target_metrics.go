@@ -17,6 +17,7 @@ func DeploymentTargetMetricsRequestToInternal(
 		MemoryBytes:        req.MemoryBytes,
 		MemoryUsage:        req.MemoryUsage,
 		DiskMetrics:        List(req.DiskMetrics, DeploymentTargetDiskMetricToInternal),
+		ContainerMetrics:   List(req.ContainerMetrics, DeploymentContainerMetricToInternal),
 	}
 }
 
@@ -30,6 +31,21 @@ func DeploymentTargetDiskMetricToInternal(disk api.DeploymentTargetDiskMetric) t
 	}
 }
 
+func DeploymentContainerMetricToInternal(container api.DeploymentContainerMetric) types.DeploymentContainerMetric {
+	return types.DeploymentContainerMetric{
+		DeploymentID:    container.DeploymentID,
+		Service:         container.Service,
+		Name:            container.Name,
+		CPUUsedMillis:   container.CPUUsedMillis,
+		CPUUsage:        container.CPUUsage,
+		MemoryUsedBytes: container.MemoryUsedBytes,
+		MemoryUsage:     container.MemoryUsage,
+		Restarts:        container.Restarts,
+		NetworkRxBytes:  container.NetworkRxBytes,
+		NetworkTxBytes:  container.NetworkTxBytes,
+	}
+}
+
 func DeploymentTargetMetricsToAPI(metrics types.DeploymentTargetMetrics) api.DeploymentTargetMetrics {
 	return api.DeploymentTargetMetrics{
 		DeploymentTargetID: metrics.DeploymentTargetID,
@@ -38,6 +54,7 @@ func DeploymentTargetMetricsToAPI(metrics types.DeploymentTargetMetrics) api.Dep
 		MemoryBytes:        metrics.MemoryBytes,
 		MemoryUsage:        metrics.MemoryUsage,
 		DiskMetrics:        List(metrics.DiskMetrics, DeploymentTargetDiskMetricToAPI),
+		ContainerMetrics:   List(metrics.ContainerMetrics, DeploymentContainerMetricToAPI),
 	}
 }
 
@@ -50,3 +67,18 @@ func DeploymentTargetDiskMetricToAPI(disk types.DeploymentTargetDiskMetric) api.
 		BytesUsed:  disk.BytesUsed,
 	}
 }
+
+func DeploymentContainerMetricToAPI(container types.DeploymentContainerMetric) api.DeploymentContainerMetric {
+	return api.DeploymentContainerMetric{
+		DeploymentID:    container.DeploymentID,
+		Service:         container.Service,
+		Name:            container.Name,
+		CPUUsedMillis:   container.CPUUsedMillis,
+		CPUUsage:        container.CPUUsage,
+		MemoryUsedBytes: container.MemoryUsedBytes,
+		MemoryUsage:     container.MemoryUsage,
+		Restarts:        container.Restarts,
+		NetworkRxBytes:  container.NetworkRxBytes,
+		NetworkTxBytes:  container.NetworkTxBytes,
+	}
+}
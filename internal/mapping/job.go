@@ -0,0 +1,27 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func JobRunToAPI(model types.JobRun) api.JobRun {
+	return api.JobRun{
+		ID:         model.ID,
+		JobName:    model.JobName,
+		Holder:     model.Holder,
+		StartedAt:  model.StartedAt,
+		FinishedAt: model.FinishedAt,
+		Status:     string(model.Status),
+		Error:      model.Error,
+	}
+}
+
+func JobLeaseToAPI(model types.JobLease) api.JobLease {
+	return api.JobLease{
+		JobName:    model.JobName,
+		Holder:     model.Holder,
+		AcquiredAt: model.AcquiredAt,
+		ExpiresAt:  model.ExpiresAt,
+	}
+}
@@ -20,6 +20,8 @@ func NotificationRecordWithCurrentStatusToAPI(
 			MetricType:                         record.MetricType,
 			DiskDevice:                         record.DiskDevice,
 			DiskPath:                           record.DiskPath,
+			DeploymentID:                       record.DeploymentID,
+			ContainerName:                      record.ContainerName,
 			PreviousDeploymentTargetMetricsID:  record.PreviousDeploymentTargetMetricsID,
 			CurrentDeploymentTargetMetricsID:   record.CurrentDeploymentTargetMetricsID,
 			Message:                            record.Message,
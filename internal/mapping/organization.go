@@ -14,3 +14,14 @@ func OrganizationToAPI(o types.Organization, billableUserCount, customerOrgCount
 		CurrentCustomerOrganizationCount: customerOrgCount,
 	}
 }
+
+func SecurityPolicyToAPI(p types.SecurityPolicy) api.OrganizationSecurityPolicy {
+	return api.OrganizationSecurityPolicy{
+		RequireWebAuthnForAdmins: p.RequireWebAuthnForAdmins,
+		RequireMFA:               p.RequireMFA,
+		IPAllowlist:              p.IPAllowlist,
+		MaxAccessTokenLifetime:   p.MaxAccessTokenLifetime,
+		DisablePasswordLogin:     p.DisablePasswordLogin,
+		SessionIdleTimeout:       p.SessionIdleTimeout,
+	}
+}
@@ -0,0 +1,25 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/orgtransfer"
+)
+
+func OrganizationImportResultToAPI(result orgtransfer.ImportResult) api.OrganizationImportResponse {
+	return api.OrganizationImportResponse{
+		OrganizationID: result.OrganizationID,
+		DryRun:         result.DryRun,
+		Tables: List(result.Tables, func(table orgtransfer.Table) api.OrganizationTransferTable {
+			return api.OrganizationTransferTable{Name: table.Name, Rows: table.Rows}
+		}),
+		Blobs: result.Blobs,
+		Conflicts: List(result.Conflicts, func(conflict orgtransfer.Conflict) api.OrganizationImportConflict {
+			return api.OrganizationImportConflict{
+				Table:      conflict.Table,
+				Column:     conflict.Column,
+				Value:      conflict.Value,
+				Resolution: conflict.Resolution,
+			}
+		}),
+	}
+}
@@ -0,0 +1,28 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func ScimTokenToDTO(model types.ScimToken) api.ScimToken {
+	return api.ScimToken{
+		ID:                     model.ID,
+		CreatedAt:              model.CreatedAt,
+		LastUsedAt:             model.LastUsedAt,
+		Label:                  model.Label,
+		CustomerOrganizationID: model.CustomerOrganizationID,
+	}
+}
+
+func ScimGroupToDTO(model types.ScimGroup) api.ScimGroup {
+	return api.ScimGroup{
+		ID:                     model.ID,
+		CreatedAt:              model.CreatedAt,
+		DisplayName:            model.DisplayName,
+		ExternalID:             model.ExternalID,
+		UserRole:               model.UserRole,
+		MemberCount:            len(model.MemberIDs),
+		CustomerOrganizationID: model.CustomerOrganizationID,
+	}
+}
@@ -0,0 +1,16 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func ServiceAccountToAPI(model types.UserAccountWithUserRole) api.ServiceAccount {
+	return api.ServiceAccount{
+		ID:           model.ID,
+		CreatedAt:    model.CreatedAt,
+		Name:         model.Name,
+		UserRole:     model.UserRole,
+		CustomRoleID: model.CustomRoleID,
+	}
+}
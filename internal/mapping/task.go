@@ -0,0 +1,24 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func TaskToAPI(model types.Task) api.Task {
+	return api.Task{
+		ID:              model.ID,
+		CreatedAt:       model.CreatedAt,
+		Type:            model.Type,
+		Status:          string(model.Status),
+		Attempts:        model.Attempts,
+		MaxAttempts:     model.MaxAttempts,
+		RunAfter:        model.RunAfter,
+		Progress:        model.Progress,
+		ProgressMessage: model.ProgressMessage,
+		Result:          model.Result,
+		Error:           model.Error,
+		StartedAt:       model.StartedAt,
+		FinishedAt:      model.FinishedAt,
+	}
+}
@@ -0,0 +1,68 @@
+package mapping
+
+import (
+	"strings"
+
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+// UserSessionToAPI returns a mapping function that marks the session with currentSessionID as current.
+func UserSessionToAPI(currentSessionID *uuid.UUID) func(types.UserSession) api.UserSession {
+	return func(model types.UserSession) api.UserSession {
+		result := api.UserSession{
+			ID:            model.ID,
+			CreatedAt:     model.CreatedAt,
+			LastSeenAt:    model.LastSeenAt,
+			ExpiresAt:     model.ExpiresAt,
+			UserAgent:     model.UserAgent,
+			RemoteAddress: model.RemoteAddress,
+			Current:       currentSessionID != nil && *currentSessionID == model.ID,
+		}
+		if model.UserAgent != nil {
+			result.Device = userAgentDevice(*model.UserAgent)
+		}
+		return result
+	}
+}
+
+// userAgentDevice describes the browser and operating system of a user agent like "Firefox on Linux". The order
+// of the checks matters, because most browsers also claim to be the ones they are based on.
+func userAgentDevice(userAgent string) string {
+	var browser, os string
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(userAgent, "Firefox/"), strings.Contains(userAgent, "FxiOS/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"), strings.Contains(userAgent, "CriOS/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	}
+	switch {
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		os = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		os = "Android"
+	case strings.Contains(userAgent, "Windows"):
+		os = "Windows"
+	case strings.Contains(userAgent, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(userAgent, "CrOS"):
+		os = "ChromeOS"
+	case strings.Contains(userAgent, "Linux"):
+		os = "Linux"
+	}
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	default:
+		return os
+	}
+}
@@ -0,0 +1,42 @@
+package mapping_test
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/mapping"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestUserSessionToAPI(t *testing.T) {
+	g := NewWithT(t)
+	current := types.UserSession{ID: uuid.New()}
+	other := types.UserSession{ID: uuid.New()}
+
+	toAPI := mapping.UserSessionToAPI(&current.ID)
+	g.Expect(toAPI(current).Current).To(BeTrue())
+	g.Expect(toAPI(other).Current).To(BeFalse())
+	g.Expect(mapping.UserSessionToAPI(nil)(current).Current).To(BeFalse())
+}
+
+func TestUserSessionToAPI_Device(t *testing.T) {
+	for userAgent, device := range map[string]string{
+		"Mozilla/5.0 (X11; Linux x86_64; rv:131.0) Gecko/20100101 Firefox/131.0": "Firefox on Linux",
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/130.0.0.0 Safari/537.36 Edg/130.0.0.0": "Edge on Windows",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/130.0.0.0 Safari/537.36": "Chrome on macOS",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 18_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) " +
+			"Version/18.0 Mobile/15E148 Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) " +
+			"Chrome/130.0.0.0 Mobile Safari/537.36": "Chrome on Android",
+		"curl/8.10.1": "",
+	} {
+		t.Run(device, func(t *testing.T) {
+			g := NewWithT(t)
+			session := types.UserSession{UserAgent: &userAgent}
+			g.Expect(mapping.UserSessionToAPI(nil)(session).Device).To(Equal(device))
+		})
+	}
+}
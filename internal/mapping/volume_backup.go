@@ -0,0 +1,54 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+)
+
+func VolumeBackupStorageToAPI(storage types.VolumeBackupStorage) api.VolumeBackupStorage {
+	return api.VolumeBackupStorage{
+		UpdatedAt:            &storage.UpdatedAt,
+		Type:                 storage.Type,
+		Retention:            storage.Retention,
+		S3Endpoint:           storage.S3Endpoint,
+		S3Region:             storage.S3Region,
+		S3Bucket:             storage.S3Bucket,
+		S3Prefix:             storage.S3Prefix,
+		S3UsePathStyle:       storage.S3UsePathStyle,
+		S3AccessKeyID:        storage.S3AccessKeyID,
+		S3SecretAccessKeySet: storage.S3SecretAccessKey != nil,
+	}
+}
+
+// VolumeBackupStorageToAgentAPI includes the credentials of the storage. A deployment target without a storage
+// keeps its snapshots locally.
+func VolumeBackupStorageToAgentAPI(storage *types.VolumeBackupStorage) *api.AgentVolumeBackupStorage {
+	if storage == nil {
+		return &api.AgentVolumeBackupStorage{
+			Type:      types.VolumeBackupStorageLocal,
+			Retention: types.DefaultVolumeBackupRetention,
+		}
+	}
+	return &api.AgentVolumeBackupStorage{
+		Type:              storage.Type,
+		Retention:         storage.Retention,
+		S3Endpoint:        util.PtrDerefOrDefault(storage.S3Endpoint),
+		S3Region:          util.PtrDerefOrDefault(storage.S3Region),
+		S3Bucket:          util.PtrDerefOrDefault(storage.S3Bucket),
+		S3Prefix:          util.PtrDerefOrDefault(storage.S3Prefix),
+		S3UsePathStyle:    storage.S3UsePathStyle,
+		S3AccessKeyID:     util.PtrDerefOrDefault(storage.S3AccessKeyID),
+		S3SecretAccessKey: util.PtrDerefOrDefault(storage.S3SecretAccessKey),
+	}
+}
+
+func DeploymentVolumeSnapshotToAPI(snapshot types.DeploymentVolumeSnapshot) api.DeploymentVolumeSnapshot {
+	return api.DeploymentVolumeSnapshot{
+		ID:                   snapshot.ID,
+		CreatedAt:            snapshot.CreatedAt,
+		DeploymentRevisionID: snapshot.DeploymentRevisionID,
+		SizeBytes:            snapshot.SizeBytes,
+		Volumes:              snapshot.Volumes,
+	}
+}
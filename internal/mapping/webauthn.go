@@ -0,0 +1,15 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func WebAuthnCredentialToAPI(model types.WebAuthnCredential) api.WebAuthnCredential {
+	return api.WebAuthnCredential{
+		ID:         model.ID,
+		CreatedAt:  model.CreatedAt,
+		Name:       model.Name,
+		LastUsedAt: model.LastUsedAt,
+	}
+}
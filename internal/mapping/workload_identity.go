@@ -0,0 +1,20 @@
+package mapping
+
+import (
+	"github.com/distr-sh/distr/api"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+func WorkloadIdentityTrustPolicyToAPI(model types.WorkloadIdentityTrustPolicy) api.WorkloadIdentityTrustPolicy {
+	return api.WorkloadIdentityTrustPolicy{
+		ID:             model.ID,
+		CreatedAt:      model.CreatedAt,
+		Name:           model.Name,
+		Issuer:         model.Issuer,
+		Audience:       model.Audience,
+		SubjectPattern: model.SubjectPattern,
+		ClaimPatterns:  model.ClaimPatterns,
+		Scopes:         model.Scopes,
+		TokenLifetime:  model.TokenLifetime,
+	}
+}
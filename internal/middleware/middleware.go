@@ -8,6 +8,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/distr-sh/distr/internal/agentnotify"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/authjwt"
 	"github.com/distr-sh/distr/internal/authkey"
@@ -39,6 +41,8 @@ func ContextInjectorMiddleware(
 	oidcer *oidc.OIDCer,
 	prometheusCollector *prometheus.DistrCollector,
 	logStore logstore.LogStore,
+	s3Client *s3.Client,
+	agentNotifier *agentnotify.Notifier,
 ) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -53,6 +57,12 @@ func ContextInjectorMiddleware(
 			if logStore != nil {
 				ctx = logstore.NewContext(ctx, logStore)
 			}
+			if s3Client != nil {
+				ctx = internalctx.WithS3Client(ctx, s3Client)
+			}
+			if agentNotifier != nil {
+				ctx = agentnotify.NewContext(ctx, agentNotifier)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -108,7 +118,9 @@ func isSuperAdmin(ctx context.Context) bool {
 	return false
 }
 
-func RequireAnyUserRole(userRoles ...types.UserRole) func(handler http.Handler) http.Handler {
+// RequirePermission only lets requests pass whose credential was granted action on resource, either by the
+// built-in role or by the custom role of the user. Super admins are granted every permission.
+func RequirePermission(resource types.Resource, action types.Action) func(handler http.Handler) http.Handler {
 	return func(handler http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			ctx := r.Context()
@@ -118,7 +130,7 @@ func RequireAnyUserRole(userRoles ...types.UserRole) func(handler http.Handler)
 			}
 			if auth, err := auth.Authentication.Get(ctx); err != nil {
 				http.Error(w, err.Error(), http.StatusForbidden)
-			} else if auth.CurrentUserRole() == nil || !slices.Contains(userRoles, *auth.CurrentUserRole()) {
+			} else if !auth.CurrentPermissions().Has(resource, action) {
 				http.Error(w, "insufficient permissions", http.StatusForbidden)
 			} else {
 				handler.ServeHTTP(w, r)
@@ -128,11 +140,6 @@ func RequireAnyUserRole(userRoles ...types.UserRole) func(handler http.Handler)
 	}
 }
 
-var (
-	RequireReadWriteOrAdmin = RequireAnyUserRole(types.UserRoleReadWrite, types.UserRoleAdmin)
-	RequireAdmin            = RequireAnyUserRole(types.UserRoleAdmin)
-)
-
 // ForbidSubscriptionTypes blocks the given subscription types. Gating is expressed as a
 // denylist of the lower plans instead of an allowlist of the higher ones, so a newly
 // introduced plan has access by default.
@@ -297,6 +304,18 @@ func RequireEmailVerified(handler http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// RequireSuperAdmin only lets super admins pass, for endpoints that concern the whole Distr instance.
+func RequireSuperAdmin(handler http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if !isSuperAdmin(r.Context()) {
+			http.Error(w, "only super admins can use this endpoint", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
 func BlockSuperAdmin(handler http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		if isSuperAdmin(r.Context()) {
@@ -308,6 +327,33 @@ func BlockSuperAdmin(handler http.Handler) http.Handler {
 	return http.HandlerFunc(fn)
 }
 
+// BlockServiceAccount rejects requests of service accounts to endpoints that only make sense for a person, like
+// the settings of the own user account.
+func BlockServiceAccount(handler http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if auth, err := auth.Authentication.Get(r.Context()); err == nil && auth.IsServiceAccount() {
+			http.Error(w, "service accounts cannot use this endpoint", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
+// BlockImpersonation rejects requests of super admins who impersonate a user to endpoints that manage the
+// credentials, identities or sessions of the user, so that an impersonation cannot outlive its short-lived token.
+// Handlers that change the password along with other settings check the impersonator themselves.
+func BlockImpersonation(handler http.Handler) http.Handler {
+	fn := func(w http.ResponseWriter, r *http.Request) {
+		if auth, err := auth.Authentication.Get(r.Context()); err == nil && auth.CurrentImpersonator() != nil {
+			http.Error(w, "impersonating super admins cannot use this endpoint", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	}
+	return http.HandlerFunc(fn)
+}
+
 func BlockSuperAdminUnlessOrganizationExpired(handler http.Handler) http.Handler {
 	fn := func(w http.ResponseWriter, r *http.Request) {
 		ctx := r.Context()
@@ -0,0 +1,4 @@
+DROP TABLE LicenseKeyExpirationNotificationRecord;
+
+ALTER TABLE Organization
+  DROP COLUMN license_key_expiration_reminder_days;
@@ -0,0 +1,12 @@
+ALTER TABLE Organization
+  ADD COLUMN license_key_expiration_reminder_days INTEGER[] NOT NULL DEFAULT '{}';
+
+CREATE TABLE LicenseKeyExpirationNotificationRecord (
+  id             UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at     TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  license_key_id UUID      NOT NULL REFERENCES LicenseKey (id) ON DELETE CASCADE,
+  -- the expiry the reminder was sent for, so that a renewed license key gets reminded again
+  expires_at     TIMESTAMP NOT NULL,
+  days_before    INTEGER   NOT NULL,
+  CONSTRAINT LicenseKeyExpirationNotificationRecord_unique UNIQUE (license_key_id, expires_at, days_before)
+);
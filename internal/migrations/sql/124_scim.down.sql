@@ -0,0 +1,4 @@
+DROP TABLE ScimGroupMember;
+DROP TABLE ScimGroup;
+DROP TABLE ScimUser;
+DROP TABLE ScimToken;
@@ -0,0 +1,55 @@
+CREATE TABLE ScimToken (
+  id                       UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  last_used_at             TIMESTAMP,
+  label                    TEXT,
+  key                      BYTEA     NOT NULL UNIQUE,
+  organization_id          UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  customer_organization_id UUID      REFERENCES CustomerOrganization (id) ON DELETE CASCADE
+);
+
+CREATE INDEX fk_ScimToken_organization_id ON ScimToken (organization_id);
+CREATE INDEX fk_ScimToken_customer_organization_id ON ScimToken (customer_organization_id);
+
+-- A ScimUser outlives the organization membership of a deactivated user, so that the identity provider can
+-- still read and reactivate it. The SCIM resource ID is the ID of the user account.
+CREATE TABLE ScimUser (
+  organization_id          UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  user_account_id          UUID      NOT NULL REFERENCES UserAccount (id) ON DELETE CASCADE,
+  customer_organization_id UUID      REFERENCES CustomerOrganization (id) ON DELETE CASCADE,
+  created_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  updated_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  external_id              TEXT,
+  active                   BOOLEAN   NOT NULL DEFAULT TRUE,
+  PRIMARY KEY (organization_id, user_account_id)
+);
+
+CREATE INDEX fk_ScimUser_user_account_id ON ScimUser (user_account_id);
+CREATE INDEX fk_ScimUser_customer_organization_id ON ScimUser (customer_organization_id);
+
+CREATE TABLE ScimGroup (
+  id                       UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  updated_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id          UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  customer_organization_id UUID      REFERENCES CustomerOrganization (id) ON DELETE CASCADE,
+  display_name             TEXT      NOT NULL,
+  external_id              TEXT,
+  -- the role granted to members of this group, NULL until an admin maps the group to a role
+  user_role                USER_ROLE,
+  CONSTRAINT ScimGroup_scope_display_name_unique
+    UNIQUE NULLS NOT DISTINCT (organization_id, customer_organization_id, display_name)
+);
+
+CREATE INDEX fk_ScimGroup_customer_organization_id ON ScimGroup (customer_organization_id);
+
+CREATE TABLE ScimGroupMember (
+  scim_group_id   UUID NOT NULL REFERENCES ScimGroup (id) ON DELETE CASCADE,
+  organization_id UUID NOT NULL,
+  user_account_id UUID NOT NULL,
+  PRIMARY KEY (scim_group_id, user_account_id),
+  FOREIGN KEY (organization_id, user_account_id)
+    REFERENCES ScimUser (organization_id, user_account_id) ON DELETE CASCADE
+);
+
+CREATE INDEX fk_ScimGroupMember_scim_user ON ScimGroupMember (organization_id, user_account_id);
@@ -0,0 +1,2 @@
+-- Enum values cannot be removed without recreating the type, and the type is referenced by a
+-- column whose data would have to be rewritten. The added value is harmless when unused.
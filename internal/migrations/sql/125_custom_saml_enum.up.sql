@@ -0,0 +1 @@
+ALTER TYPE OIDC_PROVIDER ADD VALUE IF NOT EXISTS 'saml';
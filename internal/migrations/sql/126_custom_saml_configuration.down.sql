@@ -0,0 +1,14 @@
+DROP TABLE SAMLRequest;
+
+DROP INDEX UserAccountOIDCIdentity_config_issuer_subject_uq;
+DELETE FROM UserAccountOIDCIdentity WHERE custom_saml_configuration_id IS NOT NULL;
+CREATE UNIQUE INDEX UserAccountOIDCIdentity_config_issuer_subject_uq
+  ON UserAccountOIDCIdentity (custom_oidc_configuration_id, issuer, subject) NULLS NOT DISTINCT;
+
+DROP INDEX fk_UserAccountOIDCIdentity_custom_saml_configuration_id;
+
+ALTER TABLE UserAccountOIDCIdentity
+  DROP CONSTRAINT UserAccountOIDCIdentity_custom_saml_config_check,
+  DROP COLUMN custom_saml_configuration_id;
+
+DROP TABLE CustomSAMLConfiguration;
@@ -0,0 +1,59 @@
+CREATE TABLE CustomSAMLConfiguration (
+  id                         UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at                 TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  updated_at                 TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  updated_by_user_account_id UUID      REFERENCES UserAccount (id) ON DELETE SET NULL,
+  organization_id            UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  -- RESTRICT for the same reason as CustomOIDCConfiguration.custom_domain_id
+  custom_domain_id           UUID      NOT NULL REFERENCES CustomDomain (id) ON DELETE RESTRICT,
+  name                       TEXT      NOT NULL,
+  slug                       TEXT      NOT NULL,
+  enabled                    BOOLEAN   NOT NULL DEFAULT TRUE,
+  -- the metadata document as uploaded, and the entity ID parsed from it to match identities on
+  idp_metadata               TEXT      NOT NULL,
+  idp_entity_id              TEXT      NOT NULL,
+  -- generated per configuration: the certificate is published in the service provider metadata so
+  -- that the IdP can encrypt assertions and verify signed authentication requests
+  sp_certificate             TEXT      NOT NULL,
+  sp_private_key             TEXT      NOT NULL,
+  allow_idp_initiated        BOOLEAN   NOT NULL DEFAULT FALSE,
+  email_attribute            TEXT,
+  name_attribute             TEXT,
+  role_attribute             TEXT,
+  -- attribute value -> USER_ROLE
+  role_mapping               JSONB     NOT NULL DEFAULT '{}',
+  create_unknown_users       BOOLEAN   NOT NULL DEFAULT FALSE,
+  default_user_role          USER_ROLE NOT NULL DEFAULT 'read_write',
+  allowed_email_domains      TEXT[]    NOT NULL DEFAULT '{}',
+  CONSTRAINT CustomSAMLConfiguration_org_name_unique UNIQUE (organization_id, name),
+  CONSTRAINT CustomSAMLConfiguration_org_slug_unique UNIQUE (organization_id, slug),
+  CONSTRAINT CustomSAMLConfiguration_provisioning_domains_check
+    CHECK (NOT create_unknown_users OR cardinality(allowed_email_domains) > 0)
+);
+
+CREATE INDEX fk_CustomSAMLConfiguration_organization_id ON CustomSAMLConfiguration (organization_id);
+CREATE INDEX fk_CustomSAMLConfiguration_custom_domain_id ON CustomSAMLConfiguration (custom_domain_id);
+
+ALTER TABLE UserAccountOIDCIdentity
+  ADD COLUMN custom_saml_configuration_id UUID
+    REFERENCES CustomSAMLConfiguration (id) ON DELETE CASCADE,
+  ADD CONSTRAINT UserAccountOIDCIdentity_custom_saml_config_check
+    CHECK ((provider = 'saml') = (custom_saml_configuration_id IS NOT NULL));
+
+CREATE INDEX fk_UserAccountOIDCIdentity_custom_saml_configuration_id
+  ON UserAccountOIDCIdentity (custom_saml_configuration_id);
+
+DROP INDEX UserAccountOIDCIdentity_config_issuer_subject_uq;
+CREATE UNIQUE INDEX UserAccountOIDCIdentity_config_issuer_subject_uq
+  ON UserAccountOIDCIdentity (custom_oidc_configuration_id, custom_saml_configuration_id, issuer, subject)
+  NULLS NOT DISTINCT;
+
+-- Outstanding SP-initiated authentication requests. The ID is the one of the AuthnRequest, which the
+-- IdP echoes as InResponseTo, and is passed along as RelayState to find the request again.
+CREATE TABLE SAMLRequest (
+  id                           TEXT      PRIMARY KEY,
+  created_at                   TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  custom_saml_configuration_id UUID      NOT NULL REFERENCES CustomSAMLConfiguration (id) ON DELETE CASCADE
+);
+
+CREATE INDEX fk_SAMLRequest_custom_saml_configuration_id ON SAMLRequest (custom_saml_configuration_id);
@@ -0,0 +1,14 @@
+DROP INDEX fk_AccessToken_custom_role_id;
+
+ALTER TABLE AccessToken
+  DROP CONSTRAINT AccessToken_custom_role_fk,
+  DROP COLUMN custom_role_id;
+
+DROP INDEX fk_Organization_UserAccount_custom_role_id;
+
+ALTER TABLE Organization_UserAccount
+  DROP CONSTRAINT Organization_UserAccount_custom_role_scope_check,
+  DROP CONSTRAINT Organization_UserAccount_custom_role_fk,
+  DROP COLUMN custom_role_id;
+
+DROP TABLE CustomRole;
@@ -0,0 +1,34 @@
+CREATE TABLE CustomRole (
+  id              UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at      TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  updated_at      TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  name            TEXT      NOT NULL,
+  description     TEXT,
+  -- "resource:action" pairs, validated by the application
+  permissions     TEXT[]    NOT NULL DEFAULT '{}',
+  CONSTRAINT CustomRole_org_name_unique UNIQUE (organization_id, name),
+  -- target of the composite foreign keys below, which keep assignments within the role's organization
+  CONSTRAINT CustomRole_id_org_unique UNIQUE (id, organization_id)
+);
+
+CREATE INDEX fk_CustomRole_organization_id ON CustomRole (organization_id);
+
+-- A custom role replaces the permissions of user_role, which is kept as the base role of the membership.
+-- RESTRICT so that a role can only be deleted once it is no longer assigned.
+ALTER TABLE Organization_UserAccount
+  ADD COLUMN custom_role_id UUID,
+  ADD CONSTRAINT Organization_UserAccount_custom_role_fk
+    FOREIGN KEY (custom_role_id, organization_id) REFERENCES CustomRole (id, organization_id) ON DELETE RESTRICT,
+  -- custom roles describe the vendor's own permissions and are not available to customer or partner users
+  ADD CONSTRAINT Organization_UserAccount_custom_role_scope_check
+    CHECK (custom_role_id IS NULL OR (customer_organization_id IS NULL AND partner_organization_id IS NULL));
+
+CREATE INDEX fk_Organization_UserAccount_custom_role_id ON Organization_UserAccount (custom_role_id);
+
+ALTER TABLE AccessToken
+  ADD COLUMN custom_role_id UUID,
+  ADD CONSTRAINT AccessToken_custom_role_fk
+    FOREIGN KEY (custom_role_id, organization_id) REFERENCES CustomRole (id, organization_id) ON DELETE RESTRICT;
+
+CREATE INDEX fk_AccessToken_custom_role_id ON AccessToken (custom_role_id);
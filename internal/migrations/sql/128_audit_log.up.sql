@@ -0,0 +1,26 @@
+CREATE TABLE AuditLogEntry (
+  id                       UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at               TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id          UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  -- the actor columns are kept when the user account is deleted, so that the entry stays attributable
+  actor_user_account_id    UUID      REFERENCES UserAccount (id) ON DELETE SET NULL,
+  actor_email              TEXT,
+  auth_method              TEXT      NOT NULL,
+  -- no foreign keys: entries must outlive the customer and partner organizations they mention
+  customer_organization_id UUID,
+  partner_organization_id  UUID,
+  resource                 TEXT      NOT NULL,
+  -- TEXT instead of UUID because some resources, like artifact tags, are identified by name
+  resource_id              TEXT,
+  action                   TEXT      NOT NULL,
+  http_method              TEXT,
+  path                     TEXT,
+  status_code              INT,
+  changes                  JSONB,
+  remote_address           TEXT,
+  request_id               TEXT
+);
+
+CREATE INDEX AuditLogEntry_organization_id_created_at ON AuditLogEntry (organization_id, created_at DESC);
+CREATE INDEX AuditLogEntry_created_at ON AuditLogEntry (created_at);
+CREATE INDEX fk_AuditLogEntry_actor_user_account_id ON AuditLogEntry (actor_user_account_id);
@@ -0,0 +1,5 @@
+ALTER TABLE Organization DROP COLUMN require_webauthn_for_admins;
+
+DROP TABLE WebAuthnSession;
+
+DROP TABLE WebAuthnCredential;
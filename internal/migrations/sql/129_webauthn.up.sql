@@ -0,0 +1,28 @@
+CREATE TABLE WebAuthnCredential (
+  id              UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at      TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  user_account_id UUID      NOT NULL REFERENCES UserAccount (id) ON DELETE CASCADE,
+  name            TEXT      NOT NULL,
+  -- the id chosen by the authenticator, which is sent with every assertion
+  credential_id   BYTEA     NOT NULL,
+  -- public key, sign count, flags and transports as stored by the WebAuthn library
+  credential      JSONB     NOT NULL,
+  last_used_at    TIMESTAMP,
+  CONSTRAINT WebAuthnCredential_credential_id_unique UNIQUE (credential_id),
+  CONSTRAINT WebAuthnCredential_user_name_unique UNIQUE (user_account_id, name)
+);
+
+CREATE INDEX fk_WebAuthnCredential_user_account_id ON WebAuthnCredential (user_account_id);
+
+-- holds the challenge of a registration or login ceremony between its begin and finish requests
+CREATE TABLE WebAuthnSession (
+  id              UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at      TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  -- NULL for passkey logins, where the user is only known once the assertion is finished
+  user_account_id UUID      REFERENCES UserAccount (id) ON DELETE CASCADE,
+  session_data    JSONB     NOT NULL
+);
+
+CREATE INDEX fk_WebAuthnSession_user_account_id ON WebAuthnSession (user_account_id);
+
+ALTER TABLE Organization ADD COLUMN require_webauthn_for_admins BOOLEAN NOT NULL DEFAULT false;
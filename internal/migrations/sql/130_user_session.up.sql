@@ -0,0 +1,16 @@
+-- every login token carries the id of its session as jti and is only accepted while the session exists
+CREATE TABLE UserSession (
+  id              UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at      TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  user_account_id UUID      NOT NULL REFERENCES UserAccount (id) ON DELETE CASCADE,
+  -- the organization of the latest token issued for the session, updated when switching the context
+  organization_id UUID      REFERENCES Organization (id) ON DELETE SET NULL,
+  expires_at      TIMESTAMP NOT NULL,
+  last_seen_at    TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  user_agent      TEXT,
+  remote_address  TEXT
+);
+
+CREATE INDEX fk_UserSession_user_account_id ON UserSession (user_account_id);
+CREATE INDEX fk_UserSession_organization_id ON UserSession (organization_id);
+CREATE INDEX UserSession_expires_at ON UserSession (expires_at);
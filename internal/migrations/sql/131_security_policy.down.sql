@@ -0,0 +1,9 @@
+ALTER TABLE UserSession DROP COLUMN auth_method;
+
+ALTER TABLE Organization ADD COLUMN require_webauthn_for_admins BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE Organization
+SET require_webauthn_for_admins = true
+WHERE (security_policy ->> 'requireWebAuthnForAdmins')::BOOLEAN;
+
+ALTER TABLE Organization DROP COLUMN security_policy;
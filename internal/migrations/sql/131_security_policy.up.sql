@@ -0,0 +1,10 @@
+ALTER TABLE Organization ADD COLUMN security_policy JSONB NOT NULL DEFAULT '{}';
+
+UPDATE Organization
+SET security_policy = jsonb_build_object('requireWebAuthnForAdmins', true)
+WHERE require_webauthn_for_admins;
+
+ALTER TABLE Organization DROP COLUMN require_webauthn_for_admins;
+
+-- how the user signed in, which the security policy of the organization may restrict
+ALTER TABLE UserSession ADD COLUMN auth_method TEXT NOT NULL DEFAULT 'password';
@@ -0,0 +1,4 @@
+ALTER TABLE AccessToken
+  DROP COLUMN scopes,
+  DROP COLUMN last_used_ip,
+  DROP COLUMN unused_expiry_days;
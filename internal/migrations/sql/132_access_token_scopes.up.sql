@@ -0,0 +1,6 @@
+ALTER TABLE AccessToken
+  -- "resource:action" or "artifacts:action:name", validated by the application. Empty means unrestricted.
+  ADD COLUMN scopes             TEXT[]  NOT NULL DEFAULT '{}',
+  ADD COLUMN last_used_ip       TEXT,
+  -- the token expires once it was not used for this many days
+  ADD COLUMN unused_expiry_days INTEGER CHECK (unused_expiry_days > 0);
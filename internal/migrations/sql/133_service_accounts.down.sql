@@ -0,0 +1,3 @@
+DELETE FROM UserAccount WHERE service_account_organization_id IS NOT NULL;
+
+ALTER TABLE UserAccount DROP COLUMN service_account_organization_id;
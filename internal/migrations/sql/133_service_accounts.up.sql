@@ -0,0 +1,6 @@
+ALTER TABLE UserAccount
+  -- service accounts belong to exactly one organization and cannot sign in, only use access tokens
+  ADD COLUMN service_account_organization_id UUID REFERENCES Organization (id) ON DELETE CASCADE;
+
+CREATE INDEX fk_UserAccount_service_account_organization_id
+  ON UserAccount (service_account_organization_id);
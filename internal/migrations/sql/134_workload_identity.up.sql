@@ -0,0 +1,17 @@
+CREATE TABLE WorkloadIdentityTrustPolicy (
+  id                 UUID      PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at         TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  organization_id    UUID      NOT NULL REFERENCES Organization (id) ON DELETE CASCADE,
+  -- the service account the exchanged access tokens belong to
+  service_account_id UUID      NOT NULL REFERENCES UserAccount (id) ON DELETE CASCADE,
+  name               TEXT      NOT NULL,
+  issuer             TEXT      NOT NULL,
+  audience           TEXT      NOT NULL,
+  subject_pattern    TEXT      NOT NULL,
+  claim_patterns     JSONB     NOT NULL DEFAULT '{}',
+  scopes             TEXT[]    NOT NULL DEFAULT '{}',
+  token_lifetime     TEXT      NOT NULL
+);
+
+CREATE INDEX fk_WorkloadIdentityTrustPolicy_organization_id ON WorkloadIdentityTrustPolicy (organization_id);
+CREATE INDEX fk_WorkloadIdentityTrustPolicy_service_account_id ON WorkloadIdentityTrustPolicy (service_account_id);
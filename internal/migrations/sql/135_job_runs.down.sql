@@ -0,0 +1,3 @@
+DROP TABLE JobRun;
+DROP TYPE JOB_RUN_STATUS;
+DROP TABLE JobLease;
@@ -0,0 +1,22 @@
+-- a replica only runs a scheduled job while it holds its lease, so that every run happens on a single replica
+CREATE TABLE JobLease (
+  job_name    TEXT      PRIMARY KEY,
+  holder      TEXT      NOT NULL,
+  acquired_at TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  expires_at  TIMESTAMP NOT NULL
+);
+
+CREATE TYPE JOB_RUN_STATUS AS ENUM ('running', 'succeeded', 'failed');
+
+CREATE TABLE JobRun (
+  id          UUID           PRIMARY KEY DEFAULT gen_random_uuid(),
+  job_name    TEXT           NOT NULL,
+  holder      TEXT           NOT NULL,
+  started_at  TIMESTAMP      NOT NULL DEFAULT current_timestamp,
+  finished_at TIMESTAMP,
+  status      JOB_RUN_STATUS NOT NULL DEFAULT 'running',
+  error       TEXT
+);
+
+CREATE INDEX JobRun_job_name_started_at ON JobRun (job_name, started_at DESC);
+CREATE INDEX JobRun_started_at ON JobRun (started_at);
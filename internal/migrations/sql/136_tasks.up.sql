@@ -0,0 +1,30 @@
+-- 'dead' tasks failed on their last attempt and are kept for inspection until they are retried or cleaned up
+CREATE TYPE TASK_STATUS AS ENUM ('pending', 'running', 'succeeded', 'dead');
+
+CREATE TABLE Task (
+  id               UUID        PRIMARY KEY DEFAULT gen_random_uuid(),
+  created_at       TIMESTAMP   NOT NULL DEFAULT current_timestamp,
+  -- NULL for tasks of the instance, like scheduled jobs
+  organization_id  UUID        REFERENCES Organization (id) ON DELETE CASCADE,
+  type             TEXT        NOT NULL,
+  payload          JSONB       NOT NULL DEFAULT '{}',
+  -- at most one pending or running task may exist per key
+  dedup_key        TEXT,
+  status           TASK_STATUS NOT NULL DEFAULT 'pending',
+  attempts         INTEGER     NOT NULL DEFAULT 0,
+  max_attempts     INTEGER     NOT NULL CHECK (max_attempts > 0),
+  run_after        TIMESTAMP   NOT NULL DEFAULT current_timestamp,
+  lease_holder     TEXT,
+  lease_expires_at TIMESTAMP,
+  progress         INTEGER     CHECK (progress BETWEEN 0 AND 100),
+  progress_message TEXT,
+  result           JSONB,
+  error            TEXT,
+  started_at       TIMESTAMP,
+  finished_at      TIMESTAMP
+);
+
+CREATE INDEX fk_Task_organization_id ON Task (organization_id);
+CREATE INDEX Task_run_after ON Task (run_after) WHERE status IN ('pending', 'running');
+CREATE INDEX Task_finished_at ON Task (finished_at);
+CREATE UNIQUE INDEX Task_dedup_key ON Task (dedup_key) WHERE status IN ('pending', 'running');
@@ -0,0 +1,5 @@
+DROP INDEX fk_AuditLogEntry_impersonator_user_account_id;
+
+ALTER TABLE AuditLogEntry
+  DROP COLUMN impersonator_user_account_id,
+  DROP COLUMN impersonator_email;
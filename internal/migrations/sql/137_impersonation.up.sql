@@ -0,0 +1,6 @@
+-- requests of a super admin who impersonates a user are recorded with the user as actor and the admin as impersonator
+ALTER TABLE AuditLogEntry
+  ADD COLUMN impersonator_user_account_id UUID REFERENCES UserAccount (id) ON DELETE SET NULL,
+  ADD COLUMN impersonator_email TEXT;
+
+CREATE INDEX fk_AuditLogEntry_impersonator_user_account_id ON AuditLogEntry (impersonator_user_account_id);
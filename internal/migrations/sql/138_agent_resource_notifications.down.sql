@@ -0,0 +1,12 @@
+DROP TRIGGER LicenseKeyRevision_agent_resource_changed ON LicenseKeyRevision;
+DROP FUNCTION notify_agent_resource_changed_license_key_revision;
+DROP TRIGGER ApplicationEntitlement_agent_resource_changed ON ApplicationEntitlement;
+DROP TRIGGER LicenseKey_agent_resource_changed ON LicenseKey;
+DROP TRIGGER Secret_agent_resource_changed ON Secret;
+DROP FUNCTION notify_agent_resource_changed_organization;
+DROP TRIGGER DeploymentRevision_agent_resource_changed ON DeploymentRevision;
+DROP FUNCTION notify_agent_resource_changed_deployment_revision;
+DROP TRIGGER Deployment_agent_resource_changed ON Deployment;
+DROP FUNCTION notify_agent_resource_changed_deployment;
+DROP TRIGGER DeploymentTarget_agent_resource_changed ON DeploymentTarget;
+DROP FUNCTION notify_agent_resource_changed_deployment_target;
@@ -0,0 +1,75 @@
+-- agents that wait for changes of their resource are woken up by a notification on the channel agent_resource_changed,
+-- with a payload of either deployment_target:<id> or organization:<id> for changes that concern all targets of an
+-- organization
+CREATE FUNCTION notify_agent_resource_changed_deployment_target() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || NEW.id);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER DeploymentTarget_agent_resource_changed
+  AFTER UPDATE ON DeploymentTarget
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_deployment_target();
+
+CREATE FUNCTION notify_agent_resource_changed_deployment() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || OLD.deployment_target_id);
+  ELSE
+    PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || NEW.deployment_target_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER Deployment_agent_resource_changed
+  AFTER INSERT OR UPDATE OR DELETE ON Deployment
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_deployment();
+
+CREATE FUNCTION notify_agent_resource_changed_deployment_revision() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || d.deployment_target_id)
+  FROM Deployment d WHERE d.id = NEW.deployment_id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER DeploymentRevision_agent_resource_changed
+  AFTER INSERT ON DeploymentRevision
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_deployment_revision();
+
+CREATE FUNCTION notify_agent_resource_changed_organization() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM pg_notify('agent_resource_changed', 'organization:' || OLD.organization_id);
+  ELSE
+    PERFORM pg_notify('agent_resource_changed', 'organization:' || NEW.organization_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER Secret_agent_resource_changed
+  AFTER INSERT OR UPDATE OR DELETE ON Secret
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_organization();
+
+CREATE TRIGGER LicenseKey_agent_resource_changed
+  AFTER INSERT OR UPDATE OR DELETE ON LicenseKey
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_organization();
+
+CREATE TRIGGER ApplicationEntitlement_agent_resource_changed
+  AFTER UPDATE ON ApplicationEntitlement
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_organization();
+
+CREATE FUNCTION notify_agent_resource_changed_license_key_revision() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('agent_resource_changed', 'organization:' || lk.organization_id)
+  FROM LicenseKey lk WHERE lk.id = NEW.license_key_id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER LicenseKeyRevision_agent_resource_changed
+  AFTER INSERT ON LicenseKeyRevision
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_license_key_revision();
@@ -0,0 +1,10 @@
+CREATE TABLE ApplicationVersionHealthRule (
+  id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+  application_version_id UUID NOT NULL REFERENCES ApplicationVersion (id) ON DELETE CASCADE,
+  kind TEXT NOT NULL,
+  json_path TEXT NOT NULL,
+  value TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX idx_application_version_health_rule_version_id
+  ON ApplicationVersionHealthRule (application_version_id);
@@ -0,0 +1,5 @@
+ALTER TABLE ApplicationVersion
+  DROP COLUMN IF EXISTS source_type,
+  DROP COLUMN IF EXISTS manifest_bundle_data;
+
+DROP TYPE IF EXISTS KUBERNETES_SOURCE_TYPE;
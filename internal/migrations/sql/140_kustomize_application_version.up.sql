@@ -0,0 +1,8 @@
+CREATE TYPE KUBERNETES_SOURCE_TYPE AS ENUM ('helm', 'kustomize');
+
+-- Kubernetes application versions are either a Helm chart or plain manifests or a Kustomize base in the manifest bundle
+ALTER TABLE ApplicationVersion
+  ADD COLUMN source_type KUBERNETES_SOURCE_TYPE,
+  ADD COLUMN manifest_bundle_data BYTEA;
+
+UPDATE ApplicationVersion SET source_type = 'helm' WHERE chart_type IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE ApplicationVersion
+  DROP COLUMN IF EXISTS unit_file_data,
+  DROP COLUMN IF EXISTS package_ref;
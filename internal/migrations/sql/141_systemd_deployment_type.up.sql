@@ -0,0 +1,5 @@
+ALTER TYPE DEPLOYMENT_TYPE ADD VALUE IF NOT EXISTS 'systemd';
+
+ALTER TABLE ApplicationVersion
+  ADD COLUMN package_ref TEXT,
+  ADD COLUMN unit_file_data BYTEA;
@@ -0,0 +1,4 @@
+DROP TRIGGER DeploymentRevisionPlan_agent_resource_changed ON DeploymentRevisionPlan;
+DROP FUNCTION notify_agent_resource_changed_deployment_revision_plan;
+DROP TABLE DeploymentMetadata;
+DROP TABLE DeploymentRevisionPlan;
@@ -0,0 +1,40 @@
+ALTER TYPE DEPLOYMENT_TYPE ADD VALUE IF NOT EXISTS 'opentofu';
+
+-- The latest plan of an OpenTofu deployment revision. The agent only applies a plan after it was approved, and a
+-- new plan with a different digest resets the approval.
+CREATE TABLE DeploymentRevisionPlan (
+  deployment_revision_id      UUID      PRIMARY KEY REFERENCES DeploymentRevision (id) ON DELETE CASCADE,
+  created_at                  TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  digest                      TEXT      NOT NULL,
+  has_changes                 BOOLEAN   NOT NULL,
+  output                      TEXT      NOT NULL,
+  approved_at                 TIMESTAMP,
+  approved_by_user_account_id UUID      REFERENCES UserAccount (id) ON DELETE SET NULL
+);
+
+CREATE INDEX fk_DeploymentRevisionPlan_approved_by_user_account_id
+  ON DeploymentRevisionPlan (approved_by_user_account_id);
+
+-- Metadata that the agent reports after a deployment was applied, e.g. the outputs of an OpenTofu module
+CREATE TABLE DeploymentMetadata (
+  deployment_id          UUID      PRIMARY KEY REFERENCES Deployment (id) ON DELETE CASCADE,
+  deployment_revision_id UUID      NOT NULL REFERENCES DeploymentRevision (id) ON DELETE CASCADE,
+  updated_at             TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  outputs                JSONB     NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX fk_DeploymentMetadata_deployment_revision_id ON DeploymentMetadata (deployment_revision_id);
+
+-- the approval of a plan is part of the agent resource
+CREATE FUNCTION notify_agent_resource_changed_deployment_revision_plan() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || d.deployment_target_id)
+  FROM DeploymentRevision dr JOIN Deployment d ON dr.deployment_id = d.id
+  WHERE dr.id = NEW.deployment_revision_id;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER DeploymentRevisionPlan_agent_resource_changed
+  AFTER UPDATE OF approved_at ON DeploymentRevisionPlan
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_deployment_revision_plan();
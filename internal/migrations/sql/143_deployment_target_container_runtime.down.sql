@@ -0,0 +1,2 @@
+ALTER TABLE DeploymentTarget
+    DROP COLUMN container_runtime;
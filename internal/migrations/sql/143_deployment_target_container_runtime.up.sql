@@ -0,0 +1,4 @@
+ALTER TABLE DeploymentTarget
+    ADD COLUMN container_runtime TEXT,
+    ADD CONSTRAINT deployment_target_container_runtime_check
+        CHECK (container_runtime IS NULL OR (type = 'docker' AND container_runtime IN ('docker', 'podman', 'quadlet')));
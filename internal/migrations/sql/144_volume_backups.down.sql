@@ -0,0 +1,7 @@
+DROP TABLE DeploymentVolumeSnapshot;
+DROP TRIGGER DeploymentTargetVolumeBackupStorage_agent_resource_changed ON DeploymentTargetVolumeBackupStorage;
+DROP FUNCTION notify_agent_resource_changed_volume_backup_storage;
+DROP TABLE DeploymentTargetVolumeBackupStorage;
+ALTER TABLE DeploymentRevision
+  DROP COLUMN restore_volume_snapshot_id,
+  DROP COLUMN volume_backup_policy;
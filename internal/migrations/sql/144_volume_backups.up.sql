@@ -0,0 +1,50 @@
+ALTER TABLE DeploymentRevision
+  ADD COLUMN volume_backup_policy TEXT NOT NULL DEFAULT 'none'
+    CONSTRAINT deployment_revision_volume_backup_policy_check
+      CHECK (volume_backup_policy IN ('none', 'beforeUpdate', 'beforeVersionUpdate')),
+  ADD COLUMN restore_volume_snapshot_id UUID;
+
+-- Where the agent of a deployment target stores volume snapshots. Targets without a row use the local disk.
+CREATE TABLE DeploymentTargetVolumeBackupStorage (
+  deployment_target_id UUID      PRIMARY KEY REFERENCES DeploymentTarget (id) ON DELETE CASCADE,
+  updated_at           TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  type                 TEXT      NOT NULL CHECK (type IN ('local', 's3')),
+  retention            INT       NOT NULL CHECK (retention > 0),
+  s3_endpoint          TEXT,
+  s3_region            TEXT,
+  s3_bucket            TEXT,
+  s3_prefix            TEXT,
+  s3_use_path_style    BOOLEAN   NOT NULL DEFAULT false,
+  s3_access_key_id     TEXT,
+  s3_secret_access_key TEXT,
+  CONSTRAINT deployment_target_volume_backup_storage_s3_bucket_check CHECK (type <> 's3' OR s3_bucket IS NOT NULL)
+);
+
+CREATE FUNCTION notify_agent_resource_changed_volume_backup_storage() RETURNS trigger AS $$
+BEGIN
+  IF TG_OP = 'DELETE' THEN
+    PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || OLD.deployment_target_id);
+  ELSE
+    PERFORM pg_notify('agent_resource_changed', 'deployment_target:' || NEW.deployment_target_id);
+  END IF;
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER DeploymentTargetVolumeBackupStorage_agent_resource_changed
+  AFTER INSERT OR UPDATE OR DELETE ON DeploymentTargetVolumeBackupStorage
+  FOR EACH ROW EXECUTE FUNCTION notify_agent_resource_changed_volume_backup_storage();
+
+-- The volume snapshots that the agent reports for a deployment. The snapshots themselves are only kept by the agent
+-- or in the storage of the deployment target.
+CREATE TABLE DeploymentVolumeSnapshot (
+  id                     UUID      PRIMARY KEY,
+  created_at             TIMESTAMP NOT NULL,
+  deployment_id          UUID      NOT NULL REFERENCES Deployment (id) ON DELETE CASCADE,
+  deployment_revision_id UUID      NOT NULL REFERENCES DeploymentRevision (id) ON DELETE CASCADE,
+  size_bytes             BIGINT    NOT NULL,
+  volumes                TEXT[]    NOT NULL
+);
+
+CREATE INDEX fk_DeploymentVolumeSnapshot_deployment_id ON DeploymentVolumeSnapshot (deployment_id);
+CREATE INDEX fk_DeploymentVolumeSnapshot_deployment_revision_id ON DeploymentVolumeSnapshot (deployment_revision_id);
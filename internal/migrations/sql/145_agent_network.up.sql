@@ -0,0 +1,17 @@
+-- Network settings of the agent of a deployment target, which are rendered into the agent manifest. Targets without
+-- a row connect directly and only trust the system CAs.
+CREATE TABLE DeploymentTargetAgentNetwork (
+  deployment_target_id UUID      PRIMARY KEY REFERENCES DeploymentTarget (id) ON DELETE CASCADE,
+  updated_at           TIMESTAMP NOT NULL DEFAULT current_timestamp,
+  proxy_url            TEXT,
+  proxy_username       TEXT,
+  proxy_password       TEXT,
+  no_proxy             TEXT,
+  ca_certificates      TEXT,
+  client_certificate   TEXT,
+  client_key           TEXT,
+  CONSTRAINT deployment_target_agent_network_proxy_username_check
+    CHECK (proxy_username IS NULL OR proxy_url IS NOT NULL),
+  CONSTRAINT deployment_target_agent_network_client_key_check
+    CHECK ((client_certificate IS NULL) = (client_key IS NULL))
+);
@@ -0,0 +1,9 @@
+ALTER TABLE NotificationRecord
+  DROP COLUMN deployment_id,
+  DROP COLUMN container_name;
+
+ALTER TABLE AlertConfiguration
+  DROP COLUMN container_cpu_trigger_threshold_percent,
+  DROP COLUMN container_memory_trigger_threshold_percent;
+
+DROP TABLE DeploymentContainerMetrics;
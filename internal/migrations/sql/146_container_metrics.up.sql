@@ -0,0 +1,30 @@
+-- Resource usage of the containers of a Docker deployment or the pods of a Kubernetes deployment, reported together
+-- with the metrics of the deployment target.
+CREATE TABLE DeploymentContainerMetrics (
+  id                           UUID   PRIMARY KEY DEFAULT gen_random_uuid(),
+  deployment_target_metrics_id UUID   NOT NULL REFERENCES DeploymentTargetMetrics (id) ON DELETE CASCADE,
+  deployment_id                UUID   NOT NULL REFERENCES Deployment (id) ON DELETE CASCADE,
+  service                      TEXT   NOT NULL,
+  name                         TEXT   NOT NULL,
+  cpu_used_millis              BIGINT NOT NULL,
+  cpu_usage                    FLOAT  NOT NULL,
+  memory_used_bytes            BIGINT NOT NULL,
+  memory_usage                 FLOAT  NOT NULL,
+  restarts                     BIGINT NOT NULL,
+  network_rx_bytes             BIGINT NOT NULL,
+  network_tx_bytes             BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS DeploymentContainerMetrics_metrics_id
+  ON DeploymentContainerMetrics (deployment_target_metrics_id);
+
+CREATE INDEX IF NOT EXISTS DeploymentContainerMetrics_deployment_id
+  ON DeploymentContainerMetrics (deployment_id);
+
+ALTER TABLE AlertConfiguration
+  ADD COLUMN container_cpu_trigger_threshold_percent INT,
+  ADD COLUMN container_memory_trigger_threshold_percent INT;
+
+ALTER TABLE NotificationRecord
+  ADD COLUMN deployment_id UUID REFERENCES Deployment (id) ON DELETE SET NULL,
+  ADD COLUMN container_name TEXT;
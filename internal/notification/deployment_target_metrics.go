@@ -61,7 +61,7 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 		log.Info("sending CPU alert notification")
 		if err := sendMetricNotification(
 			ctx, false, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
-			"cpu", "", "", *config.CpuTriggerThreshold, usagePercent(currentMetrics.CPUUsage),
+			"cpu", "", "", *config.CpuTriggerThreshold, usagePercent(currentMetrics.CPUUsage), nil,
 		); err != nil {
 			return err
 		}
@@ -69,7 +69,7 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 		log.Info("sending CPU alert resolved notification")
 		if err := sendMetricNotification(
 			ctx, true, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
-			"cpu", "", "", *config.CpuTriggerThreshold, usagePercent(currentMetrics.CPUUsage),
+			"cpu", "", "", *config.CpuTriggerThreshold, usagePercent(currentMetrics.CPUUsage), nil,
 		); err != nil {
 			return err
 		}
@@ -79,7 +79,7 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 		log.Info("sending memory alert notification")
 		if err := sendMetricNotification(
 			ctx, false, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
-			"memory", "", "", *config.MemoryTriggerThreshold, usagePercent(currentMetrics.MemoryUsage),
+			"memory", "", "", *config.MemoryTriggerThreshold, usagePercent(currentMetrics.MemoryUsage), nil,
 		); err != nil {
 			return err
 		}
@@ -87,7 +87,7 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 		log.Info("sending memory alert resolved notification")
 		if err := sendMetricNotification(
 			ctx, true, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
-			"memory", "", "", *config.MemoryTriggerThreshold, usagePercent(currentMetrics.MemoryUsage),
+			"memory", "", "", *config.MemoryTriggerThreshold, usagePercent(currentMetrics.MemoryUsage), nil,
 		); err != nil {
 			return err
 		}
@@ -111,7 +111,7 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 			if err := sendMetricNotification(
 				ctx, false, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
 				"disk", diskMetric.Device, diskMetric.Path, *config.DiskTriggerThreshold,
-				usagePercent(diskMetric.Usage()),
+				usagePercent(diskMetric.Usage()), nil,
 			); err != nil {
 				return err
 			}
@@ -120,16 +120,130 @@ func sendDeploymentTargetMetricsNotificationsWithConfig(
 			if err := sendMetricNotification(
 				ctx, true, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
 				"disk", diskMetric.Device, diskMetric.Path, *config.DiskTriggerThreshold,
-				usagePercent(diskMetric.Usage()),
+				usagePercent(diskMetric.Usage()), nil,
 			); err != nil {
 				return err
 			}
 		}
 	}
 
+	for _, containerMetric := range currentMetrics.ContainerMetrics {
+		previousContainerMetric := findPreviousContainerMetric(previousMetrics, containerMetric)
+		log := log.With(zap.Stringer("deploymentId", containerMetric.DeploymentID),
+			zap.String("container", containerMetric.Name))
+
+		if shouldNotifyResource(
+			config.ContainerCpuTriggerThreshold, previousContainerMetric, containerMetric, containerCPUUsage,
+		) {
+			log.Info("sending container CPU alert notification")
+			if err := sendMetricNotification(
+				ctx, false, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
+				"containerCpu", "", "", *config.ContainerCpuTriggerThreshold, usagePercent(containerMetric.CPUUsage),
+				&containerMetric,
+			); err != nil {
+				return err
+			}
+		} else if shouldNotifyResourceResolved(
+			config.ContainerCpuTriggerThreshold, previousContainerMetric, containerMetric, containerCPUUsage,
+		) {
+			log.Info("sending container CPU alert resolved notification")
+			if err := sendMetricNotification(
+				ctx, true, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
+				"containerCpu", "", "", *config.ContainerCpuTriggerThreshold, usagePercent(containerMetric.CPUUsage),
+				&containerMetric,
+			); err != nil {
+				return err
+			}
+		}
+
+		if shouldNotifyResource(
+			config.ContainerMemoryTriggerThreshold, previousContainerMetric, containerMetric, containerMemoryUsage,
+		) {
+			log.Info("sending container memory alert notification")
+			if err := sendMetricNotification(
+				ctx, false, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
+				"containerMemory", "", "", *config.ContainerMemoryTriggerThreshold,
+				usagePercent(containerMetric.MemoryUsage), &containerMetric,
+			); err != nil {
+				return err
+			}
+		} else if shouldNotifyResourceResolved(
+			config.ContainerMemoryTriggerThreshold, previousContainerMetric, containerMetric, containerMemoryUsage,
+		) {
+			log.Info("sending container memory alert resolved notification")
+			if err := sendMetricNotification(
+				ctx, true, deploymentTarget, *organization, config, previousMetrics, currentMetrics,
+				"containerMemory", "", "", *config.ContainerMemoryTriggerThreshold,
+				usagePercent(containerMetric.MemoryUsage), &containerMetric,
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// findPreviousContainerMetric returns the metric of the same container in the previous metrics. Containers are
+// identified by their deployment and name, so a pod that was replaced is treated as a new container.
+func findPreviousContainerMetric(
+	previousMetrics *types.DeploymentTargetMetrics,
+	containerMetric types.DeploymentContainerMetric,
+) *types.DeploymentContainerMetric {
+	if previousMetrics != nil {
+		for _, m := range previousMetrics.ContainerMetrics {
+			if m.DeploymentID == containerMetric.DeploymentID && m.Name == containerMetric.Name {
+				return &m
+			}
+		}
+	}
 	return nil
 }
 
+// metricsCrossThreshold mirrors the checks of sendDeploymentTargetMetricsNotificationsWithConfig.
+func metricsCrossThreshold(
+	config types.AlertConfiguration,
+	previousMetrics *types.DeploymentTargetMetrics,
+	currentMetrics types.DeploymentTargetMetrics,
+) bool {
+	if shouldNotifyResource(config.CpuTriggerThreshold, previousMetrics, currentMetrics, cpuUsage) ||
+		shouldNotifyResourceResolved(config.CpuTriggerThreshold, previousMetrics, currentMetrics, cpuUsage) ||
+		shouldNotifyResource(config.MemoryTriggerThreshold, previousMetrics, currentMetrics, memoryUsage) ||
+		shouldNotifyResourceResolved(config.MemoryTriggerThreshold, previousMetrics, currentMetrics, memoryUsage) {
+		return true
+	}
+	for _, diskMetric := range currentMetrics.DiskMetrics {
+		var previousDiskMetric *types.DeploymentTargetDiskMetric
+		if previousMetrics != nil {
+			for _, m := range previousMetrics.DiskMetrics {
+				if m.Device == diskMetric.Device && m.Path == diskMetric.Path {
+					previousDiskMetric = &m
+					break
+				}
+			}
+		}
+		if shouldNotifyResource(config.DiskTriggerThreshold, previousDiskMetric, diskMetric, diskUsage) ||
+			shouldNotifyResourceResolved(config.DiskTriggerThreshold, previousDiskMetric, diskMetric, diskUsage) {
+			return true
+		}
+	}
+	for _, containerMetric := range currentMetrics.ContainerMetrics {
+		previousContainerMetric := findPreviousContainerMetric(previousMetrics, containerMetric)
+		if shouldNotifyResource(
+			config.ContainerCpuTriggerThreshold, previousContainerMetric, containerMetric, containerCPUUsage,
+		) || shouldNotifyResourceResolved(
+			config.ContainerCpuTriggerThreshold, previousContainerMetric, containerMetric, containerCPUUsage,
+		) || shouldNotifyResource(
+			config.ContainerMemoryTriggerThreshold, previousContainerMetric, containerMetric, containerMemoryUsage,
+		) || shouldNotifyResourceResolved(
+			config.ContainerMemoryTriggerThreshold, previousContainerMetric, containerMetric, containerMemoryUsage,
+		) {
+			return true
+		}
+	}
+	return false
+}
+
 func sendMetricNotification(
 	ctx context.Context,
 	resolved bool,
@@ -143,19 +257,25 @@ func sendMetricNotification(
 	diskPath string,
 	threshold int,
 	usagePercent int64,
+	containerMetric *types.DeploymentContainerMetric,
 ) error {
+	var containerName string
+	if containerMetric != nil {
+		containerName = containerMetricDisplayName(deploymentTarget, *containerMetric)
+	}
+
 	var aggErr error
 	for _, user := range config.UserAccounts {
 		var err error
 		if resolved {
 			err = mailsending.DeploymentTargetMetricsNotificationResolved(
 				ctx, user, organization, deploymentTarget,
-				metricType, diskDevice, diskPath, threshold, usagePercent,
+				metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 			)
 		} else {
 			err = mailsending.DeploymentTargetMetricsNotificationAlert(
 				ctx, user, organization, deploymentTarget,
-				metricType, diskDevice, diskPath, threshold, usagePercent,
+				metricType, diskDevice, diskPath, containerName, threshold, usagePercent,
 			)
 		}
 		if err != nil {
@@ -189,6 +309,10 @@ func sendMetricNotification(
 	if diskPath != "" {
 		record.DiskPath = &diskPath
 	}
+	if containerMetric != nil {
+		record.DeploymentID = &containerMetric.DeploymentID
+		record.ContainerName = &containerMetric.Name
+	}
 	if previousMetrics != nil {
 		record.PreviousDeploymentTargetMetricsID = &previousMetrics.ID
 	}
@@ -233,6 +357,34 @@ func diskUsage(p *types.DeploymentTargetDiskMetric, c types.DeploymentTargetDisk
 	return usageFunc(p, c, func(m types.DeploymentTargetDiskMetric) float64 { return m.Usage() })
 }
 
+func containerCPUUsage(
+	p *types.DeploymentContainerMetric,
+	c types.DeploymentContainerMetric,
+) (*float64, float64) {
+	return usageFunc(p, c, func(m types.DeploymentContainerMetric) float64 { return m.CPUUsage })
+}
+
+func containerMemoryUsage(
+	p *types.DeploymentContainerMetric,
+	c types.DeploymentContainerMetric,
+) (*float64, float64) {
+	return usageFunc(p, c, func(m types.DeploymentContainerMetric) float64 { return m.MemoryUsage })
+}
+
+// containerMetricDisplayName prefixes the container name with the application of its deployment, because container
+// names are only unique within a deployment.
+func containerMetricDisplayName(
+	deploymentTarget types.DeploymentTargetFull,
+	containerMetric types.DeploymentContainerMetric,
+) string {
+	for _, deployment := range deploymentTarget.Deployments {
+		if deployment.ID == containerMetric.DeploymentID {
+			return deployment.Application.Name + " / " + containerMetric.Name
+		}
+	}
+	return containerMetric.Name
+}
+
 func usageFunc[T any](p *T, c T, f func(T) float64) (*float64, float64) {
 	if p != nil {
 		return new(f(*p)), f(c)
@@ -0,0 +1,51 @@
+package notification
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestMetricsCrossThreshold_Containers(t *testing.T) {
+	g := NewWithT(t)
+	deploymentID := uuid.New()
+	config := types.AlertConfiguration{ContainerMemoryTriggerThreshold: new(80)}
+	metrics := func(name string, memoryUsage float64) types.DeploymentTargetMetrics {
+		return types.DeploymentTargetMetrics{
+			ContainerMetrics: []types.DeploymentContainerMetric{
+				{DeploymentID: deploymentID, Name: name, MemoryUsage: memoryUsage, CPUUsage: 1},
+			},
+		}
+	}
+
+	// the CPU of the container is not checked by this configuration
+	g.Expect(metricsCrossThreshold(config, nil, metrics("web", 0.5))).To(BeFalse())
+	g.Expect(metricsCrossThreshold(config, nil, metrics("web", 0.9))).To(BeTrue())
+	g.Expect(metricsCrossThreshold(config, new(metrics("web", 0.5)), metrics("web", 0.9))).To(BeTrue())
+	g.Expect(metricsCrossThreshold(config, new(metrics("web", 0.9)), metrics("web", 0.95))).To(BeFalse())
+	g.Expect(metricsCrossThreshold(config, new(metrics("web", 0.9)), metrics("web", 0.5))).To(BeTrue())
+	// a replaced container is compared to nothing
+	g.Expect(metricsCrossThreshold(config, new(metrics("web-1", 0.9)), metrics("web-2", 0.95))).To(BeTrue())
+}
+
+func TestFindPreviousContainerMetric(t *testing.T) {
+	g := NewWithT(t)
+	deploymentID := uuid.New()
+	previous := types.DeploymentTargetMetrics{
+		ContainerMetrics: []types.DeploymentContainerMetric{
+			{DeploymentID: uuid.New(), Name: "web", Restarts: 1},
+			{DeploymentID: deploymentID, Name: "web", Restarts: 2},
+		},
+	}
+
+	web := types.DeploymentContainerMetric{DeploymentID: deploymentID, Name: "web"}
+	db := types.DeploymentContainerMetric{DeploymentID: deploymentID, Name: "db"}
+
+	g.Expect(findPreviousContainerMetric(nil, web)).To(BeNil())
+	result := findPreviousContainerMetric(&previous, web)
+	g.Expect(result).NotTo(BeNil())
+	g.Expect(result.Restarts).To(Equal(int64(2)))
+	g.Expect(findPreviousContainerMetric(&previous, db)).To(BeNil())
+}
@@ -0,0 +1,163 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/mailsending"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/util"
+	"go.uber.org/zap"
+)
+
+func RunLicenseKeyExpirationNotifications(ctx context.Context) error {
+	log := internalctx.GetLogger(ctx)
+
+	log.Info("sending license key expiration notifications for all organizations")
+
+	orgs, err := db.GetOrganizationsWithLicenseKeyExpirationReminders(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get organizations: %w", err)
+	}
+
+	for _, org := range orgs {
+		log := log.With(zap.Stringer("organizationId", org.ID))
+		ctx := internalctx.WithLogger(ctx, log)
+		if err := sendLicenseKeyExpirationNotificationsForOrganization(ctx, org, time.Now()); err != nil {
+			// an error for one organization must not prevent the notifications of the others
+			log.Error("failed to send license key expiration notifications", zap.Error(err))
+		}
+	}
+
+	log.Info("license key expiration notifications sent")
+
+	return nil
+}
+
+func sendLicenseKeyExpirationNotificationsForOrganization(
+	ctx context.Context,
+	org types.Organization,
+	now time.Time,
+) error {
+	log := internalctx.GetLogger(ctx)
+
+	maxDays := slices.Max(org.LicenseKeyExpirationReminderDays)
+	licenseKeys, err := db.GetLicenseKeysExpiringBefore(ctx, org.ID, now.AddDate(0, 0, maxDays))
+	if err != nil {
+		return fmt.Errorf("failed to get expiring license keys: %w", err)
+	} else if len(licenseKeys) == 0 {
+		log.Debug("no expiring license keys")
+		return nil
+	}
+
+	users, err := db.GetUserAccountsByOrgID(ctx, org.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get user accounts: %w", err)
+	}
+
+	for _, licenseKey := range licenseKeys {
+		log := log.With(zap.Stringer("licenseKeyId", licenseKey.ID))
+		ctx := internalctx.WithLogger(ctx, log)
+
+		daysLeft := licenseKeyDaysLeft(*licenseKey.ExpiresAt, now)
+		reminderDays, ok := dueLicenseKeyExpirationReminder(org.LicenseKeyExpirationReminderDays, daysLeft)
+		if !ok {
+			continue
+		}
+
+		recordID, err := db.TryClaimLicenseKeyExpirationNotification(
+			ctx, licenseKey.ID, *licenseKey.ExpiresAt, reminderDays)
+		if err != nil {
+			return err
+		} else if recordID == nil {
+			log.Debug("skip license key expiration notification because it was already sent",
+				zap.Int("reminderDays", reminderDays))
+			continue
+		}
+
+		if err := sendLicenseKeyExpirationNotifications(ctx, licenseKey, daysLeft, users); err != nil {
+			log.Warn("license key expiration notification sending failed", zap.Error(err))
+			if err := db.DeleteLicenseKeyExpirationNotificationRecord(ctx, *recordID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sendLicenseKeyExpirationNotifications notifies the vendor admins and the admins of the customer organization
+// the license key is assigned to. An error is only returned if no email could be sent at all, so that the
+// reminder is retried in the next run without notifying any recipient twice.
+func sendLicenseKeyExpirationNotifications(
+	ctx context.Context,
+	licenseKey types.LicenseKey,
+	daysLeft int,
+	users []types.UserAccountWithUserRole,
+) error {
+	log := internalctx.GetLogger(ctx)
+
+	var customerOrgName string
+	if licenseKey.CustomerOrganizationID != nil {
+		customerOrg, err := db.GetCustomerOrganizationByID(ctx, *licenseKey.CustomerOrganizationID)
+		if err != nil {
+			return fmt.Errorf("failed to get customer organization: %w", err)
+		}
+		customerOrgName = customerOrg.Name
+	}
+
+	var sent int
+	var aggErr error
+	for _, u := range users {
+		if u.UserRole != types.UserRoleAdmin || !u.EmailVerified {
+			continue
+		}
+
+		var err error
+		if u.CustomerOrganizationID == nil {
+			err = mailsending.SendLicenseKeyExpiringVendor(ctx, u.AsUserAccount(), licenseKey, daysLeft, customerOrgName)
+		} else if util.PtrEq(u.CustomerOrganizationID, licenseKey.CustomerOrganizationID) {
+			err = mailsending.SendLicenseKeyExpiringCustomer(ctx, u.AsUserAccount(), licenseKey, daysLeft)
+		} else {
+			continue
+		}
+
+		if err != nil {
+			log.Warn("failed to send license key expiring mail", zap.Error(err), zap.String("email", u.Email))
+			aggErr = errors.Join(aggErr, err)
+		} else {
+			sent++
+		}
+	}
+
+	if sent == 0 {
+		return aggErr
+	}
+	return nil
+}
+
+// licenseKeyDaysLeft returns the number of started days until the license key expires.
+func licenseKeyDaysLeft(expiresAt, now time.Time) int {
+	return int(math.Ceil(expiresAt.Sub(now).Hours() / 24))
+}
+
+// dueLicenseKeyExpirationReminder returns the smallest configured reminder offset that has been reached.
+// Only this reminder is sent, so that a license key which is already close to its expiry when reminders are
+// enabled does not trigger all of the earlier reminders at once.
+func dueLicenseKeyExpirationReminder(reminderDays []int, daysLeft int) (int, bool) {
+	var result int
+	var found bool
+	for _, days := range reminderDays {
+		if days >= daysLeft && (!found || days < result) {
+			result = days
+			found = true
+		}
+	}
+	return result, found
+}
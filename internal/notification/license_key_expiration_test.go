@@ -0,0 +1,41 @@
+package notification
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDueLicenseKeyExpirationReminder(t *testing.T) {
+	g := NewWithT(t)
+	reminderDays := []int{60, 7, 30}
+
+	_, ok := dueLicenseKeyExpirationReminder(reminderDays, 61)
+	g.Expect(ok).To(BeFalse())
+
+	days, ok := dueLicenseKeyExpirationReminder(reminderDays, 60)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(days).To(Equal(60))
+
+	// A key that is already close to its expiry only gets the closest reminder.
+	days, ok = dueLicenseKeyExpirationReminder(reminderDays, 10)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(days).To(Equal(30))
+
+	days, ok = dueLicenseKeyExpirationReminder(reminderDays, 1)
+	g.Expect(ok).To(BeTrue())
+	g.Expect(days).To(Equal(7))
+
+	_, ok = dueLicenseKeyExpirationReminder(nil, 1)
+	g.Expect(ok).To(BeFalse())
+}
+
+func TestLicenseKeyDaysLeft(t *testing.T) {
+	g := NewWithT(t)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	g.Expect(licenseKeyDaysLeft(now.Add(7*24*time.Hour), now)).To(Equal(7))
+	g.Expect(licenseKeyDaysLeft(now.Add(6*24*time.Hour+time.Minute), now)).To(Equal(7))
+	g.Expect(licenseKeyDaysLeft(now.Add(time.Hour), now)).To(Equal(1))
+}
@@ -0,0 +1,92 @@
+package notification
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+const (
+	DeploymentStatusTaskType        = "DeploymentStatusNotification"
+	DeploymentTargetMetricsTaskType = "DeploymentTargetMetricsNotification"
+)
+
+// DeploymentStatusTask carries the statuses themselves, because the previous status is only the latest one at the
+// time the new status is reported.
+type DeploymentStatusTask struct {
+	DeploymentTargetID uuid.UUID                       `json:"deploymentTargetId"`
+	DeploymentID       uuid.UUID                       `json:"deploymentId"`
+	PreviousStatus     *types.DeploymentRevisionStatus `json:"previousStatus,omitempty"`
+	CurrentStatus      types.DeploymentRevisionStatus  `json:"currentStatus"`
+}
+
+type DeploymentTargetMetricsTask struct {
+	DeploymentTargetID uuid.UUID                      `json:"deploymentTargetId"`
+	PreviousMetrics    *types.DeploymentTargetMetrics `json:"previousMetrics,omitempty"`
+	CurrentMetrics     types.DeploymentTargetMetrics  `json:"currentMetrics"`
+}
+
+func RunDeploymentStatusTask(ctx context.Context, task DeploymentStatusTask) (any, error) {
+	deploymentTarget, err := db.GetDeploymentTarget(ctx, task.DeploymentTargetID, nil, nil)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		// deleted in the meantime, so there is nobody left to notify
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	i := slices.IndexFunc(deploymentTarget.Deployments, func(d types.DeploymentWithLatestRevision) bool {
+		return d.ID == task.DeploymentID
+	})
+	if i < 0 {
+		return nil, nil
+	}
+	return nil, SendDeploymentStatusNotifications(
+		ctx, *deploymentTarget, deploymentTarget.Deployments[i], task.PreviousStatus, task.CurrentStatus,
+	)
+}
+
+func RunDeploymentTargetMetricsTask(ctx context.Context, task DeploymentTargetMetricsTask) (any, error) {
+	deploymentTarget, err := db.GetDeploymentTarget(ctx, task.DeploymentTargetID, nil, nil)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return nil, SendDeploymentTargetMetricsNotifications(
+		ctx, *deploymentTarget, task.PreviousMetrics, task.CurrentMetrics,
+	)
+}
+
+// ShouldNotifyDeploymentStatus allows to skip enqueueing a DeploymentStatusTask for most status reports of agents.
+func ShouldNotifyDeploymentStatus(
+	previousStatus *types.DeploymentRevisionStatus,
+	currentStatus types.DeploymentRevisionStatus,
+) bool {
+	return shouldNotify(previousStatus, currentStatus)
+}
+
+// ShouldNotifyDeploymentTargetMetrics reports whether the metrics crossed a threshold of an alert configuration of
+// the deployment target in either direction, which is rare compared to how often agents report metrics.
+func ShouldNotifyDeploymentTargetMetrics(
+	ctx context.Context,
+	deploymentTarget types.DeploymentTargetFull,
+	previousMetrics *types.DeploymentTargetMetrics,
+	currentMetrics types.DeploymentTargetMetrics,
+) (bool, error) {
+	if !deploymentTarget.MetricsEnabled {
+		return false, nil
+	}
+	configs, err := db.GetAlertConfigurationsForDeploymentTarget(ctx, deploymentTarget.ID)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(configs, func(config types.AlertConfiguration) bool {
+		return config.Enabled && config.AnyThresholdEnabled() &&
+			metricsCrossThreshold(config, previousMetrics, currentMetrics)
+	}), nil
+}
@@ -62,6 +62,10 @@ func Discover(ctx context.Context, issuerURL string) (*DiscoveryResult, error) {
 	if err != nil {
 		return nil, err
 	}
+	if document.AuthURL == "" || document.TokenURL == "" {
+		return nil, fmt.Errorf("the OpenID configuration of %v does not state an authorization and token endpoint",
+			document.IssuerURL)
+	}
 
 	return &DiscoveryResult{
 		Issuer:        document.IssuerURL,
@@ -140,8 +144,8 @@ func fetchDiscoveryDocument(ctx context.Context, issuerURL *url.URL) (*discovery
 	if document.IssuerURL == "" {
 		return nil, fmt.Errorf("%v does not state an issuer", discoveryURL)
 	}
-	if document.AuthURL == "" || document.TokenURL == "" || document.JWKSURL == "" {
-		return nil, fmt.Errorf("%v does not state an authorization, token and jwks endpoint", discoveryURL)
+	if document.JWKSURL == "" {
+		return nil, fmt.Errorf("%v does not state a jwks endpoint", discoveryURL)
 	}
 
 	canonical, err := ParseIssuerURL(document.IssuerURL)
@@ -0,0 +1,84 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+)
+
+// workloadVerifierTTL is how long the discovery of a workload identity issuer is reused. The keys themselves are
+// refreshed by the key set whenever a token is signed with an unknown one.
+const workloadVerifierTTL = time.Hour
+
+type workloadVerifierKey struct{ issuer, audience string }
+
+type workloadVerifier struct {
+	verifier     *oidc.IDTokenVerifier
+	discoveredAt time.Time
+}
+
+var (
+	workloadVerifiersMu sync.Mutex
+	workloadVerifiers   = map[workloadVerifierKey]workloadVerifier{}
+)
+
+// DiscoverWorkloadIssuer checks that ID tokens of the issuer can be verified. Unlike Discover, it only needs the
+// keys of the issuer, because workload identity issuers like GitHub Actions do not offer the endpoints of the
+// authorization code flow.
+func DiscoverWorkloadIssuer(ctx context.Context, issuerURL, audience string) error {
+	_, err := workloadIssuerVerifier(ctx, issuerURL, audience)
+	return err
+}
+
+// VerifyWorkloadIDToken verifies the signature, issuer, audience and expiry of an ID token that the issuer handed
+// out to a workload, like the job of a CI system, and returns its claims.
+func VerifyWorkloadIDToken(ctx context.Context, issuerURL, audience, rawIDToken string) (map[string]any, error) {
+	verifier, err := workloadIssuerVerifier(ctx, issuerURL, audience)
+	if err != nil {
+		return nil, err
+	}
+	token, err := verifier.Verify(RestrictedClientContext(ctx), rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("ID token verification failed: %w", err)
+	}
+	var claims map[string]any
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("could not parse ID token claims: %w", err)
+	}
+	return claims, nil
+}
+
+func workloadIssuerVerifier(ctx context.Context, issuerURL, audience string) (*oidc.IDTokenVerifier, error) {
+	key := workloadVerifierKey{issuerURL, audience}
+	workloadVerifiersMu.Lock()
+	cached, ok := workloadVerifiers[key]
+	workloadVerifiersMu.Unlock()
+	if ok && time.Since(cached.discoveredAt) < workloadVerifierTTL {
+		return cached.verifier, nil
+	}
+
+	parsed, err := ParseIssuerURL(issuerURL)
+	if err != nil {
+		return nil, err
+	}
+	discoveryCtx, cancel := context.WithTimeout(RestrictedClientContext(ctx), discoveryTimeout)
+	defer cancel()
+	document, err := fetchDiscoveryDocument(discoveryCtx, parsed)
+	if err != nil {
+		return nil, err
+	}
+
+	// The key set outlives this request, so it must not inherit its deadline.
+	keySet := oidc.NewRemoteKeySet(RestrictedClientContext(context.WithoutCancel(ctx)), document.JWKSURL)
+	verifier := oidc.NewVerifier(document.IssuerURL, keySet, &oidc.Config{
+		ClientID:             audience,
+		SupportedSigningAlgs: document.Algorithms,
+	})
+	workloadVerifiersMu.Lock()
+	workloadVerifiers[key] = workloadVerifier{verifier: verifier, discoveredAt: time.Now()}
+	workloadVerifiersMu.Unlock()
+	return verifier, nil
+}
@@ -0,0 +1,69 @@
+package orgtransfer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"golang.org/x/crypto/argon2"
+)
+
+// MinKeyLength is the minimum length of the key that the secrets of an archive are encrypted with.
+const MinKeyLength = 16
+
+// keyCheck is encrypted into the manifest, so that a wrong key is detected before anything is imported.
+const keyCheck = "distr"
+
+var ErrInvalidKey = apierrors.NewBadRequest("the key does not match the archive")
+
+// sealer encrypts secret values with AES-GCM and a key derived from the key supplied by the user and a random
+// salt, which is stored in the manifest.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(key string, salt []byte) (*sealer, error) {
+	if len(key) < MinKeyLength {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("the key must be at least %v characters long", MinKeyLength))
+	}
+	block, err := aes.NewCipher(argon2.IDKey([]byte(key), salt, 1, 64*1024, 4, 32))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead}, nil
+}
+
+func newSalt() []byte {
+	salt := make([]byte, 16)
+	_, _ = rand.Read(salt)
+	return salt
+}
+
+func (s *sealer) seal(plaintext string) string {
+	nonce := make([]byte, s.aead.NonceSize())
+	_, _ = rand.Read(nonce)
+	return base64.StdEncoding.EncodeToString(s.aead.Seal(nonce, nonce, []byte(plaintext), nil))
+}
+
+func (s *sealer) open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", ErrInvalidKey
+	} else if len(data) < s.aead.NonceSize() {
+		return "", ErrInvalidKey
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	if plaintext, err := s.aead.Open(nil, nonce, ciphertext, nil); err != nil {
+		return "", errors.Join(ErrInvalidKey, err)
+	} else {
+		return string(plaintext), nil
+	}
+}
@@ -0,0 +1,181 @@
+package orgtransfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/buildconfig"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	blobs3 "github.com/distr-sh/distr/internal/registry/blob/s3"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/opencontainers/go-digest"
+	"go.uber.org/zap"
+)
+
+// Export writes the archive of an organization to w, with its secret values encrypted with key. The database is read
+// in a single transaction before anything is written, so that errors can still be reported to the caller unless
+// reading a registry blob fails.
+func Export(ctx context.Context, orgID uuid.UUID, key string, w io.Writer) error {
+	log := internalctx.GetLogger(ctx)
+	manifest := Manifest{
+		FormatVersion:  FormatVersion,
+		DistrVersion:   buildconfig.Version(),
+		ExportedAt:     time.Now().UTC(),
+		OrganizationID: orgID,
+		KeySalt:        newSalt(),
+		AgentVersions:  map[uuid.UUID]string{},
+	}
+	s, err := newSealer(key, manifest.KeySalt)
+	if err != nil {
+		return err
+	}
+	manifest.KeyCheck = s.seal(keyCheck)
+
+	var organization map[string]any
+	var digests []string
+	tables := map[string][]json.RawMessage{}
+	err = db.RunTxRR(ctx, func(ctx context.Context) (err error) {
+		if manifest.SchemaVersion, err = db.GetSchemaVersion(ctx); err != nil {
+			return err
+		}
+		if row, err := db.GetOrganizationTransferRow(ctx, orgID); errors.Is(err, pgx.ErrNoRows) {
+			return apierrors.ErrNotFound
+		} else if err != nil {
+			return err
+		} else if organization, err = exportOrganization(row); err != nil {
+			return err
+		}
+		manifest.OrganizationName, _ = organization["name"].(string)
+		if agentVersions, err := db.GetAgentVersions(ctx); err != nil {
+			return err
+		} else {
+			for _, av := range agentVersions {
+				manifest.AgentVersions[av.ID] = av.Name
+			}
+		}
+		for _, table := range db.OrganizationTransferTables() {
+			rows, err := db.GetOrganizationTransferRows(ctx, orgID, table)
+			if err != nil {
+				return err
+			}
+			if columns := secretColumns[table]; len(columns) > 0 {
+				if rows, err = sealColumns(rows, columns, s); err != nil {
+					return err
+				}
+			}
+			tables[table] = rows
+			manifest.Tables = append(manifest.Tables, Table{Name: table, Rows: len(rows)})
+		}
+		digests, err = db.GetOrganizationBlobDigests(ctx, orgID)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	var blobs blob.BlobHandler
+	sizes := map[string]int64{}
+	if len(digests) > 0 {
+		if !env.RegistryEnabled() {
+			return errors.New("the organization has artifacts, but the registry is not enabled")
+		} else if blobs, err = blobs3.NewBlobHandler(ctx, internalctx.GetS3Client(ctx)); err != nil {
+			return err
+		}
+		for _, d := range digests {
+			if size, err := blobs.(blob.BlobStatHandler).Stat(ctx, "", digest.Digest(d)); errors.Is(err, blob.ErrNotFound) {
+				log.Warn("registry blob not found, it is not exported", zap.String("digest", d))
+			} else if err != nil {
+				return fmt.Errorf("failed to stat blob %v: %w", d, err)
+			} else {
+				sizes[d] = size
+				manifest.Blobs = append(manifest.Blobs, d)
+			}
+		}
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	if err := writeJSON(tw, manifestFileName, manifest); err != nil {
+		return err
+	} else if err := writeJSON(tw, organizationFileName, organization); err != nil {
+		return err
+	}
+	for _, table := range manifest.Tables {
+		if err := writeJSON(tw, tablesDir+table.Name+".json", tables[table.Name]); err != nil {
+			return err
+		}
+	}
+	for _, d := range manifest.Blobs {
+		if err := writeBlob(ctx, tw, blobs, d, sizes[d]); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// exportOrganization strips the organization row down to its ID and organizationColumns.
+func exportOrganization(row json.RawMessage) (map[string]any, error) {
+	fields, err := decodeRow(bytes.NewReader(row))
+	if err != nil {
+		return nil, err
+	}
+	result := map[string]any{"id": fields["id"]}
+	for _, column := range organizationColumns {
+		if value, ok := fields[column]; ok {
+			result[column] = value
+		}
+	}
+	return result, nil
+}
+
+func sealColumns(rows []json.RawMessage, columns []string, s *sealer) ([]json.RawMessage, error) {
+	return transformRows(rows, func(fields map[string]any) error {
+		for _, column := range columns {
+			if value, ok := fields[column].(string); ok {
+				fields[column] = s.seal(value)
+			}
+		}
+		return nil
+	})
+}
+
+func writeJSON(tw *tar.Writer, name string, value any) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o600, Size: int64(len(data))}); err != nil {
+		return err
+	}
+	_, err = tw.Write(data)
+	return err
+}
+
+func writeBlob(ctx context.Context, tw *tar.Writer, blobs blob.BlobHandler, d string, size int64) error {
+	rc, err := blobs.Get(ctx, "", digest.Digest(d), false)
+	if err != nil {
+		return fmt.Errorf("failed to get blob %v: %w", d, err)
+	}
+	defer rc.Close()
+	if err := tw.WriteHeader(&tar.Header{Name: blobsDir + d, Mode: 0o600, Size: size}); err != nil {
+		return err
+	} else if _, err := io.Copy(tw, rc); err != nil {
+		return fmt.Errorf("failed to copy blob %v: %w", d, err)
+	}
+	return nil
+}
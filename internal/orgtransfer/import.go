@@ -0,0 +1,463 @@
+package orgtransfer
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	internalctx "github.com/distr-sh/distr/internal/context"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/registry/blob"
+	blobs3 "github.com/distr-sh/distr/internal/registry/blob/s3"
+	"github.com/distr-sh/distr/internal/subscription"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/glasskube/pkg/seekbuf"
+	"github.com/google/uuid"
+	"github.com/opencontainers/go-digest"
+	"go.uber.org/zap"
+)
+
+type ImportOptions struct {
+	// Key is the key that the secrets of the archive were encrypted with.
+	Key string
+	// OwnerID is the user who becomes the admin of the new organization.
+	OwnerID uuid.UUID
+	// Name overrides the name of the organization in the archive.
+	Name string
+	// DryRun reads and checks the whole archive and reports its conflicts, but changes nothing.
+	DryRun bool
+}
+
+var errDryRun = errors.New("dry run")
+
+type importer struct {
+	opts          ImportOptions
+	manifest      Manifest
+	sealer        *sealer
+	organization  map[string]any
+	tables        map[string][]map[string]any
+	blobs         blob.BlobHandler
+	ids           map[string]string
+	agentVersions map[string]string
+	result        ImportResult
+}
+
+// Import creates a new organization from an archive written by Export. All IDs are replaced by new ones, so an
+// archive can be imported more than once. Registry blobs are uploaded while the archive is read, the database is
+// only changed after the whole archive was read and in a single transaction.
+//
+// Values that have to be unique across the instance, like the slug of the organization or the domains of its
+// branding, are not imported if they are taken, and neither are references to users of the exporting instance.
+// Both are reported as conflicts.
+func Import(ctx context.Context, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	imp := importer{
+		opts:          opts,
+		tables:        map[string][]map[string]any{},
+		ids:           map[string]string{},
+		agentVersions: map[string]string{},
+		result:        ImportResult{DryRun: opts.DryRun},
+	}
+	if err := imp.read(ctx, r); err != nil {
+		return nil, err
+	}
+
+	err := db.RunTx(ctx, func(ctx context.Context) error {
+		if reached, err := subscription.IsGlobalOrganizationLimitReached(ctx); err != nil {
+			return err
+		} else if reached {
+			return subscription.ErrGlobalOrganizationLimitReached
+		}
+		if err := imp.createOrganization(ctx); err != nil {
+			return err
+		}
+		for table := range imp.tables {
+			if !slices.Contains(db.OrganizationTransferTables(), table) {
+				imp.conflict(table, "", "", "not imported, the table does not exist")
+			}
+		}
+		for _, table := range db.OrganizationTransferTables() {
+			if rows, ok := imp.tables[table]; ok {
+				if err := imp.importTable(ctx, table, rows); err != nil {
+					return err
+				}
+			}
+		}
+		if opts.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errDryRun) {
+		return nil, err
+	}
+	return &imp.result, nil
+}
+
+func (imp *importer) read(ctx context.Context, r io.Reader) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return apierrors.NewBadRequest("the archive is not gzip compressed")
+	}
+	tr := tar.NewReader(gr)
+
+	if header, err := tr.Next(); err != nil || header.Name != manifestFileName {
+		return apierrors.NewBadRequest("the archive does not start with " + manifestFileName)
+	} else if err := json.NewDecoder(tr).Decode(&imp.manifest); err != nil {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid %v: %v", manifestFileName, err))
+	} else if err := imp.checkManifest(ctx); err != nil {
+		return err
+	}
+
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return apierrors.NewBadRequest(fmt.Sprintf("invalid archive: %v", err))
+		}
+		switch name := header.Name; {
+		case name == organizationFileName:
+			if imp.organization, err = decodeRow(tr); err != nil {
+				return apierrors.NewBadRequest(fmt.Sprintf("invalid %v: %v", name, err))
+			}
+		case strings.HasPrefix(name, tablesDir):
+			table := strings.TrimSuffix(strings.TrimPrefix(name, tablesDir), ".json")
+			if imp.tables[table], err = decodeRows(tr); err != nil {
+				return apierrors.NewBadRequest(fmt.Sprintf("invalid %v: %v", name, err))
+			}
+		case strings.HasPrefix(name, blobsDir):
+			if err := imp.importBlob(ctx, tr, strings.TrimPrefix(name, blobsDir)); err != nil {
+				return err
+			}
+		default:
+			return apierrors.NewBadRequest(fmt.Sprintf("unexpected file in archive: %v", name))
+		}
+	}
+
+	if imp.organization == nil {
+		return apierrors.NewBadRequest("the archive does not contain " + organizationFileName)
+	}
+	for _, table := range imp.manifest.Tables {
+		if len(imp.tables[table.Name]) != table.Rows {
+			return apierrors.NewBadRequest(fmt.Sprintf("the archive is incomplete, table %v is missing rows", table.Name))
+		}
+	}
+	if imp.result.Blobs != len(imp.manifest.Blobs) {
+		return apierrors.NewBadRequest("the archive is incomplete, registry blobs are missing")
+	}
+
+	if id, ok := imp.organization["id"].(string); ok {
+		imp.ids[id] = ""
+	}
+	for _, rows := range imp.tables {
+		for _, row := range rows {
+			if id, ok := row["id"].(string); ok {
+				imp.ids[id] = uuid.NewString()
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *importer) checkManifest(ctx context.Context) error {
+	if imp.manifest.FormatVersion != FormatVersion {
+		return apierrors.NewBadRequest(fmt.Sprintf("unsupported archive format version %v", imp.manifest.FormatVersion))
+	}
+	if schemaVersion, err := db.GetSchemaVersion(ctx); err != nil {
+		return err
+	} else if imp.manifest.SchemaVersion > schemaVersion {
+		return apierrors.NewBadRequest(fmt.Sprintf(
+			"the archive was exported by a newer version of Distr (%v), upgrade this instance first",
+			imp.manifest.DistrVersion,
+		))
+	}
+	var err error
+	if imp.sealer, err = newSealer(imp.opts.Key, imp.manifest.KeySalt); err != nil {
+		return err
+	} else if check, err := imp.sealer.open(imp.manifest.KeyCheck); err != nil || check != keyCheck {
+		return ErrInvalidKey
+	}
+	return nil
+}
+
+// importBlob verifies a registry blob against its digest and uploads it, unless the registry has it already.
+func (imp *importer) importBlob(ctx context.Context, r io.Reader, name string) error {
+	d, err := digest.Parse(name)
+	if err != nil || !slices.Contains(imp.manifest.Blobs, name) {
+		return apierrors.NewBadRequest(fmt.Sprintf("unexpected blob in archive: %v", name))
+	}
+	verifier := d.Verifier()
+
+	if imp.opts.DryRun {
+		if _, err := io.Copy(verifier, r); err != nil {
+			return err
+		}
+	} else {
+		if imp.blobs == nil {
+			if !env.RegistryEnabled() {
+				return apierrors.NewBadRequest("the archive contains artifacts, but the registry is not enabled")
+			} else if imp.blobs, err = blobs3.NewBlobHandler(ctx, internalctx.GetS3Client(ctx)); err != nil {
+				return err
+			}
+		}
+		buf, err := seekbuf.New(io.TeeReader(r, verifier))
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := buf.Destroy(); err != nil {
+				internalctx.GetLogger(ctx).Warn("ephemeral resource cleanup error", zap.Error(err))
+			}
+		}()
+		if !verifier.Verified() {
+			return apierrors.NewBadRequest(fmt.Sprintf("blob %v does not match its digest", d))
+		}
+		if _, err := imp.blobs.(blob.BlobStatHandler).Stat(ctx, "", d); errors.Is(err, blob.ErrNotFound) {
+			if rc, err := buf.Get(); err != nil {
+				return err
+			} else if err := imp.blobs.(blob.BlobPutHandler).Put(ctx, "", d, "", rc); err != nil {
+				return fmt.Errorf("failed to upload blob %v: %w", d, err)
+			}
+		} else if err != nil {
+			return fmt.Errorf("failed to stat blob %v: %w", d, err)
+		}
+	}
+
+	if !verifier.Verified() {
+		return apierrors.NewBadRequest(fmt.Sprintf("blob %v does not match its digest", d))
+	}
+	imp.result.Blobs++
+	return nil
+}
+
+// createOrganization creates the organization with the subscription that new organizations get on this instance,
+// and makes the owner its admin. Features that are not granted by a subscription plan are taken over.
+func (imp *importer) createOrganization(ctx context.Context) error {
+	org := types.Organization{Name: imp.opts.Name}
+	if org.Name == "" {
+		org.Name, _ = imp.organization["name"].(string)
+	}
+	if org.Name == "" {
+		return apierrors.NewBadRequest("name is required")
+	}
+	if slug, ok := imp.organization["slug"].(string); ok && slug != "" {
+		if taken, err := db.IsOrganizationSlugTaken(ctx, slug); err != nil {
+			return err
+		} else if taken {
+			imp.conflict("Organization", "slug", slug, "not set, the slug is taken")
+		} else {
+			org.Slug = &slug
+		}
+	}
+	if err := db.CreateOrganization(ctx, &org); err != nil {
+		return err
+	}
+
+	features, _ := imp.organization["features"].([]any)
+	for _, value := range features {
+		feature := types.Feature(fmt.Sprint(value))
+		if !slices.Contains(types.AllFeatures(), feature) {
+			imp.conflict("Organization", "features", string(feature), "not granted, the feature does not exist")
+		} else if !slices.Contains(types.PlanManagedFeatures, feature) {
+			org.AddFeatures(feature)
+		}
+	}
+	if err := db.UpdateOrganization(ctx, &org); err != nil {
+		return err
+	}
+
+	if columns, err := db.GetTableColumns(ctx, "Organization"); err != nil {
+		return err
+	} else {
+		var settings []string
+		for _, column := range columns {
+			if _, ok := imp.organization[column.Name]; ok && slices.Contains(organizationColumns, column.Name) &&
+				!slices.Contains([]string{"name", "slug", "features"}, column.Name) {
+				settings = append(settings, column.Name)
+			}
+		}
+		if data, err := json.Marshal(imp.organization); err != nil {
+			return err
+		} else if err := db.UpdateOrganizationFromTransferRow(ctx, org.ID, settings, data); err != nil {
+			return err
+		}
+	}
+
+	if err := db.CreateUserAccountOrganizationAssignment(
+		ctx, imp.opts.OwnerID, org.ID, types.UserRoleAdmin, nil, nil); err != nil {
+		return err
+	}
+
+	if id, ok := imp.organization["id"].(string); ok {
+		imp.ids[id] = org.ID.String()
+	}
+	imp.result.OrganizationID = org.ID
+	return nil
+}
+
+func (imp *importer) importTable(ctx context.Context, table string, rows []map[string]any) error {
+	columns, err := db.GetTableColumns(ctx, table)
+	if err != nil {
+		return err
+	}
+	var insertColumns []string
+	data := make([]json.RawMessage, len(rows))
+	for i, row := range rows {
+		for _, column := range columns {
+			if value, ok := row[column.Name]; ok && !column.Generated {
+				if !slices.Contains(insertColumns, column.Name) {
+					insertColumns = append(insertColumns, column.Name)
+				}
+				if row[column.Name], err = imp.resolve(ctx, table, column, value); err != nil {
+					return err
+				}
+			}
+		}
+		for _, column := range secretColumns[table] {
+			if value, ok := row[column].(string); ok {
+				if row[column], err = imp.sealer.open(value); err != nil {
+					return err
+				}
+			}
+		}
+		if table == "OrganizationBranding" {
+			if err := imp.resolveBrandingDomains(ctx, row); err != nil {
+				return err
+			}
+		}
+		if data[i], err = json.Marshal(row); err != nil {
+			return err
+		}
+	}
+
+	imp.result.Tables = append(imp.result.Tables, Table{Name: table, Rows: len(rows)})
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.InsertOrganizationTransferRows(ctx, table, insertColumns, data)
+}
+
+// resolve returns the value of a column on this instance: IDs are replaced by their new ones and references to
+// users of the exporting instance are removed or, if required, replaced by the owner.
+func (imp *importer) resolve(ctx context.Context, table string, column types.TableColumn, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	if column.ReferencedTable == "useraccount" {
+		if column.Nullable {
+			return nil, nil
+		}
+		return imp.opts.OwnerID.String(), nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return value, nil
+	} else if id, ok := imp.ids[s]; ok {
+		return id, nil
+	}
+
+	switch column.ReferencedTable {
+	case "":
+		return value, nil
+	case "agentversion":
+		return imp.resolveAgentVersion(ctx, table, column, s)
+	default:
+		if !column.Nullable {
+			return nil, apierrors.NewBadRequest(fmt.Sprintf(
+				"%v.%v references %v, which is not part of the archive", table, column.Name, s))
+		}
+		imp.conflict(table, column.Name, s, "not set, the referenced row is not part of the archive")
+		return nil, nil
+	}
+}
+
+// resolveAgentVersion finds the agent version with the same name as the one of the exporting instance, or falls
+// back to the current agent version.
+func (imp *importer) resolveAgentVersion(
+	ctx context.Context,
+	table string,
+	column types.TableColumn,
+	id string,
+) (any, error) {
+	if resolved, ok := imp.agentVersions[id]; ok {
+		return resolved, nil
+	}
+	var name string
+	if parsed, err := uuid.Parse(id); err == nil {
+		name = imp.manifest.AgentVersions[parsed]
+	}
+	if av, err := db.GetAgentVersionWithName(ctx, name); err == nil {
+		imp.agentVersions[id] = av.ID.String()
+	} else if !errors.Is(err, apierrors.ErrNotFound) {
+		return nil, err
+	} else if av, err := db.GetCurrentAgentVersion(ctx); err != nil {
+		return nil, err
+	} else {
+		imp.conflict(table, column.Name, name, "replaced by the current agent version "+av.Name)
+		imp.agentVersions[id] = av.ID.String()
+	}
+	return imp.agentVersions[id], nil
+}
+
+func (imp *importer) resolveBrandingDomains(ctx context.Context, row map[string]any) error {
+	for _, column := range []string{"app_domain", "registry_domain"} {
+		if domain, ok := row[column].(string); ok && domain != "" {
+			if taken, err := db.IsOrganizationBrandingDomainTaken(ctx, domain); err != nil {
+				return err
+			} else if taken {
+				row[column] = nil
+				imp.conflict("OrganizationBranding", column, domain, "not set, the domain is used by another organization")
+			}
+		}
+	}
+	return nil
+}
+
+func (imp *importer) conflict(table, column, value, resolution string) {
+	imp.result.Conflicts = append(imp.result.Conflicts, Conflict{
+		Table:      table,
+		Column:     column,
+		Value:      value,
+		Resolution: resolution,
+	})
+}
+
+// decodeRow decodes a row with a field per column. Numbers are kept as they are, as they can be larger than a
+// float64 can represent.
+func decodeRow(r io.Reader) (map[string]any, error) {
+	var row map[string]any
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return row, decoder.Decode(&row)
+}
+
+func decodeRows(r io.Reader) ([]map[string]any, error) {
+	var rows []map[string]any
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	return rows, decoder.Decode(&rows)
+}
+
+func transformRows(rows []json.RawMessage, f func(fields map[string]any) error) ([]json.RawMessage, error) {
+	result := make([]json.RawMessage, len(rows))
+	for i, row := range rows {
+		fields, err := decodeRow(bytes.NewReader(row))
+		if err != nil {
+			return nil, err
+		} else if err := f(fields); err != nil {
+			return nil, err
+		} else if result[i], err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
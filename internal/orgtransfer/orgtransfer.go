@@ -0,0 +1,92 @@
+// Package orgtransfer exports an organization into an archive and imports it into another Distr instance, for
+// teams that move between the hosted offering and self-hosted instances.
+//
+// An archive is a gzip compressed tar file. It starts with manifest.json and organization.json, followed by a
+// tables/<table>.json file with the rows of each of db.OrganizationTransferTables, and ends with the registry blobs
+// of the artifacts of the organization in blobs/<digest>. Rows are stored with a field per column, so that an
+// archive can be imported by a newer version of Distr with a different schema. Secret values are encrypted with a
+// key supplied by the user.
+//
+// Users, memberships, access tokens, the audit log, metrics, logs, custom domains and single sign-on configurations
+// are not part of an archive.
+package orgtransfer
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FormatVersion is the version of the archive layout. It changes when an archive can no longer be read by older
+// versions of this package.
+const FormatVersion = 1
+
+const (
+	manifestFileName     = "manifest.json"
+	organizationFileName = "organization.json"
+	tablesDir            = "tables/"
+	blobsDir             = "blobs/"
+)
+
+type Manifest struct {
+	FormatVersion int `json:"formatVersion"`
+	// SchemaVersion is the database migration of the exporting instance. Archives can only be imported by instances
+	// with the same or a later migration.
+	SchemaVersion    uint      `json:"schemaVersion"`
+	DistrVersion     string    `json:"distrVersion"`
+	ExportedAt       time.Time `json:"exportedAt"`
+	OrganizationID   uuid.UUID `json:"organizationId"`
+	OrganizationName string    `json:"organizationName"`
+	KeySalt          []byte    `json:"keySalt"`
+	KeyCheck         string    `json:"keyCheck"`
+	Tables           []Table   `json:"tables"`
+	// AgentVersions maps the IDs of the agent versions of the exporting instance to their names, as they differ
+	// between instances.
+	AgentVersions map[uuid.UUID]string `json:"agentVersions"`
+	Blobs         []string             `json:"blobs"`
+}
+
+type Table struct {
+	Name string `json:"name"`
+	Rows int    `json:"rows"`
+}
+
+// secretColumns are encrypted with the key of the archive.
+var secretColumns = map[string][]string{
+	"Secret":                              {"value"},
+	"ApplicationEntitlement":              {"registry_password"},
+	"Artifact":                            {"upstream_password"},
+	"DeploymentRevision":                  {"env_file_data"},
+	"DeploymentTargetAgentNetwork":        {"proxy_password", "client_key"},
+	"DeploymentTargetVolumeBackupStorage": {"s3_secret_access_key"},
+}
+
+// organizationColumns are the settings of the organization that are exported. Its subscription is not, it is up to
+// the importing instance.
+var organizationColumns = []string{
+	"name",
+	"slug",
+	"features",
+	"pre_connect_script",
+	"post_connect_script",
+	"connect_script_is_sudo",
+	"artifact_tag_limit",
+	"license_key_expiration_reminder_days",
+	"security_policy",
+}
+
+// Conflict is something in an archive that could not be imported as it was, and how the import resolved it.
+type Conflict struct {
+	Table      string
+	Column     string
+	Value      string
+	Resolution string
+}
+
+type ImportResult struct {
+	OrganizationID uuid.UUID
+	DryRun         bool
+	Tables         []Table
+	Blobs          int
+	Conflicts      []Conflict
+}
@@ -0,0 +1,157 @@
+package orgtransfer
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/db"
+	. "github.com/onsi/gomega"
+)
+
+func TestSealerRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+	salt := newSalt()
+	s, err := newSealer("correct horse battery staple", salt)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	sealed := s.seal("hunter2")
+	g.Expect(sealed).NotTo(ContainSubstring("hunter2"))
+	g.Expect(s.seal("hunter2")).NotTo(Equal(sealed), "every value gets its own nonce")
+
+	opened, err := s.open(sealed)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(opened).To(Equal("hunter2"))
+}
+
+func TestSealerRejectsWrongKey(t *testing.T) {
+	g := NewWithT(t)
+	salt := newSalt()
+	s, err := newSealer("correct horse battery staple", salt)
+	g.Expect(err).NotTo(HaveOccurred())
+	other, err := newSealer("incorrect horse battery staple", salt)
+	g.Expect(err).NotTo(HaveOccurred())
+
+	_, err = other.open(s.seal(keyCheck))
+	g.Expect(err).To(MatchError(ErrInvalidKey))
+	_, err = other.open("not base64")
+	g.Expect(err).To(MatchError(ErrInvalidKey))
+}
+
+func TestSealerRejectsShortKey(t *testing.T) {
+	g := NewWithT(t)
+	_, err := newSealer("short", newSalt())
+	g.Expect(err).To(MatchError(apierrors.ErrBadRequest))
+}
+
+func TestSealColumns(t *testing.T) {
+	g := NewWithT(t)
+	s, err := newSealer("correct horse battery staple", newSalt())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	rows, err := sealColumns([]json.RawMessage{
+		json.RawMessage(`{"id":"a","value":"secret","quota":9007199254740993}`),
+		json.RawMessage(`{"id":"b","value":null}`),
+	}, []string{"value"}, s)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(rows).To(HaveLen(2))
+
+	var first map[string]any
+	g.Expect(json.Unmarshal(rows[0], &first)).To(Succeed())
+	g.Expect(first["value"]).NotTo(Equal("secret"))
+	g.Expect(s.open(first["value"].(string))).To(Equal("secret"))
+	g.Expect(string(rows[0])).To(ContainSubstring(`"quota":9007199254740993`), "numbers are kept as they are")
+	g.Expect(rows[1]).To(MatchJSON(`{"id":"b","value":null}`))
+}
+
+// sensitiveColumn matches the names of columns that hold credentials. Hashes and salts are not secrets.
+var sensitiveColumn = regexp.MustCompile(`(password|secret|private_key|client_key|env_file_data)$`)
+
+// TestSecretColumnsAreSealed makes sure that every column of an exported table that looks like a credential is
+// listed in secretColumns, and that all of them are encrypted in the archive.
+func TestSecretColumnsAreSealed(t *testing.T) {
+	g := NewWithT(t)
+	s, err := newSealer("correct horse battery staple", newSalt())
+	g.Expect(err).NotTo(HaveOccurred())
+
+	schema := migrationColumns(t)
+	for _, table := range db.OrganizationTransferTables() {
+		g.Expect(schema).To(HaveKey(strings.ToLower(table)), "table %v is not created by any migration", table)
+		for _, column := range schema[strings.ToLower(table)] {
+			if sensitiveColumn.MatchString(column) {
+				g.Expect(secretColumns[table]).To(ContainElement(column), "%v.%v is not encrypted", table, column)
+			}
+		}
+	}
+
+	for table, columns := range secretColumns {
+		g.Expect(db.OrganizationTransferTables()).To(ContainElement(table))
+		row := map[string]any{"id": "a"}
+		for _, column := range columns {
+			g.Expect(schema[strings.ToLower(table)]).To(ContainElement(column), "%v.%v does not exist", table, column)
+			row[column] = "plaintext-" + column
+		}
+		data, err := json.Marshal(row)
+		g.Expect(err).NotTo(HaveOccurred())
+
+		sealed, err := sealColumns([]json.RawMessage{data}, columns, s)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(string(sealed[0])).NotTo(ContainSubstring("plaintext-"), "secrets of %v are not encrypted", table)
+	}
+}
+
+// migrationColumns returns the columns of every table created by the database migrations, keyed by the lower case
+// table name. Columns that were dropped again are kept, which only makes the check stricter.
+func migrationColumns(t *testing.T) map[string][]string {
+	alterTable := regexp.MustCompile(`(?is)ALTER TABLE (?:IF EXISTS )?(\w+)(.*?);`)
+	createTable := regexp.MustCompile(`(?is)CREATE TABLE (?:IF NOT EXISTS )?(\w+)\s*\((.*?)\n\);`)
+	columnDefinition := regexp.MustCompile(`(?m)^\s*(\w+)\s`)
+	addColumn := regexp.MustCompile(`(?i)ADD COLUMN (?:IF NOT EXISTS )?(\w+)`)
+	renameColumn := regexp.MustCompile(`(?i)RENAME COLUMN (\w+) TO (\w+)`)
+	renameTable := regexp.MustCompile(`(?i)^\s*RENAME TO (\w+)`)
+
+	files, err := filepath.Glob("../migrations/sql/*.up.sql")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no migrations found: %v", err)
+	}
+	// renames only make sense in the order in which the migrations are applied
+	slices.SortFunc(files, func(a, b string) int { return migrationNumber(a) - migrationNumber(b) })
+
+	result := map[string][]string{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, m := range createTable.FindAllStringSubmatch(string(data), -1) {
+			table := strings.ToLower(m[1])
+			for _, c := range columnDefinition.FindAllStringSubmatch(m[2], -1) {
+				result[table] = append(result[table], strings.ToLower(c[1]))
+			}
+		}
+		for _, m := range alterTable.FindAllStringSubmatch(string(data), -1) {
+			table := strings.ToLower(m[1])
+			for _, c := range addColumn.FindAllStringSubmatch(m[2], -1) {
+				result[table] = append(result[table], strings.ToLower(c[1]))
+			}
+			for _, c := range renameColumn.FindAllStringSubmatch(m[2], -1) {
+				result[table] = append(result[table], strings.ToLower(c[2]))
+			}
+			if c := renameTable.FindStringSubmatch(m[2]); c != nil {
+				result[strings.ToLower(c[1])] = result[table]
+			}
+		}
+	}
+	return result
+}
+
+func migrationNumber(file string) int {
+	n, _ := strconv.Atoi(strings.SplitN(filepath.Base(file), "_", 2)[0])
+	return n
+}
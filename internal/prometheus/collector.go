@@ -18,6 +18,8 @@ type DistrCollector struct {
 	organizationsTotal        prometheus.Gauge
 	deploymentStatus          *prometheus.GaugeVec
 	deploymentStatusTimestamp *prometheus.GaugeVec
+	agentsConnected           prometheus.Gauge
+	agentResourcePushes       prometheus.Counter
 }
 
 var _ prometheus.Collector = (*DistrCollector)(nil)
@@ -27,6 +29,8 @@ func (d *DistrCollector) Collect(c chan<- prometheus.Metric) {
 	d.organizationsTotal.Collect(c)
 	d.deploymentStatus.Collect(c)
 	d.deploymentStatusTimestamp.Collect(c)
+	d.agentsConnected.Collect(c)
+	d.agentResourcePushes.Collect(c)
 }
 
 // Describe implements [prometheus.Collector].
@@ -34,6 +38,8 @@ func (d *DistrCollector) Describe(c chan<- *prometheus.Desc) {
 	d.organizationsTotal.Describe(c)
 	d.deploymentStatus.Describe(c)
 	d.deploymentStatusTimestamp.Describe(c)
+	d.agentsConnected.Describe(c)
+	d.agentResourcePushes.Describe(c)
 }
 
 type InitDataSource interface {
@@ -71,6 +77,18 @@ func (d *DistrCollector) DecOrganizationsTotal() {
 	d.organizationsTotal.Dec()
 }
 
+func (d *DistrCollector) IncAgentsConnected() {
+	d.agentsConnected.Inc()
+}
+
+func (d *DistrCollector) DecAgentsConnected() {
+	d.agentsConnected.Dec()
+}
+
+func (d *DistrCollector) IncAgentResourcePushes() {
+	d.agentResourcePushes.Inc()
+}
+
 type DeploymentStatusLabels struct {
 	OrganizationName         string
 	CustomerOrganizationName *string
@@ -152,5 +170,21 @@ func NewDistrCollector() *DistrCollector {
 		slices.Concat(deploymentStatusLabels, []string{"status"}),
 	)
 
+	c.agentsConnected = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "agents_connected",
+			Help:      "Number of agents that wait for changes of their resource on this replica",
+		},
+	)
+
+	c.agentResourcePushes = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "agent_resource_pushes_total",
+			Help:      "Number of changed resources sent to waiting agents",
+		},
+	)
+
 	return c
 }
@@ -0,0 +1,54 @@
+package quadlet
+
+import (
+	"bytes"
+	"strings"
+)
+
+// sectionOrder is the order in which the sections are written, regardless of the order they were added in.
+var sectionOrder = []string{"Unit", "Container", "Network", "Volume", "Service", "Install"}
+
+type entry struct {
+	key   string
+	value string
+}
+
+// file is a systemd unit file with the sections of sectionOrder.
+type file struct {
+	comments []string
+	sections map[string][]entry
+}
+
+func (f *file) comment(comment string) {
+	f.comments = append(f.comments, comment)
+}
+
+func (f *file) add(section, key, value string) {
+	if f.sections == nil {
+		f.sections = map[string][]entry{}
+	}
+	// Percent signs would otherwise be expanded by systemd as specifiers and a line break would end the entry.
+	value = strings.ReplaceAll(strings.ReplaceAll(value, "%", "%%"), "\n", " ")
+	f.sections[section] = append(f.sections[section], entry{key: key, value: value})
+}
+
+func (f *file) bytes() []byte {
+	var buf bytes.Buffer
+	for _, comment := range f.comments {
+		buf.WriteString("# " + comment + "\n")
+	}
+	for _, section := range sectionOrder {
+		entries := f.sections[section]
+		if len(entries) == 0 {
+			continue
+		}
+		if buf.Len() > 0 {
+			buf.WriteString("\n")
+		}
+		buf.WriteString("[" + section + "]\n")
+		for _, e := range entries {
+			buf.WriteString(e.key + "=" + e.value + "\n")
+		}
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,521 @@
+// Package quadlet converts compose projects into Podman Quadlet units, from which systemd runs the containers of a
+// project without a container engine daemon.
+package quadlet
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/compose-spec/compose-go/v2/types"
+	composeapi "github.com/docker/compose/v5/pkg/api"
+)
+
+const (
+	extContainer   = ".container"
+	extNetwork     = ".network"
+	extVolume      = ".volume"
+	extEnvironment = ".env"
+)
+
+// Unit is the file of a Quadlet unit, e.g. "myapp-web.container".
+type Unit struct {
+	FileName string
+	Content  []byte
+	// Environment is the content of the environment file of a container, which is referenced by the unit rather than
+	// written into it, as it may contain secrets. It must only be readable by its owner.
+	Environment []byte
+}
+
+// EnvironmentFileName returns the name of the environment file of the unit, next to the unit file.
+func (u Unit) EnvironmentFileName() string {
+	return strings.TrimSuffix(u.FileName, path.Ext(u.FileName)) + extEnvironment
+}
+
+// IsEnvironmentFile tells the environment files of units apart from the units in the same directory.
+func IsEnvironmentFile(fileName string) bool {
+	return path.Ext(fileName) == extEnvironment
+}
+
+// ServiceName returns the name of the systemd service that Quadlet generates for the unit.
+func (u Unit) ServiceName() string {
+	return ServiceName(u.FileName)
+}
+
+func (u Unit) IsContainer() bool {
+	return path.Ext(u.FileName) == extContainer
+}
+
+// ServiceName returns the name of the systemd service that Quadlet generates for a unit file. Networks and volumes
+// get a suffix, so that they do not clash with a container of the same name.
+func ServiceName(fileName string) string {
+	ext := path.Ext(fileName)
+	name := strings.TrimSuffix(fileName, ext)
+	switch ext {
+	case extNetwork:
+		return name + "-network.service"
+	case extVolume:
+		return name + "-volume.service"
+	default:
+		return name + ".service"
+	}
+}
+
+// FromProject generates a unit for every service, network and volume of the project. The containers have the same
+// names and labels as if the project was started by compose, so that it can still be used to inspect them.
+// The images are expected to be pulled already, because Quadlet cannot pull from registries that need credentials of
+// the agent.
+//
+// Features of compose that Quadlet cannot express, like builds, replicas, secrets and configs, result in an error
+// rather than in containers that silently behave differently.
+func FromProject(project *types.Project) ([]Unit, error) {
+	var units []Unit
+	for _, name := range slices.Sorted(maps.Keys(project.Networks)) {
+		if network := project.Networks[name]; !network.External {
+			units = append(units, networkUnit(project, name, network))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(project.Volumes)) {
+		if volume := project.Volumes[name]; !volume.External {
+			units = append(units, volumeUnit(project, name, volume))
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(project.Services)) {
+		if unit, err := containerUnit(project, project.Services[name]); err != nil {
+			return nil, fmt.Errorf("service %v: %w", name, err)
+		} else {
+			units = append(units, unit)
+		}
+	}
+	return units, nil
+}
+
+func containerUnit(project *types.Project, service types.ServiceConfig) (Unit, error) {
+	if service.Image == "" {
+		return Unit{}, errors.New("services without image are not supported")
+	}
+	if (service.Scale != nil && *service.Scale > 1) ||
+		(service.Deploy != nil && service.Deploy.Replicas != nil && *service.Deploy.Replicas > 1) {
+		return Unit{}, errors.New("replicas are not supported")
+	}
+	if len(service.Secrets) > 0 || len(service.Configs) > 0 {
+		return Unit{}, errors.New("secrets and configs are not supported")
+	}
+
+	var f file
+	f.comment(fmt.Sprintf("Generated by the Distr agent from service %q of compose project %q", service.Name,
+		project.Name))
+	f.add("Unit", "Description", fmt.Sprintf("%v %v", project.Name, service.Name))
+	for _, name := range slices.Sorted(maps.Keys(service.DependsOn)) {
+		dependency := ServiceName(unitFileName(project, name, extContainer))
+		f.add("Unit", "After", dependency)
+		if service.DependsOn[name].Required {
+			f.add("Unit", "Requires", dependency)
+		} else {
+			f.add("Unit", "Wants", dependency)
+		}
+	}
+
+	f.add("Container", "Image", service.Image)
+	f.add("Container", "ContainerName", containerName(project, service))
+	// The agent has pulled the image already with its registry credentials.
+	f.add("Container", "Pull", "never")
+
+	labels := map[string]string{
+		composeapi.ProjectLabel:         project.Name,
+		composeapi.ServiceLabel:         service.Name,
+		composeapi.OneoffLabel:          "False",
+		composeapi.ContainerNumberLabel: "1",
+	}
+	maps.Copy(labels, service.Labels)
+	for _, key := range slices.Sorted(maps.Keys(labels)) {
+		f.add("Container", "Label", quote(key+"="+labels[key]))
+	}
+	environment, err := environmentFile(service.Environment)
+	if err != nil {
+		return Unit{}, err
+	}
+	unit := Unit{FileName: unitFileName(project, service.Name, extContainer), Environment: environment}
+	if len(environment) > 0 {
+		// relative to the directory of the unit
+		f.add("Container", "EnvironmentFile", unit.EnvironmentFileName())
+	}
+
+	if len(service.Entrypoint) == 1 {
+		f.add("Container", "Entrypoint", service.Entrypoint[0])
+	} else if len(service.Entrypoint) > 1 {
+		// Podman accepts the exec form of an entrypoint as JSON array.
+		f.add("Container", "Entrypoint", jsonString(service.Entrypoint))
+	}
+	if len(service.Command) > 0 {
+		f.add("Container", "Exec", quoteAll(service.Command))
+	}
+
+	if err := addNetworks(&f, project, service); err != nil {
+		return Unit{}, err
+	}
+	for _, port := range service.Ports {
+		f.add("Container", "PublishPort", publishPort(port))
+	}
+	for _, volume := range service.Volumes {
+		if key, value, err := volumeMount(project, volume); err != nil {
+			return Unit{}, err
+		} else {
+			f.add("Container", key, value)
+		}
+	}
+	for _, tmpfs := range service.Tmpfs {
+		f.add("Container", "Tmpfs", tmpfs)
+	}
+
+	addHealthCheck(&f, service.HealthCheck)
+	addRuntimeOptions(&f, service)
+
+	f.add("Service", "Restart", restartPolicy(service.Restart))
+	if service.StopGracePeriod != nil {
+		// Give podman the time to stop the container before systemd kills it.
+		f.add("Service", "TimeoutStopSec", strconv.Itoa(int(time.Duration(*service.StopGracePeriod).Seconds())+10))
+	}
+	f.add("Install", "WantedBy", "default.target")
+
+	unit.Content = f.bytes()
+	return unit, nil
+}
+
+// environmentFile renders the environment of a service in the format of "podman run --env-file", which takes
+// values literally and has no way to continue a value on the next line.
+func environmentFile(environment types.MappingWithEquals) ([]byte, error) {
+	var buf strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(environment)) {
+		if value := environment[key]; value == nil {
+			continue
+		} else if strings.ContainsAny(*value, "\r\n") {
+			return nil, fmt.Errorf("environment variable %v: multi-line values are not supported", key)
+		} else {
+			buf.WriteString(key + "=" + *value + "\n")
+		}
+	}
+	return []byte(buf.String()), nil
+}
+
+func addNetworks(f *file, project *types.Project, service types.ServiceConfig) error {
+	switch mode := service.NetworkMode; {
+	case mode == "host" || mode == "none":
+		f.add("Container", "Network", mode)
+		return nil
+	case strings.HasPrefix(mode, "service:"):
+		other, ok := project.Services[strings.TrimPrefix(mode, "service:")]
+		if !ok {
+			return fmt.Errorf("network_mode %q refers to an unknown service", mode)
+		}
+		f.add("Container", "Network", "container:"+containerName(project, other))
+		return nil
+	case strings.HasPrefix(mode, "container:"):
+		f.add("Container", "Network", mode)
+		return nil
+	case mode != "" && mode != "bridge" && mode != "default":
+		return fmt.Errorf("network_mode %q is not supported", mode)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(service.Networks)) {
+		network, ok := project.Networks[name]
+		if !ok {
+			return fmt.Errorf("network %v is not defined", name)
+		}
+		value := network.Name
+		if !network.External {
+			value = unitFileName(project, name, extNetwork)
+		}
+		// Compose makes every container reachable by the name of its service, Podman only by the container name.
+		options := []string{"alias=" + service.Name}
+		if config := service.Networks[name]; config != nil {
+			for _, alias := range config.Aliases {
+				options = append(options, "alias="+alias)
+			}
+			if config.Ipv4Address != "" {
+				options = append(options, "ip="+config.Ipv4Address)
+			}
+			if config.Ipv6Address != "" {
+				options = append(options, "ip6="+config.Ipv6Address)
+			}
+		}
+		f.add("Container", "Network", value+":"+strings.Join(options, ","))
+	}
+	return nil
+}
+
+func publishPort(port types.ServicePortConfig) string {
+	var parts []string
+	if port.HostIP != "" {
+		parts = append(parts, port.HostIP)
+	}
+	if port.Published != "" {
+		parts = append(parts, port.Published)
+	} else if port.HostIP != "" {
+		parts = append(parts, "")
+	}
+	parts = append(parts, strconv.FormatUint(uint64(port.Target), 10))
+	value := strings.Join(parts, ":")
+	if port.Protocol != "" && port.Protocol != "tcp" {
+		value += "/" + port.Protocol
+	}
+	return value
+}
+
+func volumeMount(project *types.Project, volume types.ServiceVolumeConfig) (string, string, error) {
+	var options []string
+	if volume.ReadOnly {
+		options = append(options, "ro")
+	}
+	var source string
+	switch volume.Type {
+	case types.VolumeTypeVolume:
+		if volume.Source == "" {
+			return "Volume", volume.Target, nil
+		}
+		config, ok := project.Volumes[volume.Source]
+		if !ok {
+			return "", "", fmt.Errorf("volume %v is not defined", volume.Source)
+		}
+		if config.External {
+			source = config.Name
+		} else {
+			source = unitFileName(project, volume.Source, extVolume)
+		}
+		if volume.Volume != nil && volume.Volume.NoCopy {
+			options = append(options, "nocopy")
+		}
+	case types.VolumeTypeBind:
+		source = volume.Source
+		if volume.Bind != nil {
+			if volume.Bind.SELinux != "" {
+				options = append(options, volume.Bind.SELinux)
+			}
+			if volume.Bind.Propagation != "" {
+				options = append(options, volume.Bind.Propagation)
+			}
+		}
+	case types.VolumeTypeTmpfs:
+		return "Tmpfs", volume.Target, nil
+	default:
+		return "", "", fmt.Errorf("volumes of type %v are not supported", volume.Type)
+	}
+	value := source + ":" + volume.Target
+	if len(options) > 0 {
+		value += ":" + strings.Join(options, ",")
+	}
+	return "Volume", value, nil
+}
+
+func addHealthCheck(f *file, healthCheck *types.HealthCheckConfig) {
+	if healthCheck == nil {
+		return
+	}
+	if healthCheck.Disable || (len(healthCheck.Test) > 0 && healthCheck.Test[0] == "NONE") {
+		f.add("Container", "HealthCmd", "none")
+		return
+	}
+	if len(healthCheck.Test) > 1 {
+		switch healthCheck.Test[0] {
+		case "CMD":
+			f.add("Container", "HealthCmd", jsonString(healthCheck.Test[1:]))
+		case "CMD-SHELL":
+			f.add("Container", "HealthCmd", healthCheck.Test[1])
+		}
+	}
+	if healthCheck.Interval != nil {
+		f.add("Container", "HealthInterval", healthCheck.Interval.String())
+	}
+	if healthCheck.Timeout != nil {
+		f.add("Container", "HealthTimeout", healthCheck.Timeout.String())
+	}
+	if healthCheck.StartPeriod != nil {
+		f.add("Container", "HealthStartPeriod", healthCheck.StartPeriod.String())
+	}
+	if healthCheck.Retries != nil {
+		f.add("Container", "HealthRetries", strconv.FormatUint(*healthCheck.Retries, 10))
+	}
+}
+
+func addRuntimeOptions(f *file, service types.ServiceConfig) {
+	if service.User != "" {
+		f.add("Container", "User", service.User)
+	}
+	if service.WorkingDir != "" {
+		f.add("Container", "WorkingDir", service.WorkingDir)
+	}
+	if service.Hostname != "" {
+		f.add("Container", "HostName", service.Hostname)
+	}
+	if service.Init != nil && *service.Init {
+		f.add("Container", "RunInit", "true")
+	}
+	if service.ReadOnly {
+		f.add("Container", "ReadOnly", "true")
+	}
+	if service.StopSignal != "" {
+		f.add("Container", "StopSignal", service.StopSignal)
+	}
+	if service.StopGracePeriod != nil {
+		f.add("Container", "StopTimeout",
+			strconv.Itoa(int(time.Duration(*service.StopGracePeriod).Seconds())))
+	}
+	for _, capability := range service.CapAdd {
+		f.add("Container", "AddCapability", capability)
+	}
+	for _, capability := range service.CapDrop {
+		f.add("Container", "DropCapability", capability)
+	}
+	for _, device := range service.Devices {
+		value := device.Source + ":" + device.Target
+		if device.Permissions != "" {
+			value += ":" + device.Permissions
+		}
+		f.add("Container", "AddDevice", value)
+	}
+	for _, dns := range service.DNS {
+		f.add("Container", "DNS", dns)
+	}
+	for _, host := range slices.Sorted(maps.Keys(service.ExtraHosts)) {
+		for _, ip := range service.ExtraHosts[host] {
+			f.add("Container", "AddHost", host+":"+ip)
+		}
+	}
+	for _, group := range service.GroupAdd {
+		f.add("Container", "GroupAdd", group)
+	}
+	for _, key := range slices.Sorted(maps.Keys(service.Sysctls)) {
+		f.add("Container", "Sysctl", key+"="+service.Sysctls[key])
+	}
+	for _, name := range slices.Sorted(maps.Keys(service.Ulimits)) {
+		if ulimit := service.Ulimits[name]; ulimit.Single != 0 {
+			f.add("Container", "Ulimit", fmt.Sprintf("%v=%v", name, ulimit.Single))
+		} else {
+			f.add("Container", "Ulimit", fmt.Sprintf("%v=%v:%v", name, ulimit.Soft, ulimit.Hard))
+		}
+	}
+	if service.ShmSize > 0 {
+		f.add("Container", "ShmSize", strconv.FormatInt(int64(service.ShmSize), 10))
+	}
+	if service.Logging != nil && service.Logging.Driver != "" {
+		f.add("Container", "LogDriver", service.Logging.Driver)
+	}
+	if service.Privileged {
+		f.add("Container", "PodmanArgs", "--privileged")
+	}
+	if service.MemLimit > 0 {
+		f.add("Container", "PodmanArgs", fmt.Sprintf("--memory=%d", service.MemLimit))
+	}
+	if service.CPUS > 0 {
+		f.add("Container", "PodmanArgs", fmt.Sprintf("--cpus=%v", service.CPUS))
+	}
+	for _, option := range service.SecurityOpt {
+		f.add("Container", "PodmanArgs", quote("--security-opt="+option))
+	}
+}
+
+func restartPolicy(restart string) string {
+	switch {
+	case restart == types.RestartPolicyAlways || restart == types.RestartPolicyUnlessStopped:
+		return "always"
+	case strings.HasPrefix(restart, types.RestartPolicyOnFailure):
+		return "on-failure"
+	default:
+		return "no"
+	}
+}
+
+func networkUnit(project *types.Project, name string, network types.NetworkConfig) Unit {
+	var f file
+	f.comment(fmt.Sprintf("Generated by the Distr agent from network %q of compose project %q", name, project.Name))
+	f.add("Network", "NetworkName", network.Name)
+	if network.Driver != "" {
+		f.add("Network", "Driver", network.Driver)
+	}
+	if network.Internal {
+		f.add("Network", "Internal", "true")
+	}
+	if network.EnableIPv6 != nil && *network.EnableIPv6 {
+		f.add("Network", "IPv6", "true")
+	}
+	for _, pool := range network.Ipam.Config {
+		if pool.Subnet != "" {
+			f.add("Network", "Subnet", pool.Subnet)
+		}
+		if pool.Gateway != "" {
+			f.add("Network", "Gateway", pool.Gateway)
+		}
+		if pool.IPRange != "" {
+			f.add("Network", "IPRange", pool.IPRange)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(network.DriverOpts)) {
+		f.add("Network", "Options", key+"="+network.DriverOpts[key])
+	}
+	addResourceLabels(&f, "Network", project, composeapi.NetworkLabel, name, network.Labels)
+	return Unit{FileName: unitFileName(project, name, extNetwork), Content: f.bytes()}
+}
+
+func volumeUnit(project *types.Project, name string, volume types.VolumeConfig) Unit {
+	var f file
+	f.comment(fmt.Sprintf("Generated by the Distr agent from volume %q of compose project %q", name, project.Name))
+	f.add("Volume", "VolumeName", volume.Name)
+	if volume.Driver != "" {
+		f.add("Volume", "Driver", volume.Driver)
+	}
+	for _, key := range slices.Sorted(maps.Keys(volume.DriverOpts)) {
+		f.add("Volume", "PodmanArgs", quote("--opt="+key+"="+volume.DriverOpts[key]))
+	}
+	addResourceLabels(&f, "Volume", project, composeapi.VolumeLabel, name, volume.Labels)
+	return Unit{FileName: unitFileName(project, name, extVolume), Content: f.bytes()}
+}
+
+// addResourceLabels adds the labels that compose sets on networks and volumes, so that they can be found by project
+// when the deployment is removed.
+func addResourceLabels(f *file, section string, project *types.Project, label, name string, labels types.Labels) {
+	all := map[string]string{composeapi.ProjectLabel: project.Name, label: name}
+	maps.Copy(all, labels)
+	for _, key := range slices.Sorted(maps.Keys(all)) {
+		f.add(section, "Label", quote(key+"="+all[key]))
+	}
+}
+
+func unitFileName(project *types.Project, name, ext string) string {
+	return project.Name + "-" + name + ext
+}
+
+func containerName(project *types.Project, service types.ServiceConfig) string {
+	if service.ContainerName != "" {
+		return service.ContainerName
+	}
+	return strings.Join([]string{project.Name, service.Name, "1"}, composeapi.Separator)
+}
+
+func jsonString(values []string) string {
+	data, _ := json.Marshal(values)
+	return string(data)
+}
+
+// quoteAll joins words for keys that Quadlet splits like the command line of a systemd unit.
+func quoteAll(words []string) string {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = quote(word)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quote makes a single word out of value for keys that Quadlet splits like the command line of a systemd unit, e.g.
+// Environment or Exec.
+func quote(value string) string {
+	if value != "" && !strings.ContainsAny(value, " \t\n\"'\\") {
+		return value
+	}
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(value) + `"`
+}
@@ -0,0 +1,195 @@
+package quadlet
+
+import (
+	"testing"
+
+	"github.com/compose-spec/compose-go/v2/loader"
+	"github.com/compose-spec/compose-go/v2/types"
+	. "github.com/onsi/gomega"
+)
+
+func loadProject(t *testing.T, compose string) *types.Project {
+	project, err := loader.LoadWithContext(
+		t.Context(),
+		types.ConfigDetails{
+			WorkingDir:  t.TempDir(),
+			ConfigFiles: []types.ConfigFile{{Filename: "docker-compose.yaml", Content: []byte(compose)}},
+			Environment: types.Mapping{},
+		},
+		func(o *loader.Options) { o.SetProjectName("demo", true) },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return project
+}
+
+func unitContents(units []Unit) map[string]string {
+	result := map[string]string{}
+	for _, unit := range units {
+		result[unit.FileName] = string(unit.Content)
+	}
+	return result
+}
+
+func TestFromProject(t *testing.T) {
+	g := NewWithT(t)
+	project := loadProject(t, `
+services:
+  web:
+    image: nginx:1.27
+    command: ["nginx", "-g", "daemon off;"]
+    restart: unless-stopped
+    environment:
+      GREETING: hello world
+      DISCOUNT: 50%
+    ports:
+      - "8080:80"
+      - "127.0.0.1:5353:53/udp"
+    volumes:
+      - data:/usr/share/nginx/html:ro
+      - ./config:/etc/nginx/conf.d
+    depends_on:
+      - db
+    healthcheck:
+      test: ["CMD", "curl", "-f", "http://localhost"]
+      interval: 30s
+      retries: 3
+  db:
+    image: postgres:17
+volumes:
+  data:
+`)
+
+	units, err := FromProject(project)
+	g.Expect(err).NotTo(HaveOccurred())
+	contents := unitContents(units)
+	g.Expect(contents).To(HaveLen(4))
+	g.Expect(contents).To(HaveKey("demo-default.network"))
+	g.Expect(contents).To(HaveKey("demo-db.container"))
+
+	g.Expect(contents["demo-data.volume"]).To(And(
+		ContainSubstring("VolumeName=demo_data\n"),
+		ContainSubstring("Label=com.docker.compose.project=demo\n"),
+		ContainSubstring("Label=com.docker.compose.volume=data\n"),
+	))
+
+	web := contents["demo-web.container"]
+	g.Expect(web).To(And(
+		ContainSubstring("[Unit]\nDescription=demo web\nAfter=demo-db.service\nRequires=demo-db.service\n"),
+		ContainSubstring("Image=nginx:1.27\n"),
+		ContainSubstring("ContainerName=demo-web-1\n"),
+		ContainSubstring("Pull=never\n"),
+		ContainSubstring("Label=com.docker.compose.project=demo\n"),
+		ContainSubstring("Label=com.docker.compose.service=web\n"),
+		ContainSubstring("EnvironmentFile=demo-web.env\n"),
+		ContainSubstring("Exec=nginx -g \"daemon off;\"\n"),
+		ContainSubstring("Network=demo-default.network:alias=web\n"),
+		ContainSubstring("PublishPort=8080:80\n"),
+		ContainSubstring("PublishPort=127.0.0.1:5353:53/udp\n"),
+		ContainSubstring("Volume=demo-data.volume:/usr/share/nginx/html:ro\n"),
+		ContainSubstring("/config:/etc/nginx/conf.d\n"),
+		ContainSubstring("HealthCmd=[\"curl\",\"-f\",\"http://localhost\"]\n"),
+		ContainSubstring("HealthInterval=30s\n"),
+		ContainSubstring("HealthRetries=3\n"),
+		ContainSubstring("[Service]\nRestart=always\n"),
+		ContainSubstring("[Install]\nWantedBy=default.target\n"),
+	))
+}
+
+func TestFromProjectEnvironmentFile(t *testing.T) {
+	g := NewWithT(t)
+	project := loadProject(t, `
+services:
+  web:
+    image: nginx:1.27
+    environment:
+      GREETING: hello world
+      DISCOUNT: 50%
+      DATABASE_PASSWORD: s3cr3t-p4ssw0rd
+  db:
+    image: postgres:17
+`)
+
+	units, err := FromProject(project)
+	g.Expect(err).NotTo(HaveOccurred())
+	for _, unit := range units {
+		g.Expect(string(unit.Content)).NotTo(ContainSubstring("s3cr3t-p4ssw0rd"), unit.FileName)
+		if unit.FileName == "demo-web.container" {
+			g.Expect(unit.EnvironmentFileName()).To(Equal("demo-web.env"))
+			g.Expect(string(unit.Environment)).To(Equal(
+				"DATABASE_PASSWORD=s3cr3t-p4ssw0rd\nDISCOUNT=50%\nGREETING=hello world\n"))
+		} else {
+			g.Expect(unit.Environment).To(BeEmpty(), unit.FileName)
+			g.Expect(string(unit.Content)).NotTo(ContainSubstring("EnvironmentFile="), unit.FileName)
+		}
+	}
+
+	project = loadProject(t, `
+services:
+  web:
+    image: nginx:1.27
+    environment:
+      CERTIFICATE: "line 1\nline 2"
+`)
+	_, err = FromProject(project)
+	g.Expect(err).To(MatchError(ContainSubstring("multi-line values are not supported")))
+}
+
+func TestFromProjectNetworkModes(t *testing.T) {
+	g := NewWithT(t)
+	project := loadProject(t, `
+services:
+  app:
+    image: app
+    network_mode: host
+  sidecar:
+    image: sidecar
+    network_mode: service:app
+`)
+
+	units, err := FromProject(project)
+	g.Expect(err).NotTo(HaveOccurred())
+	contents := unitContents(units)
+	g.Expect(contents["demo-app.container"]).To(ContainSubstring("Network=host\n"))
+	g.Expect(contents["demo-sidecar.container"]).To(ContainSubstring("Network=container:demo-app-1\n"))
+}
+
+func TestFromProjectUnsupported(t *testing.T) {
+	for name, compose := range map[string]string{
+		"build": `
+services:
+  app:
+    build: .
+`,
+		"replicas": `
+services:
+  app:
+    image: app
+    deploy:
+      replicas: 2
+`,
+		"secrets": `
+services:
+  app:
+    image: app
+    secrets: [token]
+secrets:
+  token:
+    environment: TOKEN
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			_, err := FromProject(loadProject(t, compose))
+			g.Expect(err).To(HaveOccurred())
+		})
+	}
+}
+
+func TestServiceName(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(ServiceName("demo-web.container")).To(Equal("demo-web.service"))
+	g.Expect(ServiceName("demo-default.network")).To(Equal("demo-default-network.service"))
+	g.Expect(ServiceName("demo-data.volume")).To(Equal("demo-data-volume.service"))
+}
@@ -2,15 +2,23 @@ package audit
 
 import (
 	"context"
+	"net/http"
 
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/registry/name"
+	"github.com/distr-sh/distr/internal/types"
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/opencontainers/go-digest"
 )
 
 type ArtifactAuditor interface {
 	AuditPull(ctx context.Context, name, reference string) error
+	// AuditPush records a pushed tag in the audit log of the organization.
+	AuditPush(req *http.Request, name, reference string) error
+	// AuditDelete records a deleted tag in the audit log of the organization.
+	AuditDelete(req *http.Request, name, reference string) error
 }
 
 type auditor struct{}
@@ -36,3 +44,37 @@ func (a *auditor) AuditPull(ctx context.Context, nameStr string, reference strin
 		)
 	}
 }
+
+// AuditPush implements ArtifactAuditor.
+func (a *auditor) AuditPush(req *http.Request, nameStr string, reference string) error {
+	return a.auditTagChange(req, nameStr, reference, types.AuditLogActionCreate, http.StatusCreated)
+}
+
+// AuditDelete implements ArtifactAuditor.
+func (a *auditor) AuditDelete(req *http.Request, nameStr string, reference string) error {
+	return a.auditTagChange(req, nameStr, reference, types.AuditLogActionDelete, http.StatusAccepted)
+}
+
+func (a *auditor) auditTagChange(
+	req *http.Request,
+	nameStr string,
+	reference string,
+	action types.AuditLogAction,
+	status int,
+) error {
+	// Manifests pushed by digest are the parts of a tag, like the platform images of a multi-arch image, and
+	// are recorded with the tag that refers to them.
+	if _, err := digest.Parse(reference); err == nil {
+		return nil
+	}
+	ctx := req.Context()
+	if name, err := name.Parse(nameStr); err != nil {
+		return err
+	} else {
+		return auditlog.Record(ctx, auth.ArtifactsAuthentication.Require(ctx), req, status, auditlog.Event{
+			Resource:   auditlog.ResourceArtifactTags,
+			ResourceID: new(name.ArtifactName + ":" + reference),
+			Action:     action,
+		})
+	}
+}
@@ -24,7 +24,7 @@ const (
 type Authorizer interface {
 	Authorize(ctx context.Context, name string, action Action) error
 	AuthorizeReference(ctx context.Context, name string, reference string, action Action) error
-	AuthorizeBlob(ctx context.Context, digest digest.Digest, action Action) error
+	AuthorizeBlob(ctx context.Context, name string, digest digest.Digest, action Action) error
 }
 
 type authorizer struct{}
@@ -34,7 +34,7 @@ func NewAuthorizer() Authorizer {
 }
 
 // authorizeWrite verifies that the authenticated principal is allowed to perform write actions.
-// Customer and partner users may never write, and vendor users require a role other than read-only.
+// Customer and partner users may never write, and vendor users require write permission on artifacts.
 func authorizeWrite(auth authinfo.AuthInfoWithOrganization) error {
 	if auth.CurrentCustomerOrgID() != nil {
 		return NewErrAccessDenied("customer user can not perform write action")
@@ -44,14 +44,23 @@ func authorizeWrite(auth authinfo.AuthInfoWithOrganization) error {
 		return NewErrAccessDenied("partner user can not perform write action")
 	}
 
-	if auth.CurrentUserRole() == nil {
-		return NewErrAccessDenied("user with no role can not perform write action")
+	if !auth.CurrentPermissions().Has(types.ResourceArtifacts, types.ActionWrite) {
+		return NewErrAccessDenied("user without write permission on artifacts can not perform write action")
 	}
 
-	if *auth.CurrentUserRole() == types.UserRoleReadOnly {
-		return NewErrAccessDenied("read-only user can not perform write action")
-	}
+	return nil
+}
 
+// authorizeScopes verifies that the scopes of an access token allow the action on the artifact. Other credentials
+// have no scopes and are not restricted by them.
+func authorizeScopes(auth authinfo.AuthInfoWithOrganization, artifactName string, action Action) error {
+	required := types.ActionRead
+	if action == ActionWrite {
+		required = types.ActionWrite
+	}
+	if !auth.CurrentAccessTokenScopes().AllowsArtifact(artifactName, required) {
+		return NewErrAccessDenied("access token scopes do not allow this action on the artifact")
+	}
 	return nil
 }
 
@@ -73,6 +82,8 @@ func (a *authorizer) Authorize(ctx context.Context, nameStr string, action Actio
 		return NewErrAccessDenied("organization has no slug")
 	} else if *org.Slug != n.OrgName {
 		return NewErrAccessDenied("organization slug does not match reference")
+	} else if err := authorizeScopes(auth, n.ArtifactName, action); err != nil {
+		return err
 	}
 
 	if action == ActionWrite {
@@ -105,6 +116,8 @@ func (a *authorizer) AuthorizeReference(ctx context.Context, nameStr string, ref
 		return NewErrAccessDenied("organization has no slug")
 	} else if *org.Slug != n.OrgName {
 		return NewErrAccessDenied("organization slug does not match reference")
+	} else if err := authorizeScopes(auth, n.ArtifactName, action); err != nil {
+		return err
 	} else if action != ActionWrite && auth.CurrentCustomerOrgID() != nil {
 		if org.HasFeature(types.FeatureLicensing) {
 			err := db.CheckEntitlementForArtifact(ctx,
@@ -134,7 +147,7 @@ func (a *authorizer) AuthorizeReference(ctx context.Context, nameStr string, ref
 }
 
 // AuthorizeBlob implements ArtifactsAuthorizer.
-func (a *authorizer) AuthorizeBlob(ctx context.Context, digest digest.Digest, action Action) error {
+func (a *authorizer) AuthorizeBlob(ctx context.Context, nameStr string, digest digest.Digest, action Action) error {
 	auth := auth.ArtifactsAuthentication.Require(ctx)
 
 	if action == ActionWrite {
@@ -143,6 +156,16 @@ func (a *authorizer) AuthorizeBlob(ctx context.Context, digest digest.Digest, ac
 		}
 	}
 
+	// Blobs are addressed by their digest only, so a token restricted to some artifacts is checked against the
+	// repository of the request.
+	if len(auth.CurrentAccessTokenScopes()) > 0 {
+		if n, err := name.Parse(nameStr); err != nil {
+			return err
+		} else if err := authorizeScopes(auth, n.ArtifactName, action); err != nil {
+			return err
+		}
+	}
+
 	if auth.CurrentCustomerOrgID() != nil && auth.CurrentOrg().HasFeature(types.FeatureLicensing) {
 		err := db.CheckEntitlementForArtifactBlob(ctx, digest.String(), *auth.CurrentCustomerOrgID(), *auth.CurrentOrgID())
 		if errors.Is(err, apierrors.ErrForbidden) {
@@ -103,7 +103,7 @@ func (b *blobs) handle(resp http.ResponseWriter, req *http.Request) *regError {
 	case http.MethodHead:
 		if h, err := digest.Parse(target); err != nil {
 			return regErrDigestInvalid
-		} else if err := b.authz.AuthorizeBlob(req.Context(), h, authz.ActionStat); err != nil {
+		} else if err := b.authz.AuthorizeBlob(req.Context(), repo, h, authz.ActionStat); err != nil {
 			if errors.Is(err, authz.ErrAccessDenied) {
 				return regErrDenied(err.Error())
 			} else if errors.Is(err, registryerror.ErrInvalidArtifactName) {
@@ -114,7 +114,7 @@ func (b *blobs) handle(resp http.ResponseWriter, req *http.Request) *regError {
 		return b.handleHead(resp, req, repo, target)
 	case http.MethodGet:
 		if h, err := digest.Parse(target); err == nil {
-			if err := b.authz.AuthorizeBlob(req.Context(), h, authz.ActionRead); err != nil {
+			if err := b.authz.AuthorizeBlob(req.Context(), repo, h, authz.ActionRead); err != nil {
 				if errors.Is(err, authz.ErrAccessDenied) {
 					return regErrDenied(err.Error())
 				} else if errors.Is(err, registryerror.ErrInvalidArtifactName) {
@@ -149,7 +149,7 @@ func (b *blobs) handle(resp http.ResponseWriter, req *http.Request) *regError {
 	case http.MethodPut:
 		if h, err := digest.Parse(digestFromQuery); err != nil {
 			return regErrDigestInvalid
-		} else if err := b.authz.AuthorizeBlob(req.Context(), h, authz.ActionWrite); err != nil {
+		} else if err := b.authz.AuthorizeBlob(req.Context(), repo, h, authz.ActionWrite); err != nil {
 			if errors.Is(err, authz.ErrAccessDenied) {
 				return regErrDenied(err.Error())
 			} else if errors.Is(err, registryerror.ErrInvalidArtifactName) {
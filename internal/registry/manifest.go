@@ -482,6 +482,12 @@ func (handler *manifests) handlePut(resp http.ResponseWriter, req *http.Request,
 		return regErrInternal(err)
 	}
 
+	if err := handler.audit.AuditPush(req, repo, target); err != nil {
+		log := internalctx.GetLogger(req.Context())
+		log.Warn("failed to audit-log push", zap.Error(err))
+		sentry.GetHubFromContext(req.Context()).CaptureException(err)
+	}
+
 	resp.Header().Set("Docker-Content-Digest", mf.Digest.String())
 	resp.Header().Set("OCI-Subject", mf.Digest.String())
 	resp.Header().Set("Location", req.URL.JoinPath(mf.Blob.Digest.String()).Path)
@@ -507,6 +513,12 @@ func (handler *manifests) handleDelete(resp http.ResponseWriter, req *http.Reque
 		return regErrInternal(err)
 	}
 
+	if err := handler.audit.AuditDelete(req, repo, target); err != nil {
+		log := internalctx.GetLogger(req.Context())
+		log.Warn("failed to audit-log delete", zap.Error(err))
+		sentry.GetHubFromContext(req.Context()).CaptureException(err)
+	}
+
 	resp.WriteHeader(http.StatusAccepted)
 	return nil
 }
@@ -164,7 +164,7 @@ func NewDefault(
 			middleware.LoggingMiddleware,
 			// The OCI registry always uses the primary db: container clients rely on read-after-write
 			// consistency (push then pull/HEAD, multi-arch, signing), which a lagging replica would break.
-			middleware.ContextInjectorMiddleware(pool, nil, mailer, nil, nil, nil),
+			middleware.ContextInjectorMiddleware(pool, nil, mailer, nil, nil, nil, s3Client, nil),
 			auth.ArtifactsAuthentication.Middleware,
 			auth.ArtifactsAuthentication.ValidatorMiddleware(func(value authinfo.AuthInfoWithOrganization) error {
 				if value.CurrentOrg() == nil {
@@ -5,6 +5,7 @@ import (
 
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/db"
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -22,3 +23,30 @@ func RunUpstreamSync(ctx context.Context, skipExistingTags bool) error {
 	}
 	return nil
 }
+
+const SyncArtifactTaskType = "ArtifactSync"
+
+type SyncArtifactTask struct {
+	OrganizationID uuid.UUID `json:"organizationId"`
+	ArtifactID     uuid.UUID `json:"artifactId"`
+}
+
+type SyncArtifactTaskResult struct {
+	ArtifactID uuid.UUID `json:"artifactId"`
+	// SyncError is set if the upstream could not be synced. It is also stored with the artifact.
+	SyncError *string `json:"syncError,omitempty"`
+}
+
+func RunSyncArtifactTask(ctx context.Context, task SyncArtifactTask) (any, error) {
+	artifact, err := db.GetArtifactByID(ctx, task.OrganizationID, task.ArtifactID, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := new(Syncer).SyncArtifactTags(ctx, &artifact.Artifact, false); err != nil {
+		return nil, err
+	}
+	if artifact, err = db.GetArtifactByID(ctx, task.OrganizationID, task.ArtifactID, nil); err != nil {
+		return nil, err
+	}
+	return SyncArtifactTaskResult{ArtifactID: artifact.ID, SyncError: artifact.LastSyncError}, nil
+}
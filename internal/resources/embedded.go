@@ -4,6 +4,8 @@ import (
 	"embed"
 	"fmt"
 	"io/fs"
+	"path"
+	"strconv"
 	"sync"
 	"text/template"
 
@@ -15,6 +17,10 @@ var (
 	embeddedFs embed.FS
 	fsys       = util.Require(fs.Sub(embeddedFs, "embedded"))
 	templates  sync.Map
+	funcs      = template.FuncMap{
+		// quote renders a string as a double-quoted YAML scalar, which may span multiple lines, e.g. PEM certificates.
+		"quote": strconv.Quote,
+	}
 )
 
 func Get(name string) ([]byte, error) {
@@ -25,7 +31,7 @@ func GetTemplate(name string) (*template.Template, error) {
 	if tmpl, ok := templates.Load(name); ok {
 		return tmpl.(*template.Template), nil
 	}
-	tmpl, err := template.ParseFS(fsys, name)
+	tmpl, err := template.New(path.Base(name)).Funcs(funcs).ParseFS(fsys, name)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse template %v: %w", name, err)
 	}
@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/distr-sh/distr/internal/agentnotify"
+	"github.com/distr-sh/distr/internal/auditlog"
 	"github.com/distr-sh/distr/internal/auth"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	"github.com/distr-sh/distr/internal/env"
@@ -67,6 +70,8 @@ func NewRouter(
 	oidcer *oidc.OIDCer,
 	prometheusCollector *prometheus.DistrCollector,
 	logStore logstore.LogStore,
+	s3Client *s3.Client,
+	agentNotifier *agentnotify.Notifier,
 ) http.Handler {
 	baseRouter := chi.NewRouter()
 	baseRouter.Use(
@@ -97,7 +102,9 @@ func NewRouter(
 			Layout:      "responsive",
 		}),
 	)
-	openapiRouter.Route("/api", ApiRouter(logger, db, dbReadonly, mailer, tracers, oidcer, prometheusCollector, logStore))
+	openapiRouter.Route("/api", ApiRouter(
+		logger, db, dbReadonly, mailer, tracers, oidcer, prometheusCollector, logStore, s3Client, agentNotifier,
+	))
 
 	baseRouter.Mount("/internal", InternalRouter())
 	baseRouter.Mount("/status", StatusRouter())
@@ -117,11 +124,27 @@ func ApiRouter(
 	oidcer *oidc.OIDCer,
 	prometheusCollector *prometheus.DistrCollector,
 	logStore logstore.LogStore,
+	s3Client *s3.Client,
+	agentNotifier *agentnotify.Notifier,
 ) func(r chiopenapi.Router) {
 	requestSize1MiB := chimiddleware.RequestSize(1024 * 1024)
 	requestSize10MiB := chimiddleware.RequestSize(10 * 1024 * 1024)
 	requestSize50MiB := chimiddleware.RequestSize(50 * 1024 * 1024)
 
+	authenticated := []func(http.Handler) http.Handler{
+		auth.Authentication.Middleware,
+		middleware.SetSentryUserFromUserAuth,
+		middleware.RequireEmailVerified,
+		httprate.LimitBy(30, 1*time.Second, middleware.RateLimitUserIDKey),
+		httprate.LimitBy(300, 1*time.Minute, middleware.RateLimitUserIDKey),
+		httprate.LimitBy(2000, 1*time.Hour, middleware.RateLimitUserIDKey),
+		auditlog.Middleware,
+
+		// TODO (low-prio) in the future, additionally check token audience and require it to be "api"/"user",
+		// such that agents cant access anything here (they also can't now, because their tokens will not
+		// pass the Authentication chain (DbAuthenticator can't find the user -> 401)
+	}
+
 	return func(r chiopenapi.Router) {
 		r.Use(
 			chimiddleware.RequestID,
@@ -130,11 +153,22 @@ func ApiRouter(
 			middleware.Sentry,
 			middleware.LoggerCtxMiddleware(logger),
 			middleware.LoggingMiddleware,
-			middleware.ContextInjectorMiddleware(db, dbReadonly, mailer, oidcer, prometheusCollector, logStore),
+			middleware.ContextInjectorMiddleware(
+				db, dbReadonly, mailer, oidcer, prometheusCollector, logStore, s3Client, agentNotifier,
+			),
 		)
 
 		r.Route("/public/v1", PublicRouter(tracers))
 
+		// SCIM provisioning for identity providers (authenticated with a SCIM token)
+		r.Group(func(r chiopenapi.Router) {
+			r.Use(
+				middleware.OTEL(tracers.Default()),
+				requestSize1MiB,
+			)
+			r.Route("/scim/v2", handlers.ScimRouter)
+		})
+
 		r.Route("/v1", func(r chiopenapi.Router) {
 			r.Group(func(r chiopenapi.Router) {
 				r.Use(
@@ -146,6 +180,7 @@ func ApiRouter(
 				r.Group(func(r chiopenapi.Router) {
 					r.Route("/auth", handlers.AuthRouter)
 					r.Route("/webhook", handlers.WebhookRouter)
+					r.Route("/workload-identity", handlers.WorkloadIdentityRouter)
 				})
 
 				// authenticated routes go here
@@ -154,25 +189,15 @@ func ApiRouter(
 						option.GroupSecurity("accessToken"),
 						option.GroupSecurity("bearer"),
 					)
-
-					r.Use(
-						auth.Authentication.Middleware,
-						middleware.SetSentryUserFromUserAuth,
-						middleware.RequireEmailVerified,
-						httprate.LimitBy(30, 1*time.Second, middleware.RateLimitUserIDKey),
-						httprate.LimitBy(300, 1*time.Minute, middleware.RateLimitUserIDKey),
-						httprate.LimitBy(2000, 1*time.Hour, middleware.RateLimitUserIDKey),
-
-						// TODO (low-prio) in the future, additionally check token audience and require it to be "api"/"user",
-						// such that agents cant access anything here (they also can't now, because their tokens will not
-						// pass the Authentication chain (DbAuthenticator can't find the user -> 401)
-					)
+					r.Use(authenticated...)
+					r.Route("/admin", handlers.AdminRouter)
 					r.Route("/agent-versions", handlers.AgentVersionsRouter)
 					r.Route("/application-entitlements", handlers.ApplicationEntitlementsRouter)
 					r.Route("/applications", handlers.ApplicationsRouter)
 					r.Route("/artifact-entitlements", handlers.ArtifactEntitlementsRouter)
 					r.With(middleware.UseReadonlyDB).Route("/artifact-pulls", handlers.ArtifactPullsRouter)
 					r.Route("/artifacts", handlers.ArtifactsRouter)
+					r.With(middleware.UseReadonlyDB).Route("/audit-log", handlers.AuditLogRouter)
 					r.Route("/billing", handlers.BillingRouter)
 					r.Route("/context", handlers.ContextRouter)
 					r.Route("/customer-organizations", handlers.CustomerOrganizationsRouter)
@@ -182,6 +207,9 @@ func ApiRouter(
 						Route("/custom-email", handlers.CustomEmailsRouter)
 					r.With(middleware.CustomOidcProvidersFeatureMiddleware).
 						Route("/custom-oidc", handlers.CustomOIDCConfigurationsRouter)
+					r.With(middleware.CustomOidcProvidersFeatureMiddleware).
+						Route("/custom-saml", handlers.CustomSAMLConfigurationsRouter)
+					r.Route("/custom-roles", handlers.CustomRolesRouter)
 					r.With(middleware.PartnerManagementFeatureMiddleware).
 						Route("/partner-organizations", handlers.PartnerOrganizationsRouter)
 					r.With(middleware.UseReadonlyDB).Route("/dashboard", handlers.DashboardRouter)
@@ -194,8 +222,11 @@ func ApiRouter(
 					r.With(middleware.UseReadonlyDB).Route("/notification-records", handlers.NotificationRecordsRouter)
 					r.Route("/organization", handlers.OrganizationRouter)
 					r.Route("/organizations", handlers.OrganizationsRouter)
+					r.With(middleware.CustomOidcProvidersFeatureMiddleware).Route("/scim", handlers.ScimSettingsRouter)
 					r.Route("/secrets", handlers.SecretsRouter)
-					r.Route("/settings", handlers.SettingsRouter)
+					r.Route("/service-accounts", handlers.ServiceAccountsRouter)
+					r.Route("/tasks", handlers.TasksRouter)
+					r.With(middleware.BlockServiceAccount).Route("/settings", handlers.SettingsRouter)
 					r.With(middleware.ProFeature).Route("/support-bundles", handlers.SupportBundlesRouter)
 					r.Route("/tutorial-progress", handlers.TutorialsRouter)
 					r.Route("/license-keys", handlers.LicenseKeysRouter)
@@ -205,6 +236,17 @@ func ApiRouter(
 				})
 			})
 
+			// authenticated routes with large request bodies go here
+			r.Group(func(r chiopenapi.Router) {
+				r.WithOptions(
+					option.GroupSecurity("accessToken"),
+					option.GroupSecurity("bearer"),
+				)
+				r.Use(middleware.OTEL(tracers.Default()))
+				r.Use(authenticated...)
+				r.Route("/organization-imports", handlers.OrganizationImportsRouter)
+			})
+
 			// agent connect and download routes go here (authenticated but with accessKeyId and accessKeySecret)
 			r.Group(func(r chiopenapi.Router) {
 				r.Group(func(r chiopenapi.Router) {
@@ -244,7 +286,7 @@ func CaddyAskRouter(logger *zap.Logger, db *pgxpool.Pool) http.Handler {
 		chimiddleware.RequestID,
 		middleware.Sentry,
 		middleware.LoggerCtxMiddleware(logger),
-		middleware.ContextInjectorMiddleware(db, nil, nil, nil, nil, nil),
+		middleware.ContextInjectorMiddleware(db, nil, nil, nil, nil, nil, nil, nil),
 	)
 	router.Get("/internal/webhook/tls/ask", handlers.TLSAskHandler())
 	return router
@@ -0,0 +1,141 @@
+package saml
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+	xrv "github.com/mattermost/xml-roundtrip-validator"
+)
+
+const (
+	spKeyBits             = 2048
+	spCertificateValidity = 10 * 365 * 24 * time.Hour
+)
+
+var ErrInvalidMetadata = errors.New("invalid SAML metadata")
+
+// ParseMetadata parses the metadata document of an identity provider, which is either a single
+// EntityDescriptor or an EntitiesDescriptor of which the first identity provider is used. The metadata must
+// contain a signing certificate, because assertions are only ever accepted with a valid signature, and a
+// single sign-on service with the HTTP-Redirect binding that logins are started with.
+func ParseMetadata(data []byte) (*saml.EntityDescriptor, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("%w: metadata is empty", ErrInvalidMetadata)
+	}
+	if err := xrv.Validate(bytes.NewReader(data)); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+	}
+
+	var entity *saml.EntityDescriptor
+	var single saml.EntityDescriptor
+	if err := xml.Unmarshal(data, &single); err == nil {
+		entity = &single
+	} else {
+		var entities saml.EntitiesDescriptor
+		if err := xml.Unmarshal(data, &entities); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidMetadata, err)
+		}
+		for i, e := range entities.EntityDescriptors {
+			if len(e.IDPSSODescriptors) > 0 {
+				entity = &entities.EntityDescriptors[i]
+				break
+			}
+		}
+	}
+
+	if entity == nil || len(entity.IDPSSODescriptors) == 0 {
+		return nil, fmt.Errorf("%w: no identity provider found", ErrInvalidMetadata)
+	}
+	if strings.TrimSpace(entity.EntityID) == "" {
+		return nil, fmt.Errorf("%w: entityID is missing", ErrInvalidMetadata)
+	}
+	if ssoLocation(entity) == "" {
+		return nil, fmt.Errorf("%w: no single sign-on service with the HTTP-Redirect binding", ErrInvalidMetadata)
+	}
+	if !hasSigningCertificate(entity) {
+		return nil, fmt.Errorf("%w: no signing certificate", ErrInvalidMetadata)
+	}
+	return entity, nil
+}
+
+func ssoLocation(entity *saml.EntityDescriptor) string {
+	for _, descriptor := range entity.IDPSSODescriptors {
+		for _, service := range descriptor.SingleSignOnServices {
+			if service.Binding == saml.HTTPRedirectBinding {
+				return service.Location
+			}
+		}
+	}
+	return ""
+}
+
+func hasSigningCertificate(entity *saml.EntityDescriptor) bool {
+	for _, descriptor := range entity.IDPSSODescriptors {
+		for _, key := range descriptor.KeyDescriptors {
+			if (key.Use == "" || key.Use == "signing") && len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// GenerateServiceProviderKeyPair creates the self-signed certificate and the private key a service provider
+// configuration is created with, both PEM encoded. Identity providers only use the certificate to encrypt
+// assertions and to verify requests, so it does not need to be issued by a trusted CA.
+func GenerateServiceProviderKeyPair(commonName string) (string, string, error) {
+	key, err := rsa.GenerateKey(rand.Reader, spKeyBits)
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate SAML service provider key: %w", err)
+	}
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate SAML service provider certificate: %w", err)
+	}
+	now := time.Now()
+	template := x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(spCertificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return "", "", fmt.Errorf("could not generate SAML service provider certificate: %w", err)
+	}
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	return string(certificatePEM), string(keyPEM), nil
+}
+
+func parseServiceProviderKeyPair(certificatePEM, keyPEM string) (*x509.Certificate, *rsa.PrivateKey, error) {
+	certificateBlock, _ := pem.Decode([]byte(certificatePEM))
+	if certificateBlock == nil {
+		return nil, nil, errors.New("invalid SAML service provider certificate")
+	}
+	certificate, err := x509.ParseCertificate(certificateBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid SAML service provider certificate: %w", err)
+	}
+	keyBlock, _ := pem.Decode([]byte(keyPEM))
+	if keyBlock == nil {
+		return nil, nil, errors.New("invalid SAML service provider key")
+	}
+	key, err := x509.ParsePKCS1PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid SAML service provider key: %w", err)
+	}
+	return certificate, key, nil
+}
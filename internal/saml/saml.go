@@ -0,0 +1,215 @@
+package saml
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+// defaultEmailAttributes are tried in order when a configuration does not name the attribute that carries the
+// email address. They cover ADFS, Entra ID, Okta and Google Workspace with their default claim rules.
+var defaultEmailAttributes = []string{
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"urn:oid:0.9.2342.19200300.100.1.3",
+	"email",
+	"mail",
+	"Email",
+}
+
+var defaultNameAttributes = []string{
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/name",
+	"http://schemas.microsoft.com/identity/claims/displayname",
+	"urn:oid:2.16.840.1.113730.3.1.241",
+	"displayName",
+	"name",
+}
+
+// Identity is the user identity asserted by an identity provider. Issuer and Subject identify the user at the
+// provider the same way as for OIDC, so SAML identities are stored alongside OIDC identities.
+type Identity struct {
+	Issuer  string
+	Subject string
+	Email   string
+	Name    string
+	// Role is the highest role mapped from the values of the role attribute, or nil if none of them is mapped.
+	Role *types.UserRole
+}
+
+type ServiceProvider struct {
+	sp            saml.ServiceProvider
+	configuration types.CustomSAMLConfiguration
+}
+
+// ServiceProviderForConfiguration builds the service provider of a configuration on the given base URL, which
+// is the scheme and host of the custom domain the configuration is bound to.
+func ServiceProviderForConfiguration(
+	configuration types.CustomSAMLConfiguration,
+	baseURL string,
+	organizationSlug string,
+) (*ServiceProvider, error) {
+	idpMetadata, err := ParseMetadata([]byte(configuration.IDPMetadata))
+	if err != nil {
+		return nil, err
+	}
+	certificate, key, err := parseServiceProviderKeyPair(configuration.SPCertificate, configuration.SPPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	metadataURL, err := url.Parse(MetadataURL(baseURL, organizationSlug, configuration.Slug))
+	if err != nil {
+		return nil, err
+	}
+	acsURL, err := url.Parse(ACSURL(baseURL, organizationSlug, configuration.Slug))
+	if err != nil {
+		return nil, err
+	}
+	return &ServiceProvider{
+		sp: saml.ServiceProvider{
+			EntityID:          metadataURL.String(),
+			Key:               key,
+			Certificate:       certificate,
+			MetadataURL:       *metadataURL,
+			AcsURL:            *acsURL,
+			IDPMetadata:       idpMetadata,
+			AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		},
+		configuration: configuration,
+	}, nil
+}
+
+// AuthnRequestURL returns the URL to redirect the browser to in order to start a login at the identity
+// provider, and the ID of the authentication request that the response has to refer to. The ID is sent as
+// the RelayState as well, which the identity provider posts back along with its response.
+func (p *ServiceProvider) AuthnRequestURL() (string, string, error) {
+	request, err := p.sp.MakeAuthenticationRequest(
+		p.sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("could not create SAML authentication request: %w", err)
+	}
+	redirectURL, err := request.Redirect(request.ID, &p.sp)
+	if err != nil {
+		return "", "", fmt.Errorf("could not create SAML authentication request: %w", err)
+	}
+	return redirectURL.String(), request.ID, nil
+}
+
+// IdentityForResponse verifies the base64 encoded SAMLResponse of the HTTP-POST binding and reads the identity
+// from its assertion. A response has to refer to requestID, unless requestID is empty and the configuration
+// allows IdP-initiated logins. Only signed responses or assertions are accepted.
+func (p *ServiceProvider) IdentityForResponse(samlResponse string, requestID string) (Identity, error) {
+	decoded, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return Identity{}, fmt.Errorf("could not decode SAMLResponse: %w", err)
+	}
+
+	sp := p.sp
+	var possibleRequestIDs []string
+	if requestID != "" {
+		possibleRequestIDs = []string{requestID}
+	} else if p.configuration.AllowIDPInitiated {
+		sp.AllowIDPInitiated = true
+	} else {
+		return Identity{}, errors.New("IdP-initiated login is not allowed")
+	}
+
+	assertion, err := sp.ParseXMLResponse(decoded, possibleRequestIDs, sp.AcsURL)
+	if err != nil {
+		if invalid, ok := errors.AsType[*saml.InvalidResponseError](err); ok {
+			return Identity{}, fmt.Errorf("invalid SAMLResponse: %w", invalid.PrivateErr)
+		}
+		return Identity{}, fmt.Errorf("invalid SAMLResponse: %w", err)
+	}
+	return identityFromAssertion(p.configuration, assertion)
+}
+
+// Metadata returns the metadata document of the service provider, which is uploaded to the identity provider.
+func (p *ServiceProvider) Metadata() ([]byte, error) {
+	data, err := xml.MarshalIndent(p.sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("could not marshal SAML metadata: %w", err)
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+func identityFromAssertion(configuration types.CustomSAMLConfiguration, assertion *saml.Assertion) (Identity, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return Identity{}, errors.New("assertion has no NameID")
+	}
+	nameID := assertion.Subject.NameID
+
+	identity := Identity{
+		Issuer:  assertion.Issuer.Value,
+		Subject: nameID.Value,
+	}
+
+	if configuration.EmailAttribute != nil {
+		identity.Email = firstAttributeValue(assertion, *configuration.EmailAttribute)
+	} else {
+		identity.Email = firstAttributeValue(assertion, defaultEmailAttributes...)
+		if identity.Email == "" && nameID.Format == string(saml.EmailAddressNameIDFormat) {
+			identity.Email = nameID.Value
+		}
+	}
+	identity.Email = strings.TrimSpace(identity.Email)
+	if identity.Email == "" {
+		return Identity{}, errors.New("assertion carries no email address")
+	}
+
+	// A transient NameID changes with every login and cannot identify the user, so the email address is
+	// used instead, which the identity provider vouches for just the same.
+	if nameID.Format == string(saml.TransientNameIDFormat) {
+		identity.Subject = identity.Email
+	}
+
+	if configuration.NameAttribute != nil {
+		identity.Name = firstAttributeValue(assertion, *configuration.NameAttribute)
+	} else {
+		identity.Name = firstAttributeValue(assertion, defaultNameAttributes...)
+	}
+	identity.Name = strings.TrimSpace(identity.Name)
+
+	if configuration.RoleAttribute != nil {
+		for _, value := range attributeValues(assertion, *configuration.RoleAttribute) {
+			if role, ok := configuration.RoleMapping[value]; ok &&
+				(identity.Role == nil || role.GreaterThan(*identity.Role)) {
+				identity.Role = &role
+			}
+		}
+	}
+
+	return identity, nil
+}
+
+func firstAttributeValue(assertion *saml.Assertion, names ...string) string {
+	for _, name := range names {
+		if values := attributeValues(assertion, name); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// attributeValues returns the values of the attribute with the given name or friendly name.
+func attributeValues(assertion *saml.Assertion, name string) []string {
+	var values []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			if attribute.Name != name && attribute.FriendlyName != name {
+				continue
+			}
+			for _, value := range attribute.Values {
+				if v := strings.TrimSpace(value.Value); v != "" && !slices.Contains(values, v) {
+					values = append(values, v)
+				}
+			}
+		}
+	}
+	return values
+}
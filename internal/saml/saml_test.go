@@ -0,0 +1,193 @@
+package saml
+
+import (
+	"encoding/pem"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/crewjam/saml"
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func testIDPMetadata(t *testing.T, use string, binding string) string {
+	t.Helper()
+	certificatePEM, _, err := GenerateServiceProviderKeyPair("idp.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode([]byte(certificatePEM))
+	return fmt.Sprintf(`<?xml version="1.0"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com/saml">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="%v">
+      <ds:KeyInfo xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+        <ds:X509Data><ds:X509Certificate>%v</ds:X509Certificate></ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="%v" Location="https://idp.example.com/saml/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`, use, pemBody(block.Bytes), binding)
+}
+
+func pemBody(der []byte) string {
+	encoded := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	encoded = strings.TrimPrefix(encoded, "-----BEGIN CERTIFICATE-----\n")
+	return strings.TrimSuffix(encoded, "-----END CERTIFICATE-----\n")
+}
+
+func TestParseMetadata(t *testing.T) {
+	g := NewWithT(t)
+
+	entity, err := ParseMetadata([]byte(testIDPMetadata(t, "signing", saml.HTTPRedirectBinding)))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entity.EntityID).To(Equal("https://idp.example.com/saml"))
+	g.Expect(ssoLocation(entity)).To(Equal("https://idp.example.com/saml/sso"))
+
+	wrapped := `<md:EntitiesDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata">` +
+		strings.TrimPrefix(testIDPMetadata(t, "", saml.HTTPRedirectBinding), `<?xml version="1.0"?>`) +
+		`</md:EntitiesDescriptor>`
+	entity, err = ParseMetadata([]byte(wrapped))
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(entity.EntityID).To(Equal("https://idp.example.com/saml"))
+}
+
+func TestParseMetadataInvalid(t *testing.T) {
+	g := NewWithT(t)
+
+	withoutCertificate := strings.ReplaceAll(
+		testIDPMetadata(t, "signing", saml.HTTPRedirectBinding), "X509Certificate>", "X509SubjectName>")
+	invalid := map[string]string{
+		"empty":                "  ",
+		"not XML":              "entityID=https://idp.example.com",
+		"no identity provider": `<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="x"/>`,
+		"encryption key only":  testIDPMetadata(t, "encryption", saml.HTTPRedirectBinding),
+		"no redirect binding":  testIDPMetadata(t, "signing", saml.HTTPPostBinding),
+		"no certificate":       withoutCertificate,
+	}
+	for name, metadata := range invalid {
+		_, err := ParseMetadata([]byte(metadata))
+		g.Expect(err).To(MatchError(ErrInvalidMetadata), name)
+	}
+}
+
+func TestGenerateServiceProviderKeyPair(t *testing.T) {
+	g := NewWithT(t)
+
+	certificatePEM, keyPEM, err := GenerateServiceProviderKeyPair("Acme SSO")
+	g.Expect(err).NotTo(HaveOccurred())
+	certificate, key, err := parseServiceProviderKeyPair(certificatePEM, keyPEM)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(certificate.Subject.CommonName).To(Equal("Acme SSO"))
+	g.Expect(certificate.PublicKey).To(Equal(&key.PublicKey))
+
+	_, _, err = parseServiceProviderKeyPair(certificatePEM, "")
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestServiceProvider(t *testing.T) {
+	g := NewWithT(t)
+
+	certificatePEM, keyPEM, err := GenerateServiceProviderKeyPair("Acme SSO")
+	g.Expect(err).NotTo(HaveOccurred())
+	configuration := types.CustomSAMLConfiguration{
+		Slug:          "acme",
+		IDPMetadata:   testIDPMetadata(t, "signing", saml.HTTPRedirectBinding),
+		SPCertificate: certificatePEM,
+		SPPrivateKey:  keyPEM,
+	}
+	sp, err := ServiceProviderForConfiguration(configuration, "https://portal.example.com", "vendor")
+	g.Expect(err).NotTo(HaveOccurred())
+
+	redirectURL, requestID, err := sp.AuthnRequestURL()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(requestID).NotTo(BeEmpty())
+	parsed, err := url.Parse(redirectURL)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(parsed.Host).To(Equal("idp.example.com"))
+	g.Expect(parsed.Query().Get("RelayState")).To(Equal(requestID))
+	g.Expect(parsed.Query().Get("SAMLRequest")).NotTo(BeEmpty())
+
+	metadata, err := sp.Metadata()
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(string(metadata)).To(
+		ContainSubstring(`entityID="https://portal.example.com/api/v1/auth/saml/vendor/acme/metadata"`))
+	g.Expect(string(metadata)).To(
+		ContainSubstring(`Location="https://portal.example.com/api/v1/auth/saml/vendor/acme/acs"`))
+
+	_, err = sp.IdentityForResponse("not base64!", "")
+	g.Expect(err).To(HaveOccurred())
+	_, err = sp.IdentityForResponse("PHJlc3BvbnNlLz4=", "")
+	g.Expect(err).To(MatchError(ContainSubstring("IdP-initiated login is not allowed")))
+}
+
+func testAssertion(nameIDFormat saml.NameIDFormat, attributes map[string][]string) *saml.Assertion {
+	statement := saml.AttributeStatement{}
+	for name, values := range attributes {
+		attribute := saml.Attribute{Name: name}
+		for _, value := range values {
+			attribute.Values = append(attribute.Values, saml.AttributeValue{Value: value})
+		}
+		statement.Attributes = append(statement.Attributes, attribute)
+	}
+	return &saml.Assertion{
+		Issuer: saml.Issuer{Value: "https://idp.example.com/saml"},
+		Subject: &saml.Subject{
+			NameID: &saml.NameID{Format: string(nameIDFormat), Value: "user-1"},
+		},
+		AttributeStatements: []saml.AttributeStatement{statement},
+	}
+}
+
+func TestIdentityFromAssertion(t *testing.T) {
+	g := NewWithT(t)
+
+	configuration := types.CustomSAMLConfiguration{
+		RoleAttribute: new("groups"),
+		RoleMapping: map[string]types.UserRole{
+			"engineers": types.UserRoleReadWrite,
+			"admins":    types.UserRoleAdmin,
+		},
+	}
+	persistent := testAssertion(saml.PersistentNameIDFormat, map[string][]string{
+		"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress": {" jane@acme.com "},
+		"displayName": {"Jane Doe"},
+		"groups":      {"engineers", "admins", "unmapped"},
+	})
+	identity, err := identityFromAssertion(configuration, persistent)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(identity).To(Equal(Identity{
+		Issuer:  "https://idp.example.com/saml",
+		Subject: "user-1",
+		Email:   "jane@acme.com",
+		Name:    "Jane Doe",
+		Role:    new(types.UserRoleAdmin),
+	}))
+
+	transient := testAssertion(saml.TransientNameIDFormat, map[string][]string{
+		"mail":   {"jane@acme.com"},
+		"groups": {"unmapped"},
+	})
+	identity, err = identityFromAssertion(configuration, transient)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(identity.Subject).To(Equal("jane@acme.com"))
+	g.Expect(identity.Role).To(BeNil())
+}
+
+func TestIdentityFromAssertionEmail(t *testing.T) {
+	g := NewWithT(t)
+
+	emailNameID := testAssertion(saml.EmailAddressNameIDFormat, nil)
+	emailNameID.Subject.NameID.Value = "jane@acme.com"
+	identity, err := identityFromAssertion(types.CustomSAMLConfiguration{}, emailNameID)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(identity.Email).To(Equal("jane@acme.com"))
+
+	// A configured attribute replaces the defaults.
+	configuration := types.CustomSAMLConfiguration{EmailAttribute: new("upn")}
+	_, err = identityFromAssertion(configuration,
+		testAssertion(saml.PersistentNameIDFormat, map[string][]string{"mail": {"jane@acme.com"}}))
+	g.Expect(err).To(MatchError(ContainSubstring("no email address")))
+}
@@ -0,0 +1,30 @@
+package saml
+
+import (
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/env"
+)
+
+// LoginPath identifies a SAML configuration by the slug of its organization and its own slug, the same way as
+// the login path of a custom OIDC provider.
+func LoginPath(organizationSlug, slug string) string {
+	return fmt.Sprintf("/api/v1/auth/saml/%v/%v", organizationSlug, slug)
+}
+
+// MetadataURL is the URL of the service provider metadata, which is also used as the entity ID of the service
+// provider.
+func MetadataURL(baseURL, organizationSlug, slug string) string {
+	return fmt.Sprintf("%v%v/metadata", baseURL, LoginPath(organizationSlug, slug))
+}
+
+// ACSURL is the assertion consumer service URL the identity provider posts its response to.
+func ACSURL(baseURL, organizationSlug, slug string) string {
+	return fmt.Sprintf("%v%v/acs", baseURL, LoginPath(organizationSlug, slug))
+}
+
+// BaseURL is the base URL of the given custom domain, using the scheme of this instance, the same one the
+// login derives from the request.
+func BaseURL(domain string) string {
+	return fmt.Sprintf("%v://%v", env.HostScheme(), domain)
+}
@@ -0,0 +1,33 @@
+package scim
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidFilter = errors.New("invalid filter")
+
+// Filter is an equality filter on a single attribute. Identity providers only use filters of the form
+// `attribute eq "value"` to look up existing resources, so other expressions are not supported.
+type Filter struct {
+	// Attribute is the lowercased name of the filtered attribute.
+	Attribute string
+	Value     string
+}
+
+func ParseFilter(expr string) (*Filter, error) {
+	attribute, rest, ok := strings.Cut(strings.TrimSpace(expr), " ")
+	if !ok {
+		return nil, ErrInvalidFilter
+	}
+	operator, value, ok := strings.Cut(strings.TrimSpace(rest), " ")
+	if !ok || !strings.EqualFold(operator, "eq") {
+		return nil, ErrInvalidFilter
+	}
+	value, err := strconv.Unquote(strings.TrimSpace(value))
+	if err != nil {
+		return nil, ErrInvalidFilter
+	}
+	return &Filter{Attribute: strings.ToLower(attribute), Value: value}, nil
+}
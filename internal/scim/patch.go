@@ -0,0 +1,234 @@
+package scim
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+const (
+	patchOpAdd     = "add"
+	patchOpReplace = "replace"
+	patchOpRemove  = "remove"
+)
+
+// ApplyToUser applies all operations of the request to the user. Attributes that Distr does not store are
+// ignored, because identity providers usually synchronize all attributes they know about.
+func (req PatchRequest) ApplyToUser(user *User) error {
+	for _, op := range req.Operations {
+		if err := applyPatchOperation(op, func(op, path string, value json.RawMessage) error {
+			return applyUserAttribute(user, op, path, value)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ApplyToGroup applies all operations of the request to the group.
+func (req PatchRequest) ApplyToGroup(group *Group) error {
+	for _, op := range req.Operations {
+		if err := applyPatchOperation(op, func(op, path string, value json.RawMessage) error {
+			return applyGroupAttribute(group, op, path, value)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyPatchOperation calls apply for every attribute changed by the operation. An operation without a path
+// carries an object of attributes as value.
+func applyPatchOperation(op PatchOperation, apply func(op, path string, value json.RawMessage) error) error {
+	opName := strings.ToLower(op.Op)
+	if opName != patchOpAdd && opName != patchOpReplace && opName != patchOpRemove {
+		return fmt.Errorf("%w: unsupported operation %q", ErrInvalidPatch, op.Op)
+	}
+	if op.Path != "" {
+		return apply(opName, op.Path, op.Value)
+	}
+	if opName == patchOpRemove {
+		return fmt.Errorf("%w: remove operation requires a path", ErrInvalidPatch)
+	}
+	var attributes map[string]json.RawMessage
+	if err := json.Unmarshal(op.Value, &attributes); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	for path, value := range attributes {
+		if err := apply(opName, path, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func applyUserAttribute(user *User, op, path string, value json.RawMessage) error {
+	switch strings.ToLower(path) {
+	case "active":
+		if op == patchOpRemove {
+			return nil
+		}
+		active, err := unmarshalBool(value)
+		if err != nil {
+			return err
+		}
+		user.Active = &active
+	case "username":
+		if op == patchOpRemove {
+			return fmt.Errorf("%w: userName is required", ErrInvalidPatch)
+		}
+		return unmarshalPatchValue(value, &user.UserName)
+	case "externalid":
+		if op == patchOpRemove {
+			user.ExternalID = nil
+			return nil
+		}
+		return unmarshalPatchValue(value, &user.ExternalID)
+	case "displayname":
+		if op == patchOpRemove {
+			user.DisplayName = ""
+			return nil
+		}
+		return unmarshalPatchValue(value, &user.DisplayName)
+	case "name":
+		if op == patchOpRemove {
+			user.Name = nil
+			return nil
+		}
+		return unmarshalPatchValue(value, &user.Name)
+	case "name.formatted", "name.givenname", "name.familyname":
+		if user.Name == nil {
+			user.Name = &Name{}
+		}
+		var s string
+		if op != patchOpRemove {
+			if err := unmarshalPatchValue(value, &s); err != nil {
+				return err
+			}
+		}
+		switch strings.ToLower(path) {
+		case "name.formatted":
+			user.Name.Formatted = s
+		case "name.givenname":
+			user.Name.GivenName = s
+		case "name.familyname":
+			user.Name.FamilyName = s
+		}
+	case "emails":
+		if op == patchOpRemove {
+			user.Emails = nil
+			return nil
+		}
+		return unmarshalPatchValue(value, &user.Emails)
+	default:
+		// Entra ID addresses the work email with a value filter, e.g. `emails[type eq "work"].value`.
+		if lower := strings.ToLower(path); strings.HasPrefix(lower, "emails[") && strings.HasSuffix(lower, "].value") {
+			if op == patchOpRemove {
+				return nil
+			}
+			var email string
+			if err := unmarshalPatchValue(value, &email); err != nil {
+				return err
+			}
+			user.Emails = []MultiValuedAttribute{{Value: email, Primary: true}}
+		}
+	}
+	return nil
+}
+
+func applyGroupAttribute(group *Group, op, path string, value json.RawMessage) error {
+	lower := strings.ToLower(path)
+	switch {
+	case lower == "displayname":
+		if op == patchOpRemove {
+			return fmt.Errorf("%w: displayName is required", ErrInvalidPatch)
+		}
+		return unmarshalPatchValue(value, &group.DisplayName)
+	case lower == "externalid":
+		if op == patchOpRemove {
+			group.ExternalID = nil
+			return nil
+		}
+		return unmarshalPatchValue(value, &group.ExternalID)
+	case lower == "members":
+		var members []MultiValuedAttribute
+		if len(value) > 0 {
+			if err := unmarshalPatchValue(value, &members); err != nil {
+				return err
+			}
+		}
+		switch op {
+		case patchOpAdd:
+			for _, member := range members {
+				if !slices.Contains(group.MemberValues(), member.Value) {
+					group.Members = append(group.Members, member)
+				}
+			}
+		case patchOpReplace:
+			group.Members = members
+		case patchOpRemove:
+			if len(members) == 0 {
+				group.Members = nil
+			} else {
+				group.removeMembers(func(value string) bool {
+					return slices.ContainsFunc(members, func(m MultiValuedAttribute) bool { return m.Value == value })
+				})
+			}
+		}
+	case strings.HasPrefix(lower, "members["):
+		// e.g. `members[value eq "2819c223-7f76-453a-919d-413861904646"]`
+		filter, err := ParseFilter(strings.TrimSuffix(path[len("members["):], "]"))
+		if err != nil || filter.Attribute != "value" || !strings.HasSuffix(path, "]") {
+			return fmt.Errorf("%w: unsupported path %q", ErrInvalidPatch, path)
+		}
+		if op != patchOpRemove {
+			return fmt.Errorf("%w: unsupported operation %q for path %q", ErrInvalidPatch, op, path)
+		}
+		group.removeMembers(func(value string) bool { return value == filter.Value })
+	}
+	return nil
+}
+
+func (g *Group) removeMembers(match func(value string) bool) {
+	g.Members = slices.DeleteFunc(g.Members, func(m MultiValuedAttribute) bool { return match(m.Value) })
+}
+
+func unmarshalPatchValue(value json.RawMessage, target any) error {
+	if err := json.Unmarshal(value, target); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return nil
+}
+
+// unmarshalBool accepts JSON booleans as well as strings, because Entra ID sends booleans as "True" or "False".
+func unmarshalBool(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	b, err := strconv.ParseBool(strings.ToLower(s))
+	if err != nil {
+		return false, fmt.Errorf("%w: %w", ErrInvalidPatch, err)
+	}
+	return b, nil
+}
@@ -0,0 +1,95 @@
+package scim
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseFilter(t *testing.T) {
+	g := NewWithT(t)
+
+	filter, err := ParseFilter(`userName eq "jane@example.com"`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*filter).To(Equal(Filter{Attribute: "username", Value: "jane@example.com"}))
+
+	filter, err = ParseFilter(`displayName EQ "Distr Admins"`)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*filter).To(Equal(Filter{Attribute: "displayname", Value: "Distr Admins"}))
+
+	for _, expr := range []string{"", "userName", `userName co "jane"`, `userName eq jane`} {
+		_, err := ParseFilter(expr)
+		g.Expect(err).To(MatchError(ErrInvalidFilter), "filter %q", expr)
+	}
+}
+
+func TestNewListResponse(t *testing.T) {
+	g := NewWithT(t)
+	items := []int{1, 2, 3, 4, 5}
+
+	res := NewListResponse(items, 1, -1)
+	g.Expect(res.TotalResults).To(Equal(5))
+	g.Expect(res.ItemsPerPage).To(Equal(5))
+	g.Expect(res.Resources).To(Equal(items))
+
+	res = NewListResponse(items, 2, 2)
+	g.Expect(res.TotalResults).To(Equal(5))
+	g.Expect(res.StartIndex).To(Equal(2))
+	g.Expect(res.Resources).To(Equal([]int{2, 3}))
+
+	res = NewListResponse(items, 10, 2)
+	g.Expect(res.ItemsPerPage).To(Equal(0))
+	g.Expect(res.Resources).To(Equal([]int{}))
+
+	res = NewListResponse(items, 0, 0)
+	g.Expect(res.StartIndex).To(Equal(1))
+	g.Expect(res.Resources).To(Equal([]int{}))
+}
+
+func TestPatchRequestApplyToUser(t *testing.T) {
+	g := NewWithT(t)
+	user := User{UserName: "jane@example.com", Active: new(true)}
+
+	var req PatchRequest
+	g.Expect(json.Unmarshal([]byte(`{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "value": {"displayName": "Jane Doe", "externalId": "abc"}},
+			{"op": "add", "path": "emails[type eq \"work\"].value", "value": "jane.doe@example.com"},
+			{"op": "replace", "path": "title", "value": "Engineer"}
+		]
+	}`), &req)).To(Succeed())
+
+	g.Expect(req.ApplyToUser(&user)).To(Succeed())
+	g.Expect(user.IsActive()).To(BeFalse())
+	g.Expect(user.FullName()).To(Equal("Jane Doe"))
+	g.Expect(user.ExternalID).To(Equal(new("abc")))
+	g.Expect(user.Email()).To(Equal("jane.doe@example.com"))
+
+	g.Expect(PatchRequest{Operations: []PatchOperation{{Op: "move", Path: "active"}}}.ApplyToUser(&user)).
+		To(MatchError(ErrInvalidPatch))
+}
+
+func TestPatchRequestApplyToGroup(t *testing.T) {
+	g := NewWithT(t)
+	group := Group{DisplayName: "Admins", Members: []MultiValuedAttribute{{Value: "a"}, {Value: "b"}}}
+
+	var req PatchRequest
+	g.Expect(json.Unmarshal([]byte(`{
+		"Operations": [
+			{"op": "add", "path": "members", "value": [{"value": "b"}, {"value": "c"}]},
+			{"op": "remove", "path": "members[value eq \"a\"]"},
+			{"op": "replace", "path": "displayName", "value": "Distr Admins"}
+		]
+	}`), &req)).To(Succeed())
+
+	g.Expect(req.ApplyToGroup(&group)).To(Succeed())
+	g.Expect(group.DisplayName).To(Equal("Distr Admins"))
+	g.Expect(group.MemberValues()).To(Equal([]string{"b", "c"}))
+
+	req = PatchRequest{Operations: []PatchOperation{{Op: "remove", Path: "members"}}}
+	g.Expect(req.ApplyToGroup(&group)).To(Succeed())
+	g.Expect(group.Members).To(BeEmpty())
+}
@@ -0,0 +1,57 @@
+// Package securitypolicy enforces the security policy of an organization for logins and sessions.
+package securitypolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/types"
+)
+
+// ErrViolation is returned when the security policy of the organization does not allow a login or the usage of a
+// session. The wrapping error explains why.
+var ErrViolation = errors.New("not allowed by the security policy of the organization")
+
+// CheckLogin checks that a user may sign in to the organization with the given method from the given address.
+// It is checked again for every request of the session, so that a policy also applies to existing sessions.
+func CheckLogin(
+	ctx context.Context,
+	org types.Organization,
+	method types.SessionAuthMethod,
+	remoteAddress string,
+) error {
+	policy := org.SecurityPolicy
+	if err := CheckAddress(org, remoteAddress); err != nil {
+		return err
+	}
+	if policy.DisablePasswordLogin && method == types.SessionAuthMethodPassword {
+		// The identity provider could have been removed since the policy was set, which must not lock out
+		// everyone who has a password.
+		if exists, err := db.ExistsEnabledCustomSSOConfigurationForOrganization(ctx, org.ID); err != nil {
+			return err
+		} else if exists {
+			return fmt.Errorf("%w: sign in with the identity provider of your organization", ErrViolation)
+		}
+	}
+	return nil
+}
+
+// CheckAddress checks the IP allowlist of the organization, which applies to logins and access tokens alike.
+func CheckAddress(org types.Organization, remoteAddress string) error {
+	if !org.SecurityPolicy.AllowsAddress(remoteAddress) {
+		return fmt.Errorf("%w: your IP address is not allowed", ErrViolation)
+	}
+	return nil
+}
+
+// CheckIdle checks that the session was used within the idle timeout of the organization.
+func CheckIdle(org types.Organization, session types.UserSession) error {
+	if timeout := org.SecurityPolicy.SessionIdleTimeout; timeout != nil &&
+		time.Since(session.LastSeenAt) > time.Duration(*timeout) {
+		return fmt.Errorf("%w: the session was idle for too long", ErrViolation)
+	}
+	return nil
+}
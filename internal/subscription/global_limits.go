@@ -42,6 +42,9 @@ const (
 	// rows are collected in memory before writing, so the cap is much lower.
 	MaxArtifactPullExportRows limit.Limit = 10_000
 
+	// MaxAuditLogExportRows caps the audit log export, which is collected in memory like the artifact pull export.
+	MaxAuditLogExportRows limit.Limit = 10_000
+
 	LogQueryWindowCommunity = 24 * time.Hour
 	LogQueryWindowBusiness  = 30 * 24 * time.Hour
 	LogQueryWindowDefault   = 7 * 24 * time.Hour
@@ -0,0 +1,184 @@
+// Package superadmin lets the operators of a Distr instance inspect and fix the organizations and users of all
+// tenants. It backs the admin API as well as the admin commands of the hub CLI.
+package superadmin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/limit"
+	"github.com/distr-sh/distr/internal/subscription"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/distr-sh/distr/internal/userauth"
+	"github.com/google/uuid"
+)
+
+// ErrOrganizationDeleted is returned for changes to a soft-deleted organization, which must be restored first.
+var ErrOrganizationDeleted = apierrors.NewConflict("the organization is deleted")
+
+// Subscription is what super admins can change about the subscription of an organization.
+type Subscription struct {
+	Type                    types.SubscriptionType
+	Period                  types.SubscriptionPeriod
+	EndsAt                  time.Time
+	CustomerOrganizationQty limit.Limit
+	UserAccountQty          limit.Limit
+}
+
+func (s Subscription) Validate() error {
+	if !slices.Contains(types.AllSubscriptionTypes(), s.Type) {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid subscription type: %q", s.Type))
+	}
+	if s.Period != types.SubscriptionPeriodMonthly && s.Period != types.SubscriptionPeriodYearly {
+		return apierrors.NewBadRequest(fmt.Sprintf("invalid subscription period: %q", s.Period))
+	}
+	if s.EndsAt.IsZero() {
+		return apierrors.NewBadRequest("subscription end is required")
+	}
+	return nil
+}
+
+// UpdateSubscription changes the subscription type and limits of an organization. Like a change of the plan in
+// billing, it grants the features of the new subscription type, and like the edition reconciliation, it revokes the
+// features of paid plans when the new type is not one.
+//
+// On instances with an enterprise license that enforces its limits, the next start of the hub resets the
+// subscriptions of all organizations to the license.
+func UpdateSubscription(
+	ctx context.Context,
+	orgID uuid.UUID,
+	s Subscription,
+) (before, after *types.OrganizationOverview, err error) {
+	if err := s.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return updateOrganization(ctx, orgID, func(org *types.Organization) {
+		org.SubscriptionType = s.Type
+		org.SubscriptionPeriod = s.Period
+		org.SubscriptionEndsAt = s.EndsAt
+		org.SubscriptionCustomerOrganizationQty = s.CustomerOrganizationQty
+		org.SubscriptionUserAccountQty = s.UserAccountQty
+		if s.Type.IsPro() {
+			org.AddFeatures(types.FeaturesForSubscriptionType(s.Type)...)
+		} else {
+			org.RemoveFeatures(types.PlanManagedFeatures...)
+		}
+	})
+}
+
+// SetFeatures grants or revokes features of an organization, regardless of its subscription.
+func SetFeatures(
+	ctx context.Context,
+	orgID uuid.UUID,
+	features []types.Feature,
+	enabled bool,
+) (before, after *types.OrganizationOverview, err error) {
+	for _, feature := range features {
+		if !slices.Contains(types.AllFeatures(), feature) {
+			return nil, nil, apierrors.NewBadRequest(fmt.Sprintf("invalid feature: %q", feature))
+		}
+	}
+	return updateOrganization(ctx, orgID, func(org *types.Organization) {
+		for _, feature := range features {
+			org.SetFeature(feature, enabled)
+		}
+	})
+}
+
+func updateOrganization(
+	ctx context.Context,
+	orgID uuid.UUID,
+	update func(org *types.Organization),
+) (before, after *types.OrganizationOverview, err error) {
+	err = db.RunTx(ctx, func(ctx context.Context) error {
+		if before, err = db.GetOrganizationOverview(ctx, orgID); err != nil {
+			return err
+		} else if before.DeletedAt != nil {
+			return ErrOrganizationDeleted
+		}
+		after = new(*before)
+		after.Features = slices.Clone(before.Features)
+		update(&after.Organization)
+		return db.UpdateOrganization(ctx, &after.Organization)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return before, after, nil
+}
+
+// DeleteOrganization soft-deletes an organization, just like its admins can. The Organization cleanup job purges it
+// once CLEANUP_ORGANIZATION_MIN_AGE has passed, until then it can be restored.
+func DeleteOrganization(ctx context.Context, orgID uuid.UUID) (*types.OrganizationOverview, error) {
+	var org *types.OrganizationOverview
+	err := db.RunTx(ctx, func(ctx context.Context) (err error) {
+		if org, err = db.GetOrganizationOverview(ctx, orgID); err != nil {
+			return err
+		} else if org.DeletedAt != nil {
+			return ErrOrganizationDeleted
+		}
+		return db.SetOrganizationDeletedAtNow(ctx, orgID)
+	})
+	return org, err
+}
+
+// RestoreOrganization restores a soft-deleted organization that was not purged yet. It fails with
+// subscription.ErrGlobalOrganizationLimitReached when the license does not allow another organization.
+func RestoreOrganization(ctx context.Context, orgID uuid.UUID) (*types.OrganizationOverview, error) {
+	var org *types.OrganizationOverview
+	err := db.RunTx(ctx, func(ctx context.Context) (err error) {
+		if org, err = db.GetOrganizationOverview(ctx, orgID); err != nil {
+			return err
+		} else if org.DeletedAt == nil {
+			return apierrors.NewConflict("the organization is not deleted")
+		} else if reached, err := subscription.IsGlobalOrganizationLimitReached(ctx); err != nil {
+			return err
+		} else if reached {
+			return subscription.ErrGlobalOrganizationLimitReached
+		}
+		return db.RestoreOrganization(ctx, orgID)
+	})
+	return org, err
+}
+
+// ResetMFA disables the second factor of a user who lost it, removes their recovery codes and revokes their
+// sessions. The user can sign in with their password afterwards and set up MFA again.
+func ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	return db.RunTx(ctx, func(ctx context.Context) error {
+		if err := db.DisableUserAccountMFA(ctx, userID); err != nil {
+			return err
+		} else if err := db.DeleteAllMFARecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+		return userauth.RevokeSessions(ctx, userID, nil)
+	})
+}
+
+// Impersonate starts a session in which the super admin acts as a member of an organization. The session is
+// short-lived, bound to the organization, and every request made with it is attributed to the super admin in the
+// audit log of the organization.
+func Impersonate(
+	ctx context.Context,
+	superAdmin types.UserAccount,
+	userID, orgID uuid.UUID,
+	client userauth.Client,
+) (string, error) {
+	user, err := db.GetUserAccountByID(ctx, userID)
+	if err != nil {
+		return "", err
+	} else if user.IsSuperAdmin {
+		return "", apierrors.NewBadRequest("super admins cannot be impersonated")
+	} else if user.ServiceAccountOrganizationID != nil {
+		return "", apierrors.NewBadRequest("service accounts cannot be impersonated")
+	}
+	token, err := userauth.GenerateImpersonationToken(ctx, *user, orgID, superAdmin, client)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		return "", fmt.Errorf("%w: the user is not a member of the organization", apierrors.ErrNotFound)
+	}
+	return token, err
+}
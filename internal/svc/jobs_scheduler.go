@@ -15,11 +15,24 @@ func (r *Registry) GetJobsScheduler() *jobs.Scheduler {
 }
 
 func (r *Registry) createJobsScheduler() (*jobs.Scheduler, error) {
-	scheduler, err := jobs.NewScheduler(r.GetLogger(), r.GetDbPool(), r.GetMailer(), r.GetTracers().Always(), r.s3Client)
+	scheduler, err := jobs.NewScheduler(
+		r.GetLogger(),
+		r.GetDbPool(),
+		r.GetMailer(),
+		r.GetTracers().Always(),
+		r.s3Client,
+		env.TaskWorkerConcurrency(),
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	scheduler.RegisterTaskFunc(upstream.SyncArtifactTaskType, jobs.NewTaskFunc(upstream.RunSyncArtifactTask))
+	scheduler.RegisterTaskFunc(notification.DeploymentStatusTaskType,
+		jobs.NewTaskFunc(notification.RunDeploymentStatusTask))
+	scheduler.RegisterTaskFunc(notification.DeploymentTargetMetricsTaskType,
+		jobs.NewTaskFunc(notification.RunDeploymentTargetMetricsTask))
+
 	if cron := env.CleanupDeploymenRevisionStatusCron(); cron != nil {
 		err = scheduler.RegisterCronJob(
 			*cron,
@@ -78,6 +91,46 @@ func (r *Registry) createJobsScheduler() (*jobs.Scheduler, error) {
 		}
 	}
 
+	if cron := env.CleanupAuditLogCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("AuditLogCleanup", cleanup.RunAuditLogCleanup, env.CleanupAuditLogTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cron := env.CleanupUserSessionCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("UserSessionCleanup", cleanup.RunUserSessionCleanup, env.CleanupUserSessionTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cron := env.CleanupJobRunCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("JobRunCleanup", cleanup.RunJobRunCleanup, env.CleanupJobRunTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if cron := env.CleanupTaskCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob("TaskCleanup", cleanup.RunTaskCleanup, env.CleanupTaskTimeout()),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if cron := env.DeploymentStatusNotificationCron(); cron != nil {
 		err = scheduler.RegisterCronJob(
 			*cron,
@@ -92,6 +145,20 @@ func (r *Registry) createJobsScheduler() (*jobs.Scheduler, error) {
 		}
 	}
 
+	if cron := env.LicenseKeyExpiryNotificationCron(); cron != nil {
+		err = scheduler.RegisterCronJob(
+			*cron,
+			jobs.NewJob(
+				"LicenseKeyExpiryNotification",
+				notification.RunLicenseKeyExpirationNotifications,
+				env.LicenseKeyExpiryNotificationTimeout(),
+			),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if cron := env.RegistryUpstreamSyncCron(); cron != nil {
 		err = scheduler.RegisterCronJob(
 			*cron,
@@ -10,6 +10,7 @@ import (
 	"syscall"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/distr-sh/distr/internal/agentnotify"
 	"github.com/distr-sh/distr/internal/buildconfig"
 	internalctx "github.com/distr-sh/distr/internal/context"
 	"github.com/distr-sh/distr/internal/env"
@@ -44,6 +45,7 @@ type Registry struct {
 	promCollector     *distrprometheus.DistrCollector
 	s3Client          *s3.Client
 	logStore          logstore.LogStore
+	agentNotifier     *agentnotify.Notifier
 }
 
 func New(ctx context.Context, options ...RegistryOption) (*Registry, error) {
@@ -72,6 +74,7 @@ func newRegistry(ctx context.Context, reg *Registry) (*Registry, error) {
 
 	reg.promCollector = distrprometheus.NewDistrCollector()
 	reg.promRegistry = createPrometheusRegistry(reg.promCollector)
+	reg.agentNotifier = agentnotify.New(reg.logger.With(zap.String("component", "agentnotify")))
 
 	if tracers, err := reg.createTracer(ctx); err != nil {
 		return nil, err
@@ -184,6 +187,8 @@ func (r *Registry) GetRouter() http.Handler {
 		r.GetOIDCer(),
 		r.GetPrometheusCollector(),
 		r.GetLogStore(),
+		r.s3Client,
+		r.agentNotifier,
 	)
 }
 
@@ -264,3 +269,7 @@ func (r *Registry) GetPrometheusCollector() *distrprometheus.DistrCollector {
 func (r *Registry) GetS3Client() *s3.Client {
 	return r.s3Client
 }
+
+func (r *Registry) GetAgentNotifier() *agentnotify.Notifier {
+	return r.agentNotifier
+}
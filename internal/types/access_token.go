@@ -8,15 +8,19 @@ import (
 )
 
 type AccessToken struct {
-	ID             uuid.UUID   `db:"id"`
-	CreatedAt      time.Time   `db:"created_at"`
-	ExpiresAt      *time.Time  `db:"expires_at"`
-	LastUsedAt     *time.Time  `db:"last_used_at"`
-	Label          *string     `db:"label"`
-	Key            authkey.Key `db:"key"`
-	UserAccountID  uuid.UUID   `db:"user_account_id"`
-	OrganizationID uuid.UUID   `db:"organization_id"`
-	UserRole       *UserRole   `db:"token_user_role"`
+	ID               uuid.UUID         `db:"id"`
+	CreatedAt        time.Time         `db:"created_at"`
+	ExpiresAt        *time.Time        `db:"expires_at"`
+	LastUsedAt       *time.Time        `db:"last_used_at"`
+	LastUsedIP       *string           `db:"last_used_ip"`
+	Label            *string           `db:"label"`
+	Key              authkey.Key       `db:"key"`
+	UserAccountID    uuid.UUID         `db:"user_account_id"`
+	OrganizationID   uuid.UUID         `db:"organization_id"`
+	UserRole         *UserRole         `db:"token_user_role"`
+	CustomRoleID     *uuid.UUID        `db:"token_custom_role_id"`
+	Scopes           AccessTokenScopes `db:"scopes"`
+	UnusedExpiryDays *int              `db:"unused_expiry_days"`
 }
 
 func (tok AccessToken) HasExpired() bool {
@@ -27,6 +31,7 @@ type AccessTokenWithUserAccount struct {
 	AccessToken
 	UserAccount            UserAccount `db:"user_account"`
 	UserRole               UserRole    `db:"user_role"`
+	CustomRoleID           *uuid.UUID  `db:"custom_role_id"`
 	CustomerOrganizationID *uuid.UUID  `db:"customer_organization_id"`
 }
 
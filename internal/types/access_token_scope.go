@@ -0,0 +1,76 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AccessTokenScope restricts an access token to an action on a resource and is written like a Permission, as
+// "resource:action". Scopes on artifacts can be restricted further to a single artifact, written as
+// "artifacts:action:name".
+type AccessTokenScope string
+
+func ParseAccessTokenScope(value string) (AccessTokenScope, error) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) < 2 {
+		return "", fmt.Errorf("invalid scope: %q", value)
+	} else if _, err := ParsePermission(parts[0] + ":" + parts[1]); err != nil {
+		return "", fmt.Errorf("invalid scope: %q", value)
+	} else if len(parts) == 3 && Resource(parts[0]) != ResourceArtifacts {
+		return "", fmt.Errorf("invalid scope: %q, only artifacts scopes can be restricted by name", value)
+	} else if len(parts) == 3 && parts[2] == "" {
+		return "", fmt.Errorf("invalid scope: %q, the artifact name must not be empty", value)
+	}
+	return AccessTokenScope(value), nil
+}
+
+// Permission returns the permission that the scope grants, regardless of a restriction to a single artifact.
+func (s AccessTokenScope) Permission() Permission {
+	resource, rest, _ := strings.Cut(string(s), ":")
+	action, _, _ := strings.Cut(rest, ":")
+	return NewPermission(Resource(resource), Action(action))
+}
+
+// ArtifactName returns the name of the artifact that the scope is restricted to, if any.
+func (s AccessTokenScope) ArtifactName() string {
+	parts := strings.SplitN(string(s), ":", 3)
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+func (s *AccessTokenScope) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	} else if scope, err := ParseAccessTokenScope(value); err != nil {
+		return err
+	} else {
+		*s = scope
+		return nil
+	}
+}
+
+// AccessTokenScopes are the scopes of an access token. A token without scopes is not restricted by them.
+type AccessTokenScopes []AccessTokenScope
+
+// Permissions returns the permissions granted by the scopes. Like the permissions of a custom role, they are only
+// an upper bound for the permissions of the user.
+func (s AccessTokenScopes) Permissions() PermissionSet {
+	permissions := make([]Permission, 0, len(s))
+	for _, scope := range s {
+		permissions = append(permissions, scope.Permission())
+	}
+	return NewPermissionSet(permissions...)
+}
+
+// AllowsArtifact reports whether the scopes allow action on the artifact with the given name.
+func (s AccessTokenScopes) AllowsArtifact(name string, action Action) bool {
+	return len(s) == 0 || slices.ContainsFunc(s, func(scope AccessTokenScope) bool {
+		return PermissionSet{scope.Permission()}.Has(ResourceArtifacts, action) &&
+			(scope.ArtifactName() == "" || scope.ArtifactName() == name)
+	})
+}
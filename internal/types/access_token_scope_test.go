@@ -0,0 +1,63 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseAccessTokenScope(t *testing.T) {
+	g := NewWithT(t)
+
+	valid := []string{"deployments:read", "license_keys:write", "artifacts:write:myapp", "artifacts:read:a/b"}
+	for _, value := range valid {
+		scope, err := ParseAccessTokenScope(value)
+		g.Expect(err).NotTo(HaveOccurred())
+		g.Expect(scope).To(Equal(AccessTokenScope(value)))
+	}
+
+	invalid := []string{"", "deployments", "deployments:push", "unknown:read", "deployments:read:x", "artifacts:write:"}
+	for _, value := range invalid {
+		_, err := ParseAccessTokenScope(value)
+		g.Expect(err).To(HaveOccurred(), value)
+	}
+
+	scope := AccessTokenScope("artifacts:write:myapp")
+	g.Expect(scope.Permission()).To(Equal(NewPermission(ResourceArtifacts, ActionWrite)))
+	g.Expect(scope.ArtifactName()).To(Equal("myapp"))
+	g.Expect(AccessTokenScope("artifacts:read").ArtifactName()).To(BeEmpty())
+
+	var scopes AccessTokenScopes
+	g.Expect(json.Unmarshal([]byte(`["artifacts:write:myapp","deployments:read"]`), &scopes)).To(Succeed())
+	g.Expect(json.Unmarshal([]byte(`["deployments:read:x"]`), &scopes)).NotTo(Succeed())
+}
+
+func TestAccessTokenScopesPermissions(t *testing.T) {
+	g := NewWithT(t)
+
+	scopes := AccessTokenScopes{"artifacts:read", "artifacts:write:myapp", "license_keys:write"}
+	g.Expect(scopes.Permissions()).To(Equal(PermissionSet{
+		NewPermission(ResourceArtifacts, ActionWrite),
+		NewPermission(ResourceLicenseKeys, ActionWrite),
+	}))
+	g.Expect(AccessTokenScopes(nil).Permissions()).To(BeEmpty())
+}
+
+func TestAccessTokenScopesAllowsArtifact(t *testing.T) {
+	g := NewWithT(t)
+
+	// Tokens without scopes are not restricted.
+	g.Expect(AccessTokenScopes(nil).AllowsArtifact("myapp", ActionWrite)).To(BeTrue())
+
+	scopes := AccessTokenScopes{"artifacts:read", "artifacts:write:myapp"}
+	g.Expect(scopes.AllowsArtifact("myapp", ActionWrite)).To(BeTrue())
+	g.Expect(scopes.AllowsArtifact("other", ActionRead)).To(BeTrue())
+	g.Expect(scopes.AllowsArtifact("other", ActionWrite)).To(BeFalse())
+
+	scopes = AccessTokenScopes{"artifacts:write:myapp", "deployments:read"}
+	g.Expect(scopes.AllowsArtifact("myapp", ActionRead)).To(BeTrue())
+	g.Expect(scopes.AllowsArtifact("other", ActionRead)).To(BeFalse())
+
+	g.Expect(AccessTokenScopes{"deployments:read"}.AllowsArtifact("myapp", ActionRead)).To(BeFalse())
+}
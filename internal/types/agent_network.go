@@ -0,0 +1,91 @@
+package types
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+// DeploymentTargetAgentNetwork holds the network settings that the agent of a deployment target needs to reach the
+// hub, like a proxy or the CA of a TLS inspecting firewall. They are rendered into the agent manifest.
+type DeploymentTargetAgentNetwork struct {
+	DeploymentTargetID uuid.UUID `db:"deployment_target_id"`
+	UpdatedAt          time.Time `db:"updated_at"`
+	ProxyURL           *string   `db:"proxy_url"`
+	ProxyUsername      *string   `db:"proxy_username"`
+	ProxyPassword      *string   `db:"proxy_password"`
+	NoProxy            *string   `db:"no_proxy"`
+	// CACertificates is a PEM bundle of certificates that the agent trusts in addition to the system CAs.
+	CACertificates *string `db:"ca_certificates"`
+	// ClientCertificate and ClientKey are PEM encoded and presented by the agent if a server asks for a client
+	// certificate.
+	ClientCertificate *string `db:"client_certificate"`
+	ClientKey         *string `db:"client_key"`
+}
+
+func (n *DeploymentTargetAgentNetwork) Validate() error {
+	if n.ProxyURL != nil {
+		if u, err := url.Parse(*n.ProxyURL); err != nil {
+			return validation.NewValidationFailedError(fmt.Sprintf("invalid proxy URL: %v", err))
+		} else if u.Scheme != "http" && u.Scheme != "https" {
+			return validation.NewValidationFailedError("proxy URL must have scheme \"http\" or \"https\"")
+		} else if u.Host == "" {
+			return validation.NewValidationFailedError("proxy URL must have a host")
+		} else if u.User != nil {
+			return validation.NewValidationFailedError("proxy URL must not contain credentials")
+		}
+	} else if n.ProxyUsername != nil {
+		return validation.NewValidationFailedError("proxy username requires a proxy URL")
+	}
+	if n.ProxyPassword != nil && n.ProxyUsername == nil {
+		return validation.NewValidationFailedError("proxy password requires a proxy username")
+	}
+	if n.NoProxy != nil && strings.ContainsFunc(*n.NoProxy, func(r rune) bool { return r == '\n' || r == '\r' }) {
+		return validation.NewValidationFailedError("no proxy must not contain line breaks")
+	}
+	if n.CACertificates != nil && !x509.NewCertPool().AppendCertsFromPEM([]byte(*n.CACertificates)) {
+		return validation.NewValidationFailedError("CA certificates must contain at least one PEM encoded certificate")
+	}
+	if (n.ClientCertificate == nil) != (n.ClientKey == nil) {
+		return validation.NewValidationFailedError("client certificate and client key must be set together")
+	} else if n.ClientCertificate != nil {
+		if _, err := tls.X509KeyPair([]byte(*n.ClientCertificate), []byte(*n.ClientKey)); err != nil {
+			return validation.NewValidationFailedError(fmt.Sprintf("invalid client certificate: %v", err))
+		}
+	}
+	return nil
+}
+
+// KeepsProxyPasswordOf returns whether n, which was saved without a proxy password, keeps the one of previous. The
+// password must be sent again if the proxy username or URL changes, so that whoever can edit the settings cannot send
+// the stored password to a proxy of their own.
+func (n *DeploymentTargetAgentNetwork) KeepsProxyPasswordOf(previous DeploymentTargetAgentNetwork) bool {
+	return n.ProxyPassword == nil && previous.ProxyPassword != nil &&
+		n.ProxyUsername != nil && util.PtrEq(n.ProxyUsername, previous.ProxyUsername) &&
+		util.PtrEq(n.ProxyURL, previous.ProxyURL)
+}
+
+// ProxyURLWithCredentials returns the proxy URL including the username and password, as it is expected in the
+// HTTPS_PROXY environment variable, or an empty string if there is no proxy.
+func (n *DeploymentTargetAgentNetwork) ProxyURLWithCredentials() (string, error) {
+	if n.ProxyURL == nil {
+		return "", nil
+	}
+	u, err := url.Parse(*n.ProxyURL)
+	if err != nil {
+		return "", err
+	}
+	if n.ProxyUsername != nil && n.ProxyPassword != nil {
+		u.User = url.UserPassword(*n.ProxyUsername, *n.ProxyPassword)
+	} else if n.ProxyUsername != nil {
+		u.User = url.User(*n.ProxyUsername)
+	}
+	return u.String(), nil
+}
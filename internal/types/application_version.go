@@ -7,30 +7,39 @@ import (
 
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
+	"oras.land/oras-go/v2/registry"
 )
 
 type ApplicationVersion struct {
 	// unfortunately Base nested type doesn't work when ApplicationVersion is a nested row in an SQL query
-	ID            uuid.UUID      `db:"id" json:"id"`
-	CreatedAt     time.Time      `db:"created_at" json:"createdAt"`
-	ArchivedAt    *time.Time     `db:"archived_at" json:"archivedAt,omitempty"`
-	Name          string         `db:"name" json:"name"`
-	LinkTemplate  string         `db:"link_template" json:"linkTemplate"`
-	ApplicationID uuid.UUID      `db:"application_id" json:"applicationId"`
-	ChartType     *HelmChartType `db:"chart_type" json:"chartType,omitempty"`
-	ChartName     *string        `db:"chart_name" json:"chartName,omitempty"`
-	ChartUrl      *string        `db:"chart_url" json:"chartUrl,omitempty"`
-	ChartVersion  *string        `db:"chart_version" json:"chartVersion,omitempty"`
+	ID            uuid.UUID  `db:"id" json:"id"`
+	CreatedAt     time.Time  `db:"created_at" json:"createdAt"`
+	ArchivedAt    *time.Time `db:"archived_at" json:"archivedAt,omitempty"`
+	Name          string     `db:"name" json:"name"`
+	LinkTemplate  string     `db:"link_template" json:"linkTemplate"`
+	ApplicationID uuid.UUID  `db:"application_id" json:"applicationId"`
+	// SourceType tells whether a Kubernetes application version is a Helm chart or a manifest bundle.
+	SourceType   *KubernetesSourceType `db:"source_type" json:"sourceType,omitempty"`
+	ChartType    *HelmChartType        `db:"chart_type" json:"chartType,omitempty"`
+	ChartName    *string               `db:"chart_name" json:"chartName,omitempty"`
+	ChartUrl     *string               `db:"chart_url" json:"chartUrl,omitempty"`
+	ChartVersion *string               `db:"chart_version" json:"chartVersion,omitempty"`
+	// PackageRef is the OCI reference of the tarball that a systemd application version installs or of the module
+	// that an OpenTofu application version applies.
+	PackageRef *string `db:"package_ref" json:"packageRef,omitempty"`
 
 	// awful but relevant: the following must be defined after the ChartType, because somehow order matters
 	// for pgx at collecting the subrows (relevant at getting application + list of its versions with these
 	// array aggregations) – long term it should probably be refactored because this is such a pitfall
 	// https://github.com/jackc/pgx/issues/1585#issuecomment-1528810634
-	ValuesFileData   []byte `db:"values_file_data" json:"-"`
-	TemplateFileData []byte `db:"template_file_data" json:"-"`
-	ComposeFileData  []byte `db:"compose_file_data" json:"-"`
+	ValuesFileData     []byte `db:"values_file_data" json:"-"`
+	TemplateFileData   []byte `db:"template_file_data" json:"-"`
+	ComposeFileData    []byte `db:"compose_file_data" json:"-"`
+	ManifestBundleData []byte `db:"manifest_bundle_data" json:"-"`
+	UnitFileData       []byte `db:"unit_file_data" json:"-"`
 
-	Resources []ApplicationVersionResource `db:"-" json:"resources,omitempty"`
+	Resources   []ApplicationVersionResource   `db:"-" json:"resources,omitempty"`
+	HealthRules []ApplicationVersionHealthRule `db:"-" json:"healthRules,omitempty"`
 }
 
 func (av ApplicationVersion) ParsedValuesFile() (result map[string]any, err error) {
@@ -60,25 +69,79 @@ func (av ApplicationVersion) ParsedComposeFile() (result map[string]any, err err
 	return result, err
 }
 
+func (av ApplicationVersion) IsKustomize() bool {
+	return av.SourceType != nil && *av.SourceType == KubernetesSourceTypeKustomize
+}
+
 func (av ApplicationVersion) Validate(deplType DeploymentType) error {
 	switch deplType {
 	case DeploymentTypeDocker:
 		if av.ComposeFileData == nil {
 			return errors.New("missing compose file")
-		} else if av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil || av.ChartVersion != nil ||
-			av.ValuesFileData != nil {
+		} else if av.SourceType != nil || av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil ||
+			av.ChartVersion != nil || av.ValuesFileData != nil || av.ManifestBundleData != nil || len(av.HealthRules) > 0 {
 			return errors.New("unexpected kubernetes specifics in docker application")
+		} else if av.PackageRef != nil || av.UnitFileData != nil {
+			return errors.New("unexpected systemd or OpenTofu specifics in docker application")
 		}
 	case DeploymentTypeKubernetes:
-		if av.ChartType == nil || *av.ChartType == "" ||
+		if av.IsKustomize() {
+			if av.ManifestBundleData == nil {
+				return errors.New("missing manifest bundle")
+			} else if av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil || av.ChartVersion != nil ||
+				av.ValuesFileData != nil {
+				return errors.New("unexpected helm chart in kustomize application version")
+			} else if av.ComposeFileData != nil {
+				return errors.New("unexpected docker file in kubernetes application")
+			}
+		} else if av.SourceType == nil || *av.SourceType != KubernetesSourceTypeHelm {
+			return errors.New("invalid source type")
+		} else if av.ChartType == nil || *av.ChartType == "" ||
 			av.ChartUrl == nil || *av.ChartUrl == "" ||
 			av.ChartVersion == nil || *av.ChartVersion == "" {
 			return errors.New("not all of chart type, url and version are given")
 		} else if *av.ChartType == HelmChartTypeRepository && (av.ChartName == nil || *av.ChartName == "") {
 			return errors.New("missing chart name")
-		} else if av.ComposeFileData != nil {
-			return errors.New("unexpected docker file in kubernetes application")
+		} else if av.ComposeFileData != nil || av.ManifestBundleData != nil {
+			return errors.New("unexpected docker file or manifest bundle in kubernetes application")
+		}
+		if av.PackageRef != nil || av.UnitFileData != nil {
+			return errors.New("unexpected systemd or OpenTofu specifics in kubernetes application")
+		}
+		for _, rule := range av.HealthRules {
+			if err := rule.Validate(); err != nil {
+				return err
+			}
+		}
+	case DeploymentTypeSystemd:
+		if err := av.validatePackageRef(); err != nil {
+			return err
+		} else if av.UnitFileData == nil {
+			return errors.New("missing unit file")
+		} else if av.SourceType != nil || av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil ||
+			av.ChartVersion != nil || av.ComposeFileData != nil || av.ManifestBundleData != nil || len(av.HealthRules) > 0 {
+			return errors.New("unexpected docker or kubernetes specifics in systemd application")
 		}
+	case DeploymentTypeOpenTofu:
+		if err := av.validatePackageRef(); err != nil {
+			return err
+		} else if av.SourceType != nil || av.ChartType != nil || av.ChartName != nil || av.ChartUrl != nil ||
+			av.ChartVersion != nil || av.ComposeFileData != nil || av.ManifestBundleData != nil || len(av.HealthRules) > 0 {
+			return errors.New("unexpected docker or kubernetes specifics in OpenTofu application")
+		} else if av.UnitFileData != nil {
+			return errors.New("unexpected unit file in OpenTofu application")
+		}
+	}
+	return nil
+}
+
+func (av ApplicationVersion) validatePackageRef() error {
+	if av.PackageRef == nil || *av.PackageRef == "" {
+		return errors.New("missing package reference")
+	} else if ref, err := registry.ParseReference(*av.PackageRef); err != nil {
+		return fmt.Errorf("invalid package reference: %w", err)
+	} else if ref.Reference == "" {
+		return errors.New("package reference must have a tag or digest")
 	}
 	return nil
 }
@@ -0,0 +1,32 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/distr-sh/distr/internal/healthrule"
+	"github.com/google/uuid"
+)
+
+// ApplicationVersionHealthRule decides whether the resources of a kind are healthy. It replaces the status computed
+// from the conditions of the resource by the agent.
+type ApplicationVersionHealthRule struct {
+	ID                   uuid.UUID `db:"id" json:"id"`
+	ApplicationVersionID uuid.UUID `db:"application_version_id" json:"applicationVersionId"`
+	// Kind of the resources the rule applies to, optionally prefixed by the API group, e.g. Ingress or
+	// networking.k8s.io/Ingress.
+	Kind string `db:"kind" json:"kind"`
+	// JSONPath is evaluated on each resource, e.g. {.status.loadBalancer.ingress}.
+	JSONPath string `db:"json_path" json:"jsonPath"`
+	// Value must be found by JSONPath. If it is empty, JSONPath must find any value other than an empty one or false.
+	Value string `db:"value" json:"value,omitempty"`
+}
+
+func (r ApplicationVersionHealthRule) Validate() error {
+	if r.Kind == "" {
+		return errors.New("health rule is missing a kind")
+	} else if _, err := healthrule.Parse(r.JSONPath); err != nil {
+		return fmt.Errorf("health rule for %v: %w", r.Kind, err)
+	}
+	return nil
+}
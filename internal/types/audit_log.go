@@ -0,0 +1,74 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditLogAuthMethod string
+
+const (
+	AuditLogAuthMethodSession     AuditLogAuthMethod = "session"
+	AuditLogAuthMethodAccessToken AuditLogAuthMethod = "access_token"
+	// AuditLogAuthMethodServiceAccount is an access token of a service account.
+	AuditLogAuthMethodServiceAccount AuditLogAuthMethod = "service_account"
+)
+
+type AuditLogAction string
+
+const (
+	AuditLogActionCreate AuditLogAction = "create"
+	AuditLogActionUpdate AuditLogAction = "update"
+	AuditLogActionDelete AuditLogAction = "delete"
+	// AuditLogActionImpersonate is recorded when a super admin starts to impersonate a member of the organization.
+	AuditLogActionImpersonate AuditLogAction = "impersonate"
+	// AuditLogActionExport is recorded when the organization is exported to move it to another Distr instance.
+	AuditLogActionExport AuditLogAction = "export"
+	// AuditLogActionImport is recorded in an organization that was created from an export.
+	AuditLogActionImport AuditLogAction = "import"
+)
+
+// AuditLogChange holds the value of a single field before and after a change. Either side is nil if the
+// field did not exist.
+type AuditLogChange struct {
+	Before any `json:"before,omitempty"`
+	After  any `json:"after,omitempty"`
+}
+
+type AuditLogEntry struct {
+	ID                     uuid.UUID                 `db:"id"`
+	CreatedAt              time.Time                 `db:"created_at"`
+	OrganizationID         uuid.UUID                 `db:"organization_id"`
+	ActorUserAccountID     *uuid.UUID                `db:"actor_user_account_id"`
+	ActorEmail             *string                   `db:"actor_email"`
+	AuthMethod             AuditLogAuthMethod        `db:"auth_method"`
+	CustomerOrganizationID *uuid.UUID                `db:"customer_organization_id"`
+	PartnerOrganizationID  *uuid.UUID                `db:"partner_organization_id"`
+	Resource               string                    `db:"resource"`
+	ResourceID             *string                   `db:"resource_id"`
+	Action                 AuditLogAction            `db:"action"`
+	HTTPMethod             *string                   `db:"http_method"`
+	Path                   *string                   `db:"path"`
+	StatusCode             *int                      `db:"status_code"`
+	Changes                map[string]AuditLogChange `db:"changes"`
+	RemoteAddress          *string                   `db:"remote_address"`
+	RequestID              *string                   `db:"request_id"`
+	// ImpersonatorUserAccountID is the super admin who acted as the actor.
+	ImpersonatorUserAccountID *uuid.UUID `db:"impersonator_user_account_id"`
+	ImpersonatorEmail         *string    `db:"impersonator_email"`
+}
+
+type AuditLogFilter struct {
+	OrgID                  uuid.UUID
+	Before                 time.Time
+	After                  time.Time
+	Count                  int
+	ActorUserAccountID     *uuid.UUID
+	AuthMethod             *AuditLogAuthMethod
+	CustomerOrganizationID *uuid.UUID
+	PartnerOrganizationID  *uuid.UUID
+	Resource               *string
+	ResourceID             *string
+	Action                 *AuditLogAction
+}
@@ -0,0 +1,19 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CustomRole is a set of permissions defined by an organization. It can be assigned to members of the
+// organization and to their access tokens in place of the permissions of a built-in UserRole.
+type CustomRole struct {
+	ID             uuid.UUID     `db:"id"`
+	CreatedAt      time.Time     `db:"created_at"`
+	UpdatedAt      time.Time     `db:"updated_at"`
+	OrganizationID uuid.UUID     `db:"organization_id"`
+	Name           string        `db:"name"`
+	Description    *string       `db:"description"`
+	Permissions    PermissionSet `db:"permissions"`
+}
@@ -0,0 +1,31 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type CustomSAMLConfiguration struct {
+	ID                     uuid.UUID           `db:"id"`
+	CreatedAt              time.Time           `db:"created_at"`
+	UpdatedAt              time.Time           `db:"updated_at"`
+	UpdatedByUserAccountID *uuid.UUID          `db:"updated_by_user_account_id"`
+	OrganizationID         uuid.UUID           `db:"organization_id"`
+	CustomDomainID         uuid.UUID           `db:"custom_domain_id"`
+	Name                   string              `db:"name"`
+	Slug                   string              `db:"slug"`
+	Enabled                bool                `db:"enabled"`
+	IDPMetadata            string              `db:"idp_metadata"`
+	IDPEntityID            string              `db:"idp_entity_id"`
+	SPCertificate          string              `db:"sp_certificate"`
+	SPPrivateKey           string              `db:"sp_private_key"`
+	AllowIDPInitiated      bool                `db:"allow_idp_initiated"`
+	EmailAttribute         *string             `db:"email_attribute"`
+	NameAttribute          *string             `db:"name_attribute"`
+	RoleAttribute          *string             `db:"role_attribute"`
+	RoleMapping            map[string]UserRole `db:"role_mapping"`
+	CreateUnknownUsers     bool                `db:"create_unknown_users"`
+	DefaultUserRole        UserRole            `db:"default_user_role"`
+	AllowedEmailDomains    []string            `db:"allowed_email_domains"`
+}
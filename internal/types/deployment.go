@@ -1,11 +1,25 @@
 package types
 
 import (
+	"errors"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// releaseNamePattern keeps release names of systemd and OpenTofu deployments usable as part of a unit name, a
+// directory name and an OpenTofu workspace name.
+var releaseNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+func ValidateReleaseName(name string) error {
+	if !releaseNamePattern.MatchString(name) {
+		return errors.New("release name must consist of at most 63 lowercase letters, " +
+			"digits and dashes and must start and end with a letter or digit")
+	}
+	return nil
+}
+
 type Deployment struct {
 	Base
 	DeploymentTargetID       uuid.UUID   `db:"deployment_target_id" json:"deploymentTargetId"`
@@ -34,6 +48,8 @@ type DeploymentWithLatestRevision struct {
 	ForceRestart            bool                      `db:"force_restart" json:"forceRestart"`
 	IgnoreRevisionSkew      bool                      `db:"ignore_revision_skew" json:"ignoreRevisionSkew"`
 	HelmOptions             *HelmOptions              `db:"helm_options" json:"helmOptions,omitempty"`
+	VolumeBackupPolicy      VolumeBackupPolicy        `db:"volume_backup_policy" json:"volumeBackupPolicy"`
+	RestoreVolumeSnapshotID *uuid.UUID                `db:"restore_volume_snapshot_id" json:"restoreVolumeSnapshotId,omitempty"` //nolint:lll
 }
 
 func (d *DeploymentWithLatestRevision) GetValuesYAML() []byte {
@@ -0,0 +1,21 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeploymentMetadata is what the agent reports about a deployment after it was applied.
+type DeploymentMetadata struct {
+	DeploymentID         uuid.UUID                   `db:"deployment_id" json:"deploymentId"`
+	DeploymentRevisionID uuid.UUID                   `db:"deployment_revision_id" json:"deploymentRevisionId"`
+	UpdatedAt            time.Time                   `db:"updated_at" json:"updatedAt"`
+	Outputs              map[string]DeploymentOutput `db:"outputs" json:"outputs"`
+}
+
+// DeploymentOutput is an output of an OpenTofu module. The agent does not report the value of sensitive outputs.
+type DeploymentOutput struct {
+	Value     any  `json:"value,omitempty"`
+	Sensitive bool `json:"sensitive,omitempty"`
+}
@@ -8,15 +8,18 @@ import (
 
 type DeploymentRevision struct {
 	Base
-	DeploymentID           uuid.UUID    `db:"deployment_id" json:"deploymentId"`
-	ApplicationVersionID   uuid.UUID    `db:"application_version_id" json:"applicationVersionId"`
-	ValuesYaml             []byte       `db:"-" json:"valuesYaml,omitempty"`
-	EnvFileData            []byte       `db:"-" json:"-"`
-	ValuesHash             []byte       `db:"values_hash" json:"-"`
-	ForceRestart           bool         `db:"force_restart" json:"forceRestart"`
-	IgnoreRevisionSkew     bool         `db:"ignore_revision_skew" json:"ignoreRevisionSkew"`
-	HelmOptions            *HelmOptions `db:"helm_options" json:"helmOptions,omitempty"`
-	CreatedByUserAccountID *uuid.UUID   `db:"created_by_user_account_id" json:"-"`
+	DeploymentID           uuid.UUID          `db:"deployment_id" json:"deploymentId"`
+	ApplicationVersionID   uuid.UUID          `db:"application_version_id" json:"applicationVersionId"`
+	ValuesYaml             []byte             `db:"-" json:"valuesYaml,omitempty"`
+	EnvFileData            []byte             `db:"-" json:"-"`
+	ValuesHash             []byte             `db:"values_hash" json:"-"`
+	ForceRestart           bool               `db:"force_restart" json:"forceRestart"`
+	IgnoreRevisionSkew     bool               `db:"ignore_revision_skew" json:"ignoreRevisionSkew"`
+	HelmOptions            *HelmOptions       `db:"helm_options" json:"helmOptions,omitempty"`
+	CreatedByUserAccountID *uuid.UUID         `db:"created_by_user_account_id" json:"-"`
+	VolumeBackupPolicy     VolumeBackupPolicy `db:"volume_backup_policy" json:"volumeBackupPolicy"`
+	// RestoreVolumeSnapshotID is set for a revision that restores the volumes of the deployment to a snapshot.
+	RestoreVolumeSnapshotID *uuid.UUID `db:"restore_volume_snapshot_id" json:"restoreVolumeSnapshotId,omitempty"`
 }
 
 type HelmOptions struct {
@@ -31,22 +34,24 @@ type HelmOptions struct {
 // configuration needed to display it (application version, release name, docker
 // type, values) and information about the user who created it.
 type DeploymentRevisionWithCreator struct {
-	ID                              uuid.UUID    `db:"id"`
-	CreatedAt                       time.Time    `db:"created_at"`
-	ApplicationVersionID            uuid.UUID    `db:"application_version_id"`
-	ApplicationVersionName          string       `db:"application_version_name"`
-	ReleaseName                     *string      `db:"release_name"`
-	DockerType                      *DockerType  `db:"docker_type"`
-	ValuesYaml                      []byte       `db:"values_yaml"`
-	EnvFileData                     []byte       `db:"env_file_data"`
-	ForceRestart                    bool         `db:"force_restart"`
-	IgnoreRevisionSkew              bool         `db:"ignore_revision_skew"`
-	HelmOptions                     *HelmOptions `db:"helm_options"`
-	CreatedByID                     *uuid.UUID   `db:"created_by_id"`
-	CreatedByName                   *string      `db:"created_by_name"`
-	CreatedByEmail                  *string      `db:"created_by_email"`
-	CreatedByImageID                *uuid.UUID   `db:"created_by_image_id"`
-	CreatedByCustomerOrganizationID *uuid.UUID   `db:"created_by_customer_organization_id"`
-	CreatedByPartnerOrganizationID  *uuid.UUID   `db:"created_by_partner_organization_id"`
-	CreatedByDeleted                bool         `db:"created_by_deleted"`
+	ID                              uuid.UUID          `db:"id"`
+	CreatedAt                       time.Time          `db:"created_at"`
+	ApplicationVersionID            uuid.UUID          `db:"application_version_id"`
+	ApplicationVersionName          string             `db:"application_version_name"`
+	ReleaseName                     *string            `db:"release_name"`
+	DockerType                      *DockerType        `db:"docker_type"`
+	ValuesYaml                      []byte             `db:"values_yaml"`
+	EnvFileData                     []byte             `db:"env_file_data"`
+	ForceRestart                    bool               `db:"force_restart"`
+	IgnoreRevisionSkew              bool               `db:"ignore_revision_skew"`
+	HelmOptions                     *HelmOptions       `db:"helm_options"`
+	VolumeBackupPolicy              VolumeBackupPolicy `db:"volume_backup_policy"`
+	RestoreVolumeSnapshotID         *uuid.UUID         `db:"restore_volume_snapshot_id"`
+	CreatedByID                     *uuid.UUID         `db:"created_by_id"`
+	CreatedByName                   *string            `db:"created_by_name"`
+	CreatedByEmail                  *string            `db:"created_by_email"`
+	CreatedByImageID                *uuid.UUID         `db:"created_by_image_id"`
+	CreatedByCustomerOrganizationID *uuid.UUID         `db:"created_by_customer_organization_id"`
+	CreatedByPartnerOrganizationID  *uuid.UUID         `db:"created_by_partner_organization_id"`
+	CreatedByDeleted                bool               `db:"created_by_deleted"`
 }
@@ -0,0 +1,23 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeploymentRevisionPlan is the plan of an OpenTofu deployment revision, which the agent only applies after it was
+// approved. The digest identifies the plan file on the agent, so that an approval never applies to a newer plan.
+type DeploymentRevisionPlan struct {
+	DeploymentRevisionID    uuid.UUID  `db:"deployment_revision_id" json:"deploymentRevisionId"`
+	CreatedAt               time.Time  `db:"created_at" json:"createdAt"`
+	Digest                  string     `db:"digest" json:"digest"`
+	HasChanges              bool       `db:"has_changes" json:"hasChanges"`
+	Output                  string     `db:"output" json:"output"`
+	ApprovedAt              *time.Time `db:"approved_at" json:"approvedAt,omitempty"`
+	ApprovedByUserAccountID *uuid.UUID `db:"approved_by_user_account_id" json:"-"`
+}
+
+func (p *DeploymentRevisionPlan) IsApproved() bool {
+	return p != nil && p.ApprovedAt != nil
+}
@@ -35,6 +35,7 @@ type DeploymentTarget struct {
 	DeploymentLogsAfter     *time.Time                 `db:"deployment_logs_after" json:"deploymentLogsAfter,omitempty"`
 	Resources               *DeploymentTargetResources `db:"resources" json:"resources,omitempty"`
 	DockerEndpoint          *string                    `db:"docker_endpoint" json:"dockerEndpoint,omitempty"`
+	ContainerRuntime        *ContainerRuntime          `db:"container_runtime" json:"containerRuntime,omitempty"`
 }
 
 type DeploymentTargetResources struct {
@@ -82,13 +83,69 @@ func (dt *DeploymentTarget) Validate() error {
 		if dt.Resources != nil {
 			return validation.NewValidationFailedError("DeploymentTarget with type \"docker\" must not have resources")
 		}
+	case DeploymentTypeSystemd:
+		if dt.Namespace != nil || dt.Scope != nil || dt.Resources != nil {
+			return validation.NewValidationFailedError(
+				"DeploymentTarget with type \"systemd\" must not have namespace, scope or resources")
+		}
+		if dt.AutohealEnabled || dt.ImageCleanupEnabled {
+			return validation.NewValidationFailedError(
+				"autoheal and image cleanup are not supported on DeploymentTarget with type \"systemd\"")
+		}
+	case DeploymentTypeOpenTofu:
+		if dt.Namespace != nil || dt.Scope != nil || dt.Resources != nil {
+			return validation.NewValidationFailedError(
+				"DeploymentTarget with type \"opentofu\" must not have namespace, scope or resources")
+		}
+		if dt.AutohealEnabled || dt.ImageCleanupEnabled {
+			return validation.NewValidationFailedError(
+				"autoheal and image cleanup are not supported on DeploymentTarget with type \"opentofu\"")
+		}
 	default:
 		return validation.NewValidationFailedError("invalid deployment target type")
 	}
-	return ValidateDockerEndpoint(dt.DockerEndpoint, dt.Type)
+	if err := ValidateDockerEndpoint(dt.DockerEndpoint, dt.Type); err != nil {
+		return err
+	}
+	return dt.validateContainerRuntime()
+}
+
+func (dt *DeploymentTarget) validateContainerRuntime() error {
+	if dt.ContainerRuntime == nil {
+		return nil
+	}
+	if dt.Type != DeploymentTypeDocker {
+		return validation.NewValidationFailedError(
+			fmt.Sprintf("DeploymentTarget with type %q must not have a container runtime", dt.Type),
+		)
+	}
+	switch *dt.ContainerRuntime {
+	case ContainerRuntimeDocker, ContainerRuntimePodman, ContainerRuntimeQuadlet:
+		return nil
+	default:
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid container runtime %q", *dt.ContainerRuntime))
+	}
 }
 
-const DefaultDockerSocketPath = "/var/run/docker.sock"
+// GetContainerRuntime returns the container runtime of a docker target, which defaults to Docker.
+func (dt *DeploymentTarget) GetContainerRuntime() ContainerRuntime {
+	if dt.ContainerRuntime != nil {
+		return *dt.ContainerRuntime
+	}
+	return ContainerRuntimeDocker
+}
+
+func (dt *DeploymentTarget) IsPodman() bool {
+	runtime := dt.GetContainerRuntime()
+	return runtime == ContainerRuntimePodman || runtime == ContainerRuntimeQuadlet
+}
+
+const (
+	DefaultDockerSocketPath = "/var/run/docker.sock"
+	// DefaultPodmanSocketPath is the socket of rootful Podman. The socket of rootless Podman is in the runtime
+	// directory of the user, e.g. /run/user/1000/podman/podman.sock.
+	DefaultPodmanSocketPath = "/run/podman/podman.sock"
+)
 
 // ParseDockerEndpoint only supports unix sockets because the endpoint is applied by bind-mounting
 // the socket into the agent container.
@@ -119,6 +176,9 @@ func (dt *DeploymentTarget) DockerSocketPath() string {
 			return socketPath
 		}
 	}
+	if dt.IsPodman() {
+		return DefaultPodmanSocketPath
+	}
 	return DefaultDockerSocketPath
 }
 
@@ -12,6 +12,7 @@ type DeploymentTargetMetrics struct {
 	MemoryBytes        int64                        `db:"memory_bytes"`
 	MemoryUsage        float64                      `db:"memory_usage"`
 	DiskMetrics        []DeploymentTargetDiskMetric `db:"disk_metrics"`
+	ContainerMetrics   []DeploymentContainerMetric  `db:"container_metrics"`
 }
 
 type DeploymentTargetDiskMetric struct {
@@ -28,3 +29,18 @@ func (m DeploymentTargetDiskMetric) Usage() float64 {
 	}
 	return float64(m.BytesUsed) / float64(m.BytesTotal)
 }
+
+// DeploymentContainerMetric is the resource usage of a container of a Docker deployment or a pod of a Kubernetes
+// deployment.
+type DeploymentContainerMetric struct {
+	DeploymentID    uuid.UUID
+	Service         string
+	Name            string
+	CPUUsedMillis   int64
+	CPUUsage        float64
+	MemoryUsedBytes int64
+	MemoryUsage     float64
+	Restarts        int64
+	NetworkRxBytes  int64
+	NetworkTxBytes  int64
+}
@@ -49,4 +49,26 @@ func TestDockerSocketPath(t *testing.T) {
 
 	dt.DockerEndpoint = new("unix:///run/user/1000/docker.sock")
 	g.Expect(dt.DockerSocketPath()).To(Equal("/run/user/1000/docker.sock"))
+
+	dt = DeploymentTarget{Type: DeploymentTypeDocker, ContainerRuntime: new(ContainerRuntimePodman)}
+	g.Expect(dt.DockerSocketPath()).To(Equal(DefaultPodmanSocketPath))
+}
+
+func TestValidateContainerRuntime(t *testing.T) {
+	g := NewWithT(t)
+
+	dt := DeploymentTarget{Type: DeploymentTypeDocker}
+	g.Expect(dt.validateContainerRuntime()).To(Succeed())
+	g.Expect(dt.GetContainerRuntime()).To(Equal(ContainerRuntimeDocker))
+	g.Expect(dt.IsPodman()).To(BeFalse())
+
+	dt.ContainerRuntime = new(ContainerRuntimeQuadlet)
+	g.Expect(dt.validateContainerRuntime()).To(Succeed())
+	g.Expect(dt.IsPodman()).To(BeTrue())
+
+	dt.ContainerRuntime = new(ContainerRuntime("containerd"))
+	g.Expect(dt.validateContainerRuntime()).NotTo(Succeed())
+
+	dt = DeploymentTarget{Type: DeploymentTypeSystemd, ContainerRuntime: new(ContainerRuntimePodman)}
+	g.Expect(dt.validateContainerRuntime()).NotTo(Succeed())
 }
@@ -0,0 +1,24 @@
+package types
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestValidateReleaseName(t *testing.T) {
+	for _, name := range []string{"a", "my-app", "app2", strings.Repeat("a", 63)} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ValidateReleaseName(name)).To(Succeed())
+		})
+	}
+
+	for _, name := range []string{"", "-app", "app-", "My-App", "my_app", "my.app", "../app", strings.Repeat("a", 64)} {
+		t.Run(name, func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(ValidateReleaseName(name)).NotTo(Succeed())
+		})
+	}
+}
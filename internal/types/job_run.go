@@ -0,0 +1,33 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning   JobRunStatus = "running"
+	JobRunStatusSucceeded JobRunStatus = "succeeded"
+	JobRunStatusFailed    JobRunStatus = "failed"
+)
+
+type JobRun struct {
+	ID         uuid.UUID    `db:"id"`
+	JobName    string       `db:"job_name"`
+	Holder     string       `db:"holder"`
+	StartedAt  time.Time    `db:"started_at"`
+	FinishedAt *time.Time   `db:"finished_at"`
+	Status     JobRunStatus `db:"status"`
+	Error      *string      `db:"error"`
+}
+
+// JobLease is held by the hub replica that currently runs a scheduled job.
+type JobLease struct {
+	JobName    string    `db:"job_name"`
+	Holder     string    `db:"holder"`
+	AcquiredAt time.Time `db:"acquired_at"`
+	ExpiresAt  time.Time `db:"expires_at"`
+}
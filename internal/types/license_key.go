@@ -29,3 +29,11 @@ type LicenseKeyRevision struct {
 	ExpiresAt    time.Time       `db:"expires_at"     json:"expiresAt"`
 	Payload      json.RawMessage `db:"payload"        json:"payload"`
 }
+
+type LicenseKeyFilter struct {
+	OrgID                  uuid.UUID
+	LicenseTemplateID      *uuid.UUID
+	CustomerOrganizationID *uuid.UUID
+	ExpiresAfter           *time.Time
+	ExpiresBefore          *time.Time
+}
@@ -27,6 +27,8 @@ type NotificationRecord struct {
 	MetricType                         *string                `db:"metric_type"`
 	DiskDevice                         *string                `db:"disk_device"`
 	DiskPath                           *string                `db:"disk_path"`
+	DeploymentID                       *uuid.UUID             `db:"deployment_id"`
+	ContainerName                      *string                `db:"container_name"`
 	PreviousDeploymentTargetMetricsID  *uuid.UUID             `db:"previous_deployment_target_metrics_id"`
 	CurrentDeploymentTargetMetricsID   *uuid.UUID             `db:"current_deployment_target_metrics_id"`
 	Message                            string                 `db:"message" json:"message"`
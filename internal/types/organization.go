@@ -25,7 +25,9 @@ type Organization struct {
 	PostConnectScript                   *string            `db:"post_connect_script" json:"postConnectScript"`
 	ConnectScriptIsSudo                 bool               `db:"connect_script_is_sudo" json:"connectScriptIsSudo"`
 	StripeWebhookSecret                 *string            `db:"stripe_webhook_secret"            json:"-"`
-	StripeWebhookSecretConfigured       bool               `db:"stripe_webhook_secret_configured" json:"stripeWebhookSecretConfigured"` //nolint:lll
+	StripeWebhookSecretConfigured       bool               `db:"stripe_webhook_secret_configured" json:"stripeWebhookSecretConfigured"`        //nolint:lll
+	LicenseKeyExpirationReminderDays    []int              `db:"license_key_expiration_reminder_days" json:"licenseKeyExpirationReminderDays"` //nolint:lll
+	SecurityPolicy                      SecurityPolicy     `db:"security_policy" json:"-"`
 }
 
 func (org *Organization) HasFeature(feature Feature) bool {
@@ -68,6 +70,23 @@ type OrganizationWithUserRole struct {
 	PartnerOrganizationID    *uuid.UUID `db:"partner_organization_id" json:"partnerOrganizationId,omitempty"`
 	PartnerOrganizationName  *string    `db:"partner_organization_name" json:"partnerOrganizationName,omitempty"`
 	JoinedOrgAt              time.Time  `db:"joined_org_at" json:"joinedOrgAt"`
+	CustomRoleID             *uuid.UUID `db:"custom_role_id" json:"customRoleId,omitempty"`
+}
+
+// OrganizationOverview is an organization as listed for super admins, including soft-deleted ones.
+type OrganizationOverview struct {
+	Organization
+	DeletedAt        *time.Time `db:"deleted_at"`
+	UserAccountCount int64      `db:"user_account_count"`
+}
+
+// OrganizationOverviewFilter selects the organizations listed for super admins. Search matches the ID, name or slug.
+// Deleted selects soft-deleted instead of active organizations.
+type OrganizationOverviewFilter struct {
+	Search  string
+	Deleted bool
+	Before  time.Time
+	Count   int
 }
 
 // OrganizationMember names a member of an organization without loading the whole user account,
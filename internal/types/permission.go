@@ -0,0 +1,204 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Resource is a kind of object that permissions are granted on.
+type Resource string
+
+const (
+	ResourceApplications          Resource = "applications"
+	ResourceArtifacts             Resource = "artifacts"
+	ResourceDeploymentTargets     Resource = "deployment_targets"
+	ResourceDeployments           Resource = "deployments"
+	ResourceLicenseKeys           Resource = "license_keys"
+	ResourceLicenseTemplates      Resource = "license_templates"
+	ResourceEntitlements          Resource = "entitlements"
+	ResourceCustomerOrganizations Resource = "customer_organizations"
+	ResourcePartnerOrganizations  Resource = "partner_organizations"
+	ResourceUserAccounts          Resource = "user_accounts"
+	ResourceSecrets               Resource = "secrets"
+	ResourceSupportBundles        Resource = "support_bundles"
+	ResourceAlertConfigurations   Resource = "alert_configurations"
+	ResourceBranding              Resource = "branding"
+	ResourceOrganization          Resource = "organization"
+	ResourceBilling               Resource = "billing"
+	ResourceIdentityProviders     Resource = "identity_providers"
+	ResourceCustomDomains         Resource = "custom_domains"
+	ResourceCustomEmails          Resource = "custom_emails"
+	ResourceRoles                 Resource = "roles"
+	ResourceAuditLog              Resource = "audit_log"
+)
+
+// contentResources are the resources every built-in role can at least read. The remaining resources are
+// reserved for the admin role.
+var contentResources = []Resource{
+	ResourceApplications,
+	ResourceArtifacts,
+	ResourceDeploymentTargets,
+	ResourceDeployments,
+	ResourceLicenseKeys,
+	ResourceLicenseTemplates,
+	ResourceEntitlements,
+	ResourceCustomerOrganizations,
+	ResourcePartnerOrganizations,
+	ResourceUserAccounts,
+	ResourceSecrets,
+	ResourceSupportBundles,
+	ResourceAlertConfigurations,
+	ResourceBranding,
+}
+
+var allResources = append(slices.Clone(contentResources),
+	ResourceOrganization,
+	ResourceBilling,
+	ResourceIdentityProviders,
+	ResourceCustomDomains,
+	ResourceCustomEmails,
+	ResourceRoles,
+	ResourceAuditLog,
+)
+
+func Resources() []Resource {
+	return slices.Clone(allResources)
+}
+
+// Action is what a permission allows on a resource. Actions are ordered: every action implies the ones
+// before it, so manage implies write and write implies read.
+type Action string
+
+const (
+	ActionRead  Action = "read"
+	ActionWrite Action = "write"
+	// ActionManage covers the operations beyond day-to-day writes, like deleting a customer organization or
+	// managing the members of the organization itself.
+	ActionManage Action = "manage"
+)
+
+var allActions = []Action{ActionRead, ActionWrite, ActionManage}
+
+func Actions() []Action {
+	return slices.Clone(allActions)
+}
+
+// Permission grants an action on a resource and is written as "resource:action".
+type Permission string
+
+func NewPermission(resource Resource, action Action) Permission {
+	return Permission(string(resource) + ":" + string(action))
+}
+
+func ParsePermission(value string) (Permission, error) {
+	resource, action, ok := strings.Cut(value, ":")
+	if !ok || !slices.Contains(allResources, Resource(resource)) || !slices.Contains(allActions, Action(action)) {
+		return "", fmt.Errorf("invalid permission: %q", value)
+	}
+	return Permission(value), nil
+}
+
+func (p Permission) Resource() Resource {
+	resource, _, _ := strings.Cut(string(p), ":")
+	return Resource(resource)
+}
+
+func (p Permission) Action() Action {
+	_, action, _ := strings.Cut(string(p), ":")
+	return Action(action)
+}
+
+func (p *Permission) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	} else if permission, err := ParsePermission(value); err != nil {
+		return err
+	} else {
+		*p = permission
+		return nil
+	}
+}
+
+// PermissionSet is a normalized list of permissions: it holds at most one permission per resource, with the
+// highest action granted on it, ordered like Resources.
+type PermissionSet []Permission
+
+// NewPermissionSet normalizes the given permissions. Invalid permissions are dropped, they can only enter the
+// codebase through the database, which is written with validated values only.
+func NewPermissionSet(permissions ...Permission) PermissionSet {
+	return PermissionSet(permissions).Intersect(adminPermissions)
+}
+
+func (s PermissionSet) level(resource Resource) int {
+	level := -1
+	for _, permission := range s {
+		if permission.Resource() == resource {
+			level = max(level, slices.Index(allActions, permission.Action()))
+		}
+	}
+	return level
+}
+
+// Has reports whether the set grants action on resource, either directly or through a higher action.
+func (s PermissionSet) Has(resource Resource, action Action) bool {
+	required := slices.Index(allActions, action)
+	return required >= 0 && s.level(resource) >= required
+}
+
+// GrantsManage reports whether the set grants the manage action on any resource, which makes its holder an admin
+// regardless of the role it came from.
+func (s PermissionSet) GrantsManage() bool {
+	return slices.ContainsFunc(s, func(permission Permission) bool { return permission.Action() == ActionManage })
+}
+
+// Contains reports whether every permission of other is also granted by s.
+func (s PermissionSet) Contains(other PermissionSet) bool {
+	for _, permission := range other {
+		if !s.Has(permission.Resource(), permission.Action()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Intersect returns the permissions granted by both s and other.
+func (s PermissionSet) Intersect(other PermissionSet) PermissionSet {
+	result := PermissionSet{}
+	for _, resource := range allResources {
+		if level := min(s.level(resource), other.level(resource)); level >= 0 {
+			result = append(result, NewPermission(resource, allActions[level]))
+		}
+	}
+	return result
+}
+
+var (
+	readOnlyPermissions  = presetPermissions(contentResources, ActionRead)
+	readWritePermissions = presetPermissions(contentResources, ActionWrite)
+	adminPermissions     = presetPermissions(allResources, ActionManage)
+)
+
+func presetPermissions(resources []Resource, action Action) PermissionSet {
+	result := make(PermissionSet, 0, len(resources))
+	for _, resource := range resources {
+		result = append(result, NewPermission(resource, action))
+	}
+	return result
+}
+
+// Permissions returns the permissions of the built-in role. It panics for unknown roles, like Rank.
+func (r UserRole) Permissions() PermissionSet {
+	switch r {
+	case UserRoleReadOnly:
+		return slices.Clone(readOnlyPermissions)
+	case UserRoleReadWrite:
+		return slices.Clone(readWritePermissions)
+	case UserRoleAdmin:
+		return slices.Clone(adminPermissions)
+	default:
+		panic(fmt.Sprintf("invalid user role: %q", string(r)))
+	}
+}
@@ -0,0 +1,113 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestPermissionSetHas(t *testing.T) {
+	g := NewWithT(t)
+
+	set := NewPermissionSet(
+		NewPermission(ResourceLicenseKeys, ActionWrite),
+		NewPermission(ResourceApplications, ActionRead),
+	)
+
+	// Higher actions imply the lower ones on the same resource.
+	g.Expect(set.Has(ResourceLicenseKeys, ActionRead)).To(BeTrue())
+	g.Expect(set.Has(ResourceLicenseKeys, ActionWrite)).To(BeTrue())
+	g.Expect(set.Has(ResourceLicenseKeys, ActionManage)).To(BeFalse())
+	g.Expect(set.Has(ResourceApplications, ActionRead)).To(BeTrue())
+	g.Expect(set.Has(ResourceApplications, ActionWrite)).To(BeFalse())
+
+	// Nothing is granted on other resources or for unknown actions.
+	g.Expect(set.Has(ResourceArtifacts, ActionRead)).To(BeFalse())
+	g.Expect(set.Has(ResourceLicenseKeys, Action("delete"))).To(BeFalse())
+	g.Expect(PermissionSet(nil).Has(ResourceLicenseKeys, ActionRead)).To(BeFalse())
+}
+
+func TestNewPermissionSet(t *testing.T) {
+	g := NewWithT(t)
+
+	set := NewPermissionSet(
+		NewPermission(ResourceLicenseKeys, ActionRead),
+		NewPermission(ResourceApplications, ActionRead),
+		NewPermission(ResourceLicenseKeys, ActionManage),
+		Permission("unknown:read"),
+		Permission("applications"),
+	)
+
+	// One permission per resource with the highest action, ordered like Resources.
+	g.Expect(set).To(Equal(PermissionSet{"applications:read", "license_keys:manage"}))
+	g.Expect(NewPermissionSet()).To(BeEmpty())
+}
+
+func TestPermissionSetContainsAndIntersect(t *testing.T) {
+	g := NewWithT(t)
+
+	writer := NewPermissionSet(NewPermission(ResourceDeployments, ActionWrite))
+	reader := NewPermissionSet(
+		NewPermission(ResourceDeployments, ActionRead),
+		NewPermission(ResourceSecrets, ActionRead),
+	)
+
+	g.Expect(writer.Contains(NewPermissionSet(NewPermission(ResourceDeployments, ActionRead)))).To(BeTrue())
+	g.Expect(writer.Contains(reader)).To(BeFalse())
+	g.Expect(writer.Contains(nil)).To(BeTrue())
+	g.Expect(writer.Intersect(reader)).To(Equal(PermissionSet{"deployments:read"}))
+	g.Expect(writer.Intersect(nil)).To(BeEmpty())
+}
+
+func TestUserRolePermissions(t *testing.T) {
+	g := NewWithT(t)
+
+	readOnly := UserRoleReadOnly.Permissions()
+	readWrite := UserRoleReadWrite.Permissions()
+	admin := UserRoleAdmin.Permissions()
+
+	// The presets keep the ordering of the built-in roles.
+	g.Expect(readWrite.Contains(readOnly)).To(BeTrue())
+	g.Expect(admin.Contains(readWrite)).To(BeTrue())
+	g.Expect(readOnly.Contains(readWrite)).To(BeFalse())
+	g.Expect(readWrite.Contains(admin)).To(BeFalse())
+
+	g.Expect(readOnly.Has(ResourceDeployments, ActionRead)).To(BeTrue())
+	g.Expect(readOnly.Has(ResourceDeployments, ActionWrite)).To(BeFalse())
+	g.Expect(readWrite.Has(ResourceDeployments, ActionWrite)).To(BeTrue())
+	g.Expect(readWrite.Has(ResourceUserAccounts, ActionManage)).To(BeFalse())
+	g.Expect(readWrite.Has(ResourceOrganization, ActionRead)).To(BeFalse())
+	g.Expect(admin.Has(ResourceRoles, ActionManage)).To(BeTrue())
+
+	// Callers get a copy they may modify.
+	readOnly[0] = NewPermission(ResourceRoles, ActionManage)
+	g.Expect(UserRoleReadOnly.Permissions().Has(ResourceRoles, ActionRead)).To(BeFalse())
+
+	g.Expect(func() { _ = UserRole("owner").Permissions() }).To(Panic())
+}
+
+func TestPermissionSetGrantsManage(t *testing.T) {
+	g := NewWithT(t)
+	g.Expect(UserRoleAdmin.Permissions().GrantsManage()).To(BeTrue())
+	g.Expect(UserRoleReadWrite.Permissions().GrantsManage()).To(BeFalse())
+	g.Expect(PermissionSet{}.GrantsManage()).To(BeFalse())
+	g.Expect(NewPermissionSet(
+		NewPermission(ResourceDeployments, ActionRead),
+		NewPermission(ResourceUserAccounts, ActionManage),
+	).GrantsManage()).To(BeTrue())
+}
+
+func TestPermissionUnmarshalJSON(t *testing.T) {
+	g := NewWithT(t)
+
+	var permissions []Permission
+	g.Expect(json.Unmarshal([]byte(`["license_keys:write","roles:read"]`), &permissions)).To(Succeed())
+	g.Expect(permissions).To(Equal([]Permission{"license_keys:write", "roles:read"}))
+	g.Expect(permissions[0].Resource()).To(Equal(ResourceLicenseKeys))
+	g.Expect(permissions[0].Action()).To(Equal(ActionWrite))
+
+	g.Expect(json.Unmarshal([]byte(`["license_keys:delete"]`), &permissions)).NotTo(Succeed())
+	g.Expect(json.Unmarshal([]byte(`["unknown:read"]`), &permissions)).NotTo(Succeed())
+	g.Expect(json.Unmarshal([]byte(`["license_keys"]`), &permissions)).NotTo(Succeed())
+}
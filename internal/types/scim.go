@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"github.com/distr-sh/distr/internal/authkey"
+	"github.com/google/uuid"
+)
+
+type ScimToken struct {
+	ID                     uuid.UUID   `db:"id"`
+	CreatedAt              time.Time   `db:"created_at"`
+	LastUsedAt             *time.Time  `db:"last_used_at"`
+	Label                  *string     `db:"label"`
+	Key                    authkey.Key `db:"key"`
+	OrganizationID         uuid.UUID   `db:"organization_id"`
+	CustomerOrganizationID *uuid.UUID  `db:"customer_organization_id"`
+}
+
+// ScimUser is a user provisioned by an identity provider. UserRole is nil if the user is not a member of the
+// organization, i.e. if it has been deactivated.
+type ScimUser struct {
+	UserAccountID          uuid.UUID   `db:"user_account_id"`
+	OrganizationID         uuid.UUID   `db:"organization_id"`
+	CustomerOrganizationID *uuid.UUID  `db:"customer_organization_id"`
+	CreatedAt              time.Time   `db:"created_at"`
+	UpdatedAt              time.Time   `db:"updated_at"`
+	ExternalID             *string     `db:"external_id"`
+	Active                 bool        `db:"active"`
+	Email                  string      `db:"email"`
+	Name                   string      `db:"name"`
+	UserRole               *UserRole   `db:"user_role"`
+	GroupIDs               []uuid.UUID `db:"group_ids"`
+}
+
+type ScimGroup struct {
+	ID                     uuid.UUID   `db:"id"`
+	CreatedAt              time.Time   `db:"created_at"`
+	UpdatedAt              time.Time   `db:"updated_at"`
+	OrganizationID         uuid.UUID   `db:"organization_id"`
+	CustomerOrganizationID *uuid.UUID  `db:"customer_organization_id"`
+	DisplayName            string      `db:"display_name"`
+	ExternalID             *string     `db:"external_id"`
+	UserRole               *UserRole   `db:"user_role"`
+	MemberIDs              []uuid.UUID `db:"member_ids"`
+}
@@ -0,0 +1,42 @@
+package types
+
+import (
+	"net/netip"
+	"slices"
+)
+
+// SecurityPolicy holds the security settings of an organization. It is stored as JSON, so that settings can be
+// added without adding columns to the organization.
+type SecurityPolicy struct {
+	// RequireWebAuthnForAdmins takes all permissions from admins who did not sign in with a passkey. Members with a
+	// custom role that grants managing any resource count as admins.
+	RequireWebAuthnForAdmins bool `json:"requireWebAuthnForAdmins,omitempty"`
+	// RequireMFA takes all permissions from members who signed in with neither a second factor, a passkey nor the
+	// identity provider of the organization.
+	RequireMFA bool `json:"requireMfa,omitempty"`
+	// IPAllowlist restricts logins and the usage of access tokens to these networks in CIDR notation, if not empty.
+	IPAllowlist []string `json:"ipAllowlist,omitempty"`
+	// MaxAccessTokenLifetime caps the expiration of new access tokens.
+	MaxAccessTokenLifetime *Duration `json:"maxAccessTokenLifetime,omitempty"`
+	// DisablePasswordLogin rejects password logins while the organization has an identity provider configured.
+	DisablePasswordLogin bool `json:"disablePasswordLogin,omitempty"`
+	// SessionIdleTimeout revokes sessions that were not used for this long.
+	SessionIdleTimeout *Duration `json:"sessionIdleTimeout,omitempty"`
+}
+
+// AllowsAddress reports whether the IP allowlist contains the given address. An address that cannot be parsed is
+// only allowed when the allowlist is empty.
+func (p SecurityPolicy) AllowsAddress(address string) bool {
+	if len(p.IPAllowlist) == 0 {
+		return true
+	}
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	return slices.ContainsFunc(p.IPAllowlist, func(entry string) bool {
+		prefix, err := netip.ParsePrefix(entry)
+		return err == nil && prefix.Contains(addr)
+	})
+}
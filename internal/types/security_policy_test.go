@@ -0,0 +1,32 @@
+package types
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestSecurityPolicyAllowsAddress(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(SecurityPolicy{}.AllowsAddress("203.0.113.7")).To(BeTrue())
+	g.Expect(SecurityPolicy{}.AllowsAddress("")).To(BeTrue())
+
+	policy := SecurityPolicy{IPAllowlist: []string{"203.0.113.0/24", "2001:db8::/32"}}
+	g.Expect(policy.AllowsAddress("203.0.113.7")).To(BeTrue())
+	g.Expect(policy.AllowsAddress("::ffff:203.0.113.7")).To(BeTrue())
+	g.Expect(policy.AllowsAddress("2001:db8::1")).To(BeTrue())
+	g.Expect(policy.AllowsAddress("198.51.100.1")).To(BeFalse())
+	g.Expect(policy.AllowsAddress("")).To(BeFalse())
+	g.Expect(policy.AllowsAddress("not-an-ip")).To(BeFalse())
+}
+
+func TestSessionAuthMethodSatisfiesMFA(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(SessionAuthMethodPassword.SatisfiesMFA(false)).To(BeFalse())
+	g.Expect(SessionAuthMethodPassword.SatisfiesMFA(true)).To(BeTrue())
+	g.Expect(SessionAuthMethodPasskey.SatisfiesMFA(false)).To(BeTrue())
+	g.Expect(SessionAuthMethodSSO.SatisfiesMFA(false)).To(BeTrue())
+	g.Expect(SessionAuthMethodOIDC.SatisfiesMFA(true)).To(BeFalse())
+}
@@ -0,0 +1,11 @@
+package types
+
+// TableColumn describes a column of a database table, for data that is copied between Distr instances that may run
+// different versions.
+type TableColumn struct {
+	Name      string `db:"name"`
+	Nullable  bool   `db:"nullable"`
+	Generated bool   `db:"generated"`
+	// ReferencedTable is the lower-case name of the table that the column is a foreign key of, or empty.
+	ReferencedTable string `db:"referenced_table"`
+}
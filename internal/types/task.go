@@ -0,0 +1,43 @@
+package types
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TaskStatus string
+
+const (
+	TaskStatusPending   TaskStatus = "pending"
+	TaskStatusRunning   TaskStatus = "running"
+	TaskStatusSucceeded TaskStatus = "succeeded"
+	// TaskStatusDead is the dead letter state of tasks whose last attempt failed.
+	TaskStatusDead TaskStatus = "dead"
+)
+
+type Task struct {
+	ID              uuid.UUID       `db:"id"`
+	CreatedAt       time.Time       `db:"created_at"`
+	OrganizationID  *uuid.UUID      `db:"organization_id"`
+	Type            string          `db:"type"`
+	Payload         json.RawMessage `db:"payload"`
+	DedupKey        *string         `db:"dedup_key"`
+	Status          TaskStatus      `db:"status"`
+	Attempts        int             `db:"attempts"`
+	MaxAttempts     int             `db:"max_attempts"`
+	RunAfter        time.Time       `db:"run_after"`
+	LeaseHolder     *string         `db:"lease_holder"`
+	LeaseExpiresAt  *time.Time      `db:"lease_expires_at"`
+	Progress        *int            `db:"progress"`
+	ProgressMessage *string         `db:"progress_message"`
+	Result          json.RawMessage `db:"result"`
+	Error           *string         `db:"error"`
+	StartedAt       *time.Time      `db:"started_at"`
+	FinishedAt      *time.Time      `db:"finished_at"`
+}
+
+func (t *Task) IsFinished() bool {
+	return t.Status == TaskStatusSucceeded || t.Status == TaskStatusDead
+}
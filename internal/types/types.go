@@ -132,6 +132,20 @@ const (
 	FeatureCustomOidcProviders    Feature = "custom_oidc_providers"
 )
 
+func AllFeatures() []Feature {
+	return []Feature{
+		FeatureLicensing,
+		FeaturePrePostScripts,
+		FeatureArtifactVersionMutable,
+		FeatureVendorBilling,
+		FeatureDeploymentLogsAfter,
+		FeaturePartnerManagement,
+		FeatureCustomDomains,
+		FeatureCustomEmails,
+		FeatureCustomOidcProviders,
+	}
+}
+
 // ProFeatures is the set of features granted to organizations with a paid (pro) subscription.
 var ProFeatures = []Feature{
 	FeatureLicensing,
@@ -226,6 +240,10 @@ type DeploymentType string
 const (
 	DeploymentTypeDocker     DeploymentType = "docker"
 	DeploymentTypeKubernetes DeploymentType = "kubernetes"
+	// DeploymentTypeSystemd deploys packages from the registry as systemd units, without containers.
+	DeploymentTypeSystemd DeploymentType = "systemd"
+	// DeploymentTypeOpenTofu applies OpenTofu or Terraform modules from the registry, e.g. for BYOC infrastructure.
+	DeploymentTypeOpenTofu DeploymentType = "opentofu"
 )
 
 var ErrInvalidDeploymentType = errors.New("invalid deployment type")
@@ -236,6 +254,10 @@ func ParseDeploymentType(value string) (DeploymentType, error) {
 		return DeploymentTypeDocker, nil
 	case string(DeploymentTypeKubernetes):
 		return DeploymentTypeKubernetes, nil
+	case string(DeploymentTypeSystemd):
+		return DeploymentTypeSystemd, nil
+	case string(DeploymentTypeOpenTofu):
+		return DeploymentTypeOpenTofu, nil
 	default:
 		return "", fmt.Errorf("%w: %v", ErrInvalidDeploymentType, value)
 	}
@@ -254,21 +276,44 @@ func (ref *DeploymentType) UnmarshalJSON(data []byte) error {
 }
 
 type (
-	HelmChartType         string
-	DeploymentTargetScope string
-	DockerType            string
-	Tutorial              string
-	FileScope             string
-	SubscriptionPeriod    string
+	HelmChartType           string
+	KubernetesSourceType    string
+	DeploymentTargetScope   string
+	DockerType              string
+	ContainerRuntime        string
+	VolumeBackupPolicy      string
+	VolumeBackupStorageType string
+	Tutorial                string
+	FileScope               string
+	SubscriptionPeriod      string
 )
 
 const (
 	HelmChartTypeRepository HelmChartType = "repository"
 	HelmChartTypeOCI        HelmChartType = "oci"
 
+	KubernetesSourceTypeHelm KubernetesSourceType = "helm"
+	// KubernetesSourceTypeKustomize is used for versions that are packaged as plain manifests or a Kustomize base
+	// instead of a Helm chart.
+	KubernetesSourceTypeKustomize KubernetesSourceType = "kustomize"
+
 	DockerTypeCompose DockerType = "compose"
 	DockerTypeSwarm   DockerType = "swarm"
 
+	ContainerRuntimeDocker ContainerRuntime = "docker"
+	ContainerRuntimePodman ContainerRuntime = "podman"
+	// ContainerRuntimeQuadlet runs the agent on the host and deploys compose files as Podman Quadlet units.
+	ContainerRuntimeQuadlet ContainerRuntime = "quadlet"
+
+	VolumeBackupPolicyNone VolumeBackupPolicy = "none"
+	// VolumeBackupPolicyBeforeUpdate creates a snapshot before every new revision of a deployment is applied.
+	VolumeBackupPolicyBeforeUpdate VolumeBackupPolicy = "beforeUpdate"
+	// VolumeBackupPolicyBeforeVersionUpdate only creates a snapshot if the application version changes.
+	VolumeBackupPolicyBeforeVersionUpdate VolumeBackupPolicy = "beforeVersionUpdate"
+
+	VolumeBackupStorageLocal VolumeBackupStorageType = "local"
+	VolumeBackupStorageS3    VolumeBackupStorageType = "s3"
+
 	DeploymentTargetScopeCluster   DeploymentTargetScope = "cluster"
 	DeploymentTargetScopeNamespace DeploymentTargetScope = "namespace"
 
@@ -23,7 +23,9 @@ type UserAccount struct {
 	MFAEnabled             bool       `db:"mfa_enabled" json:"mfaEnabled"`
 	MFAEnabledAt           *time.Time `db:"mfa_enabled_at" json:"-"`
 	IsSuperAdmin           bool       `db:"is_super_admin" json:"-"`
-	Password               string     `db:"-" json:"-"`
+	// ServiceAccountOrganizationID is set for service accounts, which belong to this organization only.
+	ServiceAccountOrganizationID *uuid.UUID `db:"service_account_organization_id" json:"-"`
+	Password                     string     `db:"-" json:"-"`
 
 	// Remember to update AsUserAccountWithRole when adding fields!
 }
@@ -32,27 +34,30 @@ func (u *UserAccount) AsUserAccountWithRole(
 	role UserRole,
 	customerOrganizationID *uuid.UUID,
 	partnerOrganizationID *uuid.UUID,
+	customRoleID *uuid.UUID,
 	joinedOrgAt time.Time,
 ) UserAccountWithUserRole {
 	return UserAccountWithUserRole{
-		ID:                     u.ID,
-		CreatedAt:              u.CreatedAt,
-		Email:                  u.Email,
-		EmailVerifiedAt:        util.PtrCopy(u.EmailVerifiedAt),
-		PasswordHash:           slices.Clone(u.PasswordHash),
-		PasswordSalt:           slices.Clone(u.PasswordSalt),
-		Name:                   u.Name,
-		ImageID:                u.ImageID,
-		MFASecret:              util.PtrCopy(u.MFASecret),
-		MFAEnabled:             u.MFAEnabled,
-		MFAEnabledAt:           util.PtrCopy(u.MFAEnabledAt),
-		IsSuperAdmin:           u.IsSuperAdmin,
-		Password:               u.Password,
-		UserRole:               role,
-		JoinedOrgAt:            joinedOrgAt,
-		CustomerOrganizationID: customerOrganizationID,
-		PartnerOrganizationID:  partnerOrganizationID,
-		LastUsedOrganizationID: u.LastUsedOrganizationID,
+		ID:                           u.ID,
+		CreatedAt:                    u.CreatedAt,
+		Email:                        u.Email,
+		EmailVerifiedAt:              util.PtrCopy(u.EmailVerifiedAt),
+		PasswordHash:                 slices.Clone(u.PasswordHash),
+		PasswordSalt:                 slices.Clone(u.PasswordSalt),
+		Name:                         u.Name,
+		ImageID:                      u.ImageID,
+		MFASecret:                    util.PtrCopy(u.MFASecret),
+		MFAEnabled:                   u.MFAEnabled,
+		MFAEnabledAt:                 util.PtrCopy(u.MFAEnabledAt),
+		IsSuperAdmin:                 u.IsSuperAdmin,
+		ServiceAccountOrganizationID: u.ServiceAccountOrganizationID,
+		Password:                     u.Password,
+		UserRole:                     role,
+		JoinedOrgAt:                  joinedOrgAt,
+		CustomerOrganizationID:       customerOrganizationID,
+		PartnerOrganizationID:        partnerOrganizationID,
+		CustomRoleID:                 customRoleID,
+		LastUsedOrganizationID:       u.LastUsedOrganizationID,
 	}
 }
 
@@ -73,6 +78,8 @@ type UserAccountWithUserRole struct {
 	MFAEnabled             bool       `db:"mfa_enabled" json:"mfaEnabled"`
 	MFAEnabledAt           *time.Time `db:"mfa_enabled_at" json:"-"`
 	IsSuperAdmin           bool       `db:"is_super_admin" json:"-"`
+	// ServiceAccountOrganizationID is set for service accounts, which belong to this organization only.
+	ServiceAccountOrganizationID *uuid.UUID `db:"service_account_organization_id" json:"-"`
 
 	// not copy+pasted
 
@@ -80,6 +87,8 @@ type UserAccountWithUserRole struct {
 	JoinedOrgAt            time.Time  `db:"joined_org_at" json:"joinedOrgAt"`
 	CustomerOrganizationID *uuid.UUID `db:"customer_organization_id" json:"customerOrganizationId,omitempty"`
 	PartnerOrganizationID  *uuid.UUID `db:"partner_organization_id" json:"partnerOrganizationId,omitempty"`
+	// CustomRoleID replaces the permissions of UserRole if set.
+	CustomRoleID *uuid.UUID `db:"custom_role_id" json:"customRoleId,omitempty"`
 
 	// copy+pasted from UserAccount
 
@@ -90,19 +99,40 @@ type UserAccountWithUserRole struct {
 
 func (u *UserAccountWithUserRole) AsUserAccount() UserAccount {
 	return UserAccount{
-		ID:                     u.ID,
-		CreatedAt:              u.CreatedAt,
-		Email:                  u.Email,
-		EmailVerifiedAt:        util.PtrCopy(u.EmailVerifiedAt),
-		PasswordHash:           slices.Clone(u.PasswordHash),
-		PasswordSalt:           slices.Clone(u.PasswordSalt),
-		Name:                   u.Name,
-		ImageID:                u.ImageID,
-		LastUsedOrganizationID: u.LastUsedOrganizationID,
-		MFASecret:              util.PtrCopy(u.MFASecret),
-		MFAEnabled:             u.MFAEnabled,
-		MFAEnabledAt:           util.PtrCopy(u.MFAEnabledAt),
-		IsSuperAdmin:           u.IsSuperAdmin,
-		Password:               u.Password,
+		ID:                           u.ID,
+		CreatedAt:                    u.CreatedAt,
+		Email:                        u.Email,
+		EmailVerifiedAt:              util.PtrCopy(u.EmailVerifiedAt),
+		PasswordHash:                 slices.Clone(u.PasswordHash),
+		PasswordSalt:                 slices.Clone(u.PasswordSalt),
+		Name:                         u.Name,
+		ImageID:                      u.ImageID,
+		LastUsedOrganizationID:       u.LastUsedOrganizationID,
+		MFASecret:                    util.PtrCopy(u.MFASecret),
+		MFAEnabled:                   u.MFAEnabled,
+		MFAEnabledAt:                 util.PtrCopy(u.MFAEnabledAt),
+		IsSuperAdmin:                 u.IsSuperAdmin,
+		ServiceAccountOrganizationID: u.ServiceAccountOrganizationID,
+		Password:                     u.Password,
 	}
 }
+
+// IsServiceAccount reports whether the account is a service account, which has no email address or password.
+func (u *UserAccount) IsServiceAccount() bool {
+	return u.ServiceAccountOrganizationID != nil
+}
+
+// UserAccountOverview is a user account as listed for super admins.
+type UserAccountOverview struct {
+	UserAccount
+	LastLoggedInAt    *time.Time `db:"last_logged_in_at"`
+	OrganizationCount int64      `db:"organization_count"`
+}
+
+// UserAccountOverviewFilter selects the user accounts listed for super admins. Search matches the ID, email or name.
+type UserAccountOverviewFilter struct {
+	Search         string
+	OrganizationID *uuid.UUID
+	Before         time.Time
+	Count          int
+}
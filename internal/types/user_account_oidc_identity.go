@@ -14,6 +14,9 @@ const (
 	OIDCProviderMicrosoft OIDCProvider = "microsoft"
 	OIDCProviderGeneric   OIDCProvider = "generic"
 	OIDCProviderCustom    OIDCProvider = "custom"
+	// OIDCProviderSAML marks identities of an organization's SAML identity provider, which are linked
+	// the same way as OIDC identities: the issuer is the entity ID of the IdP and the subject the NameID.
+	OIDCProviderSAML OIDCProvider = "saml"
 )
 
 // UserAccountOIDCIdentity is the identity of a user account at an identity provider.
@@ -31,6 +34,7 @@ type UserAccountOIDCIdentity struct {
 	Email                     *string    `db:"email"         json:"email,omitempty"`
 	LastLoginAt               *time.Time `db:"last_login_at" json:"lastLoginAt,omitempty"`
 	CustomOIDCConfigurationID *uuid.UUID `db:"custom_oidc_configuration_id" json:"-"`
+	CustomSAMLConfigurationID *uuid.UUID `db:"custom_saml_configuration_id" json:"-"`
 }
 
 type UserAccountOIDCIdentityWithConfiguration struct {
@@ -0,0 +1,46 @@
+package types
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type SessionAuthMethod string
+
+const (
+	SessionAuthMethodPassword SessionAuthMethod = "password"
+	SessionAuthMethodPasskey  SessionAuthMethod = "passkey"
+	// SessionAuthMethodOIDC is a login with one of the identity providers configured for the whole instance.
+	SessionAuthMethodOIDC SessionAuthMethod = "oidc"
+	// SessionAuthMethodSSO is a login with the OIDC or SAML identity provider of an organization.
+	SessionAuthMethodSSO SessionAuthMethod = "sso"
+	// SessionAuthMethodImpersonation is a session that a super admin started to act as the user.
+	SessionAuthMethodImpersonation SessionAuthMethod = "impersonation"
+)
+
+// SatisfiesMFA reports whether a session signed in with this method counts as multi-factor authenticated.
+// Password logins require a second factor once the user has enabled MFA, and organizations are expected to
+// enforce MFA in their own identity provider. The second factor of an impersonation is the login of the super admin.
+func (m SessionAuthMethod) SatisfiesMFA(mfaEnabled bool) bool {
+	switch m {
+	case SessionAuthMethodPasskey, SessionAuthMethodSSO, SessionAuthMethodImpersonation:
+		return true
+	case SessionAuthMethodPassword:
+		return mfaEnabled
+	default:
+		return false
+	}
+}
+
+type UserSession struct {
+	ID             uuid.UUID         `db:"id"`
+	CreatedAt      time.Time         `db:"created_at"`
+	UserAccountID  uuid.UUID         `db:"user_account_id"`
+	OrganizationID *uuid.UUID        `db:"organization_id"`
+	ExpiresAt      time.Time         `db:"expires_at"`
+	LastSeenAt     time.Time         `db:"last_seen_at"`
+	UserAgent      *string           `db:"user_agent"`
+	RemoteAddress  *string           `db:"remote_address"`
+	AuthMethod     SessionAuthMethod `db:"auth_method"`
+}
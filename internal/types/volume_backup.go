@@ -0,0 +1,70 @@
+package types
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/util"
+	"github.com/distr-sh/distr/internal/validation"
+	"github.com/google/uuid"
+)
+
+const DefaultVolumeBackupRetention = 3
+
+// VolumeBackupStorage is where the agent of a deployment target stores the volume snapshots of its deployments.
+// Targets without a storage keep the snapshots on the local disk of the agent.
+type VolumeBackupStorage struct {
+	DeploymentTargetID uuid.UUID               `db:"deployment_target_id"`
+	UpdatedAt          time.Time               `db:"updated_at"`
+	Type               VolumeBackupStorageType `db:"type"`
+	Retention          int                     `db:"retention"`
+	S3Endpoint         *string                 `db:"s3_endpoint"`
+	S3Region           *string                 `db:"s3_region"`
+	S3Bucket           *string                 `db:"s3_bucket"`
+	S3Prefix           *string                 `db:"s3_prefix"`
+	S3UsePathStyle     bool                    `db:"s3_use_path_style"`
+	S3AccessKeyID      *string                 `db:"s3_access_key_id"`
+	S3SecretAccessKey  *string                 `db:"s3_secret_access_key"`
+}
+
+func (s *VolumeBackupStorage) Validate() error {
+	if s.Retention < 1 {
+		return validation.NewValidationFailedError("retention must be at least 1")
+	}
+	switch s.Type {
+	case VolumeBackupStorageLocal:
+		return nil
+	case VolumeBackupStorageS3:
+		if s.S3Bucket == nil || *s.S3Bucket == "" {
+			return validation.NewValidationFailedError("S3 storage must have a bucket")
+		}
+		if (s.S3AccessKeyID == nil) != (s.S3SecretAccessKey == nil) {
+			return validation.NewValidationFailedError("S3 access key ID and secret access key must be set together")
+		}
+		return nil
+	default:
+		return validation.NewValidationFailedError(fmt.Sprintf("invalid volume backup storage type %q", s.Type))
+	}
+}
+
+// KeepsSecretAccessKeyOf returns whether s, which was saved without a secret access key, keeps the one of previous.
+// The secret must be sent again if the access key ID or where it is used changes, so that whoever can edit the
+// storage cannot send the stored secret to a server of their own.
+func (s *VolumeBackupStorage) KeepsSecretAccessKeyOf(previous VolumeBackupStorage) bool {
+	return s.S3SecretAccessKey == nil && previous.S3SecretAccessKey != nil &&
+		s.S3AccessKeyID != nil && util.PtrEq(s.S3AccessKeyID, previous.S3AccessKeyID) &&
+		util.PtrEq(s.S3Endpoint, previous.S3Endpoint) &&
+		util.PtrEq(s.S3Region, previous.S3Region) &&
+		util.PtrEq(s.S3Bucket, previous.S3Bucket)
+}
+
+// DeploymentVolumeSnapshot is a snapshot of the volumes of a deployment as reported by the agent. The ID is chosen
+// by the agent, because the snapshot exists in the storage before it is known to the hub.
+type DeploymentVolumeSnapshot struct {
+	ID                   uuid.UUID `db:"id"`
+	CreatedAt            time.Time `db:"created_at"`
+	DeploymentID         uuid.UUID `db:"deployment_id"`
+	DeploymentRevisionID uuid.UUID `db:"deployment_revision_id"`
+	SizeBytes            int64     `db:"size_bytes"`
+	Volumes              []string  `db:"volumes"`
+}
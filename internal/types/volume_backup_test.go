@@ -0,0 +1,49 @@
+package types
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestVolumeBackupStorageKeepsSecretAccessKeyOf(t *testing.T) {
+	g := NewWithT(t)
+	previous := VolumeBackupStorage{
+		Type:              VolumeBackupStorageS3,
+		S3Endpoint:        new("https://s3.example.com"),
+		S3Region:          new("eu-central-1"),
+		S3Bucket:          new("backups"),
+		S3AccessKeyID:     new("key"),
+		S3SecretAccessKey: new("secret"),
+	}
+	unchanged := func() VolumeBackupStorage {
+		s := previous
+		s.S3SecretAccessKey = nil
+		return s
+	}
+
+	s := unchanged()
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeTrue())
+	s.S3Prefix = new("daily/")
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeTrue(), "the prefix does not change where the secret is sent")
+
+	s = unchanged()
+	s.S3SecretAccessKey = new("other")
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeFalse())
+
+	s = unchanged()
+	s.S3AccessKeyID = new("other")
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeFalse())
+
+	s = unchanged()
+	s.S3Endpoint = new("https://attacker.example.com")
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeFalse())
+
+	s = unchanged()
+	s.S3Region = nil
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeFalse())
+
+	s = unchanged()
+	s.S3Bucket = new("other")
+	g.Expect(s.KeepsSecretAccessKeyOf(previous)).To(BeFalse())
+}
@@ -0,0 +1,17 @@
+package types
+
+import (
+	"time"
+
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+type WebAuthnCredential struct {
+	ID            uuid.UUID           `db:"id"`
+	CreatedAt     time.Time           `db:"created_at"`
+	UserAccountID uuid.UUID           `db:"user_account_id"`
+	Name          string              `db:"name"`
+	Credential    webauthn.Credential `db:"credential"`
+	LastUsedAt    *time.Time          `db:"last_used_at"`
+}
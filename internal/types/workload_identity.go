@@ -0,0 +1,78 @@
+package types
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WorkloadIdentityTrustPolicy lets workloads, like the jobs of a CI system, exchange an ID token of a trusted
+// issuer for a short-lived access token of a service account, so that they do not have to store one.
+type WorkloadIdentityTrustPolicy struct {
+	ID               uuid.UUID `db:"id"`
+	CreatedAt        time.Time `db:"created_at"`
+	OrganizationID   uuid.UUID `db:"organization_id"`
+	ServiceAccountID uuid.UUID `db:"service_account_id"`
+	Name             string    `db:"name"`
+	Issuer           string    `db:"issuer"`
+	Audience         string    `db:"audience"`
+	// SubjectPattern must match the "sub" claim of the ID token, where "*" matches any number of characters,
+	// like "repo:acme/app:ref:refs/tags/*".
+	SubjectPattern string `db:"subject_pattern"`
+	// ClaimPatterns must match the other claims of the ID token in the same way as SubjectPattern.
+	ClaimPatterns map[string]string `db:"claim_patterns"`
+	// Scopes restrict the access tokens issued for the policy. Empty means the role of the service account.
+	Scopes        AccessTokenScopes `db:"scopes"`
+	TokenLifetime Duration          `db:"token_lifetime"`
+}
+
+// Matches reports whether the claims of a verified ID token satisfy the subject and claim patterns.
+func (p WorkloadIdentityTrustPolicy) Matches(claims map[string]any) bool {
+	if !matchesClaim(p.SubjectPattern, claims["sub"]) {
+		return false
+	}
+	for name, pattern := range p.ClaimPatterns {
+		if !matchesClaim(pattern, claims[name]) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesClaim(pattern string, value any) bool {
+	switch v := value.(type) {
+	case string:
+		return MatchWildcardPattern(pattern, v)
+	case bool:
+		return MatchWildcardPattern(pattern, strconv.FormatBool(v))
+	case float64:
+		return MatchWildcardPattern(pattern, strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		// Missing claims and objects never match, not even "*".
+		return false
+	}
+}
+
+// MatchWildcardPattern reports whether value matches pattern, where "*" matches any number of characters,
+// including "/" and ":", and every other character only matches itself.
+func MatchWildcardPattern(pattern, value string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		index := strings.Index(value, part)
+		if index < 0 {
+			return false
+		}
+		value = value[index+len(part):]
+	}
+	return len(value) >= len(last) && strings.HasSuffix(value, last)
+}
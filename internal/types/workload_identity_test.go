@@ -0,0 +1,52 @@
+package types_test
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	. "github.com/onsi/gomega"
+)
+
+func TestMatchWildcardPattern(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(types.MatchWildcardPattern("repo:acme/app:ref:refs/tags/*", "repo:acme/app:ref:refs/tags/v1.2.0")).
+		To(BeTrue())
+	g.Expect(types.MatchWildcardPattern("repo:acme/app:ref:refs/tags/*", "repo:acme/app:ref:refs/heads/main")).
+		To(BeFalse())
+	g.Expect(types.MatchWildcardPattern("repo:acme/*:environment:prod", "repo:acme/app:environment:prod")).
+		To(BeTrue())
+	g.Expect(types.MatchWildcardPattern("repo:acme/*:environment:prod", "repo:acme/app:environment:staging")).
+		To(BeFalse())
+	g.Expect(types.MatchWildcardPattern("repo:acme/app", "repo:acme/app")).To(BeTrue())
+	g.Expect(types.MatchWildcardPattern("repo:acme/app", "repo:acme/app2")).To(BeFalse())
+	g.Expect(types.MatchWildcardPattern("*", "")).To(BeTrue())
+	g.Expect(types.MatchWildcardPattern("a*a", "a")).To(BeFalse())
+	g.Expect(types.MatchWildcardPattern("a*b*c", "aXbYbZc")).To(BeTrue())
+}
+
+func TestWorkloadIdentityTrustPolicyMatches(t *testing.T) {
+	g := NewWithT(t)
+
+	policy := types.WorkloadIdentityTrustPolicy{
+		SubjectPattern: "repo:acme/app:*",
+		ClaimPatterns:  map[string]string{"ref_protected": "true", "repository_owner": "acme"},
+	}
+	claims := map[string]any{
+		"sub":              "repo:acme/app:ref:refs/heads/main",
+		"ref_protected":    true,
+		"repository_owner": "acme",
+	}
+	g.Expect(policy.Matches(claims)).To(BeTrue())
+
+	claims["ref_protected"] = false
+	g.Expect(policy.Matches(claims)).To(BeFalse())
+
+	delete(claims, "ref_protected")
+	policy.ClaimPatterns = map[string]string{"ref_protected": "*"}
+	g.Expect(policy.Matches(claims)).To(BeFalse())
+
+	claims["sub"] = "repo:evil/app:ref:refs/heads/main"
+	policy.ClaimPatterns = nil
+	g.Expect(policy.Matches(claims)).To(BeFalse())
+}
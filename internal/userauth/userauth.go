@@ -3,14 +3,18 @@ package userauth
 import (
 	"context"
 	"errors"
+	"net/http"
 
 	"github.com/distr-sh/distr/internal/apierrors"
 	"github.com/distr-sh/distr/internal/authjwt"
 	"github.com/distr-sh/distr/internal/db"
 	"github.com/distr-sh/distr/internal/security"
+	"github.com/distr-sh/distr/internal/securitypolicy"
 	"github.com/distr-sh/distr/internal/subscription"
 	"github.com/distr-sh/distr/internal/types"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
 	"github.com/google/uuid"
+	"github.com/lestrrat-go/jwx/v3/jwt"
 )
 
 // SetUserPassword hashes the given password, optionally updates the name (when name is non-nil and non-empty),
@@ -101,32 +105,169 @@ func EnsurePrimaryOrganization(ctx context.Context, user types.UserAccount) (typ
 	return org, nil
 }
 
-// GenerateLoginToken generates a default login token scoped to the user's primary organization, the same kind
-// of token that is issued on a regular login. A personal organization is created when the user has none, so
-// callers must handle subscription.ErrGlobalOrganizationLimitReached as a rejection instead of a failure.
-func GenerateLoginToken(ctx context.Context, user types.UserAccount) (string, error) {
+// Client describes the device a login comes from, so that users can recognize their sessions.
+type Client struct {
+	UserAgent     string
+	RemoteAddress string
+}
+
+// ClientFromRequest returns the user agent and the client IP of the request.
+func ClientFromRequest(r *http.Request) Client {
+	return Client{UserAgent: r.UserAgent(), RemoteAddress: chimiddleware.GetClientIP(r.Context())}
+}
+
+// GenerateLoginToken creates a session and a default login token for it, scoped to the user's primary
+// organization, the same kind of token that is issued on a regular login. A personal organization is created when
+// the user has none, so callers must handle subscription.ErrGlobalOrganizationLimitReached as a rejection instead of
+// a failure. A login that is not allowed by the security policy of the organization fails with
+// securitypolicy.ErrViolation.
+func GenerateLoginToken(
+	ctx context.Context,
+	user types.UserAccount,
+	method types.SessionAuthMethod,
+	client Client,
+) (string, error) {
+	return generateLoginToken(ctx, user, method, client, authjwt.GenerateDefaultToken)
+}
+
+// GenerateWebAuthnLoginToken is like GenerateLoginToken, but for a user who signed in with a passkey.
+func GenerateWebAuthnLoginToken(ctx context.Context, user types.UserAccount, client Client) (string, error) {
+	return generateLoginToken(ctx, user, types.SessionAuthMethodPasskey, client, authjwt.GenerateWebAuthnToken)
+}
+
+func generateLoginToken(
+	ctx context.Context,
+	user types.UserAccount,
+	method types.SessionAuthMethod,
+	client Client,
+	generate generateTokenFunc,
+) (string, error) {
 	org, err := EnsurePrimaryOrganization(ctx, user)
 	if err != nil {
 		return "", err
 	}
-	_, token, err := authjwt.GenerateDefaultToken(user, org)
-	return token, err
+	return createSession(ctx, user, org, method, client, generate)
 }
 
+// GenerateLoginTokenForOrganization creates a session for a user who signed in with the identity provider of the
+// given organization.
 func GenerateLoginTokenForOrganization(
 	ctx context.Context,
 	user types.UserAccount,
 	organizationID uuid.UUID,
+	client Client,
+) (string, error) {
+	orgs, err := db.GetOrganizationsForUser(ctx, user.ID)
+	if err != nil {
+		return "", err
+	}
+	for _, org := range orgs {
+		if org.ID == organizationID {
+			return createSession(ctx, user, org, types.SessionAuthMethodSSO, client, authjwt.GenerateDefaultToken)
+		}
+	}
+	return "", apierrors.ErrNotFound
+}
+
+// GenerateImpersonationToken creates a session in which the super admin impersonator acts as user in the given
+// organization, which the user must be a member of. It returns apierrors.ErrNotFound otherwise.
+func GenerateImpersonationToken(
+	ctx context.Context,
+	user types.UserAccount,
+	organizationID uuid.UUID,
+	impersonator types.UserAccount,
+	client Client,
 ) (string, error) {
 	orgs, err := db.GetOrganizationsForUser(ctx, user.ID)
 	if err != nil {
 		return "", err
 	}
+	generate := func(
+		user types.UserAccount,
+		org types.OrganizationWithUserRole,
+		sessionID uuid.UUID,
+	) (jwt.Token, string, error) {
+		return authjwt.GenerateImpersonationToken(user, org, sessionID, impersonator)
+	}
 	for _, org := range orgs {
 		if org.ID == organizationID {
-			_, token, err := authjwt.GenerateDefaultToken(user, org)
-			return token, err
+			return createSession(ctx, user, org, types.SessionAuthMethodImpersonation, client, generate)
 		}
 	}
 	return "", apierrors.ErrNotFound
 }
+
+type generateTokenFunc func(types.UserAccount, types.OrganizationWithUserRole, uuid.UUID) (jwt.Token, string, error)
+
+func createSession(
+	ctx context.Context,
+	user types.UserAccount,
+	org types.OrganizationWithUserRole,
+	method types.SessionAuthMethod,
+	client Client,
+	generate generateTokenFunc,
+) (string, error) {
+	if !user.IsSuperAdmin {
+		if err := securitypolicy.CheckLogin(ctx, org.Organization, method, client.RemoteAddress); err != nil {
+			return "", err
+		}
+	}
+	session := types.UserSession{
+		ID:             uuid.New(),
+		UserAccountID:  user.ID,
+		OrganizationID: &org.ID,
+		AuthMethod:     method,
+	}
+	if client.UserAgent != "" {
+		session.UserAgent = &client.UserAgent
+	}
+	if client.RemoteAddress != "" {
+		session.RemoteAddress = &client.RemoteAddress
+	}
+	token, tokenString, err := generate(user, org, session.ID)
+	if err != nil {
+		return "", err
+	}
+	expiration, _ := token.Expiration()
+	session.ExpiresAt = expiration.UTC()
+	if err := db.CreateUserSession(ctx, &session); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// RefreshSessionToken generates a token for an existing session, when the user switches to another organization.
+// It returns apierrors.ErrNotFound if the session was revoked in the meantime, and securitypolicy.ErrViolation if the
+// security policy of the other organization does not allow the session.
+func RefreshSessionToken(
+	ctx context.Context,
+	user types.UserAccount,
+	org types.OrganizationWithUserRole,
+	sessionID uuid.UUID,
+	generate generateTokenFunc,
+) (string, error) {
+	if !user.IsSuperAdmin {
+		if session, err := db.GetUserSession(ctx, sessionID, user.ID); err != nil {
+			return "", err
+		} else if err := securitypolicy.CheckLogin(
+			ctx, org.Organization, session.AuthMethod, chimiddleware.GetClientIP(ctx)); err != nil {
+			return "", err
+		}
+	}
+	token, tokenString, err := generate(user, org, sessionID)
+	if err != nil {
+		return "", err
+	}
+	expiration, _ := token.Expiration()
+	if err := db.UpdateUserSessionToken(ctx, sessionID, user.ID, org.ID, expiration.UTC()); err != nil {
+		return "", err
+	}
+	return tokenString, nil
+}
+
+// RevokeSessions revokes all sessions of the user except the given one, if it is not nil. It is called whenever
+// the credentials of the user change, so that a stolen token cannot outlive a password reset.
+func RevokeSessions(ctx context.Context, userID uuid.UUID, except *uuid.UUID) error {
+	_, err := db.DeleteUserSessions(ctx, userID, except)
+	return err
+}
@@ -0,0 +1,249 @@
+// Package volumesnapshot reads and writes the archives that the Docker agent creates of the volumes of a deployment.
+//
+// A snapshot is a gzipped tar archive. Its first entry is snapshot.json with the Manifest, followed by the contents
+// of every volume below volumes/<name>/, where name is the key of the volume in the compose file. The contents of a
+// volume are taken from and restored to a container that mounts it, with the tar streams of the Docker API.
+package volumesnapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	manifestFile = "snapshot.json"
+	volumesDir   = "volumes"
+)
+
+type Manifest struct {
+	ID           uuid.UUID `json:"id"`
+	DeploymentID uuid.UUID `json:"deploymentId"`
+	// RevisionID is the revision that was running when the snapshot was created.
+	RevisionID           uuid.UUID `json:"revisionId"`
+	ApplicationVersionID uuid.UUID `json:"applicationVersionId"`
+	CreatedAt            time.Time `json:"createdAt"`
+	Volumes              []string  `json:"volumes"`
+}
+
+type Writer struct {
+	gz       *gzip.Writer
+	tw       *tar.Writer
+	manifest Manifest
+}
+
+// NewWriter writes the manifest to a new snapshot. The contents of all volumes of the manifest must then be added
+// with AddVolume.
+func NewWriter(w io.Writer, manifest Manifest) (*Writer, error) {
+	for _, volume := range manifest.Volumes {
+		if err := validateVolumeName(volume); err != nil {
+			return nil, err
+		}
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     manifestFile,
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  manifest.CreatedAt,
+	}); err != nil {
+		return nil, err
+	} else if _, err := tw.Write(data); err != nil {
+		return nil, err
+	}
+	return &Writer{gz: gz, tw: tw, manifest: manifest}, nil
+}
+
+// AddVolume adds the contents of a volume from the tar stream that Docker returns when a directory is copied from
+// a container. All entries of this stream are below a directory that has the name of the copied directory, which is
+// replaced with the directory of the volume.
+func (w *Writer) AddVolume(volume string, content io.Reader) error {
+	if !slices.Contains(w.manifest.Volumes, volume) {
+		return fmt.Errorf("volume %v is not part of the snapshot", volume)
+	}
+	dir := path.Join(volumesDir, volume)
+	tr := tar.NewReader(content)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		header.Name = rebase(header.Name, dir)
+		if header.Typeflag == tar.TypeLink {
+			header.Linkname = rebase(header.Linkname, dir)
+		}
+		if err := w.tw.WriteHeader(header); err != nil {
+			return err
+		} else if _, err := io.Copy(w.tw, tr); err != nil {
+			return err
+		}
+	}
+}
+
+func (w *Writer) Close() error {
+	return errors.Join(w.tw.Close(), w.gz.Close())
+}
+
+// RestoreFunc restores the contents of a volume from a tar stream that can be copied to the mount point of the
+// volume in a container. The root of the volume is the entry ".".
+type RestoreFunc func(volume string, content io.Reader) error
+
+// Read reads the manifest of a snapshot and passes the contents of every volume to restore, one after the other.
+// If restore is nil, only the manifest is read.
+func Read(r io.Reader, restore RestoreFunc) (*Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var manifest Manifest
+	if header, err := tr.Next(); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	} else if header.Name != manifestFile {
+		return nil, fmt.Errorf("invalid snapshot: first entry is %v instead of %v", header.Name, manifestFile)
+	} else if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid snapshot manifest: %w", err)
+	}
+	if restore == nil {
+		return &manifest, nil
+	}
+
+	var current *volumeStream
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			current.abort(err)
+			return nil, err
+		}
+		volume, name, err := splitName(header.Name)
+		if err == nil && !slices.Contains(manifest.Volumes, volume) {
+			err = fmt.Errorf("invalid snapshot: volume %v is not part of the manifest", volume)
+		}
+		if err != nil {
+			current.abort(err)
+			return nil, err
+		}
+		if current == nil || current.volume != volume {
+			if err := current.close(); err != nil {
+				return nil, err
+			}
+			current = newVolumeStream(volume, restore)
+		}
+		header.Name = name
+		if header.Typeflag == tar.TypeLink {
+			if _, header.Linkname, err = splitName(header.Linkname); err != nil {
+				current.abort(err)
+				return nil, err
+			}
+		}
+		if err := current.tw.WriteHeader(header); err != nil {
+			return nil, errors.Join(err, current.wait())
+		} else if _, err := io.Copy(current.tw, tr); err != nil {
+			return nil, errors.Join(err, current.wait())
+		}
+	}
+	if err := current.close(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// volumeStream pipes the entries of a volume to a RestoreFunc that runs concurrently.
+type volumeStream struct {
+	volume string
+	pw     *io.PipeWriter
+	tw     *tar.Writer
+	done   chan error
+}
+
+func newVolumeStream(volume string, restore RestoreFunc) *volumeStream {
+	pr, pw := io.Pipe()
+	s := &volumeStream{volume: volume, pw: pw, tw: tar.NewWriter(pw), done: make(chan error, 1)}
+	go func() {
+		err := restore(volume, pr)
+		// unblocks the writer if restore did not read the whole stream
+		_ = pr.CloseWithError(errors.Join(err, io.ErrClosedPipe))
+		s.done <- err
+	}()
+	return s
+}
+
+func (s *volumeStream) close() error {
+	if s == nil {
+		return nil
+	}
+	err := s.tw.Close()
+	_ = s.pw.CloseWithError(err)
+	if restoreErr := <-s.done; restoreErr != nil {
+		return fmt.Errorf("failed to restore volume %v: %w", s.volume, restoreErr)
+	}
+	return err
+}
+
+func (s *volumeStream) abort(err error) {
+	if s != nil {
+		_ = s.pw.CloseWithError(err)
+		<-s.done
+	}
+}
+
+func (s *volumeStream) wait() error {
+	_ = s.pw.Close()
+	if err := <-s.done; err != nil {
+		return fmt.Errorf("failed to restore volume %v: %w", s.volume, err)
+	}
+	return nil
+}
+
+// rebase replaces the first element of name with dir.
+func rebase(name, dir string) string {
+	name = strings.TrimPrefix(path.Clean("/"+name), "/")
+	if _, rest, ok := strings.Cut(name, "/"); ok {
+		return path.Join(dir, rest)
+	}
+	return dir + "/"
+}
+
+// splitName returns the volume of an entry and its name relative to the volume.
+func splitName(name string) (volume string, rel string, err error) {
+	clean := path.Clean(name)
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", "", fmt.Errorf("invalid snapshot: illegal entry %v", name)
+	}
+	parts := strings.SplitN(clean, "/", 3)
+	if len(parts) < 2 || parts[0] != volumesDir {
+		return "", "", fmt.Errorf("invalid snapshot: unexpected entry %v", name)
+	} else if len(parts) == 2 {
+		return parts[1], ".", nil
+	} else {
+		return parts[1], parts[2], nil
+	}
+}
+
+func validateVolumeName(volume string) error {
+	if volume == "" || volume == "." || volume == ".." || strings.ContainsAny(volume, "/\\") {
+		return fmt.Errorf("invalid volume name %q", volume)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package volumesnapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	content  string
+	linkname string
+}
+
+// dockerArchive builds a tar stream like the one that Docker returns for a directory copied from a container.
+func dockerArchive(t *testing.T, entries ...entry) io.Reader {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0o644}
+		if e.typeflag == tar.TypeDir {
+			header.Mode = 0o755
+		} else if e.typeflag == tar.TypeReg {
+			header.Size = int64(len(e.content))
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		} else if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func readEntries(t *testing.T, r io.Reader) []entry {
+	var result []entry
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return result
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = append(result, entry{
+			name: header.Name, typeflag: header.Typeflag, content: string(content), linkname: header.Linkname,
+		})
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	g := NewWithT(t)
+	manifest := Manifest{
+		ID:                   uuid.New(),
+		DeploymentID:         uuid.New(),
+		RevisionID:           uuid.New(),
+		ApplicationVersionID: uuid.New(),
+		CreatedAt:            time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Volumes:              []string{"db-data", "uploads"},
+	}
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, manifest)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.AddVolume("db-data", dockerArchive(t,
+		entry{name: "data/", typeflag: tar.TypeDir},
+		entry{name: "data/PG_VERSION", typeflag: tar.TypeReg, content: "17"},
+		entry{name: "data/base/", typeflag: tar.TypeDir},
+		entry{name: "data/base/1", typeflag: tar.TypeLink, linkname: "data/PG_VERSION"},
+	))).To(Succeed())
+	g.Expect(w.AddVolume("uploads", dockerArchive(t,
+		entry{name: "uploads/", typeflag: tar.TypeDir},
+		entry{name: "uploads/a.txt", typeflag: tar.TypeReg, content: "hello"},
+	))).To(Succeed())
+	g.Expect(w.AddVolume("other", dockerArchive(t))).NotTo(Succeed())
+	g.Expect(w.Close()).To(Succeed())
+	archive := buf.Bytes()
+
+	result, err := Read(bytes.NewReader(archive), nil)
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*result).To(Equal(manifest))
+
+	restored := map[string][]entry{}
+	result, err = Read(bytes.NewReader(archive), func(volume string, content io.Reader) error {
+		restored[volume] = readEntries(t, content)
+		return nil
+	})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(*result).To(Equal(manifest))
+	g.Expect(restored).To(Equal(map[string][]entry{
+		"db-data": {
+			{name: ".", typeflag: tar.TypeDir},
+			{name: "PG_VERSION", typeflag: tar.TypeReg, content: "17"},
+			{name: "base", typeflag: tar.TypeDir},
+			{name: "base/1", typeflag: tar.TypeLink, linkname: "PG_VERSION"},
+		},
+		"uploads": {
+			{name: ".", typeflag: tar.TypeDir},
+			{name: "a.txt", typeflag: tar.TypeReg, content: "hello"},
+		},
+	}))
+}
+
+func TestReadRestoreError(t *testing.T) {
+	g := NewWithT(t)
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, Manifest{Volumes: []string{"data"}})
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(w.AddVolume("data", dockerArchive(t,
+		entry{name: "data/", typeflag: tar.TypeDir},
+		entry{name: "data/a.txt", typeflag: tar.TypeReg, content: "hello"},
+	))).To(Succeed())
+	g.Expect(w.Close()).To(Succeed())
+
+	_, err = Read(&buf, func(volume string, content io.Reader) error {
+		return errors.New("container is gone")
+	})
+	g.Expect(err).To(MatchError(ContainSubstring("container is gone")))
+}
+
+func TestNewWriterInvalidVolume(t *testing.T) {
+	g := NewWithT(t)
+	_, err := NewWriter(io.Discard, Manifest{Volumes: []string{"../etc"}})
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestSplitName(t *testing.T) {
+	g := NewWithT(t)
+	volume, name, err := splitName("volumes/data/")
+	g.Expect(err).NotTo(HaveOccurred())
+	g.Expect(volume).To(Equal("data"))
+	g.Expect(name).To(Equal("."))
+	_, _, err = splitName("volumes/../../etc/passwd")
+	g.Expect(err).To(HaveOccurred())
+	_, _, err = splitName("other/data/a")
+	g.Expect(err).To(HaveOccurred())
+}
@@ -0,0 +1,75 @@
+// Package webauthn configures the WebAuthn relying party of this instance and adapts user accounts to it.
+package webauthn
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/distr-sh/distr/internal/env"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const rpDisplayName = "Distr"
+
+// RelyingParty is the WebAuthn relying party of this instance. Credentials are bound to the host of DISTR_HOST,
+// so passkeys can not be used on custom domains.
+var RelyingParty = sync.OnceValues(func() (*webauthn.WebAuthn, error) {
+	origin := strings.TrimSuffix(env.Host(), "/")
+	u, err := url.Parse(origin)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse DISTR_HOST: %w", err)
+	}
+	return webauthn.New(&webauthn.Config{
+		RPID:          u.Hostname(),
+		RPDisplayName: rpDisplayName,
+		RPOrigins:     []string{origin},
+	})
+})
+
+// User implements webauthn.User for a user account and the credentials registered for it.
+type User struct {
+	Account     types.UserAccount
+	Credentials []types.WebAuthnCredential
+}
+
+var _ webauthn.User = &User{}
+
+// WebAuthnID implements webauthn.User. The user handle is the user account ID, which authenticators return with
+// every passkey assertion, so that the user can be looked up without asking for their email address first.
+func (u *User) WebAuthnID() []byte {
+	return UserHandle(u.Account.ID)
+}
+
+// WebAuthnName implements webauthn.User.
+func (u *User) WebAuthnName() string {
+	return u.Account.Email
+}
+
+// WebAuthnDisplayName implements webauthn.User.
+func (u *User) WebAuthnDisplayName() string {
+	if u.Account.Name != "" {
+		return u.Account.Name
+	}
+	return u.Account.Email
+}
+
+// WebAuthnCredentials implements webauthn.User.
+func (u *User) WebAuthnCredentials() []webauthn.Credential {
+	result := make([]webauthn.Credential, len(u.Credentials))
+	for i, credential := range u.Credentials {
+		result[i] = credential.Credential
+	}
+	return result
+}
+
+func UserHandle(userAccountID uuid.UUID) []byte {
+	return userAccountID[:]
+}
+
+func UserAccountIDFromHandle(handle []byte) (uuid.UUID, error) {
+	return uuid.FromBytes(handle)
+}
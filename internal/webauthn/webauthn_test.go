@@ -0,0 +1,42 @@
+package webauthn
+
+import (
+	"testing"
+
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	. "github.com/onsi/gomega"
+)
+
+func TestUserHandle(t *testing.T) {
+	g := NewWithT(t)
+
+	id := uuid.New()
+	handle := UserHandle(id)
+	g.Expect(handle).To(HaveLen(16))
+	g.Expect(UserAccountIDFromHandle(handle)).To(Equal(id))
+
+	_, err := UserAccountIDFromHandle([]byte("not a user handle"))
+	g.Expect(err).To(HaveOccurred())
+}
+
+func TestUser(t *testing.T) {
+	g := NewWithT(t)
+
+	user := &User{
+		Account: types.UserAccount{ID: uuid.New(), Email: "user@example.com"},
+		Credentials: []types.WebAuthnCredential{
+			{Name: "YubiKey", Credential: webauthn.Credential{ID: []byte{1}}},
+			{Name: "Laptop", Credential: webauthn.Credential{ID: []byte{2}}},
+		},
+	}
+	g.Expect(user.WebAuthnID()).To(Equal(UserHandle(user.Account.ID)))
+	g.Expect(user.WebAuthnName()).To(Equal("user@example.com"))
+	g.Expect(user.WebAuthnDisplayName()).To(Equal("user@example.com"))
+	g.Expect(user.WebAuthnCredentials()).To(HaveLen(2))
+	g.Expect(user.WebAuthnCredentials()[1].ID).To(Equal([]byte{2}))
+
+	user.Account.Name = "Jane Doe"
+	g.Expect(user.WebAuthnDisplayName()).To(Equal("Jane Doe"))
+}
@@ -0,0 +1,79 @@
+// Package workloadidentity lets workloads, like the jobs of a CI system, authenticate with an ID token of an
+// issuer that the organization trusts instead of a stored access token.
+package workloadidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/distr-sh/distr/internal/apierrors"
+	"github.com/distr-sh/distr/internal/authkey"
+	"github.com/distr-sh/distr/internal/db"
+	"github.com/distr-sh/distr/internal/oidc"
+	"github.com/distr-sh/distr/internal/types"
+	"github.com/google/uuid"
+)
+
+// ErrUntrusted is returned when the ID token does not satisfy the trust policy. The wrapping error explains why.
+var ErrUntrusted = errors.New("the ID token is not trusted")
+
+// Token is the credential of a request that was authenticated with an ID token.
+type Token struct {
+	TrustPolicyID uuid.UUID
+}
+
+// Verify returns the trust policy with the given ID if the ID token was issued for it and matches its patterns.
+func Verify(
+	ctx context.Context,
+	trustPolicyID uuid.UUID,
+	rawIDToken string,
+) (*types.WorkloadIdentityTrustPolicy, error) {
+	policy, err := db.GetWorkloadIdentityTrustPolicy(ctx, trustPolicyID)
+	if errors.Is(err, apierrors.ErrNotFound) {
+		return nil, fmt.Errorf("%w: the trust policy does not exist", ErrUntrusted)
+	} else if err != nil {
+		return nil, err
+	}
+	claims, err := oidc.VerifyWorkloadIDToken(ctx, policy.Issuer, policy.Audience, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUntrusted, err)
+	}
+	if !policy.Matches(claims) {
+		return nil, fmt.Errorf("%w: the claims do not match the trust policy", ErrUntrusted)
+	}
+	return policy, nil
+}
+
+// IssueAccessToken creates a short-lived access token of the service account of the policy, restricted to its
+// scopes. The lifetime is capped by the security policy of the organization. Since every CI job gets a new token,
+// the expired ones of the service account are deleted along the way.
+func IssueAccessToken(ctx context.Context, policy types.WorkloadIdentityTrustPolicy) (*types.AccessToken, error) {
+	org, err := db.GetOrganizationByID(ctx, policy.OrganizationID)
+	if err != nil {
+		return nil, err
+	}
+	lifetime := time.Duration(policy.TokenLifetime)
+	if maxLifetime := org.SecurityPolicy.MaxAccessTokenLifetime; maxLifetime != nil {
+		lifetime = min(lifetime, time.Duration(*maxLifetime))
+	}
+	key, err := authkey.NewKey()
+	if err != nil {
+		return nil, err
+	}
+	token := types.AccessToken{
+		ExpiresAt:      new(time.Now().Add(lifetime)),
+		Label:          new("workload identity: " + policy.Name),
+		UserAccountID:  policy.ServiceAccountID,
+		Key:            key,
+		OrganizationID: policy.OrganizationID,
+		Scopes:         policy.Scopes,
+	}
+	if err := db.DeleteExpiredAccessTokens(ctx, policy.ServiceAccountID, policy.OrganizationID); err != nil {
+		return nil, err
+	} else if err := db.CreateAccessToken(ctx, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
@@ -1,10 +1,16 @@
 import {
   Application,
   ApplicationVersion,
+  ApplicationVersionHealthRule,
   ApplicationVersionResource,
+  DeploymentMetadata,
   DeploymentRequest,
+  DeploymentRevisionPlan,
   DeploymentTarget,
   DeploymentTargetAccessResponse,
+  DeploymentTargetAgentNetwork,
+  DeploymentVolumeSnapshot,
+  VolumeBackupStorage,
 } from '../types';
 import {ConditionalPartial, defaultClientConfig} from './config';
 
@@ -19,6 +25,10 @@ export type ApplicationVersionFiles = {
   composeFile?: string;
   baseValuesFile?: string;
   templateFile?: string;
+  /** A multi-document YAML or a gzipped tar archive, only for kustomize versions. */
+  manifestFile?: string | Uint8Array<ArrayBuffer>;
+  /** The systemd unit file, only for systemd versions. */
+  unitFile?: string;
 };
 
 /**
@@ -69,6 +79,13 @@ export class Client {
     if (files?.templateFile) {
       formData.append('templatefile', new Blob([files.templateFile], {type: 'application/yaml'}));
     }
+    if (files?.unitFile) {
+      formData.append('unitfile', new Blob([files.unitFile], {type: 'text/plain'}));
+    }
+    if (files?.manifestFile) {
+      const type = typeof files.manifestFile === 'string' ? 'application/yaml' : 'application/gzip';
+      formData.append('manifestfile', new Blob([files.manifestFile], {type}));
+    }
     const path = `applications/${applicationId}/versions`;
     const response = await fetch(`${this.config.apiBase}${path}`, {
       method: 'POST',
@@ -88,6 +105,15 @@ export class Client {
     return this.get<ApplicationVersionResource[]>(`applications/${applicationId}/versions/${versionId}/resources`);
   }
 
+  public async getApplicationVersionHealthRules(
+    applicationId: string,
+    versionId: string
+  ): Promise<ApplicationVersionHealthRule[]> {
+    return this.get<ApplicationVersionHealthRule[]>(
+      `applications/${applicationId}/versions/${versionId}/health-rules`
+    );
+  }
+
   public async getDeploymentTargets(): Promise<DeploymentTarget[]> {
     return this.get<DeploymentTarget[]>('deployment-targets');
   }
@@ -104,6 +130,56 @@ export class Client {
     return this.put<DeploymentRequest>('deployments', deploymentRequest);
   }
 
+  public async getDeploymentPlan(deploymentId: string): Promise<DeploymentRevisionPlan> {
+    return this.get<DeploymentRevisionPlan>(`deployments/${deploymentId}/plan`);
+  }
+
+  /**
+   * Approves the plan of the current revision of an OpenTofu deployment. The digest must be the one of the plan that
+   * was reviewed, otherwise the request fails with a conflict.
+   */
+  public async approveDeploymentPlan(deploymentId: string, digest: string): Promise<DeploymentRevisionPlan> {
+    return this.post<DeploymentRevisionPlan, {digest: string}>(`deployments/${deploymentId}/plan/approve`, {digest});
+  }
+
+  public async getDeploymentMetadata(deploymentId: string): Promise<DeploymentMetadata> {
+    return this.get<DeploymentMetadata>(`deployments/${deploymentId}/metadata`);
+  }
+
+  public async getDeploymentVolumeSnapshots(deploymentId: string): Promise<DeploymentVolumeSnapshot[]> {
+    return this.get<DeploymentVolumeSnapshot[]>(`deployments/${deploymentId}/volume-snapshots`);
+  }
+
+  /**
+   * Rolls a Docker deployment back to the revision of the snapshot. The agent restores the volumes from the snapshot
+   * before that revision is started.
+   */
+  public async restoreDeploymentVolumeSnapshot(deploymentId: string, snapshotId: string): Promise<void> {
+    await this.post<void, undefined>(`deployments/${deploymentId}/volume-snapshots/${snapshotId}/restore`);
+  }
+
+  public async getVolumeBackupStorage(deploymentTargetId: string): Promise<VolumeBackupStorage> {
+    return this.get<VolumeBackupStorage>(`deployment-targets/${deploymentTargetId}/volume-backup-storage`);
+  }
+
+  public async updateVolumeBackupStorage(
+    deploymentTargetId: string,
+    storage: VolumeBackupStorage
+  ): Promise<VolumeBackupStorage> {
+    return this.put<VolumeBackupStorage>(`deployment-targets/${deploymentTargetId}/volume-backup-storage`, storage);
+  }
+
+  public async getDeploymentTargetAgentNetwork(deploymentTargetId: string): Promise<DeploymentTargetAgentNetwork> {
+    return this.get<DeploymentTargetAgentNetwork>(`deployment-targets/${deploymentTargetId}/agent-network`);
+  }
+
+  public async updateDeploymentTargetAgentNetwork(
+    deploymentTargetId: string,
+    network: DeploymentTargetAgentNetwork
+  ): Promise<DeploymentTargetAgentNetwork> {
+    return this.put<DeploymentTargetAgentNetwork>(`deployment-targets/${deploymentTargetId}/agent-network`, network);
+  }
+
   public async createAccessForDeploymentTarget(deploymentTargetId: string): Promise<DeploymentTargetAccessResponse> {
     return this.post<DeploymentTargetAccessResponse>(`deployment-targets/${deploymentTargetId}/access-request`);
   }
@@ -119,7 +195,7 @@ export class Client {
     return await this.handleResponse<T>(response, 'GET', path);
   }
 
-  private async post<T>(path: string, body?: T): Promise<T> {
+  private async post<T, B = T>(path: string, body?: B): Promise<T> {
     const response = await fetch(`${this.config.apiBase}${path}`, {
       method: 'POST',
       headers: {
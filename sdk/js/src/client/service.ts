@@ -2,6 +2,7 @@ import semver from 'semver/preload';
 import {
   Application,
   ApplicationVersion,
+  ApplicationVersionHealthRule,
   ApplicationVersionResource,
   DeploymentRequest,
   DeploymentTarget,
@@ -162,6 +163,7 @@ export class DistrService {
       templateFile?: string;
       linkTemplate?: string;
       resources?: ApplicationVersionResource[];
+      healthRules?: ApplicationVersionHealthRule[];
     }
   ): Promise<ApplicationVersion> {
     return this.client.createApplicationVersion(
@@ -169,11 +171,116 @@ export class DistrService {
       {
         name: versionName,
         linkTemplate: data.linkTemplate ?? '',
+        sourceType: 'helm',
         chartName: data.chartName,
         chartVersion: data.chartVersion,
         chartType: data.chartType,
         chartUrl: data.chartUrl,
         resources: data.resources,
+        healthRules: data.healthRules,
+      },
+      {
+        baseValuesFile: data.baseValuesFile,
+        templateFile: data.templateFile,
+      }
+    );
+  }
+
+  /**
+   * Creates a new application version for the given Kubernetes application using plain manifests or a Kustomize base.
+   * The manifest file is either a multi-document YAML or a gzipped tar archive, which may contain a kustomization.yaml.
+   * @param applicationId
+   * @param versionName
+   * @param data
+   */
+  public async createKustomizeApplicationVersion(
+    applicationId: string,
+    versionName: string,
+    data: {
+      manifestFile: string | Uint8Array<ArrayBuffer>;
+      templateFile?: string;
+      linkTemplate?: string;
+      resources?: ApplicationVersionResource[];
+      healthRules?: ApplicationVersionHealthRule[];
+    }
+  ): Promise<ApplicationVersion> {
+    return this.client.createApplicationVersion(
+      applicationId,
+      {
+        name: versionName,
+        linkTemplate: data.linkTemplate ?? '',
+        sourceType: 'kustomize',
+        resources: data.resources,
+        healthRules: data.healthRules,
+      },
+      {
+        manifestFile: data.manifestFile,
+        templateFile: data.templateFile,
+      }
+    );
+  }
+
+  /**
+   * Creates a new application version for the given systemd application. The package reference points to a tarball in
+   * the registry, which is installed together with the unit file.
+   * @param applicationId
+   * @param versionName
+   * @param data
+   */
+  public async createSystemdApplicationVersion(
+    applicationId: string,
+    versionName: string,
+    data: {
+      packageRef: string;
+      unitFile: string;
+      baseValuesFile?: string;
+      templateFile?: string;
+      linkTemplate?: string;
+      resources?: ApplicationVersionResource[];
+    }
+  ): Promise<ApplicationVersion> {
+    return this.client.createApplicationVersion(
+      applicationId,
+      {
+        name: versionName,
+        linkTemplate: data.linkTemplate ?? '',
+        packageRef: data.packageRef,
+        resources: data.resources,
+      },
+      {
+        unitFile: data.unitFile,
+        baseValuesFile: data.baseValuesFile,
+        templateFile: data.templateFile,
+      }
+    );
+  }
+
+  /**
+   * Creates a new application version for the given OpenTofu application. The module reference points to a tarball
+   * of an OpenTofu or Terraform module in the registry. The values of a deployment are passed to the module as
+   * variables.
+   * @param applicationId
+   * @param versionName
+   * @param data
+   */
+  public async createOpenTofuApplicationVersion(
+    applicationId: string,
+    versionName: string,
+    data: {
+      moduleRef: string;
+      baseValuesFile?: string;
+      templateFile?: string;
+      linkTemplate?: string;
+      resources?: ApplicationVersionResource[];
+    }
+  ): Promise<ApplicationVersion> {
+    return this.client.createApplicationVersion(
+      applicationId,
+      {
+        name: versionName,
+        linkTemplate: data.linkTemplate ?? '',
+        packageRef: data.moduleRef,
+        resources: data.resources,
       },
       {
         baseValuesFile: data.baseValuesFile,
@@ -1,5 +1,5 @@
 import {BaseModel, Named} from './base';
-import {DeploymentType, HelmChartType} from './deployment';
+import {DeploymentType, HelmChartType, KubernetesSourceType} from './deployment';
 
 export interface Application extends BaseModel, Named {
   type: DeploymentType;
@@ -15,11 +15,14 @@ export interface ApplicationVersion {
   createdAt?: string;
   archivedAt?: string;
   applicationId?: string;
+  sourceType?: KubernetesSourceType;
   chartType?: HelmChartType;
   chartName?: string;
   chartUrl?: string;
   chartVersion?: string;
+  packageRef?: string;
   resources?: ApplicationVersionResource[];
+  healthRules?: ApplicationVersionHealthRule[];
 }
 
 export interface ApplicationVersionResource {
@@ -30,6 +33,14 @@ export interface ApplicationVersionResource {
   visibleToCustomers: boolean;
 }
 
+export interface ApplicationVersionHealthRule {
+  id?: string;
+  applicationVersionId?: string;
+  kind: string;
+  jsonPath: string;
+  value?: string;
+}
+
 export interface PatchApplicationRequest {
   name?: string;
   versions?: {id: string; archivedAt?: string}[];
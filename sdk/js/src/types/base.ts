@@ -11,7 +11,9 @@ export interface TokenResponse {
   token: string;
 }
 
-export type LoginResponse = ({requiresMfa: false; redirectUrl?: string} & TokenResponse) | {requiresMfa: true};
+export type LoginResponse =
+  | ({requiresMfa: false; redirectUrl?: string} & TokenResponse)
+  | {requiresMfa: true; webAuthn?: {sessionId: string; options: {publicKey: unknown}}};
 
 export interface DeploymentTargetAccessResponse {
   connectUrl: string;
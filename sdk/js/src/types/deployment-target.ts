@@ -1,7 +1,7 @@
 import {AgentVersion} from './agent-version';
 import {BaseModel, Named} from './base';
 import {CustomerOrganization} from './customer-organization';
-import {DeploymentTargetScope, DeploymentType, DeploymentWithLatestRevision} from './deployment';
+import {ContainerRuntime, DeploymentTargetScope, DeploymentType, DeploymentWithLatestRevision} from './deployment';
 
 export interface DeploymentTarget extends BaseModel, Named {
   name: string;
@@ -20,6 +20,7 @@ export interface DeploymentTarget extends BaseModel, Named {
   automaticUpdatesEnabled?: boolean;
   resources?: DeploymentTargetResources;
   dockerEndpoint?: string;
+  containerRuntime?: ContainerRuntime;
 }
 
 export interface DeploymentTargetResources {
@@ -28,3 +29,21 @@ export interface DeploymentTargetResources {
   cpuLimit: string;
   memoryLimit: string;
 }
+
+export interface DeploymentTargetAgentNetwork {
+  updatedAt?: string;
+  proxyUrl?: string;
+  proxyUsername?: string;
+  /** Only set in responses, which never contain the proxy password. */
+  proxyPasswordSet?: boolean;
+  /** Can be omitted to keep the proxy password of the existing settings. */
+  proxyPassword?: string;
+  noProxy?: string;
+  /** PEM encoded certificates that the agent trusts in addition to the system CAs. */
+  caCertificates?: string;
+  clientCertificate?: string;
+  /** Only set in responses, which never contain the client key. */
+  clientKeySet?: boolean;
+  /** Can be omitted to keep the client key of the existing settings. */
+  clientKey?: string;
+}
@@ -19,6 +19,8 @@ export interface DeploymentRequest {
   forceRestart?: boolean;
   ignoreRevisionSkew?: boolean;
   helmOptions?: HelmOptions;
+  /** Defaults to the policy of the previous revision. */
+  volumeBackupPolicy?: VolumeBackupPolicy;
 }
 
 export interface HelmOptions {
@@ -49,6 +51,8 @@ export interface DeploymentWithLatestRevision extends Deployment {
   deploymentRevisionCreatedAt?: string;
   latestStatus?: DeploymentRevisionStatus;
   helmOptions?: HelmOptions;
+  volumeBackupPolicy?: VolumeBackupPolicy;
+  restoreVolumeSnapshotId?: string;
 }
 
 export interface DeploymentRevisionStatus extends BaseModel {
@@ -78,15 +82,70 @@ export interface DeploymentRevisionResponse {
   forceRestart: boolean;
   ignoreRevisionSkew: boolean;
   helmOptions?: HelmOptions;
+  volumeBackupPolicy?: VolumeBackupPolicy;
+  restoreVolumeSnapshotId?: string;
   createdBy?: DeploymentRevisionCreator;
 }
 
-export type DeploymentType = 'docker' | 'kubernetes';
+export interface DeploymentRevisionPlan {
+  deploymentRevisionId: string;
+  createdAt: string;
+  digest: string;
+  hasChanges: boolean;
+  output: string;
+  approvedAt?: string;
+}
+
+export interface DeploymentOutput {
+  /** Omitted for sensitive outputs, which the agent does not report. */
+  value?: unknown;
+  sensitive?: boolean;
+}
+
+export interface DeploymentMetadata {
+  deploymentRevisionId: string;
+  updatedAt: string;
+  outputs: Record<string, DeploymentOutput>;
+}
+
+export interface DeploymentVolumeSnapshot {
+  id: string;
+  createdAt: string;
+  deploymentRevisionId: string;
+  sizeBytes: number;
+  volumes: string[];
+}
+
+export interface VolumeBackupStorage {
+  updatedAt?: string;
+  type: VolumeBackupStorageType;
+  retention: number;
+  s3Endpoint?: string;
+  s3Region?: string;
+  s3Bucket?: string;
+  s3Prefix?: string;
+  s3UsePathStyle?: boolean;
+  s3AccessKeyId?: string;
+  /** Only set in responses, which never contain the secret access key. */
+  s3SecretAccessKeySet?: boolean;
+  /** Can be omitted to keep the secret access key of the existing storage. */
+  s3SecretAccessKey?: string;
+}
+
+export type VolumeBackupPolicy = 'none' | 'beforeUpdate' | 'beforeVersionUpdate';
+
+export type VolumeBackupStorageType = 'local' | 's3';
+
+export type DeploymentType = 'docker' | 'kubernetes' | 'systemd' | 'opentofu';
 
 export type HelmChartType = 'repository' | 'oci';
 
+export type KubernetesSourceType = 'helm' | 'kustomize';
+
 export type DockerType = 'compose' | 'swarm';
 
+export type ContainerRuntime = 'docker' | 'podman' | 'quadlet';
+
 export type DeploymentStatusType = 'healthy' | 'running' | 'progressing' | 'error';
 
 export type DeploymentTargetScope = 'cluster' | 'namespace';